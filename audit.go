@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/selinux/go-selinux"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+)
+
+// auditVirtControl is the AUDIT_VIRT_CONTROL audit message type, used for
+// the virtual machine and container control operations.
+const auditVirtControl = 2500
+
+// auditedCommands are the commands which are recorded to the audit log.
+var auditedCommands = []string{"create", "delete", "exec", "gc", "kill", "run", "start", "update"}
+
+var (
+	// auditLog is the audit log file set by the --audit-log option, if any.
+	auditLog *os.File
+	// auditDaemon is whether the audit records are sent to the audit
+	// subsystem (see the --audit-daemon option).
+	auditDaemon bool
+	// auditPending is the audit record of the command being run, which is
+	// written again, with the result, once the command completes.
+	auditPending *auditRecord
+)
+
+// auditRecord is an audit log entry, which is written as a JSON line.
+type auditRecord struct {
+	Time    time.Time `json:"time"`
+	Command string    `json:"command"`
+	ID      string    `json:"id,omitempty"`
+	Args    []string  `json:"args"`
+	Pid     int       `json:"pid"`
+	UID     int       `json:"uid"`
+	GID     int       `json:"gid"`
+	// LoginUID is the audit login UID of the caller, which is kept across
+	// su and sudo. It is omitted if not set.
+	LoginUID *uint32 `json:"loginuid,omitempty"`
+	// SELinuxContext is the SELinux context of the caller, if SELinux is
+	// enabled.
+	SELinuxContext string `json:"selinux_context,omitempty"`
+	// Result is "attempt" for the record written before the command is
+	// run, and either "success" or "failure" for the one written once it
+	// completes. An attempt without a result (from the same pid) means runc
+	// was killed while running the command.
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+	// ExitCode is the exit code of the container process, for the commands
+	// waiting for it to exit.
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+// configAudit sets up the audit log according to the global options.
+// Failing to open the audit log is an error, so that no privileged
+// operation is left unrecorded.
+func configAudit(context *cli.Context) error {
+	if file := context.GlobalString("audit-log"); file != "" {
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_SYNC|unix.O_CLOEXEC, 0o600)
+		if err != nil {
+			return fmt.Errorf("unable to open audit log: %w", err)
+		}
+		auditLog = f
+	}
+	auditDaemon = context.GlobalBool("audit-daemon")
+	return nil
+}
+
+// auditCommands makes the commands listed in auditedCommands record their
+// invocations to the audit log.
+func auditCommands(commands []cli.Command) {
+	for i, cmd := range commands {
+		for _, name := range auditedCommands {
+			if cmd.Name != name {
+				continue
+			}
+			action := cmd.Action.(func(*cli.Context) error)
+			commands[i].Action = func(context *cli.Context) error {
+				if err := auditBegin(context); err != nil {
+					return err
+				}
+				err := action(context)
+				auditEnd(err, nil)
+				return err
+			}
+		}
+	}
+}
+
+// auditBegin writes the attempt record of the command being run to the
+// audit log, before it is run, so that the command is recorded even if runc
+// is killed before it completes. Failing to write it is an error, so that no
+// privileged operation is left unrecorded. The audit daemon only gets the
+// result record (see auditEnd), as its messages have no attempt result.
+func auditBegin(context *cli.Context) error {
+	if auditLog == nil && !auditDaemon {
+		return nil
+	}
+	r := &auditRecord{
+		Time:    time.Now().UTC(),
+		Command: context.Command.Name,
+		ID:      context.Args().First(),
+		Args:    os.Args,
+		Pid:     os.Getpid(),
+		UID:     os.Getuid(),
+		GID:     os.Getgid(),
+	}
+	if data, err := os.ReadFile("/proc/self/loginuid"); err == nil {
+		auid, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+		// (uint32)-1 means the login UID is not set.
+		if err == nil && auid != math.MaxUint32 {
+			v := uint32(auid)
+			r.LoginUID = &v
+		}
+	}
+	if selinux.GetEnabled() {
+		r.SELinuxContext, _ = selinux.CurrentLabel()
+	}
+	auditPending = r
+	if auditLog != nil {
+		attempt := *r
+		attempt.Result = "attempt"
+		if err := writeAuditRecord(&attempt); err != nil {
+			return fmt.Errorf("unable to write audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeAuditRecord appends r to the audit log, as a JSON line.
+func writeAuditRecord(r *auditRecord) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = auditLog.Write(append(data, '\n'))
+	return err
+}
+
+// auditEnd writes the result record of the command being run, if any, with
+// the command result (err), and the container process exit code, if known.
+// It is called once the command completes, either by returning, or by
+// exiting (see fatalWithCode).
+func auditEnd(err error, exitCode *int) {
+	r := auditPending
+	if r == nil {
+		return
+	}
+	auditPending = nil
+	r.Time = time.Now().UTC()
+	r.Result = "success"
+	if err != nil {
+		r.Result = "failure"
+		r.Error = err.Error()
+	}
+	r.ExitCode = exitCode
+
+	if auditLog != nil {
+		if err := writeAuditRecord(r); err != nil {
+			logrus.Errorf("unable to write audit log: %v", err)
+		}
+	}
+	if auditDaemon {
+		if err := sendAuditMessage(r); err != nil {
+			logrus.Errorf("unable to send audit message: %v", err)
+		}
+	}
+}
+
+// auditMessage formats r as an audit message. The kernel adds the caller
+// identity (pid, uid, auid, subj, etc.) to the message.
+func auditMessage(r *auditRecord) string {
+	res := "success"
+	if r.Result != "success" {
+		res = "failed"
+	}
+	return fmt.Sprintf("virt=runc op=%s vm=%s cmd=%s res=%s",
+		r.Command, auditEncode(r.ID), auditEncode(strings.Join(r.Args, " ")), res)
+}
+
+// auditEncode encodes an untrusted value the way the audit tools expect:
+// quoted if it has no special characters, and hex-encoded otherwise.
+func auditEncode(s string) string {
+	for _, c := range s {
+		if c <= ' ' || c >= 0x7f || c == '"' {
+			return strings.ToUpper(hex.EncodeToString([]byte(s)))
+		}
+	}
+	return `"` + s + `"`
+}
+
+// sendAuditMessage sends r to the audit subsystem, as an AUDIT_VIRT_CONTROL
+// message, which requires CAP_AUDIT_WRITE.
+func sendAuditMessage(r *auditRecord) error {
+	req := nl.NewNetlinkRequest(auditVirtControl, unix.NLM_F_ACK)
+	req.AddRawData(append([]byte(auditMessage(r)), 0))
+	_, err := req.Execute(unix.NETLINK_AUDIT, 0)
+	return err
+}
+
+// auditEndStatus writes the successful audit record of a command which has
+// run a container process, with its exit code (unless it is detached).
+func auditEndStatus(context *cli.Context, status int) {
+	if context.Bool("detach") {
+		auditEnd(nil, nil)
+		return
+	}
+	auditEnd(nil, &status)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func TestAuditEncode(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{in: "test", out: `"test"`},
+		{in: "", out: `""`},
+		{in: "a b", out: "612062"},
+		{in: `a"`, out: "6122"},
+	} {
+		if got := auditEncode(tc.in); got != tc.out {
+			t.Errorf("auditEncode(%q): expected %s, got %s", tc.in, tc.out, got)
+		}
+	}
+}
+
+func TestAuditEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog = f
+	defer func() {
+		auditLog = nil
+		f.Close()
+	}()
+
+	status := 3
+	auditPending = &auditRecord{Command: "exec", ID: "ct"}
+	auditEnd(nil, &status)
+	auditPending = &auditRecord{Command: "kill", ID: "ct"}
+	auditEnd(errors.New("container not running"), nil)
+	// No pending record.
+	auditEnd(nil, nil)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var recs []auditRecord
+	for dec.More() {
+		var r auditRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, r)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected 2 records, got %d: %s", len(recs), data)
+	}
+	if r := recs[0]; r.Command != "exec" || r.Result != "success" || r.ExitCode == nil || *r.ExitCode != 3 {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r := recs[1]; r.Command != "kill" || r.Result != "failure" || r.Error != "container not running" || r.ExitCode != nil {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
+
+func TestAuditBegin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditLog = f
+	defer func() {
+		auditLog = nil
+		auditPending = nil
+		f.Close()
+	}()
+
+	set := flag.NewFlagSet("kill", flag.ContinueOnError)
+	if err := set.Parse([]string{"ct"}); err != nil {
+		t.Fatal(err)
+	}
+	context := cli.NewContext(nil, set, nil)
+	context.Command = cli.Command{Name: "kill"}
+	if err := auditBegin(context); err != nil {
+		t.Fatal(err)
+	}
+	// The attempt is recorded before the command completes.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r auditRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Command != "kill" || r.ID != "ct" || r.Result != "attempt" || r.Pid != os.Getpid() {
+		t.Errorf("unexpected attempt record: %+v", r)
+	}
+
+	auditEnd(nil, nil)
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(data, []byte("\n")); n != 2 {
+		t.Fatalf("expected 2 records, got %d: %s", n, data)
+	}
+
+	// The command is not run if the attempt can't be recorded.
+	f.Close()
+	if err := auditBegin(context); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/moby/sys/userns"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -38,11 +41,19 @@ checkpointed.`,
 		cli.BoolFlag{Name: "lazy-pages", Usage: "use userfaultfd to lazily restore memory pages"},
 		cli.IntFlag{Name: "status-fd", Value: -1, Usage: "criu writes \\0 to this FD once lazy-pages is ready"},
 		cli.StringFlag{Name: "page-server", Value: "", Usage: "ADDRESS:PORT of the page server"},
+		cli.StringFlag{Name: "lazy-pages-server", Value: "", Usage: "use lazy migration, serving the memory pages on ADDRESS:PORT until they are all restored"},
 		cli.BoolFlag{Name: "file-locks", Usage: "handle file locks, for safety"},
 		cli.BoolFlag{Name: "pre-dump", Usage: "dump container's memory information only, leave the container running after this"},
+		cli.IntFlag{Name: "pre-dump-count", Usage: "do N pre-dumps before the final dump, each one relative to the previous one"},
+		cli.DurationFlag{Name: "pre-dump-interval", Usage: "time to wait after each pre-dump of --pre-dump-count"},
 		cli.StringFlag{Name: "manage-cgroups-mode", Value: "", Usage: "cgroups mode: soft|full|strict|ignore (default: soft)"},
 		cli.StringSliceFlag{Name: "empty-ns", Usage: "create a namespace, but don't restore its properties"},
 		cli.BoolFlag{Name: "auto-dedup", Usage: "enable auto deduplication of memory images"},
+		cli.StringSliceFlag{Name: "external-mount", Usage: "do not dump the mount at DEST in the container, in addition to the bind mounts (can be specified multiple times)"},
+		cli.StringFlag{Name: "external-netns", Usage: "do not dump the container network namespace, bind-mounted at PATH (by default, only a configured network namespace path is external)"},
+		cli.BoolFlag{Name: "manifest", Usage: "write the list of the container mounts, devices, and namespaces the restore host must provide to " + libcontainer.CheckpointManifestFilename + " in the image path"},
+		cli.StringFlag{Name: "progress", Usage: "report the progress of the dump as JSON lines (the only supported format is json)"},
+		cli.IntFlag{Name: "progress-fd", Value: 2, Usage: "write the progress records of --progress to this file descriptor"},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -69,17 +80,83 @@ checkpointed.`,
 			return err
 		}
 
-		err = container.Checkpoint(options)
+		var manifest *libcontainer.CheckpointManifest
+		if context.Bool("manifest") {
+			if options.PreDump {
+				return errors.New("--manifest can't be used with --pre-dump")
+			}
+			manifest = container.CheckpointManifest()
+		}
+
+		if n := context.Int("pre-dump-count"); n != 0 {
+			err = iterativeCheckpoint(container, options, n, context.Duration("pre-dump-interval"))
+		} else {
+			err = container.Checkpoint(options)
+		}
 		if err == nil && !options.LeaveRunning && !options.PreDump {
 			// Destroy the container unless we tell CRIU to keep it.
 			if err := container.Destroy(); err != nil {
 				logrus.Warn(err)
 			}
 		}
+		if err == nil && manifest != nil {
+			err = writeCheckpointManifest(options.ImagesDirectory, manifest)
+		}
 		return err
 	},
 }
 
+// writeCheckpointManifest writes manifest to the images directory dir.
+func writeCheckpointManifest(dir string, manifest *libcontainer.CheckpointManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, libcontainer.CheckpointManifestFilename), append(data, '\n'), 0o600)
+}
+
+// iterativeCheckpoint checkpoints container after count pre-dumps, waiting
+// for interval after each one. Each pre-dump is saved to the pre-dump-<N>
+// sub-directory of the images directory, relative to the previous pre-dump
+// (or to the parent image of options for the first one), and the final dump
+// is relative to the last pre-dump, so that only the memory pages modified
+// since then are dumped while the container is frozen.
+func iterativeCheckpoint(container *libcontainer.Container, options *libcontainer.CriuOpts, count int, interval time.Duration) error {
+	if options.WorkDirectory != "" {
+		if err := os.MkdirAll(options.WorkDirectory, 0o700); err != nil {
+			return err
+		}
+	}
+	parent := options.ParentImage
+	for i := 1; i <= count; i++ {
+		dir := "pre-dump-" + strconv.Itoa(i)
+		o := *options
+		o.PreDump = true
+		// The memory pages are only lazily restored from the final dump.
+		o.LazyPages = false
+		o.StatusFd = -1
+		o.ImagesDirectory = filepath.Join(options.ImagesDirectory, dir)
+		if parent != "" {
+			// The parent image path is relative to the images directory.
+			o.ParentImage = filepath.Join("..", parent)
+		}
+		if options.WorkDirectory != "" {
+			o.WorkDirectory = filepath.Join(options.WorkDirectory, dir)
+		}
+		start := time.Now()
+		if err := container.Checkpoint(&o); err != nil {
+			return fmt.Errorf("pre-dump %d of %d: %w", i, count, err)
+		}
+		logrus.Debugf("pre-dump %d of %d to %s done in %s", i, count, o.ImagesDirectory, time.Since(start))
+		parent = dir
+		if interval > 0 {
+			time.Sleep(interval)
+		}
+	}
+	options.ParentImage = parent
+	return container.Checkpoint(options)
+}
+
 func prepareImagePaths(context *cli.Context) (string, string, error) {
 	imagePath := context.String("image-path")
 	if imagePath == "" {
@@ -138,22 +215,42 @@ func criuOptions(context *cli.Context) (*libcontainer.CriuOpts, error) {
 		ManageCgroupsMode:       context.String("manage-cgroups-mode"),
 	}
 
+	opts.Progress, err = newProgressReporter(context, context.Args().First())
+	if err != nil {
+		return nil, err
+	}
+
 	// CRIU options below may or may not be set.
 
 	if psOpt := context.String("page-server"); psOpt != "" {
-		address, port, err := net.SplitHostPort(psOpt)
+		opts.PageServer, err = parsePageServer("page-server", psOpt)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		if err != nil || address == "" || port == "" {
-			return nil, errors.New("Use --page-server ADDRESS:PORT to specify page server")
+	if psOpt := context.String("lazy-pages-server"); psOpt != "" {
+		if context.String("page-server") != "" {
+			return nil, errors.New("--lazy-pages-server and --page-server are mutually exclusive")
 		}
-		portInt, err := strconv.Atoi(port)
+		opts.PageServer, err = parsePageServer("lazy-pages-server", psOpt)
 		if err != nil {
-			return nil, errors.New("Invalid port number")
+			return nil, err
 		}
-		opts.PageServer = libcontainer.CriuPageServerInfo{
-			Address: address,
-			Port:    int32(portInt),
+		opts.LazyPages = true
+	}
+
+	if n := context.Int("pre-dump-count"); n != 0 {
+		switch {
+		case n < 0:
+			return nil, errors.New("--pre-dump-count must be positive")
+		case opts.PreDump:
+			return nil, errors.New("--pre-dump-count and --pre-dump are mutually exclusive")
+		case opts.PageServer.Address != "":
+			return nil, errors.New("--pre-dump-count can't be used with a page server")
 		}
+	} else if context.IsSet("pre-dump-interval") {
+		return nil, errors.New("--pre-dump-interval requires --pre-dump-count")
 	}
 
 	// runc doesn't manage network devices and their configuration.
@@ -175,5 +272,59 @@ func criuOptions(context *cli.Context) (*libcontainer.CriuOpts, error) {
 
 	opts.EmptyNs = uint32(nsmask)
 
+	for _, s := range context.StringSlice("external-mount") {
+		m, err := parseExternalMount(s)
+		if err != nil {
+			return nil, err
+		}
+		opts.ExternalMounts = append(opts.ExternalMounts, m)
+	}
+	opts.ExternalNetns = context.String("external-netns")
+	for _, s := range context.StringSlice("inherit-fd") {
+		fd, err := parseInheritFd(s)
+		if err != nil {
+			return nil, err
+		}
+		opts.InheritFds = append(opts.InheritFds, fd)
+	}
+
 	return opts, nil
 }
+
+func parsePageServer(flag, value string) (libcontainer.CriuPageServerInfo, error) {
+	address, port, err := net.SplitHostPort(value)
+	if err != nil || address == "" || port == "" {
+		return libcontainer.CriuPageServerInfo{}, fmt.Errorf("Use --%s ADDRESS:PORT to specify page server", flag)
+	}
+	portInt, err := strconv.Atoi(port)
+	if err != nil {
+		return libcontainer.CriuPageServerInfo{}, errors.New("Invalid port number")
+	}
+	return libcontainer.CriuPageServerInfo{
+		Address: address,
+		Port:    int32(portInt),
+	}, nil
+}
+
+// parseExternalMount parses the DEST[:SOURCE] value of --external-mount. The
+// source defaults to the destination.
+func parseExternalMount(value string) (libcontainer.ExternalMount, error) {
+	dest, src, _ := strings.Cut(value, ":")
+	if src == "" {
+		src = dest
+	}
+	if !filepath.IsAbs(dest) || !filepath.IsAbs(src) {
+		return libcontainer.ExternalMount{}, fmt.Errorf("invalid --external-mount %q: use absolute DEST[:SOURCE] paths", value)
+	}
+	return libcontainer.ExternalMount{Destination: dest, Source: src}, nil
+}
+
+// parseInheritFd parses the FD:KEY value of --inherit-fd.
+func parseInheritFd(value string) (libcontainer.InheritFd, error) {
+	fdStr, key, _ := strings.Cut(value, ":")
+	fd, err := strconv.Atoi(fdStr)
+	if err != nil || fd < 3 || key == "" {
+		return libcontainer.InheritFd{}, fmt.Errorf("invalid --inherit-fd %q: use FD:KEY, with FD above 2", value)
+	}
+	return libcontainer.InheritFd{Fd: fd, Key: key}, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/containerd/console"
+	"github.com/docker/go-units"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/consolelog"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// consoleLogCommand is run by runc create and run --console-log, in the
+// background, to write the console output of the container to a log file.
+// It receives the pseudoterminal master on the file descriptor 3.
+var consoleLogCommand = cli.Command{
+	Name:      "console-log",
+	Usage:     "write the console output of a container to a log file",
+	ArgsUsage: "<path>",
+	Hidden:    true,
+	Flags: []cli.Flag{
+		cli.Int64Flag{Name: "max-size", Usage: "the size the log file is rotated at, in bytes"},
+		cli.IntFlag{Name: "max-files", Usage: "the number of rotated log files kept"},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		socket := os.NewFile(3, "console-socket")
+		f, err := utils.RecvFile(socket)
+		socket.Close()
+		if err != nil {
+			// The container has failed to start.
+			return err
+		}
+		defer f.Close()
+		if err := console.ClearONLCR(f.Fd()); err != nil {
+			return err
+		}
+		w, err := consolelog.Open(context.Args().First(), consolelog.Options{
+			MaxSize:  context.Int64("max-size"),
+			MaxFiles: context.Int("max-files"),
+		})
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		return consolelog.Copy(w, f)
+	},
+}
+
+// consoleLogArgs returns the "runc console-log" command line for the
+// --console-log options of runc create or run, or nil if those are not set.
+func consoleLogArgs(context *cli.Context) ([]string, error) {
+	path := context.String("console-log")
+	if path == "" {
+		return nil, nil
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	size, err := units.RAMInBytes(context.String("console-log-max-size"))
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("invalid console log max size %q", context.String("console-log-max-size"))
+	}
+	files := context.Int("console-log-max-files")
+	if files < 0 {
+		return nil, fmt.Errorf("invalid console log max files %d", files)
+	}
+	return append(globalArgs(context), "console-log",
+		"--max-size", strconv.FormatInt(size, 10),
+		"--max-files", strconv.Itoa(files),
+		path), nil
+}
+
+// setupConsoleLog starts "runc console-log" with args (see consoleLogArgs),
+// and sets up process so that its pseudoterminal master is handed over to
+// it. The console logger runs in its own session, and outlives runc.
+func setupConsoleLog(t *tty, process *libcontainer.Process, args []string) error {
+	parent, child, err := utils.NewSockPair("console")
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("/proc/self/exe", args...)
+	cmd.ExtraFiles = []*os.File{parent}
+	cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	parent.Close()
+	if err != nil {
+		child.Close()
+		return fmt.Errorf("unable to start the console logger: %w", err)
+	}
+	if err := cmd.Process.Release(); err != nil {
+		child.Close()
+		return err
+	}
+	t.postStart = append(t.postStart, child)
+	process.ConsoleSocket = child
+	return nil
+}
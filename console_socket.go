@@ -0,0 +1,219 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/containerd/console"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// consoleSocketProtocol is a way to hand the pseudoterminal master of a
+// process over to the caller of runc, selected by the scheme of the console
+// socket address ("<scheme>://<address>").
+type consoleSocketProtocol struct {
+	// handoff sets up process so that its pseudoterminal master is handed
+	// over to addr once it is created. The files to be closed once the
+	// process is started are added to t.postStart.
+	handoff func(t *tty, process *libcontainer.Process, addr string) error
+	// inherited is set if addr refers to the runc process itself (such as
+	// a file descriptor), so that it can't be used by a client of runc
+	// daemon.
+	inherited bool
+	// relayed is set if runc relays the console, rather than handing the
+	// pseudoterminal master over, so that runc has to keep running (which
+	// only runc daemon does).
+	relayed bool
+}
+
+var consoleSocketProtocols = map[string]consoleSocketProtocol{
+	"unix":  {handoff: unixConsoleSocket},
+	"fd":    {handoff: fdConsoleSocket, inherited: true},
+	"vsock": {handoff: vsockConsoleSocket, relayed: true},
+}
+
+// parseConsoleSocket returns the protocol and address of the console socket
+// sockpath. A path without a scheme is an AF_UNIX socket path.
+func parseConsoleSocket(sockpath string) (*consoleSocketProtocol, string, error) {
+	scheme, addr, ok := strings.Cut(sockpath, "://")
+	if !ok {
+		scheme, addr = "unix", sockpath
+	}
+	proto, ok := consoleSocketProtocols[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("invalid console socket %q: unknown protocol %q", sockpath, scheme)
+	}
+	if addr == "" {
+		return nil, "", fmt.Errorf("invalid console socket %q: empty address", sockpath)
+	}
+	return &proto, addr, nil
+}
+
+// checkConsoleSocket checks that the console socket sockpath can be used by
+// the runc CLI, or by runc daemon if daemon is set.
+func checkConsoleSocket(sockpath string, daemon bool) error {
+	proto, _, err := parseConsoleSocket(sockpath)
+	if err != nil {
+		return err
+	}
+	if daemon && proto.inherited {
+		return fmt.Errorf("console socket %q can't be used with runc daemon", sockpath)
+	}
+	if !daemon && proto.relayed {
+		return fmt.Errorf("console socket %q can only be used with runc daemon, which relays the console", sockpath)
+	}
+	return nil
+}
+
+// setupConsoleSocket sets up process so that its pseudoterminal master is
+// handed over using the console socket sockpath.
+func setupConsoleSocket(t *tty, process *libcontainer.Process, sockpath string) error {
+	proto, addr, err := parseConsoleSocket(sockpath)
+	if err != nil {
+		return err
+	}
+	return proto.handoff(t, process, addr)
+}
+
+// unixConsoleSocket sends the pseudoterminal master to the AF_UNIX socket
+// at path.
+func unixConsoleSocket(t *tty, process *libcontainer.Process, path string) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return errors.New("casting to UnixConn failed")
+	}
+	t.postStart = append(t.postStart, uc)
+	socket, err := uc.File()
+	if err != nil {
+		return err
+	}
+	t.postStart = append(t.postStart, socket)
+	process.ConsoleSocket = socket
+	return nil
+}
+
+// fdConsoleSocket sends the pseudoterminal master to the connected AF_UNIX
+// socket (such as one end of a socketpair) inherited by runc as the file
+// descriptor fd.
+func fdConsoleSocket(t *tty, process *libcontainer.Process, fd string) error {
+	n, err := strconv.Atoi(fd)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid console socket file descriptor %q", fd)
+	}
+	domain, err := unix.GetsockoptInt(n, unix.SOL_SOCKET, unix.SO_DOMAIN)
+	if err != nil {
+		return fmt.Errorf("console socket file descriptor %d: %w", n, os.NewSyscallError("getsockopt", err))
+	}
+	if domain != unix.AF_UNIX {
+		return fmt.Errorf("console socket file descriptor %d is not an AF_UNIX socket", n)
+	}
+	unix.CloseOnExec(n)
+	socket := os.NewFile(uintptr(n), "console-socket")
+	t.postStart = append(t.postStart, socket)
+	process.ConsoleSocket = socket
+	return nil
+}
+
+// vsockConsoleSocket connects to the AF_VSOCK address "cid:port", and relays
+// the console between the pseudoterminal master and the connection, as file
+// descriptors can't be passed over AF_VSOCK.
+func vsockConsoleSocket(t *tty, process *libcontainer.Process, addr string) error {
+	cid, port, ok := strings.Cut(addr, ":")
+	if !ok {
+		return fmt.Errorf("invalid vsock console socket address %q: must be 'cid:port'", addr)
+	}
+	var sa unix.SockaddrVM
+	for _, v := range []struct {
+		s string
+		p *uint32
+	}{{cid, &sa.CID}, {port, &sa.Port}} {
+		n, err := strconv.ParseUint(v.s, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid vsock console socket address %q: %w", addr, err)
+		}
+		*v.p = uint32(n)
+	}
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	if err := unix.Connect(fd, &sa); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("vsock console socket %s: %w", addr, os.NewSyscallError("connect", err))
+	}
+	// Make the connection pollable, so that closing it interrupts the relay.
+	if err := unix.SetNonblock(fd, true); err != nil {
+		unix.Close(fd)
+		return os.NewSyscallError("fcntl", err)
+	}
+	conn := os.NewFile(uintptr(fd), "vsock-console")
+
+	parent, child, err := utils.NewSockPair("console")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	t.postStart = append(t.postStart, child)
+	process.ConsoleSocket = child
+	go relayConsole(parent, conn)
+	return nil
+}
+
+// relayConsole receives the pseudoterminal master from socket, and copies
+// the data between it and conn until either is closed.
+func relayConsole(socket, conn *os.File) {
+	defer conn.Close()
+	f, err := utils.RecvFile(socket)
+	socket.Close()
+	if err != nil {
+		// The process has failed to start.
+		logrus.Warnf("console relay: %v", err)
+		return
+	}
+	cons, err := console.ConsoleFromFile(f)
+	if err != nil {
+		f.Close()
+		logrus.Warnf("console relay: %v", err)
+		return
+	}
+	epoller, err := console.NewEpoller()
+	if err != nil {
+		cons.Close()
+		logrus.Warnf("console relay: %v", err)
+		return
+	}
+	defer epoller.Close()
+	epollConsole, err := epoller.Add(cons)
+	if err != nil {
+		cons.Close()
+		logrus.Warnf("console relay: %v", err)
+		return
+	}
+	defer epollConsole.Close()
+	go func() { _ = epoller.Wait() }()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(conn, epollConsole)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(epollConsole, conn)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = epollConsole.Shutdown(epoller.CloseConsole)
+}
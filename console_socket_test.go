@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/containerd/console"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+func TestCheckConsoleSocket(t *testing.T) {
+	for _, tc := range []struct {
+		sockpath string
+		daemon   bool
+		isErr    bool
+	}{
+		{sockpath: "/run/console.sock"},
+		{sockpath: "/run/console.sock", daemon: true},
+		{sockpath: "unix:///run/console.sock"},
+		{sockpath: "fd://3"},
+		{sockpath: "fd://3", daemon: true, isErr: true},
+		{sockpath: "vsock://2:1024", isErr: true},
+		{sockpath: "vsock://2:1024", daemon: true},
+		{sockpath: "tcp://localhost:1024", isErr: true},
+		{sockpath: "unix://", isErr: true},
+	} {
+		err := checkConsoleSocket(tc.sockpath, tc.daemon)
+		if tc.isErr && err == nil {
+			t.Errorf("%s (daemon: %v): expected error, got nil", tc.sockpath, tc.daemon)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%s (daemon: %v): expected nil, got error %v", tc.sockpath, tc.daemon, err)
+		}
+	}
+}
+
+func TestFdConsoleSocket(t *testing.T) {
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Close(fds[0])
+
+	tt := &tty{}
+	process := &libcontainer.Process{}
+	if err := setupConsoleSocket(tt, process, "fd://"+strconv.Itoa(fds[1])); err != nil {
+		unix.Close(fds[1])
+		t.Fatal(err)
+	}
+	defer tt.ClosePostStart()
+	if process.ConsoleSocket == nil || process.ConsoleSocket.Fd() != uintptr(fds[1]) {
+		t.Fatalf("unexpected console socket %v", process.ConsoleSocket)
+	}
+
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := setupConsoleSocket(&tty{}, &libcontainer.Process{}, "fd://"+strconv.Itoa(int(f.Fd()))); err == nil {
+		t.Fatal("expected an error for a file descriptor which is not a socket")
+	}
+}
+
+func TestRelayConsole(t *testing.T) {
+	master, slavePath, err := console.NewPty()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slave, err := os.OpenFile(slavePath, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		t.Fatal(err)
+	}
+	defer slave.Close()
+	if err := console.ClearONLCR(slave.Fd()); err != nil {
+		t.Fatal(err)
+	}
+
+	socket, sender, err := utils.NewSockPair("console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, peer := os.NewFile(uintptr(fds[0]), "conn"), os.NewFile(uintptr(fds[1]), "peer")
+
+	done := make(chan struct{})
+	go func() {
+		relayConsole(socket, conn)
+		close(done)
+	}()
+	err = utils.SendRawFd(sender, "ptmx", master.Fd())
+	sender.Close()
+	master.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := slave.Write([]byte("out")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 3)
+	_ = peer.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(peer, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, []byte("out")) {
+		t.Fatalf("expected %q from the console, got %q", "out", buf)
+	}
+
+	if _, err := peer.Write([]byte("in\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf = make([]byte, 3)
+	if _, err := io.ReadFull(slave, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, []byte("in\n")) {
+		t.Fatalf("expected %q to the console, got %q", "in\n", buf)
+	}
+
+	// The relay stops once the connection is closed.
+	peer.Close()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the relay has not stopped")
+	}
+}
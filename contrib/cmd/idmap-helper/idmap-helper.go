@@ -0,0 +1,335 @@
+// idmap-helper is an example privileged ID mapping helper, which sets up the
+// user namespace mappings of rootless containers on behalf of runc (see
+// "runc create --idmap-helper"). Like newuidmap(1) and newgidmap(1), it only
+// allows the requesting user to map their own uid and gid, and the ranges
+// allocated to them in /etc/subuid and /etc/subgid. Unless the gid mappings
+// are all within the subordinate gid ranges, setgroups(2) is denied in the
+// user namespace, like newgidmap(1) does, so that the user can't drop their
+// supplementary groups (which may be used for negative ACLs).
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// request and response are the helper protocol messages, as documented
+// in libcontainer.HelperIDMapper.
+type request struct {
+	Pid         int     `json:"pid"`
+	UIDMappings []idMap `json:"uid_mappings"`
+	GIDMappings []idMap `json:"gid_mappings"`
+}
+
+type response struct {
+	Error string `json:"error,omitempty"`
+}
+
+type idMap struct {
+	ContainerID int64 `json:"container_id"`
+	HostID      int64 `json:"host_id"`
+	Size        int64 `json:"size"`
+}
+
+// idRange is a range of host IDs a user is allowed to map.
+type idRange struct {
+	start, size int64
+	// subID is set for a range allocated in a subordinate ID file, rather
+	// than the user's own ID.
+	subID bool
+}
+
+// procRoot is the procfs mount point. Overridden in tests.
+var procRoot = "/proc"
+
+func main() {
+	socketFile := flag.String("socketfile", "/run/idmap-helper.sock", "the helper socket (the runc --idmap-helper socket)")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	_ = os.Remove(*socketFile)
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: *socketFile, Net: "unix"})
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	// Any user may request the mappings they are allowed to.
+	if err := os.Chmod(*socketFile, 0o666); err != nil {
+		logrus.Fatal(err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, unix.SIGINT, unix.SIGTERM)
+	go func() {
+		<-sigs
+		l.Close()
+	}()
+
+	logrus.Infof("waiting for requests on %s", *socketFile)
+	for {
+		conn, err := l.AcceptUnix()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logrus.Fatal(err)
+		}
+		go func() {
+			defer conn.Close()
+			var resp response
+			if err := handle(conn); err != nil {
+				logrus.Warn(err)
+				resp.Error = err.Error()
+			}
+			_ = json.NewEncoder(conn).Encode(resp)
+		}()
+	}
+}
+
+func handle(conn *net.UnixConn) error {
+	cred, err := peerCred(conn)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64<<10)
+	oob := make([]byte, unix.CmsgSpace(4))
+	n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return fmt.Errorf("unable to read request: %w", err)
+	}
+	pidfd := -1
+	if oobn > 0 {
+		if pidfd, err = parsePidfd(oob[:oobn]); err != nil {
+			return err
+		}
+		defer unix.Close(pidfd)
+	}
+	var req request
+	if err := json.Unmarshal(buf[:n], &req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+	// The pidfd, if any, pins the process, so that its pid can't be reused
+	// by another one while we are at it.
+	if pidfd != -1 {
+		if err := checkPidfd(pidfd, req.Pid); err != nil {
+			return err
+		}
+	}
+
+	// The process must belong to the requesting user.
+	var st unix.Stat_t
+	if err := unix.Stat(procPath(req.Pid), &st); err != nil {
+		return err
+	}
+	if st.Uid != cred.Uid {
+		return fmt.Errorf("process %d does not belong to uid %d", req.Pid, cred.Uid)
+	}
+
+	u, err := user.LookupId(strconv.Itoa(int(cred.Uid)))
+	if err != nil {
+		return err
+	}
+	uidRanges, err := subIDRanges("/etc/subuid", u.Username, u.Uid, int64(cred.Uid))
+	if err != nil {
+		return err
+	}
+	gidRanges, err := subIDRanges("/etc/subgid", u.Username, u.Uid, int64(cred.Gid))
+	if err != nil {
+		return err
+	}
+	if err := checkMappings(req.UIDMappings, uidRanges); err != nil {
+		return fmt.Errorf("uid mappings: %w", err)
+	}
+	if err := checkMappings(req.GIDMappings, gidRanges); err != nil {
+		return fmt.Errorf("gid mappings: %w", err)
+	}
+
+	if err := mapIDs(&req, gidRanges); err != nil {
+		return err
+	}
+	// Make sure the process was not replaced while we were at it.
+	if pidfd != -1 {
+		if err := checkPidfd(pidfd, req.Pid); err != nil {
+			return err
+		}
+	}
+	logrus.Infof("mapped user namespace of process %d for %s", req.Pid, u.Username)
+	return nil
+}
+
+func peerCred(conn *net.UnixConn) (*unix.Ucred, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var cred *unix.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return nil, err
+	}
+	return cred, credErr
+}
+
+// checkPidfd checks that the process of pidfd is still alive, and is the
+// process pid.
+func checkPidfd(pidfd, pid int) error {
+	if err := unix.PidfdSendSignal(pidfd, 0, nil, 0); err != nil {
+		return fmt.Errorf("process %d is gone: %w", pid, err)
+	}
+	fdPid, err := pidfdPid(pidfd)
+	if err != nil {
+		return err
+	}
+	if fdPid != pid {
+		return fmt.Errorf("the pidfd is of process %d, not %d", fdPid, pid)
+	}
+	return nil
+}
+
+// pidfdPid returns the pid of the process of pidfd, from its fdinfo.
+func pidfdPid(pidfd int) (int, error) {
+	f, err := os.Open("/proc/self/fdinfo/" + strconv.Itoa(pidfd))
+	if err != nil {
+		return -1, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if v, ok := strings.CutPrefix(s.Text(), "Pid:"); ok {
+			return strconv.Atoi(strings.TrimSpace(v))
+		}
+	}
+	if err := s.Err(); err != nil {
+		return -1, err
+	}
+	return -1, errors.New("no pid in the pidfd fdinfo")
+}
+
+func parsePidfd(oob []byte) (int, error) {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return -1, err
+	}
+	if len(msgs) != 1 {
+		return -1, errors.New("unexpected control messages")
+	}
+	fds, err := unix.ParseUnixRights(&msgs[0])
+	if err != nil {
+		return -1, err
+	}
+	if len(fds) != 1 {
+		for _, fd := range fds {
+			unix.Close(fd)
+		}
+		return -1, errors.New("unexpected file descriptors")
+	}
+	return fds[0], nil
+}
+
+// subIDRanges returns the ID ranges the user is allowed to map: its own
+// ID, and the ranges allocated to it in the subordinate ID file.
+func subIDRanges(file, name, uid string, own int64) ([]idRange, error) {
+	ranges := []idRange{{start: own, size: 1}}
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ranges, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Split(strings.TrimSpace(s.Text()), ":")
+		if len(fields) != 3 || (fields[0] != name && fields[0] != uid) {
+			continue
+		}
+		start, err1 := strconv.ParseInt(fields[1], 10, 64)
+		size, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil || start < 0 || size <= 0 {
+			continue
+		}
+		ranges = append(ranges, idRange{start: start, size: size, subID: true})
+	}
+	return ranges, s.Err()
+}
+
+// checkMappings checks that each of the mapped host ID ranges is within
+// one of the allowed ranges.
+func checkMappings(mappings []idMap, allowed []idRange) error {
+next:
+	for _, m := range mappings {
+		if m.Size <= 0 || m.HostID < 0 || m.ContainerID < 0 {
+			return fmt.Errorf("invalid mapping %d %d %d", m.ContainerID, m.HostID, m.Size)
+		}
+		for _, r := range allowed {
+			if m.HostID >= r.start && m.HostID+m.Size <= r.start+r.size {
+				continue next
+			}
+		}
+		return fmt.Errorf("host range %d-%d is not allowed", m.HostID, m.HostID+m.Size-1)
+	}
+	return nil
+}
+
+// subIDMappings reports whether each of the mapped host ID ranges is within
+// one of the subordinate ID ranges of allowed.
+func subIDMappings(mappings []idMap, allowed []idRange) bool {
+next:
+	for _, m := range mappings {
+		for _, r := range allowed {
+			if r.subID && m.HostID >= r.start && m.HostID+m.Size <= r.start+r.size {
+				continue next
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// mapIDs writes the mappings of req, which are checked already. Unless the
+// gid mappings are all granted in the subordinate gid file, setgroups(2) is
+// denied first, as newgidmap(1) does (see CVE-2018-7169).
+func mapIDs(req *request, gidRanges []idRange) error {
+	if err := writeMappings(req.Pid, "uid_map", req.UIDMappings); err != nil {
+		return err
+	}
+	if len(req.GIDMappings) > 0 && !subIDMappings(req.GIDMappings, gidRanges) {
+		if err := os.WriteFile(procPath(req.Pid)+"/setgroups", []byte("deny"), 0); err != nil {
+			return fmt.Errorf("unable to deny setgroups: %w", err)
+		}
+	}
+	return writeMappings(req.Pid, "gid_map", req.GIDMappings)
+}
+
+func writeMappings(pid int, file string, mappings []idMap) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	for _, m := range mappings {
+		fmt.Fprintf(&b, "%d %d %d\n", m.ContainerID, m.HostID, m.Size)
+	}
+	return os.WriteFile(procPath(pid)+"/"+file, []byte(b.String()), 0)
+}
+
+func procPath(pid int) string {
+	return procRoot + "/" + strconv.Itoa(pid)
+}
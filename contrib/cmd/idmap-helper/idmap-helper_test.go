@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestMapIDsSetgroups(t *testing.T) {
+	procRoot = t.TempDir()
+	t.Cleanup(func() { procRoot = "/proc" })
+
+	gidRanges := []idRange{{start: 1000, size: 1}, {start: 100000, size: 65536, subID: true}}
+	for _, tc := range []struct {
+		name     string
+		mappings []idMap
+		deny     bool
+	}{
+		{name: "subgid", mappings: []idMap{{ContainerID: 0, HostID: 100000, Size: 65536}}},
+		{name: "own gid", mappings: []idMap{{ContainerID: 0, HostID: 1000, Size: 1}}, deny: true},
+		{name: "own gid and subgid", mappings: []idMap{
+			{ContainerID: 0, HostID: 1000, Size: 1},
+			{ContainerID: 1, HostID: 100000, Size: 65536},
+		}, deny: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(procRoot, "1")
+			if err := os.RemoveAll(dir); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			req := &request{Pid: 1, GIDMappings: tc.mappings}
+			if err := mapIDs(req, gidRanges); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(filepath.Join(dir, "setgroups"))
+			if tc.deny {
+				if err != nil || string(data) != "deny" {
+					t.Errorf("expected setgroups to be denied, got %q (%v)", data, err)
+				}
+			} else if !os.IsNotExist(err) {
+				t.Errorf("expected setgroups not to be written, got %q (%v)", data, err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "gid_map")); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestCheckPidfd(t *testing.T) {
+	cmd := exec.Command("sleep", "1d")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	pid := cmd.Process.Pid
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Skipf("pidfd_open: %v", err)
+	}
+	defer unix.Close(pidfd)
+
+	if err := checkPidfd(pidfd, pid); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkPidfd(pidfd, os.Getpid()); err == nil {
+		t.Error("expected an error for the pidfd of another process")
+	}
+
+	// A process which is gone may have its pid reused.
+	_ = cmd.Process.Kill()
+	_ = cmd.Wait()
+	if err := checkPidfd(pidfd, pid); err == nil {
+		t.Error("expected an error for a process which is gone")
+	}
+}
+
+func TestPidfdPid(t *testing.T) {
+	pidfd, err := unix.PidfdOpen(os.Getpid(), 0)
+	if err != nil {
+		t.Skipf("pidfd_open: %v", err)
+	}
+	defer unix.Close(pidfd)
+	pid, err := pidfdPid(pidfd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != os.Getpid() {
+		t.Errorf("expected pid %d, got %d", os.Getpid(), pid)
+	}
+	if _, err := pidfdPid(0); err == nil {
+		t.Error("expected an error for a file descriptor which is not a pidfd")
+	}
+}
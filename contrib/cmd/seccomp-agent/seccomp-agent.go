@@ -0,0 +1,67 @@
+// seccomp-agent is an example seccomp agent, built on the
+// libcontainer/seccomp/notify package. It logs the system calls notified by
+// the containers (those with the SCMP_ACT_NOTIFY action in the seccomp
+// profile), and either lets them execute, or makes them fail with EPERM.
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/seccomp/notify"
+)
+
+func main() {
+	socketFile := flag.String("socketfile", "/run/seccomp-agent.socket", "the agent socket (the seccomp listenerPath of the containers)")
+	pidFile := flag.String("pid-file", "", "write the agent PID to this file")
+	deny := flag.Bool("deny", false, "make the notified system calls fail with EPERM, rather than execute them")
+	flag.Parse()
+	if flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	l, err := notify.Listen(*socketFile)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if *pidFile != "" {
+		if err := os.WriteFile(*pidFile, []byte(strconv.Itoa(os.Getpid())), 0o644); err != nil {
+			logrus.Fatal(err)
+		}
+	}
+
+	agent := &notify.Agent{
+		Default: func(c *notify.Container, req *notify.Request) notify.Response {
+			logrus.WithFields(logrus.Fields{
+				"container": c.State.State.ID,
+				"metadata":  c.State.Metadata,
+				"pid":       req.Pid,
+				"arch":      strconv.FormatUint(uint64(req.Arch), 16),
+				"nr":        req.Nr,
+				"args":      req.Args,
+			}).Info("system call")
+			if *deny {
+				return notify.Errno(unix.EPERM)
+			}
+			return notify.Continue()
+		},
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, unix.SIGINT, unix.SIGTERM)
+	go func() {
+		<-sigs
+		l.Close()
+	}()
+
+	logrus.Infof("waiting for containers on %s", *socketFile)
+	if err := agent.ListenAndServe(l); err != nil {
+		logrus.Fatal(err)
+	}
+}
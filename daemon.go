@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/opencontainers/runc/internal/specjson"
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/utils"
+	"github.com/opencontainers/runc/types"
+	pb "github.com/opencontainers/runc/types/daemon"
+)
+
+var daemonCommand = cli.Command{
+	Name:  "daemon",
+	Usage: "serve the container operations over a gRPC API",
+	Description: `The daemon command serves the create, start, exec, kill, delete, and events
+operations over gRPC, as defined by the runc.daemon.v1.Runtime service (see
+types/daemon/runtime.proto in the runc repository). The operations are done
+by the daemon itself, rather than by a new runc process for each of them.
+
+The container processes are children of the daemon, which reaps them. They are
+left running when the daemon exits. With --shutdown-inhibit, the containers are
+stopped when the host shuts down, the shutdown being delayed until then.
+
+The global options (such as --root and --systemd-cgroup) apply to all the
+containers operated on by the daemon.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "listen",
+			Value: "unix:///run/runc.sock",
+			Usage: "the address to serve the API on (only unix sockets are supported)",
+		},
+		cli.BoolFlag{
+			Name:  "no-pivot",
+			Usage: "do not use pivot root to jail processes inside rootfs.  This should be used whenever the rootfs is on top of a ramdisk",
+		},
+		cli.BoolFlag{
+			Name:  "no-new-keyring",
+			Usage: "do not create new session keyrings for the containers.  This will cause the containers to inherit the daemon session key",
+		},
+		cli.BoolFlag{
+			Name:  "strict-spec",
+			Usage: "fail to create the containers if any spec field would be ignored, as it can not be honored on this host (or in the rootless mode)",
+		},
+		cli.BoolFlag{
+			Name:  "allow-keyring",
+			Usage: "allow the org.opencontainers.runc.keyring annotation, which links host keys into the container session keyrings, and may raise the host key quotas",
+		},
+		cli.BoolFlag{
+			Name:  "allow-shm-remount",
+			Usage: "allow the org.opencontainers.runc.shm.policy=remount annotation, which resizes a /dev/shm shared with another container",
+		},
+		cli.BoolFlag{
+			Name:  "shutdown-inhibit",
+			Usage: "delay the host shutdown (using a systemd-logind inhibitor lock) to stop the containers first",
+		},
+		cli.StringFlag{
+			Name:  "stop-signal",
+			Value: "SIGTERM",
+			Usage: "the signal sent to the containers on host shutdown, unless set by their annotations",
+		},
+		cli.DurationFlag{
+			Name:  "stop-grace-period",
+			Value: 10 * time.Second,
+			Usage: "how long the containers are given to stop on host shutdown before being killed, unless set by their annotations",
+		},
+		cli.DurationFlag{
+			Name:  "propagation-watch",
+			Usage: "check at the specified interval that the mount propagation of the shared bind mounts of the containers is working, sending a propagation event when it breaks",
+		},
+		cli.BoolFlag{
+			Name:  "propagation-remediate",
+			Usage: "re-establish the broken mount propagation of the shared bind mounts (see --propagation-watch)",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		l, err := daemonListen(context.String("listen"))
+		if err != nil {
+			return err
+		}
+		d := &daemon{
+			context:    context,
+			containers: make(map[string]*libcontainer.Container),
+			probing:    make(map[*libcontainer.Container]bool),
+		}
+		srv := grpc.NewServer()
+		pb.RegisterRuntimeServer(srv, d)
+
+		if context.Bool("shutdown-inhibit") {
+			sig, err := parseSignal(context.String("stop-signal"))
+			if err != nil {
+				return err
+			}
+			policy := stopPolicy{signal: sig, gracePeriod: context.Duration("stop-grace-period")}
+			inhibitor, err := newShutdownInhibitor()
+			if err != nil {
+				return fmt.Errorf("unable to take the shutdown inhibitor lock: %w", err)
+			}
+			defer inhibitor.Close()
+			go inhibitor.run(func() {
+				logrus.Info("the host is shutting down, stopping the containers")
+				d.stopContainers(policy)
+			})
+		}
+
+		sigc := make(chan os.Signal, 1)
+		signal.Notify(sigc, unix.SIGINT, unix.SIGTERM)
+		go func() {
+			<-sigc
+			srv.Stop()
+		}()
+		logrus.Infof("serving on %s", context.String("listen"))
+		return srv.Serve(l)
+	},
+}
+
+// daemonListen listens on the unix socket at addr, in the
+// "unix:///path/to/socket" format, which is only accessible to the owner.
+func daemonListen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix://")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("invalid listen address %q: only unix:// addresses are supported", addr)
+	}
+	// Remove a stale socket left by a daemon which has exited, but not one
+	// which is in use.
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another daemon", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	oldMask := unix.Umask(0o177)
+	defer unix.Umask(oldMask)
+	return net.Listen("unix", path)
+}
+
+// daemon implements the runc.daemon.v1.Runtime service.
+type daemon struct {
+	pb.UnimplementedRuntimeServer
+
+	context *cli.Context
+
+	mu sync.Mutex
+	// containers are the containers operated on, kept so that the events of
+	// the operations are sent to the subscribers (see Container.Subscribe).
+	containers map[string]*libcontainer.Container
+	// probing are the containers whose probes are run by the daemon (see
+	// startProbes).
+	probing map[*libcontainer.Container]bool
+}
+
+// daemonError returns err with the matching gRPC status code.
+func daemonError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, libcontainer.ErrNotExist):
+		code = codes.NotFound
+	case errors.Is(err, libcontainer.ErrExist):
+		code = codes.AlreadyExists
+	case errors.Is(err, libcontainer.ErrInvalidID), errors.Is(err, errEmptyID):
+		code = codes.InvalidArgument
+	case errors.Is(err, libcontainer.ErrExecLimit):
+		code = codes.ResourceExhausted
+	case errors.Is(err, libcontainer.ErrNotRunning), errors.Is(err, libcontainer.ErrRunning),
+		errors.Is(err, libcontainer.ErrPaused), errors.Is(err, libcontainer.ErrNotPaused):
+		code = codes.FailedPrecondition
+	case errors.Is(err, os.ErrPermission):
+		code = codes.PermissionDenied
+	}
+	return status.Error(code, err.Error())
+}
+
+// statusError returns err with the gRPC status code, or nil if err is nil.
+func statusError(code codes.Code, err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(code, err.Error())
+}
+
+// statusErrorf returns an error with the gRPC status code and the formatted
+// message. As with [fmt.Errorf], the %w verb can be used.
+func statusErrorf(code codes.Code, format string, args ...any) error {
+	return status.Error(code, fmt.Errorf(format, args...).Error())
+}
+
+// daemonStdio are the stdio fields of the create and exec requests.
+type daemonStdio struct {
+	consoleSocket, stdin, stdout, stderr string
+}
+
+// container returns the container id. The same [libcontainer.Container] is
+// returned as long as the container is not replaced by another one with the
+// same ID (e.g. by runc commands).
+func (d *daemon) container(id string) (*libcontainer.Container, error) {
+	if id == "" {
+		return nil, daemonError(errEmptyID)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	loaded, err := libcontainer.Load(d.context.GlobalString("root"), id)
+	if err != nil {
+		delete(d.containers, id)
+		return nil, daemonError(err)
+	}
+	if c, ok := d.containers[id]; ok && sameContainer(c, loaded) {
+		return c, nil
+	}
+	d.containers[id] = loaded
+	return loaded, nil
+}
+
+// sameContainer returns whether a and b are the same container instance.
+func sameContainer(a, b *libcontainer.Container) bool {
+	sa, err := a.State()
+	if err != nil {
+		return false
+	}
+	sb, err := b.State()
+	if err != nil {
+		return false
+	}
+	return sa.Created.Equal(sb.Created) && sa.InitProcessPid == sb.InitProcessPid &&
+		sa.InitProcessStartTime == sb.InitProcessStartTime
+}
+
+func (d *daemon) Create(_ context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	if req.Id == "" {
+		return nil, daemonError(errEmptyID)
+	}
+	if !filepath.IsAbs(req.Bundle) {
+		return nil, statusErrorf(codes.InvalidArgument, "bundle must be an absolute path, got %q", req.Bundle)
+	}
+	bundle := filepath.Clean(req.Bundle)
+	spec, specDigest, err := loadSpecDigest(filepath.Join(bundle, specConfig))
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, err)
+	}
+	if _, _, err := parseDependencies(req.Id, spec.Annotations); err != nil {
+		return nil, statusError(codes.InvalidArgument, err)
+	}
+	container, err := createContainer(d.context, req.Id, bundle, spec, specDigest)
+	if err != nil {
+		return nil, daemonError(err)
+	}
+	stdio := &daemonStdio{consoleSocket: req.ConsoleSocket, stdin: req.Stdin, stdout: req.Stdout, stderr: req.Stderr}
+	process, err := d.startProcess(container, spec.Process, true, stdio)
+	if err != nil {
+		if err := container.Destroy(); err != nil {
+			logrus.Warn(err)
+		}
+		return nil, daemonError(err)
+	}
+	d.mu.Lock()
+	d.containers[req.Id] = container
+	d.mu.Unlock()
+	pid, _ := process.Pid()
+	return &pb.CreateResponse{Pid: int64(pid)}, nil
+}
+
+func (d *daemon) Start(ctx context.Context, req *pb.StartRequest) (*pb.Empty, error) {
+	container, err := d.container(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	status, err := container.Status()
+	if err != nil {
+		return nil, daemonError(err)
+	}
+	switch status {
+	case libcontainer.Created:
+		if err := d.waitDependencies(ctx, container); err != nil {
+			return nil, err
+		}
+		if err := container.Exec(); err != nil {
+			return nil, daemonError(err)
+		}
+		d.startProbes(container)
+		d.startPropagationWatch(container)
+		return &pb.Empty{}, nil
+	case libcontainer.Stopped:
+		return nil, statusErrorf(codes.FailedPrecondition, "cannot start a container that has stopped")
+	case libcontainer.Running:
+		return nil, statusErrorf(codes.FailedPrecondition, "cannot start an already running container")
+	default:
+		return nil, statusErrorf(codes.FailedPrecondition, "cannot start a container in the %s state", status)
+	}
+}
+
+func (d *daemon) Exec(_ context.Context, req *pb.ExecRequest) (*pb.ExecResponse, error) {
+	container, err := d.container(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	status, err := container.Status()
+	if err != nil {
+		return nil, daemonError(err)
+	}
+	if status == libcontainer.Stopped || status == libcontainer.Paused {
+		return nil, statusErrorf(codes.FailedPrecondition, "cannot exec in a %s container", status)
+	}
+	p, err := specjson.DecodeProcess(bytes.NewReader(req.Process))
+	if err == nil {
+		err = validateProcessSpec(p)
+	}
+	if err != nil {
+		return nil, statusErrorf(codes.InvalidArgument, "invalid process: %w", err)
+	}
+	stdio := &daemonStdio{consoleSocket: req.ConsoleSocket, stdin: req.Stdin, stdout: req.Stdout, stderr: req.Stderr}
+	process, err := d.startProcess(container, p, false, stdio)
+	if err != nil {
+		return nil, daemonError(err)
+	}
+	pid, _ := process.Pid()
+	return &pb.ExecResponse{Pid: int64(pid)}, nil
+}
+
+// startProcess starts the container init (if init is set) or an exec
+// process, with the stdio of the request, and reaps it in the background.
+func (d *daemon) startProcess(container *libcontainer.Container, p *specs.Process, init bool, stdio *daemonStdio) (*libcontainer.Process, error) {
+	if p.Terminal && stdio.consoleSocket == "" {
+		return nil, statusErrorf(codes.InvalidArgument, "a console socket is required for a process with a terminal")
+	}
+	if !p.Terminal && stdio.consoleSocket != "" {
+		return nil, statusErrorf(codes.InvalidArgument, "a console socket can only be used for a process with a terminal")
+	}
+	if stdio.consoleSocket != "" {
+		if err := checkConsoleSocket(stdio.consoleSocket, true); err != nil {
+			return nil, statusError(codes.InvalidArgument, err)
+		}
+	}
+	process, err := newProcess(p)
+	if err != nil {
+		return nil, statusError(codes.InvalidArgument, err)
+	}
+	process.LogLevel = strconv.Itoa(int(logrus.GetLevel()))
+	process.CorrelationID = logCorrelationID
+	process.Init = init
+	if p.Terminal {
+		t, err := setupIO(process, container, true, true, stdio.consoleSocket, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer t.Close()
+	} else {
+		files, err := openStdio(stdio)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			for _, f := range files {
+				f.Close()
+			}
+		}()
+		process.Stdin, process.Stdout, process.Stderr = files[0], files[1], files[2]
+	}
+	if err := container.Start(process); err != nil {
+		return nil, err
+	}
+	go reapProcess(container, process)
+	return process, nil
+}
+
+// openStdio opens the stdio of the request, which defaults to /dev/null.
+func openStdio(stdio *daemonStdio) ([]*os.File, error) {
+	var files []*os.File
+	for i, path := range []string{stdio.stdin, stdio.stdout, stdio.stderr} {
+		flag := os.O_WRONLY | os.O_APPEND | os.O_CREATE
+		if i == 0 {
+			flag = os.O_RDONLY
+		}
+		if path == "" {
+			path = os.DevNull
+		}
+		f, err := os.OpenFile(path, flag|unix.O_CLOEXEC, 0o600)
+		if err != nil {
+			for _, f := range files {
+				f.Close()
+			}
+			return nil, statusError(codes.InvalidArgument, err)
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// reapProcess waits for the process to exit, as its parent, and records the
+// exit of an exec process.
+func reapProcess(container *libcontainer.Container, p *libcontainer.Process) {
+	state, _ := p.Wait()
+	if state == nil || p.Init {
+		return
+	}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok {
+		container.ExecExited(p, utils.ExitStatus(unix.WaitStatus(ws)))
+	}
+}
+
+func (d *daemon) Kill(_ context.Context, req *pb.KillRequest) (*pb.Empty, error) {
+	container, err := d.container(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	sig := unix.SIGTERM
+	if req.Signal != "" {
+		if sig, err = parseSignal(req.Signal); err != nil {
+			return nil, statusError(codes.InvalidArgument, err)
+		}
+	}
+	if err := container.Signal(sig); err != nil {
+		return nil, daemonError(err)
+	}
+	return &pb.Empty{}, nil
+}
+
+func (d *daemon) Delete(_ context.Context, req *pb.DeleteRequest) (*pb.Empty, error) {
+	container, err := d.container(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Force {
+		if s, err := container.Status(); err == nil && (s == libcontainer.Running || s == libcontainer.Paused) {
+			return nil, statusErrorf(codes.FailedPrecondition, "cannot delete container %s that is not stopped: %s", req.Id, s)
+		}
+	}
+	if err := deleteContainer(container, req.Force); err != nil {
+		return nil, daemonError(err)
+	}
+	d.mu.Lock()
+	delete(d.containers, req.Id)
+	d.mu.Unlock()
+	return &pb.Empty{}, nil
+}
+
+func (d *daemon) Events(req *pb.EventsRequest, stream grpc.ServerStreamingServer[pb.Event]) error {
+	container, err := d.container(req.Id)
+	if err != nil {
+		return err
+	}
+	status, err := container.Status()
+	if err != nil {
+		return daemonError(err)
+	}
+	if status == libcontainer.Stopped {
+		return statusErrorf(codes.FailedPrecondition, "container with id %s is not running", container.ID())
+	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	for e := range container.Subscribe(ctx) {
+		ev := &pb.Event{
+			Type:         string(e.Type),
+			Id:           container.ID(),
+			Pid:          int64(e.Pid),
+			TimeUnixNano: e.Time.UnixNano(),
+			Hook:         string(e.Hook),
+		}
+		if e.Err != nil {
+			ev.Error = e.Err.Error()
+		}
+		if p := e.Propagation; p != nil {
+			ev.Propagation, _ = json.Marshal(convertPropagation(p))
+		}
+		if x := e.Exec; x != nil {
+			ev.Exec, _ = json.Marshal(&types.Exec{
+				Time:     x.Time,
+				Pid:      x.Pid,
+				Args:     x.Args,
+				User:     x.User,
+				ExitCode: x.ExitCode,
+				Duration: x.Duration,
+			})
+		}
+		if err := stream.Send(ev); err != nil {
+			return err
+		}
+		if e.Type == libcontainer.EventExited {
+			break
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+// dependencyCondition is when a dependency of a container is met.
+type dependencyCondition string
+
+const (
+	// dependsOnStarted is met once the dependency is running.
+	dependsOnStarted dependencyCondition = "started"
+	// dependsOnReady is met once the dependency is running, and its
+	// readiness probes (if any) are passing.
+	dependsOnReady dependencyCondition = "ready"
+)
+
+// defaultDependencyTimeout is how long the dependencies are waited for,
+// unless set by [specconv.AnnotationDependsOnTimeout].
+const defaultDependencyTimeout = 5 * time.Minute
+
+// dependencyPollInterval is how often the dependencies are checked.
+const dependencyPollInterval = 100 * time.Millisecond
+
+// dependency is a container which must be started (or ready) before the
+// container depending on it is started by runc daemon.
+type dependency struct {
+	id        string
+	condition dependencyCondition
+}
+
+func (d dependency) String() string {
+	return d.id + ":" + string(d.condition)
+}
+
+// parseDependencies returns the dependencies of the container id, and how
+// long those are waited for, from its annotations (see
+// [specconv.AnnotationDependsOn]).
+func parseDependencies(id string, annotations map[string]string) ([]dependency, time.Duration, error) {
+	v, ok := annotations[specconv.AnnotationDependsOn]
+	if !ok {
+		return nil, 0, nil
+	}
+	var deps []dependency
+	for _, entry := range strings.Split(v, ",") {
+		depID, cond, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			cond = string(dependsOnReady)
+		}
+		switch {
+		case depID == "":
+			return nil, 0, fmt.Errorf("invalid dependency %q", entry)
+		case depID == id:
+			return nil, 0, fmt.Errorf("container %s can't depend on itself", id)
+		case cond != string(dependsOnStarted) && cond != string(dependsOnReady):
+			return nil, 0, fmt.Errorf("invalid dependency %q: unknown condition %q", entry, cond)
+		}
+		deps = append(deps, dependency{id: depID, condition: dependencyCondition(cond)})
+	}
+	timeout := defaultDependencyTimeout
+	if v, ok := annotations[specconv.AnnotationDependsOnTimeout]; ok {
+		var err error
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			return nil, 0, fmt.Errorf("invalid dependency timeout %q", v)
+		}
+	}
+	return deps, timeout, nil
+}
+
+// containerDependencies returns the dependencies of container, and how long
+// those are waited for.
+func containerDependencies(container *libcontainer.Container) ([]dependency, time.Duration, error) {
+	return parseDependencies(container.ID(), container.Annotations())
+}
+
+// findDependencyCycle returns the dependency cycle of the container id, such
+// as ["a", "b", "a"], if any. depsOf returns the IDs of the dependencies of a
+// container, which may not exist yet.
+func findDependencyCycle(id string, depsOf func(id string) []string) []string {
+	visited := make(map[string]bool)
+	var visit func(path []string) []string
+	visit = func(path []string) []string {
+		cur := path[len(path)-1]
+		for _, dep := range depsOf(cur) {
+			if dep == id {
+				return append(path, dep)
+			}
+			if visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if cycle := visit(append(path, dep)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return visit([]string{id})
+}
+
+// errDependencyFailed is returned when a dependency can no longer be met.
+var errDependencyFailed = errors.New("dependency failed")
+
+// checkDependency returns why dep is not met yet (or an empty string if it
+// is), or an error wrapping errDependencyFailed if it can no longer be met.
+func (d *daemon) checkDependency(dep dependency) (string, error) {
+	container, err := d.container(dep.id)
+	if err != nil {
+		if errors.Is(err, libcontainer.ErrNotExist) {
+			return dep.id + " is not created", nil
+		}
+		return "", err
+	}
+	status, err := container.Status()
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case libcontainer.Stopped:
+		return "", fmt.Errorf("%w: %s has stopped", errDependencyFailed, dep.id)
+	case libcontainer.Created:
+		return dep.id + " is not started", nil
+	}
+	if dep.condition == dependsOnStarted {
+		return "", nil
+	}
+	var readiness int
+	for _, p := range container.Config().Probes {
+		if p.Type == configs.ProbeReadiness {
+			readiness++
+		}
+	}
+	if readiness == 0 {
+		return "", nil
+	}
+	states, err := container.Probes()
+	if err != nil {
+		return "", err
+	}
+	if states == nil {
+		return dep.id + " readiness probes are not run", nil
+	}
+	for _, st := range states {
+		if st.Type != configs.ProbeReadiness {
+			continue
+		}
+		switch st.Status {
+		case libcontainer.ProbeFailing:
+			return "", fmt.Errorf("%w: %s readiness probe %v is failing", errDependencyFailed, dep.id, st.Args)
+		case libcontainer.ProbeUnknown:
+			return dep.id + " is not ready", nil
+		}
+	}
+	return "", nil
+}
+
+// waitDependencies waits for the dependencies of container to be met, for
+// up to the dependency timeout.
+func (d *daemon) waitDependencies(ctx context.Context, container *libcontainer.Container) error {
+	deps, timeout, err := containerDependencies(container)
+	if err != nil {
+		return statusError(codes.InvalidArgument, err)
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+	cycle := findDependencyCycle(container.ID(), func(id string) []string {
+		if id == container.ID() {
+			return dependencyIDs(deps)
+		}
+		c, err := d.container(id)
+		if err != nil {
+			return nil
+		}
+		deps, _, _ := containerDependencies(c)
+		return dependencyIDs(deps)
+	})
+	if cycle != nil {
+		return statusErrorf(codes.FailedPrecondition, "container %s: dependency cycle %s", container.ID(), strings.Join(cycle, " -> "))
+	}
+
+	logrus.Debugf("container %s: waiting for dependencies %v", container.ID(), deps)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+	for {
+		var pending []string
+		for _, dep := range deps {
+			reason, err := d.checkDependency(dep)
+			if err != nil {
+				logrus.Warnf("container %s: %v", container.ID(), err)
+				if errors.Is(err, errDependencyFailed) {
+					return statusErrorf(codes.FailedPrecondition, "container %s: %w", container.ID(), err)
+				}
+				return err
+			}
+			if reason != "" {
+				pending = append(pending, reason)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return statusErrorf(codes.DeadlineExceeded, "container %s: timed out waiting for dependencies: %s", container.ID(), strings.Join(pending, ", "))
+			}
+			return statusError(codes.Canceled, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func dependencyIDs(deps []dependency) []string {
+	ids := make([]string, len(deps))
+	for i, dep := range deps {
+		ids[i] = dep.id
+	}
+	return ids
+}
+
+// startProbes runs the probes of container in the background, until it
+// exits, unless those are already run by the daemon.
+func (d *daemon) startProbes(container *libcontainer.Container) {
+	if len(container.Config().Probes) == 0 {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.probing[container] {
+		return
+	}
+	d.probing[container] = true
+	go func() {
+		if err := runProbes(context.Background(), container); err != nil {
+			logrus.Warnf("container %s: unable to run probes: %v", container.ID(), err)
+		}
+		d.mu.Lock()
+		delete(d.probing, container)
+		d.mu.Unlock()
+	}()
+}
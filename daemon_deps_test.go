@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+func TestParseDependencies(t *testing.T) {
+	deps, timeout, err := parseDependencies("app", map[string]string{
+		specconv.AnnotationDependsOn:        "db, proxy:started,cache:ready",
+		specconv.AnnotationDependsOnTimeout: "30s",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []dependency{
+		{id: "db", condition: dependsOnReady},
+		{id: "proxy", condition: dependsOnStarted},
+		{id: "cache", condition: dependsOnReady},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("expected %v, got %v", expected, deps)
+	}
+	if timeout != 30*time.Second {
+		t.Errorf("expected a 30s timeout, got %v", timeout)
+	}
+
+	deps, timeout, err = parseDependencies("app", nil)
+	if err != nil || deps != nil || timeout != 0 {
+		t.Errorf("expected no dependencies, got %v, %v, %v", deps, timeout, err)
+	}
+
+	for _, annotations := range []map[string]string{
+		{specconv.AnnotationDependsOn: ""},
+		{specconv.AnnotationDependsOn: "db,"},
+		{specconv.AnnotationDependsOn: "app"},
+		{specconv.AnnotationDependsOn: "db:healthy"},
+		{specconv.AnnotationDependsOn: "db", specconv.AnnotationDependsOnTimeout: "soon"},
+		{specconv.AnnotationDependsOn: "db", specconv.AnnotationDependsOnTimeout: "-1s"},
+	} {
+		if _, _, err := parseDependencies("app", annotations); err == nil {
+			t.Errorf("%v: expected error, got nil", annotations)
+		}
+	}
+}
+
+func TestFindDependencyCycle(t *testing.T) {
+	graph := map[string][]string{
+		"a": {"b", "c"},
+		"b": {"d"},
+		"c": {"d", "e"},
+		"e": {"a"},
+	}
+	depsOf := func(id string) []string { return graph[id] }
+	if cycle := findDependencyCycle("a", depsOf); !slices.Equal(cycle, []string{"a", "c", "e", "a"}) {
+		t.Errorf("expected the a -> c -> e -> a cycle, got %v", cycle)
+	}
+	if cycle := findDependencyCycle("b", depsOf); cycle != nil {
+		t.Errorf("expected no cycle, got %v", cycle)
+	}
+	delete(graph, "e")
+	if cycle := findDependencyCycle("a", depsOf); cycle != nil {
+		t.Errorf("expected no cycle, got %v", cycle)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/types"
+)
+
+// startPropagationWatch watches the mount propagation of the shared bind
+// mounts of container in the background (see
+// [libcontainer.Container.WatchPropagation]), until it exits, if enabled by
+// the --propagation-watch option.
+func (d *daemon) startPropagationWatch(container *libcontainer.Container) {
+	interval := d.context.Duration("propagation-watch")
+	if interval <= 0 {
+		return
+	}
+	remediate := d.context.Bool("propagation-remediate")
+	go func() {
+		if err := container.WatchPropagation(context.Background(), interval, remediate); err != nil {
+			logrus.Warnf("container %s: unable to watch mount propagation: %v", container.ID(), err)
+		}
+	}()
+}
+
+func convertPropagation(p *libcontainer.PropagationState) *types.Propagation {
+	t := &types.Propagation{
+		Destination: p.Destination,
+		Source:      p.Source,
+		Broken:      p.Broken,
+		Reason:      p.Reason,
+		Remediated:  p.Remediated,
+	}
+	if p.Err != nil {
+		t.Error = p.Err.Error()
+	}
+	return t
+}
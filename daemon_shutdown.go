@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	dbus "github.com/godbus/dbus/v5"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+// The systemd-logind D-Bus API.
+const (
+	logindDest    = "org.freedesktop.login1"
+	logindPath    = dbus.ObjectPath("/org/freedesktop/login1")
+	logindManager = "org.freedesktop.login1.Manager"
+)
+
+// stopPollInterval is how often a container being stopped is checked.
+const stopPollInterval = 100 * time.Millisecond
+
+// stopKillTimeout is how long a container is waited for once killed.
+const stopKillTimeout = 10 * time.Second
+
+// stopPolicy is how a container is stopped by runc daemon when the host
+// shuts down.
+type stopPolicy struct {
+	signal      unix.Signal
+	gracePeriod time.Duration
+}
+
+// parseStopPolicy returns the stop policy of a container, from its
+// annotations (see [specconv.AnnotationStopSignal] and
+// [specconv.AnnotationStopGracePeriod]), or else from def.
+func parseStopPolicy(def stopPolicy, annotations map[string]string) (stopPolicy, error) {
+	policy := def
+	if v, ok := annotations[specconv.AnnotationStopSignal]; ok {
+		sig, err := parseSignal(v)
+		if err != nil {
+			return def, fmt.Errorf("invalid stop signal: %w", err)
+		}
+		policy.signal = sig
+	}
+	if v, ok := annotations[specconv.AnnotationStopGracePeriod]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return def, fmt.Errorf("invalid stop grace period %q", v)
+		}
+		policy.gracePeriod = d
+	}
+	return policy, nil
+}
+
+// stopContainers stops the containers in the root directory, concurrently.
+// Each container is sent the signal of its stop policy, and is killed if
+// it is still running at the end of its grace period. The containers are
+// not deleted.
+func (d *daemon) stopContainers(def stopPolicy) {
+	entries, err := os.ReadDir(d.context.GlobalString("root"))
+	if err != nil {
+		logrus.WithError(err).Warn("unable to list the containers to stop")
+		return
+	}
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		container, err := d.container(e.Name())
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := stopContainer(container, def); err != nil {
+				logrus.WithError(err).Warnf("container %s: unable to stop", container.ID())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// stopContainer stops container according to its stop policy, or def.
+func stopContainer(container *libcontainer.Container, def stopPolicy) error {
+	status, err := container.Status()
+	if err != nil {
+		return err
+	}
+	switch status {
+	case libcontainer.Stopped:
+		return nil
+	case libcontainer.Paused:
+		// The signal would not be handled until the container is resumed.
+		if err := container.Resume(); err != nil {
+			return err
+		}
+	}
+	policy, err := parseStopPolicy(def, container.Annotations())
+	if err != nil {
+		logrus.WithError(err).Warnf("container %s: using the default stop policy", container.ID())
+	}
+	logrus.Infof("container %s: stopping with %s (grace period %s)", container.ID(), unix.SignalName(policy.signal), policy.gracePeriod)
+	if err := container.Signal(policy.signal); err != nil {
+		if errors.Is(err, libcontainer.ErrNotRunning) {
+			return nil
+		}
+		return err
+	}
+	if waitStopped(container, policy.gracePeriod) {
+		return nil
+	}
+	logrus.Warnf("container %s: still running after %s, killing it", container.ID(), policy.gracePeriod)
+	if err := container.Signal(unix.SIGKILL); err != nil && !errors.Is(err, libcontainer.ErrNotRunning) {
+		return err
+	}
+	if !waitStopped(container, stopKillTimeout) {
+		return errors.New("container init still running")
+	}
+	return nil
+}
+
+// waitStopped waits for the container init to exit, for up to timeout, and
+// returns whether it did.
+func waitStopped(container *libcontainer.Container, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := container.Signal(unix.Signal(0)); err != nil {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(stopPollInterval)
+	}
+}
+
+// shutdownInhibitor holds a systemd-logind "delay" inhibitor lock for the
+// host shutdown, so that the containers can be stopped before it.
+type shutdownInhibitor struct {
+	conn *dbus.Conn
+	// prepare receives the PrepareForShutdown signals of systemd-logind.
+	prepare chan *dbus.Signal
+
+	mu   sync.Mutex
+	lock *os.File
+}
+
+// newShutdownInhibitor takes the inhibitor lock.
+func newShutdownInhibitor() (*shutdownInhibitor, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindManager),
+		dbus.WithMatchMember("PrepareForShutdown"),
+	); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	i := &shutdownInhibitor{conn: conn, prepare: make(chan *dbus.Signal, 1)}
+	conn.Signal(i.prepare)
+	if err := i.acquire(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return i, nil
+}
+
+// acquire takes the inhibitor lock, unless it is held.
+func (i *shutdownInhibitor) acquire() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.lock != nil {
+		return nil
+	}
+	var fd dbus.UnixFD
+	err := i.conn.Object(logindDest, logindPath).Call(logindManager+".Inhibit", 0,
+		"shutdown", "runc", "Stopping the containers", "delay").Store(&fd)
+	if err != nil {
+		return err
+	}
+	i.lock = os.NewFile(uintptr(fd), "inhibitor-lock")
+	return nil
+}
+
+// release releases the inhibitor lock, letting the shutdown proceed.
+func (i *shutdownInhibitor) release() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.lock != nil {
+		i.lock.Close()
+		i.lock = nil
+	}
+}
+
+// run calls stop when the host is about to shut down, and then releases the
+// inhibitor lock. The lock is taken again if the shutdown is cancelled. It
+// returns once the inhibitor is closed.
+func (i *shutdownInhibitor) run(stop func()) {
+	for sig := range i.prepare {
+		if len(sig.Body) != 1 {
+			continue
+		}
+		start, ok := sig.Body[0].(bool)
+		if !ok {
+			continue
+		}
+		if start {
+			stop()
+			i.release()
+		} else if err := i.acquire(); err != nil {
+			logrus.WithError(err).Warn("unable to take the shutdown inhibitor lock again")
+		}
+	}
+}
+
+// Close releases the inhibitor lock, if held, and stops receiving the
+// shutdown signals.
+func (i *shutdownInhibitor) Close() {
+	i.release()
+	i.conn.Close()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+func TestParseStopPolicy(t *testing.T) {
+	def := stopPolicy{signal: unix.SIGTERM, gracePeriod: 10 * time.Second}
+
+	policy, err := parseStopPolicy(def, nil)
+	if err != nil || policy != def {
+		t.Errorf("expected the default policy, got %+v, %v", policy, err)
+	}
+
+	policy, err = parseStopPolicy(def, map[string]string{
+		specconv.AnnotationStopSignal:      "int",
+		specconv.AnnotationStopGracePeriod: "1m",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (stopPolicy{signal: unix.SIGINT, gracePeriod: time.Minute}); policy != expected {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+
+	for _, annotations := range []map[string]string{
+		{specconv.AnnotationStopSignal: "SIGNOPE"},
+		{specconv.AnnotationStopGracePeriod: "soon"},
+		{specconv.AnnotationStopGracePeriod: "-1s"},
+	} {
+		policy, err := parseStopPolicy(def, annotations)
+		if err == nil {
+			t.Errorf("%v: expected an error", annotations)
+		}
+		if policy != def {
+			t.Errorf("%v: expected the default policy, got %+v", annotations, policy)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/opencontainers/runc/libcontainer"
+	pb "github.com/opencontainers/runc/types/daemon"
+)
+
+// newDaemonClient serves a daemon with a new root directory on a unix
+// socket, and returns a client for it.
+func newDaemonClient(t *testing.T) pb.RuntimeClient {
+	t.Helper()
+	dir := t.TempDir()
+	globalSet := flag.NewFlagSet("runc", flag.ContinueOnError)
+	globalSet.String("root", filepath.Join(dir, "root"), "")
+	app := cli.NewApp()
+	d := &daemon{
+		context:    cli.NewContext(app, flag.NewFlagSet("daemon", flag.ContinueOnError), cli.NewContext(app, globalSet, nil)),
+		containers: make(map[string]*libcontainer.Container),
+		probing:    make(map[*libcontainer.Container]bool),
+	}
+	l, err := daemonListen("unix://" + filepath.Join(dir, "runc.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterRuntimeServer(srv, d)
+	go srv.Serve(l) //nolint:errcheck
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("unix://"+l.Addr().(*net.UnixAddr).Name, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewRuntimeClient(conn)
+}
+
+func TestDaemonErrors(t *testing.T) {
+	c := newDaemonClient(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{"create with no id", func() error {
+			_, err := c.Create(ctx, &pb.CreateRequest{Bundle: "/bundle"})
+			return err
+		}, codes.InvalidArgument},
+		{"create with a relative bundle", func() error {
+			_, err := c.Create(ctx, &pb.CreateRequest{Id: "ctr", Bundle: "bundle"})
+			return err
+		}, codes.InvalidArgument},
+		{"start of an unknown container", func() error {
+			_, err := c.Start(ctx, &pb.StartRequest{Id: "ctr"})
+			return err
+		}, codes.NotFound},
+		{"kill with an invalid id", func() error {
+			_, err := c.Kill(ctx, &pb.KillRequest{Id: "../ctr"})
+			return err
+		}, codes.InvalidArgument},
+		{"events of an unknown container", func() error {
+			stream, err := c.Events(ctx, &pb.EventsRequest{Id: "ctr"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		}, codes.NotFound},
+	} {
+		err := tc.call()
+		if code := status.Code(err); code != tc.code {
+			t.Errorf("%s: expected code %s, got %v", tc.name, tc.code, err)
+		}
+	}
+}
@@ -42,6 +42,10 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 			Name:  "force, f",
 			Usage: "Forcibly deletes the container if it is still running (uses SIGKILL)",
 		},
+		cli.BoolFlag{
+			Name:  "remove-netns",
+			Usage: "also remove the network namespace kept for the container (see the org.opencontainers.runc.netns.keep annotation)",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -50,6 +54,12 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 
 		id := context.Args().First()
 		force := context.Bool("force")
+		removeNetns := func() error {
+			if !context.Bool("remove-netns") {
+				return nil
+			}
+			return libcontainer.RemoveKeptNetns(context.GlobalString("root"), id)
+		}
 		container, err := getContainer(context)
 		if err != nil {
 			if errors.Is(err, libcontainer.ErrNotExist) {
@@ -60,30 +70,42 @@ status of "ubuntu01" as "stopped" the following will delete resources held for
 					fmt.Fprintf(os.Stderr, "remove %s: %v\n", path, e)
 				}
 				if force {
-					return nil
+					return removeNetns()
 				}
 			}
 			return err
 		}
-		// When --force is given, we kill all container processes and
-		// then destroy the container. This is done even for a stopped
-		// container, because (in case it does not have its own PID
-		// namespace) there may be some leftover processes in the
-		// container's cgroup.
-		if force {
-			return killContainer(container)
-		}
-		s, err := container.Status()
-		if err != nil {
+		if err := deleteContainer(container, force); err != nil {
 			return err
 		}
-		switch s {
-		case libcontainer.Stopped:
-			return container.Destroy()
-		case libcontainer.Created:
-			return killContainer(container)
-		default:
-			return fmt.Errorf("cannot delete container %s that is not stopped: %s", id, s)
-		}
+		return removeNetns()
 	},
 }
+
+// deleteContainer deletes the container, which must be stopped unless force
+// is set.
+func deleteContainer(container *libcontainer.Container, force bool) error {
+	// When --force is given, we kill all container processes and
+	// then destroy the container. This is done even for a stopped
+	// container, because (in case it does not have its own PID
+	// namespace) there may be some leftover processes in the
+	// container's cgroup.
+	s, err := container.Status()
+	if err != nil && !force {
+		return err
+	}
+	// A container which was created but not started is aborted,
+	// which also works if its init is stuck.
+	if s == libcontainer.Created {
+		return container.Abort()
+	}
+	if force {
+		return killContainer(container)
+	}
+	switch s {
+	case libcontainer.Stopped:
+		return container.Destroy()
+	default:
+		return fmt.Errorf("cannot delete container %s that is not stopped: %s", container.ID(), s)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
 	"time"
 
@@ -24,23 +25,59 @@ var eventsCommand = cli.Command{
 
 Where "<container-id>" is the name for the instance of the container.`,
 	Description: `The events command displays information about the container. By default the
-information is displayed once every 5 seconds.`,
+information is displayed once every 5 seconds.
+
+With --all, only the stats of all the running containers are displayed, and no
+container id is given.
+
+With --stats-backend bpf, the CPU, memory, and pids usage of the containers is
+collected in one pass with a BPF cgroup iterator, rather than by reading the
+cgroup files of every container. The other stats are not reported. This
+requires cgroup v2, and a kernel with BPF cgroup iterators (Linux 6.1 or later),
+otherwise the cgroup files are used.`,
 	Flags: []cli.Flag{
 		cli.DurationFlag{Name: "interval", Value: 5 * time.Second, Usage: "set the stats collection interval"},
 		cli.BoolFlag{Name: "stats", Usage: "display the container's stats then exit"},
+		cli.StringFlag{Name: "format", Value: "json", Usage: "select one of: json or prometheus"},
+		cli.StringFlag{Name: "listen", Usage: "serve the stats in the prometheus format over HTTP on the specified address"},
+		cli.StringSliceFlag{Name: "psi-trigger", Usage: "notify when a pressure stall threshold is crossed, specified as resource:some|full:stall/window (e.g. memory:some:150ms/1s)"},
+		cli.StringFlag{Name: "memory-events", Usage: "notify of the cgroup v2 memory events of either the container cgroup subtree (hierarchical) or the container cgroup only (local)"},
+		cli.BoolFlag{Name: "annotations", Usage: "tag the events with the container annotations (json format only)"},
+		cli.StringFlag{Name: "stats-backend", Value: "fs", Usage: "collect the stats from the cgroup files (fs), or with a BPF cgroup iterator (bpf)"},
+		cli.BoolFlag{Name: "all", Usage: "display the stats of all the running containers"},
 	},
 	Action: func(context *cli.Context) error {
-		if err := checkArgs(context, 1, exactArgs); err != nil {
-			return err
+		nargs := 1
+		if context.Bool("all") {
+			nargs = 0
 		}
-		container, err := getContainer(context)
-		if err != nil {
+		if err := checkArgs(context, nargs, exactArgs); err != nil {
 			return err
 		}
 		duration := context.Duration("interval")
 		if duration <= 0 {
 			return errors.New("duration interval must be greater than 0")
 		}
+		backend, err := newStatsBackend(context.String("stats-backend"))
+		if err != nil {
+			return err
+		}
+		defer backend.close()
+		if context.Bool("all") {
+			for _, flag := range []string{"listen", "psi-trigger", "memory-events"} {
+				if context.IsSet(flag) {
+					return fmt.Errorf("--%s can't be used with --all", flag)
+				}
+			}
+			if context.String("format") != "json" {
+				return errors.New("--all can only be used with the json format")
+			}
+			return allStatsEvents(context, backend)
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
 		status, err := container.Status()
 		if err != nil {
 			return err
@@ -48,24 +85,85 @@ information is displayed once every 5 seconds.`,
 		if status == libcontainer.Stopped {
 			return fmt.Errorf("container with id %s is not running", container.ID())
 		}
+		format := context.String("format")
+		switch format {
+		case "json", "prometheus":
+		default:
+			return fmt.Errorf("invalid format: %q", format)
+		}
+		var triggers []*libcontainer.PSITrigger
+		for _, t := range context.StringSlice("psi-trigger") {
+			trigger, err := libcontainer.ParsePSITrigger(t)
+			if err != nil {
+				return err
+			}
+			triggers = append(triggers, trigger)
+		}
+		memoryEvents := context.String("memory-events")
+		switch memoryEvents {
+		case "", "hierarchical", "local":
+		default:
+			return fmt.Errorf("invalid --memory-events value: %q", memoryEvents)
+		}
+		if addr := context.String("listen"); addr != "" {
+			for _, flag := range []string{"stats", "psi-trigger", "memory-events", "annotations"} {
+				if context.IsSet(flag) {
+					return fmt.Errorf("--%s can't be used with --listen", flag)
+				}
+			}
+			if format != "prometheus" && context.IsSet("format") {
+				return errors.New("--listen can only be used with the prometheus format")
+			}
+			return serveMetrics(container, backend, addr)
+		}
 		var (
 			stats  = make(chan *libcontainer.Stats, 1)
+			drift  = make(chan []string, 1)
+			probes = make(chan []libcontainer.ProbeState, 1)
 			events = make(chan *types.Event, 1024)
 			group  = &sync.WaitGroup{}
+			// missing is the list of missing cgroup controllers
+			// reported by the last drift event.
+			missing []string
+			// probeStates is the probe states of the last check.
+			probeStates []libcontainer.ProbeState
 		)
+		var annotations map[string]string
+		if context.Bool("annotations") {
+			annotations = container.Annotations()
+		}
 		group.Add(1)
 		go func() {
 			defer group.Done()
 			enc := json.NewEncoder(os.Stdout)
+			var ooms uint64
 			for e := range events {
+				if format == "prometheus" {
+					// Only stats are printed; OOM events are counted,
+					// and the other events are ignored.
+					switch e.Type {
+					case "oom":
+						ooms++
+					case "stats":
+						s, _ := e.Data.(*types.Stats)
+						if err := writePrometheus(os.Stdout, e.ID, s, ooms); err != nil {
+							logrus.Error(err)
+						}
+					}
+					continue
+				}
+				e.Annotations = annotations
 				if err := enc.Encode(e); err != nil {
 					logrus.Error(err)
 				}
 			}
 		}()
 		if context.Bool("stats") {
-			s, err := container.Stats()
-			if err != nil {
+			s, err := backend.containerStats(container)
+			var missingErr *libcontainer.ControllerMissingError
+			if errors.As(err, &missingErr) {
+				events <- &types.Event{Type: "drift", ID: container.ID(), Data: &types.Drift{Controllers: missingErr.Controllers}}
+			} else if err != nil {
 				return err
 			}
 			events <- &types.Event{Type: "stats", ID: container.ID(), Data: convertLibcontainerStats(s)}
@@ -75,18 +173,56 @@ information is displayed once every 5 seconds.`,
 		}
 		go func() {
 			for range time.Tick(context.Duration("interval")) {
-				s, err := container.Stats()
-				if err != nil {
+				s, err := backend.containerStats(container)
+				var missingErr *libcontainer.ControllerMissingError
+				if errors.As(err, &missingErr) {
+					drift <- missingErr.Controllers
+				} else if err != nil {
 					logrus.Error(err)
 					continue
+				} else {
+					drift <- []string{}
 				}
 				stats <- s
 			}
 		}()
+		go func() {
+			for range time.Tick(context.Duration("interval")) {
+				p, err := container.Probes()
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				probes <- p
+			}
+		}()
 		n, err := container.NotifyOOM()
 		if err != nil {
 			return err
 		}
+		execs, err := container.NotifyExec()
+		if err != nil {
+			return err
+		}
+		psi := make(chan *libcontainer.PSITrigger)
+		for _, trigger := range triggers {
+			ch, err := container.NotifyPSI(trigger)
+			if err != nil {
+				return fmt.Errorf("unable to set up PSI trigger %s: %w", trigger, err)
+			}
+			go func() {
+				for range ch {
+					psi <- trigger
+				}
+			}()
+		}
+		var memEvents <-chan libcontainer.MemoryEventsNotification
+		if memoryEvents != "" {
+			memEvents, err = container.NotifyMemoryEvents(memoryEvents == "local")
+			if err != nil {
+				return fmt.Errorf("unable to watch memory events: %w", err)
+			}
+		}
 		for {
 			select {
 			case _, ok := <-n:
@@ -98,8 +234,51 @@ information is displayed once every 5 seconds.`,
 				} else {
 					n = nil
 				}
+			case c := <-drift:
+				// Only report changes.
+				if !slices.Equal(c, missing) {
+					events <- &types.Event{Type: "drift", ID: container.ID(), Data: &types.Drift{Controllers: c}}
+					missing = c
+				}
+			case m, ok := <-memEvents:
+				if !ok {
+					memEvents = nil
+					continue
+				}
+				events <- &types.Event{Type: "memory-events", ID: container.ID(), Data: &types.MemoryEvents{
+					Local:    memoryEvents == "local",
+					Counters: types.MemoryEventsCounters(m.Counters),
+					Delta:    types.MemoryEventsCounters(m.Delta),
+				}}
+			case t := <-psi:
+				events <- &types.Event{Type: "psi", ID: container.ID(), Data: &types.PSITrigger{Trigger: t.String()}}
+			case e := <-execs:
+				events <- &types.Event{Type: string(e.Type), ID: container.ID(), Data: &types.Exec{
+					Time:     e.Time,
+					Pid:      e.Pid,
+					Args:     e.Args,
+					User:     e.User,
+					ExitCode: e.ExitCode,
+					Duration: e.Duration,
+				}}
 			case s := <-stats:
 				events <- &types.Event{Type: "stats", ID: container.ID(), Data: convertLibcontainerStats(s)}
+			case p := <-probes:
+				// Only report the probe status changes.
+				for i, st := range p {
+					if i < len(probeStates) && probeStates[i].Status == st.Status {
+						continue
+					}
+					events <- &types.Event{Type: "probe", ID: container.ID(), Data: &types.Probe{
+						Type:     string(st.Type),
+						Args:     st.Args,
+						Status:   string(st.Status),
+						Failures: st.Failures,
+						ExitCode: st.ExitCode,
+						Error:    st.Error,
+					}}
+				}
+				probeStates = p
 			}
 			if n == nil {
 				close(events)
@@ -140,6 +319,19 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	s.Memory.Usage = convertMemoryEntry(cg.MemoryStats.Usage)
 	s.Memory.Raw = cg.MemoryStats.Stats
 	s.Memory.PSI = cg.MemoryStats.PSI
+	if sw := ls.SwapStats; sw != nil {
+		s.Memory.SwapHigh = sw.High
+		s.Memory.SwapEvents = &types.SwapEvents{
+			High: sw.HighEvents,
+			Max:  sw.MaxEvents,
+			Fail: sw.FailEvents,
+		}
+		s.Memory.Zswap = &types.Zswap{
+			Usage:     sw.ZswapUsage,
+			Limit:     sw.ZswapLimit,
+			Writeback: sw.ZswapWriteback,
+		}
+	}
 
 	s.Blkio.IoServiceBytesRecursive = convertBlkioEntry(cg.BlkioStats.IoServiceBytesRecursive)
 	s.Blkio.IoServicedRecursive = convertBlkioEntry(cg.BlkioStats.IoServicedRecursive)
@@ -176,6 +368,18 @@ func convertLibcontainerStats(ls *libcontainer.Stats) *types.Stats {
 	}
 
 	s.NetworkInterfaces = ls.Interfaces
+	if ls.HelperCgroupStats != nil {
+		s.Helpers = convertLibcontainerStats(&libcontainer.Stats{CgroupStats: ls.HelperCgroupStats})
+	}
+	if q := ls.RootfsQuotaStats; q != nil {
+		s.Rootfs = &types.RootfsQuota{
+			ProjectID:   q.ProjectID,
+			Limit:       q.Limit,
+			Usage:       q.Usage,
+			InodesLimit: q.InodesLimit,
+			InodesUsage: q.InodesUsage,
+		}
+	}
 	return &s
 }
 
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/types"
+)
+
+// promWriter writes metrics in the Prometheus text exposition format.
+type promWriter struct {
+	w  *bufio.Writer
+	id string
+}
+
+// family writes the metadata of a metric family. All the samples of a family
+// must be written right after it.
+func (p *promWriter) family(name, typ, help string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample with the container id label, and the extra labels
+// given as name, value pairs.
+func (p *promWriter) sample(name string, value float64, labels ...string) {
+	p.w.WriteString(name)
+	p.w.WriteString(`{id="`)
+	p.w.WriteString(escapeLabel(p.id))
+	p.w.WriteByte('"')
+	for i := 0; i+1 < len(labels); i += 2 {
+		fmt.Fprintf(p.w, `,%s="%s"`, labels[i], escapeLabel(labels[i+1]))
+	}
+	p.w.WriteString("} ")
+	p.w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	p.w.WriteByte('\n')
+}
+
+// metric writes a metric family with a single sample.
+func (p *promWriter) metric(name, typ, help string, value float64) {
+	p.family(name, typ, help)
+	p.sample(name, value)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// writePrometheus writes the container stats s, and the number of OOM events
+// seen, in the Prometheus text exposition format.
+func writePrometheus(w io.Writer, id string, s *types.Stats, ooms uint64) error {
+	p := &promWriter{w: bufio.NewWriter(w), id: id}
+	const ns = 1e9
+
+	p.metric("runc_oom_events_total", "counter", "Number of OOM events seen.", float64(ooms))
+	if s == nil {
+		return p.w.Flush()
+	}
+
+	p.metric("runc_cpu_usage_seconds_total", "counter", "Total CPU time consumed.", float64(s.CPU.Usage.Total)/ns)
+	p.metric("runc_cpu_user_seconds_total", "counter", "CPU time consumed in user mode.", float64(s.CPU.Usage.User)/ns)
+	p.metric("runc_cpu_kernel_seconds_total", "counter", "CPU time consumed in kernel mode.", float64(s.CPU.Usage.Kernel)/ns)
+	p.metric("runc_cpu_periods_total", "counter", "Number of enforcement periods elapsed.", float64(s.CPU.Throttling.Periods))
+	p.metric("runc_cpu_throttled_periods_total", "counter", "Number of throttled enforcement periods.", float64(s.CPU.Throttling.ThrottledPeriods))
+	p.metric("runc_cpu_throttled_seconds_total", "counter", "Total time throttled.", float64(s.CPU.Throttling.ThrottledTime)/ns)
+
+	p.metric("runc_memory_usage_bytes", "gauge", "Memory usage.", float64(s.Memory.Usage.Usage))
+	p.metric("runc_memory_max_usage_bytes", "gauge", "Maximum memory usage recorded.", float64(s.Memory.Usage.Max))
+	p.metric("runc_memory_limit_bytes", "gauge", "Memory limit.", float64(s.Memory.Usage.Limit))
+	p.metric("runc_memory_failcnt_total", "counter", "Number of times the memory limit was hit.", float64(s.Memory.Usage.Failcnt))
+	p.metric("runc_memory_cache_bytes", "gauge", "Page cache memory usage.", float64(s.Memory.Cache))
+	p.metric("runc_memory_swap_usage_bytes", "gauge", "Swap usage.", float64(s.Memory.Swap.Usage))
+	p.metric("runc_memory_swap_limit_bytes", "gauge", "Swap limit.", float64(s.Memory.Swap.Limit))
+
+	p.metric("runc_pids_current", "gauge", "Number of processes.", float64(s.Pids.Current))
+	p.metric("runc_pids_limit", "gauge", "Maximum number of processes.", float64(s.Pids.Limit))
+
+	psi := []struct {
+		resource string
+		stats    *types.PSIStats
+	}{{"cpu", s.CPU.PSI}, {"memory", s.Memory.PSI}, {"io", s.Blkio.PSI}}
+	if s.CPU.PSI != nil || s.Memory.PSI != nil || s.Blkio.PSI != nil {
+		p.family("runc_pressure_stalled_seconds_total", "counter", "Total time tasks were stalled on a resource.")
+		for _, r := range psi {
+			if r.stats != nil {
+				p.sample("runc_pressure_stalled_seconds_total", float64(r.stats.Some.Total)/1e6, "resource", r.resource, "kind", "some")
+				p.sample("runc_pressure_stalled_seconds_total", float64(r.stats.Full.Total)/1e6, "resource", r.resource, "kind", "full")
+			}
+		}
+		p.family("runc_pressure_stall_percent", "gauge", "Percentage of time tasks were stalled on a resource, averaged over a window.")
+		for _, r := range psi {
+			if r.stats == nil {
+				continue
+			}
+			for _, d := range []struct {
+				kind string
+				data types.PSIData
+			}{{"some", r.stats.Some}, {"full", r.stats.Full}} {
+				p.sample("runc_pressure_stall_percent", d.data.Avg10, "resource", r.resource, "kind", d.kind, "window", "10s")
+				p.sample("runc_pressure_stall_percent", d.data.Avg60, "resource", r.resource, "kind", d.kind, "window", "60s")
+				p.sample("runc_pressure_stall_percent", d.data.Avg300, "resource", r.resource, "kind", d.kind, "window", "300s")
+			}
+		}
+	}
+
+	blkio := func(name, help string, entries []types.BlkioEntry) {
+		if len(entries) == 0 {
+			return
+		}
+		p.family(name, "counter", help)
+		for _, e := range entries {
+			p.sample(name, float64(e.Value), "device", fmt.Sprintf("%d:%d", e.Major, e.Minor), "op", e.Op)
+		}
+	}
+	blkio("runc_blkio_service_bytes_total", "Number of bytes transferred to and from block devices.", s.Blkio.IoServiceBytesRecursive)
+	blkio("runc_blkio_serviced_total", "Number of I/O operations on block devices.", s.Blkio.IoServicedRecursive)
+
+	if len(s.Hugetlb) > 0 {
+		p.family("runc_hugetlb_usage_bytes", "gauge", "Huge pages usage.")
+		for _, size := range slices.Sorted(maps.Keys(s.Hugetlb)) {
+			p.sample("runc_hugetlb_usage_bytes", float64(s.Hugetlb[size].Usage), "pagesize", size)
+		}
+	}
+
+	if len(s.NetworkInterfaces) > 0 {
+		netdev := func(name, help string, value func(*types.NetworkInterface) uint64) {
+			p.family(name, "counter", help)
+			for _, i := range s.NetworkInterfaces {
+				p.sample(name, float64(value(i)), "interface", i.Name)
+			}
+		}
+		netdev("runc_network_receive_bytes_total", "Number of bytes received.", func(i *types.NetworkInterface) uint64 { return i.RxBytes })
+		netdev("runc_network_receive_packets_total", "Number of packets received.", func(i *types.NetworkInterface) uint64 { return i.RxPackets })
+		netdev("runc_network_transmit_bytes_total", "Number of bytes transmitted.", func(i *types.NetworkInterface) uint64 { return i.TxBytes })
+		netdev("runc_network_transmit_packets_total", "Number of packets transmitted.", func(i *types.NetworkInterface) uint64 { return i.TxPackets })
+	}
+
+	return p.w.Flush()
+}
+
+// serveMetrics serves the container stats in the Prometheus format over HTTP
+// on addr, until the container stops.
+func serveMetrics(container *libcontainer.Container, backend *statsBackend, addr string) error {
+	oom, err := container.NotifyOOM()
+	if err != nil {
+		return err
+	}
+	var ooms atomic.Uint64
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		s, err := backend.containerStats(container)
+		var missingErr *libcontainer.ControllerMissingError
+		if err != nil && !errors.As(err, &missingErr) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := writePrometheus(w, container.ID(), convertLibcontainerStats(s), ooms.Load()); err != nil {
+			logrus.Debugf("unable to write metrics: %v", err)
+		}
+	})
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	// The OOM notification channel is closed once the container is gone.
+	for {
+		select {
+		case _, ok := <-oom:
+			if ok {
+				ooms.Add(1)
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(ctx)
+		case err := <-errCh:
+			return err
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/types"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	s := &types.Stats{
+		Hugetlb: map[string]types.Hugetlb{"2MB": {Usage: 4096}, "1GB": {}},
+		NetworkInterfaces: []*types.NetworkInterface{
+			{Name: "eth0", RxBytes: 100, TxBytes: 200},
+		},
+	}
+	s.CPU.Usage.Total = 1500000000
+	s.Memory.Usage.Usage = 1024
+	s.Memory.PSI = &types.PSIStats{Some: types.PSIData{Avg10: 1.5, Total: 2500000}}
+	s.Blkio.IoServiceBytesRecursive = []types.BlkioEntry{{Major: 8, Minor: 0, Op: "Read", Value: 512}}
+
+	var out strings.Builder
+	if err := writePrometheus(&out, `my"id`, s, 2); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE runc_oom_events_total counter",
+		`runc_oom_events_total{id="my\"id"} 2`,
+		`runc_cpu_usage_seconds_total{id="my\"id"} 1.5`,
+		"# TYPE runc_memory_usage_bytes gauge",
+		`runc_memory_usage_bytes{id="my\"id"} 1024`,
+		`runc_blkio_service_bytes_total{id="my\"id",device="8:0",op="Read"} 512`,
+		`runc_hugetlb_usage_bytes{id="my\"id",pagesize="1GB"} 0` + "\n" +
+			`runc_hugetlb_usage_bytes{id="my\"id",pagesize="2MB"} 4096`,
+		`runc_pressure_stalled_seconds_total{id="my\"id",resource="memory",kind="some"} 2.5`,
+		`runc_pressure_stall_percent{id="my\"id",resource="memory",kind="some",window="10s"} 1.5`,
+		`runc_network_transmit_bytes_total{id="my\"id",interface="eth0"} 200`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected output to contain %q, got:\n%s", line, out.String())
+		}
+	}
+}
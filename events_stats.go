@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fs2"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/bpfstats"
+	"github.com/opencontainers/runc/types"
+)
+
+// statsBackend gets the container stats, either from the cgroup files (the
+// fs backend), or from a BPF cgroup iterator (the bpf backend), which only
+// has the CPU, memory, and pids usage, but collects the usage of all the
+// containers in one pass.
+type statsBackend struct {
+	bpf *bpfstats.Collector
+}
+
+// newStatsBackend returns the named stats backend. If the bpf backend is not
+// supported, the fs backend is used instead.
+func newStatsBackend(name string) (*statsBackend, error) {
+	switch name {
+	case "fs":
+		return &statsBackend{}, nil
+	case "bpf":
+		if !cgroups.IsCgroup2UnifiedMode() {
+			logrus.Warn("the bpf stats backend requires cgroup v2, using the fs stats backend")
+			return &statsBackend{}, nil
+		}
+		c, err := bpfstats.New()
+		if err != nil {
+			logrus.Warnf("unable to use the bpf stats backend, using the fs stats backend: %v", err)
+			return &statsBackend{}, nil
+		}
+		return &statsBackend{bpf: c}, nil
+	}
+	return nil, fmt.Errorf("invalid stats backend: %q", name)
+}
+
+func (b *statsBackend) close() {
+	if b.bpf != nil {
+		b.bpf.Close()
+	}
+}
+
+// collect returns the BPF cgroup usage of the cgroup path and its
+// descendants, or nil if the bpf backend is not used, or fails.
+func (b *statsBackend) collect(path string) map[uint64]*bpfstats.Usage {
+	if b.bpf == nil || path == "" {
+		return nil
+	}
+	usage, err := b.bpf.Collect(path)
+	if err != nil {
+		logrus.Debugf("unable to collect the BPF cgroup stats, using the cgroup files: %v", err)
+		return nil
+	}
+	return usage
+}
+
+// stats returns the container stats, from the BPF cgroup usage if it has
+// the container cgroup, or from the cgroup files.
+func (b *statsBackend) stats(container *libcontainer.Container, usage map[uint64]*bpfstats.Usage) (*libcontainer.Stats, error) {
+	if usage != nil {
+		s, err := container.BPFStats(usage)
+		if err == nil {
+			return s, nil
+		}
+		logrus.Debugf("unable to get the BPF stats of container %s, using the cgroup files: %v", container.ID(), err)
+	}
+	return container.Stats()
+}
+
+// containerStats returns the container stats.
+func (b *statsBackend) containerStats(container *libcontainer.Container) (*libcontainer.Stats, error) {
+	return b.stats(container, b.collect(container.CgroupPath()))
+}
+
+// allStatsEvents reports the stats events of all the running containers,
+// once, or at every interval, with their usage collected in one pass.
+func allStatsEvents(context *cli.Context, backend *statsBackend) error {
+	root := context.GlobalString("root")
+	annotations := context.Bool("annotations")
+	interval := context.Duration("interval")
+	for {
+		if err := writeAllStatsEvents(root, backend, annotations); err != nil {
+			return err
+		}
+		if context.Bool("stats") {
+			return nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+func writeAllStatsEvents(root string, backend *statsBackend, annotations bool) error {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	usage := backend.collect(fs2.UnifiedMountpoint)
+	enc := json.NewEncoder(os.Stdout)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		container, err := libcontainer.Load(root, entry.Name())
+		if err != nil {
+			if !errors.Is(err, libcontainer.ErrInvalidID) && !errors.Is(err, libcontainer.ErrNotExist) {
+				logrus.Debugf("load container %s: %v", entry.Name(), err)
+			}
+			continue
+		}
+		if status, err := container.Status(); err != nil || status == libcontainer.Stopped {
+			continue
+		}
+		s, err := backend.stats(container, usage)
+		var missingErr *libcontainer.ControllerMissingError
+		if err != nil && !errors.As(err, &missingErr) {
+			logrus.Errorf("stats for %s: %v", container.ID(), err)
+			continue
+		}
+		e := &types.Event{Type: "stats", ID: container.ID(), Data: convertLibcontainerStats(s)}
+		if annotations {
+			e.Annotations = container.Annotations()
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
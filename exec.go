@@ -8,7 +8,12 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/docker/go-units"
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/runc/internal/specjson"
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
@@ -31,7 +36,7 @@ following will output a list of processes running in the container:
 	Flags: []cli.Flag{
 		cli.StringFlag{
 			Name:  "console-socket",
-			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
+			Usage: "path to an AF_UNIX socket (or fd://N for an inherited one) which will receive a file descriptor referencing the master end of the console's pseudoterminal",
 		},
 		cli.StringFlag{
 			Name:  "pidfd-socket",
@@ -75,9 +80,13 @@ following will output a list of processes running in the container:
 			Usage: "set the asm process label for the process commonly used with selinux",
 		},
 		cli.StringFlag{
-			Name:  "apparmor",
+			Name:  "apparmor, apparmor-profile",
 			Usage: "set the apparmor profile for the process",
 		},
+		cli.StringFlag{
+			Name:  "seccomp-profile",
+			Usage: "path to the seccomp profile (in the runtime-spec linux.seccomp format) for the process, replacing the container one",
+		},
 		cli.BoolFlag{
 			Name:  "no-new-privs",
 			Usage: "set the no new privileges value for the process",
@@ -93,12 +102,53 @@ following will output a list of processes running in the container:
 		},
 		cli.StringSliceFlag{
 			Name:  "cgroup",
-			Usage: "run the process in an (existing) sub-cgroup(s). Format is [<controller>:]<cgroup>.",
+			Usage: "run the process in a sub-cgroup(s), relative to the container cgroup. Format is [<controller>:]<cgroup>.",
+		},
+		cli.BoolFlag{
+			Name:  "create-cgroup",
+			Usage: "create the --cgroup sub-cgroup(s) if those do not exist",
+		},
+		cli.StringFlag{
+			Name:  "cpus",
+			Usage: "run the process in a transient sub-cgroup, limited to this number of CPUs (e.g. 0.5)",
+		},
+		cli.StringFlag{
+			Name:  "memory",
+			Usage: "run the process in a transient sub-cgroup, with this memory limit (in bytes, or with a unit suffix such as 512m)",
+		},
+		cli.StringSliceFlag{
+			Name:  "forward-signal",
+			Usage: "forward a signal runc receives as another one, as FROM=TO (such as SIGTERM=SIGINT), unless detached (can be repeated)",
+		},
+		cli.StringFlag{
+			Name:  "no-forward-signals",
+			Usage: "comma-separated list of the signals runc receives which are not forwarded, unless detached",
 		},
 		cli.BoolFlag{
 			Name:  "ignore-paused",
 			Usage: "allow exec in a paused container",
 		},
+		cli.StringFlag{
+			Name:  "env-defaults",
+			Usage: "policy of the default PATH, HOME, and TERM variables (always, if-missing, or never), overriding the container one",
+		},
+		cli.StringFlag{
+			Name:  "wait-for-file",
+			Usage: "with --detach, wait for this file to exist in the container before returning",
+		},
+		cli.IntFlag{
+			Name:  "wait-for-port",
+			Usage: "with --detach, wait for this TCP port to be listened on in the container before returning",
+		},
+		cli.StringFlag{
+			Name:  "wait-for-exit-code",
+			Usage: "with --detach, wait for this command (split on spaces) to exit with code 0 in the container before returning",
+		},
+		cli.DurationFlag{
+			Name:  "wait-timeout",
+			Value: libcontainer.DefaultReadinessTimeout,
+			Usage: "how long to wait for the --wait-for-* checks to pass; on timeout, the process is killed",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -109,6 +159,8 @@ following will output a list of processes running in the container:
 		}
 		status, err := execProcess(context)
 		if err == nil {
+			auditEndStatus(context, status)
+			traceEnd(nil)
 			os.Exit(status)
 		}
 		fatalWithCode(fmt.Errorf("exec failed: %w", err), 255)
@@ -152,6 +204,37 @@ func getSubCgroupPaths(args []string) (map[string]string, error) {
 	return paths, nil
 }
 
+// getCgroupLimits returns the resources of the transient sub-cgroup to run
+// the process in, set by the --cpus and --memory options, or nil if neither
+// is set.
+func getCgroupLimits(context *cli.Context) (*cgroups.Resources, error) {
+	cpus, memory := context.String("cpus"), context.String("memory")
+	if cpus == "" && memory == "" {
+		return nil, nil
+	}
+	r := &cgroups.Resources{}
+	if cpus != "" {
+		n, err := strconv.ParseFloat(cpus, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid value for cpus: %q", cpus)
+		}
+		// As with the CFS period default.
+		r.CpuPeriod = 100000
+		r.CpuQuota = int64(n * float64(r.CpuPeriod))
+		if r.CpuQuota < 1000 {
+			return nil, fmt.Errorf("invalid value for cpus: %q (must be at least 0.01)", cpus)
+		}
+	}
+	if memory != "" {
+		v, err := units.RAMInBytes(memory)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid value for memory: %q", memory)
+		}
+		r.Memory = v
+	}
+	return r, nil
+}
+
 func execProcess(context *cli.Context) (int, error) {
 	container, err := getContainer(context)
 	if err != nil {
@@ -176,6 +259,40 @@ func execProcess(context *cli.Context) (int, error) {
 	if err != nil {
 		return -1, err
 	}
+	if context.Bool("create-cgroup") && len(cgPaths) == 0 {
+		return -1, errors.New("--create-cgroup requires --cgroup")
+	}
+
+	limits, err := getCgroupLimits(context)
+	if err != nil {
+		return -1, err
+	}
+	if limits != nil && len(cgPaths) > 0 {
+		return -1, errors.New("--cpus and --memory can't be used together with --cgroup")
+	}
+
+	forwarding, err := getSignalForwarding(context)
+	if err != nil {
+		return -1, err
+	}
+
+	var seccompConfig *configs.Seccomp
+	if path := context.String("seccomp-profile"); path != "" {
+		seccompConfig, err = loadSeccompProfile(path)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	envDefaults := configs.EnvDefaults(context.String("env-defaults"))
+	if !envDefaults.IsValid() {
+		return -1, fmt.Errorf("invalid value for env-defaults: %q (must be always, if-missing, or never)", envDefaults)
+	}
+
+	readiness, err := getReadiness(context)
+	if err != nil {
+		return -1, err
+	}
 
 	r := &runner{
 		enableSubreaper: false,
@@ -189,10 +306,62 @@ func execProcess(context *cli.Context) (int, error) {
 		init:            false,
 		preserveFDs:     context.Int("preserve-fds"),
 		subCgroupPaths:  cgPaths,
+		createCgroups:   context.Bool("create-cgroup"),
+		cgroupLimits:    limits,
+		seccomp:         seccompConfig,
+		envDefaults:     envDefaults,
+		readiness:       readiness,
+		forwarding:      forwarding,
 	}
 	return r.run(p)
 }
 
+// getReadiness returns the readiness probe of the process, set by the
+// --wait-for-* options, or nil if none is set.
+func getReadiness(context *cli.Context) (*libcontainer.Readiness, error) {
+	r := &libcontainer.Readiness{
+		File:    context.String("wait-for-file"),
+		Port:    context.Int("wait-for-port"),
+		Args:    strings.Fields(context.String("wait-for-exit-code")),
+		Timeout: context.Duration("wait-timeout"),
+	}
+	if r.File == "" && r.Port == 0 && len(r.Args) == 0 {
+		return nil, nil
+	}
+	if !context.Bool("detach") {
+		return nil, errors.New("--wait-for-* options require --detach")
+	}
+	if r.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid value for wait-timeout: %s", r.Timeout)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadSeccompProfile loads the seccomp profile at path, which is in the
+// format of the runtime-spec linux.seccomp object.
+func loadSeccompProfile(path string) (*configs.Seccomp, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var profile specs.LinuxSeccomp
+	if err := json.NewDecoder(f).Decode(&profile); err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile %s: %w", path, err)
+	}
+	config, err := specconv.SetupSeccomp(&profile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid seccomp profile %s: %w", path, err)
+	}
+	if config == nil {
+		return nil, fmt.Errorf("invalid seccomp profile %s: no default action nor syscalls", path)
+	}
+	return config, nil
+}
+
 func getProcess(context *cli.Context, c *libcontainer.Container) (*specs.Process, error) {
 	if path := context.String("process"); path != "" {
 		f, err := os.Open(path)
@@ -200,11 +369,11 @@ func getProcess(context *cli.Context, c *libcontainer.Container) (*specs.Process
 			return nil, err
 		}
 		defer f.Close()
-		var p specs.Process
-		if err := json.NewDecoder(f).Decode(&p); err != nil {
+		p, err := specjson.DecodeProcess(f)
+		if err != nil {
 			return nil, err
 		}
-		return &p, validateProcessSpec(&p)
+		return p, validateProcessSpec(p)
 	}
 	// Process from config.json and CLI flags.
 	bundle, ok := utils.SearchLabels(c.Config().Labels, "bundle")
@@ -2,15 +2,9 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 
-	"github.com/opencontainers/runc/libcontainer/capabilities"
-	"github.com/opencontainers/runc/libcontainer/configs"
-	"github.com/opencontainers/runc/libcontainer/seccomp"
-	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/features"
 	runcfeatures "github.com/opencontainers/runc/types/features"
-	"github.com/opencontainers/runtime-spec/specs-go"
-	"github.com/opencontainers/runtime-spec/specs-go/features"
 	"github.com/urfave/cli"
 )
 
@@ -21,70 +15,31 @@ var featuresCommand = cli.Command{
 	Description: `Show the enabled features.
    The result is parsable as a JSON.
    See https://github.com/opencontainers/runtime-spec/blob/main/features.md for the type definition.
+   In addition to the fields defined there, the result has the "schemaVersion"
+   and "extensions" fields, see github.com/opencontainers/runc/types/features.
+
+   The features above are those runc knows of, whether or not the host
+   supports them. With --probe, the "host" field is added, with the subset
+   of the features which is actually usable on the host (such as the
+   namespaces, mount options, cgroup controllers, seccomp actions and flags,
+   idmapped mounts, and checkpoint/restore), as probed at runtime.
 `,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "probe",
+			Usage: "also probe the features actually usable on the host",
+		},
+	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
 
-		t := true
-
-		feat := features.Features{
-			OCIVersionMin: "1.0.0",
-			OCIVersionMax: specs.Version,
-			Annotations: map[string]string{
-				runcfeatures.AnnotationRuncVersion:           version,
-				runcfeatures.AnnotationRuncCommit:            gitCommit,
-				runcfeatures.AnnotationRuncCheckpointEnabled: "true",
-			},
-			Hooks:        configs.KnownHookNames(),
-			MountOptions: specconv.KnownMountOptions(),
-			Linux: &features.Linux{
-				Namespaces:   specconv.KnownNamespaces(),
-				Capabilities: capabilities.KnownCapabilities(),
-				Cgroup: &features.Cgroup{
-					V1:          &t,
-					V2:          &t,
-					Systemd:     &t,
-					SystemdUser: &t,
-					Rdma:        &t,
-				},
-				Apparmor: &features.Apparmor{
-					Enabled: &t,
-				},
-				Selinux: &features.Selinux{
-					Enabled: &t,
-				},
-				IntelRdt: &features.IntelRdt{
-					Enabled: &t,
-				},
-				MountExtensions: &features.MountExtensions{
-					IDMap: &features.IDMap{
-						Enabled: &t,
-					},
-				},
-				NetDevices: &features.NetDevices{
-					Enabled: &t,
-				},
-			},
-			PotentiallyUnsafeConfigAnnotations: []string{
-				"bundle",
-				"org.systemd.property.", // prefix form
-				"org.criu.config",
-			},
-		}
-
-		if seccomp.Enabled {
-			feat.Linux.Seccomp = &features.Seccomp{
-				Enabled:        &t,
-				Actions:        seccomp.KnownActions(),
-				Operators:      seccomp.KnownOperators(),
-				Archs:          seccomp.KnownArchs(),
-				KnownFlags:     seccomp.KnownFlags(),
-				SupportedFlags: seccomp.SupportedFlags(),
-			}
-			major, minor, patch := seccomp.Version()
-			feat.Annotations[runcfeatures.AnnotationLibseccompVersion] = fmt.Sprintf("%d.%d.%d", major, minor, patch)
+		feat := features.Get()
+		feat.Annotations[runcfeatures.AnnotationRuncVersion] = version
+		feat.Annotations[runcfeatures.AnnotationRuncCommit] = gitCommit
+		if context.Bool("probe") {
+			feat.Host = features.Probe()
 		}
 
 		enc := json.NewEncoder(context.App.Writer)
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+var gcCommand = cli.Command{
+	Name:  "gc",
+	Usage: "remove the resources left behind by the containers which are gone",
+	ArgsUsage: `
+
+The resources left behind, such as after the host crashed, are:
+ * the state of the containers without processes which were created before
+   the host booted (or all the stopped containers, with --stopped), and the
+   state directories without a state;
+ * the empty cgroups without a container, among the child cgroups of the
+   resource groups, the runc-*.scope units of system.slice, and the child
+   cgroups of the --cgroup-parent cgroups;
+ * the network namespaces kept for the containers which are no longer
+   mounted (or all those kept for the containers which do not exist, with
+   --kept-netns);
+ * the user namespace ID ranges allocated to the containers which are gone.
+
+EXAMPLE:
+To list the resources which would be removed, including the stopped
+containers:
+       # runc gc --stopped --dry-run`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "dry-run, n",
+			Usage: "only list the resources which would be removed",
+		},
+		cli.BoolFlag{
+			Name:  "stopped",
+			Usage: "also remove all the stopped containers, like runc delete",
+		},
+		cli.BoolFlag{
+			Name:  "kept-netns",
+			Usage: "also remove the network namespaces kept for the containers which do not exist",
+		},
+		cli.StringSliceFlag{
+			Name:  "cgroup-parent",
+			Usage: "also remove the empty child cgroups without a container of a parent cgroup, relative to the cgroup root (can be specified multiple times)",
+		},
+		cli.DurationFlag{
+			Name:  "min-age",
+			Value: time.Minute,
+			Usage: "the minimum age of the state directories without a state, and of the cgroups, to be removed",
+		},
+		cli.StringFlag{
+			Name:  "format, f",
+			Value: "table",
+			Usage: `select one of: table or json`,
+		},
+	},
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 0, exactArgs); err != nil {
+			return err
+		}
+		format := context.String("format")
+		if format != "table" && format != "json" {
+			return errors.New("invalid format option")
+		}
+		root := context.GlobalString("root")
+		orphans, err := libcontainer.FindOrphans(root, &libcontainer.GCOptions{
+			Stopped:       context.Bool("stopped"),
+			KeptNetns:     context.Bool("kept-netns"),
+			CgroupParents: context.StringSlice("cgroup-parent"),
+			MinAge:        context.Duration("min-age"),
+		})
+		if err != nil {
+			return err
+		}
+		removed := orphans
+		if !context.Bool("dry-run") {
+			removed = nil
+			for _, o := range orphans {
+				if err := libcontainer.RemoveOrphan(root, o); err != nil {
+					logrus.Errorf("unable to remove %s %s: %v", o.Kind, o.Path, err)
+					continue
+				}
+				removed = append(removed, o)
+			}
+		}
+		if err := printOrphans(format, removed); err != nil {
+			return err
+		}
+		if n := len(orphans) - len(removed); n > 0 {
+			return fmt.Errorf("unable to remove %d of %d resources", n, len(orphans))
+		}
+		return nil
+	},
+}
+
+func printOrphans(format string, orphans []libcontainer.Orphan) error {
+	if format == "json" {
+		if orphans == nil {
+			orphans = []libcontainer.Orphan{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(orphans)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+	fmt.Fprint(w, "KIND\tID\tPATH\tREASON\n")
+	for _, o := range orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Kind, o.ID, o.Path, o.Reason)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+)
+
+var groupResourcesFlag = cli.StringFlag{
+	Name:  "resources, r",
+	Value: "",
+	Usage: "path to the file containing the group resource limits (in the runtime-spec linux.resources format), or '-' to read from the standard input",
+}
+
+var groupCommand = cli.Command{
+	Name:  "group",
+	Usage: "manage resource groups (parent cgroups with limits shared by member containers)",
+	Description: `A resource group is a named parent cgroup with resource limits, which are
+shared by all the containers created as its members (see the --group option
+of runc create and runc run).`,
+	Subcommands: []cli.Command{
+		{
+			Name:      "create",
+			Usage:     "create a resource group",
+			ArgsUsage: `<group-name>`,
+			Flags:     []cli.Flag{groupResourcesFlag},
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 1, exactArgs); err != nil {
+					return err
+				}
+				r, err := groupResources(context)
+				if err != nil {
+					return err
+				}
+				rootless, err := shouldUseRootlessCgroupManager(context)
+				if err != nil {
+					return err
+				}
+				_, err = libcontainer.CreateGroup(context.GlobalString("root"), context.Args().First(), &cgroups.Cgroup{
+					Systemd:   context.GlobalBool("systemd-cgroup"),
+					Rootless:  rootless,
+					Resources: r,
+				})
+				return err
+			},
+		},
+		{
+			Name:      "update",
+			Usage:     "replace the resource limits of a resource group",
+			ArgsUsage: `<group-name>`,
+			Flags:     []cli.Flag{groupResourcesFlag},
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 1, exactArgs); err != nil {
+					return err
+				}
+				g, err := libcontainer.LoadGroup(context.GlobalString("root"), context.Args().First())
+				if err != nil {
+					return err
+				}
+				r, err := groupResources(context)
+				if err != nil {
+					return err
+				}
+				return g.Set(r)
+			},
+		},
+		{
+			Name:      "stats",
+			Usage:     "display the aggregated stats of the resource group members",
+			ArgsUsage: `<group-name>`,
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 1, exactArgs); err != nil {
+					return err
+				}
+				g, err := libcontainer.LoadGroup(context.GlobalString("root"), context.Args().First())
+				if err != nil {
+					return err
+				}
+				s, err := g.Stats()
+				if err != nil {
+					return err
+				}
+				return json.NewEncoder(os.Stdout).Encode(convertLibcontainerStats(&libcontainer.Stats{CgroupStats: s}))
+			},
+		},
+		{
+			Name:      "delete",
+			Usage:     "delete a resource group, which must have no running members",
+			ArgsUsage: `<group-name>`,
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 1, exactArgs); err != nil {
+					return err
+				}
+				g, err := libcontainer.LoadGroup(context.GlobalString("root"), context.Args().First())
+				if err != nil {
+					return err
+				}
+				return g.Destroy()
+			},
+		},
+		{
+			Name:  "list",
+			Usage: "list the resource groups",
+			Action: func(context *cli.Context) error {
+				if err := checkArgs(context, 0, exactArgs); err != nil {
+					return err
+				}
+				root := context.GlobalString("root")
+				names, err := libcontainer.ListGroups(root)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
+				fmt.Fprint(w, "NAME\tCGROUP\tCREATED\n")
+				for _, name := range names {
+					g, err := libcontainer.LoadGroup(root, name)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "load group %s: %v\n", name, err)
+						continue
+					}
+					fmt.Fprintf(w, "%s\t%s\t%s\n", name, g.Cgroup(), g.Created().Local().Format(time.RFC3339Nano))
+				}
+				return w.Flush()
+			},
+		},
+	},
+}
+
+// groupResources reads the resource limits given by the --resources option,
+// and converts them to the cgroup configuration.
+func groupResources(context *cli.Context) (*cgroups.Resources, error) {
+	r := &specs.LinuxResources{}
+	if in := context.String("resources"); in != "" {
+		f := os.Stdin
+		if in != "-" {
+			var err error
+			f, err = os.Open(in)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+		}
+		if err := json.NewDecoder(f).Decode(r); err != nil {
+			return nil, err
+		}
+	}
+	cg, err := specconv.CreateCgroupConfig(&specconv.CreateOpts{
+		Spec: &specs.Spec{Linux: &specs.Linux{Resources: r}},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return cg.Resources, nil
+}
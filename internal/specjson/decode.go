@@ -0,0 +1,589 @@
+package specjson
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// The fast decoder only handles a strict subset of JSON, and gives up as
+// soon as it sees anything else, such as:
+//   - strings containing escape sequences or invalid UTF-8;
+//   - numbers which are not plain integers, or do not fit in 18 digits;
+//   - duplicate object keys, or keys which differ from a known field
+//     name only in case (encoding/json matches those case-insensitively);
+//   - any syntax error.
+//
+// Objects with fields not handled by the fast decoder are passed to
+// encoding/json, with the handled fields removed.
+
+// decodeSpec is the fast path for DecodeSpec.
+func decodeSpec(data []byte) (*specs.Spec, bool) {
+	p := parser{data: string(data)}
+	s := new(specs.Spec)
+	p.partial(s, specKeys, func(key string) {
+		switch key {
+		case "process":
+			ptr(&p, &s.Process, p.process)
+		case "mounts":
+			list(&p, &s.Mounts, p.mount)
+		case "linux":
+			ptr(&p, &s.Linux, p.linux)
+		}
+	})
+	return s, !p.bad
+}
+
+// decodeProcess is the fast path for DecodeProcess.
+func decodeProcess(data []byte) (*specs.Process, bool) {
+	p := parser{data: string(data)}
+	proc := new(specs.Process)
+	p.process(proc)
+	return proc, !p.bad
+}
+
+type parser struct {
+	// data is the input, converted to a string once, so that all decoded
+	// strings can share its memory rather than being allocated separately.
+	data string
+	off  int
+	// key is the current object member key.
+	key string
+	// bad is set once the parser gives up.
+	bad bool
+}
+
+var (
+	specKeys      = []string{"process", "mounts", "linux"}
+	processKeys   = []string{"args", "env"}
+	linuxKeys     = []string{"devices", "resources"}
+	resourcesKeys = []string{"devices"}
+)
+
+func (p *parser) process(proc *specs.Process) {
+	p.partial(proc, processKeys, func(key string) {
+		switch key {
+		case "args":
+			p.strings(&proc.Args)
+		case "env":
+			p.strings(&proc.Env)
+		}
+	})
+}
+
+func (p *parser) linux(l *specs.Linux) {
+	p.partial(l, linuxKeys, func(key string) {
+		switch key {
+		case "devices":
+			list(p, &l.Devices, p.device)
+		case "resources":
+			ptr(p, &l.Resources, p.resources)
+		}
+	})
+}
+
+func (p *parser) resources(r *specs.LinuxResources) {
+	p.partial(r, resourcesKeys, func(string) {
+		list(p, &r.Devices, p.deviceCgroup)
+	})
+}
+
+var mountKeys = []string{"destination", "type", "source", "options", "uidMappings", "gidMappings"}
+
+func (p *parser) mount(m *specs.Mount) {
+	var seen uint64
+	for more := p.objectStart(); more; more = p.objectNext() {
+		switch p.key {
+		case "destination":
+			p.once(&seen, 0)
+			p.string(&m.Destination)
+		case "type":
+			p.once(&seen, 1)
+			p.string(&m.Type)
+		case "source":
+			p.once(&seen, 2)
+			p.string(&m.Source)
+		case "options":
+			p.once(&seen, 3)
+			p.strings(&m.Options)
+		case "uidMappings":
+			p.once(&seen, 4)
+			list(p, &m.UIDMappings, p.idMapping)
+		case "gidMappings":
+			p.once(&seen, 5)
+			list(p, &m.GIDMappings, p.idMapping)
+		default:
+			p.unknown(mountKeys)
+		}
+	}
+}
+
+var idMappingKeys = []string{"containerID", "hostID", "size"}
+
+func (p *parser) idMapping(m *specs.LinuxIDMapping) {
+	var seen uint64
+	for more := p.objectStart(); more; more = p.objectNext() {
+		switch p.key {
+		case "containerID":
+			p.once(&seen, 0)
+			p.uint32(&m.ContainerID)
+		case "hostID":
+			p.once(&seen, 1)
+			p.uint32(&m.HostID)
+		case "size":
+			p.once(&seen, 2)
+			p.uint32(&m.Size)
+		default:
+			p.unknown(idMappingKeys)
+		}
+	}
+}
+
+var deviceKeys = []string{"path", "type", "major", "minor", "fileMode", "uid", "gid"}
+
+func (p *parser) device(d *specs.LinuxDevice) {
+	var seen uint64
+	for more := p.objectStart(); more; more = p.objectNext() {
+		switch p.key {
+		case "path":
+			p.once(&seen, 0)
+			p.string(&d.Path)
+		case "type":
+			p.once(&seen, 1)
+			p.string(&d.Type)
+		case "major":
+			p.once(&seen, 2)
+			p.int64(&d.Major)
+		case "minor":
+			p.once(&seen, 3)
+			p.int64(&d.Minor)
+		case "fileMode":
+			p.once(&seen, 4)
+			if !p.null() {
+				v := new(uint32)
+				p.uint32(v)
+				d.FileMode = (*os.FileMode)(v)
+			}
+		case "uid":
+			p.once(&seen, 5)
+			p.uint32Ptr(&d.UID)
+		case "gid":
+			p.once(&seen, 6)
+			p.uint32Ptr(&d.GID)
+		default:
+			p.unknown(deviceKeys)
+		}
+	}
+}
+
+var deviceCgroupKeys = []string{"allow", "type", "major", "minor", "access"}
+
+func (p *parser) deviceCgroup(d *specs.LinuxDeviceCgroup) {
+	var seen uint64
+	for more := p.objectStart(); more; more = p.objectNext() {
+		switch p.key {
+		case "allow":
+			p.once(&seen, 0)
+			p.bool(&d.Allow)
+		case "type":
+			p.once(&seen, 1)
+			p.string(&d.Type)
+		case "major":
+			p.once(&seen, 2)
+			p.int64Ptr(&d.Major)
+		case "minor":
+			p.once(&seen, 3)
+			p.int64Ptr(&d.Minor)
+		case "access":
+			p.once(&seen, 4)
+			p.string(&d.Access)
+		default:
+			p.unknown(deviceCgroupKeys)
+		}
+	}
+}
+
+// ptr decodes a value which is either null, or decoded by fn into a newly
+// allocated T.
+func ptr[T any](p *parser, dst **T, fn func(*T)) {
+	if p.null() {
+		return
+	}
+	v := new(T)
+	fn(v)
+	*dst = v
+}
+
+// list decodes a value which is either null, or an array of elements which
+// are decoded by fn.
+func list[T any](p *parser, dst *[]T, fn func(*T)) {
+	if p.null() {
+		return
+	}
+	s := make([]T, 0, 4)
+	for more := p.arrayStart(); more; more = p.arrayNext() {
+		var zero T
+		s = append(s, zero)
+		if !p.null() {
+			fn(&s[len(s)-1])
+		}
+	}
+	*dst = s
+}
+
+func (p *parser) fail() bool {
+	p.bad = true
+	return false
+}
+
+// once marks the known field i as seen, and gives up if it was seen before.
+func (p *parser) once(seen *uint64, i int) {
+	if *seen&(1<<i) != 0 {
+		p.fail()
+	}
+	*seen |= 1 << i
+}
+
+// unknown skips the value of an unknown object member, making sure
+// encoding/json would ignore it, too.
+func (p *parser) unknown(known []string) {
+	if !p.plainKey(known) {
+		p.fail()
+		return
+	}
+	if raw := p.skip(); !p.bad && !json.Valid([]byte(raw)) {
+		p.fail()
+	}
+}
+
+// partial decodes an object into v (a pointer to struct), using fn for the
+// given keys, and encoding/json for all the other keys.
+func (p *parser) partial(v any, keys []string, fn func(key string)) {
+	var seen uint64
+	rest := []byte{'{'}
+	for more := p.objectStart(); more; more = p.objectNext() {
+		if i := slices.Index(keys, p.key); i != -1 {
+			p.once(&seen, i)
+			fn(keys[i])
+			continue
+		}
+		if !p.plainKey(keys) {
+			p.fail()
+			return
+		}
+		key := p.key
+		raw := p.skip()
+		if len(rest) > 1 {
+			rest = append(rest, ',')
+		}
+		rest = append(rest, '"')
+		rest = append(rest, key...)
+		rest = append(rest, '"', ':')
+		rest = append(rest, raw...)
+	}
+	if p.bad || len(rest) == 1 {
+		return
+	}
+	rest = append(rest, '}')
+	if json.Unmarshal(rest, v) != nil {
+		p.fail()
+	}
+}
+
+// plainKey checks that the current key can not be matched by encoding/json
+// to any of the known field names.
+func (p *parser) plainKey(known []string) bool {
+	for i := 0; i < len(p.key); i++ {
+		if p.key[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	for _, k := range known {
+		if strings.EqualFold(p.key, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// objectStart starts decoding an object, and reads the first member key.
+// It returns false if the object is empty, or on error.
+func (p *parser) objectStart() bool {
+	if p.bad {
+		return false
+	}
+	if !p.consume('{') {
+		return p.fail()
+	}
+	if p.consume('}') {
+		return false
+	}
+	return p.member()
+}
+
+// objectNext reads the next member key. It returns false at the end of the
+// object, or on error.
+func (p *parser) objectNext() bool {
+	if p.bad {
+		return false
+	}
+	if p.consume(',') {
+		return p.member()
+	}
+	if !p.consume('}') {
+		p.fail()
+	}
+	return false
+}
+
+func (p *parser) member() bool {
+	key, ok := p.str()
+	if !ok || !p.consume(':') {
+		return p.fail()
+	}
+	p.key = key
+	return true
+}
+
+// arrayStart starts decoding an array. It returns false if the array is
+// empty, or on error.
+func (p *parser) arrayStart() bool {
+	if p.bad {
+		return false
+	}
+	if !p.consume('[') {
+		return p.fail()
+	}
+	return !p.consume(']')
+}
+
+// arrayNext returns false at the end of the array, or on error.
+func (p *parser) arrayNext() bool {
+	if p.bad {
+		return false
+	}
+	if p.consume(',') {
+		return true
+	}
+	if !p.consume(']') {
+		p.fail()
+	}
+	return false
+}
+
+func (p *parser) ws() {
+	for p.off < len(p.data) {
+		switch p.data[p.off] {
+		case ' ', '\t', '\n', '\r':
+			p.off++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips whitespace and then c, if it is the next character.
+func (p *parser) consume(c byte) bool {
+	p.ws()
+	if p.off < len(p.data) && p.data[p.off] == c {
+		p.off++
+		return true
+	}
+	return false
+}
+
+// literal skips whitespace and then lit, if it comes next.
+func (p *parser) literal(lit string) bool {
+	p.ws()
+	if end := p.off + len(lit); end <= len(p.data) && p.data[p.off:end] == lit {
+		p.off = end
+		return true
+	}
+	return false
+}
+
+func (p *parser) null() bool {
+	return p.literal("null")
+}
+
+func (p *parser) bool(dst *bool) {
+	switch {
+	case p.literal("true"):
+		*dst = true
+	case p.literal("false"):
+		*dst = false
+	case !p.null():
+		p.fail()
+	}
+}
+
+// str reads a string without escape sequences, returning its contents.
+func (p *parser) str() (string, bool) {
+	if !p.consume('"') {
+		return "", false
+	}
+	start := p.off
+	ascii := true
+	for ; p.off < len(p.data); p.off++ {
+		c := p.data[p.off]
+		switch {
+		case c == '"':
+			b := p.data[start:p.off]
+			p.off++
+			if !ascii && !utf8.ValidString(b) {
+				return "", false
+			}
+			return b, true
+		case c == '\\' || c < ' ':
+			return "", false
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return "", false
+}
+
+func (p *parser) string(dst *string) {
+	if p.bad || p.null() {
+		return
+	}
+	s, ok := p.str()
+	if !ok {
+		p.fail()
+		return
+	}
+	*dst = s
+}
+
+func (p *parser) strings(dst *[]string) {
+	list(p, dst, p.string)
+}
+
+// integer reads an integer of up to 18 digits, which can not overflow.
+func (p *parser) integer(signed bool) int64 {
+	p.ws()
+	neg := false
+	if signed && p.off < len(p.data) && p.data[p.off] == '-' {
+		neg = true
+		p.off++
+	}
+	start := p.off
+	var n int64
+	for ; p.off < len(p.data); p.off++ {
+		c := p.data[p.off]
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + int64(c-'0')
+	}
+	digits := p.off - start
+	if digits == 0 || digits > 18 || (digits > 1 && p.data[start] == '0') {
+		p.fail()
+		return 0
+	}
+	if p.off < len(p.data) {
+		switch p.data[p.off] {
+		case '.', 'e', 'E':
+			p.fail()
+			return 0
+		}
+	}
+	if neg {
+		n = -n
+	}
+	return n
+}
+
+func (p *parser) int64(dst *int64) {
+	if p.bad || p.null() {
+		return
+	}
+	*dst = p.integer(true)
+}
+
+func (p *parser) int64Ptr(dst **int64) {
+	if p.bad || p.null() {
+		return
+	}
+	v := new(int64)
+	*v = p.integer(true)
+	*dst = v
+}
+
+func (p *parser) uint32(dst *uint32) {
+	if p.bad || p.null() {
+		return
+	}
+	n := p.integer(false)
+	if n > 1<<32-1 {
+		p.fail()
+		return
+	}
+	*dst = uint32(n)
+}
+
+func (p *parser) uint32Ptr(dst **uint32) {
+	if p.bad || p.null() {
+		return
+	}
+	v := new(uint32)
+	p.uint32(v)
+	*dst = v
+}
+
+// skip skips a value and returns its raw text. The value is not fully
+// validated, which is left to the caller.
+func (p *parser) skip() string {
+	p.ws()
+	start := p.off
+	depth := 0
+	for p.off < len(p.data) {
+		c := p.data[p.off]
+		switch c {
+		case '"':
+			p.off++
+			for p.off < len(p.data) && p.data[p.off] != '"' {
+				if p.data[p.off] == '\\' {
+					p.off++
+				}
+				p.off++
+			}
+			if p.off >= len(p.data) {
+				p.fail()
+				return ""
+			}
+			p.off++
+		case '{', '[':
+			depth++
+			p.off++
+		case '}', ']':
+			if depth == 0 {
+				if p.off == start {
+					p.fail()
+				}
+				return p.data[start:p.off]
+			}
+			depth--
+			p.off++
+		case ',', ' ', '\t', '\n', '\r':
+			if depth == 0 {
+				if p.off == start {
+					p.fail()
+				}
+				return p.data[start:p.off]
+			}
+			p.off++
+		default:
+			p.off++
+		}
+		if depth == 0 && (c == '"' || c == '}' || c == ']') {
+			return p.data[start:p.off]
+		}
+	}
+	p.fail()
+	return ""
+}
@@ -0,0 +1,212 @@
+package specjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func bigSpec(n int) *specs.Spec {
+	mode := os.FileMode(0o666)
+	id := uint32(0)
+	major, minor := int64(1), int64(3)
+	s := &specs.Spec{
+		Version: specs.Version,
+		Root:    &specs.Root{Path: "rootfs"},
+		Process: &specs.Process{
+			Args: []string{"sh", "-c", "true"},
+			Env:  []string{"PATH=/usr/bin:/bin", "TERM=xterm"},
+			Cwd:  "/",
+		},
+		Hostname: "runc",
+		Linux: &specs.Linux{
+			Resources: &specs.LinuxResources{
+				Devices: []specs.LinuxDeviceCgroup{{Allow: false, Access: "rwm"}},
+				Memory:  &specs.LinuxMemory{Limit: &major},
+			},
+			Namespaces: []specs.LinuxNamespace{{Type: specs.MountNamespace}},
+		},
+		Annotations: map[string]string{"a": "b"},
+	}
+	for i := 0; i < n; i++ {
+		s.Process.Env = append(s.Process.Env, fmt.Sprintf("VAR%d=value%d", i, i))
+		s.Mounts = append(s.Mounts, specs.Mount{
+			Destination: fmt.Sprintf("/dev/dri/card%d", i),
+			Type:        "bind",
+			Source:      fmt.Sprintf("/dev/dri/card%d", i),
+			Options:     []string{"rbind", "nosuid", "noexec"},
+		})
+		s.Linux.Devices = append(s.Linux.Devices, specs.LinuxDevice{
+			Path:     fmt.Sprintf("/dev/nvidia%d", i),
+			Type:     "c",
+			Major:    195,
+			Minor:    int64(i),
+			FileMode: &mode,
+			UID:      &id,
+			GID:      &id,
+		})
+		s.Linux.Resources.Devices = append(s.Linux.Resources.Devices, specs.LinuxDeviceCgroup{
+			Allow:  true,
+			Type:   "c",
+			Major:  &major,
+			Minor:  &minor,
+			Access: "rwm",
+		})
+	}
+	s.Mounts = append(s.Mounts, specs.Mount{
+		Destination: "/data",
+		Source:      "/srv/data",
+		Type:        "bind",
+		Options:     []string{"bind", "idmap"},
+		UIDMappings: []specs.LinuxIDMapping{{ContainerID: 0, HostID: 100000, Size: 65536}},
+		GIDMappings: []specs.LinuxIDMapping{{ContainerID: 0, HostID: 100000, Size: 65536}},
+	})
+	return s
+}
+
+var specSeeds = []string{
+	`{}`,
+	`null`,
+	`{"mounts":[]}`,
+	`{"mounts":null,"linux":null,"process":null}`,
+	`{"mounts":[null,{"destination":"/x","options":null}]}`,
+	`{"mounts":[{"destination":"/x","foo":{"bar":[1,2,"]"]}}]}`,
+	`{"mounts":[{"destination":"/x","Destination":"/y"}]}`,
+	`{"mounts":[{"destination":"/x","destination":"/y"}]}`,
+	`{"mounts":[{"destination":"/x"}]}`,
+	`{"mounts":[{"destination":"/x","uidMappings":[{"containerID":0,"hostID":4294967295,"size":1}]}]}`,
+	`{"mounts":[{"destination":"/x","uidMappings":[{"containerID":0,"hostID":4294967296,"size":1}]}]}`,
+	`{"mounts":[],"Mounts":[{"destination":"/y"}]}`,
+	`{"linux":{"devices":[{"path":"/dev/a","major":-1,"minor":01}]}}`,
+	`{"linux":{"devices":[{"path":"/dev/a","major":1.0,"minor":1e3}]}}`,
+	`{"linux":{"devices":[{"path":"/dev/a","fileMode":null,"uid":0,"gid":-0}]}}`,
+	`{"linux":{"resources":{"devices":[{"allow":true,"major":null,"minor":-0}],"pids":{"limit":10}}}}`,
+	`{"linux":{"resources":null,"devices":[]}}`,
+	`{"process":{"args":["a","b"],"env":["A=\xff"],"cwd":"/"}}`,
+	`{"process":{"args":["a"],"env":[null,"X=Y"],"cwd":1}}`,
+	` {"hostname" : "x" , "mounts" : [ ] } trailing`,
+	`{"hostname":"x",}`,
+	`{"hostname":tru}`,
+	`{"hostname":"x" "mounts":[]}`,
+	`{"mounts":[{"destination":"/x"}]`,
+	"{\"mounts\":[{\"destination\":\"K\"}],\"Key\":1}",
+	`[]`,
+	``,
+}
+
+// checkSpec makes sure that decodeSpec either gives up, or gives the same
+// result as encoding/json.
+func checkSpec(t *testing.T, data []byte) {
+	got, ok := decodeSpec(data)
+	if !ok {
+		return
+	}
+	var want *specs.Spec
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&want); err != nil {
+		t.Fatalf("fast decoder accepted input %q rejected by encoding/json: %v", data, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("input %q: decoded spec mismatch:\n got: %+v\nwant: %+v", data, got, want)
+	}
+}
+
+func checkProcess(t *testing.T, data []byte) {
+	got, ok := decodeProcess(data)
+	if !ok {
+		return
+	}
+	var want specs.Process
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&want); err != nil {
+		t.Fatalf("fast decoder accepted input %q rejected by encoding/json: %v", data, err)
+	}
+	if !reflect.DeepEqual(got, &want) {
+		t.Fatalf("input %q: decoded process mismatch:\n got: %+v\nwant: %+v", data, got, &want)
+	}
+}
+
+func TestDecodeSpec(t *testing.T) {
+	data, err := json.Marshal(bigSpec(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodeSpec(data); !ok {
+		t.Fatal("fast decoder unable to decode a regular spec")
+	}
+	checkSpec(t, data)
+	for _, seed := range specSeeds {
+		checkSpec(t, []byte(seed))
+	}
+}
+
+func TestDecodeSpecFallback(t *testing.T) {
+	for _, tc := range []string{
+		`{"mounts":[{"destination":"/\u0078"}]}`,
+		`{"mounts":[],"Mounts":[{"destination":"/y"}]}`,
+		`{"linux":{"devices":[{"path":"/dev/a","major":1.0}]}}`,
+	} {
+		if _, ok := decodeSpec([]byte(tc)); ok {
+			t.Errorf("input %q: expected the fast decoder to give up", tc)
+		}
+	}
+}
+
+func TestDecodeProcess(t *testing.T) {
+	data, err := json.Marshal(bigSpec(10).Process)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := decodeProcess(data); !ok {
+		t.Fatal("fast decoder unable to decode a regular process")
+	}
+	checkProcess(t, data)
+}
+
+func FuzzDecodeSpec(f *testing.F) {
+	data, err := json.Marshal(bigSpec(3))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	for _, seed := range specSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(checkSpec)
+}
+
+func FuzzDecodeProcess(f *testing.F) {
+	data, err := json.Marshal(bigSpec(3).Process)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add([]byte(`{"args":["a"],"env":null,"Env":["X=1"]}`))
+	f.Add([]byte(`{"args":["a",null],"terminal":true,"user":{"uid":1}}`))
+	f.Fuzz(checkProcess)
+}
+
+func BenchmarkDecodeSpec(b *testing.B) {
+	data, err := json.Marshal(bigSpec(5000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("std", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var s *specs.Spec
+			if err := json.Unmarshal(data, &s); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("fast", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, ok := decodeSpec(data); !ok {
+				b.Fatal("decodeSpec failed")
+			}
+		}
+	})
+}
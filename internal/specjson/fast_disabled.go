@@ -0,0 +1,5 @@
+//go:build !runc_fastjson
+
+package specjson
+
+const fastDecoding = false
@@ -0,0 +1,55 @@
+// Package specjson decodes OCI runtime spec (config.json) and process spec
+// (process.json) files.
+//
+// By default, encoding/json is used. When runc is built with the
+// runc_fastjson build tag, a specialized decoder is used for the parts of the
+// spec which can be very large (mounts, devices, environment), falling back
+// to encoding/json for everything else. The result is identical to that of
+// encoding/json in all cases; for any input the fast decoder can not handle,
+// or which is invalid, encoding/json is used to decode the whole file.
+package specjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// DecodeSpec decodes a runtime spec from r. It returns nil spec and nil
+// error if the JSON value is null.
+func DecodeSpec(r io.Reader) (*specs.Spec, error) {
+	var spec *specs.Spec
+	if !fastDecoding {
+		err := json.NewDecoder(r).Decode(&spec)
+		return spec, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if spec, ok := decodeSpec(data); ok {
+		return spec, nil
+	}
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(&spec)
+	return spec, err
+}
+
+// DecodeProcess decodes a process spec from r.
+func DecodeProcess(r io.Reader) (*specs.Process, error) {
+	var p specs.Process
+	if !fastDecoding {
+		err := json.NewDecoder(r).Decode(&p)
+		return &p, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if p, ok := decodeProcess(data); ok {
+		return p, nil
+	}
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(&p)
+	return &p, err
+}
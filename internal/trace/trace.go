@@ -0,0 +1,309 @@
+// Package trace implements a minimal OpenTelemetry tracer, which times the
+// phases of a runc command, and exports them as spans with the OTLP/HTTP
+// protocol (using the JSON encoding) once the command completes.
+//
+// The tracer is disabled unless [Enable] is called, in which case [Start]
+// returns a nil *Span, and all the Span methods are no-ops.
+package trace
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exportTimeout is the timeout for exporting the spans.
+const exportTimeout = 5 * time.Second
+
+// Span is an operation being traced, such as a phase of the container
+// start.
+type Span struct {
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	parent   *Span
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	err      error
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+var (
+	mu       sync.Mutex
+	endpoint string
+	resource []attribute
+	// remoteTraceID and remoteParentID are the span context of the caller,
+	// if passed in the TRACEPARENT environment variable.
+	remoteTraceID  [16]byte
+	remoteParentID [8]byte
+	// current is the innermost span being run, which is the parent of the
+	// spans started next.
+	current *Span
+	ended   []*Span
+)
+
+// Enable enables the tracer, exporting the spans to the OTLP/HTTP endpoint
+// addr, such as "http://localhost:4318". If addr has no path, the spans are
+// sent to the "/v1/traces" path.
+//
+// If the TRACEPARENT environment variable is set, as defined by the W3C
+// Trace Context specification, the spans are a part of the caller trace.
+func Enable(addr, serviceVersion string) error {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid OTLP endpoint %q: unsupported scheme %q", addr, u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if tp := os.Getenv("TRACEPARENT"); tp != "" {
+		// Ignore an invalid traceparent, as the spec says.
+		remoteTraceID, remoteParentID, _ = parseTraceparent(tp)
+	}
+	resource = []attribute{
+		attr("service.name", "runc"),
+		attr("service.version", serviceVersion),
+		attr("process.pid", os.Getpid()),
+	}
+	endpoint = u.String()
+	return nil
+}
+
+// parseTraceparent parses a traceparent header value, such as
+// "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01".
+func parseTraceparent(tp string) (traceID [16]byte, parentID [8]byte, _ error) {
+	parts := strings.Split(tp, "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return traceID, parentID, fmt.Errorf("invalid traceparent %q", tp)
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil {
+		return traceID, parentID, fmt.Errorf("invalid traceparent %q: %w", tp, err)
+	}
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil {
+		return traceID, parentID, fmt.Errorf("invalid traceparent %q: %w", tp, err)
+	}
+	if traceID == [16]byte{} || parentID == [8]byte{} {
+		return [16]byte{}, [8]byte{}, fmt.Errorf("invalid traceparent %q: zero ID", tp)
+	}
+	return traceID, parentID, nil
+}
+
+// Start starts a span named name, as a child of the innermost span being
+// run, if any, and returns it. It returns nil if the tracer is disabled.
+//
+// Spans are to be started and ended in a nested fashion, by a single
+// goroutine at a time.
+func Start(name string) *Span {
+	mu.Lock()
+	defer mu.Unlock()
+	if endpoint == "" {
+		return nil
+	}
+	s := &Span{
+		parent: current,
+		name:   name,
+		start:  time.Now(),
+	}
+	if current != nil {
+		s.traceID = current.traceID
+		s.parentID = current.spanID
+	} else {
+		s.traceID = remoteTraceID
+		s.parentID = remoteParentID
+		if s.traceID == [16]byte{} {
+			_, _ = rand.Read(s.traceID[:])
+		}
+	}
+	_, _ = rand.Read(s.spanID[:])
+	current = s
+	return s
+}
+
+// SetAttribute sets the attribute key of the span to value, which is either
+// a string, an integer, or a bool.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	s.attrs = append(s.attrs, attr(key, value))
+}
+
+func attr(key string, value any) attribute {
+	var v map[string]any
+	switch value := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": value}
+	case bool:
+		v = map[string]any{"boolValue": value}
+	case int:
+		v = map[string]any{"intValue": strconv.Itoa(value)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case uint64:
+		v = map[string]any{"intValue": strconv.FormatUint(value, 10)}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(value)}
+	}
+	return attribute{Key: key, Value: v}
+}
+
+// End ends the span, with err as its result. Ending a span twice is a
+// no-op.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	s.endLocked(err, time.Now())
+}
+
+func (s *Span) endLocked(err error, now time.Time) {
+	if !s.end.IsZero() {
+		return
+	}
+	s.end = now
+	s.err = err
+	ended = append(ended, s)
+	if !s.isRunning() {
+		return
+	}
+	// Also end the inner spans which were not ended, if any.
+	for ; current != s; current = current.parent {
+		if current.end.IsZero() {
+			current.end = now
+			current.err = errors.New("not ended")
+			ended = append(ended, current)
+		}
+	}
+	current = s.parent
+	for current != nil && !current.end.IsZero() {
+		current = current.parent
+	}
+}
+
+// isRunning returns whether s is the innermost span being run, or one of its
+// parents.
+func (s *Span) isRunning() bool {
+	for c := current; c != nil; c = c.parent {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush ends the spans which are still being run, if any, and exports all
+// the ended spans. It is a no-op if the tracer is disabled.
+func Flush() error {
+	mu.Lock()
+	if endpoint == "" {
+		mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	for current != nil {
+		current.endLocked(errors.New("not ended"), now)
+	}
+	spans := ended
+	ended = nil
+	data, err := json.Marshal(request(spans))
+	target := endpoint
+	mu.Unlock()
+	if err != nil || len(spans) == 0 {
+		return err
+	}
+
+	client := &http.Client{Timeout: exportTimeout}
+	resp, err := client.Post(target, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unable to export spans to %s: %s", target, resp.Status)
+	}
+	return nil
+}
+
+// request returns the OTLP ExportTraceServiceRequest for spans.
+func request(spans []*Span) any {
+	type status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	type span struct {
+		TraceID           string      `json:"traceId"`
+		SpanID            string      `json:"spanId"`
+		ParentSpanID      string      `json:"parentSpanId,omitempty"`
+		Name              string      `json:"name"`
+		Kind              int         `json:"kind"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+		Attributes        []attribute `json:"attributes,omitempty"`
+		Status            *status     `json:"status,omitempty"`
+	}
+	const (
+		spanKindInternal = 1
+		statusCodeError  = 2
+	)
+
+	list := make([]span, 0, len(spans))
+	for _, s := range spans {
+		o := span{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        s.attrs,
+		}
+		if s.parentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			o.Status = &status{Code: statusCodeError, Message: s.err.Error()}
+		}
+		list = append(list, o)
+	}
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": resource},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/opencontainers/runc"},
+				"spans": list,
+			}},
+		}},
+	}
+}
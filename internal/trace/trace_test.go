@@ -0,0 +1,138 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Status       *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// collect starts an OTLP endpoint, enables the tracer with it, and returns
+// a function returning the spans exported to it.
+func collect(t *testing.T) func() map[string]exportedSpan {
+	t.Helper()
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+	}))
+	t.Cleanup(func() {
+		srv.Close()
+		endpoint = ""
+		current = nil
+		ended = nil
+		remoteTraceID, remoteParentID = [16]byte{}, [8]byte{}
+	})
+	if err := Enable(srv.URL, "test"); err != nil {
+		t.Fatal(err)
+	}
+	return func() map[string]exportedSpan {
+		t.Helper()
+		if err := Flush(); err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Fatal(err)
+		}
+		spans := make(map[string]exportedSpan)
+		for _, s := range req.ResourceSpans[0].ScopeSpans[0].Spans {
+			spans[s.Name] = s
+		}
+		return spans
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	s := Start("test")
+	if s != nil {
+		t.Fatal("expected a nil span")
+	}
+	s.SetAttribute("key", "value")
+	s.End(nil)
+	if err := Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	flush := collect(t)
+
+	root := Start("root")
+	child := Start("child")
+	child.End(nil)
+	failed := Start("failed")
+	failed.End(errors.New("failure"))
+	Start("unended")
+	root.End(nil)
+
+	spans := flush()
+	if len(spans) != 4 {
+		t.Fatalf("expected 4 spans, got %+v", spans)
+	}
+	r := spans["root"]
+	if r.ParentSpanID != "" || r.Status != nil {
+		t.Errorf("unexpected root span: %+v", r)
+	}
+	for _, name := range []string{"child", "failed", "unended"} {
+		s := spans[name]
+		if s.TraceID != r.TraceID || s.ParentSpanID != r.SpanID {
+			t.Errorf("span %s is not a child of root: %+v", name, s)
+		}
+	}
+	if s := spans["failed"]; s.Status == nil || s.Status.Code != 2 || s.Status.Message != "failure" {
+		t.Errorf("unexpected failed span status: %+v", s.Status)
+	}
+	if s := spans["unended"]; s.Status == nil || s.Status.Code != 2 {
+		t.Errorf("unexpected unended span status: %+v", s.Status)
+	}
+}
+
+func TestTraceparent(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	flush := collect(t)
+
+	Start("root").End(nil)
+
+	s := flush()["root"]
+	if s.TraceID != "0af7651916cd43dd8448eb211c80319c" || s.ParentSpanID != "b7ad6b7169203331" {
+		t.Errorf("unexpected root span: %+v", s)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	for _, tp := range []string{
+		"",
+		"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+		"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+		"00-0af7651916cd43dd8448eb211c80319c-0000000000000000-01",
+		"00-0af7651916cd43dd8448eb211c80319x-b7ad6b7169203331-01",
+	} {
+		if _, _, err := parseTraceparent(tp); err == nil {
+			t.Errorf("%q: expected an error", tp)
+		}
+	}
+}
@@ -13,7 +13,7 @@ import (
 
 var killCommand = cli.Command{
 	Name:  "kill",
-	Usage: "kill sends the specified signal (default: SIGTERM) to the container's init process",
+	Usage: "kill sends the specified signal (default: SIGTERM) to the container's init process, or all its processes",
 	ArgsUsage: `<container-id> [signal]
 
 Where "<container-id>" is the name for the instance of the container and
@@ -26,9 +26,12 @@ signal to the init process of the "ubuntu01" container:
        # runc kill ubuntu01 KILL`,
 	Flags: []cli.Flag{
 		cli.BoolFlag{
-			Name:   "all, a",
-			Usage:  "(obsoleted, do not use)",
-			Hidden: true,
+			Name:  "all, a",
+			Usage: "send the signal to all the container processes, not only to init",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "wait up to this duration for the processes to be gone, then send SIGKILL (e.g. 10s)",
 		},
 	},
 	Action: func(context *cli.Context) error {
@@ -52,7 +55,10 @@ signal to the init process of the "ubuntu01" container:
 		if err != nil {
 			return err
 		}
-		err = container.Signal(signal)
+		err = container.Kill(signal, libcontainer.KillOpts{
+			All:     context.Bool("all"),
+			Timeout: context.Duration("timeout"),
+		})
 		if errors.Is(err, libcontainer.ErrNotRunning) && context.Bool("all") {
 			err = nil
 		}
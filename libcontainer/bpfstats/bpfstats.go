@@ -0,0 +1,427 @@
+// Package bpfstats collects the CPU, memory, and pids usage of all the
+// cgroups of a cgroup v2 subtree in one pass, using a BPF cgroup iterator,
+// rather than reading the stat files of every cgroup.
+//
+// The iterator program is assembled at run time, with the offsets of the
+// kernel structures it reads found in the kernel BTF, so that it does not
+// have to be compiled for a given kernel. It requires a kernel with the
+// cgroup iterator and the css_rstat_flush (or cgroup_rstat_flush) kfunc
+// (Linux 6.1 or later), with BTF, and the privileges to load BPF tracing
+// programs (CAP_BPF and CAP_PERFMON, or CAP_SYS_ADMIN).
+package bpfstats
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+)
+
+// ErrNotSupported is returned by New if the kernel does not support the
+// collection of the stats with a BPF cgroup iterator.
+var ErrNotSupported = errors.New("BPF cgroup iterator stats are not supported")
+
+// Usage is the usage of a cgroup, including its descendants.
+type Usage struct {
+	// CPUTotal, CPUUser, and CPUSystem are the CPU time (in nanoseconds).
+	// Unlike in cpu.stat, the user and system times are not scaled for
+	// their sum to be the total time.
+	CPUTotal  uint64
+	CPUUser   uint64
+	CPUSystem uint64
+	// Memory is the memory usage (in bytes), if HasMemory is set, that is
+	// if the memory controller is enabled for the cgroup.
+	Memory    uint64
+	HasMemory bool
+	// Pids is the number of tasks, if HasPids is set, that is if the pids
+	// controller is enabled for the cgroup.
+	Pids    uint64
+	HasPids bool
+}
+
+// record is the record written by the iterator program for every cgroup,
+// in the usage map, by cgroup ID.
+type record struct {
+	CPUTotal  uint64
+	CPUUser   uint64
+	CPUSystem uint64
+	Memory    uint64
+	Pids      uint64
+	Flags     uint64
+}
+
+const recordSize = int16(unsafe.Sizeof(record{}))
+
+// maxCgroups is the maximum number of cgroups whose usage is collected at
+// once, that is the usage map size.
+const maxCgroups = 1 << 16
+
+// The record flags.
+const (
+	flagMemory = 1 << iota
+	flagPids
+)
+
+// Collector collects the usage of cgroups with a BPF cgroup iterator.
+type Collector struct {
+	// mu serializes the collections, which share the usage map.
+	mu       sync.Mutex
+	prog     *ebpf.Program
+	usage    *ebpf.Map
+	pageSize uint64
+}
+
+// New loads the iterator program. The returned Collector is to be closed
+// once it is no longer used. If the kernel does not support the iterator,
+// the error wraps ErrNotSupported.
+func New() (*Collector, error) {
+	spec, err := btf.LoadKernelSpec()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotSupported, err)
+	}
+	usage, err := ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "runc_cg_usage",
+		Type:       ebpf.Hash,
+		KeySize:    8,
+		ValueSize:  uint32(recordSize),
+		MaxEntries: maxCgroups,
+		Flags:      unix.BPF_F_NO_PREALLOC,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the cgroup usage map: %w", err)
+	}
+	insns, err := program(spec, usage)
+	if err != nil {
+		usage.Close()
+		return nil, fmt.Errorf("%w: %w", ErrNotSupported, err)
+	}
+	prog, err := ebpf.NewProgramWithOptions(&ebpf.ProgramSpec{
+		Name:         "runc_cg_stats",
+		Type:         ebpf.Tracing,
+		AttachType:   ebpf.AttachTraceIter,
+		AttachTo:     "cgroup",
+		Flags:        unix.BPF_F_SLEEPABLE,
+		License:      "GPL",
+		Instructions: insns,
+	}, ebpf.ProgramOptions{KernelTypes: spec})
+	if err != nil {
+		usage.Close()
+		if errors.Is(err, ebpf.ErrNotSupported) || errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOENT) {
+			err = fmt.Errorf("%w: %w", ErrNotSupported, err)
+		}
+		return nil, fmt.Errorf("unable to load the cgroup iterator program: %w", err)
+	}
+	return &Collector{prog: prog, usage: usage, pageSize: uint64(os.Getpagesize())}, nil
+}
+
+// Close releases the iterator program.
+func (c *Collector) Close() error {
+	return errors.Join(c.prog.Close(), c.usage.Close())
+}
+
+// Collect returns the usage of the cgroup v2 directory path, and of all its
+// descendants (up to 65536 cgroups), by cgroup ID (see CgroupID). The usage
+// of the root cgroup is not accounted, and reported as zero.
+func (c *Collector) Collect(path string) (map[uint64]*Usage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.iterate(path); err != nil {
+		return nil, err
+	}
+	usage := make(map[uint64]*Usage)
+	var (
+		id   uint64
+		r    record
+		keys []uint64
+	)
+	entries := c.usage.Iterate()
+	for entries.Next(&id, &r) {
+		usage[id] = &Usage{
+			CPUTotal:  r.CPUTotal,
+			CPUUser:   r.CPUUser,
+			CPUSystem: r.CPUSystem,
+			Memory:    r.Memory * c.pageSize,
+			HasMemory: r.Flags&flagMemory != 0,
+			Pids:      r.Pids,
+			HasPids:   r.Flags&flagPids != 0,
+		}
+		keys = append(keys, id)
+	}
+	err := entries.Err()
+	// Empty the map for the next collection.
+	for _, id := range keys {
+		if err := c.usage.Delete(id); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return nil, fmt.Errorf("unable to clear the cgroup usage map: %w", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the cgroup usage map: %w", err)
+	}
+	return usage, nil
+}
+
+// iterate runs the iterator program on the cgroup v2 directory path, and its
+// descendants, which fills the usage map.
+func (c *Collector) iterate(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	link, err := iterLinkCreate(c.prog.FD(), int(dir.Fd()), unix.BPF_CGROUP_ITER_DESCENDANTS_PRE)
+	if err != nil {
+		return fmt.Errorf("unable to attach the cgroup iterator to %s: %w", path, err)
+	}
+	defer unix.Close(link)
+	iter, err := iterCreate(link)
+	if err != nil {
+		return fmt.Errorf("unable to create the cgroup iterator: %w", err)
+	}
+	f := os.NewFile(uintptr(iter), "bpf_iter")
+	defer f.Close()
+	// The program writes nothing, and runs for all the cgroups during the
+	// first read.
+	if _, err := io.Copy(io.Discard, f); err != nil {
+		return fmt.Errorf("unable to run the cgroup iterator: %w", err)
+	}
+	return nil
+}
+
+// CgroupID returns the ID of the cgroup v2 directory path, which is its
+// inode number.
+func CgroupID(path string) (uint64, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, &os.PathError{Op: "stat", Path: path, Err: err}
+	}
+	return st.Ino, nil
+}
+
+// bpfAttr is the union bpf_attr of the BPF_LINK_CREATE (for an iterator)
+// and BPF_ITER_CREATE commands.
+type bpfAttr struct {
+	progFd      uint32
+	targetFd    uint32
+	attachType  uint32
+	flags       uint32
+	iterInfo    uint64
+	iterInfoLen uint32
+	_           [44]byte
+}
+
+// iterLinkInfo is the cgroup member of union bpf_iter_link_info, which the
+// cilium/ebpf iterator links do not support.
+type iterLinkInfo struct {
+	order    uint32
+	cgroupFd uint32
+	cgroupID uint64
+}
+
+func bpf(cmd int, attr *bpfAttr) (int, error) {
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, uintptr(cmd), uintptr(unsafe.Pointer(attr)), unsafe.Sizeof(*attr))
+	runtime.KeepAlive(attr)
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+func iterLinkCreate(progFd, cgroupFd int, order uint32) (int, error) {
+	info := iterLinkInfo{order: order, cgroupFd: uint32(cgroupFd)}
+	fd, err := bpf(unix.BPF_LINK_CREATE, &bpfAttr{
+		progFd:      uint32(progFd),
+		attachType:  unix.BPF_TRACE_ITER,
+		iterInfo:    uint64(uintptr(unsafe.Pointer(&info))),
+		iterInfoLen: uint32(unsafe.Sizeof(info)),
+	})
+	runtime.KeepAlive(&info)
+	return fd, err
+}
+
+func iterCreate(linkFd int) (int, error) {
+	return bpf(unix.BPF_ITER_CREATE, &bpfAttr{progFd: uint32(linkFd)})
+}
+
+// offsets are the kernel structure offsets used by the iterator program.
+type offsets struct {
+	kn, knID, bstat, subsys  int16
+	cputime, utime, stime    int16
+	sumExecRuntime           int16
+	memory, memoryUsage      int32
+	pidsCounter              int32
+	memoryCgrpID, pidsCgrpID int16
+	flush                    *btf.Func
+}
+
+// memberOffset returns the byte offset of the member of the named struct.
+func memberOffset(spec *btf.Spec, typ, member string) (int, error) {
+	var s *btf.Struct
+	if err := spec.TypeByName(typ, &s); err != nil {
+		return 0, err
+	}
+	for _, m := range s.Members {
+		if m.Name == member {
+			return int(m.Offset.Bytes()), nil
+		}
+	}
+	return 0, fmt.Errorf("struct %s has no %s member", typ, member)
+}
+
+// subsysID returns the id of the cgroup subsystem, or -1 if the kernel
+// does not have it.
+func subsysID(spec *btf.Spec, name string) int16 {
+	var e *btf.Enum
+	if err := spec.TypeByName("cgroup_subsys_id", &e); err != nil {
+		return -1
+	}
+	for _, v := range e.Values {
+		if v.Name == name {
+			return int16(v.Value)
+		}
+	}
+	return -1
+}
+
+func kernelOffsets(spec *btf.Spec) (*offsets, error) {
+	var (
+		o   offsets
+		err error
+	)
+	get := func(typ, member string) int {
+		var off int
+		if err == nil {
+			off, err = memberOffset(spec, typ, member)
+		}
+		return off
+	}
+	o.kn = int16(get("cgroup", "kn"))
+	o.knID = int16(get("kernfs_node", "id"))
+	o.bstat = int16(get("cgroup", "bstat"))
+	o.subsys = int16(get("cgroup", "subsys"))
+	o.cputime = int16(get("cgroup_base_stat", "cputime"))
+	o.utime = int16(get("task_cputime", "utime"))
+	o.stime = int16(get("task_cputime", "stime"))
+	o.sumExecRuntime = int16(get("task_cputime", "sum_exec_runtime"))
+	if err != nil {
+		return nil, err
+	}
+	o.memoryCgrpID = subsysID(spec, "memory_cgrp_id")
+	if o.memoryCgrpID >= 0 {
+		o.memory = int32(get("mem_cgroup", "memory"))
+		o.memoryUsage = int32(get("page_counter", "usage"))
+	}
+	o.pidsCgrpID = subsysID(spec, "pids_cgrp_id")
+	if o.pidsCgrpID >= 0 {
+		o.pidsCounter = int32(get("pids_cgroup", "counter"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The function flushing the cgroup stats was renamed in Linux 6.16.
+	for _, name := range []string{"css_rstat_flush", "cgroup_rstat_flush"} {
+		if err = spec.TypeByName(name, &o.flush); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// program returns the iterator program, which writes the usage of every
+// cgroup, once its stats are flushed, to the usage map.
+func program(spec *btf.Spec, usage *ebpf.Map) (asm.Instructions, error) {
+	o, err := kernelOffsets(spec)
+	if err != nil {
+		return nil, err
+	}
+	flushID, err := spec.TypeID(o.flush)
+	if err != nil {
+		return nil, err
+	}
+	// The cgroup ID (the map key) and the record are on the stack.
+	const key = -8
+	rec := key - recordSize
+	field := func(off uintptr) int16 { return rec + int16(off) }
+	var r record
+	insns := asm.Instructions{
+		// r6 = ctx->cgroup, which is NULL once the iteration is done.
+		asm.LoadMem(asm.R6, asm.R1, 8, asm.DWord),
+		asm.JEq.Imm(asm.R6, 0, "exit"),
+		// Flush the cgroup CPU stats (cgroup->self is at offset 0).
+		asm.Mov.Reg(asm.R1, asm.R6),
+		{OpCode: asm.OpCode(asm.JumpClass).SetJumpOp(asm.Call), Src: asm.PseudoKfuncCall, Constant: int64(flushID)},
+
+		asm.LoadMem(asm.R1, asm.R6, o.kn, asm.DWord),
+		asm.LoadMem(asm.R1, asm.R1, o.knID, asm.DWord),
+		asm.StoreMem(asm.RFP, key, asm.R1, asm.DWord),
+		asm.StoreImm(asm.RFP, field(unsafe.Offsetof(r.Memory)), 0, asm.DWord),
+		asm.StoreImm(asm.RFP, field(unsafe.Offsetof(r.Pids)), 0, asm.DWord),
+		asm.StoreImm(asm.RFP, field(unsafe.Offsetof(r.Flags)), 0, asm.DWord),
+	}
+	for _, f := range []struct {
+		off   int16
+		field uintptr
+	}{
+		{o.sumExecRuntime, unsafe.Offsetof(r.CPUTotal)},
+		{o.utime, unsafe.Offsetof(r.CPUUser)},
+		{o.stime, unsafe.Offsetof(r.CPUSystem)},
+	} {
+		insns = append(insns,
+			asm.LoadMem(asm.R1, asm.R6, o.bstat+o.cputime+f.off, asm.DWord),
+			asm.StoreMem(asm.RFP, field(f.field), asm.R1, asm.DWord),
+		)
+	}
+	// The memory and pids usage are read from the controller state, if the
+	// controller is enabled for the cgroup.
+	for _, c := range []struct {
+		id    int16
+		off   int32
+		field uintptr
+		flag  int32
+		label string
+	}{
+		{o.memoryCgrpID, o.memory + o.memoryUsage, unsafe.Offsetof(r.Memory), flagMemory, "no_memory"},
+		{o.pidsCgrpID, o.pidsCounter, unsafe.Offsetof(r.Pids), flagPids, "no_pids"},
+	} {
+		if c.id < 0 {
+			continue
+		}
+		insns = append(insns,
+			asm.LoadMem(asm.R3, asm.R6, o.subsys+8*c.id, asm.DWord),
+			asm.JEq.Imm(asm.R3, 0, c.label),
+			asm.Add.Imm(asm.R3, c.off),
+			asm.Mov.Reg(asm.R1, asm.RFP),
+			asm.Add.Imm(asm.R1, int32(field(c.field))),
+			asm.Mov.Imm(asm.R2, 8),
+			asm.FnProbeReadKernel.Call(),
+			asm.JNE.Imm(asm.R0, 0, c.label),
+			asm.LoadMem(asm.R1, asm.RFP, field(unsafe.Offsetof(r.Flags)), asm.DWord),
+			asm.Or.Imm(asm.R1, c.flag),
+			asm.StoreMem(asm.RFP, field(unsafe.Offsetof(r.Flags)), asm.R1, asm.DWord),
+			asm.Mov.Imm(asm.R0, 0).WithSymbol(c.label),
+		)
+	}
+	insns = append(insns,
+		// bpf_map_update_elem(usage, &key, &rec, BPF_ANY).
+		asm.LoadMapPtr(asm.R1, usage.FD()),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, key),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, int32(rec)),
+		asm.Mov.Imm(asm.R4, 0),
+		asm.FnMapUpdateElem.Call(),
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+		asm.Return(),
+	)
+	return insns, nil
+}
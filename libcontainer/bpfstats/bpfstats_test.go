@@ -0,0 +1,59 @@
+package bpfstats
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/opencontainers/cgroups"
+)
+
+func TestKernelOffsets(t *testing.T) {
+	spec, err := btf.LoadKernelSpec()
+	if err != nil {
+		t.Skipf("no kernel BTF: %v", err)
+	}
+	o, err := kernelOffsets(spec)
+	if err != nil {
+		t.Skipf("unsupported kernel: %v", err)
+	}
+	// The cgroup self css is the first member, which the flush kfunc
+	// relies on, and the cgroup kernfs node is after it.
+	if self, err := memberOffset(spec, "cgroup", "self"); err != nil || self != 0 {
+		t.Fatalf("expected the cgroup self css at offset 0, got %d (%v)", self, err)
+	}
+	if o.kn <= 0 || o.bstat <= o.kn {
+		t.Errorf("unexpected cgroup offsets: %+v", o)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("requires cgroup v2")
+	}
+	c, err := New()
+	if err != nil {
+		if errors.Is(err, ErrNotSupported) || errors.Is(err, os.ErrPermission) {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	defer c.Close()
+	const root = "/sys/fs/cgroup"
+	usage, err := c.Collect(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := CgroupID(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := usage[id]; !ok {
+		t.Fatalf("expected the usage of the root cgroup (%d), got %d cgroups", id, len(usage))
+	}
+	// The map is emptied after every collection.
+	if again, err := c.Collect(root); err != nil || len(again) == 0 {
+		t.Fatalf("expected a second collection to work, got %d cgroups (%v)", len(again), err)
+	}
+}
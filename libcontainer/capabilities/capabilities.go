@@ -43,6 +43,56 @@ func KnownCapabilities() []string {
 	return res
 }
 
+// Mask returns the bitmask (as in the Cap* fields of /proc/<pid>/status) of
+// the capabilities in names. Unknown or unavailable capabilities are ignored.
+func Mask(names []string) (uint64, error) {
+	cm, err := capMap()
+	if err != nil {
+		return 0, err
+	}
+	var mask uint64
+	for _, name := range names {
+		if c, ok := cm[name]; ok {
+			mask |= 1 << uint(c)
+		}
+	}
+	return mask, nil
+}
+
+// Unsupported returns the sorted list of the capabilities in names which are
+// unknown or unavailable in the current environment, and thus would be ignored.
+func Unsupported(names []string) ([]string, error) {
+	cm, err := capMap()
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	for _, name := range names {
+		if _, ok := cm[name]; !ok && !slices.Contains(res, name) {
+			res = append(res, name)
+		}
+	}
+	slices.Sort(res)
+	return res, nil
+}
+
+// Names returns the names of the capabilities in the bitmask mask. The
+// capabilities unknown to runc are named by their number, as in "CAP_63".
+func Names(mask uint64) []string {
+	var names []string
+	for c := capability.Cap(0); c < 64; c++ {
+		if mask&(1<<uint(c)) == 0 {
+			continue
+		}
+		if slices.Contains(capability.ListKnown(), c) {
+			names = append(names, capToStr(c))
+		} else {
+			names = append(names, fmt.Sprintf("CAP_%d", c))
+		}
+	}
+	return names
+}
+
 // New creates a new Caps from the given Capabilities config. Unknown Capabilities
 // or Capabilities that are unavailable in the current environment are ignored,
 // printing a warning instead.
@@ -3,6 +3,7 @@ package capabilities
 import (
 	"io"
 	"os"
+	"slices"
 	"testing"
 
 	"github.com/moby/sys/capability"
@@ -77,3 +78,27 @@ func TestNew(t *testing.T) {
 
 	hook.Reset()
 }
+
+func TestMaskNames(t *testing.T) {
+	mask, err := Mask([]string{"CAP_CHOWN", "CAP_KILL", "CAP_UNKNOWN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(1<<capability.CAP_CHOWN | 1<<capability.CAP_KILL); mask != want {
+		t.Fatalf("expected mask %#x, got %#x", want, mask)
+	}
+	names := Names(mask | 1<<63)
+	if want := []string{"CAP_CHOWN", "CAP_KILL", "CAP_63"}; !slices.Equal(names, want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	unsupported, err := Unsupported([]string{"CAP_KILL", "CAP_UNKNOWN", "CAP_CHOWN", "CAP_UNKNOWN", "CAP_BOGUS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"CAP_BOGUS", "CAP_UNKNOWN"}; !slices.Equal(unsupported, want) {
+		t.Fatalf("expected %v, got %v", want, unsupported)
+	}
+}
@@ -0,0 +1,183 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fs"
+)
+
+// ControllerMissingError is returned when some of the cgroup controllers
+// used by the container are no longer available for its cgroup. This can
+// happen when controllers are disabled at runtime, for example by systemd
+// re-applying DisableControllers= or by an administrator modifying
+// cgroup.subtree_control.
+type ControllerMissingError struct {
+	// Controllers is the list of missing controllers.
+	Controllers []string
+	// Err is the error from the failed operation, if any.
+	Err error
+}
+
+func (e *ControllerMissingError) Error() string {
+	msg := "cgroup controllers no longer available: " + strings.Join(e.Controllers, ", ")
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *ControllerMissingError) Unwrap() error {
+	return e.Err
+}
+
+// missingControllers returns those of the controllers in want which are no
+// longer available for the cgroup managed by m. On cgroup v1, a nil want
+// means all the controllers the container has joined.
+func missingControllers(m cgroups.Manager, want []string) ([]string, error) {
+	// A cgroup which is gone entirely is not a drift.
+	if !m.Exists() {
+		return nil, nil
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		return missingControllersV2(m.Path(""), want)
+	}
+	return missingControllersV1(m.GetPaths(), want), nil
+}
+
+func missingControllersV1(paths map[string]string, want []string) []string {
+	if want == nil {
+		want = slices.Sorted(maps.Keys(paths))
+	}
+	var missing []string
+	for _, ctrl := range want {
+		// An empty path means the controller was never joined
+		// (e.g. for rootless containers), which is not a drift.
+		path := paths[ctrl]
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			missing = append(missing, ctrl)
+		}
+	}
+	return missing
+}
+
+func missingControllersV2(path string, want []string) ([]string, error) {
+	if len(want) == 0 {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	avail := strings.Fields(string(data))
+	var missing []string
+	for _, ctrl := range want {
+		if !slices.Contains(avail, ctrl) {
+			missing = append(missing, ctrl)
+		}
+	}
+	return missing, nil
+}
+
+// requiredControllers returns the list of controllers needed to apply r.
+func requiredControllers(r *cgroups.Resources, unified bool) []string {
+	if r == nil {
+		return nil
+	}
+	req := map[string]bool{}
+	req["memory"] = r.Memory != 0 || r.MemoryReservation != 0 || r.MemorySwap != 0 ||
+		r.OomKillDisable || r.MemorySwappiness != nil
+	req["cpu"] = r.CpuShares != 0 || r.CpuQuota != 0 || r.CpuPeriod != 0 || r.CpuBurst != nil ||
+		r.CpuRtRuntime != 0 || r.CpuRtPeriod != 0 || r.CpuWeight != 0 || r.CPUIdle != nil
+	req["cpuset"] = r.CpusetCpus != "" || r.CpusetMems != ""
+	req["pids"] = r.PidsLimit != 0
+	req["hugetlb"] = len(r.HugetlbLimit) > 0
+	req["rdma"] = len(r.Rdma) > 0
+	blkio := r.BlkioWeight != 0 || r.BlkioLeafWeight != 0 || len(r.BlkioWeightDevice) > 0 ||
+		len(r.BlkioThrottleReadBpsDevice) > 0 || len(r.BlkioThrottleWriteBpsDevice) > 0 ||
+		len(r.BlkioThrottleReadIOPSDevice) > 0 || len(r.BlkioThrottleWriteIOPSDevice) > 0
+	if unified {
+		req["io"] = blkio
+		for key := range r.Unified {
+			ctrl, _, ok := strings.Cut(key, ".")
+			if ok && ctrl != "cgroup" {
+				req[ctrl] = true
+			}
+		}
+	} else {
+		req["blkio"] = blkio
+		req["net_cls"] = r.NetClsClassid != 0
+		req["net_prio"] = len(r.NetPrioIfpriomap) > 0
+	}
+	var ret []string
+	for ctrl, ok := range req {
+		if ok {
+			ret = append(ret, ctrl)
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// cgroupStats returns the container cgroup stats. If some of the container's
+// controllers are gone, the stats for the remaining ones are returned along
+// with a *ControllerMissingError.
+func (c *Container) cgroupStats() (*cgroups.Stats, error) {
+	stats, err := c.cgroupManager.GetStats()
+	var want []string
+	if cgroups.IsCgroup2UnifiedMode() {
+		want = requiredControllers(c.config.Cgroups.Resources, true)
+	}
+	missing, err2 := missingControllers(c.cgroupManager, want)
+	if err2 != nil || len(missing) == 0 {
+		return stats, err
+	}
+	if err != nil && !cgroups.IsCgroup2UnifiedMode() {
+		// The v1 manager stops at the first failing controller,
+		// so retry with the remaining ones.
+		if s, err2 := c.availableCgroupStatsV1(missing); err2 == nil {
+			stats, err = s, nil
+		}
+	}
+	return stats, &ControllerMissingError{Controllers: missing, Err: err}
+}
+
+func (c *Container) availableCgroupStatsV1(missing []string) (*cgroups.Stats, error) {
+	cg, err := c.cgroupManager.GetCgroups()
+	if err != nil {
+		return nil, err
+	}
+	paths := maps.Clone(c.cgroupManager.GetPaths())
+	for _, ctrl := range missing {
+		delete(paths, ctrl)
+	}
+	m, err := fs.NewManager(cg, paths)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cgroup manager: %w", err)
+	}
+	return m.GetStats()
+}
+
+// checkControllers returns a *ControllerMissingError if some of the
+// controllers needed to apply r are no longer available.
+func (c *Container) checkControllers(r *cgroups.Resources) error {
+	want := requiredControllers(r, cgroups.IsCgroup2UnifiedMode())
+	missing, err := missingControllers(c.cgroupManager, want)
+	if err != nil || len(missing) == 0 {
+		// Let the manager report the actual error, if any.
+		return nil
+	}
+	return &ControllerMissingError{Controllers: missing}
+}
@@ -0,0 +1,80 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+)
+
+func TestMissingControllersV1(t *testing.T) {
+	root := t.TempDir()
+	paths := map[string]string{}
+	for _, ctrl := range []string{"cpu", "memory", "pids"} {
+		paths[ctrl] = filepath.Join(root, ctrl, "test")
+		if err := os.MkdirAll(paths[ctrl], 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths["rdma"] = ""
+	if missing := missingControllersV1(paths, nil); len(missing) != 0 {
+		t.Fatalf("expected no missing controllers, got %v", missing)
+	}
+	if err := os.Remove(paths["memory"]); err != nil {
+		t.Fatal(err)
+	}
+	if missing := missingControllersV1(paths, nil); !slices.Equal(missing, []string{"memory"}) {
+		t.Errorf("expected [memory], got %v", missing)
+	}
+	if missing := missingControllersV1(paths, []string{"cpu", "pids"}); len(missing) != 0 {
+		t.Errorf("expected no missing controllers, got %v", missing)
+	}
+}
+
+func TestMissingControllersV2(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpuset cpu pids\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	missing, err := missingControllersV2(dir, []string{"cpu", "memory", "pids"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(missing, []string{"memory"}) {
+		t.Errorf("expected [memory], got %v", missing)
+	}
+	// A removed cgroup is not a drift.
+	missing, err = missingControllersV2(filepath.Join(dir, "gone"), []string{"memory"})
+	if err != nil || missing != nil {
+		t.Errorf("expected nil, nil; got %v, %v", missing, err)
+	}
+}
+
+func TestRequiredControllers(t *testing.T) {
+	r := &cgroups.Resources{
+		Memory:      1 << 20,
+		CpuWeight:   100,
+		BlkioWeight: 10,
+		Unified:     map[string]string{"hugetlb.2MB.max": "0", "cgroup.freeze": "0"},
+	}
+	if req := requiredControllers(r, true); !slices.Equal(req, []string{"cpu", "hugetlb", "io", "memory"}) {
+		t.Errorf("unexpected v2 controllers: %v", req)
+	}
+	r.Unified = nil
+	if req := requiredControllers(r, false); !slices.Equal(req, []string{"blkio", "cpu", "memory"}) {
+		t.Errorf("unexpected v1 controllers: %v", req)
+	}
+}
+
+func TestControllerMissingError(t *testing.T) {
+	err := error(&ControllerMissingError{Controllers: []string{"io", "memory"}, Err: os.ErrNotExist})
+	if err.Error() != "cgroup controllers no longer available: io, memory: file does not exist" {
+		t.Errorf("unexpected error message: %q", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Error("expected error to wrap os.ErrNotExist")
+	}
+}
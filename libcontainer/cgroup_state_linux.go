@@ -0,0 +1,204 @@
+package libcontainer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"golang.org/x/sys/unix"
+)
+
+// The freezer states of [CgroupState.Freezer].
+const (
+	FreezerThawed   = "thawed"
+	FreezerFreezing = "freezing"
+	FreezerFrozen   = "frozen"
+)
+
+// CgroupState is the live state of the container cgroup and of its
+// processes, see [Container.CgroupState].
+type CgroupState struct {
+	// Freezer is the freezer state of the container cgroup: FreezerThawed,
+	// FreezerFrozen, or FreezerFreezing while the processes are being
+	// frozen (or if they are stuck, such as in an uninterruptible sleep).
+	// It is empty if the freezer is not available.
+	Freezer string `json:"freezer,omitempty"`
+
+	// Processes is the number of processes in the container cgroup,
+	// including its sub-cgroups.
+	Processes int `json:"processes"`
+
+	// PendingFatalSignals lists the processes with a pending signal which
+	// kills them once delivered, that is, the processes which are dying, or
+	// will once thawed.
+	PendingFatalSignals []PendingSignals `json:"pendingFatalSignals,omitempty"`
+}
+
+// PendingSignals are the pending fatal signals of a process.
+type PendingSignals struct {
+	Pid     int      `json:"pid"`
+	Signals []string `json:"signals"`
+}
+
+// CgroupState returns the live state of the container cgroup and of its
+// processes, read from the cgroup filesystem and from procfs. It returns
+// nil if the container cgroup does not exist.
+func (c *Container) CgroupState() (*CgroupState, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !c.cgroupManager.Exists() {
+		return nil, nil
+	}
+	var (
+		s   = &CgroupState{}
+		err error
+	)
+	if cgroups.IsCgroup2UnifiedMode() {
+		s.Freezer, err = readFreezerState2(c.cgroupManager.Path(""))
+	} else {
+		s.Freezer, err = readFreezerState1(c.cgroupManager.Path("freezer"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	pids, err := c.cgroupManager.GetAllPids()
+	if err != nil {
+		return nil, err
+	}
+	s.Processes = len(pids)
+	for _, pid := range pids {
+		signals, err := pendingFatalSignals(pid)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				// The process is gone.
+				continue
+			}
+			return nil, err
+		}
+		if len(signals) > 0 {
+			s.PendingFatalSignals = append(s.PendingFatalSignals, PendingSignals{Pid: pid, Signals: signals})
+		}
+	}
+	return s, nil
+}
+
+// readFreezerState1 returns the state of the cgroup v1 freezer of dir,
+// which, unlike [cgroups.Manager.GetFreezerState], does not wait for a
+// freezing cgroup to be frozen.
+func readFreezerState1(dir string) (string, error) {
+	if dir == "" {
+		return "", nil
+	}
+	state, err := cgroups.ReadFile(dir, "freezer.state")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	switch strings.TrimSpace(state) {
+	case "THAWED":
+		return FreezerThawed, nil
+	case "FREEZING":
+		return FreezerFreezing, nil
+	case "FROZEN":
+		return FreezerFrozen, nil
+	}
+	return "", fmt.Errorf("unknown freezer.state %q", state)
+}
+
+// readFreezerState2 returns the state of the cgroup v2 freezer of dir: it
+// is freezing if cgroup.freeze is set while cgroup.events does not report
+// the cgroup as frozen yet.
+func readFreezerState2(dir string) (string, error) {
+	freeze, err := cgroups.ReadFile(dir, "cgroup.freeze")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	if strings.TrimSpace(freeze) == "0" {
+		return FreezerThawed, nil
+	}
+	events, err := cgroups.ReadFile(dir, "cgroup.events")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(events, "\n") {
+		if val, ok := strings.CutPrefix(line, "frozen "); ok && val == "1" {
+			return FreezerFrozen, nil
+		}
+	}
+	return FreezerFreezing, nil
+}
+
+// nonFatalSignals is the set of the signals whose default action is not to
+// terminate the process.
+var nonFatalSignals = sigMask(unix.SIGCHLD, unix.SIGCONT, unix.SIGSTOP, unix.SIGTSTP,
+	unix.SIGTTIN, unix.SIGTTOU, unix.SIGURG, unix.SIGWINCH)
+
+func sigMask(signals ...unix.Signal) uint64 {
+	var mask uint64
+	for _, s := range signals {
+		mask |= 1 << (s - 1)
+	}
+	return mask
+}
+
+// pendingFatalSignals returns the names of the pending signals of the
+// process pid which terminate it once delivered.
+func pendingFatalSignals(pid int) ([]string, error) {
+	f, err := os.Open("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsePendingFatalSignals(f)
+}
+
+// parsePendingFatalSignals returns the names of the pending fatal signals
+// of a process, from its /proc/[pid]/status: SIGKILL, or the signals which
+// are neither blocked, ignored, nor caught, and whose default action is to
+// terminate the process.
+func parsePendingFatalSignals(r io.Reader) ([]string, error) {
+	masks := make(map[string]uint64, 5)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		key, val, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "SigPnd", "ShdPnd", "SigBlk", "SigIgn", "SigCgt":
+			mask, err := strconv.ParseUint(strings.TrimSpace(val), 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", key, err)
+			}
+			masks[key] = mask
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	pending := masks["SigPnd"] | masks["ShdPnd"]
+	fatal := pending &^ nonFatalSignals &^ masks["SigBlk"] &^ masks["SigIgn"] &^ masks["SigCgt"]
+	fatal |= pending & sigMask(unix.SIGKILL)
+	var names []string
+	for sig := unix.Signal(1); sig <= 64; sig++ {
+		if fatal&sigMask(sig) == 0 {
+			continue
+		}
+		name := unix.SignalName(sig)
+		if name == "" {
+			name = strconv.Itoa(int(sig))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -0,0 +1,91 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+)
+
+func TestReadFreezerState(t *testing.T) {
+	cgroups.TestMode = true
+	defer func() { cgroups.TestMode = false }()
+	dir := t.TempDir()
+	write := func(file, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(read func(string) (string, error), expected string) {
+		t.Helper()
+		state, err := read(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != expected {
+			t.Errorf("expected %q, got %q", expected, state)
+		}
+	}
+
+	// No freezer.
+	check(readFreezerState1, "")
+	check(readFreezerState2, "")
+
+	for data, expected := range map[string]string{
+		"THAWED\n":   FreezerThawed,
+		"FREEZING\n": FreezerFreezing,
+		"FROZEN\n":   FreezerFrozen,
+	} {
+		write("freezer.state", data)
+		check(readFreezerState1, expected)
+	}
+
+	write("cgroup.freeze", "0\n")
+	write("cgroup.events", "populated 1\nfrozen 0\n")
+	check(readFreezerState2, FreezerThawed)
+	write("cgroup.freeze", "1\n")
+	check(readFreezerState2, FreezerFreezing)
+	write("cgroup.events", "populated 1\nfrozen 1\n")
+	check(readFreezerState2, FreezerFrozen)
+}
+
+func TestParsePendingFatalSignals(t *testing.T) {
+	status := func(pnd, shdPnd, blk, ign, cgt string) string {
+		return "Name:\tsleep\nState:\tS (sleeping)\n" +
+			"SigQ:\t1/63322\nSigPnd:\t" + pnd + "\nShdPnd:\t" + shdPnd +
+			"\nSigBlk:\t" + blk + "\nSigIgn:\t" + ign + "\nSigCgt:\t" + cgt + "\n"
+	}
+	const none = "0000000000000000"
+	for _, tc := range []struct {
+		name     string
+		status   string
+		expected []string
+	}{
+		{name: "none", status: status(none, none, none, none, none)},
+		// SIGTERM.
+		{name: "term", status: status(none, "0000000000004000", none, none, none), expected: []string{"SIGTERM"}},
+		{name: "caught", status: status(none, "0000000000004000", none, none, "0000000000004000")},
+		{name: "ignored", status: status("0000000000004000", none, none, "0000000000004000", none)},
+		{name: "blocked", status: status(none, "0000000000004000", "0000000000004000", none, none)},
+		// SIGCHLD.
+		{name: "non-fatal", status: status(none, "0000000000010000", none, none, none)},
+		// SIGKILL and SIGHUP, with all the signals caught.
+		{name: "kill", status: status("0000000000000100", "0000000000000001", none, none, "fffffffffffffeff"), expected: []string{"SIGKILL"}},
+		// SIGHUP and SIGRTMIN+1 (35).
+		{name: "realtime", status: status("0000000400000001", none, none, none, none), expected: []string{"SIGHUP", "35"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signals, err := parsePendingFatalSignals(strings.NewReader(tc.status))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(signals, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, signals)
+			}
+		})
+	}
+}
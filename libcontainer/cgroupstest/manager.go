@@ -0,0 +1,180 @@
+// Package cgroupstest provides an in-memory cgroup manager, so that the code
+// using libcontainer can be tested without privileges (see
+// [libcontainer.WithCgroupManager]).
+package cgroupstest
+
+import (
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/opencontainers/cgroups"
+)
+
+// ErrNotExist is returned by the [Manager] methods requiring the cgroup to
+// exist.
+var ErrNotExist = errors.New("cgroup does not exist")
+
+// Manager is an in-memory [cgroups.Manager]. It records the resources it is
+// set to, and returns the processes, statistics, and OOM kill count it is
+// given by the test.
+type Manager struct {
+	mu       sync.Mutex
+	cgroup   *cgroups.Cgroup
+	exists   bool
+	pids     []int
+	state    cgroups.FreezerState
+	stats    cgroups.Stats
+	oomKills uint64
+
+	// ApplyErr, SetErr, and DestroyErr, if set, are returned by Apply,
+	// Set, and Destroy.
+	ApplyErr, SetErr, DestroyErr error
+}
+
+var _ cgroups.Manager = (*Manager)(nil)
+
+// NewManager returns a Manager of the cgroup config, which does not exist
+// until Apply is called.
+func NewManager(config *cgroups.Cgroup) *Manager {
+	if config == nil {
+		config = &cgroups.Cgroup{}
+	}
+	if config.Resources == nil {
+		config.Resources = &cgroups.Resources{}
+	}
+	return &Manager{cgroup: config, state: cgroups.Thawed}
+}
+
+func (m *Manager) Apply(pid int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ApplyErr != nil {
+		return m.ApplyErr
+	}
+	m.exists = true
+	if pid != -1 && !slices.Contains(m.pids, pid) {
+		m.pids = append(m.pids, pid)
+	}
+	return nil
+}
+
+func (m *Manager) GetPids() ([]int, error) {
+	return m.GetAllPids()
+}
+
+func (m *Manager) GetAllPids() ([]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.exists {
+		return nil, ErrNotExist
+	}
+	return slices.Clone(m.pids), nil
+}
+
+func (m *Manager) GetStats() (*cgroups.Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.exists {
+		return nil, ErrNotExist
+	}
+	stats := m.stats
+	stats.PidsStats.Current = uint64(len(m.pids))
+	return &stats, nil
+}
+
+func (m *Manager) Freeze(state cgroups.FreezerState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.exists {
+		return ErrNotExist
+	}
+	m.state = state
+	return nil
+}
+
+func (m *Manager) Destroy() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.DestroyErr != nil {
+		return m.DestroyErr
+	}
+	m.exists = false
+	m.pids = nil
+	m.state = cgroups.Thawed
+	return nil
+}
+
+// Path returns an empty path, as the cgroup is not in the filesystem.
+func (m *Manager) Path(string) string {
+	return ""
+}
+
+func (m *Manager) Set(r *cgroups.Resources) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.SetErr != nil {
+		return m.SetErr
+	}
+	if r != nil {
+		m.cgroup.Resources = r
+	}
+	return nil
+}
+
+// GetPaths returns no paths, as the cgroup is not in the filesystem.
+func (m *Manager) GetPaths() map[string]string {
+	return map[string]string{}
+}
+
+func (m *Manager) GetCgroups() (*cgroups.Cgroup, error) {
+	return m.cgroup, nil
+}
+
+func (m *Manager) GetFreezerState() (cgroups.FreezerState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state, nil
+}
+
+func (m *Manager) Exists() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.exists
+}
+
+func (m *Manager) OOMKillCount() (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.oomKills, nil
+}
+
+// Resources returns the resources the cgroup was last set to.
+func (m *Manager) Resources() *cgroups.Resources {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cgroup.Resources
+}
+
+// SetPids sets the processes in the cgroup, creating it if needed.
+func (m *Manager) SetPids(pids ...int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exists = true
+	m.pids = slices.Clone(pids)
+}
+
+// SetStats sets the statistics returned by GetStats, other than the number
+// of processes.
+func (m *Manager) SetStats(stats *cgroups.Stats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats = *stats
+}
+
+// AddOOMKill increments the OOM kill count.
+func (m *Manager) AddOOMKill() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.oomKills++
+}
@@ -0,0 +1,210 @@
+// Package cgtrace records the cgroup file writes done by a cgroup manager, and
+// can also compute the writes a manager would do without applying them.
+//
+// The writes are recorded as they happen, from the inotify events of the
+// cgroup directories, so only the cgroupfs part of the configuration is
+// covered; in particular, device rules and properties set via systemd are
+// not reported.
+package cgtrace
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fs"
+	"github.com/opencontainers/cgroups/fs2"
+	"github.com/sirupsen/logrus"
+)
+
+// Write is a single write to a cgroup file.
+type Write struct {
+	// Path is the cgroup file path.
+	Path string `json:"path"`
+	// Value is the content of the file once the write is done, that is, the
+	// value in force, as formatted by the kernel. For a failed write, it is
+	// empty.
+	Value string `json:"value"`
+	// Error is the write error, if any.
+	Error string `json:"error,omitempty"`
+	// Unchecked is set for a write which DryRun does not do, as it has side
+	// effects beyond the cgroup (see sideEffectFiles). Value is then the
+	// value to be written, which is not checked by the kernel.
+	Unchecked bool `json:"unchecked,omitempty"`
+}
+
+// sideEffectFiles are the cgroup v2 files which DryRun does not write, as
+// writing them affects more than the scratch cgroup: a partition root takes
+// its exclusive CPUs away from its parent and siblings (including the
+// container cgroup), and a reclaim acts on the memory right away.
+var sideEffectFiles = []string{
+	"cpuset.cpus.partition",
+	"cpuset.cpus.exclusive",
+	"memory.reclaim",
+}
+
+// Manager is a cgroups.Manager which reports all the writes done by Set.
+type Manager struct {
+	cgroups.Manager
+	record func(Write)
+}
+
+// New returns a Manager wrapping m, which calls record for every cgroup file
+// written by Set. A failed write is reported with Error set, and is the last
+// one reported for the Set call.
+//
+// The writes to the cgroup files done by other processes while Set runs are
+// reported too.
+func New(m cgroups.Manager, record func(Write)) *Manager {
+	return &Manager{Manager: m, record: record}
+}
+
+// Set implements cgroups.Manager.
+func (m *Manager) Set(r *cgroups.Resources) error {
+	dirs := make(map[string]string)
+	for _, path := range m.GetPaths() {
+		dirs[path] = path
+	}
+	w, err := newWatcher(dirs)
+	if err != nil {
+		logrus.Debugf("cgtrace: unable to watch cgroup writes: %v", err)
+		return m.Manager.Set(r)
+	}
+	defer w.close()
+	err = m.Manager.Set(r)
+	writes, werr := w.writes(err)
+	if werr != nil {
+		logrus.Debugf("cgtrace: unable to read cgroup writes: %v", werr)
+	}
+	for _, w := range writes {
+		m.record(w)
+	}
+	return err
+}
+
+// DryRun returns the list of cgroup file writes which m.Set(r) would
+// perform, in the order they would be done, without changing the cgroup of
+// m: the resources are set on an empty scratch cgroup, created next to it
+// (so the values are checked by the kernel), and removed afterwards. If a
+// write fails, it is the last one returned, with Error set.
+//
+// The scratch cgroup is a real one, so the files whose writes affect more
+// than the cgroup itself (see sideEffectFiles) are not written, and are
+// returned last, with Unchecked set.
+//
+// A successful dry run only proves that the kernel accepts the values in an
+// empty cgroup with the same parent. The writes which depend on the
+// processes in the cgroup (such as the memory limits lower than the current
+// usage), or on its current state, do not fail in the scratch cgroup, and
+// the ones which depend on the parent (such as the cpuset or hugetlb ones)
+// may fail in both.
+func DryRun(m cgroups.Manager, r *cgroups.Resources) (_ []Write, retErr error) {
+	if r == nil {
+		return nil, nil
+	}
+	cg, err := m.GetCgroups()
+	if err != nil {
+		return nil, err
+	}
+	paths := m.GetPaths()
+	if len(paths) == 0 {
+		return nil, errors.New("no cgroup paths")
+	}
+
+	// The scratch cgroups, by controller, and the real cgroup of each.
+	scratch := make(map[string]string, len(paths))
+	dirs := make(map[string]string, len(paths))
+	defer func() {
+		for dir := range dirs {
+			if err := os.Remove(dir); err != nil && retErr == nil {
+				retErr = fmt.Errorf("unable to remove scratch cgroup: %w", err)
+			}
+		}
+	}()
+	byPath := make(map[string]string, len(paths))
+	for ctrl, path := range paths {
+		// Several cgroup v1 controllers can share a hierarchy.
+		if dir, ok := byPath[path]; ok {
+			scratch[ctrl] = dir
+			continue
+		}
+		dir, err := os.MkdirTemp(filepath.Dir(path), filepath.Base(path)+".dryrun-")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create scratch cgroup: %w", err)
+		}
+		byPath[path] = dir
+		scratch[ctrl] = dir
+		dirs[dir] = path
+	}
+
+	// Device rules are not written to files on cgroup v2, and the scratch
+	// cgroup has no processes to apply them to.
+	rr := *r
+	rr.SkipDevices = true
+	var unchecked []Write
+	for _, file := range sideEffectFiles {
+		v, ok := rr.Unified[file]
+		if !ok {
+			continue
+		}
+		if len(unchecked) == 0 {
+			rr.Unified = maps.Clone(rr.Unified)
+		}
+		delete(rr.Unified, file)
+		unchecked = append(unchecked, Write{Path: filepath.Join(paths[""], file), Value: v, Unchecked: true})
+	}
+	shadowCg := *cg
+	shadowCg.Resources = &rr
+
+	var sm cgroups.Manager
+	if cgroups.IsCgroup2UnifiedMode() {
+		sm, err = fs2.NewManager(&shadowCg, scratch[""])
+	} else {
+		sm, err = fs.NewManager(&shadowCg, scratch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	w, err := newWatcher(dirs)
+	if err != nil {
+		return nil, err
+	}
+	defer w.close()
+	writes, err := w.writes(sm.Set(&rr))
+	if err != nil || (len(writes) > 0 && writes[len(writes)-1].Error != "") {
+		return writes, err
+	}
+	return append(writes, unchecked...), nil
+}
+
+// failedWrite returns the write of a cgroup file of the watched directories
+// which failed with err, if any.
+func (w *watcher) failedWrite(err error) (Write, bool) {
+	var pathErr *os.PathError
+	if err == nil || !errors.As(err, &pathErr) {
+		return Write{}, false
+	}
+	dir := filepath.Dir(pathErr.Path)
+	real, ok := w.dirs[dir]
+	if !ok {
+		return Write{}, false
+	}
+	return Write{
+		Path:  filepath.Join(real, filepath.Base(pathErr.Path)),
+		Error: strings.ReplaceAll(err.Error(), dir, real),
+	}, true
+}
+
+// readValue returns the content of the cgroup file path, or an empty string
+// if it can not be read (some cgroup files are write-only).
+func readValue(path string) string {
+	data, err := cgroups.ReadFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(data, "\n")
+}
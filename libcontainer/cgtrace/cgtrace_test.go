@@ -0,0 +1,146 @@
+package cgtrace
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/manager"
+)
+
+// newManager returns the manager of a new cgroup, with no processes.
+func newManager(t *testing.T) cgroups.Manager {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	cg := &cgroups.Cgroup{
+		Path:      "/cgtrace-test-" + strconv.Itoa(os.Getpid()),
+		Resources: &cgroups.Resources{SkipDevices: true},
+	}
+	m, err := manager.New(cg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Apply(-1); err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { _ = m.Destroy() })
+	return m
+}
+
+func pidsDir(m cgroups.Manager) string {
+	if cgroups.IsCgroup2UnifiedMode() {
+		return m.Path("")
+	}
+	return m.Path("pids")
+}
+
+func TestDryRun(t *testing.T) {
+	m := newManager(t)
+	dir := pidsDir(m)
+
+	writes, err := DryRun(m, &cgroups.Resources{PidsLimit: 42, SkipDevices: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, w := range writes {
+		if w.Error != "" {
+			t.Errorf("unexpected failed write: %+v", w)
+		}
+		if w.Path == filepath.Join(dir, "pids.max") {
+			found = true
+			if w.Value != "42" {
+				t.Errorf("expected value %q, got %q", "42", w.Value)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("no write of pids.max in %+v", writes)
+	}
+
+	// The real cgroup must be left intact, and the scratch one removed.
+	data, err := cgroups.ReadFile(dir, "pids.max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "max\n" {
+		t.Errorf("dry run modified pids.max: %q", data)
+	}
+	matches, _ := filepath.Glob(dir + ".dryrun-*")
+	if len(matches) != 0 {
+		t.Errorf("scratch cgroups left: %v", matches)
+	}
+}
+
+func TestDryRunError(t *testing.T) {
+	m := newManager(t)
+	dir := pidsDir(m)
+
+	// The kernel rejects a limit above PID_MAX_LIMIT.
+	writes, err := DryRun(m, &cgroups.Resources{PidsLimit: 1 << 30, SkipDevices: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) == 0 {
+		t.Fatal("expected a failed write")
+	}
+	last := writes[len(writes)-1]
+	if last.Path != filepath.Join(dir, "pids.max") || last.Error == "" {
+		t.Errorf("expected a failed write of pids.max, got %+v", last)
+	}
+}
+
+func TestManagerRecordsWrites(t *testing.T) {
+	var writes []Write
+	m := New(newManager(t), func(w Write) {
+		writes = append(writes, w)
+	})
+	dir := pidsDir(m)
+	for _, limit := range []int64{42, 43} {
+		if err := m.Set(&cgroups.Resources{PidsLimit: limit, SkipDevices: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var values []string
+	for _, w := range writes {
+		if w.Path == filepath.Join(dir, "pids.max") {
+			values = append(values, w.Value)
+		}
+	}
+	if len(values) != 2 || values[0] != "42" || values[1] != "43" {
+		t.Errorf("expected the writes of 42 and 43 to pids.max, got %+v", writes)
+	}
+}
+
+func TestDryRunSideEffects(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("requires cgroup v2")
+	}
+	m := newManager(t)
+	dir := m.Path("")
+
+	r := &cgroups.Resources{
+		PidsLimit:   42,
+		SkipDevices: true,
+		Unified:     map[string]string{"cpuset.cpus.partition": "root"},
+	}
+	writes, err := DryRun(m, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) == 0 {
+		t.Fatal("expected writes")
+	}
+	last := writes[len(writes)-1]
+	expected := Write{Path: filepath.Join(dir, "cpuset.cpus.partition"), Value: "root", Unchecked: true}
+	if last != expected {
+		t.Errorf("expected %+v, got %+v", expected, last)
+	}
+	if _, ok := r.Unified["cpuset.cpus.partition"]; !ok {
+		t.Error("expected the resources not to be modified")
+	}
+}
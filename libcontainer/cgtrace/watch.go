@@ -0,0 +1,102 @@
+package cgtrace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watcher gets the writes to the files of cgroup directories from their
+// inotify events, which the kernel queues as the writes are done.
+type watcher struct {
+	fd int
+	// dirs maps the watched directories to the cgroup directories their
+	// writes are reported for (the same ones, except for a dry run).
+	dirs map[string]string
+	// wds maps the watch descriptors to the watched directories.
+	wds map[int32]string
+}
+
+// watchMask are the inotify events watched. A write is reported by
+// IN_MODIFY; the open and close events are only watched so that the
+// consecutive writes to a file are not coalesced into a single event.
+const watchMask = unix.IN_MODIFY | unix.IN_OPEN | unix.IN_CLOSE_WRITE
+
+// newWatcher watches the writes to the files of the directories which are
+// the keys of dirs, reported with the paths in the directories which are
+// their values. The directories which do not exist (such as the ones of the
+// controllers a cgroup is not created for) are skipped.
+func newWatcher(dirs map[string]string) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+	w := &watcher{fd: fd, dirs: dirs, wds: make(map[int32]string, len(dirs))}
+	for dir := range dirs {
+		wd, err := unix.InotifyAddWatch(fd, dir, watchMask)
+		if errors.Is(err, unix.ENOENT) {
+			continue
+		}
+		if err != nil {
+			w.close()
+			return nil, &os.PathError{Op: "inotify_add_watch", Path: dir, Err: err}
+		}
+		w.wds[int32(wd)] = dir
+	}
+	return w, nil
+}
+
+func (w *watcher) close() {
+	unix.Close(w.fd)
+}
+
+// writes returns the writes done since the watcher was created, in order,
+// followed by the failed one if setErr is the error of a cgroup file write.
+// The event queue must not overflow (it holds 16384 events by default).
+func (w *watcher) writes(setErr error) ([]Write, error) {
+	var (
+		writes []Write
+		buf    [64 * (unix.SizeofInotifyEvent + unix.NAME_MAX + 1)]byte
+	)
+	for {
+		n, err := unix.Read(w.fd, buf[:])
+		if errors.Is(err, unix.EAGAIN) {
+			break
+		}
+		if err != nil {
+			return writes, os.NewSyscallError("read inotify events", err)
+		}
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameBuf := buf[off+unix.SizeofInotifyEvent : off+unix.SizeofInotifyEvent+int(ev.Len)]
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+			if ev.Mask&unix.IN_Q_OVERFLOW != 0 {
+				return writes, errors.New("inotify event queue overflow")
+			}
+			if ev.Mask&unix.IN_MODIFY == 0 {
+				continue
+			}
+			dir, ok := w.wds[ev.Wd]
+			if !ok {
+				continue
+			}
+			name := unix.ByteSliceToString(nameBuf)
+			// The kernel also reports the changes of the read-only
+			// files (such as cgroup.events) as modifications.
+			if fi, err := os.Stat(filepath.Join(dir, name)); err != nil || fi.Mode().Perm()&0o222 == 0 {
+				continue
+			}
+			writes = append(writes, Write{
+				Path:  filepath.Join(w.dirs[dir], name),
+				Value: readValue(filepath.Join(dir, name)),
+			})
+		}
+	}
+	if fw, ok := w.failedWrite(setErr); ok {
+		writes = append(writes, fw)
+	}
+	return writes, nil
+}
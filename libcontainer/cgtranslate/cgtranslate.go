@@ -0,0 +1,227 @@
+// Package cgtranslate translates the cgroup v1 resources of a runtime spec
+// into their closest cgroup v2 equivalents, easing the migration of the
+// configurations written for cgroup v1.
+//
+// The settings having a cgroup v2 equivalent are moved to the unified
+// resources (linux.resources.unified), with their values converted the same
+// way runc does when it applies them on cgroup v2. The per-device block I/O
+// settings, and the device rules, are kept as is, since runc applies them on
+// cgroup v2 already (as io.weight, io.max, and an eBPF program). The settings
+// with no cgroup v2 equivalent are dropped, and reported as warnings.
+package cgtranslate
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// Warning is a cgroup v1 setting which could not be translated exactly.
+type Warning struct {
+	// Path is the path of the spec field, such as
+	// "linux.resources.memory.kernel".
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return w.Path + ": " + w.Message
+}
+
+// translator holds the state of a translation.
+type translator struct {
+	unified  map[string]string
+	warnings []Warning
+}
+
+func (t *translator) warn(path, format string, args ...any) {
+	t.warnings = append(t.warnings, Warning{Path: "linux.resources." + path, Message: fmt.Sprintf(format, args...)})
+}
+
+// set sets the unified resource file to value, unless it is set already.
+func (t *translator) set(path, file, value string) {
+	if v, ok := t.unified[file]; ok {
+		if v != value {
+			t.warn(path, "dropped, as %s is set to %q in unified already", file, v)
+		}
+		return
+	}
+	t.unified[file] = value
+}
+
+// unlimited formats v as a limit, a negative value meaning no limit.
+func unlimited(v int64) string {
+	if v < 0 {
+		return "max"
+	}
+	return strconv.FormatInt(v, 10)
+}
+
+// Resources returns the cgroup v2 translation of r, along with the warnings
+// about the settings which could not be translated exactly. r is left
+// unchanged.
+func Resources(r *specs.LinuxResources) (*specs.LinuxResources, []Warning) {
+	if r == nil {
+		return nil, nil
+	}
+	t := &translator{unified: make(map[string]string, len(r.Unified))}
+	for k, v := range r.Unified {
+		t.unified[k] = v
+	}
+	out := *r
+	out.Memory = t.memory(r.Memory)
+	out.CPU = t.cpu(r.CPU)
+	out.BlockIO = t.blockIO(r.BlockIO)
+	if p := r.Pids; p != nil {
+		// As with runc, 0 means no limit is set.
+		if p.Limit != 0 {
+			t.set("pids.limit", "pids.max", unlimited(p.Limit))
+		}
+		out.Pids = nil
+	}
+	if len(r.HugepageLimits) > 0 {
+		for i, h := range r.HugepageLimits {
+			t.set(fmt.Sprintf("hugepageLimits[%d]", i), "hugetlb."+h.Pagesize+".max", strconv.FormatUint(h.Limit, 10))
+		}
+		out.HugepageLimits = nil
+	}
+	if n := r.Network; n != nil {
+		if n.ClassID != nil {
+			t.warn("network.classID", "dropped, as the net_cls controller has no cgroup v2 equivalent")
+		}
+		if len(n.Priorities) > 0 {
+			t.warn("network.priorities", "dropped, as the net_prio controller has no cgroup v2 equivalent")
+		}
+		out.Network = nil
+	}
+	if len(t.unified) > 0 {
+		out.Unified = t.unified
+	}
+	return &out, t.warnings
+}
+
+func (t *translator) memory(m *specs.LinuxMemory) *specs.LinuxMemory {
+	if m == nil {
+		return nil
+	}
+	if m.Limit != nil && *m.Limit != 0 {
+		t.set("memory.limit", "memory.max", unlimited(*m.Limit))
+	}
+	if m.Reservation != nil && *m.Reservation != 0 {
+		t.set("memory.reservation", "memory.low", unlimited(*m.Reservation))
+	}
+	if m.Swap != nil {
+		var limit int64
+		if m.Limit != nil {
+			limit = *m.Limit
+		}
+		// The cgroup v1 swap limit is of memory+swap, rather than of swap.
+		swap, err := cgroups.ConvertMemorySwapToCgroupV2Value(*m.Swap, limit)
+		if err != nil {
+			t.warn("memory.swap", "dropped: %v", err)
+		} else if swap != 0 {
+			t.set("memory.swap", "memory.swap.max", unlimited(swap))
+		}
+	}
+	if m.Kernel != nil { //nolint:staticcheck // Ignore SA1019. The deprecated fields are the ones translated.
+		t.warn("memory.kernel", "dropped, as the kernel memory is accounted with the user memory (memory.max) on cgroup v2")
+	}
+	if m.KernelTCP != nil { //nolint:staticcheck // Ignore SA1019.
+		t.warn("memory.kernelTCP", "dropped, as the TCP buffer memory is accounted with the user memory (memory.max) on cgroup v2")
+	}
+	if m.Swappiness != nil {
+		t.warn("memory.swappiness", "dropped, as there is no per-cgroup swappiness on cgroup v2")
+	}
+	if m.DisableOOMKiller != nil && *m.DisableOOMKiller {
+		t.warn("memory.disableOOMKiller", "dropped, as the OOM killer can't be disabled on cgroup v2")
+	}
+	if m.UseHierarchy != nil && !*m.UseHierarchy {
+		t.warn("memory.useHierarchy", "dropped, as the memory accounting is always hierarchical on cgroup v2")
+	}
+	if m.CheckBeforeUpdate == nil {
+		return nil
+	}
+	// Not a cgroup v1 setting.
+	return &specs.LinuxMemory{CheckBeforeUpdate: m.CheckBeforeUpdate}
+}
+
+func (t *translator) cpu(c *specs.LinuxCPU) *specs.LinuxCPU {
+	if c == nil {
+		return nil
+	}
+	if c.Shares != nil {
+		if w := cgroups.ConvertCPUSharesToCgroupV2Value(*c.Shares); w != 0 {
+			t.set("cpu.shares", "cpu.weight", strconv.FormatUint(w, 10))
+		}
+	}
+	var quota int64
+	if c.Quota != nil {
+		quota = *c.Quota
+	}
+	var period uint64
+	if c.Period != nil {
+		period = *c.Period
+	}
+	if quota != 0 || period != 0 {
+		limit := "max"
+		if quota > 0 {
+			limit = strconv.FormatInt(quota, 10)
+		}
+		if period == 0 {
+			period = 100000
+		}
+		t.set("cpu.quota", "cpu.max", limit+" "+strconv.FormatUint(period, 10))
+	}
+	if c.Burst != nil {
+		t.set("cpu.burst", "cpu.max.burst", strconv.FormatUint(*c.Burst, 10))
+	}
+	if c.Idle != nil {
+		t.set("cpu.idle", "cpu.idle", strconv.FormatInt(*c.Idle, 10))
+	}
+	if c.Cpus != "" {
+		t.set("cpu.cpus", "cpuset.cpus", c.Cpus)
+	}
+	if c.Mems != "" {
+		t.set("cpu.mems", "cpuset.mems", c.Mems)
+	}
+	if c.RealtimeRuntime != nil || c.RealtimePeriod != nil {
+		t.warn("cpu.realtimeRuntime", "dropped, as the realtime CPU bandwidth can't be set per cgroup on cgroup v2")
+	}
+	return nil
+}
+
+func (t *translator) blockIO(b *specs.LinuxBlockIO) *specs.LinuxBlockIO {
+	if b == nil {
+		return nil
+	}
+	if b.Weight != nil {
+		if w := cgroups.ConvertBlkIOToIOWeightValue(*b.Weight); w != 0 {
+			t.set("blockIO.weight", "io.weight", strconv.FormatUint(w, 10))
+		}
+	}
+	if b.LeafWeight != nil {
+		t.warn("blockIO.leafWeight", "dropped, as there are no leaf weights on cgroup v2")
+	}
+	out := &specs.LinuxBlockIO{
+		ThrottleReadBpsDevice:   b.ThrottleReadBpsDevice,
+		ThrottleWriteBpsDevice:  b.ThrottleWriteBpsDevice,
+		ThrottleReadIOPSDevice:  b.ThrottleReadIOPSDevice,
+		ThrottleWriteIOPSDevice: b.ThrottleWriteIOPSDevice,
+	}
+	for i, d := range b.WeightDevice {
+		if d.LeafWeight != nil {
+			t.warn(fmt.Sprintf("blockIO.weightDevice[%d].leafWeight", i), "dropped, as there are no leaf weights on cgroup v2")
+			d.LeafWeight = nil
+		}
+		if d.Weight != nil {
+			out.WeightDevice = append(out.WeightDevice, d)
+		}
+	}
+	if out.WeightDevice == nil && out.ThrottleReadBpsDevice == nil && out.ThrottleWriteBpsDevice == nil &&
+		out.ThrottleReadIOPSDevice == nil && out.ThrottleWriteIOPSDevice == nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package cgtranslate
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestResources(t *testing.T) {
+	limit, swap, kernel := int64(1<<30), int64(3<<30), int64(1<<20)
+	shares, quota, period := uint64(1024), int64(-1), uint64(50000)
+	weight, leafWeight := uint16(1000), uint16(10)
+	swappiness := uint64(60)
+	r := &specs.LinuxResources{
+		Memory: &specs.LinuxMemory{
+			Limit:      &limit,
+			Swap:       &swap,
+			Kernel:     &kernel,
+			Swappiness: &swappiness,
+		},
+		CPU: &specs.LinuxCPU{Shares: &shares, Quota: &quota, Period: &period, Mems: "0"},
+		BlockIO: &specs.LinuxBlockIO{
+			Weight:       &weight,
+			WeightDevice: []specs.LinuxWeightDevice{{Weight: &weight, LeafWeight: &leafWeight}},
+		},
+		Pids:           &specs.LinuxPids{Limit: -1},
+		HugepageLimits: []specs.LinuxHugepageLimit{{Pagesize: "2MB", Limit: 1 << 21}},
+		Unified:        map[string]string{"cpu.weight": "50"},
+	}
+	out, warnings := Resources(r)
+
+	expected := map[string]string{
+		"memory.max":      "1073741824",
+		"memory.swap.max": "2147483648",
+		"cpu.weight":      "50",
+		"cpu.max":         "max 50000",
+		"cpuset.mems":     "0",
+		"io.weight":       "10000",
+		"pids.max":        "max",
+		"hugetlb.2MB.max": "2097152",
+	}
+	if !maps.Equal(out.Unified, expected) {
+		t.Errorf("expected unified %v, got %v", expected, out.Unified)
+	}
+	if out.Memory != nil || out.CPU != nil || out.Pids != nil || out.HugepageLimits != nil {
+		t.Errorf("expected the translated settings to be unset, got %+v", out)
+	}
+	if b := out.BlockIO; b == nil || len(b.WeightDevice) != 1 || b.WeightDevice[0].LeafWeight != nil {
+		t.Errorf("expected the weight device to be kept without its leaf weight, got %+v", b)
+	}
+
+	var paths []string
+	for _, w := range warnings {
+		paths = append(paths, w.Path)
+	}
+	expectedPaths := []string{
+		"linux.resources.memory.kernel",
+		"linux.resources.memory.swappiness",
+		"linux.resources.cpu.shares",
+		"linux.resources.blockIO.weightDevice[0].leafWeight",
+	}
+	if !slices.Equal(paths, expectedPaths) {
+		t.Errorf("expected warnings about %v, got %v", expectedPaths, warnings)
+	}
+
+	// r is left unchanged.
+	if r.Memory.Limit == nil || r.BlockIO.WeightDevice[0].LeafWeight == nil || len(r.Unified) != 1 {
+		t.Errorf("the resources were modified: %+v", r)
+	}
+}
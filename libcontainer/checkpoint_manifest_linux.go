@@ -0,0 +1,146 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// CheckpointManifestFilename is the name of the checkpoint manifest file in
+// the images directory, see [Container.CheckpointManifest].
+const CheckpointManifestFilename = "checkpoint-manifest.json"
+
+// CheckpointManifest lists the dependencies of a checkpointed container on
+// the host, which the host the container is restored on must provide: the
+// bind mount sources and the device nodes (restored by criu as external
+// mounts), and the namespaces the container has joined.
+type CheckpointManifest struct {
+	ID         string              `json:"id"`
+	Mounts     []ManifestMount     `json:"mounts"`
+	Devices    []ManifestDevice    `json:"devices"`
+	Namespaces []ManifestNamespace `json:"namespaces"`
+}
+
+// ManifestMount is a bind mount of a [CheckpointManifest].
+type ManifestMount struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	// Kind is "directory" or "file" (for any other kind of file), as found
+	// on the checkpointed host, or an empty string if the source was not
+	// found.
+	Kind string `json:"kind,omitempty"`
+}
+
+// ManifestDevice is a device node of a [CheckpointManifest].
+type ManifestDevice struct {
+	Path string `json:"path"`
+	// Type is "c" for a character device, or "b" for a block device.
+	Type  string `json:"type"`
+	Major int64  `json:"major"`
+	Minor int64  `json:"minor"`
+}
+
+// ManifestNamespace is a namespace joined by the container, of a
+// [CheckpointManifest].
+type ManifestNamespace struct {
+	Type string `json:"type"`
+	Path string `json:"path"`
+}
+
+// CheckpointManifest returns the manifest of the host dependencies of the
+// container, to check a host before the container checkpoint is restored on
+// it (see [CheckpointManifest.Check]).
+func (c *Container) CheckpointManifest() *CheckpointManifest {
+	c.m.Lock()
+	defer c.m.Unlock()
+	m := &CheckpointManifest{
+		ID:         c.id,
+		Mounts:     []ManifestMount{},
+		Devices:    []ManifestDevice{},
+		Namespaces: []ManifestNamespace{},
+	}
+	for _, mnt := range c.config.Mounts {
+		if !mnt.IsBind() {
+			continue
+		}
+		mm := ManifestMount{
+			Source:      mnt.Source,
+			Destination: strings.TrimPrefix(mnt.Destination, c.config.Rootfs),
+		}
+		if fi, err := os.Stat(mnt.Source); err == nil {
+			mm.Kind = fileKind(fi)
+		}
+		m.Mounts = append(m.Mounts, mm)
+	}
+	for _, d := range c.config.Devices {
+		m.Devices = append(m.Devices, ManifestDevice{
+			Path:  d.Path,
+			Type:  string(d.Type),
+			Major: d.Major,
+			Minor: d.Minor,
+		})
+	}
+	for _, ns := range c.config.Namespaces {
+		if ns.Path != "" {
+			m.Namespaces = append(m.Namespaces, ManifestNamespace{
+				Type: configs.NsName(ns.Type),
+				Path: ns.Path,
+			})
+		}
+	}
+	return m
+}
+
+func fileKind(fi os.FileInfo) string {
+	if fi.IsDir() {
+		return "directory"
+	}
+	return "file"
+}
+
+// Check checks that the current host provides the dependencies of the
+// manifest, and returns all the missing ones, joined. The bind mount
+// sources are checked to exist, with the same kind; the device nodes to
+// exist, with the same type and numbers; and the namespaces to exist.
+func (m *CheckpointManifest) Check() error {
+	var errs []error
+	for _, mnt := range m.Mounts {
+		fi, err := os.Stat(mnt.Source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mount %s: %w", mnt.Destination, err))
+			continue
+		}
+		if mnt.Kind != "" && fileKind(fi) != mnt.Kind {
+			errs = append(errs, fmt.Errorf("mount %s: source %s is not a %s", mnt.Destination, mnt.Source, mnt.Kind))
+		}
+	}
+	for _, d := range m.Devices {
+		var st unix.Stat_t
+		if err := unix.Stat(d.Path, &st); err != nil {
+			errs = append(errs, fmt.Errorf("device %s: %w", d.Path, &os.PathError{Op: "stat", Path: d.Path, Err: err}))
+			continue
+		}
+		typ := ""
+		switch st.Mode & unix.S_IFMT {
+		case unix.S_IFCHR:
+			typ = "c"
+		case unix.S_IFBLK:
+			typ = "b"
+		}
+		major, minor := int64(unix.Major(st.Rdev)), int64(unix.Minor(st.Rdev))
+		if typ != d.Type || major != d.Major || minor != d.Minor {
+			errs = append(errs, fmt.Errorf("device %s: expected %s %d:%d", d.Path, d.Type, d.Major, d.Minor))
+		}
+	}
+	for _, ns := range m.Namespaces {
+		if _, err := os.Stat(ns.Path); err != nil {
+			errs = append(errs, fmt.Errorf("%s namespace: %w", ns.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,72 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devices "github.com/opencontainers/cgroups/devices/config"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestCheckpointManifest(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := &Container{
+		id: "myid",
+		config: &configs.Config{
+			Rootfs: "/rootfs",
+			Mounts: []*configs.Mount{
+				{Source: "proc", Destination: "/proc", Device: "proc"},
+				{Source: dir, Destination: "/rootfs/data", Device: "bind", Flags: unix.MS_BIND},
+				{Source: file, Destination: "/etc/file", Device: "bind", Flags: unix.MS_BIND},
+			},
+			Devices: []*devices.Device{
+				{Path: "/dev/null", Rule: devices.Rule{Type: devices.CharDevice, Major: 1, Minor: 3}},
+			},
+			Namespaces: configs.Namespaces{
+				{Type: configs.NEWNS},
+				{Type: configs.NEWNET, Path: "/proc/self/ns/net"},
+			},
+		},
+	}
+	m := c.CheckpointManifest()
+	if m.ID != "myid" {
+		t.Errorf("expected id myid, got %q", m.ID)
+	}
+	expectedMounts := []ManifestMount{
+		{Source: dir, Destination: "/data", Kind: "directory"},
+		{Source: file, Destination: "/etc/file", Kind: "file"},
+	}
+	if len(m.Mounts) != len(expectedMounts) || m.Mounts[0] != expectedMounts[0] || m.Mounts[1] != expectedMounts[1] {
+		t.Errorf("expected mounts %+v, got %+v", expectedMounts, m.Mounts)
+	}
+	if len(m.Devices) != 1 || m.Devices[0] != (ManifestDevice{Path: "/dev/null", Type: "c", Major: 1, Minor: 3}) {
+		t.Errorf("unexpected devices %+v", m.Devices)
+	}
+	if len(m.Namespaces) != 1 || m.Namespaces[0] != (ManifestNamespace{Type: "net", Path: "/proc/self/ns/net"}) {
+		t.Errorf("unexpected namespaces %+v", m.Namespaces)
+	}
+	if err := m.Check(); err != nil {
+		t.Errorf("expected the host to provide the dependencies, got %v", err)
+	}
+
+	// Break all the dependencies.
+	m.Mounts[0].Kind = "file"
+	m.Mounts[1].Source = filepath.Join(dir, "missing")
+	m.Devices[0].Minor = 5
+	m.Namespaces[0].Path = "/proc/self/ns/missing"
+	err := m.Check()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := len(strings.Split(err.Error(), "\n")); n != 4 {
+		t.Errorf("expected 4 errors, got %d: %v", n, err)
+	}
+}
@@ -0,0 +1,39 @@
+package configs
+
+import "strings"
+
+// Annotation returns the value of the OCI spec annotation key, and whether
+// it is set.
+func (c *Config) Annotation(key string) (string, bool) {
+	v, ok := c.Annotations[key]
+	return v, ok
+}
+
+// Bundle returns the path of the OCI bundle the container was created from,
+// which is recorded in the "bundle" label, or "" if there is none.
+func (c *Config) Bundle() string {
+	for _, l := range c.Labels {
+		if v, ok := strings.CutPrefix(l, "bundle="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// AnnotationsFromLabels returns the annotations encoded as "key=value" in
+// labels (other than the "bundle" label), for the configurations created
+// before Annotations was added, or nil if there are none.
+func AnnotationsFromLabels(labels []string) map[string]string {
+	var annotations map[string]string
+	for _, l := range labels {
+		k, v, ok := strings.Cut(l, "=")
+		if !ok || k == "bundle" {
+			continue
+		}
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[k] = v
+	}
+	return annotations
+}
@@ -0,0 +1,33 @@
+package configs
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestAnnotationsFromLabels(t *testing.T) {
+	labels := []string{"bundle=/run/bundle", "com.example.key=a=b", "org.example.empty=", "invalid"}
+	expected := map[string]string{"com.example.key": "a=b", "org.example.empty": ""}
+	if a := AnnotationsFromLabels(labels); !maps.Equal(a, expected) {
+		t.Errorf("expected %v, got %v", expected, a)
+	}
+	if a := AnnotationsFromLabels([]string{"bundle=/run/bundle"}); a != nil {
+		t.Errorf("expected nil, got %v", a)
+	}
+}
+
+func TestConfigAnnotation(t *testing.T) {
+	c := &Config{
+		Labels:      []string{"a=b=c", "bundle=/run/bundle"},
+		Annotations: map[string]string{"a=b": "c"},
+	}
+	if v, ok := c.Annotation("a=b"); !ok || v != "c" {
+		t.Errorf("expected annotation a=b to be c, got %q (set: %v)", v, ok)
+	}
+	if _, ok := c.Annotation("a"); ok {
+		t.Error("expected annotation a not to be set")
+	}
+	if b := c.Bundle(); b != "/run/bundle" {
+		t.Errorf("expected bundle /run/bundle, got %q", b)
+	}
+}
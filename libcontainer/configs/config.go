@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -19,6 +20,9 @@ import (
 	"github.com/opencontainers/cgroups"
 	devices "github.com/opencontainers/cgroups/devices/config"
 	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/faultinject"
+	"github.com/opencontainers/runc/libcontainer/sched"
 )
 
 type Rlimit struct {
@@ -115,6 +119,10 @@ type Config struct {
 	// Specifies the mount propagation flags to be applied to /.
 	RootPropagation int `json:"rootPropagation,omitempty"`
 
+	// PropagationPaths are the mount propagation flags to be applied to
+	// paths in the container, after those of RootPropagation, in order.
+	PropagationPaths []PropagationPath `json:"propagation_paths,omitempty"`
+
 	// Mounts specify additional source and destination paths that will be mounted inside the container's
 	// rootfs and mount namespace if specified.
 	Mounts []*Mount `json:"mounts"`
@@ -137,6 +145,12 @@ type Config struct {
 	// If a namespace is not provided that namespace is shared from the container's parent process.
 	Namespaces Namespaces `json:"namespaces"`
 
+	// AllowUnknownNamespaces allows Namespaces to have namespace types which
+	// are unknown to runc, so that new kernel namespaces can be used before
+	// runc supports them. The type of such a namespace is the name of its
+	// /proc/[pid]/ns file, and its clone flag is obtained from the kernel.
+	AllowUnknownNamespaces bool `json:"allow_unknown_namespaces,omitempty"`
+
 	// Capabilities specify the capabilities to keep when executing the process inside the container
 	// All capabilities not specified will be dropped from the processes capability mask.
 	Capabilities *Capabilities `json:"capabilities,omitempty"`
@@ -193,6 +207,12 @@ type Config struct {
 	// A default action to be taken if no rules match is also given.
 	Seccomp *Seccomp `json:"seccomp,omitempty"`
 
+	// ExecSeccomp is the list of the seccomp filters added once the
+	// container is created (see the AddExecSeccomp method of the container),
+	// which are only installed (after Seccomp, or the process one) into the
+	// processes executed in the container afterwards, not into the init.
+	ExecSeccomp []*Seccomp `json:"exec_seccomp,omitempty"`
+
 	// NoNewPrivileges controls whether processes in the container can gain additional privileges.
 	NoNewPrivileges bool `json:"no_new_privileges,omitempty"`
 
@@ -203,13 +223,42 @@ type Config struct {
 	// Version is the version of opencontainer specification that is supported.
 	Version string `json:"version"`
 
-	// Labels are user defined metadata that is stored in the config and populated on the state
+	// Labels are user defined metadata that is stored in the config and populated on the state.
+	// They include the OCI spec annotations, as "key=value" labels, for
+	// compatibility; Annotations is to be used instead.
 	Labels []string `json:"labels"`
 
+	// Annotations are the OCI spec annotations of the container, which are
+	// also in the OCI state passed to the hooks.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// SpecDigest is the digest of the OCI spec file (config.json) the
+	// container was created from, in the "sha256:<hex>" format.
+	SpecDigest string `json:"spec_digest,omitempty"`
+
+	// StrictSpec makes the container start fail if any of the cgroup
+	// resources can not be applied (such as those of a cgroup v1 controller
+	// which is not mounted, or in the rootless cgroups mode), or if the
+	// io.cost settings are not supported by the kernel, rather than those
+	// being ignored with a warning.
+	StrictSpec bool `json:"strict_spec,omitempty"`
+
+	// HelperCgroup is the name of the sub-cgroup of the container cgroup
+	// which the helper processes runc runs on behalf of the container (such
+	// as the criu lazy-pages daemon) are moved into, so that those are
+	// accounted to the container (and subject to its cgroup limits). On
+	// cgroup v1, only the accounting controllers (such as memory, cpu, and
+	// pids) are used. If empty, the helpers are not moved.
+	HelperCgroup string `json:"helper_cgroup,omitempty"`
+
 	// NoNewKeyring will not allocated a new session keyring for the container.  It will use the
 	// callers keyring in this case.
 	NoNewKeyring bool `json:"no_new_keyring,omitempty"`
 
+	// Keyring is the configuration of the session keyring of the container,
+	// unless NoNewKeyring is set.
+	Keyring *Keyring `json:"keyring,omitempty"`
+
 	// IntelRdt specifies settings for Intel RDT group that the container is placed into
 	// to limit the resources (e.g., L3 cache, memory bandwidth) the container has available
 	IntelRdt *IntelRdt `json:"intel_rdt,omitempty"`
@@ -238,65 +287,203 @@ type Config struct {
 
 	// ExecCPUAffinity is CPU affinity for a non-init process to be run in the container.
 	ExecCPUAffinity *CPUAffinity `json:"exec_cpu_affinity,omitempty"`
+
+	// InitCPUAffinity is CPU affinity for the container init process. The
+	// initial affinity applies to runc init, and the final one is set once
+	// the cgroup configuration is applied.
+	InitCPUAffinity *CPUAffinity `json:"init_cpu_affinity,omitempty"`
+
+	// EnvDefaults is the policy of the default environment variables of
+	// the container processes, both the init and the exec ones.
+	EnvDefaults EnvDefaults `json:"env_defaults,omitempty"`
+
+	// InitSignals is the signal mask and the ignored signals the container
+	// init process starts with. If nil, the ones runc inherits are reset.
+	InitSignals *InitSignals `json:"init_signals,omitempty"`
+
+	// KeepNetns, if set, makes runc keep the network namespace it creates
+	// for the container (bind-mounted under the root directory) once the
+	// container is destroyed, and reuse it for the next container with the
+	// same ID, along with its network devices (see NetDevices). It is
+	// removed by [libcontainer.RemoveKeptNetns].
+	KeepNetns bool `json:"keep_netns,omitempty"`
+
+	// IRQAffinity, if set, makes runc set the affinity of the interrupts of
+	// the PCI network devices in the container network namespace to the
+	// container cpuset (Cgroups.Resources.CpusetCpus), once the
+	// createRuntime hooks (which may set up the network) are run, until the
+	// container is destroyed.
+	IRQAffinity bool `json:"irq_affinity,omitempty"`
+
+	// SchedCore, if set, makes runc create a core scheduling cookie for the
+	// container init, which is shared by all the container processes, so
+	// that those never run on the SMT siblings of a core at the same time
+	// as the tasks of other containers, or of the host.
+	SchedCore bool `json:"sched_core,omitempty"`
+
+	// StateBlobs, if set, makes runc store the large fields of the
+	// container state (such as Seccomp) in blob files, shared by the
+	// containers with identical configurations, rather than in state.json.
+	// Such a state can't be loaded by the runc versions without blob
+	// support.
+	StateBlobs bool `json:"state_blobs,omitempty"`
+
+	// Shm specifies the size of the container's /dev/shm, and how to handle
+	// the case when /dev/shm is shared with the host or other containers.
+	Shm *Shm `json:"shm,omitempty"`
+
+	// Swap specifies a swap area to be set up for the container.
+	Swap *Swap `json:"swap,omitempty"`
+
+	// CpusetRequest is a symbolic cpuset request (such as "numa:1" or
+	// "cores:4-exclusive"), which is resolved against the host CPU topology
+	// when the container is created. The resolved CPU list is stored in
+	// Cgroups.Resources.CpusetCpus, and, unless set explicitly, the memory
+	// nodes list in Cgroups.Resources.CpusetMems.
+	CpusetRequest string `json:"cpuset_request,omitempty"`
+
+	// MemsMigration is how the memory on the NUMA nodes removed from
+	// Cgroups.Resources.CpusetMems is handled, when the container is
+	// updated.
+	MemsMigration MemsMigration `json:"mems_migration,omitempty"`
+
+	// DelegatePty specifies that the PTYs for the container console and
+	// exec processes are to be allocated from the container's own devpts
+	// instance mounted at /dev/pts, and owned by the container process
+	// user and group. This is useful for user namespace containers, where
+	// the owner of a PTY from a different devpts instance may be unmapped.
+	DelegatePty bool `json:"delegate_pty,omitempty"`
+
+	// Landlock specifies the Landlock LSM ruleset to be enforced for the
+	// container processes.
+	Landlock *Landlock `json:"landlock,omitempty"`
+
+	// ReadonlyCheck, if set, enables the verification that all the mounts
+	// requested to be read-only (including the root filesystem and
+	// ReadonlyPaths) actually are, once the root filesystem is set up.
+	ReadonlyCheck ReadonlyCheck `json:"readonly_check,omitempty"`
+
+	// NamespaceOwnerCheck is the policy of the verification of the owner of
+	// the existing namespaces to join (see Namespace.Owner). It defaults to
+	// NamespaceOwnerCheckFail.
+	NamespaceOwnerCheck NamespaceOwnerCheck `json:"namespace_owner_check,omitempty"`
+
+	// MountPolicy, if set, specifies the mount flags to be enforced on all
+	// bind mounts, regardless of the mount options requested.
+	MountPolicy *MountPolicy `json:"mount_policy,omitempty"`
+
+	// Tmpfiles are the files, directories, symlinks, and device nodes to be
+	// provisioned in the root filesystem, in order, once the mounts are set
+	// up (but before the pivot_root).
+	Tmpfiles []*Tmpfile `json:"tmpfiles,omitempty"`
+
+	// SysfsWritable is a list of paths under /sys which are writable, while
+	// the rest of the /sys mount is read-only. Each path is bind mounted
+	// onto itself read-write, once all the mounts are set up.
+	SysfsWritable []string `json:"sysfs_writable,omitempty"`
+
+	// ExecLimits, if set, limits the number and rate of the processes
+	// executed in the container.
+	ExecLimits *ExecLimits `json:"exec_limits,omitempty"`
+
+	// Probes are the readiness and liveness probes of the container,
+	// which are run by "runc probe".
+	Probes []*Probe `json:"probes,omitempty"`
+
+	// NetSysctl is a map of the network namespace sysctls, set as soon as
+	// the container network namespace is created (by runc, it can't be an
+	// existing one), before the network interfaces are configured and the
+	// hooks are run. The general Sysctl map is only set later on.
+	NetSysctl map[string]string `json:"net_sysctl,omitempty"`
+
+	// IOCost, if set, is the cgroup v2 blk-iocost configuration of the
+	// block devices. As it is set in the root cgroup, it applies to all the
+	// cgroups, until the container is destroyed and the previous parameters
+	// of the devices are restored.
+	IOCost *IOCost `json:"io_cost,omitempty"`
+
+	// PowerHint, if set, is the power management hint of the container,
+	// which requests a performance (or a power saving) bias for it.
+	PowerHint *PowerHint `json:"power_hint,omitempty"`
+
+	// RootfsQuota, if set, limits the disk usage of the root filesystem,
+	// with a project quota set up when the container is created.
+	RootfsQuota *RootfsQuota `json:"rootfs_quota,omitempty"`
+
+	// MachineID, if set, is the machine ID of the container (32 lowercase
+	// hexadecimal characters), bind mounted read-only over /etc/machine-id
+	// in its root filesystem, rather than the one of the image.
+	MachineID string `json:"machine_id,omitempty"`
+
+	// VirtualBootID, if set, makes the container see a boot ID of its own,
+	// generated each time it is started, in /proc/sys/kernel/random/boot_id,
+	// rather than the host one.
+	VirtualBootID bool `json:"virtual_boot_id,omitempty"`
+
+	// SignalForwarding, if set, is the policy of the signals forwarded to
+	// the container processes run in the foreground.
+	SignalForwarding *SignalForwarding `json:"signal_forwarding,omitempty"`
+
+	// UsernsAuto, if set, means that UIDMappings and GIDMappings map an
+	// ID range automatically allocated from the host-wide pool, which is
+	// released when the container is destroyed.
+	UsernsAuto bool `json:"userns_auto,omitempty"`
+
+	// IPCLimits, if set, are the IPC limits of the container IPC namespace,
+	// set before the Sysctl ones.
+	IPCLimits *IPCLimits `json:"ipc_limits,omitempty"`
+
+	// MemoryPolicy, if set, is the NUMA memory policy of the container
+	// init and exec processes.
+	MemoryPolicy *MemoryPolicy `json:"memory_policy,omitempty"`
 }
 
+// MountPolicy is a set of mount flags enforced on the bind mounts.
+type MountPolicy struct {
+	// NoSuid enforces the nosuid flag.
+	NoSuid bool `json:"nosuid,omitempty"`
+	// NoDev enforces the nodev flag.
+	NoDev bool `json:"nodev,omitempty"`
+	// NoExec enforces the noexec flag.
+	NoExec bool `json:"noexec,omitempty"`
+	// Allow is the list of mount destinations exempt from the policy.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// ReadonlyCheck is the policy of the read-only mounts verification.
+type ReadonlyCheck string
+
+const (
+	// ReadonlyCheckWarn logs a warning for each mount which is requested to
+	// be read-only but is not.
+	ReadonlyCheckWarn ReadonlyCheck = "warn"
+
+	// ReadonlyCheckFail makes the container start fail if any mount which is
+	// requested to be read-only is not.
+	ReadonlyCheckFail ReadonlyCheck = "fail"
+)
+
+// NamespaceOwnerCheck is the policy of the namespace owner verification.
+type NamespaceOwnerCheck string
+
+const (
+	// NamespaceOwnerCheckWarn logs a warning for each namespace to join
+	// which is not owned as expected, and joins it anyway.
+	NamespaceOwnerCheckWarn NamespaceOwnerCheck = "warn"
+
+	// NamespaceOwnerCheckFail makes the container start fail if any
+	// namespace to join is not owned as expected.
+	NamespaceOwnerCheckFail NamespaceOwnerCheck = "fail"
+)
+
 // Scheduler is based on the Linux sched_setattr(2) syscall.
 type Scheduler = specs.Scheduler
 
 // ToSchedAttr is to convert *configs.Scheduler to *unix.SchedAttr.
+//
+// Deprecated: use [sched.ToSchedAttr].
 func ToSchedAttr(scheduler *Scheduler) (*unix.SchedAttr, error) {
-	var policy uint32
-	switch scheduler.Policy {
-	case specs.SchedOther:
-		policy = 0
-	case specs.SchedFIFO:
-		policy = 1
-	case specs.SchedRR:
-		policy = 2
-	case specs.SchedBatch:
-		policy = 3
-	case specs.SchedISO:
-		policy = 4
-	case specs.SchedIdle:
-		policy = 5
-	case specs.SchedDeadline:
-		policy = 6
-	default:
-		return nil, fmt.Errorf("invalid scheduler policy: %s", scheduler.Policy)
-	}
-
-	var flags uint64
-	for _, flag := range scheduler.Flags {
-		switch flag {
-		case specs.SchedFlagResetOnFork:
-			flags |= 0x01
-		case specs.SchedFlagReclaim:
-			flags |= 0x02
-		case specs.SchedFlagDLOverrun:
-			flags |= 0x04
-		case specs.SchedFlagKeepPolicy:
-			flags |= 0x08
-		case specs.SchedFlagKeepParams:
-			flags |= 0x10
-		case specs.SchedFlagUtilClampMin:
-			flags |= 0x20
-		case specs.SchedFlagUtilClampMax:
-			flags |= 0x40
-		default:
-			return nil, fmt.Errorf("invalid scheduler flag: %s", flag)
-		}
-	}
-
-	return &unix.SchedAttr{
-		Size:     unix.SizeofSchedAttr,
-		Policy:   policy,
-		Flags:    flags,
-		Nice:     scheduler.Nice,
-		Priority: uint32(scheduler.Priority),
-		Runtime:  scheduler.Runtime,
-		Deadline: scheduler.Deadline,
-		Period:   scheduler.Period,
-	}, nil
+	return sched.ToSchedAttr(scheduler)
 }
 
 type IOPriority = specs.LinuxIOPriority
@@ -519,16 +706,140 @@ func (hooks *Hooks) MarshalJSON() ([]byte, error) {
 	})
 }
 
-// Run executes all hooks for the given hook name.
-func (hooks Hooks) Run(name HookName, state *specs.State) error {
+// HookResult is the result of running a hook.
+type HookResult struct {
+	Name  HookName `json:"name"`
+	Index int      `json:"index"`
+	// Path is the path of the hook command, or an empty string if the hook
+	// is not a command (see [FuncHook]).
+	Path     string        `json:"path,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Attempts is the number of times the hook was run, which is more than
+	// one if it was retried (see [Command.Retries]).
+	Attempts int `json:"attempts"`
+	// ExitCode is the exit code of the hook command (of its last attempt),
+	// or -1 if it has not exited (such as if it timed out), or if the hook
+	// is not a command and failed.
+	ExitCode int `json:"exitCode"`
+	// Stdout and Stderr are the output of the hook command (of its last
+	// attempt).
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	// Err is the error of the hook (of its last attempt), if it failed.
+	Err error `json:"-"`
+}
+
+// Run executes all hooks for the given hook name, and returns the result of
+// each hook which was run, in order. The consecutive hooks with
+// [Command.Parallel] set are run concurrently, and the failed hooks are
+// retried as set by [Command.Retries].
+//
+// An error is returned for the first hook which has failed, unless it has
+// [Command.ContinueOnError] set, after which no more hooks are run.
+//
+// The hooks with [Command.Async] set are skipped (see [Hooks.StartAsync]).
+func (hooks Hooks) Run(name HookName, state *specs.State) ([]HookResult, error) {
 	list := hooks[name]
-	for i, h := range list {
-		if err := h.Run(state); err != nil {
-			return fmt.Errorf("error running %s hook #%d: %w", name, i, err)
+	results := make([]HookResult, 0, len(list))
+	for start := 0; start < len(list); {
+		if isAsync(list[start]) {
+			start++
+			continue
 		}
+		// A group of parallel hooks, or a single hook.
+		end := start + 1
+		if isParallel(list[start]) {
+			for end < len(list) && isParallel(list[end]) {
+				end++
+			}
+		}
+		group := make([]HookResult, end-start)
+		var wg sync.WaitGroup
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := faultinject.Check(faultinject.Hook(string(name), i)); err != nil {
+					group[i-start] = HookResult{Err: err, ExitCode: -1, Attempts: 1}
+				} else {
+					group[i-start] = runHook(list[i], state)
+				}
+				group[i-start].Name, group[i-start].Index = name, i
+			}()
+		}
+		wg.Wait()
+		results = append(results, group...)
+
+		for i, res := range group {
+			logResult(res)
+			if res.Err != nil && !continueOnError(list[start+i]) {
+				return results, fmt.Errorf("error running %s hook #%d: %w", name, res.Index, res.Err)
+			}
+		}
+		start = end
 	}
 
-	return nil
+	return results, nil
+}
+
+// isParallel returns whether h can run concurrently with its neighbours.
+func isParallel(h Hook) bool {
+	ch, ok := h.(CommandHook)
+	return ok && ch.Command != nil && ch.Parallel
+}
+
+// continueOnError returns whether the failure of h is ignored.
+func continueOnError(h Hook) bool {
+	ch, ok := h.(CommandHook)
+	return ok && ch.Command != nil && ch.ContinueOnError
+}
+
+// runHook runs h, retrying it if it is a command which fails.
+func runHook(h Hook, state *specs.State) HookResult {
+	ch, ok := h.(CommandHook)
+	if !ok || ch.Command == nil {
+		start := time.Now()
+		res := HookResult{Attempts: 1}
+		res.Err = h.Run(state)
+		res.Duration = time.Since(start)
+		if res.Err != nil {
+			res.ExitCode = -1
+		}
+		return res
+	}
+	var res HookResult
+	var elapsed time.Duration
+	for attempt := 1; ; attempt++ {
+		res = ch.run(state)
+		elapsed += res.Duration
+		if res.Err == nil || attempt > ch.Retries {
+			res.Attempts = attempt
+			break
+		}
+		logrus.WithError(res.Err).Debugf("hook %s failed (attempt %d of %d), retrying", ch.Path, attempt, ch.Retries+1)
+	}
+	res.Duration = elapsed
+	return res
+}
+
+// logResult logs the result of a hook, with a warning if the hook failed.
+func logResult(res HookResult) {
+	log := logrus.WithFields(logrus.Fields{
+		"hook":      res.Name,
+		"index":     res.Index,
+		"path":      res.Path,
+		"duration":  res.Duration,
+		"attempts":  res.Attempts,
+		"exit_code": res.ExitCode,
+	})
+	if res.Err == nil {
+		log.Debug("hook completed")
+		return
+	}
+	log.WithFields(logrus.Fields{
+		"stdout": res.Stdout,
+		"stderr": res.Stderr,
+	}).WithError(res.Err).Warn("hook failed")
 }
 
 // SetDefaultEnv sets the environment for those CommandHook entries
@@ -541,6 +852,17 @@ func (hooks HookList) SetDefaultEnv(env []string) {
 	}
 }
 
+// SetClock sets the clock of all the CommandHook entries.
+func (hooks Hooks) SetClock(clk Clock) {
+	for _, list := range hooks {
+		for _, h := range list {
+			if ch, ok := h.(CommandHook); ok && ch.Command != nil {
+				ch.Clock = clk
+			}
+		}
+	}
+}
+
 type Hook interface {
 	// Run executes the hook with the provided state.
 	Run(*specs.State) error
@@ -567,6 +889,44 @@ type Command struct {
 	Env     []string       `json:"env"`
 	Dir     string         `json:"dir"`
 	Timeout *time.Duration `json:"timeout"`
+	// Parallel makes the hook run concurrently with the adjacent hooks of
+	// the same type which have Parallel set (see [Hooks.Run]).
+	Parallel bool `json:"parallel,omitempty"`
+	// Retries is the number of times the hook is run again if it fails.
+	// The timeout applies to each attempt.
+	Retries int `json:"retries,omitempty"`
+	// ContinueOnError makes the failure of the hook logged, rather than
+	// failing the container operation.
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+	// Async makes the hook started by [Hooks.StartAsync] rather than run
+	// by [Hooks.Run], so that it runs while the container is created, which
+	// is only completed once the hook is ready (see [AsyncHook]). The
+	// timeout applies to the hook readiness.
+	Async bool `json:"async,omitempty"`
+	// Clock is used to measure the hook duration, and enforce its timeout.
+	// If nil, the system clock is used.
+	Clock Clock `json:"-"`
+}
+
+// Clock is a time source, which can be replaced by a fake one in tests.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel receiving the current time once d has
+	// elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the [Clock] of the system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (c *Command) clock() Clock {
+	if c.Clock == nil {
+		return systemClock{}
+	}
+	return c.Clock
 }
 
 // NewCommandHook will execute the provided command when the hook is run.
@@ -581,9 +941,20 @@ type CommandHook struct {
 }
 
 func (c *Command) Run(s *specs.State) error {
+	return c.run(s).Err
+}
+
+// run runs the hook command once, and returns its result.
+func (c *Command) run(s *specs.State) (res HookResult) {
+	res = HookResult{Path: c.Path, Attempts: 1, ExitCode: -1}
+	clk := c.clock()
+	start := clk.Now()
+	defer func() { res.Duration = clk.Now().Sub(start) }()
+
 	b, err := json.Marshal(s)
 	if err != nil {
-		return err
+		res.Err = err
+		return res
 	}
 	var stdout, stderr bytes.Buffer
 	cmd := exec.Cmd{
@@ -595,28 +966,30 @@ func (c *Command) Run(s *specs.State) error {
 		Stderr: &stderr,
 	}
 	if err := cmd.Start(); err != nil {
-		return err
+		res.Err = err
+		return res
 	}
 	errC := make(chan error, 1)
 	go func() {
-		err := cmd.Wait()
-		if err != nil {
-			err = fmt.Errorf("%w, stdout: %s, stderr: %s", err, stdout.String(), stderr.String())
-		}
-		errC <- err
+		errC <- cmd.Wait()
 	}()
 	var timerCh <-chan time.Time
 	if c.Timeout != nil {
-		timer := time.NewTimer(*c.Timeout)
-		defer timer.Stop()
-		timerCh = timer.C
+		timerCh = clk.After(*c.Timeout)
 	}
 	select {
-	case err := <-errC:
-		return err
+	case err = <-errC:
+		if err != nil {
+			err = fmt.Errorf("%w, stdout: %s, stderr: %s", err, stdout.String(), stderr.String())
+		}
 	case <-timerCh:
 		_ = cmd.Process.Kill()
 		<-errC
-		return fmt.Errorf("hook ran past specified timeout of %.1fs", c.Timeout.Seconds())
+		err = fmt.Errorf("hook ran past specified timeout of %.1fs", c.Timeout.Seconds())
+	}
+	if err == nil || cmd.ProcessState.Exited() {
+		res.ExitCode = cmd.ProcessState.ExitCode()
 	}
+	res.Stdout, res.Stderr, res.Err = stdout.String(), stderr.String(), err
+	return res
 }
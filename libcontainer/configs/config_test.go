@@ -226,3 +226,211 @@ func TestCommandHookRunTimeout(t *testing.T) {
 		t.Error("Expected error to occur but it was nil")
 	}
 }
+
+// fakeClock is a [configs.Clock] whose time only advances when its timers
+// are fired.
+type fakeClock struct {
+	now    time.Time
+	timers chan chan time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.timers <- ch
+	return ch
+}
+
+func TestCommandHookRunClock(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0), timers: make(chan chan time.Time, 1)}
+	timeout := time.Hour
+	hooks := configs.Hooks{
+		configs.CreateRuntime: configs.HookList{configs.NewCommandHook(&configs.Command{
+			Path:    "/bin/sleep",
+			Args:    []string{"/bin/sleep", "10"},
+			Timeout: &timeout,
+		})},
+	}
+	hooks.SetClock(clk)
+
+	go func() {
+		// Fire the timeout of the hook, an hour later.
+		timer := <-clk.timers
+		clk.now = clk.now.Add(timeout)
+		timer <- clk.now
+	}()
+	start := time.Now()
+	res, err := hooks.Run(configs.CreateRuntime, &specs.State{})
+	if err == nil {
+		t.Fatal("expected a timeout error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the fake clock was not used: the hook ran for %s", elapsed)
+	}
+	if len(res) != 1 || res[0].Duration != timeout {
+		t.Errorf("expected the hook to run for %s, got %+v", timeout, res)
+	}
+}
+
+func writeHookScript(t *testing.T, script string) string {
+	t.Helper()
+	filename := t.TempDir() + "/hook.sh"
+	if err := os.WriteFile(filename, []byte("#!/bin/sh\n"+script), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestHooksRunParallel(t *testing.T) {
+	marker := t.TempDir() + "/marker"
+	timeout := 5 * time.Second
+	// The first hook waits for the second one, so they can only succeed
+	// if run concurrently.
+	wait := writeHookScript(t, "while [ ! -e "+marker+" ]; do sleep 0.01; done\n")
+	touch := writeHookScript(t, "echo done > "+marker+"\n")
+	hooks := configs.Hooks{
+		configs.Poststart: configs.HookList{
+			configs.NewCommandHook(&configs.Command{Path: wait, Args: []string{wait}, Timeout: &timeout, Parallel: true}),
+			configs.NewCommandHook(&configs.Command{Path: touch, Args: []string{touch}, Parallel: true}),
+		},
+	}
+	results, err := hooks.Run(configs.Poststart, &specs.State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	for i, res := range results {
+		if res.Name != configs.Poststart || res.Index != i || res.ExitCode != 0 || res.Attempts != 1 {
+			t.Errorf("unexpected result %+v", res)
+		}
+	}
+}
+
+func TestHooksRunRetries(t *testing.T) {
+	counter := t.TempDir() + "/counter"
+	// Fails on the first two attempts.
+	hook := writeHookScript(t, "echo x >> "+counter+"\n[ $(wc -l < "+counter+") -ge 3 ] || { echo failing >&2; exit 3; }\n")
+	cmd := &configs.Command{Path: hook, Args: []string{hook}, Retries: 1}
+	hooks := configs.Hooks{configs.CreateRuntime: configs.HookList{configs.NewCommandHook(cmd)}}
+
+	results, err := hooks.Run(configs.CreateRuntime, &specs.State{})
+	if err == nil {
+		t.Fatal("expected an error after 2 attempts")
+	}
+	if len(results) != 1 || results[0].Attempts != 2 || results[0].ExitCode != 3 || results[0].Stderr != "failing\n" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	os.Remove(counter)
+	cmd.Retries = 2
+	results, err = hooks.Run(configs.CreateRuntime, &specs.State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Attempts != 3 || results[0].ExitCode != 0 {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
+
+func TestHooksRunContinueOnError(t *testing.T) {
+	fail := writeHookScript(t, "exit 1\n")
+	hooks := configs.Hooks{
+		configs.Poststop: configs.HookList{
+			configs.NewCommandHook(&configs.Command{Path: fail, Args: []string{fail}, ContinueOnError: true}),
+			configs.NewFunctionHook(func(*specs.State) error { return nil }),
+			configs.NewCommandHook(&configs.Command{Path: fail, Args: []string{fail}}),
+			configs.NewFunctionHook(func(*specs.State) error { return nil }),
+		},
+	}
+	results, err := hooks.Run(configs.Poststop, &specs.State{})
+	if err == nil || err.Error() != "error running poststop hook #2: exit status 1, stdout: , stderr: " {
+		t.Fatalf("expected the error of hook #2, got %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected the hooks to stop running after hook #2, got %+v", results)
+	}
+	if results[0].Err == nil || results[0].ExitCode != 1 || results[1].Err != nil || results[1].Path != "" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
+
+func TestHooksStartAsync(t *testing.T) {
+	out := t.TempDir() + "/state"
+	// Gets the state, tells it is ready, and keeps running.
+	ready := writeHookScript(t, "printf state >&3\ndd bs=4096 count=1 <&3 > "+out+" 2>/dev/null\nprintf ready >&3\nexec sleep 10\n")
+	sync := writeHookScript(t, "exit 0\n")
+	hooks := configs.Hooks{
+		configs.CreateRuntime: configs.HookList{
+			configs.NewCommandHook(&configs.Command{Path: sync, Args: []string{sync}}),
+			configs.NewCommandHook(&configs.Command{Path: ready, Args: []string{ready}, Async: true}),
+		},
+	}
+	state := &specs.State{ID: "async", Status: specs.StateCreating}
+	async, err := hooks.StartAsync(configs.CreateRuntime, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := hooks.Run(configs.CreateRuntime, state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != sync {
+		t.Fatalf("expected the asynchronous hook to be skipped, got %+v", results)
+	}
+	if len(async) != 1 {
+		t.Fatalf("expected 1 asynchronous hook, got %d", len(async))
+	}
+	defer async[0].Kill()
+	res, err := async[0].Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != configs.CreateRuntime || res.Index != 1 || res.ExitCode != -1 {
+		t.Errorf("unexpected result %+v", res)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got specs.State
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("invalid state %q: %v", b, err)
+	}
+	if got.ID != "async" || got.Status != specs.StateCreating {
+		t.Errorf("unexpected state %+v", got)
+	}
+}
+
+func TestHooksStartAsyncFailure(t *testing.T) {
+	timeout := 100 * time.Millisecond
+	fail := writeHookScript(t, "exit 2\n")
+	exit := writeHookScript(t, "exit 0\n")
+	stuck := writeHookScript(t, "exec sleep 10\n")
+	for _, tc := range []struct {
+		cmd      configs.Command
+		isErr    bool
+		exitCode int
+	}{
+		{cmd: configs.Command{Path: fail, Args: []string{fail}}, isErr: true, exitCode: 2},
+		{cmd: configs.Command{Path: fail, Args: []string{fail}, ContinueOnError: true}, exitCode: 2},
+		{cmd: configs.Command{Path: exit, Args: []string{exit}}, exitCode: 0},
+		{cmd: configs.Command{Path: stuck, Args: []string{stuck}, Timeout: &timeout}, isErr: true, exitCode: -1},
+	} {
+		tc.cmd.Async = true
+		hooks := configs.Hooks{configs.Prestart: configs.HookList{configs.NewCommandHook(&tc.cmd)}}
+		async, err := hooks.StartAsync(configs.Prestart, &specs.State{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := async[0].Wait()
+		if tc.isErr != (err != nil) || res.ExitCode != tc.exitCode {
+			t.Errorf("%s: unexpected result %+v (error: %v)", tc.cmd.Path, res, err)
+		}
+		async[0].Kill()
+	}
+}
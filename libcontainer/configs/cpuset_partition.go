@@ -0,0 +1,33 @@
+package configs
+
+// CpusetPartitionFile is the cgroup v2 file setting the cpuset partition
+// type of a cgroup. As the cgroup resources have no field for it, the
+// partition type is set as a unified resource.
+const CpusetPartitionFile = "cpuset.cpus.partition"
+
+// CpusetPartition is a cgroup v2 cpuset partition type.
+type CpusetPartition string
+
+const (
+	// CpusetPartitionMember makes the cgroup a member of the partition of
+	// its parent. This is the default.
+	CpusetPartitionMember CpusetPartition = "member"
+
+	// CpusetPartitionRoot makes the cgroup the root of a partition, with
+	// CPUs exclusive to it.
+	CpusetPartitionRoot CpusetPartition = "root"
+
+	// CpusetPartitionIsolated makes the cgroup the root of a partition,
+	// with CPUs exclusive to it and isolated from the scheduler load
+	// balancing.
+	CpusetPartitionIsolated CpusetPartition = "isolated"
+)
+
+// IsValid reports whether p is a known partition type.
+func (p CpusetPartition) IsValid() bool {
+	switch p {
+	case CpusetPartitionMember, CpusetPartitionRoot, CpusetPartitionIsolated:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package configs
+
+// EnvDefaults is the policy of the default environment variables which runc
+// adds to the environment of the container processes: PATH, HOME (the home
+// directory of the process user in the container /etc/passwd, or "/"), and
+// TERM for a process with a terminal.
+type EnvDefaults string
+
+const (
+	// EnvDefaultsLegacy, the zero value, only adds HOME if it is missing
+	// or empty, as earlier runc versions did.
+	EnvDefaultsLegacy EnvDefaults = ""
+
+	// EnvDefaultsAlways sets the default variables, overriding the ones of
+	// the process environment.
+	EnvDefaultsAlways EnvDefaults = "always"
+
+	// EnvDefaultsIfMissing adds the default variables which are missing or
+	// empty in the process environment.
+	EnvDefaultsIfMissing EnvDefaults = "if-missing"
+
+	// EnvDefaultsNever adds no variable, leaving the process environment
+	// as is.
+	EnvDefaultsNever EnvDefaults = "never"
+)
+
+const (
+	// DefaultPathEnv is the default value of PATH.
+	DefaultPathEnv = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	// DefaultTermEnv is the default value of TERM.
+	DefaultTermEnv = "xterm"
+)
+
+// KnownEnvDefaults returns the names of the policies which can be set.
+func KnownEnvDefaults() []string {
+	return []string{string(EnvDefaultsAlways), string(EnvDefaultsIfMissing), string(EnvDefaultsNever)}
+}
+
+// IsValid reports whether p is a known policy.
+func (p EnvDefaults) IsValid() bool {
+	switch p {
+	case EnvDefaultsLegacy, EnvDefaultsAlways, EnvDefaultsIfMissing, EnvDefaultsNever:
+		return true
+	}
+	return false
+}
+
+// Variables returns the names of the variables which p adds, for a process
+// with a terminal or not.
+func (p EnvDefaults) Variables(terminal bool) []string {
+	switch p {
+	case EnvDefaultsLegacy:
+		return []string{"HOME"}
+	case EnvDefaultsAlways, EnvDefaultsIfMissing:
+		if terminal {
+			return []string{"PATH", "HOME", "TERM"}
+		}
+		return []string{"PATH", "HOME"}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package configs
+
+import "time"
+
+// ExecLimits limits the processes which can be executed in an existing
+// container (as opposed to the container init), such as by "runc exec".
+// A zero value means no limit.
+type ExecLimits struct {
+	// MaxConcurrent is the maximum number of exec processes running at
+	// the same time.
+	MaxConcurrent int `json:"max_concurrent,omitempty"`
+
+	// MaxPerWindow is the maximum number of exec processes started within
+	// any period of Window.
+	MaxPerWindow int `json:"max_per_window,omitempty"`
+
+	// Window is the period of MaxPerWindow.
+	Window time.Duration `json:"window,omitempty"`
+
+	// MaxTotal is the maximum number of exec processes started over the
+	// container lifetime.
+	MaxTotal int `json:"max_total,omitempty"`
+}
@@ -0,0 +1,225 @@
+package configs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// AsyncHookFd is the file descriptor of the socket of an asynchronous hook
+// (see [AsyncHook]), which is also in its RUNC_HOOK_FD environment variable.
+const AsyncHookFd = 3
+
+// AsyncHook is a running asynchronous hook command (see [Command.Async]),
+// started by [Hooks.StartAsync].
+//
+// The hook has a SOCK_SEQPACKET socket as file descriptor 3, and each
+// message it sends on it is a request, which is answered with one message:
+//
+//   - "state" is answered with the current container state, as JSON;
+//   - "ready" tells that the hook is ready, and is answered with "ok".
+//
+// A hook which exits successfully before it has sent "ready" is ready too.
+// The socket is closed once the container is destroyed, or once the runc
+// process exits, whichever comes first, but the hook keeps running.
+type AsyncHook struct {
+	Name  HookName
+	Index int
+
+	cmd     *Command
+	proc    *exec.Cmd
+	conn    *net.UnixConn
+	start   time.Time
+	timeout <-chan time.Time
+	state   atomic.Pointer[specs.State]
+
+	once  sync.Once
+	ready chan struct{}
+	res   HookResult
+}
+
+// isAsync returns whether h is an asynchronous hook command.
+func isAsync(h Hook) bool {
+	ch, ok := h.(CommandHook)
+	return ok && ch.Command != nil && ch.Async
+}
+
+// StartAsync starts the asynchronous hooks of the given type, which
+// [Hooks.Run] skips, and returns them. An error is returned for the first
+// hook which fails to start, unless it has [Command.ContinueOnError] set,
+// and the hooks already started are then killed.
+func (hooks Hooks) StartAsync(name HookName, state *specs.State) ([]*AsyncHook, error) {
+	var started []*AsyncHook
+	for i, h := range hooks[name] {
+		if !isAsync(h) {
+			continue
+		}
+		ch := h.(CommandHook)
+		ah, err := ch.startAsync(name, i, state)
+		if err != nil {
+			res := HookResult{Name: name, Index: i, Path: ch.Path, Attempts: 1, ExitCode: -1, Err: err}
+			logResult(res)
+			if ch.ContinueOnError {
+				continue
+			}
+			for _, ah := range started {
+				ah.Kill()
+			}
+			return nil, fmt.Errorf("error running %s hook #%d: %w", name, i, err)
+		}
+		started = append(started, ah)
+	}
+	return started, nil
+}
+
+func (c *Command) startAsync(name HookName, index int, s *specs.State) (_ *AsyncHook, retErr error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_SEQPACKET|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("socketpair", err)
+	}
+	local := os.NewFile(uintptr(fds[0]), "hook-socket")
+	defer local.Close()
+	remote := os.NewFile(uintptr(fds[1]), "hook-socket")
+	defer remote.Close()
+	fc, err := net.FileConn(local)
+	if err != nil {
+		return nil, err
+	}
+	conn := fc.(*net.UnixConn)
+	defer func() {
+		if retErr != nil {
+			conn.Close()
+		}
+	}()
+
+	clk := c.clock()
+	h := &AsyncHook{
+		Name:  name,
+		Index: index,
+		cmd:   c,
+		conn:  conn,
+		start: clk.Now(),
+		ready: make(chan struct{}),
+		proc: &exec.Cmd{
+			Path:       c.Path,
+			Args:       c.Args,
+			Env:        append(slices.Clip(c.Env), "RUNC_HOOK_FD="+strconv.Itoa(AsyncHookFd)),
+			Stdin:      bytes.NewReader(b),
+			ExtraFiles: []*os.File{remote},
+		},
+	}
+	h.state.Store(s)
+	if c.Timeout != nil {
+		h.timeout = clk.After(*c.Timeout)
+	}
+	if err := h.proc.Start(); err != nil {
+		return nil, err
+	}
+	go h.serve()
+	go func() {
+		err := h.proc.Wait()
+		if err != nil {
+			err = fmt.Errorf("hook exited before it was ready: %w", err)
+		}
+		h.setReady(err, h.proc.ProcessState.ExitCode())
+	}()
+	return h, nil
+}
+
+// serve answers the requests of the hook, until the socket is closed.
+func (h *AsyncHook) serve() {
+	buf := make([]byte, 256)
+	for {
+		n, err := h.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		var reply []byte
+		switch string(bytes.TrimSpace(buf[:n])) {
+		case "state":
+			reply, err = json.Marshal(h.state.Load())
+			if err != nil {
+				reply = []byte("error: " + err.Error())
+			}
+		case "ready":
+			h.setReady(nil, -1)
+			reply = []byte("ok")
+		default:
+			reply = []byte("error: unknown request")
+		}
+		if _, err := h.conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+// setReady records the readiness of the hook, or its failure, once. The
+// exit code is that of the hook if it has exited, or -1.
+func (h *AsyncHook) setReady(err error, exitCode int) {
+	h.once.Do(func() {
+		h.res = HookResult{
+			Name:     h.Name,
+			Index:    h.Index,
+			Path:     h.cmd.Path,
+			Duration: h.cmd.clock().Now().Sub(h.start),
+			Attempts: 1,
+			ExitCode: exitCode,
+			Err:      err,
+		}
+		close(h.ready)
+	})
+}
+
+// SetState sets the container state which the hook gets for its "state"
+// requests.
+func (h *AsyncHook) SetState(s *specs.State) {
+	h.state.Store(s)
+}
+
+// Wait waits until the hook is ready, or has failed, and returns its
+// result, where Duration is the time it took the hook to be ready. The
+// hook is killed if it is not ready before its timeout. An error is
+// returned if the hook has failed, unless it has
+// [Command.ContinueOnError] set.
+func (h *AsyncHook) Wait() (HookResult, error) {
+	select {
+	case <-h.ready:
+	case <-h.timeout:
+		h.setReady(fmt.Errorf("hook was not ready within the specified timeout of %.1fs", h.cmd.Timeout.Seconds()), -1)
+		h.Kill()
+	}
+	res := h.res
+	logResult(res)
+	if res.Err != nil && !h.cmd.ContinueOnError {
+		return res, fmt.Errorf("error running %s hook #%d: %w", h.Name, h.Index, res.Err)
+	}
+	return res, nil
+}
+
+// Kill kills the hook, and closes its socket.
+func (h *AsyncHook) Kill() {
+	h.setReady(errors.New("hook was killed"), -1)
+	_ = h.proc.Process.Kill()
+	h.Close()
+}
+
+// Close closes the hook socket, leaving the hook running.
+func (h *AsyncHook) Close() {
+	h.conn.Close()
+}
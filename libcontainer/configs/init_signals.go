@@ -0,0 +1,21 @@
+package configs
+
+import "golang.org/x/sys/unix"
+
+// InitSignals is the signal configuration the container init process starts
+// with, that is, with which the container process is executed.
+//
+// Unless Inherit is set, the signal mask and the ignored signals which runc
+// inherits from its caller (such as an ignored SIGPIPE) are reset, so that
+// only Blocked are blocked, and only Ignored are ignored.
+type InitSignals struct {
+	// Blocked are the signals blocked in the signal mask.
+	Blocked []unix.Signal `json:"blocked,omitempty"`
+
+	// Ignored are the signals whose disposition is set to be ignored.
+	Ignored []unix.Signal `json:"ignored,omitempty"`
+
+	// Inherit keeps the signal mask and the ignored signals runc inherits,
+	// in addition to Blocked and Ignored.
+	Inherit bool `json:"inherit,omitempty"`
+}
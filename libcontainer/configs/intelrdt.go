@@ -13,4 +13,24 @@ type IntelRdt struct {
 	// The unit of memory bandwidth is specified in "percentages" by
 	// default, and in "MBps" if MBA Software Controller is enabled.
 	MemBwSchema string `json:"memBwSchema,omitempty"`
+
+	// EnableCMT and EnableMBM enable the Intel RDT cache monitoring
+	// (CMT) and memory bandwidth monitoring (MBM) of the container, using
+	// a monitoring group of its own in the clos group.
+	EnableCMT bool `json:"enableCMT,omitempty"`
+	EnableMBM bool `json:"enableMBM,omitempty"`
+
+	// Shared makes the ClosID clos group shared by the containers which
+	// use it: it is created (if it does not exist) for the first one, and
+	// removed along with the last one, and each container has a monitoring
+	// group of its own in it, if the monitoring is supported. The schemas
+	// apply to the whole group, so they should be the same for all the
+	// containers.
+	Shared bool `json:"shared,omitempty"`
+}
+
+// Monitoring reports whether the Intel RDT monitoring of the container is
+// enabled.
+func (r *IntelRdt) Monitoring() bool {
+	return r.EnableCMT || r.EnableMBM
 }
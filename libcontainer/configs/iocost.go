@@ -0,0 +1,86 @@
+package configs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The cgroup v2 blk-iocost files. They only exist in the root cgroup, so the
+// parameters set in these apply to all the cgroups doing IO on the device.
+const (
+	IOCostQoSFile   = "io.cost.qos"
+	IOCostModelFile = "io.cost.model"
+)
+
+var (
+	// IOCostQoSParams are the parameters of the io.cost.qos file.
+	IOCostQoSParams = []string{"enable", "ctrl", "rpct", "rlat", "wpct", "wlat", "min", "max"}
+	// IOCostModelParams are the parameters of the io.cost.model file.
+	IOCostModelParams = []string{"ctrl", "model", "rbps", "rseqiops", "rrandiops", "wbps", "wseqiops", "wrandiops"}
+)
+
+// IOCost is the cgroup v2 blk-iocost configuration, which enables the
+// work-conserving proportional IO control (according to io.weight) of the
+// block devices.
+type IOCost struct {
+	// QoS are the io.cost.qos parameters of the block devices.
+	QoS []*IOCostDevice `json:"qos,omitempty"`
+	// Model are the io.cost.model parameters of the block devices.
+	Model []*IOCostDevice `json:"model,omitempty"`
+}
+
+// IOCostDevice are the blk-iocost parameters (in io.cost.qos or
+// io.cost.model) of a block device.
+type IOCostDevice struct {
+	Major  int64             `json:"major"`
+	Minor  int64             `json:"minor"`
+	Params map[string]string `json:"params"`
+}
+
+// String returns the parameters in the io.cost.qos and io.cost.model
+// format, i.e. "MAJ:MIN key=value ...".
+func (d *IOCostDevice) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d:%d", d.Major, d.Minor)
+	keys := make([]string, 0, len(d.Params))
+	for k := range d.Params {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		b.WriteString(" " + k + "=" + d.Params[k])
+	}
+	return b.String()
+}
+
+// ParseIOCostDevice parses blk-iocost parameters in the io.cost.qos and
+// io.cost.model format (see [IOCostDevice.String]). The parameter names are
+// not checked.
+func ParseIOCostDevice(v string) (*IOCostDevice, error) {
+	fields := strings.Fields(v)
+	if len(fields) < 2 {
+		return nil, fmt.Errorf("invalid io.cost parameters %q: must be 'major:minor key=value ...'", v)
+	}
+	major, minor, ok := strings.Cut(fields[0], ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid io.cost device %q: must be 'major:minor'", fields[0])
+	}
+	d := &IOCostDevice{Params: map[string]string{}}
+	var err error
+	if d.Major, err = strconv.ParseInt(major, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid io.cost device %q: %w", fields[0], err)
+	}
+	if d.Minor, err = strconv.ParseInt(minor, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid io.cost device %q: %w", fields[0], err)
+	}
+	for _, p := range fields[1:] {
+		key, val, ok := strings.Cut(p, "=")
+		if !ok || key == "" || val == "" {
+			return nil, fmt.Errorf("invalid io.cost parameter %q: must be 'key=value'", p)
+		}
+		d.Params[key] = val
+	}
+	return d, nil
+}
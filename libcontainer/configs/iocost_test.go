@@ -0,0 +1,32 @@
+package configs
+
+import "testing"
+
+func TestParseIOCostDevice(t *testing.T) {
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{in: "8:0 enable=1 ctrl=user rpct=95 rlat=5000", out: "8:0 ctrl=user enable=1 rlat=5000 rpct=95"},
+		{in: "  259:16\tmodel=linear rbps=1000000 ", out: "259:16 model=linear rbps=1000000"},
+		{in: "8:0"},
+		{in: "8 enable=1"},
+		{in: "8:x enable=1"},
+		{in: "8:0 enable"},
+		{in: "8:0 enable="},
+	} {
+		d, err := ParseIOCostDevice(tc.in)
+		if tc.out == "" {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tc.in, d)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if s := d.String(); s != tc.out {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.out, s)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package configs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IPCLimits are the System V IPC and POSIX message queue limits of the
+// container IPC namespace, set once it is created, as a structured (and
+// validated) alternative to the kernel.msg*, kernel.sem, kernel.shm*, and
+// fs.mqueue.* sysctls. A zero value leaves the namespace default.
+type IPCLimits struct {
+	// MsgMax, MsgMnb, and MsgMni are the maximum size of a System V
+	// message, the maximum size of a message queue (both in bytes), and
+	// the maximum number of message queues (kernel.msgmax, kernel.msgmnb,
+	// and kernel.msgmni).
+	MsgMax uint64 `json:"msgmax,omitempty"`
+	MsgMnb uint64 `json:"msgmnb,omitempty"`
+	MsgMni uint64 `json:"msgmni,omitempty"`
+
+	// ShmMax, ShmAll, and ShmMni are the maximum size of a System V shared
+	// memory segment (in bytes), the maximum total size of the segments
+	// (in pages), and the maximum number of segments (kernel.shmmax,
+	// kernel.shmall, and kernel.shmmni). ShmRmidForced makes the segments
+	// be destroyed once they are no longer used (kernel.shm_rmid_forced).
+	ShmMax        uint64 `json:"shmmax,omitempty"`
+	ShmAll        uint64 `json:"shmall,omitempty"`
+	ShmMni        uint64 `json:"shmmni,omitempty"`
+	ShmRmidForced bool   `json:"shm_rmid_forced,omitempty"`
+
+	// SemMsl, SemMns, SemOpm, and SemMni are the maximum number of
+	// semaphores per set, the maximum number of semaphores, the maximum
+	// number of operations per semop call, and the maximum number of
+	// semaphore sets (the kernel.sem fields).
+	SemMsl uint64 `json:"semmsl,omitempty"`
+	SemMns uint64 `json:"semmns,omitempty"`
+	SemOpm uint64 `json:"semopm,omitempty"`
+	SemMni uint64 `json:"semmni,omitempty"`
+
+	// MqueueMsgMax, MqueueMsgSizeMax, and MqueueQueuesMax are the maximum
+	// number of messages in a POSIX message queue, the maximum size of a
+	// message (in bytes), and the maximum number of queues, while
+	// MqueueMsgDefault and MqueueMsgSizeDefault are the defaults used when
+	// a queue is created without attributes (fs.mqueue.msg_max,
+	// fs.mqueue.msgsize_max, fs.mqueue.queues_max, fs.mqueue.msg_default,
+	// and fs.mqueue.msgsize_default).
+	MqueueMsgMax         uint64 `json:"mqueue_msg_max,omitempty"`
+	MqueueMsgSizeMax     uint64 `json:"mqueue_msgsize_max,omitempty"`
+	MqueueQueuesMax      uint64 `json:"mqueue_queues_max,omitempty"`
+	MqueueMsgDefault     uint64 `json:"mqueue_msg_default,omitempty"`
+	MqueueMsgSizeDefault uint64 `json:"mqueue_msgsize_default,omitempty"`
+}
+
+// SemSysctl is the sysctl of the semaphore limits (see [IPCLimits.Sem]).
+const SemSysctl = "kernel.sem"
+
+// Sysctls returns the key and value pairs of the sysctls setting the
+// limits, other than the semaphore ones, which are set together (see Sem),
+// in the order they are to be set (the message queue defaults last, as
+// those can't exceed the maximums).
+func (l *IPCLimits) Sysctls() [][2]string {
+	var sysctls [][2]string
+	for _, s := range []struct {
+		key   string
+		value uint64
+	}{
+		{"kernel.msgmax", l.MsgMax},
+		{"kernel.msgmnb", l.MsgMnb},
+		{"kernel.msgmni", l.MsgMni},
+		{"kernel.shmmax", l.ShmMax},
+		{"kernel.shmall", l.ShmAll},
+		{"kernel.shmmni", l.ShmMni},
+		{"fs.mqueue.msg_max", l.MqueueMsgMax},
+		{"fs.mqueue.msgsize_max", l.MqueueMsgSizeMax},
+		{"fs.mqueue.queues_max", l.MqueueQueuesMax},
+		{"fs.mqueue.msg_default", l.MqueueMsgDefault},
+		{"fs.mqueue.msgsize_default", l.MqueueMsgSizeDefault},
+	} {
+		if s.value != 0 {
+			sysctls = append(sysctls, [2]string{s.key, strconv.FormatUint(s.value, 10)})
+		}
+	}
+	if l.ShmRmidForced {
+		sysctls = append(sysctls, [2]string{"kernel.shm_rmid_forced", "1"})
+	}
+	return sysctls
+}
+
+// HasSem returns whether any of the semaphore limits is set.
+func (l *IPCLimits) HasSem() bool {
+	return l.SemMsl != 0 || l.SemMns != 0 || l.SemOpm != 0 || l.SemMni != 0
+}
+
+// Sem returns the kernel.sem value setting the semaphore limits, with the
+// ones which are not set kept from current, the current kernel.sem value.
+func (l *IPCLimits) Sem(current string) (string, error) {
+	fields := strings.Fields(current)
+	if len(fields) != 4 {
+		return "", fmt.Errorf("invalid %s value %q", SemSysctl, current)
+	}
+	for i, v := range []uint64{l.SemMsl, l.SemMns, l.SemOpm, l.SemMni} {
+		if v != 0 {
+			fields[i] = strconv.FormatUint(v, 10)
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
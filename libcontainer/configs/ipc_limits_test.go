@@ -0,0 +1,31 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIPCLimitsSysctls(t *testing.T) {
+	l := &IPCLimits{MsgMax: 65536, ShmRmidForced: true, MqueueMsgMax: 100, SemMni: 256}
+	expected := [][2]string{
+		{"kernel.msgmax", "65536"},
+		{"fs.mqueue.msg_max", "100"},
+		{"kernel.shm_rmid_forced", "1"},
+	}
+	if s := l.Sysctls(); !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %v, got %v", expected, s)
+	}
+	if !l.HasSem() {
+		t.Error("expected semaphore limits")
+	}
+	sem, err := l.Sem("32000\t1024000000\t500\t32000\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sem != "32000 1024000000 500 256" {
+		t.Errorf("unexpected kernel.sem value %q", sem)
+	}
+	if _, err := l.Sem("1 2 3"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,47 @@
+package configs
+
+// Keyring is the configuration of the session keyring of the container
+// processes, which is only created if NoNewKeyring is not set.
+type Keyring struct {
+	// Name is the name of the session keyring, which the exec processes
+	// join too, after the "_ses." prefix. Containers with the same name
+	// share the keyring. If empty, the container ID is used.
+	Name string `json:"name,omitempty"`
+
+	// Links are the host keys linked into the session keyring when the
+	// container is created, such as Kerberos credentials or fscrypt keys.
+	Links []KeyLink `json:"links,omitempty"`
+
+	// Quota, if set, is the minimum per-user key quota of the host.
+	Quota *KeyringQuota `json:"quota,omitempty"`
+}
+
+// KeyLink is a host key, searched for in the keyrings of the runc caller
+// (its session keyring, then its user keyring).
+type KeyLink struct {
+	// Type is the key type, such as "user" or "logon".
+	Type string `json:"type"`
+	// Description is the key description, such as "fscrypt:<descriptor>".
+	Description string `json:"description"`
+}
+
+// KeyringQuota is a per-user key quota. The kernel quotas are host-wide,
+// and apply to each user (root has its own ones): runc raises them to these
+// values if they are lower, and never lowers them.
+type KeyringQuota struct {
+	// MaxKeys is the maximum number of keys of a user
+	// (kernel.keys.maxkeys and kernel.keys.root_maxkeys).
+	MaxKeys uint32 `json:"maxkeys,omitempty"`
+	// MaxBytes is the maximum size of the key payloads of a user
+	// (kernel.keys.maxbytes and kernel.keys.root_maxbytes).
+	MaxBytes uint32 `json:"maxbytes,omitempty"`
+}
+
+// SessionName returns the name of the session keyring of the container
+// id.
+func (k *Keyring) SessionName(id string) string {
+	if k != nil && k.Name != "" {
+		return "_ses." + k.Name
+	}
+	return "_ses." + id
+}
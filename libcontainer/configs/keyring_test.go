@@ -0,0 +1,14 @@
+package configs
+
+import "testing"
+
+func TestKeyringSessionName(t *testing.T) {
+	var k *Keyring
+	if name := k.SessionName("ctr"); name != "_ses.ctr" {
+		t.Errorf("expected the default name, got %q", name)
+	}
+	k = &Keyring{Name: "krb5"}
+	if name := k.SessionName("ctr"); name != "_ses.krb5" {
+		t.Errorf("expected _ses.krb5, got %q", name)
+	}
+}
@@ -0,0 +1,37 @@
+package configs
+
+// Landlock is the Landlock LSM configuration for the container processes.
+// Access rights are specified by their names, without the LANDLOCK_ACCESS_FS_
+// or LANDLOCK_ACCESS_NET_ prefix, in lower case (e.g. "read_file").
+type Landlock struct {
+	// HandledAccessFS is the list of filesystem access rights which are
+	// denied unless allowed by a PathBeneath rule.
+	HandledAccessFS []string `json:"handled_access_fs,omitempty"`
+
+	// HandledAccessNetwork is the list of network access rights which are
+	// denied unless allowed by a NetPort rule.
+	HandledAccessNetwork []string `json:"handled_access_network,omitempty"`
+
+	// PathBeneath rules allow filesystem access to file hierarchies.
+	PathBeneath []LandlockPathBeneath `json:"path_beneath,omitempty"`
+
+	// NetPort rules allow network access to TCP ports.
+	NetPort []LandlockNetPort `json:"net_port,omitempty"`
+
+	// DisableBestEffort makes it an error if the kernel does not support
+	// Landlock, or some of the access rights used. By default, unsupported
+	// access rights are ignored.
+	DisableBestEffort bool `json:"disable_best_effort,omitempty"`
+}
+
+// LandlockPathBeneath allows access to the file hierarchies of Paths.
+type LandlockPathBeneath struct {
+	AllowedAccess []string `json:"allowed_access"`
+	Paths         []string `json:"paths"`
+}
+
+// LandlockNetPort allows network access to Ports.
+type LandlockNetPort struct {
+	AllowedAccess []string `json:"allowed_access"`
+	Ports         []uint64 `json:"ports"`
+}
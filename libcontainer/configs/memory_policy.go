@@ -0,0 +1,86 @@
+package configs
+
+import "fmt"
+
+// The memory policy modes and flags (see set_mempolicy(2)).
+const (
+	MPOL_DEFAULT = iota //nolint:golint,revive // ignore "don't use ALL_CAPS" warning
+	MPOL_PREFERRED
+	MPOL_BIND
+	MPOL_INTERLEAVE
+	MPOL_LOCAL
+	MPOL_PREFERRED_MANY
+	MPOL_WEIGHTED_INTERLEAVE
+
+	MPOL_F_NUMA_BALANCING = 1 << 13 //nolint:golint,revive // ignore "don't use ALL_CAPS" warning
+	MPOL_F_RELATIVE_NODES = 1 << 14
+	MPOL_F_STATIC_NODES   = 1 << 15
+)
+
+var (
+	memoryPolicyModes = map[string]int{
+		"MPOL_DEFAULT":             MPOL_DEFAULT,
+		"MPOL_PREFERRED":           MPOL_PREFERRED,
+		"MPOL_BIND":                MPOL_BIND,
+		"MPOL_INTERLEAVE":          MPOL_INTERLEAVE,
+		"MPOL_LOCAL":               MPOL_LOCAL,
+		"MPOL_PREFERRED_MANY":      MPOL_PREFERRED_MANY,
+		"MPOL_WEIGHTED_INTERLEAVE": MPOL_WEIGHTED_INTERLEAVE,
+	}
+	memoryPolicyFlags = map[string]int{
+		"MPOL_F_NUMA_BALANCING": MPOL_F_NUMA_BALANCING,
+		"MPOL_F_RELATIVE_NODES": MPOL_F_RELATIVE_NODES,
+		"MPOL_F_STATIC_NODES":   MPOL_F_STATIC_NODES,
+	}
+)
+
+// MemoryPolicy is the NUMA memory policy of the container processes, set
+// with set_mempolicy(2) before they are executed, and inherited by their
+// children. It mirrors the linux.memoryPolicy proposal of the OCI runtime
+// spec.
+type MemoryPolicy struct {
+	// Mode is the policy mode (one of the MPOL_* modes).
+	Mode int `json:"mode"`
+	// Nodes are the NUMA nodes of the policy.
+	Nodes []int `json:"nodes,omitempty"`
+	// Flags are the mode flags (the MPOL_F_* flags).
+	Flags int `json:"flags,omitempty"`
+}
+
+// MemoryPolicyMode returns the memory policy mode of the given name (such as
+// "MPOL_BIND").
+func MemoryPolicyMode(name string) (int, error) {
+	mode, ok := memoryPolicyModes[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid memory policy mode %q", name)
+	}
+	return mode, nil
+}
+
+// MemoryPolicyFlag returns the memory policy flag of the given name (such as
+// "MPOL_F_STATIC_NODES").
+func MemoryPolicyFlag(name string) (int, error) {
+	flag, ok := memoryPolicyFlags[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid memory policy flag %q", name)
+	}
+	return flag, nil
+}
+
+// Nodemask returns the nodemask of the policy nodes, as set_mempolicy(2)
+// takes it, and its maxnode argument.
+func (p *MemoryPolicy) Nodemask() ([]uint64, uint64) {
+	if len(p.Nodes) == 0 {
+		return nil, 0
+	}
+	maxNode := 0
+	for _, n := range p.Nodes {
+		maxNode = max(maxNode, n)
+	}
+	mask := make([]uint64, maxNode/64+1)
+	for _, n := range p.Nodes {
+		mask[n/64] |= 1 << (n % 64)
+	}
+	// The kernel ignores the last bit of maxnode.
+	return mask, uint64(len(mask))*64 + 1
+}
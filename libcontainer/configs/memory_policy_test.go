@@ -0,0 +1,25 @@
+package configs
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMemoryPolicyNodemask(t *testing.T) {
+	for _, tc := range []struct {
+		nodes   []int
+		mask    []uint64
+		maxNode uint64
+	}{
+		{},
+		{nodes: []int{0}, mask: []uint64{1}, maxNode: 65},
+		{nodes: []int{1, 3}, mask: []uint64{0b1010}, maxNode: 65},
+		{nodes: []int{0, 64, 130}, mask: []uint64{1, 1, 4}, maxNode: 193},
+	} {
+		p := &MemoryPolicy{Mode: MPOL_BIND, Nodes: tc.nodes}
+		mask, maxNode := p.Nodemask()
+		if !slices.Equal(mask, tc.mask) || maxNode != tc.maxNode {
+			t.Errorf("%v: expected %v, %d, got %v, %d", tc.nodes, tc.mask, tc.maxNode, mask, maxNode)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package configs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The cgroup v2 files of the swap and zswap controls finer than the swap
+// limit. As the cgroup resources have no fields for them, they are set as
+// unified resources.
+const (
+	// MemorySwapHighFile sets the swap usage throttle limit, above which
+	// the cgroup allocations are throttled rather than failing.
+	MemorySwapHighFile = "memory.swap.high"
+	// MemoryZswapMaxFile sets the limit of the zswap compressed pool usage.
+	MemoryZswapMaxFile = "memory.zswap.max"
+	// MemoryZswapWritebackFile sets whether the pages in the zswap pool
+	// are written back to the swap device ("1", the default), or rather
+	// kept in memory ("0").
+	MemoryZswapWritebackFile = "memory.zswap.writeback"
+)
+
+// ValidateMemorySwap checks the swap and zswap controls of the unified
+// resources, if any.
+func ValidateMemorySwap(unified map[string]string) error {
+	for _, file := range []string{MemorySwapHighFile, MemoryZswapMaxFile} {
+		if v, ok := unified[file]; ok {
+			if v = strings.TrimSpace(v); v == "max" {
+				continue
+			}
+			if _, err := strconv.ParseUint(v, 10, 64); err != nil {
+				return fmt.Errorf("invalid %s value %q (must be a number of bytes or \"max\")", file, unified[file])
+			}
+		}
+	}
+	if v, ok := unified[MemoryZswapWritebackFile]; ok {
+		if v = strings.TrimSpace(v); v != "0" && v != "1" {
+			return fmt.Errorf("invalid %s value %q (must be 0 or 1)", MemoryZswapWritebackFile, unified[MemoryZswapWritebackFile])
+		}
+	}
+	return nil
+}
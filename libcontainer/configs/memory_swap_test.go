@@ -0,0 +1,29 @@
+package configs
+
+import "testing"
+
+func TestValidateMemorySwap(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		isErr   bool
+		unified map[string]string
+	}{
+		{name: "none"},
+		{name: "other", unified: map[string]string{"memory.high": "foo"}},
+		{name: "limits", unified: map[string]string{MemorySwapHighFile: "1048576", MemoryZswapMaxFile: "max\n"}},
+		{name: "writeback", unified: map[string]string{MemoryZswapWritebackFile: "0"}},
+		{name: "negative", isErr: true, unified: map[string]string{MemorySwapHighFile: "-1"}},
+		{name: "units", isErr: true, unified: map[string]string{MemoryZswapMaxFile: "1G"}},
+		{name: "bad writeback", isErr: true, unified: map[string]string{MemoryZswapWritebackFile: "yes"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateMemorySwap(tc.unified)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package configs
+
+// MemsMigration is how the memory of a running container is handled when
+// NUMA memory nodes are removed from its cpuset mems, on update.
+type MemsMigration string
+
+const (
+	// MemsMigrationNone sets the new cpuset mems right away, leaving it to
+	// the kernel to move the memory on the removed nodes (which, on cgroup
+	// v1, it only does with cpuset.memory_migrate set). This is the
+	// default.
+	MemsMigrationNone MemsMigration = "none"
+
+	// MemsMigrationMigrate first moves the memory of the container
+	// processes off the removed nodes, with migrate_pages(2), after
+	// checking that the remaining nodes have enough free memory for it.
+	// Only then are the new cpuset mems set.
+	MemsMigrationMigrate MemsMigration = "migrate"
+
+	// MemsMigrationReclaim is as MemsMigrationMigrate, except that, if the
+	// remaining nodes lack free memory, as much of the container memory as
+	// is missing is reclaimed first (with the cgroup v2 memory.reclaim).
+	MemsMigrationReclaim MemsMigration = "reclaim"
+)
+
+// IsValid reports whether m is a known migration policy, "" being
+// MemsMigrationNone.
+func (m MemsMigration) IsValid() bool {
+	switch m {
+	case "", MemsMigrationNone, MemsMigrationMigrate, MemsMigrationReclaim:
+		return true
+	}
+	return false
+}
@@ -5,3 +5,15 @@ const (
 	// a tmpfs is mounted over it.
 	EXT_COPYUP = 1 << iota //nolint:golint,revive // ignore "don't use ALL_CAPS" warning
 )
+
+// PropagationPath is the mount propagation of a path in the container. As
+// the propagation is a property of the mounts, a path which is not a mount
+// point is bind mounted onto itself first.
+type PropagationPath struct {
+	// Path is the absolute path in the container.
+	Path string `json:"path"`
+	// Flags is one of the MS_SHARED, MS_SLAVE, MS_PRIVATE, and
+	// MS_UNBINDABLE propagation flags, with MS_REC for the mounts under
+	// the path as well.
+	Flags int `json:"flags"`
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strings"
 	"sync"
 )
 
@@ -23,7 +24,8 @@ var (
 	supportedNamespaces = make(map[NamespaceType]bool)
 )
 
-// NsName converts the namespace type to its filename
+// NsName converts the namespace type to its filename. The filename of a
+// namespace type unknown to runc is the type itself.
 func NsName(ns NamespaceType) string {
 	switch ns {
 	case NEWNET:
@@ -43,7 +45,7 @@ func NsName(ns NamespaceType) string {
 	case NEWTIME:
 		return "time"
 	}
-	return ""
+	return string(ns)
 }
 
 // IsNamespaceSupported returns whether a namespace is available or
@@ -56,8 +58,7 @@ func IsNamespaceSupported(ns NamespaceType) bool {
 		return supported
 	}
 	nsFile := NsName(ns)
-	// if the namespace type is unknown, just return false
-	if nsFile == "" {
+	if nsFile == "" || strings.Contains(nsFile, "/") {
 		return false
 	}
 	// We don't need to use /proc/thread-self here because the list of
@@ -70,6 +71,13 @@ func IsNamespaceSupported(ns NamespaceType) bool {
 	return supported
 }
 
+// IsKnownNamespace returns whether ns is one of the namespace types known to
+// runc (see [NamespaceTypes]). The other namespace types can only be used if
+// allowed by [Config.AllowUnknownNamespaces].
+func IsKnownNamespace(ns NamespaceType) bool {
+	return slices.Contains(NamespaceTypes(), ns)
+}
+
 func NamespaceTypes() []NamespaceType {
 	return []NamespaceType{
 		NEWUSER, // Keep user NS always first, don't move it.
@@ -88,6 +96,24 @@ func NamespaceTypes() []NamespaceType {
 type Namespace struct {
 	Type NamespaceType `json:"type"`
 	Path string        `json:"path,omitempty"`
+	// Owner, if set, is the expected ownership of the existing namespace
+	// at Path, checked before joining it according to
+	// Config.NamespaceOwnerCheck.
+	Owner *NamespaceOwner `json:"owner,omitempty"`
+}
+
+// NamespaceOwner is the expected ownership of an existing namespace. The
+// unset fields are not checked.
+type NamespaceOwner struct {
+	// UID is the owner UID of the user namespace owning the namespace, that
+	// is, the effective UID of the process which created that user
+	// namespace. For a user namespace, it is its own owner UID.
+	UID *uint32 `json:"uid,omitempty"`
+	// UsernsInode is the inode number of the user namespace owning the
+	// namespace. For a user namespace, it is that of its parent.
+	UsernsInode uint64 `json:"userns_inode,omitempty"`
+	// Inode is the inode number of the namespace itself.
+	Inode uint64 `json:"inode,omitempty"`
 }
 
 func (n *Namespace) GetPath(pid int) string {
@@ -2,10 +2,36 @@
 
 package configs
 
-import "golang.org/x/sys/unix"
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
 
 func (n *Namespace) Syscall() int {
-	return namespaceInfo[n.Type]
+	if flag, ok := namespaceInfo[n.Type]; ok {
+		return flag
+	}
+	return unknownNamespaceFlag(n.Type)
+}
+
+// unknownNamespaceFlag returns the clone flag of the namespace type ns, which
+// is unknown to runc, as reported by the kernel for its /proc/self/ns file,
+// or 0 if the kernel does not support it.
+func unknownNamespaceFlag(ns NamespaceType) int {
+	if !IsNamespaceSupported(ns) {
+		return 0
+	}
+	f, err := os.Open("/proc/self/ns/" + NsName(ns))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	flag, err := unix.IoctlRetInt(int(f.Fd()), unix.NS_GET_NSTYPE)
+	if err != nil {
+		return 0
+	}
+	return flag
 }
 
 var namespaceInfo = map[NamespaceType]int{
@@ -27,7 +53,7 @@ func (n *Namespaces) CloneFlags() uintptr {
 		if v.Path != "" {
 			continue
 		}
-		flag |= namespaceInfo[v.Type]
+		flag |= v.Syscall()
 	}
 	return uintptr(flag)
 }
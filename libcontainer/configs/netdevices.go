@@ -4,4 +4,20 @@ package configs
 type LinuxNetDevice struct {
 	// Name of the device in the container namespace.
 	Name string `json:"name,omitempty"`
+
+	// MTU of the device in the container namespace, if set.
+	MTU int `json:"mtu,omitempty"`
+
+	// HardwareAddress is the MAC address of the device in the container
+	// namespace, if set.
+	HardwareAddress string `json:"hardware_address,omitempty"`
+
+	// TxQueueLen is the transmit queue length of the device in the
+	// container namespace, if set.
+	TxQueueLen int `json:"txqueuelen,omitempty"`
+
+	// Queues is the number of combined (receive and transmit) queues of
+	// the device, as set by "ethtool -L <dev> combined <n>", if set. It is
+	// not restored when the device leaves the container.
+	Queues uint32 `json:"queues,omitempty"`
 }
@@ -0,0 +1,95 @@
+package configs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The cgroup files of the utilization clamps, see [PowerHint].
+const (
+	UclampMinFile = "cpu.uclamp.min"
+	UclampMaxFile = "cpu.uclamp.max"
+)
+
+// EPPPath returns the path of the energy_performance_preference file of
+// the CPU cpu.
+func EPPPath(cpu string) string {
+	return "/sys/devices/system/cpu/cpu" + cpu + "/cpufreq/energy_performance_preference"
+}
+
+// PowerHint is the power management hint of a container, which requests a
+// performance (or a power saving) bias for it.
+type PowerHint struct {
+	// EPP is the energy_performance_preference of the CPUs of the container
+	// cpuset (such as "performance", "balance_power", or a number from 0
+	// to 255), which the cpufreq driver biases their frequency selection
+	// with. As it applies to the CPUs, rather than to the container tasks,
+	// it is only meaningful with an exclusive cpuset. The previous value is
+	// restored once the container is gone.
+	EPP string `json:"epp,omitempty"`
+	// UclampMin and UclampMax are the cpu.uclamp.min and cpu.uclamp.max
+	// utilization clamps of the container cgroup: a percentage with up to
+	// two decimals, or "max". The scheduler (and the schedutil cpufreq
+	// governor) handle the container tasks as if their utilization was at
+	// least UclampMin, and at most UclampMax.
+	UclampMin string `json:"uclamp_min,omitempty"`
+	UclampMax string `json:"uclamp_max,omitempty"`
+}
+
+// String returns the hint in the format parsed by [ParsePowerHint].
+func (h *PowerHint) String() string {
+	var s []string
+	for _, kv := range []struct{ k, v string }{
+		{"epp", h.EPP},
+		{"uclamp.min", h.UclampMin},
+		{"uclamp.max", h.UclampMax},
+	} {
+		if kv.v != "" {
+			s = append(s, kv.k+"="+kv.v)
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+// ParsePowerHint parses a power hint in the "key=value,..." format, with the
+// epp, uclamp.min, and uclamp.max keys (such as
+// "epp=performance,uclamp.min=50"), on top of base, if not nil. A key with
+// an empty value is unset. It returns nil if no key is set.
+func ParsePowerHint(v string, base *PowerHint) (*PowerHint, error) {
+	var h PowerHint
+	if base != nil {
+		h = *base
+	}
+	for _, kv := range strings.Split(v, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid power hint %q: must be key=value", kv)
+		}
+		switch key {
+		case "epp":
+			if strings.ContainsAny(val, " \t\n/") {
+				return nil, fmt.Errorf("invalid power hint epp %q", val)
+			}
+			h.EPP = val
+		case "uclamp.min", "uclamp.max":
+			if val != "" && val != "max" {
+				p, err := strconv.ParseFloat(val, 64)
+				if err != nil || p < 0 || p > 100 {
+					return nil, fmt.Errorf("invalid power hint %s %q: must be a percentage, or max", key, val)
+				}
+			}
+			if key == "uclamp.min" {
+				h.UclampMin = val
+			} else {
+				h.UclampMax = val
+			}
+		default:
+			return nil, fmt.Errorf("unknown power hint %q", key)
+		}
+	}
+	if h == (PowerHint{}) {
+		return nil, nil
+	}
+	return &h, nil
+}
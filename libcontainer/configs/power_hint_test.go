@@ -0,0 +1,49 @@
+package configs
+
+import "testing"
+
+func TestParsePowerHint(t *testing.T) {
+	base := &PowerHint{EPP: "performance", UclampMin: "50"}
+	for _, tc := range []struct {
+		in   string
+		base *PowerHint
+		out  string
+		err  bool
+	}{
+		{in: "epp=performance", out: "epp=performance"},
+		{in: " uclamp.min=12.5, uclamp.max=max ", out: "uclamp.min=12.5,uclamp.max=max"},
+		{in: "uclamp.max=80", base: base, out: "epp=performance,uclamp.min=50,uclamp.max=80"},
+		{in: "epp=,uclamp.min=", base: base, out: ""},
+		{in: "epp=balance_power", base: base, out: "epp=balance_power,uclamp.min=50"},
+		{in: "epp", err: true},
+		{in: "epp=../power", err: true},
+		{in: "uclamp.min=101", err: true},
+		{in: "uclamp.max=-1", err: true},
+		{in: "uclamp.min=min", err: true},
+		{in: "boost=1", err: true},
+	} {
+		h, err := ParsePowerHint(tc.in, tc.base)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v", tc.in, h)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if tc.out == "" {
+			if h != nil {
+				t.Errorf("%q: expected nil, got %q", tc.in, h)
+			}
+			continue
+		}
+		if h == nil || h.String() != tc.out {
+			t.Errorf("%q: expected %q, got %v", tc.in, tc.out, h)
+		}
+	}
+	if base.UclampMax != "" {
+		t.Error("the base hint was modified")
+	}
+}
@@ -0,0 +1,35 @@
+package configs
+
+import "time"
+
+// ProbeType is the type of a container [Probe].
+type ProbeType string
+
+const (
+	// ProbeReadiness checks whether the container is ready to do its job
+	// (e.g. to serve requests).
+	ProbeReadiness ProbeType = "readiness"
+	// ProbeLiveness checks whether the container is still working.
+	ProbeLiveness ProbeType = "liveness"
+)
+
+// Probe is a command periodically run in the container to check its
+// health. The check passes if the command exits with status 0 within the
+// timeout.
+type Probe struct {
+	Type ProbeType `json:"type"`
+
+	// Args is the probe command line.
+	Args []string `json:"args"`
+
+	// Interval is the time between two runs of the probe.
+	Interval time.Duration `json:"interval"`
+
+	// Timeout is how long the probe command can run before it is killed,
+	// and the check fails.
+	Timeout time.Duration `json:"timeout"`
+
+	// FailureThreshold is the number of consecutive failed checks after
+	// which the probe is failing.
+	FailureThreshold int `json:"failure_threshold"`
+}
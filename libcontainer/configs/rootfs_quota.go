@@ -0,0 +1,18 @@
+package configs
+
+// RootfsQuota limits the disk usage of the container root filesystem, with
+// a project quota (which requires the root filesystem to be a directory on
+// an XFS, or an ext4 filesystem with the project feature, mounted with
+// project quotas enabled). A zero limit means no limit.
+type RootfsQuota struct {
+	// Size is the limit on the disk space used, in bytes.
+	Size uint64 `json:"size,omitempty"`
+
+	// Inodes is the limit on the number of inodes used.
+	Inodes uint64 `json:"inodes,omitempty"`
+
+	// ProjectID is the project the root filesystem files are assigned to.
+	// If zero, the project the root filesystem directory is assigned to
+	// already, if any, is used, or else an unused one.
+	ProjectID uint32 `json:"project_id,omitempty"`
+}
@@ -0,0 +1,42 @@
+package configs
+
+import "path/filepath"
+
+// ShmPolicy determines how the /dev/shm size is applied when /dev/shm is not
+// a tmpfs mounted by runc for the container, which is usually the case when
+// the IPC namespace is shared with the host or another container.
+type ShmPolicy string
+
+const (
+	// ShmPolicySkip leaves a shared /dev/shm untouched. This is the default.
+	ShmPolicySkip ShmPolicy = "skip"
+
+	// ShmPolicyRemount resizes a shared /dev/shm in place. Note this
+	// affects every container that shares the same tmpfs, so it is only
+	// done for a tmpfs created by runc for another container, and never
+	// for one mounted on the host (such as the host /dev/shm).
+	ShmPolicyRemount ShmPolicy = "remount"
+)
+
+// Shm holds the /dev/shm configuration of a container.
+type Shm struct {
+	// Size is the size of /dev/shm, in bytes. Zero means the size is
+	// determined by the mount options (or the kernel default).
+	Size int64 `json:"size,omitempty"`
+
+	// Policy determines what happens if /dev/shm is shared. If empty,
+	// ShmPolicySkip is used.
+	Policy ShmPolicy `json:"policy,omitempty"`
+}
+
+// IsShmShared reports whether /dev/shm of a container with the given config is
+// not a tmpfs instance private to the container, i.e. it is either inherited
+// from the rootfs, or bind mounted from elsewhere.
+func (c *Config) IsShmShared() bool {
+	for _, m := range c.Mounts {
+		if m.Device == "tmpfs" && filepath.Clean(m.Destination) == "/dev/shm" {
+			return false
+		}
+	}
+	return true
+}
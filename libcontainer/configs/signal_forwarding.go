@@ -0,0 +1,65 @@
+package configs
+
+import (
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// SignalForwarding is the policy of the signals which runc forwards to the
+// container process it runs in the foreground (with runc run or runc exec,
+// without --detach). By default, the signals runc receives are forwarded as
+// they are, except for the ones runc handles itself (SIGCHLD, SIGWINCH, and
+// SIGURG).
+type SignalForwarding struct {
+	// Map maps the signals runc receives to the signals forwarded instead
+	// (such as SIGTERM to SIGINT).
+	Map map[unix.Signal]unix.Signal `json:"map,omitempty"`
+
+	// Ignored are the signals which are not forwarded.
+	Ignored []unix.Signal `json:"ignored,omitempty"`
+}
+
+// Forwarded returns the signal to forward to the container process when
+// runc receives sig, or 0 if it is not to be forwarded. f may be nil.
+func (f *SignalForwarding) Forwarded(sig unix.Signal) unix.Signal {
+	if f == nil {
+		return sig
+	}
+	if slices.Contains(f.Ignored, sig) {
+		return 0
+	}
+	if to, ok := f.Map[sig]; ok {
+		return to
+	}
+	return sig
+}
+
+// Merge returns the policy with the mapped and ignored signals of o added
+// to (or replacing) the ones of f. Either may be nil.
+func (f *SignalForwarding) Merge(o *SignalForwarding) *SignalForwarding {
+	if o == nil {
+		return f
+	}
+	if f == nil {
+		return o
+	}
+	m := &SignalForwarding{
+		Map:     make(map[unix.Signal]unix.Signal, len(f.Map)+len(o.Map)),
+		Ignored: slices.Clone(o.Ignored),
+	}
+	for from, to := range f.Map {
+		if !slices.Contains(o.Ignored, from) {
+			m.Map[from] = to
+		}
+	}
+	for from, to := range o.Map {
+		m.Map[from] = to
+	}
+	for _, sig := range f.Ignored {
+		if _, ok := o.Map[sig]; !ok && !slices.Contains(m.Ignored, sig) {
+			m.Ignored = append(m.Ignored, sig)
+		}
+	}
+	return m
+}
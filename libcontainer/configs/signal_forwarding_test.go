@@ -0,0 +1,52 @@
+package configs
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSignalForwarding(t *testing.T) {
+	var none *SignalForwarding
+	if s := none.Forwarded(unix.SIGTERM); s != unix.SIGTERM {
+		t.Errorf("expected SIGTERM to be forwarded as is, got %v", s)
+	}
+	f := &SignalForwarding{
+		Map:     map[unix.Signal]unix.Signal{unix.SIGTERM: unix.SIGINT},
+		Ignored: []unix.Signal{unix.SIGHUP},
+	}
+	for sig, expected := range map[unix.Signal]unix.Signal{
+		unix.SIGTERM: unix.SIGINT,
+		unix.SIGHUP:  0,
+		unix.SIGUSR1: unix.SIGUSR1,
+	} {
+		if s := f.Forwarded(sig); s != expected {
+			t.Errorf("%v: expected %v, got %v", sig, expected, s)
+		}
+	}
+}
+
+func TestSignalForwardingMerge(t *testing.T) {
+	f := &SignalForwarding{
+		Map:     map[unix.Signal]unix.Signal{unix.SIGTERM: unix.SIGINT, unix.SIGUSR1: unix.SIGUSR2},
+		Ignored: []unix.Signal{unix.SIGHUP, unix.SIGQUIT},
+	}
+	o := &SignalForwarding{
+		Map:     map[unix.Signal]unix.Signal{unix.SIGHUP: unix.SIGUSR1},
+		Ignored: []unix.Signal{unix.SIGTERM},
+	}
+	expected := &SignalForwarding{
+		Map:     map[unix.Signal]unix.Signal{unix.SIGUSR1: unix.SIGUSR2, unix.SIGHUP: unix.SIGUSR1},
+		Ignored: []unix.Signal{unix.SIGTERM, unix.SIGQUIT},
+	}
+	if m := f.Merge(o); !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected %+v, got %+v", expected, m)
+	}
+	if m := f.Merge(nil); m != f {
+		t.Errorf("expected %+v, got %+v", f, m)
+	}
+	if m := (*SignalForwarding)(nil).Merge(o); m != o {
+		t.Errorf("expected %+v, got %+v", o, m)
+	}
+}
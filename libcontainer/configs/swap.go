@@ -0,0 +1,30 @@
+package configs
+
+// SwapType is the kind of backing store used for a container's managed swap.
+type SwapType string
+
+const (
+	// SwapFile uses a regular file as a swap area.
+	SwapFile SwapType = "file"
+
+	// SwapZram uses a newly allocated zram device as a swap area.
+	SwapZram SwapType = "zram"
+)
+
+// Swap describes a swap area which is provisioned by libcontainer when the
+// container is created, and removed when it is destroyed.
+//
+// Note that a swap area, once enabled, can be used by any process on the
+// host. The container is allowed to use up to Size bytes of swap by the
+// means of the memory cgroup swap limit, which is set accordingly.
+type Swap struct {
+	// Type is the swap area type.
+	Type SwapType `json:"type"`
+
+	// Size is the swap area size, in bytes.
+	Size int64 `json:"size"`
+
+	// Path is the swap file path. Only used (and required) for SwapFile.
+	// The file must not exist, and it must not be on tmpfs.
+	Path string `json:"path,omitempty"`
+}
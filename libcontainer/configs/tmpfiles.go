@@ -0,0 +1,62 @@
+package configs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TmpfileType is the type of a [Tmpfile], named as in tmpfiles.d(5).
+type TmpfileType string
+
+const (
+	// TmpfileDir creates a directory.
+	TmpfileDir TmpfileType = "d"
+	// TmpfileFile creates a file, with the argument as its contents.
+	TmpfileFile TmpfileType = "f"
+	// TmpfileSymlink creates a symlink to the argument.
+	TmpfileSymlink TmpfileType = "L"
+	// TmpfileCharDevice creates a character device node, with the argument
+	// as its "major:minor" device number.
+	TmpfileCharDevice TmpfileType = "c"
+	// TmpfileBlockDevice creates a block device node, with the argument as
+	// its "major:minor" device number.
+	TmpfileBlockDevice TmpfileType = "b"
+	// TmpfileAdjust adjusts the mode and owner of an existing path.
+	TmpfileAdjust TmpfileType = "z"
+)
+
+// Tmpfile is an entry to be provisioned in the container root filesystem,
+// in the manner of tmpfiles.d(5). An existing entry is left as is, except for
+// its mode and owner, which are adjusted if set.
+type Tmpfile struct {
+	Type TmpfileType `json:"type"`
+	// Path is the path in the container root filesystem.
+	Path string `json:"path"`
+	// Mode is the permission bits. If not set, a new entry has mode 0755
+	// if it is a directory, and 0644 otherwise.
+	Mode *uint32 `json:"mode,omitempty"`
+	// UID and GID are the owner of the entry, in the container user
+	// namespace. If not set, a new entry is owned by root.
+	UID *uint32 `json:"uid,omitempty"`
+	GID *uint32 `json:"gid,omitempty"`
+	// Argument is the type-specific argument.
+	Argument string `json:"argument,omitempty"`
+}
+
+// DeviceNumber returns the major and minor numbers of a device node entry.
+func (t *Tmpfile) DeviceNumber() (major, minor uint32, _ error) {
+	maj, min, ok := strings.Cut(t.Argument, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid device number %q: expected major:minor", t.Argument)
+	}
+	ma, err := strconv.ParseUint(maj, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid device number %q: %w", t.Argument, err)
+	}
+	mi, err := strconv.ParseUint(min, 10, 32)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid device number %q: %w", t.Argument, err)
+	}
+	return uint32(ma), uint32(mi), nil
+}
@@ -1,16 +1,24 @@
 package validate
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 
 	"github.com/opencontainers/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/landlock"
+	"github.com/opencontainers/runc/libcontainer/sched"
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	selinux "github.com/opencontainers/selinux/go-selinux"
 	"github.com/sirupsen/logrus"
@@ -19,39 +27,168 @@ import (
 
 type check func(config *configs.Config) error
 
+// Severity is the severity of a [Finding].
+type Severity string
+
+const (
+	// SeverityError is a problem which prevents the container from being
+	// created.
+	SeverityError Severity = "error"
+	// SeverityWarning is a problem which does not prevent the container
+	// from being created, such as the use of a deprecated feature.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is a configuration problem found by [ValidateAll].
+type Finding struct {
+	// Path is the path of the runtime spec field the problem is about,
+	// such as "linux.namespaces", or an empty string if the field is not
+	// a part of the spec (such as the runc options).
+	Path     string   `json:"path"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Fix is a suggested fix, if any.
+	Fix string `json:"fix,omitempty"`
+}
+
+// rule is a configuration check, along with the runtime spec field it is
+// about, and a suggested fix for its failures, unless a failure has its own
+// (see withFix).
+type rule struct {
+	check check
+	path  string
+	fix   string
+}
+
+// fixError is a check failure with its own suggested fix.
+type fixError struct {
+	err error
+	fix string
+}
+
+func (e *fixError) Error() string { return e.err.Error() }
+
+func (e *fixError) Unwrap() error { return e.err }
+
+// withFix returns err with the suggested fix, which is reported by
+// [ValidateAll] in place of the one of the rule.
+func withFix(err error, fix string) error {
+	return &fixError{err: err, fix: fix}
+}
+
+var (
+	// resourceRules are the rules about the resources which can be changed
+	// on a running container, see ValidateResources.
+	resourceRules = []rule{
+		{cgroupsCheck, "linux.resources", "use cgroup resources supported by the host"},
+		{intelrdtCheck, "linux.intelRdt", "use a CLOS and schemas enabled on the host"},
+		{shm, "annotations", "fix the /dev/shm size annotation"},
+		{cpusetCheck, "linux.resources.cpu", "use CPUs and NUMA nodes online on the host"},
+		{ioCost, "annotations", "fix the io.cost annotations"},
+		{powerHint, "annotations", "fix the power hint annotation"},
+	}
+	rules = append(slices.Clip(resourceRules), []rule{
+		{rootfs, "root.path", "set the root path to an existing directory"},
+		{network, "linux.namespaces", "add a network namespace"},
+		{netdevices, "linux.netDevices", "add a network namespace"},
+		{uts, "linux.namespaces", "add a uts namespace"},
+		{security, "linux.maskedPaths", "add a mount namespace"},
+		{namespaces, "linux.namespaces", "use namespaces supported by the kernel"},
+		{sysctl, "linux.sysctl", "remove the sysctls of the namespaces the container lacks"},
+		{rootlessEUIDCheck, "linux.uidMappings", `generate a rootless configuration with "runc spec --rootless"`},
+		{mountsStrict, "mounts", "fix the mount options"},
+		{scheduler, "process.scheduler", "fix the scheduler settings"},
+		{ioPriority, "process.ioPriority", "use a valid I/O priority class and level"},
+		{swap, "annotations", "fix the swap annotation"},
+		{delegatePty, "mounts", "add a devpts mount at /dev/pts"},
+		{landlockCheck, "annotations", "fix the Landlock annotation"},
+		{readonlyCheck, "annotations", "fix the read-only check annotation"},
+		{mountPolicy, "", "fix the mount policy"},
+		{sysfsWritable, "annotations", "fix the writable sysfs annotation"},
+		{tmpfilesCheck, "annotations", "fix the tmpfiles annotation"},
+		{execLimits, "annotations", "fix the exec limits annotation"},
+		{probes, "annotations", "fix the probes annotation"},
+		{netSysctl, "annotations", "add a network namespace without a path"},
+		{helperCgroup, "annotations", "use a plain helper cgroup name"},
+		{keepNetns, "annotations", "add a new network namespace, without a user namespace"},
+		{schedCore, "", "remove the core scheduling annotation"},
+		{initSignals, "annotations", "fix the init signals annotation"},
+		{signalForwarding, "annotations", "fix the signal forwarding annotation"},
+		{rootfsQuota, "annotations", "fix the root filesystem quota annotation"},
+		{usernsAuto, "annotations", "add a new user namespace"},
+		{identity, "annotations", "fix the identity annotation"},
+		{ipcLimits, "annotations", "fix the IPC limits annotation"},
+		{memoryPolicy, "annotations", "fix the memory policy annotation"},
+		{asyncHooks, "annotations", "fix the async hooks annotation"},
+		{propagationPaths, "annotations", "fix the mount propagation annotation"},
+		{namespaceOwner, "annotations", "fix the namespace owner annotation"},
+		{envDefaults, "annotations", "fix the env defaults annotation"},
+		{keyring, "annotations", "fix the keyring annotation"},
+	}...)
+	// Relaxed validation rules for backward compatibility
+	warnRules = []rule{
+		{mountsWarn, "mounts", "use absolute mount destinations"},
+	}
+)
+
+// Validate checks that the configuration is valid, and returns the first
+// problem found, if any. The warnings are logged.
 func Validate(config *configs.Config) error {
-	checks := []check{
-		cgroupsCheck,
-		rootfs,
-		network,
-		netdevices,
-		uts,
-		security,
-		namespaces,
-		sysctl,
-		intelrdtCheck,
-		rootlessEUIDCheck,
-		mountsStrict,
-		scheduler,
-		ioPriority,
-	}
-	for _, c := range checks {
-		if err := c(config); err != nil {
+	for _, r := range rules {
+		if err := r.check(config); err != nil {
 			return err
 		}
 	}
-	// Relaxed validation rules for backward compatibility
-	warns := []check{
-		mountsWarn,
-	}
-	for _, c := range warns {
-		if err := c(config); err != nil {
+	for _, r := range warnRules {
+		if err := r.check(config); err != nil {
 			logrus.WithError(err).Warn("configuration")
 		}
 	}
 	return nil
 }
 
+// ValidateResources checks that the resources of the configuration of a
+// running container are valid, the same way as [Validate] does for a new
+// container, and returns the first problem found, if any. The cpuset
+// request, which is only resolved when the container is created, is not
+// checked.
+func ValidateResources(config *configs.Config) error {
+	c := *config
+	c.CpusetRequest = ""
+	for _, r := range resourceRules {
+		if err := r.check(&c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateAll checks that the configuration is valid, and returns all the
+// problems found, including the warnings, rather than only the first one.
+func ValidateAll(config *configs.Config) []Finding {
+	var findings []Finding
+	add := func(rules []rule, severity Severity) {
+		for _, r := range rules {
+			if err := r.check(config); err != nil {
+				fix := r.fix
+				var fixErr *fixError
+				if errors.As(err, &fixErr) {
+					fix = fixErr.fix
+				}
+				findings = append(findings, Finding{
+					Path:     r.path,
+					Severity: severity,
+					Message:  err.Error(),
+					Fix:      fix,
+				})
+			}
+		}
+	}
+	add(rules, SeverityError)
+	add(warnRules, SeverityWarning)
+	return findings
+}
+
 // rootfs validates if the rootfs is an absolute path and is not a symlink
 // to the container's root filesystem.
 func rootfs(config *configs.Config) error {
@@ -104,6 +241,14 @@ func netdevices(config *configs.Config) error {
 		if netdev.Name != "" && !devValidName(netdev.Name) {
 			return fmt.Errorf("invalid network device name %q", netdev.Name)
 		}
+		if netdev.MTU < 0 || netdev.TxQueueLen < 0 {
+			return fmt.Errorf("network device %s: negative mtu or txqueuelen", name)
+		}
+		if netdev.HardwareAddress != "" {
+			if _, err := net.ParseMAC(netdev.HardwareAddress); err != nil {
+				return fmt.Errorf("network device %s: %w", name, err)
+			}
+		}
 	}
 	return nil
 }
@@ -180,6 +325,41 @@ func namespaces(config *configs.Config) error {
 		}
 	}
 
+	for _, ns := range config.Namespaces {
+		if configs.IsKnownNamespace(ns.Type) {
+			continue
+		}
+		if !config.AllowUnknownNamespaces {
+			return fmt.Errorf("unknown namespace type %q", ns.Type)
+		}
+		if err := unknownNamespace(ns); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unknownNamespace checks that the namespace ns, of a type unknown to runc,
+// is supported by the kernel, and is not a known namespace in disguise.
+func unknownNamespace(ns configs.Namespace) error {
+	name := string(ns.Type)
+	if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid namespace type %q", name)
+	}
+	if !configs.IsNamespaceSupported(ns.Type) {
+		return fmt.Errorf("namespace %q is not supported by the kernel (no /proc/self/ns/%s)", name, name)
+	}
+	flag := ns.Syscall()
+	if flag == 0 {
+		return fmt.Errorf("unable to get the clone flag of namespace %q", name)
+	}
+	for _, t := range configs.NamespaceTypes() {
+		known := configs.Namespace{Type: t}
+		if known.Syscall() == flag {
+			return fmt.Errorf("namespace %q is a %s namespace, which must be configured as such", name, configs.NsName(t))
+		}
+	}
 	return nil
 }
 
@@ -286,6 +466,9 @@ func intelrdtCheck(config *configs.Config) error {
 		if config.IntelRdt.ClosID == "." || config.IntelRdt.ClosID == ".." || strings.Contains(config.IntelRdt.ClosID, "/") {
 			return fmt.Errorf("invalid intelRdt.ClosID %q", config.IntelRdt.ClosID)
 		}
+		if config.IntelRdt.Shared && config.IntelRdt.ClosID == "" {
+			return errors.New("intelRdt.shared requires intelRdt.ClosID")
+		}
 
 		if !intelrdt.IsCATEnabled() && config.IntelRdt.L3CacheSchema != "" {
 			return errors.New("intelRdt.l3CacheSchema is specified in config, but Intel RDT/CAT is not enabled")
@@ -293,6 +476,12 @@ func intelrdtCheck(config *configs.Config) error {
 		if !intelrdt.IsMBAEnabled() && config.IntelRdt.MemBwSchema != "" {
 			return errors.New("intelRdt.memBwSchema is specified in config, but Intel RDT/MBA is not enabled")
 		}
+		if !intelrdt.IsCMTEnabled() && config.IntelRdt.EnableCMT {
+			return errors.New("intelRdt.enableCMT is specified in config, but Intel RDT/CMT is not enabled")
+		}
+		if !intelrdt.IsMBMEnabled() && config.IntelRdt.EnableMBM {
+			return errors.New("intelRdt.enableMBM is specified in config, but Intel RDT/MBM is not enabled")
+		}
 	}
 
 	return nil
@@ -305,7 +494,7 @@ func cgroupsCheck(config *configs.Config) error {
 	}
 
 	if (c.Name != "" || c.Parent != "") && c.Path != "" {
-		return fmt.Errorf("cgroup: either Path or Name and Parent should be used, got %+v", c)
+		return withFix(fmt.Errorf("cgroup: either Path or Name and Parent should be used, got %+v", c), "set either a cgroups path, or a cgroup name and parent")
 	}
 
 	r := c.Resources
@@ -314,13 +503,27 @@ func cgroupsCheck(config *configs.Config) error {
 	}
 
 	if !cgroups.IsCgroup2UnifiedMode() && r.Unified != nil {
-		return cgroups.ErrV1NoUnified
+		return withFix(cgroups.ErrV1NoUnified, "remove the unified resources")
+	}
+
+	if v, ok := r.Unified[configs.CpusetPartitionFile]; ok {
+		p := configs.CpusetPartition(strings.TrimSpace(v))
+		if !p.IsValid() {
+			return withFix(fmt.Errorf("cgroup: invalid cpuset partition type %q", v), "use the member, root, or isolated cpuset partition type")
+		}
+		// A partition root with no CPUs would be invalid.
+		if p != configs.CpusetPartitionMember && r.CpusetCpus == "" && config.CpusetRequest == "" {
+			return withFix(fmt.Errorf("cgroup: cpuset partition type %q requires the cpuset CPUs to be set", p), "set the cpuset CPUs")
+		}
+	}
+	if err := configs.ValidateMemorySwap(r.Unified); err != nil {
+		return withFix(fmt.Errorf("cgroup: %w", err), "set the swap and zswap limits in bytes")
 	}
 
 	if cgroups.IsCgroup2UnifiedMode() {
 		_, err := cgroups.ConvertMemorySwapToCgroupV2Value(r.MemorySwap, r.Memory)
 		if err != nil {
-			return err
+			return withFix(err, "set a memory+swap limit no lower than the memory limit")
 		}
 	}
 
@@ -419,21 +622,10 @@ func scheduler(config *configs.Config) error {
 	if s == nil {
 		return nil
 	}
-	if s.Policy == "" {
-		return errors.New("scheduler policy is required")
-	}
-	if s.Policy == specs.SchedOther || s.Policy == specs.SchedBatch {
-		if s.Nice < -20 || s.Nice > 19 {
-			return fmt.Errorf("invalid scheduler.nice: %d when scheduler.policy is %s", s.Nice, string(s.Policy))
-		}
-	}
-	if s.Priority != 0 && (s.Policy != specs.SchedFIFO && s.Policy != specs.SchedRR) {
-		return errors.New("scheduler.priority can only be specified for SchedFIFO or SchedRR policy")
-	}
-	if s.Policy != specs.SchedDeadline && (s.Runtime != 0 || s.Deadline != 0 || s.Period != 0) {
-		return errors.New("scheduler runtime/deadline/period can only be specified for SchedDeadline policy")
+	if err := sched.Validate(s); err != nil {
+		return err
 	}
-	return nil
+	return sched.CheckSupported(s)
 }
 
 func ioPriority(config *configs.Config) error {
@@ -454,3 +646,745 @@ func ioPriority(config *configs.Config) error {
 
 	return nil
 }
+
+func shm(config *configs.Config) error {
+	if config.Shm == nil {
+		return nil
+	}
+	if config.Shm.Size < 0 {
+		return fmt.Errorf("invalid shm.size: %d", config.Shm.Size)
+	}
+	switch config.Shm.Policy {
+	case "", configs.ShmPolicySkip, configs.ShmPolicyRemount:
+	default:
+		return fmt.Errorf("invalid shm.policy: %q", config.Shm.Policy)
+	}
+	if config.Shm.Size > 0 && !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("unable to set /dev/shm size without a private MNT namespace")
+	}
+	return nil
+}
+
+func swap(config *configs.Config) error {
+	s := config.Swap
+	if s == nil {
+		return nil
+	}
+	if config.RootlessEUID {
+		return errors.New("swap can not be set up for rootless containers")
+	}
+	if s.Size <= 0 {
+		return fmt.Errorf("invalid swap.size: %d", s.Size)
+	}
+	switch s.Type {
+	case configs.SwapFile:
+		if !filepath.IsAbs(s.Path) {
+			return fmt.Errorf("swap.path must be an absolute path, got %q", s.Path)
+		}
+	case configs.SwapZram:
+		if s.Path != "" {
+			return errors.New("swap.path can only be set for file swap")
+		}
+	default:
+		return fmt.Errorf("invalid swap.type: %q", s.Type)
+	}
+	return nil
+}
+
+// cpusetCheck validates the symbolic cpuset request, checks that the
+// explicitly set cpuset CPUs are available on the host, and that the cpuset
+// mems are NUMA nodes with memory, and validates the mems migration policy.
+func cpusetCheck(config *configs.Config) error {
+	if !config.MemsMigration.IsValid() {
+		return withFix(fmt.Errorf("invalid mems migration policy %q", config.MemsMigration), "use the none, migrate, or reclaim mems migration policy")
+	}
+	if config.MemsMigration == configs.MemsMigrationReclaim && !cgroups.IsCgroup2UnifiedMode() {
+		return withFix(errors.New("mems migration policy \"reclaim\" requires cgroup v2"), "use the migrate mems migration policy")
+	}
+	if config.Cgroups == nil || config.Cgroups.Resources == nil {
+		return nil
+	}
+	r := config.Cgroups.Resources
+	if err := cpusetMemsCheck(r.CpusetMems); err != nil {
+		return err
+	}
+	if config.CpusetRequest != "" {
+		if _, err := cpuset.ParseRequest(config.CpusetRequest); err != nil {
+			return err
+		}
+		if r.CpusetCpus != "" {
+			return withFix(errors.New("cpuset request can not be used together with cpuset cpus"), "remove the cpuset cpus")
+		}
+		return nil
+	}
+	if r.CpusetCpus == "" {
+		return nil
+	}
+	if cpuset.IsRequest(r.CpusetCpus) {
+		return withFix(fmt.Errorf("cpuset request %q is not allowed in cpuset cpus", r.CpusetCpus), "only use a cpuset request when creating the container")
+	}
+	// Leave the lists we can't parse for the kernel to check.
+	cpus, err := cpuset.Parse(strings.TrimSpace(r.CpusetCpus))
+	if err != nil {
+		return nil
+	}
+	topo, err := cpuset.HostTopology()
+	if err != nil {
+		return nil
+	}
+	var missing []int
+	for _, cpu := range cpus {
+		if !slices.Contains(topo.Online, cpu) {
+			missing = append(missing, cpu)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cpuset cpus %s are not online on this host", cpuset.Format(missing))
+	}
+	return nil
+}
+
+// cpusetMemsCheck checks that the cpuset mems are NUMA nodes with memory, as
+// the allocations of the container would otherwise fail on those.
+func cpusetMemsCheck(mems string) error {
+	if mems == "" {
+		return nil
+	}
+	// As with the CPUs, leave the lists we can't parse to the kernel.
+	nodes, err := cpuset.Parse(strings.TrimSpace(mems))
+	if err != nil {
+		return nil
+	}
+	topo, err := cpuset.HostTopology()
+	if err != nil {
+		return nil
+	}
+	var missing []int
+	for _, node := range nodes {
+		if !slices.Contains(topo.MemoryNodes, node) {
+			missing = append(missing, node)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("cpuset mems %s are not NUMA nodes with memory on this host", cpuset.Format(missing))
+	}
+	return nil
+}
+
+func delegatePty(config *configs.Config) error {
+	if !config.DelegatePty {
+		return nil
+	}
+	for _, m := range config.Mounts {
+		if m.Device == "devpts" && filepath.Clean(m.Destination) == "/dev/pts" {
+			return nil
+		}
+	}
+	return errors.New("pty delegation requires a devpts mount at /dev/pts")
+}
+
+func landlockCheck(config *configs.Config) error {
+	ll := config.Landlock
+	if ll == nil {
+		return nil
+	}
+	handledFS, err := landlock.ParseAccessFS(ll.HandledAccessFS)
+	if err != nil {
+		return err
+	}
+	handledNet, err := landlock.ParseAccessNetwork(ll.HandledAccessNetwork)
+	if err != nil {
+		return err
+	}
+	if handledFS == 0 && handledNet == 0 {
+		return errors.New("landlock: no handled access rights")
+	}
+	for _, rule := range ll.PathBeneath {
+		access, err := landlock.ParseAccessFS(rule.AllowedAccess)
+		if err != nil {
+			return err
+		}
+		if access == 0 {
+			return errors.New("landlock: path beneath rule with no allowed access rights")
+		}
+		if access&^handledFS != 0 {
+			return errors.New("landlock: path beneath rule allows access rights which are not handled")
+		}
+		for _, path := range rule.Paths {
+			if !filepath.IsAbs(path) {
+				return fmt.Errorf("landlock: path beneath rule path %q is not absolute", path)
+			}
+		}
+	}
+	for _, rule := range ll.NetPort {
+		access, err := landlock.ParseAccessNetwork(rule.AllowedAccess)
+		if err != nil {
+			return err
+		}
+		if access == 0 {
+			return errors.New("landlock: net port rule with no allowed access rights")
+		}
+		if access&^handledNet != 0 {
+			return errors.New("landlock: net port rule allows access rights which are not handled")
+		}
+		for _, port := range rule.Ports {
+			if port > 65535 {
+				return fmt.Errorf("landlock: invalid net port rule port %d", port)
+			}
+		}
+	}
+	return nil
+}
+
+func readonlyCheck(config *configs.Config) error {
+	switch config.ReadonlyCheck {
+	case "", configs.ReadonlyCheckWarn, configs.ReadonlyCheckFail:
+	default:
+		return fmt.Errorf("invalid read-only check policy: %q", config.ReadonlyCheck)
+	}
+	if config.ReadonlyCheck != "" && !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("read-only check requires a mount namespace")
+	}
+	return nil
+}
+
+func mountPolicy(config *configs.Config) error {
+	p := config.MountPolicy
+	if p == nil {
+		return nil
+	}
+	if !p.NoSuid && !p.NoDev && !p.NoExec {
+		return errors.New("mount policy: no mount flags to enforce")
+	}
+	for _, dst := range p.Allow {
+		if !filepath.IsAbs(dst) {
+			return fmt.Errorf("mount policy: allowed mount destination %q is not absolute", dst)
+		}
+	}
+	return nil
+}
+
+func tmpfilesCheck(config *configs.Config) error {
+	for _, t := range config.Tmpfiles {
+		if !filepath.IsAbs(t.Path) || filepath.Clean(t.Path) == "/" {
+			return fmt.Errorf("tmpfiles: invalid path %q: must be absolute, and not /", t.Path)
+		}
+		switch t.Type {
+		case configs.TmpfileDir, configs.TmpfileFile, configs.TmpfileAdjust:
+		case configs.TmpfileSymlink:
+			if t.Argument == "" {
+				return fmt.Errorf("tmpfiles: symlink %s has no target", t.Path)
+			}
+		case configs.TmpfileCharDevice, configs.TmpfileBlockDevice:
+			if _, _, err := t.DeviceNumber(); err != nil {
+				return fmt.Errorf("tmpfiles: device %s: %w", t.Path, err)
+			}
+		default:
+			return fmt.Errorf("tmpfiles: %s has an unsupported type %q", t.Path, t.Type)
+		}
+		if t.Mode != nil && *t.Mode&^0o7777 != 0 {
+			return fmt.Errorf("tmpfiles: %s has an invalid mode %#o", t.Path, *t.Mode)
+		}
+	}
+	return nil
+}
+
+func sysfsWritable(config *configs.Config) error {
+	if len(config.SysfsWritable) == 0 {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("writable /sys paths require a mount namespace")
+	}
+	var sys *configs.Mount
+	for _, m := range config.Mounts {
+		if filepath.Clean(m.Destination) == "/sys" {
+			sys = m
+		}
+	}
+	if sys == nil {
+		return errors.New("writable /sys paths require a /sys mount")
+	}
+	// A sysfs instance can only be mounted in a user namespace owning the
+	// network namespace, which is never the case for the host one.
+	hostNet := !config.Namespaces.Contains(configs.NEWNET)
+	if sys.Device == "sysfs" && config.Namespaces.Contains(configs.NEWUSER) && hostNet {
+		return errors.New("writable /sys paths: sysfs can not be mounted in a user namespace without its own network namespace, use a /sys bind mount")
+	}
+	for _, path := range config.SysfsWritable {
+		if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+			return fmt.Errorf("writable /sys path %q must be absolute and clean", path)
+		}
+		rel, ok := strings.CutPrefix(path, "/sys/")
+		if !ok || rel == "" {
+			return fmt.Errorf("writable /sys path %q is not under /sys", path)
+		}
+		// Like the net sysctls, network devices can not be configured in
+		// the host network namespace.
+		if hostNet && (strings.HasPrefix(rel, "class/net/") || strings.HasPrefix(rel, "devices/virtual/net/")) {
+			return fmt.Errorf("writable /sys path %q not allowed in host network namespace", path)
+		}
+	}
+	return nil
+}
+
+func execLimits(config *configs.Config) error {
+	l := config.ExecLimits
+	if l == nil {
+		return nil
+	}
+	if l.MaxConcurrent < 0 || l.MaxPerWindow < 0 || l.MaxTotal < 0 {
+		return fmt.Errorf("invalid exec limits: %+v", *l)
+	}
+	if l.MaxPerWindow > 0 && l.Window <= 0 {
+		return fmt.Errorf("invalid exec rate limit period: %s", l.Window)
+	}
+	return nil
+}
+
+func helperCgroup(config *configs.Config) error {
+	name := config.HelperCgroup
+	if name != "" && (strings.Contains(name, "/") || strings.HasPrefix(name, ".")) {
+		return fmt.Errorf("invalid helper cgroup name %q", name)
+	}
+	return nil
+}
+
+func netSysctl(config *configs.Config) error {
+	if len(config.NetSysctl) == 0 {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNET) || config.Namespaces.PathOf(configs.NEWNET) != "" {
+		return errors.New("net sysctls can only be set for a new network namespace")
+	}
+	for s := range config.NetSysctl {
+		if !strings.HasPrefix(convertSysctlVariableToDotsSeparator(s), "net.") {
+			return fmt.Errorf("sysctl %q is not a net sysctl", s)
+		}
+	}
+	return nil
+}
+
+func keepNetns(config *configs.Config) error {
+	if !config.KeepNetns {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNET) || config.Namespaces.PathOf(configs.NEWNET) != "" {
+		return errors.New("only a new network namespace can be kept")
+	}
+	if config.Namespaces.Contains(configs.NEWUSER) {
+		return errors.New("a network namespace can't be kept with a user namespace")
+	}
+	if config.RootlessEUID {
+		return errors.New("a network namespace can't be kept in the rootless mode")
+	}
+	return nil
+}
+
+func schedCore(config *configs.Config) error {
+	if !config.SchedCore {
+		return nil
+	}
+	// ENODEV (no SMT on the host) is fine, there is nothing to protect the
+	// container from then.
+	if _, err := system.CoreSchedCookie(0); errors.Is(err, unix.EINVAL) {
+		return errors.New("core scheduling is not supported by the kernel")
+	}
+	return nil
+}
+
+func initSignals(config *configs.Config) error {
+	s := config.InitSignals
+	if s == nil {
+		return nil
+	}
+	for _, sig := range slices.Concat(s.Blocked, s.Ignored) {
+		switch {
+		case sig < 1 || sig > 64:
+			return fmt.Errorf("invalid signal %d", sig)
+		case sig == unix.SIGKILL || sig == unix.SIGSTOP:
+			return fmt.Errorf("%s can't be blocked or ignored", unix.SignalName(sig))
+		}
+	}
+	return nil
+}
+
+func signalForwarding(config *configs.Config) error {
+	f := config.SignalForwarding
+	if f == nil {
+		return nil
+	}
+	for sig, to := range f.Map {
+		if err := forwardedSignal(sig); err != nil {
+			return err
+		}
+		if to < 1 || to > 64 {
+			return fmt.Errorf("invalid signal %d", to)
+		}
+		if slices.Contains(f.Ignored, sig) {
+			return fmt.Errorf("%s can't be both mapped and ignored", unix.SignalName(sig))
+		}
+	}
+	for _, sig := range f.Ignored {
+		if err := forwardedSignal(sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forwardedSignal checks that runc can forward sig, rather than handling
+// it itself.
+func forwardedSignal(sig unix.Signal) error {
+	switch sig {
+	case unix.SIGCHLD, unix.SIGWINCH, unix.SIGURG:
+		return fmt.Errorf("%s is handled by runc, and never forwarded", unix.SignalName(sig))
+	}
+	if sig < 1 || sig > 64 {
+		return fmt.Errorf("invalid signal %d", sig)
+	}
+	return nil
+}
+
+func powerHint(config *configs.Config) error {
+	h := config.PowerHint
+	if h == nil || h.EPP == "" {
+		return nil
+	}
+	if config.RootlessEUID {
+		return errors.New("the energy performance preference can't be set in the rootless mode")
+	}
+	if r := config.Cgroups; r == nil || r.Resources == nil || (r.Resources.CpusetCpus == "" && config.CpusetRequest == "") {
+		return errors.New("the energy performance preference requires a cpuset")
+	}
+	if files, _ := filepath.Glob(configs.EPPPath("*")); len(files) == 0 {
+		return errors.New("the energy performance preference is not supported by the cpufreq driver")
+	}
+	return nil
+}
+
+func ioCost(config *configs.Config) error {
+	c := config.IOCost
+	if c == nil {
+		return nil
+	}
+	for _, p := range []struct {
+		file    string
+		devices []*configs.IOCostDevice
+		params  []string
+	}{
+		{configs.IOCostQoSFile, c.QoS, configs.IOCostQoSParams},
+		{configs.IOCostModelFile, c.Model, configs.IOCostModelParams},
+	} {
+		for _, d := range p.devices {
+			if len(d.Params) == 0 {
+				return fmt.Errorf("%s: no parameters for device %d:%d", p.file, d.Major, d.Minor)
+			}
+			for k := range d.Params {
+				if !slices.Contains(p.params, k) {
+					return fmt.Errorf("%s: unknown parameter %q", p.file, k)
+				}
+			}
+		}
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return errors.New("io.cost requires cgroup v2")
+	}
+	if config.RootlessCgroups {
+		return errors.New("io.cost can't be set with rootless cgroups")
+	}
+	controllers, err := cgroups.ReadFile("/sys/fs/cgroup", "cgroup.controllers")
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(strings.Fields(controllers), "io") {
+		return errors.New("io.cost requires the io cgroup controller, which is not available")
+	}
+	return nil
+}
+
+func probes(config *configs.Config) error {
+	for _, p := range config.Probes {
+		if p.Type != configs.ProbeReadiness && p.Type != configs.ProbeLiveness {
+			return fmt.Errorf("invalid probe type %q", p.Type)
+		}
+		if len(p.Args) == 0 {
+			return fmt.Errorf("%s probe has no command", p.Type)
+		}
+		if p.Interval <= 0 || p.Timeout <= 0 || p.FailureThreshold <= 0 {
+			return fmt.Errorf("invalid %s probe: interval %s, timeout %s, failure threshold %d", p.Type, p.Interval, p.Timeout, p.FailureThreshold)
+		}
+	}
+	return nil
+}
+
+func rootfsQuota(config *configs.Config) error {
+	q := config.RootfsQuota
+	if q == nil {
+		return nil
+	}
+	if q.Size == 0 && q.Inodes == 0 {
+		return errors.New("rootfs quota requires a size or an inodes limit")
+	}
+	if config.RootlessEUID {
+		return errors.New("rootfs quota can't be set in the rootless mode")
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(config.Rootfs, &st); err != nil {
+		return &os.PathError{Op: "statfs", Path: config.Rootfs, Err: err}
+	}
+	if st.Type != unix.XFS_SUPER_MAGIC && st.Type != unix.EXT4_SUPER_MAGIC {
+		return fmt.Errorf("rootfs quota requires the root filesystem to be on XFS or ext4, not filesystem type %#x", st.Type)
+	}
+	return nil
+}
+
+func identity(config *configs.Config) error {
+	if config.MachineID == "" && !config.VirtualBootID {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("machine ID and virtual boot ID require a mount namespace")
+	}
+	if id := config.MachineID; id != "" {
+		if len(id) != 32 || strings.Trim(id, "0123456789abcdef") != "" {
+			return fmt.Errorf("invalid machine ID %q: must be 32 lowercase hexadecimal characters", id)
+		}
+		if strings.Trim(id, "0") == "" {
+			return errors.New("invalid machine ID: must not be all zeros")
+		}
+	}
+	if config.VirtualBootID && !slices.ContainsFunc(config.Mounts, func(m *configs.Mount) bool {
+		return m.Device == "proc" && filepath.Clean(m.Destination) == "/proc"
+	}) {
+		return errors.New("virtual boot ID requires a /proc mount")
+	}
+	return nil
+}
+
+func usernsAuto(config *configs.Config) error {
+	if !config.UsernsAuto {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWUSER) || config.Namespaces.PathOf(configs.NEWUSER) != "" {
+		return errors.New("automatic user namespace allocation requires a new user namespace")
+	}
+	if config.RootlessEUID {
+		return errors.New("automatic user namespace allocation can't be used in the rootless mode")
+	}
+	return nil
+}
+
+func ipcLimits(config *configs.Config) error {
+	l := config.IPCLimits
+	if l == nil {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWIPC) {
+		return errors.New("IPC limits require an ipc namespace")
+	}
+	const (
+		intMax = math.MaxInt32
+		ipcMni = 1 << 15 // IPCMNI, the maximum number of IPC identifiers.
+
+		// The fs.mqueue bounds (HARD_MSGMAX, MIN_MSGSIZEMAX, and
+		// HARD_MSGSIZEMAX).
+		mqMsgMax     = 65536
+		mqMsgSizeMin = 128
+		mqMsgSizeMax = 16 << 20
+	)
+	for _, b := range []struct {
+		name     string
+		value    uint64
+		min, max uint64
+	}{
+		{"msgmax", l.MsgMax, 1, intMax},
+		{"msgmnb", l.MsgMnb, 1, intMax},
+		{"msgmni", l.MsgMni, 1, ipcMni},
+		{"shmmni", l.ShmMni, 1, ipcMni},
+		{"semmsl", l.SemMsl, 1, intMax},
+		{"semmns", l.SemMns, 1, intMax},
+		{"semopm", l.SemOpm, 1, intMax},
+		{"semmni", l.SemMni, 1, ipcMni},
+		{"mqueue.msg_max", l.MqueueMsgMax, 1, mqMsgMax},
+		{"mqueue.msgsize_max", l.MqueueMsgSizeMax, mqMsgSizeMin, mqMsgSizeMax},
+		{"mqueue.queues_max", l.MqueueQueuesMax, 1, intMax},
+		{"mqueue.msg_default", l.MqueueMsgDefault, 1, cmp.Or(l.MqueueMsgMax, mqMsgMax)},
+		{"mqueue.msgsize_default", l.MqueueMsgSizeDefault, mqMsgSizeMin, cmp.Or(l.MqueueMsgSizeMax, mqMsgSizeMax)},
+	} {
+		if b.value != 0 && (b.value < b.min || b.value > b.max) {
+			return fmt.Errorf("IPC limit %s=%d out of range [%d, %d]", b.name, b.value, b.min, b.max)
+		}
+	}
+	var keys []string
+	for _, kv := range l.Sysctls() {
+		keys = append(keys, kv[0])
+	}
+	if l.HasSem() {
+		keys = append(keys, configs.SemSysctl)
+	}
+	for _, key := range keys {
+		if _, ok := config.Sysctl[key]; ok {
+			return fmt.Errorf("IPC limits conflict with the %s sysctl", key)
+		}
+	}
+	return nil
+}
+
+func memoryPolicy(config *configs.Config) error {
+	p := config.MemoryPolicy
+	if p == nil {
+		return nil
+	}
+	if p.Mode < configs.MPOL_DEFAULT || p.Mode > configs.MPOL_WEIGHTED_INTERLEAVE {
+		return fmt.Errorf("invalid memory policy mode %d", p.Mode)
+	}
+	const nodeFlags = configs.MPOL_F_STATIC_NODES | configs.MPOL_F_RELATIVE_NODES
+	if p.Flags&^(nodeFlags|configs.MPOL_F_NUMA_BALANCING) != 0 {
+		return fmt.Errorf("invalid memory policy flags %#x", p.Flags)
+	}
+	if p.Flags&nodeFlags == nodeFlags {
+		return errors.New("memory policy flags MPOL_F_STATIC_NODES and MPOL_F_RELATIVE_NODES are mutually exclusive")
+	}
+	if p.Flags&configs.MPOL_F_NUMA_BALANCING != 0 && p.Mode != configs.MPOL_BIND {
+		return errors.New("memory policy flag MPOL_F_NUMA_BALANCING requires the MPOL_BIND mode")
+	}
+	switch p.Mode {
+	case configs.MPOL_DEFAULT, configs.MPOL_LOCAL:
+		if len(p.Nodes) > 0 || p.Flags != 0 {
+			return errors.New("memory policy modes MPOL_DEFAULT and MPOL_LOCAL take no nodes and no flags")
+		}
+		return nil
+	case configs.MPOL_PREFERRED:
+		// No nodes means the local allocation.
+	default:
+		if len(p.Nodes) == 0 {
+			return errors.New("memory policy requires nodes")
+		}
+	}
+	const maxNodes = 1 << 10 // The largest MAX_NUMNODES.
+	for _, n := range p.Nodes {
+		if n < 0 || n >= maxNodes {
+			return fmt.Errorf("invalid memory policy node %d", n)
+		}
+	}
+	if p.Flags&nodeFlags != 0 || len(p.Nodes) == 0 {
+		// The nodes are not (or not only) host nodes.
+		return nil
+	}
+	if topo, err := cpuset.HostTopology(); err == nil {
+		var missing []int
+		for _, n := range p.Nodes {
+			if !slices.Contains(topo.MemoryNodes, n) {
+				missing = append(missing, n)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("memory policy nodes %s are not NUMA nodes with memory on this host", cpuset.Format(missing))
+		}
+	}
+	if config.Cgroups != nil && config.Cgroups.Resources != nil && config.Cgroups.CpusetMems != "" {
+		mems, err := cpuset.Parse(strings.TrimSpace(config.Cgroups.CpusetMems))
+		if err == nil && !slices.ContainsFunc(p.Nodes, func(n int) bool { return slices.Contains(mems, n) }) {
+			return fmt.Errorf("memory policy nodes %s are not in the cpuset mems %s", cpuset.Format(p.Nodes), config.Cgroups.CpusetMems)
+		}
+	}
+	return nil
+}
+
+// asyncHooks checks the hooks with the async option, which are only started
+// by runc itself for the prestart and createRuntime hooks, and run once.
+func asyncHooks(config *configs.Config) error {
+	for name, list := range config.Hooks {
+		for i, h := range list {
+			ch, ok := h.(configs.CommandHook)
+			if !ok || ch.Command == nil || !ch.Async {
+				continue
+			}
+			if name != configs.Prestart && name != configs.CreateRuntime {
+				return fmt.Errorf("%s hook #%d: only prestart and createRuntime hooks can be asynchronous", name, i)
+			}
+			if ch.Parallel || ch.Retries > 0 {
+				return fmt.Errorf("%s hook #%d: an asynchronous hook can not be parallel or retried", name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// propagationPaths checks the paths whose mount propagation is set, which
+// are in the container mount namespace.
+func propagationPaths(config *configs.Config) error {
+	if len(config.PropagationPaths) == 0 {
+		return nil
+	}
+	if !config.Namespaces.Contains(configs.NEWNS) {
+		return errors.New("propagation paths require a mount namespace")
+	}
+	const propagation = unix.MS_SHARED | unix.MS_SLAVE | unix.MS_PRIVATE | unix.MS_UNBINDABLE
+	seen := make(map[string]bool)
+	for _, p := range config.PropagationPaths {
+		if !filepath.IsAbs(p.Path) || filepath.Clean(p.Path) != p.Path || p.Path == "/" {
+			return fmt.Errorf("invalid propagation path %q: must be a clean absolute path other than / (see rootfsPropagation)", p.Path)
+		}
+		if seen[p.Path] {
+			return fmt.Errorf("propagation path %s is set more than once", p.Path)
+		}
+		seen[p.Path] = true
+		// Exactly one propagation flag, with MS_REC or not.
+		f := p.Flags &^ unix.MS_REC
+		if f&^propagation != 0 || f == 0 || f&(f-1) != 0 {
+			return fmt.Errorf("invalid propagation flags %#x of %s", p.Flags, p.Path)
+		}
+	}
+	return nil
+}
+
+// namespaceOwner checks the expected owners of the namespaces to join.
+func namespaceOwner(config *configs.Config) error {
+	switch config.NamespaceOwnerCheck {
+	case "", configs.NamespaceOwnerCheckWarn, configs.NamespaceOwnerCheckFail:
+	default:
+		return fmt.Errorf("invalid namespace owner check policy: %q", config.NamespaceOwnerCheck)
+	}
+	for _, ns := range config.Namespaces {
+		if ns.Owner == nil {
+			continue
+		}
+		if ns.Path == "" {
+			return fmt.Errorf("%s namespace: an owner requires a namespace path", configs.NsName(ns.Type))
+		}
+		if ns.Owner.UID == nil && ns.Owner.UsernsInode == 0 && ns.Owner.Inode == 0 {
+			return fmt.Errorf("%s namespace: empty owner", configs.NsName(ns.Type))
+		}
+	}
+	return nil
+}
+
+// envDefaults checks the policy of the default environment variables.
+func envDefaults(config *configs.Config) error {
+	if !config.EnvDefaults.IsValid() {
+		return fmt.Errorf("invalid env defaults policy: %q", config.EnvDefaults)
+	}
+	return nil
+}
+
+// keyring checks the configuration of the session keyring.
+func keyring(config *configs.Config) error {
+	k := config.Keyring
+	if k == nil {
+		return nil
+	}
+	if config.NoNewKeyring {
+		return errors.New("keyring can't be configured with no new keyring")
+	}
+	if strings.HasPrefix(k.Name, ".") {
+		return fmt.Errorf("invalid keyring name %q: names starting with a dot are reserved", k.Name)
+	}
+	for _, l := range k.Links {
+		if l.Type == "" || l.Description == "" {
+			return fmt.Errorf("invalid key link %s:%s: empty key type or description", l.Type, l.Description)
+		}
+	}
+	return nil
+}
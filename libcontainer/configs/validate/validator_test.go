@@ -1,12 +1,16 @@
 package validate
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/opencontainers/cgroups"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/sys/unix"
 )
@@ -22,6 +26,52 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateAll(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:   "/var",
+		Hostname: "runc",
+		Networks: []*configs.Network{{Type: "loopback"}},
+		Mounts:   []*configs.Mount{{Destination: "relative", Device: "tmpfs"}},
+	}
+
+	findings := ValidateAll(config)
+	expected := []Finding{
+		{Path: "linux.namespaces", Severity: SeverityError},
+		{Path: "linux.namespaces", Severity: SeverityError},
+		{Path: "mounts", Severity: SeverityWarning},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %+v", len(expected), findings)
+	}
+	for i, f := range findings {
+		if f.Path != expected[i].Path || f.Severity != expected[i].Severity || f.Message == "" || f.Fix == "" {
+			t.Errorf("unexpected finding %+v, expected path %q and severity %q", f, expected[i].Path, expected[i].Severity)
+		}
+	}
+	// Validate only returns the first error.
+	if err := Validate(config); err == nil || err.Error() != findings[0].Message {
+		t.Errorf("expected error %q, got %v", findings[0].Message, err)
+	}
+
+	if findings := ValidateAll(&configs.Config{Rootfs: "/var"}); len(findings) != 0 {
+		t.Errorf("expected no findings, got %+v", findings)
+	}
+}
+
+func TestValidateAllSpecificFix(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:        "/var",
+		MemsMigration: "bogus",
+	}
+	findings := ValidateAll(config)
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	if fix := "use the none, migrate, or reclaim mems migration policy"; findings[0].Fix != fix {
+		t.Errorf("expected fix %q, got %q", fix, findings[0].Fix)
+	}
+}
+
 func TestValidateWithInvalidRootfs(t *testing.T) {
 	dir := "rootfs"
 	if err := os.Symlink("/var", dir); err != nil {
@@ -262,6 +312,31 @@ func TestValidateTimeOffsetsWithoutTimeNamespace(t *testing.T) {
 	}
 }
 
+func TestValidateUnknownNamespace(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		allow bool
+		err   string
+	}{
+		{name: "foo", err: "unknown namespace type"},
+		{name: "foo", allow: true, err: "not supported by the kernel"},
+		{name: "../net", allow: true, err: "invalid namespace type"},
+		// Known namespaces in disguise.
+		{name: "net", allow: true, err: "is a net namespace"},
+		{name: "pid_for_children", allow: true, err: "is a pid namespace"},
+	} {
+		config := &configs.Config{
+			Rootfs:                 "/var",
+			Namespaces:             configs.Namespaces{{Type: configs.NamespaceType(tc.name)}},
+			AllowUnknownNamespaces: tc.allow,
+		}
+		err := Validate(config)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("namespace %q (allow: %v): expected error containing %q, got %v", tc.name, tc.allow, tc.err, err)
+		}
+	}
+}
+
 // TestConvertSysctlVariableToDotsSeparator tests whether the sysctl variable
 // can be correctly converted to a dot as a separator.
 func TestConvertSysctlVariableToDotsSeparator(t *testing.T) {
@@ -812,9 +887,15 @@ func TestValidateScheduler(t *testing.T) {
 		{isErr: true, policy: "SCHED_BATCH", deadline: 30},
 		{isErr: true, policy: "SCHED_IDLE", period: 40},
 		{isErr: true, policy: "SCHED_DEADLINE", priority: 100},
-		{isErr: false, policy: "SCHED_DEADLINE", runtime: 200},
-		{isErr: false, policy: "SCHED_DEADLINE", deadline: 300},
-		{isErr: false, policy: "SCHED_DEADLINE", period: 400},
+		{isErr: false, policy: "SCHED_DEADLINE", runtime: 10000, deadline: 20000},
+		{isErr: false, policy: "SCHED_DEADLINE", runtime: 10000, deadline: 20000, period: 30000},
+		{isErr: false, policy: "SCHED_DEADLINE", runtime: 20000, deadline: 20000, period: 20000},
+		{isErr: true, policy: "SCHED_DEADLINE", runtime: 200},
+		{isErr: true, policy: "SCHED_DEADLINE", deadline: 300},
+		{isErr: true, policy: "SCHED_DEADLINE", period: 400},
+		{isErr: true, policy: "SCHED_DEADLINE", runtime: 200, deadline: 300},
+		{isErr: true, policy: "SCHED_DEADLINE", runtime: 30000, deadline: 20000},
+		{isErr: true, policy: "SCHED_DEADLINE", runtime: 10000, deadline: 20000, period: 15000},
 		{isErr: true, policy: "SCHED_OTHER", niceValue: 20},
 		{isErr: true, policy: "SCHED_OTHER", niceValue: -21},
 		{isErr: false, policy: "SCHED_FIFO", priority: 100, niceValue: 100},
@@ -1001,6 +1082,46 @@ func TestValidateNetDevices(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "network device bad hardware address",
+			isErr: true,
+			config: &configs.Config{
+				Namespaces: configs.Namespaces(
+					[]configs.Namespace{
+						{
+							Type: configs.NEWNET,
+							Path: "/var/run/netns/blue",
+						},
+					},
+				),
+				NetDevices: map[string]*configs.LinuxNetDevice{
+					"eth0": {
+						HardwareAddress: "00:11:22",
+					},
+				},
+			},
+		},
+		{
+			name: "network device settings",
+			config: &configs.Config{
+				Namespaces: configs.Namespaces(
+					[]configs.Namespace{
+						{
+							Type: configs.NEWNET,
+							Path: "/var/run/netns/blue",
+						},
+					},
+				),
+				NetDevices: map[string]*configs.LinuxNetDevice{
+					"eth0": {
+						MTU:             9000,
+						HardwareAddress: "02:11:22:33:44:55",
+						TxQueueLen:      1000,
+						Queues:          4,
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -1043,3 +1164,1062 @@ func TestDevValidName(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateShm(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isErr bool
+		shm   *configs.Shm
+		ns    []configs.Namespace
+	}{
+		{name: "size", shm: &configs.Shm{Size: 65536}, ns: []configs.Namespace{{Type: configs.NEWNS}}},
+		{name: "remount policy", shm: &configs.Shm{Size: 65536, Policy: configs.ShmPolicyRemount}, ns: []configs.Namespace{{Type: configs.NEWNS}}},
+		{name: "negative size", isErr: true, shm: &configs.Shm{Size: -1}, ns: []configs.Namespace{{Type: configs.NEWNS}}},
+		{name: "bad policy", isErr: true, shm: &configs.Shm{Policy: "resize"}, ns: []configs.Namespace{{Type: configs.NEWNS}}},
+		{name: "no mount namespace", isErr: true, shm: &configs.Shm{Size: 65536}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:     "/var",
+				Namespaces: tc.ns,
+				Shm:        tc.shm,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateSwap(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isErr bool
+		swap  *configs.Swap
+	}{
+		{name: "file", swap: &configs.Swap{Type: configs.SwapFile, Size: 1 << 20, Path: "/var/lib/swapfile"}},
+		{name: "zram", swap: &configs.Swap{Type: configs.SwapZram, Size: 1 << 20}},
+		{name: "no size", isErr: true, swap: &configs.Swap{Type: configs.SwapZram}},
+		{name: "bad type", isErr: true, swap: &configs.Swap{Type: "disk", Size: 1 << 20}},
+		{name: "file without path", isErr: true, swap: &configs.Swap{Type: configs.SwapFile, Size: 1 << 20}},
+		{name: "file with relative path", isErr: true, swap: &configs.Swap{Type: configs.SwapFile, Size: 1 << 20, Path: "swapfile"}},
+		{name: "zram with path", isErr: true, swap: &configs.Swap{Type: configs.SwapZram, Size: 1 << 20, Path: "/dev/zram0"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs: "/var",
+				Swap:   tc.swap,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateCpuset(t *testing.T) {
+	testCases := []struct {
+		name    string
+		isErr   bool
+		request string
+		cpus    string
+		mems    string
+		migrate configs.MemsMigration
+	}{
+		{name: "none"},
+		{name: "cpus", cpus: "0"},
+		{name: "mems", mems: "0"},
+		{name: "missing mems", isErr: true, mems: "0,1000"},
+		{name: "migrate", mems: "0", migrate: configs.MemsMigrationMigrate},
+		{name: "reclaim", isErr: !cgroups.IsCgroup2UnifiedMode(), migrate: configs.MemsMigrationReclaim},
+		{name: "bad migration", isErr: true, migrate: "move"},
+		{name: "numa", request: "numa:0"},
+		{name: "cores", request: "cores:2-exclusive"},
+		{name: "bad request", isErr: true, request: "cores:many"},
+		{name: "request with cpus", isErr: true, request: "numa:0", cpus: "0"},
+		{name: "request in cpus", isErr: true, cpus: "numa:0"},
+		{name: "offline cpus", isErr: true, cpus: "0,65000"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs: "/var",
+				Cgroups: &cgroups.Cgroup{
+					Resources: &cgroups.Resources{CpusetCpus: tc.cpus, CpusetMems: tc.mems},
+				},
+				CpusetRequest: tc.request,
+				MemsMigration: tc.migrate,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateDelegatePty(t *testing.T) {
+	devpts := &configs.Mount{Device: "devpts", Source: "devpts", Destination: "/dev/pts/"}
+	testCases := []struct {
+		name   string
+		isErr  bool
+		mounts []*configs.Mount
+	}{
+		{name: "devpts", mounts: []*configs.Mount{devpts}},
+		{name: "no devpts", isErr: true},
+		{name: "bind devpts", isErr: true, mounts: []*configs.Mount{
+			{Device: "bind", Source: "/dev/pts", Destination: "/dev/pts", Flags: unix.MS_BIND},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:      "/var",
+				Mounts:      tc.mounts,
+				DelegatePty: true,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateLandlock(t *testing.T) {
+	testCases := []struct {
+		name     string
+		isErr    bool
+		landlock configs.Landlock
+	}{
+		{
+			name: "fs",
+			landlock: configs.Landlock{
+				HandledAccessFS: []string{"execute", "read_file", "write_file"},
+				PathBeneath: []configs.LandlockPathBeneath{
+					{AllowedAccess: []string{"execute", "read_file"}, Paths: []string{"/usr", "/etc"}},
+				},
+			},
+		},
+		{
+			name: "net",
+			landlock: configs.Landlock{
+				HandledAccessNetwork: []string{"bind_tcp", "connect_tcp"},
+				NetPort: []configs.LandlockNetPort{
+					{AllowedAccess: []string{"connect_tcp"}, Ports: []uint64{80, 443}},
+				},
+			},
+		},
+		{
+			name:  "nothing handled",
+			isErr: true,
+		},
+		{
+			name:     "unknown handled right",
+			isErr:    true,
+			landlock: configs.Landlock{HandledAccessFS: []string{"read_dir", "fly"}},
+		},
+		{
+			name:  "unknown allowed right",
+			isErr: true,
+			landlock: configs.Landlock{
+				HandledAccessNetwork: []string{"bind_tcp"},
+				NetPort:              []configs.LandlockNetPort{{AllowedAccess: []string{"bind_udp"}, Ports: []uint64{53}}},
+			},
+		},
+		{
+			name:  "allowed right not handled",
+			isErr: true,
+			landlock: configs.Landlock{
+				HandledAccessFS: []string{"read_file"},
+				PathBeneath:     []configs.LandlockPathBeneath{{AllowedAccess: []string{"write_file"}, Paths: []string{"/tmp"}}},
+			},
+		},
+		{
+			name:  "no allowed rights",
+			isErr: true,
+			landlock: configs.Landlock{
+				HandledAccessFS: []string{"read_file"},
+				PathBeneath:     []configs.LandlockPathBeneath{{Paths: []string{"/tmp"}}},
+			},
+		},
+		{
+			name:  "relative path",
+			isErr: true,
+			landlock: configs.Landlock{
+				HandledAccessFS: []string{"read_file"},
+				PathBeneath:     []configs.LandlockPathBeneath{{AllowedAccess: []string{"read_file"}, Paths: []string{"tmp"}}},
+			},
+		},
+		{
+			name:  "invalid port",
+			isErr: true,
+			landlock: configs.Landlock{
+				HandledAccessNetwork: []string{"bind_tcp"},
+				NetPort:              []configs.LandlockNetPort{{AllowedAccess: []string{"bind_tcp"}, Ports: []uint64{65536}}},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:   "/var",
+				Landlock: &tc.landlock,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateReadonlyCheck(t *testing.T) {
+	testCases := []struct {
+		name   string
+		isErr  bool
+		policy configs.ReadonlyCheck
+		noMnt  bool
+	}{
+		{name: "none"},
+		{name: "warn", policy: configs.ReadonlyCheckWarn},
+		{name: "fail", policy: configs.ReadonlyCheckFail},
+		{name: "invalid", isErr: true, policy: "ignore"},
+		{name: "no mount namespace", isErr: true, policy: configs.ReadonlyCheckWarn, noMnt: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:        "/var",
+				ReadonlyCheck: tc.policy,
+			}
+			if !tc.noMnt {
+				config.Namespaces = configs.Namespaces{{Type: configs.NEWNS}}
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateMountPolicy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		isErr  bool
+		policy *configs.MountPolicy
+	}{
+		{name: "none"},
+		{name: "nosuid,nodev", policy: &configs.MountPolicy{NoSuid: true, NoDev: true}},
+		{name: "allow", policy: &configs.MountPolicy{NoExec: true, Allow: []string{"/opt"}}},
+		{name: "no flags", isErr: true, policy: &configs.MountPolicy{Allow: []string{"/opt"}}},
+		{name: "relative allow", isErr: true, policy: &configs.MountPolicy{NoSuid: true, Allow: []string{"opt"}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:      "/var",
+				MountPolicy: tc.policy,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateTmpfiles(t *testing.T) {
+	mode := uint32(0o1777)
+	badMode := uint32(0o10000)
+	testCases := []struct {
+		name    string
+		isErr   bool
+		tmpfile configs.Tmpfile
+	}{
+		{name: "dir", tmpfile: configs.Tmpfile{Type: configs.TmpfileDir, Path: "/run/app", Mode: &mode}},
+		{name: "symlink", tmpfile: configs.Tmpfile{Type: configs.TmpfileSymlink, Path: "/etc/mtab", Argument: "../proc/self/mounts"}},
+		{name: "device", tmpfile: configs.Tmpfile{Type: configs.TmpfileCharDevice, Path: "/dev/fuse", Argument: "10:229"}},
+		{name: "relative path", isErr: true, tmpfile: configs.Tmpfile{Type: configs.TmpfileDir, Path: "run/app"}},
+		{name: "root", isErr: true, tmpfile: configs.Tmpfile{Type: configs.TmpfileAdjust, Path: "/"}},
+		{name: "no symlink target", isErr: true, tmpfile: configs.Tmpfile{Type: configs.TmpfileSymlink, Path: "/etc/mtab"}},
+		{name: "bad device number", isErr: true, tmpfile: configs.Tmpfile{Type: configs.TmpfileBlockDevice, Path: "/dev/sda", Argument: "8"}},
+		{name: "unknown type", isErr: true, tmpfile: configs.Tmpfile{Type: "p", Path: "/run/fifo"}},
+		{name: "bad mode", isErr: true, tmpfile: configs.Tmpfile{Type: configs.TmpfileDir, Path: "/run/app", Mode: &badMode}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:   "/var",
+				Tmpfiles: []*configs.Tmpfile{&tc.tmpfile},
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateExecLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		isErr  bool
+		limits configs.ExecLimits
+	}{
+		{name: "all", limits: configs.ExecLimits{MaxConcurrent: 2, MaxPerWindow: 10, Window: time.Minute, MaxTotal: 100}},
+		{name: "none"},
+		{name: "negative", isErr: true, limits: configs.ExecLimits{MaxTotal: -1}},
+		{name: "no window", isErr: true, limits: configs.ExecLimits{MaxPerWindow: 10}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:     "/var",
+				ExecLimits: &tc.limits,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateHelperCgroup(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isErr bool
+	}{
+		{name: ""},
+		{name: "runc-helpers"},
+		{name: "a/b", isErr: true},
+		{name: "..", isErr: true},
+		{name: ".hidden", isErr: true},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:       "/var",
+			HelperCgroup: tc.name,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%q: expected error, got nil", tc.name)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%q: %v", tc.name, err)
+		}
+	}
+}
+
+func TestValidateProbes(t *testing.T) {
+	valid := configs.Probe{Type: configs.ProbeLiveness, Args: []string{"/check"}, Interval: time.Second, Timeout: time.Second, FailureThreshold: 3}
+	testCases := []struct {
+		name  string
+		isErr bool
+		probe func(*configs.Probe)
+	}{
+		{name: "valid", probe: func(*configs.Probe) {}},
+		{name: "type", isErr: true, probe: func(p *configs.Probe) { p.Type = "startup" }},
+		{name: "no args", isErr: true, probe: func(p *configs.Probe) { p.Args = nil }},
+		{name: "no interval", isErr: true, probe: func(p *configs.Probe) { p.Interval = 0 }},
+		{name: "no threshold", isErr: true, probe: func(p *configs.Probe) { p.FailureThreshold = 0 }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			probe := valid
+			tc.probe(&probe)
+			config := &configs.Config{
+				Rootfs: "/var",
+				Probes: []*configs.Probe{&probe},
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateNetSysctl(t *testing.T) {
+	testCases := []struct {
+		name       string
+		isErr      bool
+		namespaces []configs.Namespace
+		sysctl     map[string]string
+	}{
+		{name: "new netns", namespaces: []configs.Namespace{{Type: configs.NEWNET}}, sysctl: map[string]string{"net.core.somaxconn": "4096", "net/ipv4/ip_forward": "1"}},
+		{name: "host netns", isErr: true, sysctl: map[string]string{"net.core.somaxconn": "4096"}},
+		{name: "netns path", isErr: true, namespaces: []configs.Namespace{{Type: configs.NEWNET, Path: "/proc/1/ns/net"}}, sysctl: map[string]string{"net.core.somaxconn": "4096"}},
+		{name: "not net", isErr: true, namespaces: []configs.Namespace{{Type: configs.NEWNET}}, sysctl: map[string]string{"kernel.msgmax": "8192"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:     "/var",
+				Namespaces: tc.namespaces,
+				NetSysctl:  tc.sysctl,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateKeepNetns(t *testing.T) {
+	testCases := []struct {
+		name       string
+		isErr      bool
+		namespaces []configs.Namespace
+	}{
+		{name: "new netns", namespaces: []configs.Namespace{{Type: configs.NEWNET}}},
+		{name: "host netns", isErr: true},
+		{name: "netns path", isErr: true, namespaces: []configs.Namespace{{Type: configs.NEWNET, Path: "/proc/1/ns/net"}}},
+		{name: "userns", isErr: true, namespaces: []configs.Namespace{{Type: configs.NEWNET}, {Type: configs.NEWUSER}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:     "/var",
+				Namespaces: tc.namespaces,
+				KeepNetns:  true,
+			}
+			if tc.namespaces != nil && tc.namespaces[len(tc.namespaces)-1].Type == configs.NEWUSER {
+				config.UIDMappings = []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 1}}
+				config.GIDMappings = []configs.IDMap{{ContainerID: 0, HostID: 1000, Size: 1}}
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateSchedCore(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:    "/var",
+		SchedCore: true,
+	}
+	err := Validate(config)
+	_, getErr := system.CoreSchedCookie(0)
+	if errors.Is(getErr, unix.EINVAL) {
+		if err == nil {
+			t.Error("expected error, got nil (core scheduling is not supported)")
+		}
+	} else if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateInitSignals(t *testing.T) {
+	testCases := []struct {
+		isErr   bool
+		signals configs.InitSignals
+	}{
+		{signals: configs.InitSignals{Blocked: []unix.Signal{unix.SIGUSR1}, Ignored: []unix.Signal{unix.SIGPIPE}}},
+		{isErr: true, signals: configs.InitSignals{Blocked: []unix.Signal{unix.SIGKILL}}},
+		{isErr: true, signals: configs.InitSignals{Ignored: []unix.Signal{unix.SIGSTOP}}},
+		{isErr: true, signals: configs.InitSignals{Blocked: []unix.Signal{0}}},
+		{isErr: true, signals: configs.InitSignals{Ignored: []unix.Signal{65}}},
+	}
+	for _, tc := range testCases {
+		config := &configs.Config{
+			Rootfs:      "/var",
+			InitSignals: &tc.signals,
+		}
+		err := Validate(config)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.signals)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%+v: %v", tc.signals, err)
+		}
+	}
+}
+
+func TestValidatePowerHint(t *testing.T) {
+	_, eppErr := os.Stat(configs.EPPPath("0"))
+	testCases := []struct {
+		name   string
+		isErr  bool
+		hint   *configs.PowerHint
+		cpuset string
+	}{
+		{name: "uclamp", hint: &configs.PowerHint{UclampMin: "50", UclampMax: "max"}},
+		{name: "epp without cpuset", isErr: true, hint: &configs.PowerHint{EPP: "performance"}},
+		{name: "epp", isErr: eppErr != nil, hint: &configs.PowerHint{EPP: "performance"}, cpuset: "0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:    "/var",
+				Cgroups:   &cgroups.Cgroup{Resources: &cgroups.Resources{CpusetCpus: tc.cpuset}},
+				PowerHint: tc.hint,
+			}
+			err := powerHint(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateIOCost(t *testing.T) {
+	testCases := []struct {
+		name   string
+		isErr  bool
+		ioCost *configs.IOCost
+	}{
+		{name: "unknown qos", isErr: true, ioCost: &configs.IOCost{QoS: []*configs.IOCostDevice{{Major: 8, Params: map[string]string{"rbps": "1000"}}}}},
+		{name: "unknown model", isErr: true, ioCost: &configs.IOCost{Model: []*configs.IOCostDevice{{Major: 8, Params: map[string]string{"rlat": "5000"}}}}},
+		{name: "no params", isErr: true, ioCost: &configs.IOCost{QoS: []*configs.IOCostDevice{{Major: 8}}}},
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		testCases = append(testCases, struct {
+			name   string
+			isErr  bool
+			ioCost *configs.IOCost
+		}{name: "cgroup v1", isErr: true, ioCost: &configs.IOCost{QoS: []*configs.IOCostDevice{{Major: 8, Params: map[string]string{"enable": "1"}}}}})
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs: "/var",
+				IOCost: tc.ioCost,
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateCpusetPartition(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("Test requires cgroup v2.")
+	}
+	testCases := []struct {
+		name      string
+		isErr     bool
+		partition string
+		cpus      string
+	}{
+		{name: "member", partition: "member"},
+		{name: "root", partition: "root", cpus: "0"},
+		{name: "isolated", partition: "isolated\n", cpus: "0"},
+		{name: "no cpus", isErr: true, partition: "root"},
+		{name: "unknown", isErr: true, partition: "root invalid", cpus: "0"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs: "/var",
+				Cgroups: &cgroups.Cgroup{
+					Resources: &cgroups.Resources{
+						CpusetCpus: tc.cpus,
+						Unified:    map[string]string{configs.CpusetPartitionFile: tc.partition},
+					},
+				},
+			}
+			err := Validate(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateSysfsWritable(t *testing.T) {
+	testCases := []struct {
+		name       string
+		isErr      bool
+		paths      []string
+		namespaces []configs.NamespaceType
+		device     string
+	}{
+		{name: "cgroup", paths: []string{"/sys/fs/cgroup"}},
+		{name: "net device", paths: []string{"/sys/class/net/eth0"}, namespaces: []configs.NamespaceType{configs.NEWNET}},
+		{name: "userns bind", paths: []string{"/sys/kernel/mm"}, namespaces: []configs.NamespaceType{configs.NEWUSER}, device: "bind"},
+		{name: "relative", isErr: true, paths: []string{"sys/fs/cgroup"}},
+		{name: "unclean", isErr: true, paths: []string{"/sys/fs/../kernel"}},
+		{name: "sys", isErr: true, paths: []string{"/sys"}},
+		{name: "not sys", isErr: true, paths: []string{"/proc/sys"}},
+		{name: "host net device", isErr: true, paths: []string{"/sys/class/net/eth0"}},
+		{name: "userns sysfs", isErr: true, paths: []string{"/sys/kernel/mm"}, namespaces: []configs.NamespaceType{configs.NEWUSER}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespaces := configs.Namespaces{{Type: configs.NEWNS}}
+			for _, ns := range tc.namespaces {
+				namespaces = append(namespaces, configs.Namespace{Type: ns})
+			}
+			device := tc.device
+			if device == "" {
+				device = "sysfs"
+			}
+			config := &configs.Config{
+				Rootfs:        "/var",
+				Namespaces:    namespaces,
+				Mounts:        []*configs.Mount{{Source: "/sys", Destination: "/sys", Device: device}},
+				SysfsWritable: tc.paths,
+			}
+			err := sysfsWritable(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	// A /sys mount is required.
+	config := &configs.Config{
+		Rootfs:        "/var",
+		Namespaces:    configs.Namespaces{{Type: configs.NEWNS}},
+		SysfsWritable: []string{"/sys/fs/cgroup"},
+	}
+	if err := Validate(config); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestValidateRootfsQuota(t *testing.T) {
+	testCases := []struct {
+		name     string
+		isErr    bool
+		rootfs   string
+		quota    *configs.RootfsQuota
+		rootless bool
+	}{
+		{name: "none", rootfs: "/var"},
+		{name: "no limits", isErr: true, rootfs: "/var", quota: &configs.RootfsQuota{ProjectID: 1}},
+		{name: "rootless", isErr: true, rootfs: "/var", quota: &configs.RootfsQuota{Size: 1 << 20}, rootless: true},
+		{name: "procfs", isErr: true, rootfs: "/proc", quota: &configs.RootfsQuota{Inodes: 100}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:       tc.rootfs,
+				RootfsQuota:  tc.quota,
+				RootlessEUID: tc.rootless,
+			}
+			err := rootfsQuota(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateIdentity(t *testing.T) {
+	proc := &configs.Mount{Source: "proc", Destination: "/proc", Device: "proc"}
+	testCases := []struct {
+		name      string
+		isErr     bool
+		machineID string
+		bootID    bool
+		noMountNS bool
+		mounts    []*configs.Mount
+	}{
+		{name: "none", noMountNS: true},
+		{name: "machine id", machineID: "0123456789abcdef0123456789abcdef"},
+		{name: "boot id", bootID: true, mounts: []*configs.Mount{proc}},
+		{name: "uppercase", isErr: true, machineID: "0123456789ABCDEF0123456789ABCDEF"},
+		{name: "short", isErr: true, machineID: "0123456789abcdef"},
+		{name: "zeros", isErr: true, machineID: "00000000000000000000000000000000"},
+		{name: "no mount ns", isErr: true, machineID: "0123456789abcdef0123456789abcdef", noMountNS: true},
+		{name: "boot id without proc", isErr: true, bootID: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:        "/var",
+				MachineID:     tc.machineID,
+				VirtualBootID: tc.bootID,
+				Mounts:        tc.mounts,
+			}
+			if !tc.noMountNS {
+				config.Namespaces = configs.Namespaces{{Type: configs.NEWNS}}
+			}
+			err := identity(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateSignalForwarding(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isErr bool
+		f     *configs.SignalForwarding
+	}{
+		{name: "none"},
+		{name: "valid", f: &configs.SignalForwarding{
+			Map:     map[unix.Signal]unix.Signal{unix.SIGTERM: unix.SIGINT},
+			Ignored: []unix.Signal{unix.SIGHUP},
+		}},
+		{name: "invalid", isErr: true, f: &configs.SignalForwarding{Ignored: []unix.Signal{65}}},
+		{name: "invalid target", isErr: true, f: &configs.SignalForwarding{
+			Map: map[unix.Signal]unix.Signal{unix.SIGTERM: 0},
+		}},
+		{name: "handled", isErr: true, f: &configs.SignalForwarding{
+			Map: map[unix.Signal]unix.Signal{unix.SIGWINCH: unix.SIGUSR1},
+		}},
+		{name: "mapped and ignored", isErr: true, f: &configs.SignalForwarding{
+			Map:     map[unix.Signal]unix.Signal{unix.SIGTERM: unix.SIGINT},
+			Ignored: []unix.Signal{unix.SIGTERM},
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{Rootfs: "/var", SignalForwarding: tc.f}
+			err := signalForwarding(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateUsernsAuto(t *testing.T) {
+	testCases := []struct {
+		name     string
+		isErr    bool
+		ns       configs.Namespaces
+		rootless bool
+	}{
+		{name: "userns", ns: configs.Namespaces{{Type: configs.NEWUSER}}},
+		{name: "no userns", isErr: true},
+		{name: "userns path", isErr: true, ns: configs.Namespaces{{Type: configs.NEWUSER, Path: "/proc/1/ns/user"}}},
+		{name: "rootless", isErr: true, ns: configs.Namespaces{{Type: configs.NEWUSER}}, rootless: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:       "/var",
+				Namespaces:   tc.ns,
+				UsernsAuto:   true,
+				RootlessEUID: tc.rootless,
+			}
+			err := usernsAuto(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateIPCLimits(t *testing.T) {
+	ipcns := configs.Namespaces{{Type: configs.NEWIPC}}
+	testCases := []struct {
+		name   string
+		isErr  bool
+		ns     configs.Namespaces
+		limits configs.IPCLimits
+		sysctl map[string]string
+	}{
+		{name: "limits", ns: ipcns, limits: configs.IPCLimits{MsgMax: 65536, SemMni: 256, MqueueMsgMax: 100, MqueueMsgDefault: 100}},
+		{name: "no ipcns", isErr: true, limits: configs.IPCLimits{MsgMax: 65536}},
+		{name: "msgmni too big", isErr: true, ns: ipcns, limits: configs.IPCLimits{MsgMni: 1 << 16}},
+		{name: "msgmax too big", isErr: true, ns: ipcns, limits: configs.IPCLimits{MsgMax: 1 << 31}},
+		{name: "mqueue msgsize_max too small", isErr: true, ns: ipcns, limits: configs.IPCLimits{MqueueMsgSizeMax: 64}},
+		{name: "mqueue msg_default above msg_max", isErr: true, ns: ipcns, limits: configs.IPCLimits{MqueueMsgMax: 10, MqueueMsgDefault: 20}},
+		{name: "sysctl conflict", isErr: true, ns: ipcns, limits: configs.IPCLimits{ShmMax: 1 << 30}, sysctl: map[string]string{"kernel.shmmax": "1024"}},
+		{name: "sem sysctl conflict", isErr: true, ns: ipcns, limits: configs.IPCLimits{SemMsl: 250}, sysctl: map[string]string{"kernel.sem": "250 32000 32 128"}},
+		{name: "other sysctl", ns: ipcns, limits: configs.IPCLimits{ShmMax: 1 << 30}, sysctl: map[string]string{"kernel.sem": "250 32000 32 128"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:     "/var",
+				Namespaces: tc.ns,
+				IPCLimits:  &tc.limits,
+				Sysctl:     tc.sysctl,
+			}
+			err := ipcLimits(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateMemoryPolicy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		isErr  bool
+		policy configs.MemoryPolicy
+		mems   string
+	}{
+		{name: "bind", policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND, Nodes: []int{0}}},
+		{name: "bind numa balancing", policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND, Nodes: []int{0}, Flags: configs.MPOL_F_NUMA_BALANCING}},
+		{name: "local", policy: configs.MemoryPolicy{Mode: configs.MPOL_LOCAL}},
+		{name: "preferred local", policy: configs.MemoryPolicy{Mode: configs.MPOL_PREFERRED}},
+		{name: "static nodes", policy: configs.MemoryPolicy{Mode: configs.MPOL_INTERLEAVE, Nodes: []int{0, 1023}, Flags: configs.MPOL_F_STATIC_NODES}},
+		{name: "invalid mode", isErr: true, policy: configs.MemoryPolicy{Mode: 42}},
+		{name: "invalid flags", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND, Nodes: []int{0}, Flags: 1}},
+		{name: "static and relative", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND, Nodes: []int{0}, Flags: configs.MPOL_F_STATIC_NODES | configs.MPOL_F_RELATIVE_NODES}},
+		{name: "interleave numa balancing", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_INTERLEAVE, Nodes: []int{0}, Flags: configs.MPOL_F_NUMA_BALANCING}},
+		{name: "default with nodes", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_DEFAULT, Nodes: []int{0}}},
+		{name: "bind without nodes", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND}},
+		{name: "node too big", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND, Nodes: []int{1024}, Flags: configs.MPOL_F_STATIC_NODES}},
+		{name: "node not in cpuset mems", isErr: true, policy: configs.MemoryPolicy{Mode: configs.MPOL_BIND, Nodes: []int{0}}, mems: "1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:       "/var",
+				MemoryPolicy: &tc.policy,
+				Cgroups:      &cgroups.Cgroup{Resources: &cgroups.Resources{CpusetMems: tc.mems}},
+			}
+			err := memoryPolicy(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateAsyncHooks(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isErr bool
+		hook  configs.HookName
+		cmd   configs.Command
+	}{
+		{name: "createRuntime", hook: configs.CreateRuntime, cmd: configs.Command{Async: true}},
+		{name: "prestart continue on error", hook: configs.Prestart, cmd: configs.Command{Async: true, ContinueOnError: true}},
+		{name: "sync poststart", hook: configs.Poststart, cmd: configs.Command{Parallel: true, Retries: 1}},
+		{name: "createContainer", isErr: true, hook: configs.CreateContainer, cmd: configs.Command{Async: true}},
+		{name: "poststop", isErr: true, hook: configs.Poststop, cmd: configs.Command{Async: true}},
+		{name: "parallel", isErr: true, hook: configs.CreateRuntime, cmd: configs.Command{Async: true, Parallel: true}},
+		{name: "retries", isErr: true, hook: configs.CreateRuntime, cmd: configs.Command{Async: true, Retries: 2}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs: "/var",
+				Hooks:  configs.Hooks{tc.hook: configs.HookList{configs.NewCommandHook(&tc.cmd)}},
+			}
+			err := asyncHooks(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidatePropagationPaths(t *testing.T) {
+	testCases := []struct {
+		name  string
+		isErr bool
+		paths []configs.PropagationPath
+		noNs  bool
+	}{
+		{name: "shared", paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_SHARED}}},
+		{name: "recursive", paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_SLAVE | unix.MS_REC}, {Path: "/mnt/a", Flags: unix.MS_PRIVATE}}},
+		{name: "no mount namespace", isErr: true, noNs: true, paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_SHARED}}},
+		{name: "root", isErr: true, paths: []configs.PropagationPath{{Path: "/", Flags: unix.MS_SHARED}}},
+		{name: "relative", isErr: true, paths: []configs.PropagationPath{{Path: "mnt", Flags: unix.MS_SHARED}}},
+		{name: "unclean", isErr: true, paths: []configs.PropagationPath{{Path: "/mnt/../mnt", Flags: unix.MS_SHARED}}},
+		{name: "twice", isErr: true, paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_SHARED}, {Path: "/mnt", Flags: unix.MS_SLAVE}}},
+		{name: "no flags", isErr: true, paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_REC}}},
+		{name: "two flags", isErr: true, paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_SHARED | unix.MS_SLAVE}}},
+		{name: "other flags", isErr: true, paths: []configs.PropagationPath{{Path: "/mnt", Flags: unix.MS_SHARED | unix.MS_RDONLY}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:           "/var",
+				PropagationPaths: tc.paths,
+			}
+			if !tc.noNs {
+				config.Namespaces = configs.Namespaces{{Type: configs.NEWNS}}
+			}
+			err := propagationPaths(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateNamespaceOwner(t *testing.T) {
+	uid := uint32(1000)
+	testCases := []struct {
+		name  string
+		isErr bool
+		check configs.NamespaceOwnerCheck
+		ns    configs.Namespace
+	}{
+		{name: "uid", ns: configs.Namespace{Type: configs.NEWNET, Path: "/run/netns/a", Owner: &configs.NamespaceOwner{UID: &uid}}},
+		{name: "inodes", check: configs.NamespaceOwnerCheckWarn, ns: configs.Namespace{Type: configs.NEWUSER, Path: "/proc/1/ns/user", Owner: &configs.NamespaceOwner{UsernsInode: 1, Inode: 2}}},
+		{name: "no owner", check: configs.NamespaceOwnerCheckFail, ns: configs.Namespace{Type: configs.NEWNET, Path: "/run/netns/a"}},
+		{name: "invalid policy", isErr: true, check: "ignore", ns: configs.Namespace{Type: configs.NEWNET}},
+		{name: "no path", isErr: true, ns: configs.Namespace{Type: configs.NEWNET, Owner: &configs.NamespaceOwner{UID: &uid}}},
+		{name: "empty owner", isErr: true, ns: configs.Namespace{Type: configs.NEWNET, Path: "/run/netns/a", Owner: &configs.NamespaceOwner{}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := &configs.Config{
+				Rootfs:              "/var",
+				Namespaces:          configs.Namespaces{tc.ns},
+				NamespaceOwnerCheck: tc.check,
+			}
+			err := namespaceOwner(config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateEnvDefaults(t *testing.T) {
+	for _, p := range []configs.EnvDefaults{"", configs.EnvDefaultsAlways, configs.EnvDefaultsIfMissing, configs.EnvDefaultsNever} {
+		if err := envDefaults(&configs.Config{EnvDefaults: p}); err != nil {
+			t.Errorf("%q: %v", p, err)
+		}
+	}
+	if err := envDefaults(&configs.Config{EnvDefaults: "sometimes"}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestValidateKeyring(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		isErr  bool
+		config configs.Config
+	}{
+		{name: "none"},
+		{name: "named", config: configs.Config{Keyring: &configs.Keyring{Name: "shared"}}},
+		{name: "links", config: configs.Config{Keyring: &configs.Keyring{Links: []configs.KeyLink{{Type: "logon", Description: "fscrypt:0123456789abcdef"}}}}},
+		{name: "no new keyring", isErr: true, config: configs.Config{NoNewKeyring: true, Keyring: &configs.Keyring{Name: "shared"}}},
+		{name: "reserved name", isErr: true, config: configs.Config{Keyring: &configs.Keyring{Name: ".builtin_trusted_keys"}}},
+		{name: "empty link", isErr: true, config: configs.Config{Keyring: &configs.Keyring{Links: []configs.KeyLink{{Type: "user"}}}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := keyring(&tc.config)
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestValidateIntelRdtShared(t *testing.T) {
+	config := &configs.Config{IntelRdt: &configs.IntelRdt{Shared: true}}
+	if err := intelrdtCheck(config); err == nil {
+		t.Error("expected error with no CLOS ID, got nil")
+	}
+	config.IntelRdt.ClosID = "shared"
+	if err := intelrdtCheck(config); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateResources(t *testing.T) {
+	config := &configs.Config{
+		Rootfs:        "/var",
+		CpusetRequest: "2",
+		Cgroups: &cgroups.Cgroup{
+			Resources: &cgroups.Resources{CpusetCpus: "0"},
+		},
+	}
+	// The cpuset request is resolved into the cpuset CPUs on create.
+	if err := ValidateResources(config); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+
+	config.Shm = &configs.Shm{Size: -1}
+	if err := ValidateResources(config); err == nil {
+		t.Error("expected an error for the invalid shm size")
+	}
+}
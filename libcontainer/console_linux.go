@@ -1,8 +1,10 @@
 package libcontainer
 
 import (
+	"errors"
 	"os"
 
+	"github.com/containerd/console"
 	"github.com/opencontainers/runc/internal/linux"
 	"golang.org/x/sys/unix"
 )
@@ -24,6 +26,39 @@ func mountConsole(slavePath string) error {
 	return mount(slavePath, "/dev/console", "bind", unix.MS_BIND, "")
 }
 
+// newDelegatedPty allocates a new pty from the devpts instance mounted at
+// /dev/pts (rather than the one /dev/ptmx leads to). In addition to the master
+// console and the slave path, it returns the slave opened via TIOCGPTPEER,
+// which guarantees it is the peer of the master.
+func newDelegatedPty() (_ console.Console, _ string, _ *os.File, retErr error) {
+	fd, err := linux.Open("/dev/pts/ptmx", unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	master := os.NewFile(uintptr(fd), "/dev/pts/ptmx")
+	defer func() {
+		if retErr != nil {
+			master.Close()
+		}
+	}()
+	var st unix.Statfs_t
+	if err := unix.Fstatfs(fd, &st); err != nil {
+		return nil, "", nil, &os.PathError{Op: "fstatfs", Path: master.Name(), Err: err}
+	}
+	if st.Type != unix.DEVPTS_SUPER_MAGIC {
+		return nil, "", nil, errors.New("/dev/pts is not a devpts mount")
+	}
+	pty, slavePath, err := console.NewPtyFromFile(master)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	peer, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), unix.TIOCGPTPEER, unix.O_RDWR|unix.O_NOCTTY|unix.O_CLOEXEC)
+	if errno != 0 {
+		return nil, "", nil, os.NewSyscallError("ioctl TIOCGPTPEER", errno)
+	}
+	return pty, slavePath, os.NewFile(peer, slavePath), nil
+}
+
 // dupStdio opens the slavePath for the console and dups the fds to the current
 // processes stdio, fd 0,1,2.
 func dupStdio(slavePath string) error {
@@ -31,6 +66,11 @@ func dupStdio(slavePath string) error {
 	if err != nil {
 		return err
 	}
+	return dupStdioFd(fd)
+}
+
+// dupStdioFd dups fd to the current processes stdio, fd 0,1,2.
+func dupStdioFd(fd int) error {
 	for _, i := range []int{0, 1, 2} {
 		if err := linux.Dup3(fd, i, 0); err != nil {
 			return err
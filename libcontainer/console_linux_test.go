@@ -0,0 +1,47 @@
+package libcontainer
+
+import (
+	"os"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestNewDelegatedPty(t *testing.T) {
+	if _, err := os.Stat("/dev/pts/ptmx"); err != nil {
+		t.Skip(err)
+	}
+	pty, slavePath, slave, err := newDelegatedPty()
+	if err != nil {
+		if os.IsPermission(err) {
+			t.Skip(err)
+		}
+		t.Fatal(err)
+	}
+	defer pty.Close()
+	defer slave.Close()
+
+	var fromPath, fromPeer unix.Stat_t
+	if err := unix.Stat(slavePath, &fromPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Fstat(int(slave.Fd()), &fromPeer); err != nil {
+		t.Fatal(err)
+	}
+	if fromPath.Rdev != fromPeer.Rdev {
+		t.Fatalf("slave path %s does not match the peer", slavePath)
+	}
+
+	// Check the master and the slave are connected.
+	if _, err := pty.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	n, err := slave.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != "hello\n" {
+		t.Fatalf("expected %q, got %q", "hello\n", buf[:n])
+	}
+}
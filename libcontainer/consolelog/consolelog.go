@@ -0,0 +1,123 @@
+// Package consolelog writes the output of a container console to a log
+// file, which is rotated once it reaches a size limit.
+package consolelog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// Options are the rotation options of a [Writer].
+type Options struct {
+	// MaxSize is the size the log file is rotated at, or 0 for no limit.
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept, as path.1 (the most
+	// recent one) to path.MaxFiles. If it is 0, the log file is truncated
+	// instead of being rotated.
+	MaxFiles int
+}
+
+// Writer writes to a log file, rotating it as configured by its [Options].
+type Writer struct {
+	path string
+	opts Options
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the log file path for appending, creating it if needed.
+func Open(path string, opts Options) (*Writer, error) {
+	if opts.MaxSize < 0 || opts.MaxFiles < 0 {
+		return nil, fmt.Errorf("invalid console log options %+v", opts)
+	}
+	w := &Writer{path: path, opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|unix.O_CLOEXEC, 0o640)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file, w.size = f, st.Size()
+	return nil
+}
+
+// rotate renames the log file to path.1 (and the older rotated files to
+// the next number, dropping the oldest one), and opens a new log file.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	if w.opts.MaxFiles == 0 {
+		if err := os.Truncate(w.path, 0); err != nil {
+			return err
+		}
+		return w.open()
+	}
+	for i := w.opts.MaxFiles - 1; i > 0; i-- {
+		err := os.Rename(w.rotated(i), w.rotated(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, w.rotated(1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func (w *Writer) rotated(i int) string {
+	return w.path + "." + strconv.Itoa(i)
+}
+
+// Write writes p to the log file, rotating it first if p would make it
+// exceed the size limit. The data is not split, so a log file may exceed
+// the size limit if it was empty.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, fmt.Errorf("unable to rotate the console log: %w", err)
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Copy copies the console output read from the pseudoterminal master r to
+// w, until the console is closed by all the container processes.
+func Copy(w io.Writer, r io.Reader) error {
+	_, err := io.Copy(w, r)
+	// Reading from the pseudoterminal master fails with EIO once the
+	// other side is closed.
+	if errors.Is(err, unix.EIO) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package consolelog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	w, err := Open(path, Options{MaxSize: 10, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, expected := range map[string]string{
+		path:        "dddddd\n",
+		path + ".1": "cccccc\n",
+		path + ".2": "bbbbbb\n",
+	} {
+		if got := readFile(t, file); got != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, got)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected %s.3 not to exist, got %v", path, err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "console.log")
+	if err := os.WriteFile(path, []byte("previous run\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := Open(path, Options{MaxSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("new output\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); got != "new output\n" {
+		t.Errorf("expected the log file to be truncated, got %q", got)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected %s.1 not to exist, got %v", path, err)
+	}
+}
+
+type eioReader struct {
+	data string
+}
+
+func (r *eioReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, &os.PathError{Op: "read", Path: "/dev/ptmx", Err: unix.EIO}
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestCopy(t *testing.T) {
+	var b strings.Builder
+	if err := Copy(&b, &eioReader{data: "hello\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "hello\n" {
+		t.Errorf("expected %q, got %q", "hello\n", b.String())
+	}
+}
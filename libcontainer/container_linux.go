@@ -2,12 +2,13 @@ package libcontainer
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
-	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
@@ -21,9 +22,14 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/runc/internal/trace"
+	"github.com/opencontainers/runc/libcontainer/cgtrace"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/exeseal"
+	"github.com/opencontainers/runc/libcontainer/faultinject"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/sched"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
@@ -44,6 +50,29 @@ type Container struct {
 	state                containerState
 	created              time.Time
 	fifo                 *os.File
+	swapDevice           string
+	rootfsProject        uint32
+	netDevices           []NetDeviceState
+	ioCostSaved          []IOCostState
+	irqAffinity          []IRQAffinityState
+	eppSaved             []EPPState
+
+	// stateDigest is the SHA-256 digest of the state.json content last
+	// loaded or saved, so that an unchanged state is not written again.
+	stateDigest [sha256.Size]byte
+
+	// asyncHooks are the asynchronous hooks started by this process, see
+	// startAsyncHooks.
+	asyncHooks []*configs.AsyncHook
+
+	// skippedResources is the list of cgroup resources which could not be
+	// applied in the rootless cgroups mode, see skippedCgroupResources.
+	skippedResources []string
+
+	// subMu protects the event subscribers, see Subscribe.
+	subMu    sync.Mutex
+	subs     map[*subscriber]struct{}
+	watching bool
 }
 
 // State represents a running container's state
@@ -73,6 +102,44 @@ type State struct {
 
 	// Intel RDT "resource control" filesystem path.
 	IntelRdtPath string `json:"intel_rdt_path,omitempty"`
+
+	// Path to the swap file or device provisioned for the container.
+	SwapDevice string `json:"swap_device,omitempty"`
+
+	// RootfsProjectID is the project ID the root filesystem quota is set
+	// for, see [configs.Config.RootfsQuota].
+	RootfsProjectID uint32 `json:"rootfs_project_id,omitempty"`
+
+	// NetDevices are the network devices moved into the container network
+	// namespace, see [configs.Config.NetDevices].
+	NetDevices []NetDeviceState `json:"net_devices,omitempty"`
+
+	// IOCostSaved are the blk-iocost parameters of the block devices before
+	// they were set for the container, see [configs.Config.IOCost].
+	IOCostSaved []IOCostState `json:"io_cost_saved,omitempty"`
+
+	// IRQAffinity are the previous affinities of the interrupts steered to
+	// the container cpuset, see [configs.Config.IRQAffinity].
+	IRQAffinity []IRQAffinityState `json:"irq_affinity,omitempty"`
+
+	// EPPSaved are the previous energy performance preferences of the
+	// container CPUs, see [configs.PowerHint].
+	EPPSaved []EPPState `json:"epp_saved,omitempty"`
+
+	// SkippedCgroupResources is the list of cgroup resources which are not
+	// in force because of a lack of permissions in the rootless cgroups
+	// mode. It may contain "cgroup" (the container cgroup could not be
+	// created), the names of the cgroup v1 controllers which could not be
+	// joined, and "devices" (the device rules could not be applied).
+	SkippedCgroupResources []string `json:"skipped_cgroup_resources,omitempty"`
+
+	// StateVersion is the version of the state format: 0 for a
+	// self-contained state, or 1 if its large fields are stored in blobs
+	// (see [configs.Config.StateBlobs]).
+	StateVersion int `json:"state_version,omitempty"`
+
+	// digest is the SHA-256 digest of the loaded state.json content.
+	digest [sha256.Size]byte
 }
 
 // ID returns the container's unique ID
@@ -85,6 +152,11 @@ func (c *Container) Config() configs.Config {
 	return *c.config
 }
 
+// Annotations returns the OCI spec annotations of the container.
+func (c *Container) Annotations() map[string]string {
+	return maps.Clone(c.config.Annotations)
+}
+
 // Status returns the current status of the container.
 func (c *Container) Status() (Status, error) {
 	c.m.Lock()
@@ -132,15 +204,33 @@ func (c *Container) Processes() ([]int, error) {
 	return pids, nil
 }
 
+// Scheduler returns the current scheduler configuration of the container
+// init process.
+func (c *Container) Scheduler() (*configs.Scheduler, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !c.hasInit() {
+		return nil, ErrNotRunning
+	}
+	return sched.Get(c.initProcess.pid())
+}
+
 // Stats returns statistics for the container.
 func (c *Container) Stats() (*Stats, error) {
 	var (
 		err   error
 		stats = &Stats{}
 	)
-	if stats.CgroupStats, err = c.cgroupManager.GetStats(); err != nil {
+	// If some cgroup controllers are gone, return the available stats
+	// along with the error.
+	var missingErr *ControllerMissingError
+	stats.CgroupStats, err = c.cgroupStats()
+	if err != nil && (!errors.As(err, &missingErr) || stats.CgroupStats == nil) {
 		return stats, fmt.Errorf("unable to get container cgroup stats: %w", err)
 	}
+	if stats.HelperCgroupStats, err = c.helperCgroupStats(); err != nil {
+		logrus.Debugf("unable to get the stats of the container helpers: %v", err)
+	}
 	if c.intelRdtManager != nil {
 		if stats.IntelRdtStats, err = c.intelRdtManager.GetStats(); err != nil {
 			return stats, fmt.Errorf("unable to get container Intel RDT stats: %w", err)
@@ -156,11 +246,42 @@ func (c *Container) Stats() (*Stats, error) {
 			stats.Interfaces = append(stats.Interfaces, istats)
 		}
 	}
+	if stats.RootfsQuotaStats, err = c.rootfsQuotaStats(); err != nil {
+		return stats, fmt.Errorf("unable to get root filesystem quota stats: %w", err)
+	}
+	if stats.SwapStats, err = c.swapStats(); err != nil {
+		return stats, fmt.Errorf("unable to get swap stats: %w", err)
+	}
+	if len(c.netDevices) > 0 && c.hasInit() {
+		istats, err := netDeviceStats(c.initProcess.pid(), c.netDevices)
+		if err != nil {
+			return stats, fmt.Errorf("unable to get network device stats: %w", err)
+		}
+		stats.Interfaces = append(stats.Interfaces, istats...)
+	}
+	if missingErr != nil {
+		return stats, missingErr
+	}
 	return stats, nil
 }
 
 // Set resources of container as configured. Can be used to change resources
 // when the container is running.
+//
+// This includes the device rules (unless config.Cgroups.SkipDevices is set),
+// which are swapped without a window where the container could access the
+// devices denied by either the old or the new rules (on cgroup v2, the eBPF
+// device filter is replaced atomically where the kernel supports it).
+//
+// The time namespace offsets (config.TimeOffsets) can't be changed, as the
+// kernel only allows to set them before a process enters the time
+// namespace. The blk-iocost parameters
+// (config.IOCost) are set in the root cgroup, and the previous ones are
+// restored when the container is destroyed.
+//
+// The resources are validated and applied the same way as when the
+// container is created (see applyResources); use [DiffResources] to know
+// which of those differ from the current ones.
 func (c *Container) Set(config configs.Config) error {
 	c.m.Lock()
 	defer c.m.Unlock()
@@ -171,31 +292,41 @@ func (c *Container) Set(config configs.Config) error {
 	if status == Stopped {
 		return ErrNotRunning
 	}
-	if err := c.cgroupManager.Set(config.Cgroups.Resources); err != nil {
-		// Set configs back
-		if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
-			logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
-		}
+	if err := c.applyResources(c.config, &config); err != nil {
 		return err
 	}
-	if c.intelRdtManager != nil {
-		if err := c.intelRdtManager.Set(&config); err != nil {
-			// Set configs back
-			if err2 := c.cgroupManager.Set(c.config.Cgroups.Resources); err2 != nil {
-				logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
-			}
-			if err2 := c.intelRdtManager.Set(c.config); err2 != nil {
-				logrus.Warnf("Setting back intelrdt configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err2)
-			}
-			return err
-		}
-	}
 	// After config setting succeed, update config and states
 	c.config = &config
 	_, err = c.updateState(nil)
 	return err
 }
 
+// TraceCgroupWrites makes the container call record for every cgroup file
+// written by subsequent Start, Run and Set calls.
+func (c *Container) TraceCgroupWrites(record func(cgtrace.Write)) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.cgroupManager = cgtrace.New(c.cgroupManager, record)
+}
+
+// DryRunSet returns the list of cgroup file writes which Set(config) would
+// do, without applying them. See [cgtrace.DryRun] for limitations.
+func (c *Container) DryRunSet(config configs.Config) ([]cgtrace.Write, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status == Stopped {
+		return nil, ErrNotRunning
+	}
+	if err := validate.ValidateResources(&config); err != nil {
+		return nil, err
+	}
+	return cgtrace.DryRun(c.cgroupManager, config.Cgroups.Resources)
+}
+
 // Start starts a process inside the container. Returns error if process fails
 // to start. You can track process lifecycle with passed Process structure.
 func (c *Container) Start(process *Process) error {
@@ -207,9 +338,30 @@ func (c *Container) Start(process *Process) error {
 // Run immediately starts the process inside the container. Returns an error if
 // the process fails to start. It does not block waiting for the exec fifo
 // after start returns but opens the fifo after start returns.
+//
+// For a non-init process with a [Process.Readiness] probe, Run also waits
+// for the process to be ready.
 func (c *Container) Run(process *Process) error {
+	if err := c.run(process); err != nil {
+		return err
+	}
+	if process.Readiness != nil && !process.Init {
+		return c.waitReady(process)
+	}
+	return nil
+}
+
+func (c *Container) run(process *Process) error {
 	c.m.Lock()
 	defer c.m.Unlock()
+	if process.Readiness != nil {
+		if process.Init {
+			return errors.New("readiness probe is only supported for exec processes")
+		}
+		if err := process.Readiness.Validate(); err != nil {
+			return err
+		}
+	}
 	if err := c.start(process); err != nil {
 		return err
 	}
@@ -227,6 +379,18 @@ func (c *Container) Exec() error {
 }
 
 func (c *Container) exec() error {
+	span := trace.Start("container.exec")
+	err := c.awaitExecFifo()
+	span.End(err)
+	if err != nil {
+		return err
+	}
+	c.publish(Event{Type: EventStarted, Pid: c.initProcess.pid()})
+	c.updateAsyncHooks()
+	return nil
+}
+
+func (c *Container) awaitExecFifo() error {
 	path := filepath.Join(c.stateDir, execFifoFilename)
 	pid := c.initProcess.pid()
 	blockingFifoOpenCh := awaitFifoOpen(path)
@@ -299,6 +463,10 @@ type openResult struct {
 }
 
 func (c *Container) start(process *Process) (retErr error) {
+	span := trace.Start("container.start")
+	span.SetAttribute("process.init", process.Init)
+	defer func() { span.End(retErr) }()
+
 	if c.config.Cgroups.Resources.SkipDevices {
 		return errors.New("can't start container with SkipDevices set")
 	}
@@ -313,6 +481,9 @@ func (c *Container) start(process *Process) (retErr error) {
 		if c.initProcessStartTime != 0 {
 			return errors.New("container already has init process")
 		}
+		if err := setupKeyringQuota(c.config.Keyring); err != nil {
+			return err
+		}
 		if err := c.createExecFifo(); err != nil {
 			return err
 		}
@@ -321,6 +492,16 @@ func (c *Container) start(process *Process) (retErr error) {
 				c.deleteExecFifo()
 			}
 		}()
+	} else if c.config.ExecLimits != nil {
+		limiter, err := c.checkExecLimits()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := limiter.done(process, retErr); err != nil {
+				logrus.Warnf("unable to account for the exec process: %v", err)
+			}
+		}()
 	}
 
 	parent, err := c.newParentProcess(process)
@@ -358,19 +539,23 @@ func (c *Container) start(process *Process) (retErr error) {
 
 	if process.Init {
 		c.fifo.Close()
+		c.publish(Event{Type: EventCreated, Pid: parent.pid()})
+		c.watch()
 		if c.config.HasHook(configs.Poststart) {
 			s, err := c.currentOCIState()
 			if err != nil {
 				return err
 			}
 
-			if err := c.config.Hooks.Run(configs.Poststart, s); err != nil {
+			if err := c.runHooks(configs.Poststart, s); err != nil {
 				if err := ignoreTerminateErrors(parent.terminate()); err != nil {
 					logrus.Warn(fmt.Errorf("error running poststart hook: %w", err))
 				}
 				return err
 			}
 		}
+	} else {
+		c.execStarted(process)
 	}
 	return nil
 }
@@ -392,26 +577,42 @@ func (c *Container) Signal(s os.Signal) error {
 	// OTOH, if PID namespace is shared, we should kill all pids to avoid
 	// leftover processes. Handle this special case here.
 	if s == unix.SIGKILL && !c.config.Namespaces.IsPrivate(configs.NEWPID) {
-		if err := signalAllProcesses(c.cgroupManager, unix.SIGKILL); err != nil {
-			if c.config.RootlessCgroups { // may not have an access to cgroup
-				logrus.WithError(err).Warn("failed to kill all processes, possibly due to lack of cgroup (Hint: enable cgroup v2 delegation)")
-				// Some processes may leak when cgroup is not delegated
-				// https://github.com/opencontainers/runc/pull/4395#pullrequestreview-2291179652
-				return c.signal(s)
-			}
-			// For not rootless container, if there is no init process and no cgroup,
-			// it means that the container is not running.
-			if errors.Is(err, ErrCgroupNotExist) && !c.hasInit() {
-				err = ErrNotRunning
-			}
-			return fmt.Errorf("unable to kill all processes: %w", err)
-		}
-		return nil
+		return c.signalAll(s)
 	}
 
 	return c.signal(s)
 }
 
+// signalAll sends s to all the container processes, through the cgroup.
+func (c *Container) signalAll(s os.Signal) error {
+	sig, ok := s.(unix.Signal)
+	if !ok {
+		return fmt.Errorf("unsupported signal %v", s)
+	}
+	if err := signalAllProcesses(c.cgroupManager, sig); err != nil {
+		if c.config.RootlessCgroups { // may not have an access to cgroup
+			logrus.WithError(err).Warn("failed to kill all processes, possibly due to lack of cgroup (Hint: enable cgroup v2 delegation)")
+			// Some processes may leak when cgroup is not delegated
+			// https://github.com/opencontainers/runc/pull/4395#pullrequestreview-2291179652
+			return c.signal(s)
+		}
+		// For not rootless container, if there is no init process and no cgroup,
+		// it means that the container is not running.
+		if errors.Is(err, ErrCgroupNotExist) && !c.hasInit() {
+			err = ErrNotRunning
+		}
+		return fmt.Errorf("unable to kill all processes: %w", err)
+	}
+	if sig == unix.SIGKILL {
+		// With cgroup.kill on a hybrid hierarchy, the processes are
+		// still frozen by the cgroup v1 freezer.
+		if paused, _ := c.isPaused(); paused {
+			_ = c.cgroupManager.Freeze(cgroups.Thawed)
+		}
+	}
+	return nil
+}
+
 func (c *Container) signal(s os.Signal) error {
 	// To avoid a PID reuse attack, don't kill non-running container.
 	if !c.hasInit() {
@@ -524,6 +725,9 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 		cmd.SysProcAttr = &unix.SysProcAttr{}
 	}
 	cmd.Env = append(cmd.Env, "GOMAXPROCS="+os.Getenv("GOMAXPROCS"))
+	if env := faultinject.Env(); env != "" {
+		cmd.Env = append(cmd.Env, env)
+	}
 	cmd.ExtraFiles = append(cmd.ExtraFiles, p.ExtraFiles...)
 	if p.ConsoleSocket != nil {
 		cmd.ExtraFiles = append(cmd.ExtraFiles, p.ConsoleSocket)
@@ -547,6 +751,9 @@ func (c *Container) newParentProcess(p *Process) (parentProcess, error) {
 	if p.LogLevel != "" {
 		cmd.Env = append(cmd.Env, "_LIBCONTAINER_LOGLEVEL="+p.LogLevel)
 	}
+	if p.CorrelationID != "" {
+		cmd.Env = append(cmd.Env, "_LIBCONTAINER_CORRELATIONID="+p.CorrelationID)
+	}
 
 	if p.PidfdSocket != nil {
 		cmd.ExtraFiles = append(cmd.ExtraFiles, p.PidfdSocket)
@@ -611,22 +818,34 @@ func (c *Container) newInitProcess(p *Process, cmd *exec.Cmd, comm *processComm)
 			nsMaps[ns.Type] = ns.Path
 		}
 	}
-	data, err := c.bootstrapData(c.config.Namespaces.CloneFlags(), nsMaps)
+	nsFiles, err := c.openOwnedNamespaces(nsMaps)
 	if err != nil {
 		return nil, err
 	}
+	data, err := c.bootstrapData(c.config.Namespaces.CloneFlags(), nsMaps, p.IDMapper != nil)
+	if err != nil {
+		closeFiles(nsFiles)
+		return nil, err
+	}
+
+	config := c.newInitConfig(p)
+	if err := c.writeIdentityFiles(config); err != nil {
+		closeFiles(nsFiles)
+		return nil, err
+	}
 
 	init := &initProcess{
 		containerProcess: containerProcess{
 			cmd:           cmd,
 			comm:          comm,
 			manager:       c.cgroupManager,
-			config:        c.newInitConfig(p),
+			config:        config,
 			process:       p,
 			bootstrapData: data,
 			container:     c,
 		},
 		intelRdtManager: c.intelRdtManager,
+		nsFiles:         nsFiles,
 	}
 	c.initProcess = init
 	return init, nil
@@ -637,7 +856,7 @@ func (c *Container) newSetnsProcess(p *Process, cmd *exec.Cmd, comm *processComm
 	state := c.currentState()
 	// for setns process, we don't have to set cloneflags as the process namespaces
 	// will only be set via setns syscall
-	data, err := c.bootstrapData(0, state.NamespacePaths)
+	data, err := c.bootstrapData(0, state.NamespacePaths, false)
 	if err != nil {
 		return nil, err
 	}
@@ -651,21 +870,18 @@ func (c *Container) newSetnsProcess(p *Process, cmd *exec.Cmd, comm *processComm
 			bootstrapData: data,
 			container:     c,
 		},
-		cgroupPaths:     state.CgroupPaths,
+		cgroupPaths:     maps.Clone(state.CgroupPaths),
 		rootlessCgroups: c.config.RootlessCgroups,
 		intelRdtPath:    state.IntelRdtPath,
 		initProcessPid:  state.InitProcessPid,
+		coreSchedPid:    state.InitProcessPid,
 	}
 	if len(p.SubCgroupPaths) > 0 {
 		if add, ok := p.SubCgroupPaths[""]; ok {
 			// cgroup v1: using the same path for all controllers.
 			// cgroup v2: the only possible way.
 			for k := range proc.cgroupPaths {
-				subPath := path.Join(proc.cgroupPaths[k], add)
-				if !strings.HasPrefix(subPath, proc.cgroupPaths[k]) {
-					return nil, fmt.Errorf("%s is not a sub cgroup path", add)
-				}
-				proc.cgroupPaths[k] = subPath
+				proc.cgroupPaths[k] = subCgroupPath(proc.cgroupPaths[k], add)
 			}
 			// cgroup v2: do not try to join init process's cgroup
 			// as a fallback (see (*setnsProcess).start).
@@ -674,16 +890,34 @@ func (c *Container) newSetnsProcess(p *Process, cmd *exec.Cmd, comm *processComm
 			// Per-controller paths.
 			for ctrl, add := range p.SubCgroupPaths {
 				if val, ok := proc.cgroupPaths[ctrl]; ok {
-					subPath := path.Join(val, add)
-					if !strings.HasPrefix(subPath, val) {
-						return nil, fmt.Errorf("%s is not a sub cgroup path", add)
-					}
-					proc.cgroupPaths[ctrl] = subPath
+					proc.cgroupPaths[ctrl] = subCgroupPath(val, add)
 				} else {
 					return nil, fmt.Errorf("unknown controller %s in SubCgroupPaths", ctrl)
 				}
 			}
 		}
+		if p.CreateSubCgroups {
+			for ctrl, path := range proc.cgroupPaths {
+				if err := createSubCgroup(state.CgroupPaths[ctrl], path, ctrl); err != nil {
+					return nil, fmt.Errorf("unable to create sub-cgroup %s: %w", path, err)
+				}
+			}
+		}
+	}
+	if p.SubCgroupResources != nil {
+		if len(p.SubCgroupPaths) > 0 {
+			return nil, errors.New("SubCgroupResources can't be used together with SubCgroupPaths")
+		}
+		proc.transient, err = newTransientCgroup(state.CgroupPaths, p.SubCgroupResources)
+		if err != nil {
+			return nil, err
+		}
+		for k, path := range proc.cgroupPaths {
+			proc.cgroupPaths[k] = subCgroupPath(path, proc.transient.name)
+		}
+		// As with SubCgroupPaths, do not fall back to joining the cgroup
+		// of the container init.
+		proc.initProcessPid = 0
 	}
 	return proc, nil
 }
@@ -710,6 +944,7 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 		IOPriority:       c.config.IOPriority,
 		Scheduler:        c.config.Scheduler,
 		CPUAffinity:      c.config.ExecCPUAffinity,
+		EnvDefaults:      c.config.EnvDefaults,
 		CreateConsole:    process.ConsoleSocket != nil,
 		ConsoleWidth:     process.ConsoleWidth,
 		ConsoleHeight:    process.ConsoleHeight,
@@ -726,6 +961,12 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 	if process.AppArmorProfile != "" {
 		cfg.AppArmorProfile = process.AppArmorProfile
 	}
+	if process.Seccomp != nil {
+		// Do not modify the container configuration.
+		config := *c.config
+		config.Seccomp = process.Seccomp
+		cfg.Config = &config
+	}
 	if process.Label != "" {
 		cfg.ProcessLabel = process.Label
 	}
@@ -738,7 +979,12 @@ func (c *Container) newInitConfig(process *Process) *initConfig {
 	if process.Scheduler != nil {
 		cfg.Scheduler = process.Scheduler
 	}
-	if process.CPUAffinity != nil {
+	if process.EnvDefaults != "" {
+		cfg.EnvDefaults = process.EnvDefaults
+	}
+	if process.Init {
+		cfg.CPUAffinity = c.config.InitCPUAffinity
+	} else if process.CPUAffinity != nil {
 		cfg.CPUAffinity = process.CPUAffinity
 	}
 
@@ -767,6 +1013,97 @@ func (c *Container) Destroy() error {
 	return nil
 }
 
+// abortTimeout is how long Abort waits for the container processes to be gone.
+const abortTimeout = 10 * time.Second
+
+// Abort aborts a container which was created but not started (see
+// [Container.Exec]), including one whose runc init is stuck or still being
+// set up. It kills all the container processes, waits for them to be gone
+// (so that the container mounts are released along with its mount
+// namespace), and then destroys the container, as [Container.Destroy] does.
+//
+// A stopped container is destroyed. It is an error to abort a container
+// which has been started.
+func (c *Container) Abort() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	// The exec fifo is removed once the container is started.
+	_, err = os.Stat(filepath.Join(c.stateDir, execFifoFilename))
+	started := err != nil
+	switch {
+	case status == Running:
+		return ErrRunning
+	case status == Paused && started:
+		return ErrPaused
+	}
+	if status != Stopped && c.hasInit() {
+		if err := c.killAll(); err != nil {
+			return fmt.Errorf("unable to abort container: %w", err)
+		}
+		// Make sure the state is refreshed.
+		c.state = &stoppedState{c: c}
+	}
+	if err := destroy(c); err != nil {
+		return fmt.Errorf("unable to destroy container: %w", err)
+	}
+	return nil
+}
+
+// killAll kills all the container processes, and waits (up to abortTimeout)
+// for them to be gone.
+func (c *Container) killAll() error {
+	pid := c.initProcess.pid()
+	// Open the pidfd before killing init, to be able to wait for it to
+	// exit even if its PID is reused.
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err == nil {
+		defer unix.Close(pidfd)
+	} else {
+		pidfd = -1
+	}
+	if err := signalAllProcesses(c.cgroupManager, unix.SIGKILL); err != nil {
+		// No cgroup (e.g. rootless), or no access to it.
+		if err := c.initProcess.signal(unix.SIGKILL); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(abortTimeout)
+	if pidfd != -1 {
+		fds := []unix.PollFd{{Fd: int32(pidfd), Events: unix.POLLIN}}
+		for {
+			n, err := unix.Poll(fds, int(time.Until(deadline).Milliseconds()))
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				return os.NewSyscallError("poll", err)
+			}
+			if n == 0 {
+				return fmt.Errorf("container init (pid %d) did not exit in %s", pid, abortTimeout)
+			}
+			break
+		}
+	}
+	// Wait for the other processes, such as the ones forked by runc init
+	// while being set up, which are in the container cgroup.
+	for c.cgroupManager.Exists() {
+		pids, err := c.cgroupManager.GetAllPids()
+		if err != nil || len(pids) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("container processes %v did not exit in %s", pids, abortTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
 // Pause pauses the container, if its state is RUNNING or CREATED, changing
 // its state to PAUSED. If the state is already PAUSED, does nothing.
 func (c *Container) Pause() error {
@@ -781,9 +1118,13 @@ func (c *Container) Pause() error {
 		if err := c.cgroupManager.Freeze(cgroups.Frozen); err != nil {
 			return err
 		}
-		return c.state.transition(&pausedState{
+		if err := c.state.transition(&pausedState{
 			c: c,
-		})
+		}); err != nil {
+			return err
+		}
+		c.publish(Event{Type: EventPaused})
+		return nil
 	}
 	return ErrNotRunning
 }
@@ -805,9 +1146,13 @@ func (c *Container) Resume() error {
 	if err := c.cgroupManager.Freeze(cgroups.Thawed); err != nil {
 		return err
 	}
-	return c.state.transition(&runningState{
+	if err := c.state.transition(&runningState{
 		c: c,
-	})
+	}); err != nil {
+		return err
+	}
+	c.publish(Event{Type: EventResumed})
+	return nil
 }
 
 // NotifyOOM returns a read-only channel signaling when the container receives
@@ -846,6 +1191,16 @@ func (c *Container) updateState(process parentProcess) (*State, error) {
 }
 
 func (c *Container) saveState(s *State) (retErr error) {
+	data, digests, err := c.encodeState(s)
+	if err != nil {
+		return err
+	}
+	// The state is saved by most operations, but seldom changes.
+	digest := sha256.Sum256(data)
+	if digest == c.stateDigest {
+		return nil
+	}
+
 	tmpFile, err := os.CreateTemp(c.stateDir, "state-")
 	if err != nil {
 		return err
@@ -858,7 +1213,7 @@ func (c *Container) saveState(s *State) (retErr error) {
 		}
 	}()
 
-	err = utils.WriteJSON(tmpFile, s)
+	_, err = tmpFile.Write(data)
 	if err != nil {
 		return err
 	}
@@ -868,7 +1223,14 @@ func (c *Container) saveState(s *State) (retErr error) {
 	}
 
 	stateFilePath := filepath.Join(c.stateDir, stateFilename)
-	return os.Rename(tmpFile.Name(), stateFilePath)
+	if err := os.Rename(tmpFile.Name(), stateFilePath); err != nil {
+		return err
+	}
+	c.stateDigest = digest
+	if s.Config.StateBlobs {
+		c.removeUnusedBlobs(digests)
+	}
+	return nil
 }
 
 func (c *Container) currentStatus() (Status, error) {
@@ -949,11 +1311,18 @@ func (c *Container) currentState() *State {
 			InitProcessStartTime: startTime,
 			Created:              c.created,
 		},
-		Rootless:            c.config.RootlessEUID && c.config.RootlessCgroups,
-		CgroupPaths:         c.cgroupManager.GetPaths(),
-		IntelRdtPath:        intelRdtPath,
-		NamespacePaths:      make(map[configs.NamespaceType]string),
-		ExternalDescriptors: externalDescriptors,
+		Rootless:               c.config.RootlessEUID && c.config.RootlessCgroups,
+		CgroupPaths:            c.cgroupManager.GetPaths(),
+		IntelRdtPath:           intelRdtPath,
+		NamespacePaths:         make(map[configs.NamespaceType]string),
+		ExternalDescriptors:    externalDescriptors,
+		SwapDevice:             c.swapDevice,
+		RootfsProjectID:        c.rootfsProject,
+		NetDevices:             c.netDevices,
+		IOCostSaved:            c.ioCostSaved,
+		IRQAffinity:            c.irqAffinity,
+		EPPSaved:               c.eppSaved,
+		SkippedCgroupResources: c.skippedResources,
 	}
 	if pid > 0 {
 		for _, ns := range c.config.Namespaces {
@@ -973,12 +1342,11 @@ func (c *Container) currentState() *State {
 }
 
 func (c *Container) currentOCIState() (*specs.State, error) {
-	bundle, annotations := utils.Annotations(c.config.Labels)
 	state := &specs.State{
 		Version:     specs.Version,
 		ID:          c.ID(),
-		Bundle:      bundle,
-		Annotations: annotations,
+		Bundle:      c.config.Bundle(),
+		Annotations: maps.Clone(c.config.Annotations),
 	}
 	status, err := c.currentStatus()
 	if err != nil {
@@ -997,7 +1365,15 @@ func (c *Container) currentOCIState() (*specs.State, error) {
 // can setns in order.
 func (c *Container) orderNamespacePaths(namespaces map[configs.NamespaceType]string) ([]string, error) {
 	paths := []string{}
-	for _, ns := range configs.NamespaceTypes() {
+	types := configs.NamespaceTypes()
+	// The unknown namespaces (see configs.Config.AllowUnknownNamespaces)
+	// are joined after the known ones.
+	for _, ns := range c.config.Namespaces {
+		if !configs.IsKnownNamespace(ns.Type) {
+			types = append(types, ns.Type)
+		}
+	}
+	for _, ns := range types {
 
 		// Remove namespaces that we don't need to join.
 		if !c.config.Namespaces.Contains(ns) {
@@ -1047,8 +1423,9 @@ type netlinkError struct{ error }
 // Consumer can write the data to a bootstrap program
 // such as one that uses nsenter package to bootstrap the container's
 // init process correctly, i.e. with correct namespaces, uid/gid
-// mapping etc.
-func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.NamespaceType]string) (_ io.Reader, Err error) {
+// mapping etc. If delegateIDMap is set, the bootstrap program requests
+// the uid/gid mappings to be set up by runc (see [Process.IDMapper]).
+func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.NamespaceType]string, delegateIDMap bool) (_ io.Reader, Err error) {
 	// create the netlink message
 	r := nl.NewNetlinkRequest(int(InitMsg), 0)
 
@@ -1086,9 +1463,15 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 	// write namespace paths only when we are not joining an existing user ns
 	_, joinExistingUser := nsMaps[configs.NEWUSER]
 	if !joinExistingUser {
+		if delegateIDMap && (len(c.config.UIDMappings) > 0 || len(c.config.GIDMappings) > 0) {
+			r.AddData(&Boolmsg{
+				Type:  IDMapDelegateAttr,
+				Value: true,
+			})
+		}
 		// write uid mappings
 		if len(c.config.UIDMappings) > 0 {
-			if c.config.RootlessEUID {
+			if c.config.RootlessEUID && !delegateIDMap {
 				// We resolve the paths for new{u,g}idmap from
 				// the context of runc to avoid doing a path
 				// lookup in the nsexec context.
@@ -1119,7 +1502,7 @@ func (c *Container) bootstrapData(cloneFlags uintptr, nsMaps map[configs.Namespa
 				Type:  GidmapAttr,
 				Value: b,
 			})
-			if c.config.RootlessEUID {
+			if c.config.RootlessEUID && !delegateIDMap {
 				if path, err := exec.LookPath("newgidmap"); err == nil {
 					r.AddData(&Bytemsg{
 						Type:  GidmapPathAttr,
@@ -11,9 +11,10 @@ import (
 )
 
 type mockCgroupManager struct {
-	pids    []int
-	allPids []int
-	paths   map[string]string
+	pids       []int
+	allPids    []int
+	paths      map[string]string
+	destroyErr error
 }
 
 func (m *mockCgroupManager) GetPids() ([]int, error) {
@@ -37,7 +38,7 @@ func (m *mockCgroupManager) Set(_ *cgroups.Resources) error {
 }
 
 func (m *mockCgroupManager) Destroy() error {
-	return nil
+	return m.destroyErr
 }
 
 func (m *mockCgroupManager) Exists() bool {
@@ -0,0 +1,182 @@
+// Package cpuset implements parsing of CPU lists, and resolving of symbolic
+// cpuset requests (such as "numa:1" or "cores:4-exclusive") against the host
+// CPU topology.
+package cpuset
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a CPU (or memory node) list in the kernel format, such as
+// "0-3,8,10-11", returning a sorted list of unique numbers. A trailing
+// newline (as found in sysfs files) is ignored.
+func Parse(list string) ([]int, error) {
+	list = strings.TrimSuffix(list, "\n")
+	if list == "" {
+		return nil, nil
+	}
+	var ret []int
+	for _, r := range strings.Split(list, ",") {
+		r0, r1, isRange := strings.Cut(r, "-")
+		start, err := strconv.ParseUint(r0, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid list %q: %w", list, err)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.ParseUint(r1, 10, 16); err != nil {
+				return nil, fmt.Errorf("invalid list %q: %w", list, err)
+			}
+			if start > end {
+				return nil, fmt.Errorf("invalid list %q: invalid range %s", list, r)
+			}
+		}
+		for i := start; i <= end; i++ {
+			ret = append(ret, int(i))
+		}
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret), nil
+}
+
+// Format is the opposite of Parse. The list must be sorted.
+func Format(list []int) string {
+	var b strings.Builder
+	for i := 0; i < len(list); {
+		j := i
+		for j+1 < len(list) && list[j+1] == list[j]+1 {
+			j++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(list[i]))
+		if j > i {
+			b.WriteByte('-')
+			b.WriteString(strconv.Itoa(list[j]))
+		}
+		i = j + 1
+	}
+	return b.String()
+}
+
+const (
+	numaPrefix      = "numa:"
+	coresPrefix     = "cores:"
+	exclusiveSuffix = "-exclusive"
+)
+
+// IsRequest tells whether s is a symbolic cpuset request rather than
+// a CPU list.
+func IsRequest(s string) bool {
+	return strings.HasPrefix(s, numaPrefix) || strings.HasPrefix(s, coresPrefix)
+}
+
+// Request is a symbolic cpuset request. It is one of:
+//
+//   - numa:<nodes> -- all CPUs of the given NUMA node(s), for example
+//     "numa:1" or "numa:0-1";
+//   - cores:<n> -- n physical cores (that is, including all their SMT
+//     siblings), preferably from a single NUMA node;
+//   - cores:<n>-exclusive -- same as above, but only using cores which
+//     are not exclusively allocated to other containers.
+type Request struct {
+	// Nodes is the list of NUMA nodes, for a numa: request.
+	Nodes []int
+	// Cores is the number of physical cores, for a cores: request.
+	Cores int
+	// Exclusive is set for a cores:<n>-exclusive request.
+	Exclusive bool
+}
+
+// ParseRequest parses a symbolic cpuset request.
+func ParseRequest(s string) (*Request, error) {
+	if nodes, ok := strings.CutPrefix(s, numaPrefix); ok {
+		list, err := Parse(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cpuset request %q: %w", s, err)
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("invalid cpuset request %q: no NUMA nodes", s)
+		}
+		return &Request{Nodes: list}, nil
+	}
+	if cores, ok := strings.CutPrefix(s, coresPrefix); ok {
+		r := &Request{}
+		cores, r.Exclusive = strings.CutSuffix(cores, exclusiveSuffix)
+		n, err := strconv.ParseUint(cores, 10, 16)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid cpuset request %q: bad number of cores", s)
+		}
+		r.Cores = int(n)
+		return r, nil
+	}
+	return nil, fmt.Errorf("invalid cpuset request %q", s)
+}
+
+// Resolve returns the CPUs and memory nodes to use for the request, given the
+// host topology t and the list of CPUs exclusively allocated to other
+// containers.
+func (r *Request) Resolve(t *Topology, exclusive []int) (cpus, mems []int, _ error) {
+	if r.Cores == 0 {
+		for _, node := range r.Nodes {
+			nodeCPUs, ok := t.Nodes[node]
+			if !ok {
+				return nil, nil, fmt.Errorf("NUMA node %d does not exist", node)
+			}
+			if len(nodeCPUs) == 0 {
+				return nil, nil, fmt.Errorf("NUMA node %d has no online CPUs", node)
+			}
+			cpus = append(cpus, nodeCPUs...)
+		}
+		slices.Sort(cpus)
+		return cpus, r.Nodes, nil
+	}
+
+	// Group the available cores by NUMA node.
+	byNode := make(map[int][][]int)
+	var nodes []int
+	for _, core := range t.Cores {
+		if r.Exclusive && slices.ContainsFunc(core, func(cpu int) bool {
+			return slices.Contains(exclusive, cpu)
+		}) {
+			continue
+		}
+		node := t.nodeOf(core[0])
+		if _, ok := byNode[node]; !ok {
+			nodes = append(nodes, node)
+		}
+		byNode[node] = append(byNode[node], core)
+	}
+	slices.Sort(nodes)
+
+	var cores [][]int
+	for _, node := range nodes {
+		if len(byNode[node]) >= r.Cores {
+			cores = byNode[node][:r.Cores]
+			break
+		}
+	}
+	if cores == nil {
+		// No single node has enough cores, so span multiple nodes.
+		for _, node := range nodes {
+			cores = append(cores, byNode[node]...)
+		}
+		if len(cores) < r.Cores {
+			return nil, nil, fmt.Errorf("not enough available CPU cores: requested %d, available %d", r.Cores, len(cores))
+		}
+		cores = cores[:r.Cores]
+	}
+	for _, core := range cores {
+		cpus = append(cpus, core...)
+		if node := t.nodeOf(core[0]); !slices.Contains(mems, node) {
+			mems = append(mems, node)
+		}
+	}
+	slices.Sort(cpus)
+	slices.Sort(mems)
+	return cpus, mems, nil
+}
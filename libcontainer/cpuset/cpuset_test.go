@@ -0,0 +1,180 @@
+package cpuset
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		in    string
+		out   []int
+		str   string
+		isErr bool
+	}{
+		{in: "", out: nil, str: ""},
+		{in: "0\n", out: []int{0}, str: "0"},
+		{in: "0-3,8,10-11", out: []int{0, 1, 2, 3, 8, 10, 11}, str: "0-3,8,10-11"},
+		{in: "5,1-2,2", out: []int{1, 2, 5}, str: "1-2,5"},
+		{in: "3-1", isErr: true},
+		{in: "1,,2", isErr: true},
+		{in: "-1", isErr: true},
+		{in: "a", isErr: true},
+	}
+	for _, tc := range testCases {
+		out, err := Parse(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if !slices.Equal(out, tc.out) {
+			t.Errorf("%q: expected %v, got %v", tc.in, tc.out, out)
+		}
+		if str := Format(out); str != tc.str {
+			t.Errorf("%q: expected %q, got %q", tc.in, tc.str, str)
+		}
+	}
+}
+
+func TestParseRequest(t *testing.T) {
+	testCases := []struct {
+		in    string
+		req   Request
+		isErr bool
+	}{
+		{in: "numa:1", req: Request{Nodes: []int{1}}},
+		{in: "numa:0-1", req: Request{Nodes: []int{0, 1}}},
+		{in: "cores:4", req: Request{Cores: 4}},
+		{in: "cores:4-exclusive", req: Request{Cores: 4, Exclusive: true}},
+		{in: "numa:", isErr: true},
+		{in: "cores:0", isErr: true},
+		{in: "cores:-exclusive", isErr: true},
+		{in: "cores:4-shared", isErr: true},
+		{in: "0-3", isErr: true},
+	}
+	for _, tc := range testCases {
+		req, err := ParseRequest(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if !slices.Equal(req.Nodes, tc.req.Nodes) || req.Cores != tc.req.Cores || req.Exclusive != tc.req.Exclusive {
+			t.Errorf("%q: expected %+v, got %+v", tc.in, tc.req, *req)
+		}
+	}
+}
+
+// testTopology is a host with two NUMA nodes, each having 4 cores with
+// 2 threads each, numbered like on x86 (0-3 and 8-11 on node 0).
+func testTopology() *Topology {
+	t := &Topology{Nodes: map[int][]int{
+		0: {0, 1, 2, 3, 8, 9, 10, 11},
+		1: {4, 5, 6, 7, 12, 13, 14, 15},
+	}}
+	for cpu := 0; cpu < 8; cpu++ {
+		t.Cores = append(t.Cores, []int{cpu, cpu + 8})
+		t.Online = append(t.Online, cpu, cpu+8)
+	}
+	slices.Sort(t.Online)
+	return t
+}
+
+func TestResolve(t *testing.T) {
+	testCases := []struct {
+		req       string
+		exclusive string
+		cpus      string
+		mems      string
+		isErr     bool
+	}{
+		{req: "numa:1", cpus: "4-7,12-15", mems: "1"},
+		{req: "numa:0-1", cpus: "0-15", mems: "0-1"},
+		{req: "numa:2", isErr: true},
+		{req: "cores:2", cpus: "0-1,8-9", mems: "0"},
+		{req: "cores:2", exclusive: "0-3", cpus: "0-1,8-9", mems: "0"},
+		{req: "cores:2-exclusive", exclusive: "0-1", cpus: "2-3,10-11", mems: "0"},
+		{req: "cores:2-exclusive", exclusive: "0-2", cpus: "4-5,12-13", mems: "1"},
+		{req: "cores:6", cpus: "0-5,8-13", mems: "0-1"},
+		{req: "cores:8-exclusive", exclusive: "15", isErr: true},
+		{req: "cores:9", isErr: true},
+	}
+	topo := testTopology()
+	for _, tc := range testCases {
+		req, err := ParseRequest(tc.req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exclusive, err := Parse(tc.exclusive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cpus, mems, err := req.Resolve(topo, exclusive)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tc.req)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.req, err)
+			continue
+		}
+		if c, m := Format(cpus), Format(mems); c != tc.cpus || m != tc.mems {
+			t.Errorf("%s (exclusive %q): expected cpus %q mems %q, got %q %q", tc.req, tc.exclusive, tc.cpus, tc.mems, c, m)
+		}
+	}
+}
+
+func TestReadTopology(t *testing.T) {
+	root := t.TempDir()
+	write := func(path, data string) {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// CPU 3 is offline.
+	write("cpu/online", "0-2")
+	write("cpu/cpu0/topology/core_cpus_list", "0,2")
+	write("cpu/cpu1/topology/thread_siblings_list", "1,3")
+	write("cpu/cpu2/topology/core_cpus_list", "0,2")
+	write("node/node0/cpulist", "0,2")
+	write("node/node1/cpulist", "1,3")
+	// Node 1 is CPU-only.
+	write("node/has_memory", "0")
+
+	topo, err := readTopology(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(topo.Online, []int{0, 1, 2}) {
+		t.Errorf("unexpected online CPUs: %v", topo.Online)
+	}
+	if len(topo.Cores) != 2 || !slices.Equal(topo.Cores[0], []int{0, 2}) || !slices.Equal(topo.Cores[1], []int{1}) {
+		t.Errorf("unexpected cores: %v", topo.Cores)
+	}
+	if len(topo.Nodes) != 2 || !slices.Equal(topo.Nodes[0], []int{0, 2}) || !slices.Equal(topo.Nodes[1], []int{1}) {
+		t.Errorf("unexpected nodes: %v", topo.Nodes)
+	}
+	if !slices.Equal(topo.MemoryNodes, []int{0}) {
+		t.Errorf("unexpected memory nodes: %v", topo.MemoryNodes)
+	}
+}
@@ -0,0 +1,127 @@
+package cpuset
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Topology is the host CPU topology.
+type Topology struct {
+	// Online is the list of online CPUs.
+	Online []int
+	// Cores is the list of physical cores, each being a list of online
+	// CPUs (SMT siblings), ordered by the first CPU.
+	Cores [][]int
+	// Nodes maps a NUMA node number to the list of its online CPUs.
+	Nodes map[int][]int
+	// MemoryNodes is the list of NUMA nodes with memory.
+	MemoryNodes []int
+}
+
+// ErrNoTopology is returned when the host CPU topology is not available.
+var ErrNoTopology = errors.New("host CPU topology not available")
+
+// HostTopology reads the CPU topology of the host from sysfs.
+func HostTopology() (*Topology, error) {
+	return readTopology("/sys/devices/system")
+}
+
+func readList(path string) ([]int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data))
+}
+
+func readTopology(root string) (*Topology, error) {
+	online, err := readList(filepath.Join(root, "cpu", "online"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoTopology
+		}
+		return nil, err
+	}
+	t := &Topology{Online: online, Nodes: make(map[int][]int)}
+
+	seen := make(map[int]bool, len(online))
+	for _, cpu := range online {
+		if seen[cpu] {
+			continue
+		}
+		dir := filepath.Join(root, "cpu", "cpu"+strconv.Itoa(cpu), "topology")
+		// core_cpus_list is the new name for thread_siblings_list (Linux 5.7).
+		siblings, err := readList(filepath.Join(dir, "core_cpus_list"))
+		if os.IsNotExist(err) {
+			siblings, err = readList(filepath.Join(dir, "thread_siblings_list"))
+		}
+		if os.IsNotExist(err) {
+			siblings, err = []int{cpu}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var core []int
+		for _, s := range siblings {
+			if slices.Contains(online, s) && !seen[s] {
+				seen[s] = true
+				core = append(core, s)
+			}
+		}
+		if !slices.Contains(core, cpu) {
+			// Inconsistent sysfs data; treat cpu as a separate core.
+			seen[cpu] = true
+			core = []int{cpu}
+		}
+		t.Cores = append(t.Cores, core)
+	}
+
+	nodeDirs, err := filepath.Glob(filepath.Join(root, "node", "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	if len(nodeDirs) == 0 {
+		// The kernel is compiled without NUMA support.
+		t.Nodes[0] = online
+		t.MemoryNodes = []int{0}
+		return t, nil
+	}
+	for _, dir := range nodeDirs {
+		node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		cpus, err := readList(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read NUMA node %d CPUs: %w", node, err)
+		}
+		t.Nodes[node] = slices.DeleteFunc(cpus, func(cpu int) bool {
+			return !slices.Contains(online, cpu)
+		})
+	}
+	t.MemoryNodes, err = readList(filepath.Join(root, "node", "has_memory"))
+	if os.IsNotExist(err) {
+		t.MemoryNodes = slices.Sorted(maps.Keys(t.Nodes))
+		err = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read NUMA nodes with memory: %w", err)
+	}
+	return t, nil
+}
+
+// nodeOf returns the NUMA node of the cpu.
+func (t *Topology) nodeOf(cpu int) int {
+	for node, cpus := range t.Nodes {
+		if slices.Contains(cpus, cpu) {
+			return node
+		}
+	}
+	return 0
+}
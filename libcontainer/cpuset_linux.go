@@ -0,0 +1,157 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
+)
+
+const (
+	// cpusetLockFilename is the file of the root directory which is locked
+	// while the CPUs of an "exclusive" request are chosen and reserved.
+	cpusetLockFilename = "cpuset.lock"
+	// cpusetReservedFilename is the file of a container state directory
+	// which holds the CPUs exclusively allocated to the container, until
+	// its state is saved.
+	cpusetReservedFilename = "cpuset.reserved"
+)
+
+// resolveCpuset resolves the symbolic cpuset request from config, if any,
+// against the host CPU topology, and sets the container cpuset accordingly.
+//
+// For an "exclusive" request, the root directory is locked until the
+// returned reservation is released, so that the concurrent creations do not
+// choose the same CPUs; the reservation must be saved into the container
+// state directory before.
+func resolveCpuset(root string, config *configs.Config) (*cpusetReservation, error) {
+	if config.CpusetRequest == "" {
+		return nil, nil
+	}
+	req, err := cpuset.ParseRequest(config.CpusetRequest)
+	if err != nil {
+		return nil, err
+	}
+	topo, err := cpuset.HostTopology()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rsv       *cpusetReservation
+		exclusive []int
+	)
+	if req.Exclusive {
+		if rsv, err = lockCpusets(root); err != nil {
+			return nil, err
+		}
+		if exclusive, err = exclusiveCPUs(root); err != nil {
+			rsv.release()
+			return nil, err
+		}
+	}
+	cpus, mems, err := req.Resolve(topo, exclusive)
+	if err != nil {
+		rsv.release()
+		return nil, fmt.Errorf("unable to resolve cpuset request %q: %w", config.CpusetRequest, err)
+	}
+	r := config.Cgroups.Resources
+	r.CpusetCpus = cpuset.Format(cpus)
+	if r.CpusetMems == "" {
+		r.CpusetMems = cpuset.Format(mems)
+	}
+	if rsv != nil {
+		rsv.cpus = r.CpusetCpus
+	}
+	return rsv, nil
+}
+
+// cpusetReservation is a set of CPUs being exclusively allocated to a
+// container, with the root directory locked.
+type cpusetReservation struct {
+	lock *os.File
+	cpus string
+}
+
+// lockCpusets locks the cpuset lock file of root.
+func lockCpusets(root string) (*cpusetReservation, error) {
+	path := filepath.Join(root, cpusetLockFilename)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open cpuset lock file: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to lock cpuset lock file: %w", err)
+	}
+	return &cpusetReservation{lock: f}, nil
+}
+
+// save records the reserved CPUs in the container state directory, so that
+// they are taken into account by the other creations before the container
+// state is saved. The reservation is removed along with the directory.
+func (r *cpusetReservation) save(stateDir string) error {
+	if r == nil {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(stateDir, cpusetReservedFilename), []byte(r.cpus), 0o600); err != nil {
+		return fmt.Errorf("unable to reserve cpuset: %w", err)
+	}
+	return nil
+}
+
+// release unlocks the root directory.
+func (r *cpusetReservation) release() {
+	if r != nil {
+		r.lock.Close()
+	}
+}
+
+// exclusiveCPUs returns the list of CPUs exclusively allocated to containers
+// in the given root directory, including the ones being created. It must
+// be called with the root locked by lockCpusets.
+func exclusiveCPUs(root string) ([]int, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	var ret []int
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		stateDir := filepath.Join(root, e.Name())
+		var spec string
+		if state, err := loadState(stateDir); err == nil {
+			config := &state.Config
+			if config.CpusetRequest == "" {
+				continue
+			}
+			if req, err := cpuset.ParseRequest(config.CpusetRequest); err != nil || !req.Exclusive {
+				continue
+			}
+			spec = config.Cgroups.Resources.CpusetCpus
+		} else {
+			// Not a container, or not yet started.
+			data, err := os.ReadFile(filepath.Join(stateDir, cpusetReservedFilename))
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			spec = strings.TrimSpace(string(data))
+		}
+		cpus, err := cpuset.Parse(spec)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, cpus...)
+	}
+	return ret, nil
+}
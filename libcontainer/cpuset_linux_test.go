@@ -0,0 +1,37 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestExclusiveCPUsReserved(t *testing.T) {
+	root := t.TempDir()
+	rsv, err := lockCpusets(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsv.release()
+	rsv.cpus = "2-3"
+	stateDir := filepath.Join(root, "creating")
+	if err := os.Mkdir(stateDir, 0o711); err != nil {
+		t.Fatal(err)
+	}
+	if err := rsv.save(stateDir); err != nil {
+		t.Fatal(err)
+	}
+	// A directory with neither a state nor a reservation.
+	if err := os.Mkdir(filepath.Join(root, "other"), 0o711); err != nil {
+		t.Fatal(err)
+	}
+
+	cpus, err := exclusiveCPUs(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cpus, []int{2, 3}) {
+		t.Errorf("expected the reserved CPUs [2 3], got %v", cpus)
+	}
+}
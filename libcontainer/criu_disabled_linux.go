@@ -13,3 +13,7 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 	return ErrNoCR
 }
+
+func CriuVersion() (int, error) {
+	return 0, ErrNoCR
+}
@@ -0,0 +1,111 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// lazyPagesSocket is the name of the socket the criu lazy-pages daemon
+	// listens on, in the criu work directory.
+	lazyPagesSocket = "lazy-pages.socket"
+	// lazyPagesTimeout is how long to wait for the lazy-pages daemon to
+	// become ready.
+	lazyPagesTimeout = 10 * time.Second
+)
+
+// lazyPagesDaemon is a "criu lazy-pages" daemon, which serves the memory
+// pages of a lazily restored container, fetching them from a remote page
+// server (see "runc checkpoint --lazy-pages-server").
+type lazyPagesDaemon struct {
+	cmd    *exec.Cmd
+	socket string
+	exited chan struct{}
+	err    error
+}
+
+// startLazyPagesDaemon starts a lazy-pages daemon for the checkpoint images
+// in imagesDir, which fetches the memory pages from ps, and waits for it to
+// be ready to accept connections from criu restore. The daemon socket and
+// log are in workDir, which must be the same as the one of criu restore.
+func startLazyPagesDaemon(imagesDir, workDir string, ps CriuPageServerInfo) (*lazyPagesDaemon, error) {
+	d := &lazyPagesDaemon{
+		socket: filepath.Join(workDir, lazyPagesSocket),
+		exited: make(chan struct{}),
+	}
+	// Remove a stale socket left from a previous run, so it's not mistaken
+	// for the one of the new daemon.
+	if err := os.Remove(d.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	d.cmd = exec.Command("criu", "lazy-pages",
+		"--page-server",
+		"--address", ps.Address,
+		"--port", strconv.Itoa(int(ps.Port)),
+		"--images-dir", imagesDir,
+		"--work-dir", workDir,
+		"--log-file", "lazy-pages.log",
+		"-v4")
+	// The daemon may outlive runc, so make sure it does not keep the runc
+	// stdio open, and is not killed along with the runc process group.
+	d.cmd.SysProcAttr = &unix.SysProcAttr{Setsid: true}
+	if err := d.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("unable to start criu lazy-pages: %w", err)
+	}
+	go func() {
+		d.err = d.cmd.Wait()
+		close(d.exited)
+	}()
+
+	timeout := time.After(lazyPagesTimeout)
+	for {
+		if _, err := os.Stat(d.socket); err == nil {
+			logrus.Debugf("criu lazy-pages daemon (pid %d) is ready", d.cmd.Process.Pid)
+			return d, nil
+		}
+		select {
+		case <-d.exited:
+			return nil, fmt.Errorf("criu lazy-pages exited prematurely: %w (see %s)", d.exitError(), filepath.Join(workDir, "lazy-pages.log"))
+		case <-timeout:
+			d.stop()
+			return nil, fmt.Errorf("timeout waiting for criu lazy-pages to create %s", d.socket)
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (d *lazyPagesDaemon) exitError() error {
+	if d.err != nil {
+		return d.err
+	}
+	return errors.New("exit status 0")
+}
+
+// stop kills the daemon, waits for it to exit, and removes its socket.
+// It is used when the restore has failed.
+func (d *lazyPagesDaemon) stop() {
+	_ = d.cmd.Process.Kill()
+	<-d.exited
+	_ = os.Remove(d.socket)
+}
+
+// release lets the daemon serve the restored container until all the memory
+// pages are transferred, after which the daemon exits and its socket is
+// removed. If runc exits first, the daemon keeps running on its own.
+func (d *lazyPagesDaemon) release() {
+	go func() {
+		<-d.exited
+		if d.err != nil {
+			logrus.Warnf("criu lazy-pages: %v", d.err)
+		}
+		_ = os.Remove(d.socket)
+	}()
+}
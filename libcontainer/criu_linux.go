@@ -13,6 +13,8 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -95,16 +97,25 @@ func (c *Container) checkCriuVersion(minVersion int) error {
 		return compareCriuVersion(c.criuVersion, minVersion)
 	}
 
-	criu := criu.MakeCriu()
 	var err error
-	c.criuVersion, err = criu.GetCriuVersion()
+	c.criuVersion, err = CriuVersion()
 	if err != nil {
-		return fmt.Errorf("CRIU version check failed: %w", err)
+		return err
 	}
 
 	return compareCriuVersion(c.criuVersion, minVersion)
 }
 
+// CriuVersion returns the version of the installed CRIU, e.g. 31900 for
+// 3.19.0, which must be at least 30000 for checkpoint/restore to work.
+func CriuVersion() (int, error) {
+	v, err := criu.MakeCriu().GetCriuVersion()
+	if err != nil {
+		return 0, fmt.Errorf("CRIU version check failed: %w", err)
+	}
+	return v, nil
+}
+
 const descriptorsFilename = "descriptors.json"
 
 func (c *Container) addCriuDumpMount(req *criurpc.CriuReq, m *configs.Mount) {
@@ -186,12 +197,32 @@ func criuNsToKey(t configs.NamespaceType) string {
 	return "extRoot" + strings.Title(configs.NsName(t)) + "NS" //nolint:staticcheck // SA1019: strings.Title is deprecated
 }
 
-func (c *Container) handleCheckpointingExternalNamespaces(rpcOpts *criurpc.CriuOpts, t configs.NamespaceType) error {
+// externalNsPath returns the path of the namespace of type t which CRIU
+// does not dump: the one of the container configuration, or else, for a
+// network namespace, extNetns (see [CriuOpts.ExternalNetns]).
+func (c *Container) externalNsPath(t configs.NamespaceType, extNetns string) (string, error) {
+	nsPath := c.config.Namespaces.PathOf(t)
+	if t != configs.NEWNET || extNetns == "" {
+		return nsPath, nil
+	}
+	if !c.config.Namespaces.Contains(configs.NEWNET) {
+		return "", fmt.Errorf("external network namespace %s: the container uses the host network namespace", extNetns)
+	}
+	if nsPath != "" && nsPath != extNetns {
+		return "", fmt.Errorf("external network namespace %s: the container is configured with the network namespace %s", extNetns, nsPath)
+	}
+	return extNetns, nil
+}
+
+func (c *Container) handleCheckpointingExternalNamespaces(rpcOpts *criurpc.CriuOpts, criuOpts *CriuOpts, t configs.NamespaceType) error {
 	if !c.criuSupportsExtNS(t) {
 		return fmt.Errorf("criu lacks support for external %s namespace during checkpointing process (old criu version?)", configs.NsName(t))
 	}
 
-	nsPath := c.config.Namespaces.PathOf(t)
+	nsPath, err := c.externalNsPath(t, criuOpts.ExternalNetns)
+	if err != nil {
+		return err
+	}
 	if nsPath == "" {
 		return nil
 	}
@@ -202,6 +233,16 @@ func (c *Container) handleCheckpointingExternalNamespaces(rpcOpts *criurpc.CriuO
 	if err := unix.Stat(nsPath, &ns); err != nil {
 		return err
 	}
+	if t == configs.NEWNET && nsPath == criuOpts.ExternalNetns {
+		// Unlike the configured one, it may not be the container's.
+		var ctrNs unix.Stat_t
+		if err := unix.Stat("/proc/"+strconv.Itoa(c.initProcess.pid())+"/ns/net", &ctrNs); err != nil {
+			return err
+		}
+		if ns.Dev != ctrNs.Dev || ns.Ino != ctrNs.Ino {
+			return fmt.Errorf("external network namespace %s is not the one of the container", nsPath)
+		}
+	}
 	criuExternal := fmt.Sprintf("%s[%d]:%s", configs.NsName(t), ns.Ino, criuNsToKey(t))
 	rpcOpts.External = append(rpcOpts.External, criuExternal)
 
@@ -359,12 +400,12 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 	// will expect that the namespace exists during restore.
 	// This basically means that CRIU will ignore the namespace
 	// and expect to be setup correctly.
-	if err := c.handleCheckpointingExternalNamespaces(&rpcOpts, configs.NEWNET); err != nil {
+	if err := c.handleCheckpointingExternalNamespaces(&rpcOpts, criuOpts, configs.NEWNET); err != nil {
 		return err
 	}
 
 	// Same for possible external PID namespaces
-	if err := c.handleCheckpointingExternalNamespaces(&rpcOpts, configs.NEWPID); err != nil {
+	if err := c.handleCheckpointingExternalNamespaces(&rpcOpts, criuOpts, configs.NEWPID); err != nil {
 		return err
 	}
 
@@ -471,6 +512,10 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 			c.addCriuDumpMount(req, m)
 		}
 
+		for _, m := range criuOpts.ExternalMounts {
+			c.addCriuDumpMount(req, &configs.Mount{Destination: m.Destination})
+		}
+
 		// Write the FD info to a file in the image directory
 		fdsJSON, err := json.Marshal(c.initProcess.externalDescriptors())
 		if err != nil {
@@ -483,7 +528,16 @@ func (c *Container) Checkpoint(criuOpts *CriuOpts) error {
 		}
 	}
 
+	phase := "dump"
+	if criuOpts.PreDump {
+		phase = "pre-dump"
+	}
+	// The images are about the size of the container memory.
+	progress := trackProgress(criuOpts.Progress, phase, c.memoryUsage, func() uint64 {
+		return dirSize(criuOpts.ImagesDirectory)
+	})
 	err = c.criuSwrk(nil, req, criuOpts, nil)
+	progress.end(err)
 	if err != nil {
 		logCriuErrors(logDir, logFile)
 		return err
@@ -724,18 +778,42 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 	}
 	c.handleCriuConfigurationFile(req.Opts)
 
+	if criuOpts.ExternalNetns != "" {
+		// Restore into it, and record it as the container's.
+		nsPath, err := c.externalNsPath(configs.NEWNET, criuOpts.ExternalNetns)
+		if err != nil {
+			return err
+		}
+		c.config.Namespaces = slices.Clone(c.config.Namespaces)
+		for i := range c.config.Namespaces {
+			if c.config.Namespaces[i].Type == configs.NEWNET {
+				c.config.Namespaces[i].Path = nsPath
+			}
+		}
+	}
 	if err := c.handleRestoringNamespaces(req.Opts, &extraFiles); err != nil {
 		return err
 	}
 
+	// The external mounts are restored as bind mounts.
+	mounts := c.config.Mounts
+	for _, m := range criuOpts.ExternalMounts {
+		mounts = append(slices.Clip(mounts), &configs.Mount{
+			Source:      m.Source,
+			Destination: m.Destination,
+			Device:      "bind",
+			Flags:       unix.MS_BIND,
+		})
+	}
+
 	// This will modify the rootfs of the container in the same way runc
 	// modifies the container during initial creation.
-	if err := c.prepareCriuRestoreMounts(c.config.Mounts); err != nil {
+	if err := c.prepareCriuRestoreMounts(mounts); err != nil {
 		return err
 	}
 
 	hasCgroupns := c.config.Namespaces.Contains(configs.NEWCGROUP)
-	for _, m := range c.config.Mounts {
+	for _, m := range mounts {
 		switch m.Device {
 		case "bind":
 			c.addCriuRestoreMount(req, m)
@@ -787,10 +865,59 @@ func (c *Container) Restore(process *Process, criuOpts *CriuOpts) error {
 			req.Opts.InheritFd = append(req.Opts.InheritFd, inheritFd)
 		}
 	}
+
+	for _, ifd := range criuOpts.InheritFds {
+		// Dup the descriptor, as all extraFiles are closed once CRIU is done.
+		fd, err := unix.FcntlInt(uintptr(ifd.Fd), unix.F_DUPFD_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("invalid inherited fd %d (%s): %w", ifd.Fd, ifd.Key, err)
+		}
+		req.Opts.InheritFd = append(req.Opts.InheritFd, &criurpc.InheritFd{
+			Key: proto.String(ifd.Key),
+			Fd:  proto.Int32(int32(4 + len(extraFiles))),
+		})
+		extraFiles = append(extraFiles, os.NewFile(uintptr(fd), ifd.Key))
+	}
+
+	// If a remote page server is specified for a lazy restore, run the
+	// lazy-pages daemon fetching the memory pages from it.
+	var lazyPages *lazyPagesDaemon
+	if criuOpts.LazyPages && criuOpts.PageServer.Address != "" && criuOpts.PageServer.Port != 0 {
+		feat := criurpc.CriuFeatures{
+			LazyPages: proto.Bool(true),
+		}
+		if err := c.checkCriuFeatures(criuOpts, &feat); err != nil {
+			return err
+		}
+		workDir := criuOpts.WorkDirectory
+		if workDir == "" {
+			workDir = criuOpts.ImagesDirectory
+		}
+		lazyPages, err = startLazyPagesDaemon(criuOpts.ImagesDirectory, workDir, criuOpts.PageServer)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The restored container memory is about the size of the pages images.
+	progress := trackProgress(criuOpts.Progress, "restore", func() uint64 {
+		return criuPagesSize(criuOpts.ImagesDirectory)
+	}, c.memoryUsage)
 	err = c.criuSwrk(process, req, criuOpts, extraFiles)
+	progress.end(err)
 	if err != nil {
 		logCriuErrors(logDir, logFile)
 	}
+	if lazyPages != nil {
+		if err != nil {
+			lazyPages.stop()
+		} else {
+			if err := c.addHelper(lazyPages.cmd.Process.Pid); err != nil {
+				logrus.Warnf("unable to account criu lazy-pages to the container: %v", err)
+			}
+			lazyPages.release()
+		}
+	}
 
 	// Now that CRIU is done let's close all opened FDs CRIU needed.
 	for _, fd := range extraFiles {
@@ -1122,10 +1249,13 @@ func (c *Container) criuNotifications(resp *criurpc.CriuResp, process *Process,
 			}
 			s.Pid = int(notify.GetPid())
 
-			if err := c.config.Hooks.Run(configs.Prestart, s); err != nil {
+			if err := c.runHooks(configs.Prestart, s); err != nil {
+				return err
+			}
+			if err := c.runHooks(configs.CreateRuntime, s); err != nil {
 				return err
 			}
-			if err := c.config.Hooks.Run(configs.CreateRuntime, s); err != nil {
+			if err := c.waitAsyncHooks(); err != nil {
 				return err
 			}
 		}
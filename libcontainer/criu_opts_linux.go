@@ -10,6 +10,21 @@ type VethPairName struct {
 	HostInterfaceName      string
 }
 
+// ExternalMount is a mount of the container which CRIU does not dump, in
+// addition to the bind mounts of the container configuration (such as a
+// mount propagated into the container once it is created).
+type ExternalMount struct {
+	Destination string // path of the mount in the container
+	Source      string // host path the mount is restored from
+}
+
+// InheritFd is a file descriptor of the runc process, which CRIU restores
+// an external resource of the container with.
+type InheritFd struct {
+	Fd  int    // file descriptor number
+	Key string // CRIU key of the resource, such as "socket:[1234]"
+}
+
 type CriuOpts struct {
 	ImagesDirectory         string             // directory for storing image files
 	WorkDirectory           string             // directory to cd and write logs/pidfiles/stats to
@@ -22,7 +37,7 @@ type CriuOpts struct {
 	ShellJob                bool               // allow to dump and restore shell jobs
 	FileLocks               bool               // handle file locks, for safety
 	PreDump                 bool               // call criu predump to perform iterative checkpoint
-	PageServer              CriuPageServerInfo // allow to dump to criu page server
+	PageServer              CriuPageServerInfo // allow to dump to criu page server, or to lazily restore from it
 	VethPairs               []VethPairName     // pass the veth to criu when restore
 	EmptyNs                 uint32             // don't c/r properties for namespace from this mask
 	AutoDedup               bool               // auto deduplication for incremental dumps
@@ -36,4 +51,22 @@ type CriuOpts struct {
 	// "strict", "ignore", or "" (empty string) for criu default.
 	// See https://criu.org/CGroups for more details.
 	ManageCgroupsMode string
+
+	// ExternalMounts are the mounts, other than the bind mounts of the
+	// container configuration, which CRIU does not dump, and restores from
+	// their sources.
+	ExternalMounts []ExternalMount
+	// ExternalNetns is the path of the network namespace which CRIU does
+	// not dump, and restores the container into, when the container
+	// configuration has no network namespace path (such as for a pod
+	// network namespace created along with the first container of the
+	// pod). The network namespace path of the configuration, if any, is
+	// external anyway.
+	ExternalNetns string
+	// InheritFds are the file descriptors passed to CRIU on restore, for
+	// the external resources of the container.
+	InheritFds []InheritFd
+	// Progress, if set, is called with the progress of the checkpoint or
+	// of the restore, periodically and once it is complete.
+	Progress func(Progress)
 }
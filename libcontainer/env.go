@@ -9,6 +9,8 @@ import (
 
 	"github.com/moby/sys/user"
 	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
 // prepareEnv processes a list of environment variables, preparing it
@@ -16,22 +18,24 @@ import (
 //   - validates each variable is in the NAME=VALUE format and
 //     contains no \0 (nil) bytes;
 //   - removes any duplicates (keeping only the last value for each key)
-//   - sets PATH for the current process, if found in the list;
-//   - adds HOME to returned environment, if not found in the list,
-//     or the value is empty.
+//   - adds the default variables as set by the policy (see
+//     [configs.EnvDefaults]), at the end of the list, for a process with a
+//     terminal or not;
+//   - sets PATH for the current process, if found in the returned list.
 //
 // Returns the prepared environment.
-func prepareEnv(env []string, uid int) ([]string, error) {
-	if env == nil {
+func prepareEnv(env []string, uid int, policy configs.EnvDefaults, terminal bool) ([]string, error) {
+	if env == nil && policy == configs.EnvDefaultsLegacy {
 		return nil, nil
 	}
-	var homeIsSet bool
+	defaults := policy.Variables(terminal)
+	isSet := make(map[string]bool, len(defaults))
 
 	// Deduplication code based on dedupEnv from Go 1.22 os/exec.
 
 	// Construct the output in reverse order, to preserve the
 	// last occurrence of each key.
-	out := make([]string, 0, len(env))
+	out := make([]string, 0, len(env)+len(defaults))
 	saw := make(map[string]bool, len(env))
 	for n := len(env); n > 0; n-- {
 		kv := env[n-1]
@@ -51,34 +55,47 @@ func prepareEnv(env []string, uid int) ([]string, error) {
 		if strings.IndexByte(kv, 0) >= 0 {
 			return nil, fmt.Errorf("invalid environment variable %q: contains nul byte (\\x00)", key)
 		}
+		if slices.Contains(defaults, key) {
+			if val == "" || policy == configs.EnvDefaultsAlways {
+				// Don't add the variable, we will override it later.
+				continue
+			}
+			isSet[key] = true
+		}
 		if key == "PATH" {
 			// Needs to be set as it is used for binary lookup.
 			if err := os.Setenv("PATH", val); err != nil {
 				return nil, err
 			}
 		}
-		if key == "HOME" {
-			if val != "" {
-				homeIsSet = true
-			} else {
-				// Don't add empty HOME to the environment, we will override it later.
-				continue
-			}
-		}
 		out = append(out, kv)
 	}
 	// Restore the original order.
 	slices.Reverse(out)
 
-	// If HOME is not found in env, get it from container's /etc/passwd and add.
-	if !homeIsSet {
-		home, err := getUserHome(uid)
-		if err != nil {
-			// For backward compatibility, don't return an error, but merely log it.
-			logrus.WithError(err).Debugf("HOME not set in process.env, and getting UID %d homedir failed", uid)
+	for _, key := range defaults {
+		if isSet[key] {
+			continue
 		}
-
-		out = append(out, "HOME="+home)
+		var val string
+		switch key {
+		case "PATH":
+			val = configs.DefaultPathEnv
+			if err := os.Setenv("PATH", val); err != nil {
+				return nil, err
+			}
+		case "HOME":
+			// Get it from container's /etc/passwd.
+			home, err := getUserHome(uid)
+			if err != nil {
+				// For backward compatibility, don't return an error, but merely log it.
+				logrus.WithError(err).Debugf("HOME not set in process.env, and getting UID %d homedir failed", uid)
+			}
+			val = home
+		case "TERM":
+			val = configs.DefaultTermEnv
+		}
+		out = append(out, key+"="+val)
 	}
 
 	return out, nil
@@ -1,10 +1,13 @@
 package libcontainer
 
 import (
+	"os"
 	"os/user"
 	"slices"
 	"strconv"
 	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
 func TestPrepareEnv(t *testing.T) {
@@ -52,7 +55,7 @@ func TestPrepareEnv(t *testing.T) {
 	}
 
 	for _, tc := range tests {
-		env, err := prepareEnv(tc.env, uid)
+		env, err := prepareEnv(tc.env, uid, configs.EnvDefaultsLegacy, false)
 		if err != nil {
 			t.Error(err)
 			continue
@@ -62,3 +65,47 @@ func TestPrepareEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestPrepareEnvDefaults(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := "HOME=" + u.HomeDir
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "PATH=" + configs.DefaultPathEnv
+	term := "TERM=" + configs.DefaultTermEnv
+	t.Setenv("PATH", os.Getenv("PATH"))
+
+	tests := []struct {
+		policy   configs.EnvDefaults
+		terminal bool
+		env      []string
+		wantEnv  []string
+	}{
+		{policy: configs.EnvDefaultsLegacy, env: nil, wantEnv: nil},
+		{policy: configs.EnvDefaultsIfMissing, env: nil, wantEnv: []string{path, home}},
+		{policy: configs.EnvDefaultsIfMissing, terminal: true, env: []string{"PATH=/bin", "HOME="}, wantEnv: []string{"PATH=/bin", home, term}},
+		{policy: configs.EnvDefaultsIfMissing, terminal: true, env: []string{"TERM=vt100", "HOME=/foo"}, wantEnv: []string{"TERM=vt100", "HOME=/foo", path}},
+		{policy: configs.EnvDefaultsAlways, env: []string{"PATH=/bin", "FOO=bar", "HOME=/foo", "TERM=vt100"}, wantEnv: []string{"FOO=bar", "TERM=vt100", path, home}},
+		{policy: configs.EnvDefaultsNever, terminal: true, env: []string{"FOO=bar", "HOME="}, wantEnv: []string{"FOO=bar", "HOME="}},
+		{policy: configs.EnvDefaultsNever, env: []string{}, wantEnv: []string{}},
+	}
+
+	for _, tc := range tests {
+		env, err := prepareEnv(tc.env, uid, tc.policy, tc.terminal)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !slices.Equal(env, tc.wantEnv) {
+			t.Errorf("%q %v: want %v, got %v", tc.policy, tc.env, tc.wantEnv, env)
+		}
+	}
+	if p := os.Getenv("PATH"); p != configs.DefaultPathEnv {
+		t.Errorf("expected PATH to be set to the default, got %q", p)
+	}
+}
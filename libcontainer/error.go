@@ -11,4 +11,8 @@ var (
 	ErrNotRunning     = errors.New("container not running")
 	ErrNotPaused      = errors.New("container not paused")
 	ErrCgroupNotExist = errors.New("cgroup not exist")
+	ErrGroupExist     = errors.New("resource group already exists")
+	ErrGroupNotExist  = errors.New("resource group does not exist")
+	ErrGroupInUse     = errors.New("resource group is in use")
+	ErrExecLimit      = errors.New("exec limit exceeded")
 )
@@ -0,0 +1,203 @@
+package libcontainer
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/internal/trace"
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// EventType is the type of a container lifecycle [Event].
+type EventType string
+
+const (
+	// EventCreated is sent once the container init process is created.
+	EventCreated EventType = "created"
+	// EventStarted is sent once the user process is started by the
+	// container init.
+	EventStarted EventType = "started"
+	// EventOOM is sent when a container process is killed by the OOM killer.
+	EventOOM EventType = "oom"
+	// EventPaused is sent when the container is paused.
+	EventPaused EventType = "paused"
+	// EventResumed is sent when the container is resumed.
+	EventResumed EventType = "resumed"
+	// EventExited is sent when the container init process exits.
+	EventExited EventType = "exited"
+	// EventHookFailed is sent when a hook run by libcontainer fails.
+	EventHookFailed EventType = "hook-failed"
+	// EventExecStarted is sent when a process is executed in the container.
+	EventExecStarted EventType = "exec-started"
+	// EventExecExited is sent when an exec process exits, see
+	// [Container.ExecExited].
+	EventExecExited EventType = "exec-exited"
+	// EventProbe is sent when the status of a container probe changes, see
+	// [Container.RunProbes].
+	EventProbe EventType = "probe"
+	// EventPropagation is sent when the mount propagation of a shared bind
+	// mount breaks, is working again, or is re-established, see
+	// [Container.WatchPropagation].
+	EventPropagation EventType = "propagation"
+)
+
+// eventBufferSize is the number of events buffered for each subscriber.
+const eventBufferSize = 64
+
+// Event is a container lifecycle event, see [Container.Subscribe].
+type Event struct {
+	Type EventType
+	// Time is when the event happened.
+	Time time.Time
+	// Pid is the container init PID, for EventCreated, EventStarted and
+	// EventExited, or the exec process PID, for EventExecStarted and
+	// EventExecExited.
+	Pid int
+	// Exec is the exec process event, for EventExecStarted and
+	// EventExecExited.
+	Exec *ExecEvent
+	// Probe is the probe state, for EventProbe.
+	Probe *ProbeState
+	// Propagation is the mount propagation state, for EventPropagation.
+	Propagation *PropagationState
+	// Hook is the name of the failed hook, for EventHookFailed.
+	Hook configs.HookName
+	// Err is the hook error, for EventHookFailed.
+	Err error
+	// Dropped is the number of events not delivered to the subscriber
+	// right before this one, because it was not keeping up.
+	Dropped int
+}
+
+type subscriber struct {
+	ch      chan Event
+	dropped int
+}
+
+// Subscribe returns a channel receiving the lifecycle events of the
+// container, until ctx is done (at which point the channel is closed).
+//
+// The events are only sent for the container operations done using c (or
+// the same [Container] object), with the exception of EventOOM and
+// EventExited, which are detected for the running container init.
+//
+// Sending events never blocks the container operations: if the subscriber
+// does not keep up and the channel buffer is full, the events are dropped,
+// and the number of events dropped is reported in the next event received.
+func (c *Container) Subscribe(ctx context.Context) <-chan Event {
+	sub := &subscriber{ch: make(chan Event, eventBufferSize)}
+	c.subMu.Lock()
+	if c.subs == nil {
+		c.subs = make(map[*subscriber]struct{})
+	}
+	c.subs[sub] = struct{}{}
+	c.subMu.Unlock()
+
+	c.m.Lock()
+	c.watch()
+	c.m.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.subMu.Lock()
+		delete(c.subs, sub)
+		close(sub.ch)
+		c.subMu.Unlock()
+	}()
+	return sub.ch
+}
+
+// publish sends an event to all subscribers.
+func (c *Container) publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	for sub := range c.subs {
+		ev.Dropped = sub.dropped
+		select {
+		case sub.ch <- ev:
+			sub.dropped = 0
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// runHooks runs the hooks of the given type, after starting the
+// asynchronous ones, sending an EventHookFailed event if a hook fails.
+func (c *Container) runHooks(name configs.HookName, s *specs.State) error {
+	span := trace.Start("hooks." + string(name))
+	span.SetAttribute("hooks.count", len(c.config.Hooks[name]))
+	err := c.startAsyncHooks(name, s)
+	if err == nil {
+		_, err = c.config.Hooks.Run(name, s)
+	}
+	span.End(err)
+	if err != nil {
+		c.publish(Event{Type: EventHookFailed, Hook: name, Err: err})
+	}
+	return err
+}
+
+// watch starts watching the container init for OOM and exit events, if
+// there are subscribers and it is not already watched. It must be called
+// with c.m held.
+func (c *Container) watch() {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	if c.watching || len(c.subs) == 0 || !c.hasInit() {
+		return
+	}
+	pid := c.initProcess.pid()
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		logrus.Debugf("unable to watch container init: pidfd_open: %v", err)
+		return
+	}
+	// Make sure the pidfd refers to the container init, not to a process
+	// which reused its PID.
+	if !c.hasInit() {
+		unix.Close(pidfd)
+		return
+	}
+	c.watching = true
+
+	if oom, err := c.NotifyOOM(); err != nil {
+		logrus.Debugf("unable to watch container for OOM events: %v", err)
+	} else {
+		go func() {
+			for range oom {
+				c.publish(Event{Type: EventOOM})
+			}
+		}()
+	}
+	go func() {
+		if err := waitPidfd(pidfd); err != nil {
+			logrus.Debugf("unable to wait for container init: %v", err)
+		}
+		unix.Close(pidfd)
+		c.subMu.Lock()
+		c.watching = false
+		c.subMu.Unlock()
+		c.publish(Event{Type: EventExited, Pid: pid})
+	}()
+}
+
+// waitPidfd waits for the process referred to by pidfd to exit.
+func waitPidfd(pidfd int) error {
+	fds := []unix.PollFd{{Fd: int32(pidfd), Events: unix.POLLIN}}
+	for {
+		_, err := unix.Poll(fds, -1)
+		if errors.Is(err, unix.EINTR) {
+			continue
+		}
+		return err
+	}
+}
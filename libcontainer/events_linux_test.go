@@ -0,0 +1,105 @@
+package libcontainer
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+func recvEvent(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatal("events channel closed")
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestSubscribeDropped(t *testing.T) {
+	c := &Container{config: &configs.Config{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := c.Subscribe(ctx)
+
+	for range eventBufferSize + 3 {
+		c.publish(Event{Type: EventPaused})
+	}
+	for range eventBufferSize {
+		if ev := recvEvent(t, ch); ev.Dropped != 0 {
+			t.Fatalf("expected no dropped events, got %d", ev.Dropped)
+		}
+	}
+	c.publish(Event{Type: EventResumed})
+	if ev := recvEvent(t, ch); ev.Type != EventResumed || ev.Dropped != 3 {
+		t.Errorf("expected resumed event with 3 dropped, got %+v", ev)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("expected events channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("timed out waiting for events channel to be closed")
+	}
+}
+
+func TestSubscribeHookFailed(t *testing.T) {
+	hookErr := errors.New("hook failed")
+	c := &Container{config: &configs.Config{
+		Hooks: configs.Hooks{
+			configs.Poststop: configs.HookList{configs.NewFunctionHook(func(*specs.State) error {
+				return hookErr
+			})},
+		},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := c.Subscribe(ctx)
+
+	if err := c.runHooks(configs.Poststop, &specs.State{}); !errors.Is(err, hookErr) {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+	ev := recvEvent(t, ch)
+	if ev.Type != EventHookFailed || ev.Hook != configs.Poststop || !errors.Is(ev.Err, hookErr) {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+}
+
+func TestSubscribeExited(t *testing.T) {
+	cmd := exec.Command("sleep", "0.2")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := system.Stat(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Container{
+		config:               &configs.Config{},
+		cgroupManager:        &mockCgroupManager{},
+		initProcess:          &mockProcess{_pid: cmd.Process.Pid, started: stat.StartTime},
+		initProcessStartTime: stat.StartTime,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := c.Subscribe(ctx)
+
+	ev := recvEvent(t, ch)
+	if ev.Type != EventExited || ev.Pid != cmd.Process.Pid {
+		t.Errorf("unexpected event: %+v", ev)
+	}
+	_ = cmd.Wait()
+}
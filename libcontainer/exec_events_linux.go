@@ -0,0 +1,174 @@
+package libcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// execEventsFilename is the name of the file in the container state
+// directory which keeps the record of the exec process events, so that
+// they can be followed by other runc invocations (see [Container.NotifyExec]).
+const execEventsFilename = "exec-events.json"
+
+// ExecEvent is an exec process lifecycle event, that is, the start or the
+// exit of a process executed in an existing container (as opposed to the
+// container init).
+type ExecEvent struct {
+	// Type is either EventExecStarted or EventExecExited.
+	Type EventType `json:"type"`
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+	// Pid is the exec process PID.
+	Pid int `json:"pid"`
+	// Args is the exec process command line.
+	Args []string `json:"args,omitempty"`
+	// User is the user the process is run as, in the "uid:gid" format
+	// (inside the container).
+	User string `json:"user"`
+	// ExitCode is the process exit code, for EventExecExited.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Duration is how long the process has run, for EventExecExited.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// execStarted records the start of the exec process p.
+func (c *Container) execStarted(p *Process) {
+	p.started = time.Now()
+	pid, _ := p.Pid()
+	c.recordExec(&ExecEvent{
+		Type: EventExecStarted,
+		Time: p.started,
+		Pid:  pid,
+		Args: p.Args,
+		User: strconv.Itoa(p.UID) + ":" + strconv.Itoa(p.GID),
+	})
+}
+
+// ExecExited records the exit of the exec process p, which was started by
+// [Container.Start] or [Container.Run], with the given exit code. It must be
+// called by whoever waits for the process to exit, as only the parent
+// process can get the exit code. If it is not called (e.g. for a process
+// left running in the background), only the exec process start is recorded.
+func (c *Container) ExecExited(p *Process, exitCode int) {
+	if p.Init || p.started.IsZero() {
+		return
+	}
+	pid, _ := p.Pid()
+	now := time.Now()
+	c.recordExec(&ExecEvent{
+		Type:     EventExecExited,
+		Time:     now,
+		Pid:      pid,
+		Args:     p.Args,
+		User:     strconv.Itoa(p.UID) + ":" + strconv.Itoa(p.GID),
+		ExitCode: &exitCode,
+		Duration: now.Sub(p.started),
+	})
+}
+
+// recordExec appends ev to the exec events file, records it in the exec
+// history, and publishes it to the container subscribers. A failure to
+// record the event is logged, but it does not affect the exec process.
+func (c *Container) recordExec(ev *ExecEvent) {
+	c.publish(Event{Type: ev.Type, Time: ev.Time, Pid: ev.Pid, Exec: ev})
+	if err := c.recordExecSession(ev); err != nil {
+		logrus.Warnf("unable to record exec session: %v", err)
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		logrus.Warnf("unable to record exec event: %v", err)
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(c.stateDir, execEventsFilename), os.O_WRONLY|os.O_APPEND|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		logrus.Warnf("unable to record exec event: %v", err)
+		return
+	}
+	defer f.Close()
+	// A single append write, so that the concurrent exec processes records
+	// are not interleaved.
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		logrus.Warnf("unable to record exec event: %v", err)
+	}
+}
+
+// NotifyExec returns a channel receiving the exec process events recorded
+// from now on, by any runc invocation, for the container. The channel is
+// never closed.
+func (c *Container) NotifyExec() (<-chan ExecEvent, error) {
+	path := filepath.Join(c.stateDir, execEventsFilename)
+	f, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to init inotify: %w", err)
+	}
+	// Add the watch before seeking to the end, so that no event recorded
+	// in between is missed.
+	if _, err := unix.InotifyAddWatch(fd, path, unix.IN_MODIFY); err != nil {
+		unix.Close(fd)
+		f.Close()
+		return nil, fmt.Errorf("unable to add inotify watch: %w", err)
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		unix.Close(fd)
+		f.Close()
+		return nil, err
+	}
+	ch := make(chan ExecEvent)
+	go func() {
+		defer func() {
+			unix.Close(fd)
+			f.Close()
+		}()
+		var (
+			r       = bufio.NewReader(f)
+			partial []byte
+			buffer  [unix.SizeofInotifyEvent + unix.PathMax + 1]byte
+		)
+		for {
+			for {
+				line, err := r.ReadBytes('\n')
+				if err != nil {
+					// The rest of the line is not written yet.
+					partial = append(partial, line...)
+					break
+				}
+				if len(partial) > 0 {
+					line = append(partial, line...)
+					partial = nil
+				}
+				var ev ExecEvent
+				if err := json.Unmarshal(bytes.TrimSpace(line), &ev); err != nil {
+					logrus.Warnf("invalid exec event record: %v", err)
+					continue
+				}
+				ch <- ev
+			}
+			_, err := unix.Read(fd, buffer[:])
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				logrus.Warnf("unable to read event data from inotify, got error: %v", os.NewSyscallError("read", err))
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,73 @@
+package libcontainer
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+type fakeProcessOps struct {
+	processOperations
+	p int
+}
+
+func (f *fakeProcessOps) pid() int {
+	return f.p
+}
+
+func TestExecEvents(t *testing.T) {
+	c := &Container{config: &configs.Config{}, stateDir: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := c.Subscribe(ctx)
+
+	// Events recorded before NotifyExec are not reported.
+	c.execStarted(&Process{Args: []string{"true"}, ops: &fakeProcessOps{p: 10}})
+	if ev := recvEvent(t, sub); ev.Type != EventExecStarted || ev.Pid != 10 {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+	execs, err := c.NotifyExec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Process{Args: []string{"sh", "-c", "exit 3"}, UID: 1000, GID: 100, ops: &fakeProcessOps{p: 42}}
+	c.execStarted(p)
+	c.ExecExited(p, 3)
+
+	for _, typ := range []EventType{EventExecStarted, EventExecExited} {
+		if ev := recvEvent(t, sub); ev.Type != typ || ev.Exec == nil || ev.Exec.Pid != 42 {
+			t.Fatalf("expected %s event, got %+v", typ, ev)
+		}
+		var ev ExecEvent
+		select {
+		case ev = <-execs:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s exec event", typ)
+		}
+		if ev.Type != typ || ev.Pid != 42 || ev.User != "1000:100" || !slices.Equal(ev.Args, p.Args) {
+			t.Fatalf("unexpected %s exec event: %+v", typ, ev)
+		}
+		if typ == EventExecExited && (ev.ExitCode == nil || *ev.ExitCode != 3 || ev.Duration <= 0) {
+			t.Fatalf("unexpected exit code or duration: %+v", ev)
+		}
+	}
+}
+
+func TestExecExitedInit(t *testing.T) {
+	c := &Container{config: &configs.Config{}, stateDir: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := c.Subscribe(ctx)
+
+	// The init process exit is not an exec event.
+	c.ExecExited(&Process{Init: true, ops: &fakeProcessOps{p: 1}}, 0)
+	select {
+	case ev := <-sub:
+		t.Fatalf("unexpected event: %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,129 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// execHistoryFilename is the name of the file in the container state
+	// directory which keeps the exec history (see [Container.ExecHistory]).
+	execHistoryFilename = "exec-history.json"
+	// execHistorySize is the number of the last exec sessions kept in the
+	// exec history.
+	execHistorySize = 100
+)
+
+// ExecSession is an exec session of the container exec history, that is, a
+// process executed in the container.
+type ExecSession struct {
+	// Pid is the exec process PID.
+	Pid int `json:"pid"`
+	// Args is the exec process command line.
+	Args []string `json:"args,omitempty"`
+	// User is the user the process is run as, in the "uid:gid" format
+	// (inside the container).
+	User string `json:"user"`
+	// Started is when the process was started.
+	Started time.Time `json:"started"`
+	// Exited is when the process exited, or nil if it is still running, or
+	// if its exit was not recorded (see [Container.ExecExited]).
+	Exited *time.Time `json:"exited,omitempty"`
+	// ExitCode is the process exit code, if it exited.
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+// ExecHistory returns the last exec sessions of the container, by any runc
+// invocation, from the oldest to the most recent.
+func (c *Container) ExecHistory() ([]ExecSession, error) {
+	f, err := os.Open(filepath.Join(c.stateDir, execHistoryFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_SH); err != nil {
+		return nil, fmt.Errorf("unable to lock exec history file: %w", err)
+	}
+	var sessions []ExecSession
+	if err := json.NewDecoder(f).Decode(&sessions); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to read exec history file: %w", err)
+	}
+	return sessions, nil
+}
+
+// recordExecSession records the exec process event ev in the exec history.
+func (c *Container) recordExecSession(ev *ExecEvent) error {
+	f, err := os.OpenFile(filepath.Join(c.stateDir, execHistoryFilename), os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	// Closing the file releases the lock.
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return err
+	}
+	var sessions []ExecSession
+	if err := json.NewDecoder(f).Decode(&sessions); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	sessions = addExecSession(sessions, ev)
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// addExecSession returns sessions updated with the exec process event ev,
+// and bounded to the last execHistorySize sessions.
+func addExecSession(sessions []ExecSession, ev *ExecEvent) []ExecSession {
+	switch ev.Type {
+	case EventExecStarted:
+		sessions = append(sessions, ExecSession{
+			Pid:     ev.Pid,
+			Args:    ev.Args,
+			User:    ev.User,
+			Started: ev.Time,
+		})
+	case EventExecExited:
+		exited := ev.Time
+		var s *ExecSession
+		// The most recent session of the process, as the PIDs are reused.
+		for i := len(sessions) - 1; i >= 0; i-- {
+			if sessions[i].Pid == ev.Pid && sessions[i].Exited == nil {
+				s = &sessions[i]
+				break
+			}
+		}
+		if s == nil {
+			// The start of the session is no longer in the history.
+			sessions = append(sessions, ExecSession{
+				Pid:     ev.Pid,
+				Args:    ev.Args,
+				User:    ev.User,
+				Started: ev.Time.Add(-ev.Duration),
+			})
+			s = &sessions[len(sessions)-1]
+		}
+		s.Exited = &exited
+		s.ExitCode = ev.ExitCode
+	}
+	if n := len(sessions) - execHistorySize; n > 0 {
+		sessions = sessions[n:]
+	}
+	return sessions
+}
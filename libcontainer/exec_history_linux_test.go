@@ -0,0 +1,70 @@
+package libcontainer
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestExecHistory(t *testing.T) {
+	c := &Container{config: &configs.Config{}, stateDir: t.TempDir()}
+	if h, err := c.ExecHistory(); err != nil || h != nil {
+		t.Fatalf("expected no history, got %+v, %v", h, err)
+	}
+
+	p1 := &Process{Args: []string{"sh", "-c", "exit 3"}, UID: 1000, GID: 100, ops: &fakeProcessOps{p: 42}}
+	c.execStarted(p1)
+	// A process left running.
+	p2 := &Process{Args: []string{"sleep", "1d"}, ops: &fakeProcessOps{p: 43}}
+	c.execStarted(p2)
+	c.ExecExited(p1, 3)
+
+	h, err := c.ExecHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 2 {
+		t.Fatalf("expected 2 sessions, got %+v", h)
+	}
+	s := h[0]
+	if s.Pid != 42 || s.User != "1000:100" || !slices.Equal(s.Args, p1.Args) || !s.Started.Equal(p1.started) {
+		t.Errorf("unexpected session %+v", s)
+	}
+	if s.Exited == nil || s.Exited.Before(s.Started) || s.ExitCode == nil || *s.ExitCode != 3 {
+		t.Errorf("unexpected session exit %+v", s)
+	}
+	if s := h[1]; s.Pid != 43 || s.Exited != nil || s.ExitCode != nil {
+		t.Errorf("unexpected running session %+v", s)
+	}
+}
+
+func TestAddExecSession(t *testing.T) {
+	start := time.Now()
+	var sessions []ExecSession
+	for i := range execHistorySize + 10 {
+		sessions = addExecSession(sessions, &ExecEvent{Type: EventExecStarted, Time: start, Pid: i})
+	}
+	if len(sessions) != execHistorySize || sessions[0].Pid != 10 {
+		t.Fatalf("expected the last %d sessions, got %d from pid %d", execHistorySize, len(sessions), sessions[0].Pid)
+	}
+
+	// The exit of a session no longer in the history.
+	code := 1
+	sessions = addExecSession(sessions, &ExecEvent{Type: EventExecExited, Time: start.Add(time.Minute), Pid: 5, ExitCode: &code, Duration: time.Minute})
+	s := sessions[len(sessions)-1]
+	if len(sessions) != execHistorySize || s.Pid != 5 || !s.Started.Equal(start) || s.Exited == nil || *s.ExitCode != 1 {
+		t.Errorf("unexpected session %+v", s)
+	}
+
+	// A reused PID matches the most recent session still running.
+	sessions = addExecSession(sessions, &ExecEvent{Type: EventExecStarted, Time: start, Pid: 5})
+	sessions = addExecSession(sessions, &ExecEvent{Type: EventExecExited, Time: start, Pid: 5, ExitCode: &code})
+	if s := sessions[len(sessions)-2]; s.Exited == nil {
+		t.Errorf("expected the previous session to stay exited, got %+v", s)
+	}
+	if s := sessions[len(sessions)-1]; s.Pid != 5 || s.Exited == nil {
+		t.Errorf("unexpected session %+v", s)
+	}
+}
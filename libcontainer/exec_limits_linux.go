@@ -0,0 +1,179 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+// execLimitsFilename is the name of the file in the container state
+// directory which keeps the exec processes accounting, shared by all the
+// runc invocations, for [configs.ExecLimits] enforcement.
+const execLimitsFilename = "exec-limits.json"
+
+// ExecLimitType is the kind of the exec limit which was exceeded.
+type ExecLimitType string
+
+const (
+	ExecLimitConcurrent ExecLimitType = "concurrent"
+	ExecLimitRate       ExecLimitType = "rate"
+	ExecLimitTotal      ExecLimitType = "total"
+)
+
+// ExecLimitError is returned by [Container.Start] and [Container.Run] when
+// the exec process can not be started because of the container exec limits.
+// It matches [ErrExecLimit] with [errors.Is].
+type ExecLimitError struct {
+	// Limit is the limit which was exceeded.
+	Limit ExecLimitType
+	// Max is the value of the limit.
+	Max int
+	// Window is the rate limit period, for ExecLimitRate.
+	Window time.Duration
+}
+
+func (e *ExecLimitError) Error() string {
+	switch e.Limit {
+	case ExecLimitConcurrent:
+		return fmt.Sprintf("%v: at most %d exec processes can run concurrently", ErrExecLimit, e.Max)
+	case ExecLimitRate:
+		return fmt.Sprintf("%v: at most %d exec processes can be started per %s", ErrExecLimit, e.Max, e.Window)
+	}
+	return fmt.Sprintf("%v: at most %d exec processes can be started", ErrExecLimit, e.Max)
+}
+
+func (e *ExecLimitError) Is(target error) bool {
+	return target == ErrExecLimit
+}
+
+// execLimitsState is the contents of the exec limits file.
+type execLimitsState struct {
+	// Total is the number of exec processes started so far.
+	Total int `json:"total"`
+	// Starts are the start times of the exec processes started within the
+	// last rate limit period.
+	Starts []time.Time `json:"starts,omitempty"`
+	// Running are the exec processes which may still be running.
+	Running []execLimitsProcess `json:"running,omitempty"`
+}
+
+type execLimitsProcess struct {
+	Pid int `json:"pid"`
+	// StartTime is the process start time, as in /proc/[pid]/stat, to
+	// detect a PID reuse.
+	StartTime uint64 `json:"start_time"`
+}
+
+// isRunning returns whether the process is still running.
+func (p execLimitsProcess) isRunning() bool {
+	stat, err := system.Stat(p.Pid)
+	return err == nil && stat.StartTime == p.StartTime && stat.State != system.Zombie && stat.State != system.Dead
+}
+
+// execLimiter holds the exec limits file lock from the check of the limits
+// to the accounting of the exec process being started.
+type execLimiter struct {
+	limits *configs.ExecLimits
+	f      *os.File
+	state  execLimitsState
+}
+
+// checkExecLimits locks the exec limits file, and checks that one more exec
+// process can be started. On success, the caller must call done once the
+// process is started (or has failed to start).
+func (c *Container) checkExecLimits() (*execLimiter, error) {
+	l := &execLimiter{limits: c.config.ExecLimits}
+	f, err := os.OpenFile(filepath.Join(c.stateDir, execLimitsFilename), os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open exec limits file: %w", err)
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to lock exec limits file: %w", err)
+	}
+	l.f = f
+	if err := json.NewDecoder(f).Decode(&l.state); err != nil && !errors.Is(err, io.EOF) {
+		l.close()
+		return nil, fmt.Errorf("unable to read exec limits file: %w", err)
+	}
+	if err := l.check(time.Now()); err != nil {
+		l.close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// check drops the state entries which have expired, and checks the limits
+// against the remaining ones.
+func (l *execLimiter) check(now time.Time) error {
+	running := l.state.Running[:0]
+	for _, p := range l.state.Running {
+		if p.isRunning() {
+			running = append(running, p)
+		}
+	}
+	l.state.Running = running
+
+	starts := l.state.Starts[:0]
+	if l.limits.Window > 0 {
+		for _, t := range l.state.Starts {
+			if now.Sub(t) < l.limits.Window {
+				starts = append(starts, t)
+			}
+		}
+	}
+	l.state.Starts = starts
+
+	if max := l.limits.MaxTotal; max > 0 && l.state.Total >= max {
+		return &ExecLimitError{Limit: ExecLimitTotal, Max: max}
+	}
+	if max := l.limits.MaxConcurrent; max > 0 && len(l.state.Running) >= max {
+		return &ExecLimitError{Limit: ExecLimitConcurrent, Max: max}
+	}
+	if max := l.limits.MaxPerWindow; max > 0 && len(l.state.Starts) >= max {
+		return &ExecLimitError{Limit: ExecLimitRate, Max: max, Window: l.limits.Window}
+	}
+	return nil
+}
+
+// done accounts for the exec process p, if it has been started (that is,
+// startErr is nil), and unlocks the exec limits file.
+func (l *execLimiter) done(p *Process, startErr error) error {
+	defer l.close()
+	if startErr != nil {
+		return nil
+	}
+	now := time.Now()
+	l.state.Total++
+	if l.limits.MaxPerWindow > 0 {
+		l.state.Starts = append(l.state.Starts, now)
+	}
+	if pid, err := p.Pid(); err == nil {
+		if stat, err := system.Stat(pid); err == nil {
+			l.state.Running = append(l.state.Running, execLimitsProcess{Pid: pid, StartTime: stat.StartTime})
+		}
+	}
+	data, err := json.Marshal(&l.state)
+	if err != nil {
+		return err
+	}
+	if err := l.f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = l.f.WriteAt(data, 0)
+	return err
+}
+
+func (l *execLimiter) close() {
+	// Closing the file releases the lock.
+	l.f.Close()
+}
@@ -0,0 +1,76 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// startExec runs the exec limits check and accounting of c for an exec
+// process with the given PID, as Container.start does.
+func startExec(c *Container, pid int) error {
+	l, err := c.checkExecLimits()
+	if err != nil {
+		return err
+	}
+	return l.done(&Process{ops: &fakeProcessOps{p: pid}}, nil)
+}
+
+func TestExecLimits(t *testing.T) {
+	testCases := []struct {
+		name   string
+		limits configs.ExecLimits
+		limit  ExecLimitType
+	}{
+		{name: "concurrent", limits: configs.ExecLimits{MaxConcurrent: 2}, limit: ExecLimitConcurrent},
+		{name: "rate", limits: configs.ExecLimits{MaxPerWindow: 2, Window: time.Hour}, limit: ExecLimitRate},
+		{name: "total", limits: configs.ExecLimits{MaxTotal: 2}, limit: ExecLimitTotal},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Container{config: &configs.Config{ExecLimits: &tc.limits}, stateDir: t.TempDir()}
+			// The test process itself is the running exec process.
+			for i := 0; i < 2; i++ {
+				if err := startExec(c, os.Getpid()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := startExec(c, os.Getpid())
+			var limitErr *ExecLimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tc.limit || limitErr.Max != 2 {
+				t.Fatalf("expected %s limit error, got %v", tc.limit, err)
+			}
+			if !errors.Is(err, ErrExecLimit) {
+				t.Fatalf("expected ErrExecLimit, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExecLimitsExpired(t *testing.T) {
+	limits := &configs.ExecLimits{MaxConcurrent: 1, MaxPerWindow: 1, Window: time.Millisecond}
+	c := &Container{config: &configs.Config{ExecLimits: limits}, stateDir: t.TempDir()}
+	for i := 0; i < 3; i++ {
+		// A process which has exited (or a PID which is not in use) does
+		// not count as running.
+		if err := startExec(c, 1<<22+1); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	// A failed start is not accounted for.
+	l, err := c.checkExecLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.done(&Process{ops: &fakeProcessOps{p: os.Getpid()}}, errors.New("failed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := startExec(c, os.Getpid()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,46 @@
+package libcontainer
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+)
+
+// AddExecSeccomp stacks the seccomp filter s onto the seccomp filter of the
+// processes executed in the container from now on (such as by "runc exec"),
+// recording it in the container state (see [configs.Config.ExecSeccomp]).
+// It only applies to those: the kernel has no way to install a seccomp
+// filter into another process, so the container init, and the processes
+// already running in the container, are not affected.
+//
+// The filter can't use the notify action, as a process can only have a
+// single seccomp notify listener.
+func (c *Container) AddExecSeccomp(s *configs.Seccomp) error {
+	if !seccomp.Enabled {
+		return errors.New("seccomp is not supported")
+	}
+	if s == nil {
+		return errors.New("no seccomp filter")
+	}
+	if s.DefaultAction == configs.Notify || s.ListenerPath != "" ||
+		slices.ContainsFunc(s.Syscalls, func(sc *configs.Syscall) bool { return sc.Action == configs.Notify }) {
+		return errors.New("a stacked seccomp filter can't use the notify action")
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return err
+	}
+	if status == Stopped {
+		return ErrNotRunning
+	}
+	// Do not modify the original slice, which may be shared.
+	config := *c.config
+	config.ExecSeccomp = append(slices.Clip(config.ExecSeccomp), s)
+	c.config = &config
+	_, err = c.updateState(nil)
+	return err
+}
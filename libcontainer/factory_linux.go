@@ -1,7 +1,7 @@
 package libcontainer
 
 import (
-	"encoding/json"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
@@ -22,6 +22,41 @@ const (
 	execFifoFilename = "exec.fifo"
 )
 
+// Option is an option of [Create] and [Load].
+type Option func(*options)
+
+type options struct {
+	cgroupManager cgroups.Manager
+	clock         configs.Clock
+}
+
+// WithCgroupManager makes the container use m, rather than the cgroup
+// manager for its configuration. Along with an in-memory manager (see the
+// cgroupstest package), this allows to test the code using libcontainer
+// without privileges, as long as no container process is started.
+func WithCgroupManager(m cgroups.Manager) Option {
+	return func(o *options) {
+		o.cgroupManager = m
+	}
+}
+
+// WithClock makes the container hooks use clk to measure their duration,
+// and enforce their timeouts (see [configs.Command.Clock]), so that those
+// can be tested with a fake clock.
+func WithClock(clk configs.Clock) Option {
+	return func(o *options) {
+		o.clock = clk
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
 // Create creates a new container with the given id inside a given state
 // directory (root), and returns a Container object.
 //
@@ -32,7 +67,8 @@ const (
 // The id must not be empty and consist of only the following characters:
 // ASCII letters, digits, underscore, plus, minus, period. The id must be
 // unique and non-existent for the given root path.
-func Create(root, id string, config *configs.Config) (*Container, error) {
+func Create(root, id string, config *configs.Config, opts ...Option) (*Container, error) {
+	o := newOptions(opts)
 	if root == "" {
 		return nil, errors.New("root not set")
 	}
@@ -55,10 +91,24 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 		return nil, err
 	}
 
-	cm, err := manager.New(config.Cgroups)
+	cpusetRsv, err := resolveCpuset(root, config)
 	if err != nil {
 		return nil, err
 	}
+	defer cpusetRsv.release()
+	if err := resolveKeptNetns(root, id, config); err != nil {
+		return nil, err
+	}
+
+	cm := o.cgroupManager
+	if cm == nil {
+		if cm, err = manager.New(config.Cgroups); err != nil {
+			return nil, err
+		}
+	}
+	if o.clock != nil {
+		config.Hooks.SetClock(o.clock)
+	}
 
 	// Check that cgroup does not exist or empty (no processes).
 	// Note for cgroup v1 this check is not thorough, as there are multiple
@@ -91,6 +141,10 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 	if err := os.Mkdir(stateDir, 0o711); err != nil {
 		return nil, err
 	}
+	if err := cpusetRsv.save(stateDir); err != nil {
+		_ = os.Remove(stateDir)
+		return nil, err
+	}
 	c := &Container{
 		id:              id,
 		stateDir:        stateDir,
@@ -105,7 +159,8 @@ func Create(root, id string, config *configs.Config) (*Container, error) {
 // Load takes a path to the state directory (root) and an id of an existing
 // container, and returns a Container object reconstructed from the saved
 // state. This presents a read only view of the container.
-func Load(root, id string) (*Container, error) {
+func Load(root, id string, opts ...Option) (*Container, error) {
+	o := newOptions(opts)
 	if root == "" {
 		return nil, errors.New("root not set")
 	}
@@ -126,9 +181,14 @@ func Load(root, id string) (*Container, error) {
 		processStartTime: state.InitProcessStartTime,
 		fds:              state.ExternalDescriptors,
 	}
-	cm, err := manager.NewWithPaths(state.Config.Cgroups, state.CgroupPaths)
-	if err != nil {
-		return nil, err
+	cm := o.cgroupManager
+	if cm == nil {
+		if cm, err = manager.NewWithPaths(state.Config.Cgroups, state.CgroupPaths); err != nil {
+			return nil, err
+		}
+	}
+	if o.clock != nil {
+		state.Config.Hooks.SetClock(o.clock)
 	}
 	c := &Container{
 		initProcess:          r,
@@ -139,6 +199,14 @@ func Load(root, id string) (*Container, error) {
 		intelRdtManager:      intelrdt.NewManager(&state.Config, id, state.IntelRdtPath),
 		stateDir:             stateDir,
 		created:              state.Created,
+		swapDevice:           state.SwapDevice,
+		rootfsProject:        state.RootfsProjectID,
+		netDevices:           state.NetDevices,
+		ioCostSaved:          state.IOCostSaved,
+		irqAffinity:          state.IRQAffinity,
+		eppSaved:             state.EPPSaved,
+		skippedResources:     state.SkippedCgroupResources,
+		stateDigest:          state.digest,
 	}
 	c.state = &loadedState{c: c}
 	if err := c.refreshState(); err != nil {
@@ -152,22 +220,27 @@ func loadState(root string) (*State, error) {
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Open(stateFilePath)
+	data, err := os.ReadFile(stateFilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, ErrNotExist
 		}
 		return nil, err
 	}
-	defer f.Close()
-	var state *State
-	if err := json.NewDecoder(f).Decode(&state); err != nil {
+	state, err := decodeState(root, data)
+	if err != nil {
 		return nil, err
 	}
+	state.digest = sha256.Sum256(data)
 	// Cgroup v1 fs manager expect Resources to never be nil.
 	if state.Config.Cgroups.Resources == nil {
 		state.Config.Cgroups.Resources = &cgroups.Resources{}
 	}
+	// The annotations of the containers created by an older runc version
+	// are only in the labels.
+	if state.Config.Annotations == nil {
+		state.Config.Annotations = configs.AnnotationsFromLabels(state.Config.Labels)
+	}
 	return state, nil
 }
 
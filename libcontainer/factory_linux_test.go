@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroupstest"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -95,3 +96,39 @@ type unserializableHook struct{}
 func (unserializableHook) Run(*specs.State) error {
 	return nil
 }
+
+func TestCreateWithCgroupManager(t *testing.T) {
+	root := t.TempDir()
+	config := &configs.Config{
+		Rootfs:  t.TempDir(),
+		Cgroups: &cgroups.Cgroup{Resources: &cgroups.Resources{}},
+	}
+
+	// The cgroup of a new container must be empty.
+	m := cgroupstest.NewManager(config.Cgroups)
+	m.SetPids(1234)
+	if _, err := Create(root, "busy", config, WithCgroupManager(m)); err == nil {
+		t.Fatal("expected an error creating a container in a non-empty cgroup")
+	}
+
+	m = cgroupstest.NewManager(config.Cgroups)
+	container, err := Create(root, "test", config, WithCgroupManager(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := container.Status(); err != nil || status != Stopped {
+		t.Fatalf("expected the stopped status, got %v (%v)", status, err)
+	}
+	if err := m.Apply(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := container.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Exists() {
+		t.Error("the container cgroup is not destroyed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "test")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the container state directory is not removed: %v", err)
+	}
+}
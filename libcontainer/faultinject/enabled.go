@@ -0,0 +1,6 @@
+//go:build runc_faultinject
+
+package faultinject
+
+// Enabled is whether runc is built with fault injection.
+const Enabled = true
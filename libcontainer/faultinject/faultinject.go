@@ -0,0 +1,138 @@
+// Package faultinject makes runc fail at specific points of the container
+// creation, so that the error handling of the code using runc can be tested
+// against realistic failures.
+//
+// Fault injection is only available when runc is built with the
+// runc_faultinject build tag; otherwise, [Check] never fails, and the
+// environment is not looked at. The faults are set by the RUNC_FAULTS
+// environment variable of runc (or of the process using libcontainer), as a
+// comma-separated list of point=error entries, such as:
+//
+//	RUNC_FAULTS=cgroup.apply=EBUSY,mount.2=ENOSPC,hook.prestart.0=timed out
+//
+// The points are:
+//   - cgroup.apply: applying the cgroup configuration to the container init;
+//   - mount.N: the mount with the index N in the container config;
+//   - seccomp.load: loading the seccomp filter (of the container init, or of
+//     an exec'd process);
+//   - hook.NAME.N: the hook with the index N of the NAME kind, such as
+//     prestart or createRuntime.
+//
+// The error is an errno name, such as EBUSY, or else any message.
+package faultinject
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// EnvName is the name of the environment variable setting the faults.
+const EnvName = "RUNC_FAULTS"
+
+// The fault points with no index.
+const (
+	CgroupApply = "cgroup.apply"
+	SeccompLoad = "seccomp.load"
+)
+
+// Mount returns the fault point of the mount with the index i.
+func Mount(i int) string {
+	return "mount." + strconv.Itoa(i)
+}
+
+// Hook returns the fault point of the hook with the index i of the name kind.
+func Hook(name string, i int) string {
+	return "hook." + name + "." + strconv.Itoa(i)
+}
+
+// Error is an injected fault.
+type Error struct {
+	Point string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return "injected fault at " + e.Point + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// faults is the parsed environment variable, loaded once.
+var faults = sync.OnceValues(func() (map[string]error, error) {
+	return parse(os.Getenv(EnvName))
+})
+
+// Load reads the faults from the environment, unless it is done already. It
+// needs to be called before the environment is cleared, which runc init
+// does.
+func Load() {
+	if Enabled {
+		_, _ = faults()
+	}
+}
+
+// Check returns the fault injected at point, if any. An invalid value of the
+// environment variable is returned at every point, so that it is not
+// mistaken for the faults not being hit.
+func Check(point string) error {
+	if !Enabled {
+		return nil
+	}
+	m, err := faults()
+	if err != nil {
+		return err
+	}
+	if err := m[point]; err != nil {
+		return &Error{Point: point, Err: err}
+	}
+	return nil
+}
+
+// Env returns the environment variable entry to pass the faults to another
+// runc process, or an empty string if there are none.
+func Env() string {
+	if !Enabled {
+		return ""
+	}
+	v, ok := os.LookupEnv(EnvName)
+	if !ok {
+		return ""
+	}
+	return EnvName + "=" + v
+}
+
+// parse parses the value of the environment variable.
+func parse(spec string) (map[string]error, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	m := make(map[string]error)
+	for _, entry := range strings.Split(spec, ",") {
+		point, msg, ok := strings.Cut(entry, "=")
+		if !ok || point == "" || msg == "" {
+			return nil, fmt.Errorf("invalid %s entry %q", EnvName, entry)
+		}
+		m[point] = parseError(msg)
+	}
+	return m, nil
+}
+
+// parseError returns the errno named msg, or else an error with the message.
+func parseError(msg string) error {
+	if strings.HasPrefix(msg, "E") {
+		for e := unix.Errno(1); e < 256; e++ {
+			if unix.ErrnoName(e) == msg {
+				return e
+			}
+		}
+	}
+	return errors.New(msg)
+}
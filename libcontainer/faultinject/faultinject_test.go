@@ -0,0 +1,48 @@
+package faultinject
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParse(t *testing.T) {
+	m, err := parse("cgroup.apply=EBUSY,mount.2=ENOSPC,hook.prestart.0=timed out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 {
+		t.Fatalf("expected 3 faults, got %v", m)
+	}
+	if !errors.Is(m[CgroupApply], unix.EBUSY) {
+		t.Errorf("%s: expected EBUSY, got %v", CgroupApply, m[CgroupApply])
+	}
+	if !errors.Is(m[Mount(2)], unix.ENOSPC) {
+		t.Errorf("%s: expected ENOSPC, got %v", Mount(2), m[Mount(2)])
+	}
+	if err := m[Hook("prestart", 0)]; err == nil || err.Error() != "timed out" {
+		t.Errorf("%s: expected \"timed out\", got %v", Hook("prestart", 0), err)
+	}
+
+	m, err = parse("")
+	if err != nil || m != nil {
+		t.Errorf("empty: expected no faults, got %v, %v", m, err)
+	}
+
+	for _, spec := range []string{"cgroup.apply", "=EBUSY", "mount.0=", "seccomp.load=EPERM,"} {
+		if _, err := parse(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	err := error(&Error{Point: SeccompLoad, Err: unix.EINVAL})
+	if !errors.Is(err, unix.EINVAL) {
+		t.Errorf("expected %v to be EINVAL", err)
+	}
+	if got, want := err.Error(), "injected fault at seccomp.load: invalid argument"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,133 @@
+// Package features reports the features supported by libcontainer, in the
+// format of "runc features".
+package features
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+
+	"github.com/opencontainers/runc/libcontainer/capabilities"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	runcfeatures "github.com/opencontainers/runc/types/features"
+)
+
+var (
+	extMu      sync.Mutex
+	extensions = map[string]json.RawMessage{}
+)
+
+// RegisterExtension adds an entry to the Extensions map returned by [Get],
+// allowing a downstream build to advertise its own capabilities. It is meant
+// to be called from an init function. The name should be in the reverse
+// domain notation (e.g. "com.example.feature"), and value must be
+// marshalable to JSON.
+//
+// RegisterExtension panics if value can't be marshaled, or if name is
+// already registered.
+func RegisterExtension(name string, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Sprintf("features: extension %q: %v", name, err))
+	}
+	extMu.Lock()
+	defer extMu.Unlock()
+	if _, ok := extensions[name]; ok {
+		panic(fmt.Sprintf("features: extension %q registered twice", name))
+	}
+	extensions[name] = data
+}
+
+// Get returns the features supported by libcontainer. The runc version
+// annotations ([runcfeatures.AnnotationRuncVersion] and
+// [runcfeatures.AnnotationRuncCommit]) are not set, as these are only known
+// to the binary using libcontainer.
+func Get() *runcfeatures.Features {
+	t := true
+
+	feat := &runcfeatures.Features{
+		Features: features.Features{
+			OCIVersionMin: "1.0.0",
+			OCIVersionMax: specs.Version,
+			Annotations: map[string]string{
+				runcfeatures.AnnotationRuncCheckpointEnabled: "true",
+			},
+			Hooks:        configs.KnownHookNames(),
+			MountOptions: specconv.KnownMountOptions(),
+			Linux: &features.Linux{
+				Namespaces:   specconv.KnownNamespaces(),
+				Capabilities: capabilities.KnownCapabilities(),
+				Cgroup: &features.Cgroup{
+					V1:          &t,
+					V2:          &t,
+					Systemd:     &t,
+					SystemdUser: &t,
+					Rdma:        &t,
+				},
+				Apparmor: &features.Apparmor{
+					Enabled: &t,
+				},
+				Selinux: &features.Selinux{
+					Enabled: &t,
+				},
+				IntelRdt: &features.IntelRdt{
+					Enabled: &t,
+				},
+				MountExtensions: &features.MountExtensions{
+					IDMap: &features.IDMap{
+						Enabled: &t,
+					},
+				},
+				NetDevices: &features.NetDevices{
+					Enabled: &t,
+				},
+			},
+			PotentiallyUnsafeConfigAnnotations: []string{
+				"bundle",
+				"org.systemd.property.", // prefix form
+				"org.criu.config",
+				"org.opencontainers.runc.swap.",    // prefix form
+				"org.opencontainers.runc.io.cost.", // prefix form
+				"org.opencontainers.runc.irq.affinity",
+				"org.opencontainers.runc.power.hint",
+				"org.opencontainers.runc.keyring",
+				"org.opencontainers.runc.shm.policy",
+			},
+		},
+		SchemaVersion: runcfeatures.SchemaVersion,
+		EnvDefaults: &runcfeatures.EnvDefaults{
+			Policies: configs.KnownEnvDefaults(),
+			Variables: map[string]string{
+				"PATH": configs.DefaultPathEnv,
+				"TERM": configs.DefaultTermEnv,
+			},
+		},
+	}
+
+	if seccomp.Enabled {
+		feat.Linux.Seccomp = &features.Seccomp{
+			Enabled:        &t,
+			Actions:        seccomp.KnownActions(),
+			Operators:      seccomp.KnownOperators(),
+			Archs:          seccomp.KnownArchs(),
+			KnownFlags:     seccomp.KnownFlags(),
+			SupportedFlags: seccomp.SupportedFlags(),
+		}
+		major, minor, patch := seccomp.Version()
+		feat.Annotations[runcfeatures.AnnotationLibseccompVersion] = fmt.Sprintf("%d.%d.%d", major, minor, patch)
+	}
+
+	extMu.Lock()
+	if len(extensions) > 0 {
+		feat.Extensions = maps.Clone(extensions)
+	}
+	extMu.Unlock()
+
+	return feat
+}
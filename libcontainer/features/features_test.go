@@ -0,0 +1,62 @@
+package features
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+
+	runcfeatures "github.com/opencontainers/runc/types/features"
+)
+
+func TestGet(t *testing.T) {
+	RegisterExtension("org.example.test", map[string]bool{"enabled": true})
+	defer func() {
+		extMu.Lock()
+		delete(extensions, "org.example.test")
+		extMu.Unlock()
+	}()
+
+	data, err := json.Marshal(Get())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var feat runcfeatures.Features
+	if err := json.Unmarshal(data, &feat); err != nil {
+		t.Fatal(err)
+	}
+	if feat.SchemaVersion != runcfeatures.SchemaVersion {
+		t.Errorf("expected schema version %q, got %q", runcfeatures.SchemaVersion, feat.SchemaVersion)
+	}
+	if ext := string(feat.Extensions["org.example.test"]); ext != `{"enabled":true}` {
+		t.Errorf("unexpected extension value: %s", ext)
+	}
+	if e := feat.EnvDefaults; e == nil || len(e.Policies) != 3 || e.Variables["PATH"] == "" {
+		t.Errorf("unexpected env defaults: %+v", e)
+	}
+
+	// The output must remain parsable as the runtime-spec features.
+	var ociFeat features.Features
+	if err := json.Unmarshal(data, &ociFeat); err != nil {
+		t.Fatal(err)
+	}
+	if ociFeat.OCIVersionMin != "1.0.0" || ociFeat.Linux == nil {
+		t.Errorf("unexpected runtime-spec features: %+v", ociFeat)
+	}
+}
+
+func TestRegisterExtensionTwice(t *testing.T) {
+	RegisterExtension("org.example.twice", true)
+	defer func() {
+		extMu.Lock()
+		delete(extensions, "org.example.twice")
+		extMu.Unlock()
+	}()
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic, got none")
+		}
+	}()
+	RegisterExtension("org.example.twice", false)
+}
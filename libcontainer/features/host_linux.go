@@ -0,0 +1,123 @@
+package features
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/systemd"
+	"github.com/opencontainers/selinux/go-selinux"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/system/kernelversion"
+	runcfeatures "github.com/opencontainers/runc/types/features"
+)
+
+// minCriuVersion is the minimum CRIU version for checkpoint/restore.
+const minCriuVersion = 30000
+
+// Probe returns the subset of the features which is actually usable on the
+// host, as probed at runtime (see [runcfeatures.Features.Host]). It may run
+// criu, to get its version.
+func Probe() *runcfeatures.Host {
+	setattr := mountSetattrSupported()
+	host := &runcfeatures.Host{
+		Kernel:       kernelRelease(),
+		Namespaces:   specconv.SupportedNamespaces(),
+		MountOptions: supportedMountOptions(setattr),
+		// Idmapped mounts were added along with mount_setattr(2).
+		IDMap:      setattr,
+		Cgroup:     probeCgroup(),
+		Apparmor:   apparmor.IsEnabled(),
+		Selinux:    selinux.GetEnabled(),
+		IntelRdt:   intelrdt.IsCATEnabled() || intelrdt.IsMBAEnabled(),
+		Checkpoint: probeCheckpoint(),
+	}
+	if seccomp.Enabled {
+		host.Seccomp = &runcfeatures.HostSeccomp{
+			Actions: seccomp.SupportedActions(),
+			Flags:   seccomp.SupportedFlags(),
+		}
+	}
+	return host
+}
+
+func kernelRelease() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uts.Release[:])
+}
+
+// mountSetattrSupported returns whether the kernel has mount_setattr(2)
+// (Linux 5.12).
+func mountSetattrSupported() bool {
+	err := unix.MountSetattr(-1, "", 0, &unix.MountAttr{})
+	return !errors.Is(err, unix.ENOSYS)
+}
+
+// supportedMountOptions returns the known mount options which the kernel
+// supports, setattr telling whether it has mount_setattr(2).
+func supportedMountOptions(setattr bool) []string {
+	atLeast := func(kernel, major uint64) bool {
+		ok, err := kernelversion.GreaterEqualThan(kernelversion.KernelVersion{Kernel: kernel, Major: major})
+		return err == nil && ok
+	}
+	// MS_NOSYMFOLLOW was added in Linux 5.10, and MOUNT_ATTR_NOSYMFOLLOW
+	// in Linux 5.14.
+	nosymfollow := atLeast(5, 10)
+	recNosymfollow := setattr && atLeast(5, 14)
+	recursive := specconv.KnownRecursiveMountOptions()
+
+	var res []string
+	for _, o := range specconv.KnownMountOptions() {
+		ok := true
+		switch {
+		case o == "nosymfollow" || o == "symfollow":
+			ok = nosymfollow
+		case o == "rnosymfollow" || o == "rsymfollow":
+			ok = recNosymfollow
+		case slices.Contains(recursive, o):
+			ok = setattr
+		}
+		if ok {
+			res = append(res, o)
+		}
+	}
+	return res
+}
+
+func probeCgroup() *runcfeatures.HostCgroup {
+	cg := &runcfeatures.HostCgroup{
+		V2:      cgroups.IsCgroup2UnifiedMode(),
+		Systemd: systemd.IsRunningSystemd(),
+	}
+	if controllers, err := cgroups.GetAllSubsystems(); err == nil {
+		sort.Strings(controllers)
+		cg.Controllers = slices.Compact(controllers)
+	}
+	return cg
+}
+
+func probeCheckpoint() *runcfeatures.HostCheckpoint {
+	v, err := libcontainer.CriuVersion()
+	if err != nil {
+		return &runcfeatures.HostCheckpoint{Error: err.Error()}
+	}
+	cp := &runcfeatures.HostCheckpoint{
+		Enabled:     v >= minCriuVersion,
+		CriuVersion: fmt.Sprintf("%d.%d.%d", v/10000, v/100%100, v%100),
+	}
+	if !cp.Enabled {
+		cp.Error = fmt.Sprintf("CRIU version %s must be 3.0.0 or higher", cp.CriuVersion)
+	}
+	return cp
+}
@@ -0,0 +1,64 @@
+package features
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	runcfeatures "github.com/opencontainers/runc/types/features"
+)
+
+func TestProbe(t *testing.T) {
+	host := Probe()
+	if host.Kernel == "" {
+		t.Error("expected the kernel release")
+	}
+	if !slices.Contains(host.Namespaces, "mount") {
+		t.Errorf("expected the mount namespace to be supported, got %v", host.Namespaces)
+	}
+	known := specconv.KnownMountOptions()
+	for _, o := range host.MountOptions {
+		if !slices.Contains(known, o) {
+			t.Errorf("unknown mount option %q", o)
+		}
+	}
+	if !slices.Contains(host.MountOptions, "bind") {
+		t.Errorf("expected the bind mount option to be supported, got %v", host.MountOptions)
+	}
+	if host.Cgroup == nil || host.Checkpoint == nil {
+		t.Fatalf("expected the cgroup and checkpoint support, got %+v", host)
+	}
+	if !host.Checkpoint.Enabled && host.Checkpoint.Error == "" {
+		t.Error("expected why checkpoint/restore is not enabled")
+	}
+
+	// The host features are part of the "runc features" output.
+	feat := Get()
+	feat.Host = host
+	data, err := json.Marshal(feat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got runcfeatures.Features
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Host == nil || got.Host.Kernel != host.Kernel {
+		t.Errorf("unexpected host features: %+v", got.Host)
+	}
+}
+
+func TestSupportedMountOptions(t *testing.T) {
+	// Without mount_setattr(2), the recursive mount options are not
+	// supported.
+	opts := supportedMountOptions(false)
+	for _, o := range specconv.KnownRecursiveMountOptions() {
+		if slices.Contains(opts, o) {
+			t.Errorf("expected %q not to be supported", o)
+		}
+	}
+	if !slices.Contains(opts, "ro") || !slices.Contains(opts, "rbind") {
+		t.Errorf("expected ro and rbind to be supported, got %v", opts)
+	}
+}
@@ -0,0 +1,378 @@
+package libcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fs2"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// The kinds of the orphaned resources found by [FindOrphans].
+const (
+	// OrphanContainer is the state of a container without processes, or
+	// a state directory without a state.
+	OrphanContainer = "container"
+	// OrphanCgroup is an empty cgroup without a container.
+	OrphanCgroup = "cgroup"
+	// OrphanNetns is a network namespace kept for a container (see
+	// [configs.Config.KeepNetns]).
+	OrphanNetns = "netns"
+	// OrphanUserns is an ID range allocated by [AllocateUsernsRange].
+	OrphanUserns = "userns"
+)
+
+// Orphan is a resource left behind by a container which is gone, or by one
+// whose processes are all gone (such as after the host crashed), as found
+// by [FindOrphans].
+type Orphan struct {
+	// Kind is the kind of the resource (one of the Orphan* kinds).
+	Kind string `json:"kind"`
+	// ID is the ID of the container, if known.
+	ID string `json:"id,omitempty"`
+	// Path is the path of the resource: the state directory, the cgroup
+	// path (relative to the cgroup root), the kept network namespace, or
+	// the container state directory the ID range is allocated to.
+	Path string `json:"path"`
+	// Reason is why the resource is orphaned.
+	Reason string `json:"reason"`
+}
+
+// GCOptions are the options of [FindOrphans].
+type GCOptions struct {
+	// Stopped makes all the stopped containers orphaned, rather than only
+	// those created before the host booted.
+	Stopped bool
+	// KeptNetns makes the network namespaces kept for the containers which
+	// do not exist orphaned, rather than only the stale ones (which are no
+	// longer mounted).
+	KeptNetns bool
+	// CgroupParents are the parent cgroups (relative to the cgroup root)
+	// of which all the empty child cgroups which are not of a container or
+	// resource group are orphaned. The child cgroups of the resource
+	// groups, and the runc-*.scope units of system.slice, are always
+	// checked.
+	CgroupParents []string
+	// MinAge is the minimum age of the state directories without a state,
+	// and of the cgroups, to be orphaned, so that those of the containers
+	// being created are not.
+	MinAge time.Duration
+}
+
+// FindOrphans returns the orphaned resources of the containers of the given
+// state directory (root), which [RemoveOrphan] removes.
+func FindOrphans(root string, opts *GCOptions) ([]Orphan, error) {
+	if opts == nil {
+		opts = &GCOptions{}
+	}
+	boot, err := bootTime()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	var (
+		orphans []Orphan
+		ids     []string
+		// The cgroups of the containers.
+		known = make(map[string]bool)
+	)
+	for _, e := range entries {
+		id := e.Name()
+		if !e.IsDir() || validateID(id) != nil {
+			continue
+		}
+		ids = append(ids, id)
+		dir := filepath.Join(root, id)
+		c, err := Load(root, id)
+		if err != nil {
+			// Only the state directories without a state at all are
+			// orphaned (after MinAge, as those of the containers being
+			// created have none yet), never the ones whose state can't
+			// be loaded.
+			if hasStateFile(dir) {
+				logrus.Warnf("gc: skipping container %s: %v", id, err)
+				continue
+			}
+			if olderThan(dir, opts.MinAge) {
+				orphans = append(orphans, Orphan{Kind: OrphanContainer, ID: id, Path: dir, Reason: "no state"})
+			}
+			continue
+		}
+		state, err := c.State()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range state.CgroupPaths {
+			known[p] = true
+		}
+		status, err := c.Status()
+		if err != nil || status != Stopped {
+			continue
+		}
+		switch {
+		case state.Created.Before(boot):
+			orphans = append(orphans, Orphan{Kind: OrphanContainer, ID: id, Path: dir, Reason: "created before the host booted"})
+		case opts.Stopped:
+			orphans = append(orphans, Orphan{Kind: OrphanContainer, ID: id, Path: dir, Reason: "stopped"})
+		}
+	}
+
+	cgroupOrphans, err := findCgroupOrphans(root, opts, ids, known)
+	if err != nil {
+		return nil, err
+	}
+	orphans = append(orphans, cgroupOrphans...)
+
+	netnsEntries, err := os.ReadDir(filepath.Join(root, netnsDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range netnsEntries {
+		id := e.Name()
+		p := keptNetnsPath(root, id)
+		switch {
+		case !isNetnsMount(p):
+			orphans = append(orphans, Orphan{Kind: OrphanNetns, ID: id, Path: p, Reason: "not mounted"})
+		case opts.KeptNetns && !slices.Contains(ids, id):
+			orphans = append(orphans, Orphan{Kind: OrphanNetns, ID: id, Path: p, Reason: "no container"})
+		}
+	}
+
+	allocs, err := staleUsernsAllocations()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range allocs {
+		orphans = append(orphans, Orphan{
+			Kind:   OrphanUserns,
+			ID:     filepath.Base(a.StateDir),
+			Path:   a.StateDir,
+			Reason: fmt.Sprintf("IDs %d-%d allocated to no container", a.HostID, a.HostID+a.Size-1),
+		})
+	}
+	return orphans, nil
+}
+
+// RemoveOrphan removes an orphaned resource found by [FindOrphans] in the
+// same state directory (root).
+func RemoveOrphan(root string, o Orphan) error {
+	switch o.Kind {
+	case OrphanContainer:
+		c, err := Load(root, o.ID)
+		if err != nil {
+			if filepath.Dir(o.Path) != filepath.Clean(root) {
+				return fmt.Errorf("invalid container state directory %s", o.Path)
+			}
+			if hasStateFile(o.Path) {
+				return fmt.Errorf("container %s has a state: %w", o.ID, err)
+			}
+			return os.RemoveAll(o.Path)
+		}
+		if status, err := c.Status(); err != nil || status != Stopped {
+			return fmt.Errorf("container %s is no longer stopped", o.ID)
+		}
+		return c.Destroy()
+	case OrphanCgroup:
+		mountpoints, err := cgroupMountpoints()
+		if err != nil {
+			return err
+		}
+		for _, mp := range mountpoints {
+			if err := cgroups.RemovePath(filepath.Join(mp, o.Path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case OrphanNetns:
+		return RemoveKeptNetns(root, o.ID)
+	case OrphanUserns:
+		return releaseUsernsRange(o.Path)
+	}
+	return fmt.Errorf("invalid orphan kind %q", o.Kind)
+}
+
+// findCgroupOrphans returns the empty cgroups without a container, which
+// are not among the known cgroup paths, and are not named after any of
+// the containers of ids.
+func findCgroupOrphans(root string, opts *GCOptions, ids []string, known map[string]bool) ([]Orphan, error) {
+	mountpoints, err := cgroupMountpoints()
+	if err != nil {
+		return nil, err
+	}
+	type parent struct {
+		path string
+		// match returns the container ID of a child cgroup, if it may be
+		// the cgroup of a container.
+		match func(name string) (string, bool)
+	}
+	anyChild := func(name string) (string, bool) { return name, true }
+	scope := func(name string) (string, bool) {
+		name, ok := strings.CutPrefix(name, "runc-")
+		if !ok {
+			return "", false
+		}
+		return strings.CutSuffix(name, ".scope")
+	}
+	parents := []parent{{"/system.slice", scope}}
+	// The parent of the resource groups of all the state directories.
+	for _, mp := range mountpoints {
+		known[filepath.Join(mp, groupCgroupRoot)] = true
+	}
+	groups, err := ListGroups(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range groups {
+		g, err := LoadGroup(root, name)
+		if err != nil {
+			continue
+		}
+		p := path.Join("/", g.Cgroup())
+		if g.state.Config.Systemd {
+			parents = append(parents, parent{p, scope})
+		} else {
+			parents = append(parents, parent{p, anyChild})
+		}
+		for _, mp := range mountpoints {
+			known[filepath.Join(mp, p)] = true
+		}
+	}
+	for _, p := range opts.CgroupParents {
+		parents = append(parents, parent{path.Join("/", p), anyChild})
+	}
+
+	var orphans []Orphan
+	seen := make(map[string]bool)
+	for _, p := range parents {
+		for _, mp := range mountpoints {
+			entries, err := os.ReadDir(filepath.Join(mp, p.path))
+			if err != nil {
+				continue
+			}
+			for _, e := range entries {
+				rel := path.Join(p.path, e.Name())
+				id, ok := p.match(e.Name())
+				if !e.IsDir() || !ok || seen[rel] || slices.Contains(ids, id) {
+					continue
+				}
+				seen[rel] = true
+				if isCgroupOrphan(mountpoints, rel, known, opts.MinAge) {
+					orphans = append(orphans, Orphan{Kind: OrphanCgroup, ID: id, Path: rel, Reason: "empty, and no container"})
+				}
+			}
+		}
+	}
+	return orphans, nil
+}
+
+// isCgroupOrphan returns whether the cgroup at path rel is empty in all
+// the cgroup hierarchies, and not a known one.
+func isCgroupOrphan(mountpoints []string, rel string, known map[string]bool, minAge time.Duration) bool {
+	found := false
+	for _, mp := range mountpoints {
+		dir := filepath.Join(mp, rel)
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		if known[dir] || !olderThan(dir, minAge) {
+			return false
+		}
+		pids, err := cgroups.GetAllPids(dir)
+		if err != nil || len(pids) > 0 {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// cgroupMountpoints returns the mountpoints of the cgroup hierarchies.
+func cgroupMountpoints() ([]string, error) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		return []string{fs2.UnifiedMountpoint}, nil
+	}
+	mounts, err := cgroups.GetCgroupMounts(false)
+	if err != nil {
+		return nil, err
+	}
+	var mountpoints []string
+	for _, m := range mounts {
+		if m.Root == "/" && !slices.Contains(mountpoints, m.Mountpoint) {
+			mountpoints = append(mountpoints, m.Mountpoint)
+		}
+	}
+	return mountpoints, nil
+}
+
+// olderThan returns whether path was last modified at least d ago.
+// hasStateFile reports whether the state directory dir has a state file (or
+// whether it can't be known), even if it can't be loaded.
+func hasStateFile(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, stateFilename))
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+func olderThan(path string, d time.Duration) bool {
+	fi, err := os.Stat(path)
+	return err == nil && time.Since(fi.ModTime()) >= d
+}
+
+// bootTime returns the time the host booted.
+func bootTime() (time.Time, error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if v, ok := bytes.CutPrefix(s.Bytes(), []byte("btime ")); ok {
+			sec, err := strconv.ParseInt(string(v), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid btime in /proc/stat: %w", err)
+			}
+			return time.Unix(sec, 0), nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, errors.New("no btime in /proc/stat")
+}
+
+// staleUsernsAllocations returns the stale allocations of the user
+// namespace ID range registry, which are dropped at its next update.
+func staleUsernsAllocations() ([]usernsAllocation, error) {
+	f, err := os.Open(usernsAutoFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_SH); err != nil {
+		return nil, &os.PathError{Op: "flock", Path: usernsAutoFile, Err: err}
+	}
+	var allocs []usernsAllocation
+	if err := json.NewDecoder(f).Decode(&allocs); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to read %s: %w", usernsAutoFile, err)
+	}
+	return slices.DeleteFunc(allocs, func(a usernsAllocation) bool { return !a.stale() }), nil
+}
@@ -0,0 +1,90 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindOrphans(t *testing.T) {
+	dir := t.TempDir()
+	old := usernsAutoFile
+	usernsAutoFile = filepath.Join(dir, "userns-auto.json")
+	t.Cleanup(func() { usernsAutoFile = old })
+
+	root := filepath.Join(dir, "root")
+	for _, d := range []string{"nostate", "aborted", "corrupt", netnsDir} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A state which can't be loaded is never orphaned.
+	if err := os.WriteFile(filepath.Join(root, "corrupt", stateFilename), []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The state directory of a container being created may only have its
+	// CPU reservation, see below for MinAge.
+	if err := os.WriteFile(filepath.Join(root, "aborted", cpusetReservedFilename), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keptNetnsPath(root, "gone"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	allocs, err := json.Marshal([]usernsAllocation{
+		{StateDir: filepath.Join(root, "aborted"), HostID: 100000, Size: 65536, Pid: -1},
+		{StateDir: filepath.Join(root, "gone"), HostID: 165536, Size: 65536, Pid: -1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(usernsAutoFile, allocs, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := FindOrphans(root, &GCOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Orphan{
+		{Kind: OrphanContainer, ID: "aborted", Path: filepath.Join(root, "aborted"), Reason: "no state"},
+		{Kind: OrphanContainer, ID: "nostate", Path: filepath.Join(root, "nostate"), Reason: "no state"},
+		{Kind: OrphanNetns, ID: "gone", Path: keptNetnsPath(root, "gone"), Reason: "not mounted"},
+		{Kind: OrphanUserns, ID: "gone", Path: filepath.Join(root, "gone"), Reason: "IDs 165536-231071 allocated to no container"},
+	}
+	if !reflect.DeepEqual(orphans, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, orphans)
+	}
+
+	// The state directories without a state may be of the containers
+	// being created.
+	orphans, err = FindOrphans(root, &GCOptions{MinAge: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 2 || orphans[0].Kind != OrphanNetns {
+		t.Fatalf("expected no container orphans, got %+v", orphans)
+	}
+
+	for _, o := range expected {
+		if err := RemoveOrphan(root, o); err != nil {
+			t.Fatalf("%+v: %v", o, err)
+		}
+	}
+	orphans, err = FindOrphans(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 0 {
+		t.Fatalf("expected no orphans left, got %+v", orphans)
+	}
+	if _, err := os.Stat(filepath.Join(root, "corrupt", stateFilename)); err != nil {
+		t.Fatal(err)
+	}
+	err = RemoveOrphan(root, Orphan{Kind: OrphanContainer, ID: "corrupt", Path: filepath.Join(root, "corrupt")})
+	if err == nil {
+		t.Fatal("expected an error removing a container with a state")
+	}
+}
@@ -0,0 +1,283 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/manager"
+
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+const (
+	// groupsDir is the directory in root where the resource groups are
+	// stored. It contains a character which is not valid in a container
+	// ID, so it never clashes with a container state directory.
+	groupsDir = "@groups"
+	// groupCgroupRoot is the parent cgroup of the resource groups cgroups,
+	// for the cgroupfs driver.
+	groupCgroupRoot = "/runc-groups"
+	// groupSlicePrefix is the prefix of the resource groups slice units,
+	// for the systemd driver.
+	groupSlicePrefix = "runc_group_"
+)
+
+// Group is a named resource group: a parent cgroup with resource limits
+// which are shared by the containers created as its members (see
+// [Group.Join]).
+type Group struct {
+	root  string
+	state *groupState
+	m     cgroups.Manager
+}
+
+type groupState struct {
+	Name    string            `json:"name"`
+	Config  *cgroups.Cgroup   `json:"config"`
+	Paths   map[string]string `json:"paths"`
+	Created time.Time         `json:"created"`
+}
+
+// CreateGroup creates a new resource group with the given name inside a
+// given state directory (root), which is the same as the one of the member
+// containers. The group cgroup is configured according to config, of which
+// Systemd, Rootless and Resources are used, and the cgroup location is set
+// by CreateGroup.
+//
+// The name has the same format as a container ID, except that for the
+// systemd cgroup driver, it must not contain a minus sign.
+func CreateGroup(root, name string, config *cgroups.Cgroup) (*Group, error) {
+	if root == "" {
+		return nil, errors.New("root not set")
+	}
+	if err := validateID(name); err != nil {
+		return nil, err
+	}
+	var res cgroups.Resources
+	if config.Resources != nil {
+		res = *config.Resources
+	}
+	// The device rules are set per container.
+	res.SkipDevices = true
+	cg := &cgroups.Cgroup{
+		Systemd:   config.Systemd,
+		Rootless:  config.Rootless,
+		Resources: &res,
+	}
+	if cg.Systemd {
+		// A minus sign in a slice name denotes a parent slice.
+		if strings.Contains(name, "-") {
+			return nil, fmt.Errorf("invalid resource group name %q: must not contain a minus sign with systemd cgroup driver", name)
+		}
+		cg.Name = groupSlicePrefix + name + ".slice"
+		cg.Parent = "-.slice"
+	} else {
+		cg.Path = path.Join(groupCgroupRoot, name)
+	}
+
+	dir := filepath.Join(root, groupsDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	stateFile := filepath.Join(dir, name+".json")
+	if _, err := os.Stat(stateFile); err == nil {
+		return nil, ErrGroupExist
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	m, err := manager.New(cg)
+	if err != nil {
+		return nil, err
+	}
+	if m.Exists() {
+		return nil, fmt.Errorf("resource group cgroup %s already exists", m.Path(""))
+	}
+	// Create the cgroup without adding any process to it.
+	if err := m.Apply(-1); err != nil {
+		_ = m.Destroy()
+		return nil, fmt.Errorf("unable to create resource group cgroup: %w", err)
+	}
+	if err := m.Set(cg.Resources); err != nil {
+		_ = m.Destroy()
+		return nil, fmt.Errorf("unable to set resource group limits: %w", err)
+	}
+	g := &Group{
+		root: root,
+		m:    m,
+		state: &groupState{
+			Name:    name,
+			Config:  cg,
+			Paths:   m.GetPaths(),
+			Created: time.Now().UTC(),
+		},
+	}
+	if err := g.save(); err != nil {
+		_ = m.Destroy()
+		return nil, err
+	}
+	return g, nil
+}
+
+// LoadGroup loads the resource group with the given name from the given
+// state directory (root).
+func LoadGroup(root, name string) (*Group, error) {
+	if root == "" {
+		return nil, errors.New("root not set")
+	}
+	if err := validateID(name); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(root, groupsDir, name+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrGroupNotExist
+		}
+		return nil, err
+	}
+	var state *groupState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.Config.Resources == nil {
+		state.Config.Resources = &cgroups.Resources{}
+	}
+	m, err := manager.NewWithPaths(state.Config, state.Paths)
+	if err != nil {
+		return nil, err
+	}
+	return &Group{root: root, state: state, m: m}, nil
+}
+
+// ListGroups returns the names of the resource groups in the given state
+// directory (root).
+func ListGroups(root string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(root, groupsDir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// Name returns the resource group name.
+func (g *Group) Name() string {
+	return g.state.Name
+}
+
+// Created returns the resource group creation time.
+func (g *Group) Created() time.Time {
+	return g.state.Created
+}
+
+// Resources returns the resource limits of the group.
+func (g *Group) Resources() cgroups.Resources {
+	return *g.state.Config.Resources
+}
+
+// Cgroup returns the group cgroup path (relative to the cgroup root), or,
+// for the systemd cgroup driver, the group slice unit name.
+func (g *Group) Cgroup() string {
+	if g.state.Config.Systemd {
+		return g.state.Config.Name
+	}
+	return g.state.Config.Path
+}
+
+// Set updates the resource limits of the group, which replace the current
+// ones.
+func (g *Group) Set(r *cgroups.Resources) error {
+	res := *r
+	res.SkipDevices = true
+	if err := g.m.Set(&res); err != nil {
+		return err
+	}
+	g.state.Config.Resources = &res
+	return g.save()
+}
+
+// Stats returns the stats of the group cgroup. As cgroup accounting is
+// hierarchical, those are the aggregated stats of all member containers.
+func (g *Group) Stats() (*cgroups.Stats, error) {
+	return g.m.GetStats()
+}
+
+// Join configures the cgroup of a container, given by config, so that the
+// container becomes a member of the group. It must be called before the
+// container is created. The config cgroup location must not be set
+// explicitly (e.g. with the runtime-spec cgroupsPath).
+func (g *Group) Join(config *cgroups.Cgroup) error {
+	gc := g.state.Config
+	if config.Systemd != gc.Systemd {
+		return errors.New("the container and the resource group must use the same cgroup driver")
+	}
+	if config.Systemd {
+		if config.Parent != "" {
+			return errors.New("cgroup parent slice can not be set for a resource group member")
+		}
+		config.Parent = gc.Name
+		return nil
+	}
+	if config.Path != "" || config.Parent != "" {
+		return errors.New("cgroup path can not be set for a resource group member")
+	}
+	config.Path = path.Join(gc.Path, config.Name)
+	config.Name = ""
+	return nil
+}
+
+// Destroy removes the resource group. It fails with ErrGroupInUse if there
+// are any processes in the group cgroup.
+func (g *Group) Destroy() error {
+	pids, err := g.m.GetAllPids()
+	if err != nil && g.m.Exists() {
+		return err
+	}
+	if len(pids) > 0 {
+		return ErrGroupInUse
+	}
+	if err := g.m.Destroy(); err != nil {
+		return fmt.Errorf("unable to remove resource group cgroup: %w", err)
+	}
+	err = os.Remove(filepath.Join(g.root, groupsDir, g.state.Name+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	return err
+}
+
+func (g *Group) save() (retErr error) {
+	dir := filepath.Join(g.root, groupsDir)
+	tmpFile, err := os.CreateTemp(dir, "group-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+		}
+	}()
+	if err := utils.WriteJSON(tmpFile, g.state); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filepath.Join(dir, g.state.Name+".json"))
+}
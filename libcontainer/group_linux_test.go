@@ -0,0 +1,92 @@
+package libcontainer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+)
+
+func TestGroupJoin(t *testing.T) {
+	fsGroup := &Group{state: &groupState{Name: "g", Config: &cgroups.Cgroup{Path: "/runc-groups/g"}}}
+	sdGroup := &Group{state: &groupState{Name: "g", Config: &cgroups.Cgroup{Systemd: true, Name: "runc_group_g.slice", Parent: "-.slice"}}}
+
+	testCases := []struct {
+		name   string
+		group  *Group
+		config cgroups.Cgroup
+		expect cgroups.Cgroup
+		isErr  bool
+	}{
+		{
+			name:   "fs",
+			group:  fsGroup,
+			config: cgroups.Cgroup{Name: "ct"},
+			expect: cgroups.Cgroup{Path: "/runc-groups/g/ct"},
+		},
+		{
+			name:   "fs with path",
+			group:  fsGroup,
+			config: cgroups.Cgroup{Path: "/foo"},
+			isErr:  true,
+		},
+		{
+			name:   "systemd",
+			group:  sdGroup,
+			config: cgroups.Cgroup{Systemd: true, ScopePrefix: "runc", Name: "ct"},
+			expect: cgroups.Cgroup{Systemd: true, ScopePrefix: "runc", Name: "ct", Parent: "runc_group_g.slice"},
+		},
+		{
+			name:   "systemd with parent",
+			group:  sdGroup,
+			config: cgroups.Cgroup{Systemd: true, Parent: "system.slice", ScopePrefix: "runc", Name: "ct"},
+			isErr:  true,
+		},
+		{
+			name:   "driver mismatch",
+			group:  sdGroup,
+			config: cgroups.Cgroup{Name: "ct"},
+			isErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.group.Join(&tc.config)
+			if tc.isErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.config.Path != tc.expect.Path || tc.config.Name != tc.expect.Name ||
+				tc.config.Parent != tc.expect.Parent || tc.config.ScopePrefix != tc.expect.ScopePrefix {
+				t.Errorf("expected %+v, got %+v", tc.expect, tc.config)
+			}
+		})
+	}
+}
+
+func TestCreateGroupInvalidName(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"", "..", "a/b"} {
+		if _, err := CreateGroup(root, name, &cgroups.Cgroup{}); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("%q: expected ErrInvalidID, got %v", name, err)
+		}
+	}
+	if _, err := CreateGroup(root, "a-b", &cgroups.Cgroup{Systemd: true}); err == nil {
+		t.Error("expected error for a systemd group name with a minus sign, got nil")
+	}
+}
+
+func TestLoadGroupNotExist(t *testing.T) {
+	if _, err := LoadGroup(t.TempDir(), "nope"); !errors.Is(err, ErrGroupNotExist) {
+		t.Fatalf("expected ErrGroupNotExist, got %v", err)
+	}
+	names, err := ListGroups(t.TempDir())
+	if err != nil || len(names) != 0 {
+		t.Fatalf("expected no groups, got %v, %v", names, err)
+	}
+}
@@ -0,0 +1,89 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fs"
+	"github.com/opencontainers/cgroups/fs2"
+)
+
+// AddHelper moves the process pid, which runc runs on behalf of the container
+// (such as the probe runner of "runc run"), into the helper sub-cgroup of the
+// container (see [configs.Config.HelperCgroup]), so that its resource usage
+// is accounted to the container. It does nothing if there is no helper
+// sub-cgroup.
+func (c *Container) AddHelper(pid int) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.addHelper(pid)
+}
+
+func (c *Container) addHelper(pid int) error {
+	for _, path := range c.helperCgroupPaths() {
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			return err
+		}
+		if err := cgroups.WriteCgroupProc(path, pid); err != nil {
+			return fmt.Errorf("unable to add helper process %d to %s: %w", pid, path, err)
+		}
+	}
+	return nil
+}
+
+// helperControllers are the cgroup v1 controllers of the helper sub-cgroup.
+// Those are the accounting ones, so that the helpers are not restricted by
+// the other controllers of the container (such as devices or cpuset).
+var helperControllers = []string{"blkio", "cpu", "cpuacct", "hugetlb", "memory", "misc", "pids", "rdma"}
+
+// helperCgroupPaths returns the paths of the helper sub-cgroup of the
+// container, by controller (as returned by [cgroups.Manager.GetPaths]), or
+// nil if there is none.
+func (c *Container) helperCgroupPaths() map[string]string {
+	if c.config.HelperCgroup == "" {
+		return nil
+	}
+	v2 := cgroups.IsCgroup2UnifiedMode()
+	paths := make(map[string]string)
+	for ctrl, path := range c.cgroupManager.GetPaths() {
+		if path != "" && (v2 || slices.Contains(helperControllers, ctrl)) {
+			paths[ctrl] = filepath.Join(path, c.config.HelperCgroup)
+		}
+	}
+	return paths
+}
+
+// helperCgroupStats returns the stats of the helper sub-cgroup of the
+// container, or nil if there is none, or no helper was added to it yet.
+func (c *Container) helperCgroupStats() (*cgroups.Stats, error) {
+	paths := c.helperCgroupPaths()
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	var m cgroups.Manager
+	var err error
+	if cgroups.IsCgroup2UnifiedMode() {
+		if _, err := os.Stat(paths[""]); errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		m, err = fs2.NewManager(c.config.Cgroups, paths[""])
+	} else {
+		for ctrl, path := range paths {
+			if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+				delete(paths, ctrl)
+			}
+		}
+		if len(paths) == 0 {
+			return nil, nil
+		}
+		m, err = fs.NewManager(c.config.Cgroups, paths)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.GetStats()
+}
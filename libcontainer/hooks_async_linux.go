@@ -0,0 +1,67 @@
+package libcontainer
+
+import (
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// startAsyncHooks starts the asynchronous hooks of the given type, which
+// are waited for by waitAsyncHooks.
+func (c *Container) startAsyncHooks(name configs.HookName, s *specs.State) error {
+	hooks, err := c.config.Hooks.StartAsync(name, s)
+	if err != nil {
+		return err
+	}
+	c.asyncHooks = append(c.asyncHooks, hooks...)
+	return nil
+}
+
+// waitAsyncHooks waits for the asynchronous hooks to be ready, sending an
+// EventHookFailed event if a hook fails, in which case all of them are
+// killed.
+func (c *Container) waitAsyncHooks() error {
+	for _, h := range c.asyncHooks {
+		if _, err := h.Wait(); err != nil {
+			c.publish(Event{Type: EventHookFailed, Hook: h.Name, Err: err})
+			c.killAsyncHooks()
+			return err
+		}
+	}
+	c.updateAsyncHooks()
+	return nil
+}
+
+// updateAsyncHooks updates the container state the asynchronous hooks get.
+func (c *Container) updateAsyncHooks() {
+	if len(c.asyncHooks) == 0 {
+		return
+	}
+	s, err := c.currentOCIState()
+	if err != nil {
+		logrus.Debugf("unable to update the state of the asynchronous hooks: %v", err)
+		return
+	}
+	for _, h := range c.asyncHooks {
+		h.SetState(s)
+	}
+}
+
+// killAsyncHooks kills the asynchronous hooks, if the container could not
+// be created.
+func (c *Container) killAsyncHooks() {
+	for _, h := range c.asyncHooks {
+		h.Kill()
+	}
+	c.asyncHooks = nil
+}
+
+// closeAsyncHooks closes the sockets of the asynchronous hooks, which keep
+// running.
+func (c *Container) closeAsyncHooks() {
+	for _, h := range c.asyncHooks {
+		h.Close()
+	}
+	c.asyncHooks = nil
+}
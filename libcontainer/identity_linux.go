@@ -0,0 +1,87 @@
+package libcontainer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+const (
+	machineIDFilename = "machine-id"
+	bootIDFilename    = "boot_id"
+)
+
+// writeIdentityFiles writes the machine ID of the container, and a new boot
+// ID if it has a virtual one, in the container state directory, for the
+// container init to bind mount them (see identityMounts).
+func (c *Container) writeIdentityFiles(cfg *initConfig) error {
+	if c.config.MachineID != "" {
+		path := filepath.Join(c.stateDir, machineIDFilename)
+		if err := writeIdentityFile(path, c.config.MachineID); err != nil {
+			return err
+		}
+		cfg.MachineIDFile = path
+	}
+	if c.config.VirtualBootID {
+		id, err := newBootID()
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(c.stateDir, bootIDFilename)
+		if err := writeIdentityFile(path, id); err != nil {
+			return err
+		}
+		cfg.BootIDFile = path
+	}
+	return nil
+}
+
+// writeIdentityFile writes a read-only file with the id, replacing any
+// previous one.
+func writeIdentityFile(path, id string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.WriteFile(path, []byte(id+"\n"), 0o444)
+}
+
+// newBootID returns a random boot ID, formatted as the kernel formats
+// /proc/sys/kernel/random/boot_id (a version 4 UUID).
+func newBootID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("unable to generate a boot ID: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// identityMounts returns the read-only bind mounts of the files written by
+// writeIdentityFiles over the container /etc/machine-id and boot ID. The
+// latter is mounted once /proc is, as a part of the container mounts.
+func identityMounts(cfg *initConfig) []*configs.Mount {
+	var mounts []*configs.Mount
+	for _, f := range []struct{ src, dst string }{
+		{cfg.MachineIDFile, "/etc/machine-id"},
+		{cfg.BootIDFile, "/proc/sys/kernel/random/boot_id"},
+	} {
+		if f.src == "" {
+			continue
+		}
+		mounts = append(mounts, &configs.Mount{
+			Source:      f.src,
+			Destination: f.dst,
+			Device:      "bind",
+			Flags:       unix.MS_BIND | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV | unix.MS_NOEXEC,
+		})
+	}
+	return mounts
+}
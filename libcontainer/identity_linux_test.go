@@ -0,0 +1,34 @@
+package libcontainer
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewBootID(t *testing.T) {
+	re := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for range 16 {
+		id, err := newBootID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !re.MatchString(id) {
+			t.Fatalf("expected a version 4 UUID, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("got the boot ID %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestIdentityMounts(t *testing.T) {
+	if m := identityMounts(&initConfig{}); len(m) != 0 {
+		t.Fatalf("expected no mounts, got %+v", m)
+	}
+	m := identityMounts(&initConfig{BootIDFile: "/run/runc/test/boot_id"})
+	if len(m) != 1 || m[0].Destination != "/proc/sys/kernel/random/boot_id" || !m[0].IsBind() {
+		t.Fatalf("expected a boot ID bind mount, got %+v", m)
+	}
+}
@@ -0,0 +1,237 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/moby/sys/user"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// syncUsermapAck is sent to runc init once the user namespace mappings
+// are set up (see SYNC_USERMAP_ACK in nsenter/nsexec.c).
+const syncUsermapAck = 0x41
+
+// IDMapper sets up the uid and gid mappings of a new user namespace. It is
+// used by runc init (see [Process.IDMapper]) instead of writing the
+// mappings directly, or running the newuidmap and newgidmap tools when
+// runc is rootless.
+type IDMapper interface {
+	// MapIDs sets up the mappings of the user namespace of the process
+	// pid. Any of uidMappings and gidMappings may be empty, in which case
+	// the corresponding mappings must not be written.
+	MapIDs(pid int, uidMappings, gidMappings []configs.IDMap) error
+}
+
+// IDMapperFunc is an [IDMapper] implemented by a function.
+type IDMapperFunc func(pid int, uidMappings, gidMappings []configs.IDMap) error
+
+// MapIDs calls f(pid, uidMappings, gidMappings).
+func (f IDMapperFunc) MapIDs(pid int, uidMappings, gidMappings []configs.IDMap) error {
+	return f(pid, uidMappings, gidMappings)
+}
+
+// DirectIDMapper writes the mappings to /proc/<pid>/uid_map and gid_map,
+// which requires CAP_SETUID and CAP_SETGID over the parent user namespace
+// (unless only the caller's own uid and gid are mapped).
+type DirectIDMapper struct{}
+
+// MapIDs implements [IDMapper].
+func (DirectIDMapper) MapIDs(pid int, uidMappings, gidMappings []configs.IDMap) error {
+	for _, m := range []struct {
+		file     string
+		mappings []configs.IDMap
+	}{
+		{"uid_map", uidMappings},
+		{"gid_map", gidMappings},
+	} {
+		if len(m.mappings) == 0 {
+			continue
+		}
+		data, err := encodeIDMapping(m.mappings)
+		if err != nil {
+			return err
+		}
+		path := "/proc/" + strconv.Itoa(pid) + "/" + m.file
+		// The mappings must be written with a single write.
+		if err := os.WriteFile(path, data, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SubIDMapper writes the mappings directly, like [DirectIDMapper], after
+// checking that, as with the newuidmap(1) and newgidmap(1) tools, only the
+// uid and gid of the user running runc, and the subordinate ID ranges
+// allocated to them in /etc/subuid and /etc/subgid, are mapped.
+//
+// It is meant to be used by a runc with the CAP_SETUID and CAP_SETGID
+// capabilities (for instance, as file capabilities), so that rootless
+// containers with multiple ID ranges can be run without the shadow-utils
+// tools.
+type SubIDMapper struct {
+	// SubUIDFile and SubGIDFile are the subordinate ID files. If empty,
+	// /etc/subuid and /etc/subgid are used.
+	SubUIDFile, SubGIDFile string
+}
+
+// MapIDs implements [IDMapper].
+func (s *SubIDMapper) MapIDs(pid int, uidMappings, gidMappings []configs.IDMap) error {
+	if err := s.check(uidMappings, gidMappings); err != nil {
+		return err
+	}
+	return DirectIDMapper{}.MapIDs(pid, uidMappings, gidMappings)
+}
+
+// check checks that the mappings only map the IDs allowed to the user
+// running runc.
+func (s *SubIDMapper) check(uidMappings, gidMappings []configs.IDMap) error {
+	u, err := user.CurrentUser()
+	if err != nil {
+		return err
+	}
+	for _, m := range []struct {
+		kind, file, defaultFile string
+		own                     int
+		mappings                []configs.IDMap
+	}{
+		{"uid", s.SubUIDFile, "/etc/subuid", os.Getuid(), uidMappings},
+		{"gid", s.SubGIDFile, "/etc/subgid", os.Getgid(), gidMappings},
+	} {
+		if len(m.mappings) == 0 {
+			continue
+		}
+		if m.file == "" {
+			m.file = m.defaultFile
+		}
+		allowed, err := user.ParseSubIDFileFilter(m.file, func(e user.SubID) bool {
+			return e.Name == u.Name || e.Name == strconv.Itoa(u.Uid)
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		allowed = append(allowed, user.SubID{SubID: int64(m.own), Count: 1})
+	next:
+		for _, idmap := range m.mappings {
+			for _, r := range allowed {
+				if idmap.Size > 0 && idmap.HostID >= r.SubID && idmap.HostID+idmap.Size <= r.SubID+r.Count {
+					continue next
+				}
+			}
+			return fmt.Errorf("%s mapping %d %d %d is not allowed for user %s (see %s)", m.kind, idmap.ContainerID, idmap.HostID, idmap.Size, u.Name, m.file)
+		}
+	}
+	return nil
+}
+
+// ToolIDMapper runs the newuidmap(1) and newgidmap(1) tools, or
+// compatible ones, to set up the mappings.
+type ToolIDMapper struct {
+	// UIDMapPath and GIDMapPath are the paths of the tools. If empty,
+	// newuidmap and newgidmap are looked up in $PATH.
+	UIDMapPath, GIDMapPath string
+}
+
+// MapIDs implements [IDMapper].
+func (t *ToolIDMapper) MapIDs(pid int, uidMappings, gidMappings []configs.IDMap) error {
+	if err := runIDMapTool(t.UIDMapPath, "newuidmap", pid, uidMappings); err != nil {
+		return err
+	}
+	return runIDMapTool(t.GIDMapPath, "newgidmap", pid, gidMappings)
+}
+
+func runIDMapTool(path, name string, pid int, mappings []configs.IDMap) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	if path == "" {
+		path = name
+	}
+	args := []string{strconv.Itoa(pid)}
+	for _, m := range mappings {
+		args = append(args, strconv.FormatInt(m.ContainerID, 10), strconv.FormatInt(m.HostID, 10), strconv.FormatInt(m.Size, 10))
+	}
+	cmd := exec.Command(path, args...)
+	// Like nsexec, run the tool with an empty environment.
+	cmd.Env = []string{}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if out := strings.TrimSpace(string(out)); out != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, out)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// HelperIDMapper requests a privileged helper, listening on a unix
+// socket, to set up the mappings.
+//
+// For each request, the helper is sent a JSON object with the "pid",
+// "uid_mappings" and "gid_mappings" fields (the mappings being encoded as
+// in the container configuration), along with a pidfd of the process if
+// the kernel supports it, to let the helper make sure the process has not
+// been replaced. The helper replies with a JSON object, whose "error"
+// field is set to the error message if the mappings could not be set up.
+type HelperIDMapper struct {
+	// Socket is the path of the helper socket.
+	Socket string
+}
+
+// idMapRequest is the request sent to an ID mapping helper.
+type idMapRequest struct {
+	Pid         int             `json:"pid"`
+	UIDMappings []configs.IDMap `json:"uid_mappings,omitempty"`
+	GIDMappings []configs.IDMap `json:"gid_mappings,omitempty"`
+}
+
+// idMapResponse is the response of an ID mapping helper.
+type idMapResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// MapIDs implements [IDMapper].
+func (h *HelperIDMapper) MapIDs(pid int, uidMappings, gidMappings []configs.IDMap) error {
+	conn, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: h.Socket, Net: "unix"})
+	if err != nil {
+		return fmt.Errorf("unable to connect to the ID mapping helper: %w", err)
+	}
+	defer conn.Close()
+
+	req, err := json.Marshal(idMapRequest{Pid: pid, UIDMappings: uidMappings, GIDMappings: gidMappings})
+	if err != nil {
+		return err
+	}
+	var oob []byte
+	pidfd, err := unix.PidfdOpen(pid, 0)
+	if err == nil {
+		defer unix.Close(pidfd)
+		oob = unix.UnixRights(pidfd)
+	} else if !errors.Is(err, unix.ENOSYS) {
+		return os.NewSyscallError("pidfd_open", err)
+	}
+	if _, _, err := conn.WriteMsgUnix(req, oob, nil); err != nil {
+		return fmt.Errorf("unable to send request to the ID mapping helper: %w", err)
+	}
+	if err := conn.CloseWrite(); err != nil {
+		return err
+	}
+
+	var resp idMapResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("unable to read the ID mapping helper response: %w", err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("ID mapping helper: %s", resp.Error)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package libcontainer
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/moby/sys/user"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+var (
+	testUIDMappings = []configs.IDMap{{ContainerID: 0, HostID: 100000, Size: 1000}, {ContainerID: 1000, HostID: 1000, Size: 1}}
+	testGIDMappings = []configs.IDMap{{ContainerID: 0, HostID: 200000, Size: 65536}}
+)
+
+func TestDirectIDMapper(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	cmd := exec.Command("sleep", "100")
+	cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: unix.CLONE_NEWUSER}
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	pid := cmd.Process.Pid
+
+	if err := (DirectIDMapper{}).MapIDs(pid, testUIDMappings, testGIDMappings); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string][]configs.IDMap{"uid_map": testUIDMappings, "gid_map": testGIDMappings} {
+		data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []configs.IDMap
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var m configs.IDMap
+			f := strings.Fields(line)
+			m.ContainerID, _ = strconv.ParseInt(f[0], 10, 64)
+			m.HostID, _ = strconv.ParseInt(f[1], 10, 64)
+			m.Size, _ = strconv.ParseInt(f[2], 10, 64)
+			got = append(got, m)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: expected %v, got %v", file, want, got)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("%s: expected %v, got %v", file, want, got)
+			}
+		}
+	}
+}
+
+func TestToolIDMapper(t *testing.T) {
+	dir := t.TempDir()
+	var tools [2]string
+	for i, name := range []string{"uidmap", "gidmap"} {
+		tools[i] = filepath.Join(dir, name)
+		script := "#!/bin/sh\necho \"$@\" > " + tools[i] + ".args\n"
+		if err := os.WriteFile(tools[i], []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &ToolIDMapper{UIDMapPath: tools[0], GIDMapPath: tools[1]}
+	if err := m.MapIDs(42, testUIDMappings, testGIDMappings); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"42 0 100000 1000 1000 1000 1", "42 0 200000 65536"} {
+		args, err := os.ReadFile(tools[i] + ".args")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.TrimSpace(string(args)); got != want {
+			t.Errorf("expected %s to be run with %q, got %q", tools[i], want, got)
+		}
+	}
+
+	m.UIDMapPath = "/bin/false"
+	if err := m.MapIDs(42, testUIDMappings, testGIDMappings); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSubIDMapperCheck(t *testing.T) {
+	u, err := user.CurrentUser()
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	m := &SubIDMapper{SubUIDFile: filepath.Join(dir, "subuid"), SubGIDFile: filepath.Join(dir, "subgid")}
+	for file, contents := range map[string]string{
+		m.SubUIDFile: "other:300000:65536\n" + u.Name + ":100000:1000\n",
+		m.SubGIDFile: strconv.Itoa(u.Uid) + ":200000:65536\n",
+	} {
+		if err := os.WriteFile(file, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uid, gid := int64(os.Getuid()), int64(os.Getgid())
+	for _, tc := range []struct {
+		uidMappings, gidMappings []configs.IDMap
+		isErr                    bool
+	}{
+		{
+			uidMappings: []configs.IDMap{{ContainerID: 0, HostID: uid, Size: 1}, {ContainerID: 1, HostID: 100000, Size: 1000}},
+			gidMappings: []configs.IDMap{{ContainerID: 0, HostID: 200000, Size: 65536}},
+		},
+		{
+			gidMappings: []configs.IDMap{{ContainerID: 0, HostID: gid, Size: 1}},
+		},
+		{
+			uidMappings: []configs.IDMap{{ContainerID: 0, HostID: 100500, Size: 1000}},
+			isErr:       true,
+		},
+		{
+			uidMappings: []configs.IDMap{{ContainerID: 0, HostID: 300000, Size: 1}},
+			isErr:       true,
+		},
+		{
+			gidMappings: []configs.IDMap{{ContainerID: 0, HostID: 100000, Size: 1}},
+			isErr:       true,
+		},
+	} {
+		err := m.check(tc.uidMappings, tc.gidMappings)
+		if tc.isErr && err == nil {
+			t.Errorf("%v %v: expected error, got nil", tc.uidMappings, tc.gidMappings)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%v %v: expected nil, got error %v", tc.uidMappings, tc.gidMappings, err)
+		}
+	}
+}
+
+func TestHelperIDMapper(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "helper.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: sock, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// The helper replies with the error in the reqError channel, and sends
+	// the requests it gets, with whether a pidfd was received.
+	type received struct {
+		req   idMapRequest
+		pidfd bool
+	}
+	reqs := make(chan received, 1)
+	reqError := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := l.AcceptUnix()
+			if err != nil {
+				return
+			}
+			var r received
+			buf := make([]byte, 4096)
+			oob := make([]byte, unix.CmsgSpace(4))
+			n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+			if err == nil {
+				_ = json.Unmarshal(buf[:n], &r.req)
+				if msgs, err := unix.ParseSocketControlMessage(oob[:oobn]); err == nil && len(msgs) == 1 {
+					if fds, err := unix.ParseUnixRights(&msgs[0]); err == nil {
+						r.pidfd = len(fds) == 1
+						for _, fd := range fds {
+							unix.Close(fd)
+						}
+					}
+				}
+			}
+			reqs <- r
+			_ = json.NewEncoder(conn).Encode(idMapResponse{Error: <-reqError})
+			conn.Close()
+		}
+	}()
+
+	m := &HelperIDMapper{Socket: sock}
+	pid := os.Getpid()
+	reqError <- ""
+	if err := m.MapIDs(pid, testUIDMappings, testGIDMappings); err != nil {
+		t.Fatal(err)
+	}
+	r := <-reqs
+	if r.req.Pid != pid || len(r.req.UIDMappings) != 2 || r.req.UIDMappings[1] != testUIDMappings[1] || len(r.req.GIDMappings) != 1 {
+		t.Errorf("unexpected request %+v", r.req)
+	}
+	if fd, err := unix.PidfdOpen(pid, 0); err == nil {
+		unix.Close(fd)
+		if !r.pidfd {
+			t.Error("expected a pidfd to be sent")
+		}
+	}
+
+	reqError <- "not allowed"
+	err = m.MapIDs(pid, testUIDMappings, nil)
+	<-reqs
+	if err == nil || !strings.Contains(err.Error(), "not allowed") {
+		t.Fatalf("expected the helper error, got %v", err)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"runtime/debug"
 	"strconv"
 	"syscall"
+	"unsafe"
 
 	"github.com/containerd/console"
 	"github.com/opencontainers/runtime-spec/specs-go"
@@ -23,6 +24,9 @@ import (
 	"github.com/opencontainers/runc/internal/linux"
 	"github.com/opencontainers/runc/libcontainer/capabilities"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/faultinject"
+	"github.com/opencontainers/runc/libcontainer/logs"
+	"github.com/opencontainers/runc/libcontainer/sched"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
@@ -37,6 +41,9 @@ const (
 type pid struct {
 	Pid           int `json:"stage2_pid"`
 	PidFirstChild int `json:"stage1_pid"`
+	// UsermapPid is set instead of the above when runc init requests
+	// the user namespace mappings of that process to be set up.
+	UsermapPid int `json:"usermap_pid,omitempty"`
 }
 
 // network is an internal struct used to setup container networks.
@@ -85,6 +92,7 @@ type initConfig struct {
 	IOPriority      *configs.IOPriority   `json:"io_priority,omitempty"`
 	Scheduler       *configs.Scheduler    `json:"scheduler,omitempty"`
 	CPUAffinity     *configs.CPUAffinity  `json:"cpu_affinity,omitempty"`
+	EnvDefaults     configs.EnvDefaults   `json:"env_defaults,omitempty"`
 
 	// Miscellaneous properties, filled in by [Container.newInitConfig]
 	// unless documented otherwise.
@@ -95,6 +103,12 @@ type initConfig struct {
 	// Networks is filled in from container config by [initProcess.createNetworkInterfaces].
 	Networks []*network `json:"network"`
 
+	// MachineIDFile and BootIDFile are the files to bind mount over the
+	// container /etc/machine-id and /proc/sys/kernel/random/boot_id, filled
+	// in by [Container.newInitProcess].
+	MachineIDFile string `json:"machine_id_file,omitempty"`
+	BootIDFile    string `json:"boot_id_file,omitempty"`
+
 	// SpecState is filled in by [initProcess.Start].
 	SpecState *specs.State `json:"spec_state,omitempty"`
 }
@@ -176,6 +190,9 @@ func startInitialization() (retErr error) {
 
 	logrus.SetOutput(logPipe)
 	logrus.SetFormatter(new(logrus.JSONFormatter))
+	if id := os.Getenv("_LIBCONTAINER_CORRELATIONID"); id != "" {
+		logrus.AddHook(&logs.FieldsHook{Fields: logrus.Fields{logs.CorrelationIDKey: id}})
+	}
 	logrus.Debug("child process in init()")
 
 	// Only init processes have FIFOFD.
@@ -212,6 +229,7 @@ func startInitialization() (retErr error) {
 
 	// From here on, we don't need current process environment. It is not
 	// used directly anywhere below this point, but let's clear it anyway.
+	faultinject.Load()
 	os.Clearenv()
 
 	defer func() {
@@ -322,7 +340,7 @@ func finalizeNamespace(config *initConfig) error {
 
 	// We should set envs after we are in the jail of the container.
 	// Please see https://github.com/opencontainers/runc/issues/4688
-	env, err := prepareEnv(config.Env, config.UID)
+	env, err := prepareEnv(config.Env, config.UID, config.EnvDefaults, config.CreateConsole)
 	if err != nil {
 		return err
 	}
@@ -377,12 +395,31 @@ func setupConsole(socket *os.File, config *initConfig, mount bool) error {
 	// the UID owner of the console to be the user the process will run as (so
 	// they can actually control their console).
 
-	pty, slavePath, err := console.NewPty()
+	var (
+		pty       console.Console
+		slavePath string
+		slave     *os.File
+		err       error
+	)
+	if config.Config.DelegatePty {
+		pty, slavePath, slave, err = newDelegatedPty()
+	} else {
+		pty, slavePath, err = console.NewPty()
+	}
 	if err != nil {
 		return err
 	}
 	// After we return from here, we don't need the console anymore.
 	defer pty.Close()
+	if slave != nil {
+		defer slave.Close()
+		// The slave is from the container's own devpts instance, so
+		// its owner can be changed to the container user and group,
+		// which is what programs like screen or tmux expect.
+		if err := slave.Chown(config.UID, config.GID); err != nil {
+			return err
+		}
+	}
 
 	if config.ConsoleHeight != 0 && config.ConsoleWidth != 0 {
 		err = pty.Resize(console.WinSize{
@@ -407,6 +444,9 @@ func setupConsole(socket *os.File, config *initConfig, mount bool) error {
 	runtime.KeepAlive(pty)
 
 	// Now, dup over all the things.
+	if slave != nil {
+		return dupStdioFd(int(slave.Fd()))
+	}
 	return dupStdio(slavePath)
 }
 
@@ -617,7 +657,7 @@ func setupScheduler(config *initConfig) error {
 	if config.Scheduler == nil {
 		return nil
 	}
-	attr, err := configs.ToSchedAttr(config.Scheduler)
+	attr, err := sched.ToSchedAttr(config.Scheduler)
 	if err != nil {
 		return err
 	}
@@ -659,6 +699,25 @@ func setupIOPriority(config *initConfig) error {
 	return nil
 }
 
+// setupMemoryPolicy sets the NUMA memory policy of the process.
+func setupMemoryPolicy(config *configs.Config) error {
+	p := config.MemoryPolicy
+	if p == nil {
+		return nil
+	}
+	mask, maxNode := p.Nodemask()
+	var maskPtr unsafe.Pointer
+	if len(mask) > 0 {
+		maskPtr = unsafe.Pointer(&mask[0])
+	}
+	_, _, errno := unix.Syscall(unix.SYS_SET_MEMPOLICY, uintptr(p.Mode|p.Flags), uintptr(maskPtr), uintptr(maxNode))
+	runtime.KeepAlive(mask)
+	if errno != 0 {
+		return fmt.Errorf("unable to set the memory policy: %w", errno)
+	}
+	return nil
+}
+
 func setupPersonality(config *configs.Config) error {
 	return system.SetLinuxPersonality(config.Personality.Domain)
 }
@@ -680,14 +739,25 @@ func signalAllProcesses(m cgroups.Manager, s unix.Signal) error {
 		}
 	}
 
+	// A paused container is left frozen, unless the processes are killed.
+	paused := false
+	if state, err := m.GetFreezerState(); err == nil && state == cgroups.Frozen {
+		paused = s != unix.SIGKILL
+	}
+	thaw := func() {
+		if paused {
+			return
+		}
+		if err := m.Freeze(cgroups.Thawed); err != nil {
+			logrus.Warn(err)
+		}
+	}
 	if err := m.Freeze(cgroups.Frozen); err != nil {
 		logrus.Warn(err)
 	}
 	pids, err := m.GetAllPids()
 	if err != nil {
-		if err := m.Freeze(cgroups.Thawed); err != nil {
-			logrus.Warn(err)
-		}
+		thaw()
 		return err
 	}
 	for _, pid := range pids {
@@ -696,9 +766,7 @@ func signalAllProcesses(m cgroups.Manager, s unix.Signal) error {
 			logrus.Warnf("kill %d: %v", pid, err)
 		}
 	}
-	if err := m.Freeze(cgroups.Thawed); err != nil {
-		logrus.Warn(err)
-	}
+	thaw()
 
 	return nil
 }
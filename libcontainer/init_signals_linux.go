@@ -0,0 +1,70 @@
+package libcontainer
+
+import (
+	"os"
+	"os/signal"
+	"slices"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// maxSignal is the highest signal number (SIGRTMAX).
+const maxSignal = 64
+
+// setupInitSignals sets the signal mask and the ignored signals of the
+// container process (see [configs.InitSignals]). It must be called by the
+// thread executing the container process, before the seccomp filter is
+// installed.
+func setupInitSignals(config *configs.InitSignals) error {
+	var s configs.InitSignals
+	if config != nil {
+		s = *config
+	}
+
+	var mask unix.Sigset_t
+	for _, sig := range s.Blocked {
+		sigaddset(&mask, sig)
+	}
+	how := unix.SIG_SETMASK
+	if s.Inherit {
+		how = unix.SIG_BLOCK
+	}
+	if err := unix.PthreadSigmask(how, &mask, nil); err != nil {
+		return os.NewSyscallError("rt_sigprocmask", err)
+	}
+
+	if !s.Inherit {
+		// The Go runtime leaves some signals ignored if they are when it
+		// starts (such as SIGHUP). Have it handle those instead, as the
+		// signals with a handler are reset to their default disposition by
+		// execve(2).
+		var reset []os.Signal
+		for sig := unix.Signal(1); sig <= maxSignal; sig++ {
+			if signal.Ignored(sig) && !slices.Contains(s.Ignored, sig) {
+				reset = append(reset, sig)
+			}
+		}
+		if len(reset) > 0 {
+			signal.Notify(make(chan os.Signal, 1), reset...)
+		}
+	}
+	if len(s.Ignored) > 0 {
+		ignored := make([]os.Signal, 0, len(s.Ignored))
+		for _, sig := range s.Ignored {
+			ignored = append(ignored, sig)
+		}
+		// An ignored signal is left ignored by execve(2).
+		signal.Ignore(ignored...)
+	}
+	return nil
+}
+
+// sigaddset adds the signal sig to the set.
+func sigaddset(set *unix.Sigset_t, sig unix.Signal) {
+	const bits = 8 * unsafe.Sizeof(set.Val[0])
+	n := uintptr(sig - 1)
+	set.Val[n/bits] |= 1 << (n % bits)
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"syscall"
 	"testing"
+	"time"
 
 	"github.com/opencontainers/cgroups"
 	"github.com/opencontainers/cgroups/systemd"
@@ -1409,6 +1410,44 @@ func testPidnsInitKill(t *testing.T, config *configs.Config) {
 	}
 }
 
+func TestHostPidnsKillTimeout(t *testing.T) {
+	if testing.Short() {
+		return
+	}
+	config := newTemplateConfig(t, nil)
+	config.Namespaces.Remove(configs.NEWPID)
+	container, err := newContainer(t, config)
+	ok(t, err)
+	defer destroyContainer(container)
+
+	// Processes ignoring SIGTERM.
+	var waits []chan error
+	for _, init := range []bool{true, false} {
+		process := &libcontainer.Process{
+			Cwd:  "/",
+			Args: []string{"sh", "-c", "trap '' TERM; while :; do sleep 1; done"},
+			Env:  standardEnvironment,
+			Init: init,
+		}
+		ok(t, container.Run(process))
+		wait := make(chan error, 1)
+		go func() {
+			_, err := process.Wait()
+			wait <- err
+		}()
+		waits = append(waits, wait)
+	}
+
+	// All the processes are killed once the timeout expires.
+	err = container.Kill(syscall.SIGTERM, libcontainer.KillOpts{All: true, Timeout: 500 * time.Millisecond})
+	ok(t, err)
+	for _, wait := range waits {
+		if err := <-wait; err == nil {
+			t.Fatal("expected Wait to indicate failure")
+		}
+	}
+}
+
 func TestInitJoinPID(t *testing.T) {
 	if testing.Short() {
 		return
@@ -0,0 +1,153 @@
+package intelrdt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/sys/unix"
+)
+
+// closGroupsFile is the host-wide registry of the shared CLOS groups (see
+// [configs.IntelRdt.Shared]), which is also locked during their updates.
+var closGroupsFile = "/run/runc/intelrdt-groups.json"
+
+// ClosGroup is a CLOS group shared by containers.
+type ClosGroup struct {
+	// ClosID is the name of the group, that is, of its directory in the
+	// resctrl filesystem.
+	ClosID string `json:"-"`
+	// Created is whether the group was created for its first container, in
+	// which case it is removed along with its last one. Otherwise, the
+	// group is externally managed, and is never removed.
+	Created bool `json:"created"`
+	// Containers are the IDs of the containers in the group. Each one has
+	// its own monitoring group of the same name in the group, if the
+	// monitoring is supported (see [MonGroupPath]).
+	Containers []string `json:"containers"`
+}
+
+// ClosGroupPath returns the path of the CLOS group closID.
+func ClosGroupPath(closID string) (string, error) {
+	root, err := Root()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, closID), nil
+}
+
+// GetClosGroup returns the shared CLOS group closID, or nil if no container
+// shares it.
+func GetClosGroup(closID string) (*ClosGroup, error) {
+	f, err := os.Open(closGroupsFile)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_SH); err != nil {
+		return nil, fmt.Errorf("unable to lock %s: %w", closGroupsFile, err)
+	}
+	groups, err := readClosGroups(f)
+	if err != nil {
+		return nil, err
+	}
+	g := groups[closID]
+	if g != nil {
+		g.ClosID = closID
+	}
+	return g, nil
+}
+
+func readClosGroups(f *os.File) (map[string]*ClosGroup, error) {
+	groups := make(map[string]*ClosGroup)
+	if err := json.NewDecoder(f).Decode(&groups); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to read %s: %w", closGroupsFile, err)
+	}
+	return groups, nil
+}
+
+// updateClosGroup calls fn with the shared CLOS group closID while the
+// registry is locked, then saves the group, or removes it from the
+// registry if it has no containers left.
+func updateClosGroup(closID string, fn func(*ClosGroup) error) error {
+	if err := os.MkdirAll(filepath.Dir(closGroupsFile), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(closGroupsFile, os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	// Closing the file releases the lock.
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return fmt.Errorf("unable to lock %s: %w", closGroupsFile, err)
+	}
+	groups, err := readClosGroups(f)
+	if err != nil {
+		return err
+	}
+	g := groups[closID]
+	if g == nil {
+		g = &ClosGroup{}
+	}
+	g.ClosID = closID
+	if err := fn(g); err != nil {
+		return err
+	}
+	if len(g.Containers) == 0 {
+		delete(groups, closID)
+	} else {
+		groups[closID] = g
+	}
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// joinClosGroup adds the container id to the shared CLOS group of path,
+// creating the group if it does not exist.
+func joinClosGroup(closID, path, id string) error {
+	return updateClosGroup(closID, func(g *ClosGroup) error {
+		if err := os.Mkdir(path, 0o755); err == nil {
+			g.Created = true
+		} else if !errors.Is(err, os.ErrExist) {
+			return newLastCmdError(err)
+		} else if len(g.Containers) == 0 {
+			// An existing group with no containers is externally
+			// managed.
+			g.Created = false
+		}
+		if !slices.Contains(g.Containers, id) {
+			g.Containers = append(g.Containers, id)
+		}
+		return nil
+	})
+}
+
+// leaveClosGroup removes the container id from the shared CLOS group of
+// path, removing the group along with its last container if it was created
+// for the first one.
+func leaveClosGroup(closID, path, id string) error {
+	return updateClosGroup(closID, func(g *ClosGroup) error {
+		g.Containers = slices.DeleteFunc(g.Containers, func(c string) bool { return c == id })
+		if len(g.Containers) == 0 && g.Created {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -178,6 +178,7 @@ func newManager(config *configs.Config, id string, path string) *Manager {
 
 const (
 	intelRdtTasks = "tasks"
+	monGroupsDir  = "mon_groups"
 )
 
 var (
@@ -416,6 +417,12 @@ func WriteIntelRdtTasks(dir string, pid int) error {
 	return nil
 }
 
+// MonGroupPath returns the path of the monitoring group of the container id
+// in the clos group closPath.
+func MonGroupPath(closPath, id string) string {
+	return filepath.Join(closPath, monGroupsDir, id)
+}
+
 // IsCATEnabled checks if Intel RDT/CAT is enabled.
 func IsCATEnabled() bool {
 	featuresInit()
@@ -430,17 +437,23 @@ func IsMBAEnabled() bool {
 
 // Get the path of the clos group in "resource control" filesystem that the container belongs to
 func (m *Manager) getIntelRdtPath() (string, error) {
-	rootPath, err := Root()
-	if err != nil {
-		return "", err
-	}
+	return ClosGroupPath(m.closID())
+}
 
-	clos := m.id
+// closID returns the name of the clos group of the container.
+func (m *Manager) closID() string {
 	if m.config.IntelRdt != nil && m.config.IntelRdt.ClosID != "" {
-		clos = m.config.IntelRdt.ClosID
+		return m.config.IntelRdt.ClosID
 	}
+	return m.id
+}
 
-	return filepath.Join(rootPath, clos), nil
+// monGroup reports whether the container has a monitoring group of its own
+// in its clos group: if its monitoring is enabled, or if the clos group is
+// shared and the monitoring is supported.
+func (m *Manager) monGroup() bool {
+	r := m.config.IntelRdt
+	return r.Monitoring() || r.Shared && (IsCMTEnabled() || IsMBMEnabled())
 }
 
 // Apply applies Intel RDT configuration to the process with the specified pid.
@@ -458,7 +471,11 @@ func (m *Manager) Apply(pid int) (err error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.config.IntelRdt.ClosID != "" && m.config.IntelRdt.L3CacheSchema == "" && m.config.IntelRdt.MemBwSchema == "" {
+	if m.config.IntelRdt.Shared {
+		if err := joinClosGroup(m.closID(), path, m.id); err != nil {
+			return err
+		}
+	} else if m.config.IntelRdt.ClosID != "" && m.config.IntelRdt.L3CacheSchema == "" && m.config.IntelRdt.MemBwSchema == "" {
 		// Check that the CLOS exists, i.e. it has been pre-configured to
 		// conform with the runtime spec
 		if _, err := os.Stat(path); err != nil {
@@ -474,22 +491,51 @@ func (m *Manager) Apply(pid int) (err error) {
 		return newLastCmdError(err)
 	}
 
+	// The monitoring group of the container, so that its monitoring data
+	// does not include the other tasks of the clos group.
+	if m.monGroup() {
+		monPath := MonGroupPath(path, m.id)
+		if err := os.Mkdir(monPath, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+			return newLastCmdError(err)
+		}
+		if err := WriteIntelRdtTasks(monPath, pid); err != nil {
+			return err
+		}
+	}
+
 	m.path = path
 	return nil
 }
 
-// Destroy destroys the Intel RDT container-specific container_id group.
+// Destroy destroys the Intel RDT container-specific container_id group, or
+// the container monitoring group if the clos group is not container-specific.
+// A shared clos group is destroyed along with its last container, if it was
+// created for its first one.
 func (m *Manager) Destroy() error {
+	if m.config.IntelRdt == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	// Don't remove resctrl group if closid has been explicitly specified. The
 	// group is likely externally managed, i.e. by some other entity than us.
 	// There are probably other containers/tasks sharing the same group.
-	if m.config.IntelRdt != nil && m.config.IntelRdt.ClosID == "" {
-		m.mu.Lock()
-		defer m.mu.Unlock()
+	if m.config.IntelRdt.ClosID == "" {
 		if err := os.RemoveAll(m.GetPath()); err != nil {
 			return err
 		}
 		m.path = ""
+		return nil
+	}
+	if m.monGroup() {
+		// The monitoring group directory can only be removed with rmdir,
+		// which is what os.Remove does for a directory.
+		if err := os.Remove(MonGroupPath(m.GetPath(), m.id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if m.config.IntelRdt.Shared {
+		return leaveClosGroup(m.closID(), m.GetPath(), m.id)
 	}
 	return nil
 }
@@ -580,7 +626,11 @@ func (m *Manager) GetStats() (*Stats, error) {
 	}
 
 	if IsMBMEnabled() || IsCMTEnabled() {
-		err = getMonitoringStats(containerPath, stats)
+		monPath := containerPath
+		if m.monGroup() {
+			monPath = MonGroupPath(containerPath, m.id)
+		}
+		err = getMonitoringStats(monPath, stats)
 		if err != nil {
 			return nil, err
 		}
@@ -3,6 +3,7 @@ package intelrdt
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -125,3 +126,111 @@ func TestApply(t *testing.T) {
 		t.Fatalf("unexpected tasks file, expected '1235', got %q", pids)
 	}
 }
+
+func TestMonGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+
+	const closID = "test-clos"
+	closPath := filepath.Join(intelRdtRoot, closID)
+	if err := os.Mkdir(closPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// The kernel creates the mon_groups directory of a clos group.
+	if err := os.Mkdir(filepath.Join(closPath, monGroupsDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	helper.config.IntelRdt.ClosID = closID
+	helper.config.IntelRdt.EnableCMT = true
+	intelrdt := newManager(helper.config, "ctr", helper.IntelRdtPath)
+	if err := intelrdt.Apply(1236); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+
+	monPath := MonGroupPath(closPath, "ctr")
+	pids, err := getIntelRdtParamString(monPath, "tasks")
+	if err != nil {
+		t.Fatalf("failed to read mon group tasks file: %v", err)
+	}
+	if pids != "1236" {
+		t.Fatalf("unexpected mon group tasks file, expected '1236', got %q", pids)
+	}
+
+	// Unlike in resctrl, the mock tasks file prevents rmdir.
+	if err := os.Remove(filepath.Join(monPath, "tasks")); err != nil {
+		t.Fatal(err)
+	}
+	if err := intelrdt.Destroy(); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if _, err := os.Stat(monPath); !os.IsNotExist(err) {
+		t.Fatalf("mon group should be removed, got %v", err)
+	}
+	if _, err := os.Stat(closPath); err != nil {
+		t.Fatalf("clos group should be kept, got %v", err)
+	}
+	// Destroy is idempotent.
+	if err := intelrdt.Destroy(); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+}
+
+func TestSharedClosGroup(t *testing.T) {
+	helper := NewIntelRdtTestUtil(t)
+	old := closGroupsFile
+	closGroupsFile = filepath.Join(t.TempDir(), "intelrdt-groups.json")
+	t.Cleanup(func() { closGroupsFile = old })
+
+	const closID = "shared-clos"
+	closPath := filepath.Join(intelRdtRoot, closID)
+	helper.config.IntelRdt.ClosID = closID
+	helper.config.IntelRdt.Shared = true
+	m1 := newManager(helper.config, "ctr1", "")
+	m2 := newManager(helper.config, "ctr2", "")
+
+	// The group is created for the first container, even with no schema.
+	if err := m1.Apply(1237); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if err := m2.Apply(1238); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	g, err := GetClosGroup(closID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g == nil || !g.Created || !slices.Equal(g.Containers, []string{"ctr1", "ctr2"}) {
+		t.Fatalf("unexpected clos group %+v", g)
+	}
+
+	// The group is kept until its last container is destroyed.
+	if err := m1.Destroy(); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if _, err := os.Stat(closPath); err != nil {
+		t.Fatalf("clos group should be kept, got %v", err)
+	}
+	if err := m2.Destroy(); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if _, err := os.Stat(closPath); !os.IsNotExist(err) {
+		t.Fatalf("clos group should be removed, got %v", err)
+	}
+	if g, err := GetClosGroup(closID); err != nil || g != nil {
+		t.Fatalf("expected no clos group, got %+v, %v", g, err)
+	}
+
+	// An existing group is externally managed, and is never removed.
+	if err := os.Mkdir(closPath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := m1.Apply(1239); err != nil {
+		t.Fatalf("Apply() failed: %v", err)
+	}
+	if err := m1.Destroy(); err != nil {
+		t.Fatalf("Destroy() failed: %v", err)
+	}
+	if _, err := os.Stat(closPath); err != nil {
+		t.Fatalf("external clos group should be kept, got %v", err)
+	}
+}
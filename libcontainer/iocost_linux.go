@@ -0,0 +1,114 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// ioCostRoot is the cgroup v2 root, where the blk-iocost files are.
+const ioCostRoot = "/sys/fs/cgroup"
+
+// IOCostState are the blk-iocost parameters of a block device before they
+// were first set for the container, which are restored once it is
+// destroyed.
+type IOCostState struct {
+	// File is either io.cost.qos or io.cost.model.
+	File string `json:"file"`
+	// Device is the "major:minor" device number.
+	Device string `json:"device"`
+	// Line is the line of the device in File, or empty if there was none.
+	Line string `json:"line,omitempty"`
+}
+
+// applyIOCost writes the blk-iocost parameters to the root cgroup, saving
+// the previous ones of each device first. The parameters which are no longer
+// in ioCost are left as they are. If the kernel does not support blk-iocost,
+// the parameters are ignored (with a warning), and only io.weight applies,
+// if the IO scheduler supports it.
+func (c *Container) applyIOCost(ioCost *configs.IOCost) error {
+	if ioCost == nil || len(ioCost.QoS)+len(ioCost.Model) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(ioCostRoot, configs.IOCostQoSFile)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			logrus.Warn("io.cost is not supported by the kernel (CONFIG_BLK_CGROUP_IOCOST), ignoring the io.cost settings")
+			return nil
+		}
+		return err
+	}
+	for _, f := range []struct {
+		name    string
+		devices []*configs.IOCostDevice
+	}{
+		{configs.IOCostQoSFile, ioCost.QoS},
+		{configs.IOCostModelFile, ioCost.Model},
+	} {
+		for _, d := range f.devices {
+			if err := c.saveIOCost(f.name, fmt.Sprintf("%d:%d", d.Major, d.Minor)); err != nil {
+				return err
+			}
+			if err := cgroups.WriteFile(ioCostRoot, f.name, d.String()); err != nil {
+				return fmt.Errorf("unable to set %s: %w", f.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// saveIOCost saves the parameters of the device in the blk-iocost file,
+// unless they were already saved.
+func (c *Container) saveIOCost(file, device string) error {
+	for _, s := range c.ioCostSaved {
+		if s.File == file && s.Device == device {
+			return nil
+		}
+	}
+	data, err := cgroups.ReadFile(ioCostRoot, file)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", file, err)
+	}
+	c.ioCostSaved = append(c.ioCostSaved, IOCostState{
+		File:   file,
+		Device: device,
+		Line:   ioCostLine(data, device),
+	})
+	return nil
+}
+
+// ioCostLine returns the line of the device in the content of a blk-iocost
+// file, if any.
+func ioCostLine(data, device string) string {
+	for _, line := range strings.Split(data, "\n") {
+		if dev, _, _ := strings.Cut(line, " "); dev == device {
+			return line
+		}
+	}
+	return ""
+}
+
+// restoreIOCost restores the blk-iocost parameters saved by applyIOCost. A
+// device which had no parameters gets the kernel defaults, that is, with
+// blk-iocost disabled, and the automatic cost model.
+func (c *Container) restoreIOCost() {
+	for _, s := range c.ioCostSaved {
+		line := s.Line
+		if line == "" {
+			line = s.Device + " enable=0"
+			if s.File == configs.IOCostModelFile {
+				line = s.Device + " ctrl=auto"
+			}
+		}
+		if err := cgroups.WriteFile(ioCostRoot, s.File, line); err != nil {
+			logrus.Warnf("unable to restore %s of device %s: %v", s.File, s.Device, err)
+		}
+	}
+	c.ioCostSaved = nil
+}
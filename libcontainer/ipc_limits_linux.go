@@ -0,0 +1,36 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// setupIPCLimits sets the IPC limits in the container IPC namespace.
+func setupIPCLimits(l *configs.IPCLimits) error {
+	if l == nil {
+		return nil
+	}
+	for _, s := range l.Sysctls() {
+		if err := writeSystemProperty(s[0], s[1]); err != nil {
+			return fmt.Errorf("unable to set %s: %w", s[0], err)
+		}
+	}
+	if l.HasSem() {
+		// The semaphore limits are set at once, so the ones which are not
+		// set are kept from the namespace.
+		current, err := os.ReadFile("/proc/sys/kernel/sem")
+		if err != nil {
+			return err
+		}
+		sem, err := l.Sem(string(current))
+		if err != nil {
+			return err
+		}
+		if err := writeSystemProperty(configs.SemSysctl, sem); err != nil {
+			return fmt.Errorf("unable to set %s: %w", configs.SemSysctl, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+)
+
+// Overridden in tests.
+var (
+	procIRQRoot    = "/proc/irq"
+	pciDevicesRoot = "/sys/bus/pci/devices"
+)
+
+// IRQAffinityState is the affinity of an interrupt before it was set for
+// the container, which is restored once it is destroyed.
+type IRQAffinityState struct {
+	IRQ int `json:"irq"`
+	// CPUs is the previous smp_affinity_list, in the cpuset list format.
+	CPUs string `json:"cpus"`
+}
+
+// steerIRQs sets the affinity of the interrupts of the PCI network devices
+// in the network namespace of the process pid to cpus (in the cpuset list
+// format), so that those are handled on the CPUs the container runs on. It
+// returns the previous affinities, for restoreIRQAffinity.
+//
+// The interrupts whose affinity can't be set by userspace (the ones managed
+// by the kernel) are skipped.
+func steerIRQs(pid int, cpus string) ([]IRQAffinityState, error) {
+	if cpus == "" {
+		return nil, errors.New("the container has no cpuset")
+	}
+	ns, err := netns.GetFromPid(pid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get network namespace: %w", err)
+	}
+	defer ns.Close()
+
+	nh, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, err
+	}
+	defer nh.Close()
+	links, err := nh.LinkList()
+	// Do not fail when the kernel returns NLM_F_DUMP_INTR, see devChangeNetNamespace.
+	if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
+		return nil, fmt.Errorf("unable to list network devices: %w", err)
+	}
+	sock, err := socketAt(ns)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(sock)
+
+	var irqs []int
+	for _, link := range links {
+		name := link.Attrs().Name
+		info, err := unix.IoctlGetEthtoolDrvinfo(sock, name)
+		if err != nil {
+			// Not supported by virtual devices, such as the loopback.
+			logrus.Debugf("irq affinity: skipping %s: %v", name, err)
+			continue
+		}
+		devIRQs, err := pciDeviceIRQs(unix.ByteSliceToString(info.Bus_info[:]))
+		if err != nil {
+			logrus.Debugf("irq affinity: skipping %s: %v", name, err)
+			continue
+		}
+		irqs = append(irqs, devIRQs...)
+	}
+	return setIRQAffinity(irqs, cpus)
+}
+
+// setIRQAffinity sets the affinity of irqs to cpus, and returns the previous
+// affinities. On error, the affinities already set are restored.
+func setIRQAffinity(irqs []int, cpus string) ([]IRQAffinityState, error) {
+	var saved []IRQAffinityState
+	for _, irq := range irqs {
+		path := filepath.Join(procIRQRoot, strconv.Itoa(irq), "smp_affinity_list")
+		prev, err := os.ReadFile(path)
+		if err != nil {
+			restoreIRQs(saved)
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(cpus), 0); err != nil {
+			if errors.Is(err, unix.EIO) {
+				// A managed interrupt, see irq_can_set_affinity_usr.
+				logrus.Debugf("irq affinity: skipping interrupt %d: %v", irq, err)
+				continue
+			}
+			restoreIRQs(saved)
+			return nil, fmt.Errorf("unable to set the affinity of interrupt %d: %w", irq, err)
+		}
+		saved = append(saved, IRQAffinityState{IRQ: irq, CPUs: strings.TrimSpace(string(prev))})
+	}
+	return saved, nil
+}
+
+// restoreIRQs restores the interrupt affinities saved by setIRQAffinity.
+func restoreIRQs(saved []IRQAffinityState) {
+	for _, s := range saved {
+		path := filepath.Join(procIRQRoot, strconv.Itoa(s.IRQ), "smp_affinity_list")
+		if err := os.WriteFile(path, []byte(s.CPUs), 0); err != nil {
+			logrus.WithError(err).Warnf("unable to restore the affinity of interrupt %d", s.IRQ)
+		}
+	}
+}
+
+// restoreIRQAffinity restores the affinity of the interrupts steered to the
+// container cpuset (see [configs.Config.IRQAffinity]).
+func (c *Container) restoreIRQAffinity() {
+	restoreIRQs(c.irqAffinity)
+	c.irqAffinity = nil
+}
+
+// socketAt returns a socket, usable for the device ioctls, in the network
+// namespace ns.
+func socketAt(ns netns.NsHandle) (int, error) {
+	type result struct {
+		fd  int
+		err error
+	}
+	resCh := make(chan result)
+	// Use a goroutine to dedicate an OS thread.
+	go func() {
+		runtime.LockOSThread()
+		if err := netns.Set(ns); err != nil {
+			resCh <- result{-1, fmt.Errorf("unable to join network namespace: %w", err)}
+			return
+		}
+		fd, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+		if err != nil {
+			err = os.NewSyscallError("socket", err)
+		}
+		resCh <- result{fd, err}
+		// Deliberately omit runtime.UnlockOSThread here, so that the
+		// thread, which is in another network namespace, is terminated.
+	}()
+	res := <-resCh
+	return res.fd, res.err
+}
+
+// pciDeviceIRQs returns the interrupts of the PCI device with the bus
+// address addr: its MSI ones, or else its legacy one.
+func pciDeviceIRQs(addr string) ([]int, error) {
+	if addr == "" || addr != filepath.Base(addr) {
+		return nil, fmt.Errorf("not a PCI device (bus info %q)", addr)
+	}
+	dir := filepath.Join(pciDevicesRoot, addr)
+	entries, err := os.ReadDir(filepath.Join(dir, "msi_irqs"))
+	if err == nil && len(entries) > 0 {
+		irqs := make([]int, 0, len(entries))
+		for _, e := range entries {
+			irq, err := strconv.Atoi(e.Name())
+			if err != nil {
+				continue
+			}
+			irqs = append(irqs, irq)
+		}
+		return irqs, nil
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "irq"))
+	if err != nil {
+		return nil, err
+	}
+	irq, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid irq of PCI device %s: %w", addr, err)
+	}
+	if irq == 0 {
+		return nil, nil
+	}
+	return []int{irq}, nil
+}
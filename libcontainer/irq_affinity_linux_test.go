@@ -0,0 +1,111 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPCIDeviceIRQs(t *testing.T) {
+	pciDevicesRoot = t.TempDir()
+	t.Cleanup(func() { pciDevicesRoot = "/sys/bus/pci/devices" })
+
+	writeTestFile(t, filepath.Join(pciDevicesRoot, "0000:01:00.0", "msi_irqs", "130"), "msix\n")
+	writeTestFile(t, filepath.Join(pciDevicesRoot, "0000:01:00.0", "msi_irqs", "131"), "msix\n")
+	writeTestFile(t, filepath.Join(pciDevicesRoot, "0000:01:00.0", "irq"), "0\n")
+	writeTestFile(t, filepath.Join(pciDevicesRoot, "0000:02:00.0", "irq"), "17\n")
+	writeTestFile(t, filepath.Join(pciDevicesRoot, "0000:03:00.0", "irq"), "0\n")
+
+	for _, tc := range []struct {
+		addr string
+		irqs []int
+		err  bool
+	}{
+		{addr: "0000:01:00.0", irqs: []int{130, 131}},
+		{addr: "0000:02:00.0", irqs: []int{17}},
+		{addr: "0000:03:00.0"},
+		{addr: "0000:04:00.0", err: true},
+		{addr: "", err: true},
+		{addr: "../0000:01:00.0", err: true},
+	} {
+		irqs, err := pciDeviceIRQs(tc.addr)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", tc.addr, irqs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.addr, err)
+			continue
+		}
+		if !reflect.DeepEqual(irqs, tc.irqs) {
+			t.Errorf("%q: expected %v, got %v", tc.addr, tc.irqs, irqs)
+		}
+	}
+}
+
+func TestSetIRQAffinity(t *testing.T) {
+	procIRQRoot = t.TempDir()
+	t.Cleanup(func() { procIRQRoot = "/proc/irq" })
+
+	affinity := func(irq int) string {
+		data, err := os.ReadFile(filepath.Join(procIRQRoot, strconv.Itoa(irq), "smp_affinity_list"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	writeTestFile(t, filepath.Join(procIRQRoot, "130", "smp_affinity_list"), "0-7\n")
+	writeTestFile(t, filepath.Join(procIRQRoot, "131", "smp_affinity_list"), "3\n")
+
+	saved, err := setIRQAffinity([]int{130, 131}, "4-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []IRQAffinityState{{IRQ: 130, CPUs: "0-7"}, {IRQ: 131, CPUs: "3"}}
+	if !reflect.DeepEqual(saved, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, saved)
+	}
+	if a := affinity(130); a != "4-5" {
+		t.Errorf("expected the affinity of interrupt 130 to be 4-5, got %q", a)
+	}
+
+	c := &Container{irqAffinity: saved}
+	c.restoreIRQAffinity()
+	if a := affinity(130); a != "0-7" {
+		t.Errorf("expected the affinity of interrupt 130 to be restored to 0-7, got %q", a)
+	}
+	if a := affinity(131); a != "3" {
+		t.Errorf("expected the affinity of interrupt 131 to be restored to 3, got %q", a)
+	}
+	if c.irqAffinity != nil {
+		t.Error("expected the saved affinities to be cleared")
+	}
+
+	// An error restores the affinities already set.
+	if _, err := setIRQAffinity([]int{130, 132}, "4-5"); err == nil {
+		t.Fatal("expected an error for a missing interrupt")
+	}
+	if a := affinity(130); a != "0-7" {
+		t.Errorf("expected the affinity of interrupt 130 to be restored to 0-7, got %q", a)
+	}
+}
+
+func TestSteerIRQsNoCpuset(t *testing.T) {
+	if _, err := steerIRQs(os.Getpid(), ""); err == nil {
+		t.Fatal("expected an error without a cpuset")
+	}
+}
@@ -0,0 +1,63 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// keysSysctlDir is the directory of the kernel key quota sysctls.
+var keysSysctlDir = "/proc/sys/kernel/keys"
+
+// setupKeyringQuota raises the host per-user key quotas to the ones of the
+// container keyring, if they are lower (see [configs.KeyringQuota]).
+func setupKeyringQuota(k *configs.Keyring) error {
+	if k == nil || k.Quota == nil {
+		return nil
+	}
+	for _, q := range []struct {
+		files []string
+		min   uint32
+	}{
+		{[]string{"maxkeys", "root_maxkeys"}, k.Quota.MaxKeys},
+		{[]string{"maxbytes", "root_maxbytes"}, k.Quota.MaxBytes},
+	} {
+		if q.min == 0 {
+			continue
+		}
+		for _, file := range q.files {
+			if err := raiseKeysSysctl(file, q.min); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// raiseKeysSysctl sets the kernel.keys sysctl file to value, unless it is
+// already higher.
+func raiseKeysSysctl(file string, value uint32) error {
+	path := filepath.Join(keysSysctlDir, file)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	current, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid kernel.keys.%s: %w", file, err)
+	}
+	if current >= uint64(value) {
+		return nil
+	}
+	if err := os.WriteFile(path, []byte(strconv.FormatUint(uint64(value), 10)), 0o644); err != nil {
+		return fmt.Errorf("unable to raise kernel.keys.%s from %d to %d: %w", file, current, value, err)
+	}
+	logrus.Infof("raised kernel.keys.%s from %d to %d", file, current, value)
+	return nil
+}
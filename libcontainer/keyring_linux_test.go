@@ -0,0 +1,45 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestSetupKeyringQuota(t *testing.T) {
+	dir := t.TempDir()
+	saved := keysSysctlDir
+	keysSysctlDir = dir
+	t.Cleanup(func() { keysSysctlDir = saved })
+	for file, value := range map[string]string{
+		"maxkeys":       "200\n",
+		"root_maxkeys":  "1000000\n",
+		"maxbytes":      "20000\n",
+		"root_maxbytes": "25000000\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := setupKeyringQuota(&configs.Keyring{Quota: &configs.KeyringQuota{MaxKeys: 500}}); err != nil {
+		t.Fatal(err)
+	}
+	// The quotas are raised, never lowered, and those not set are kept.
+	for file, expected := range map[string]string{
+		"maxkeys":       "500",
+		"root_maxkeys":  "1000000\n",
+		"maxbytes":      "20000\n",
+		"root_maxbytes": "25000000\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, data)
+		}
+	}
+}
@@ -43,3 +43,28 @@ func ModKeyringPerm(ringID KeySerial, mask, setbits uint32) error {
 
 	return unix.KeyctlSetperm(int(ringID), perm)
 }
+
+// Search searches the keyring ringID (which can be a special keyring ID,
+// such as unix.KEY_SPEC_SESSION_KEYRING), and the keyrings linked into it,
+// for a key of the given type and description, and links it into the
+// keyring destID, unless it is zero.
+func Search(ringID int, keyType, description string, destID int) (KeySerial, error) {
+	id, err := unix.KeyctlSearch(ringID, keyType, description, destID)
+	if err != nil {
+		return 0, err
+	}
+	return KeySerial(id), nil
+}
+
+// Link links the key keyID into the keyring ringID.
+func Link(keyID, ringID KeySerial) error {
+	_, err := unix.KeyctlInt(unix.KEYCTL_LINK, int(keyID), int(ringID), 0, 0)
+	return err
+}
+
+// Clear removes all the links of the keyring ringID (which can be a special
+// keyring ID).
+func Clear(ringID int) error {
+	_, err := unix.KeyctlInt(unix.KEYCTL_CLEAR, ringID, 0, 0, 0)
+	return err
+}
@@ -0,0 +1,99 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+var (
+	// killPollInterval is the interval at which [Container.Kill] checks
+	// whether the processes it signaled are gone.
+	killPollInterval = 100 * time.Millisecond
+	// killGracePeriod is how long [Container.Kill] waits for the processes
+	// to be gone after SIGKILL, before reporting the survivors.
+	killGracePeriod = 5 * time.Second
+)
+
+// KillOpts are the options of [Container.Kill].
+type KillOpts struct {
+	// All sends the signal to all the container processes, rather than to
+	// the init process only. It works whether or not the container has its
+	// own PID namespace, using cgroup.kill for SIGKILL on cgroup v2, or
+	// freezing the cgroup and signaling each of its processes otherwise.
+	All bool
+	// Timeout, if non-zero, is how long to wait for the signaled processes
+	// to be gone, before sending them SIGKILL.
+	Timeout time.Duration
+}
+
+// Kill sends s to the container init process, or to all the container
+// processes with opts.All. With opts.Timeout, it waits for those processes
+// to be gone, escalating to SIGKILL once the timeout expires, and returns an
+// error listing the PIDs of the processes which survived SIGKILL.
+func (c *Container) Kill(s os.Signal, opts KillOpts) error {
+	if err := c.kill(s, opts.All); err != nil {
+		return err
+	}
+	if opts.Timeout <= 0 {
+		return nil
+	}
+	if s != unix.SIGKILL {
+		if c.waitKilled(opts.All, opts.Timeout) == nil {
+			return nil
+		}
+		if err := c.kill(unix.SIGKILL, opts.All); err != nil {
+			return err
+		}
+	}
+	if pids := c.waitKilled(opts.All, killGracePeriod); pids != nil {
+		return fmt.Errorf("processes survived SIGKILL: %v", pids)
+	}
+	return nil
+}
+
+func (c *Container) kill(s os.Signal, all bool) error {
+	if !all {
+		return c.Signal(s)
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.signalAll(s)
+}
+
+// waitKilled waits up to timeout for the processes signaled by
+// [Container.Kill] to be gone, and returns the PIDs of those left.
+func (c *Container) waitKilled(all bool, timeout time.Duration) []int {
+	deadline := time.Now().Add(timeout)
+	for {
+		pids := c.killedPids(all)
+		if pids == nil || time.Now().After(deadline) {
+			return pids
+		}
+		time.Sleep(killPollInterval)
+	}
+}
+
+// killedPids returns the PIDs of the processes signaled by [Container.Kill]
+// which still exist: the init process, and with all, those of the container
+// cgroup.
+func (c *Container) killedPids(all bool) []int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	var pids []int
+	if all && c.cgroupManager.Exists() {
+		pids, _ = c.cgroupManager.GetAllPids()
+	}
+	if c.hasInit() {
+		if pid := c.initProcess.pid(); !slices.Contains(pids, pid) {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) == 0 {
+		return nil
+	}
+	return pids
+}
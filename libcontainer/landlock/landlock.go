@@ -0,0 +1,123 @@
+// Package landlock implements Landlock LSM support for containers.
+package landlock
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Access rights, as defined in include/uapi/linux/landlock.h.
+const (
+	accessFSExecute    = 1 << 0
+	accessFSWriteFile  = 1 << 1
+	accessFSReadFile   = 1 << 2
+	accessFSReadDir    = 1 << 3
+	accessFSRemoveDir  = 1 << 4
+	accessFSRemoveFile = 1 << 5
+	accessFSMakeChar   = 1 << 6
+	accessFSMakeDir    = 1 << 7
+	accessFSMakeReg    = 1 << 8
+	accessFSMakeSock   = 1 << 9
+	accessFSMakeFifo   = 1 << 10
+	accessFSMakeBlock  = 1 << 11
+	accessFSMakeSym    = 1 << 12
+	accessFSRefer      = 1 << 13
+	accessFSTruncate   = 1 << 14
+	accessFSIoctlDev   = 1 << 15
+
+	accessNetBindTCP    = 1 << 0
+	accessNetConnectTCP = 1 << 1
+
+	// Access rights which can be used for a file rather than a directory.
+	accessFSFile = accessFSExecute | accessFSWriteFile | accessFSReadFile | accessFSTruncate | accessFSIoctlDev
+)
+
+type right struct {
+	name string
+	bit  uint64
+	// abi is the first Landlock ABI version supporting the right.
+	abi int
+}
+
+var fsRights = []right{
+	{"execute", accessFSExecute, 1},
+	{"write_file", accessFSWriteFile, 1},
+	{"read_file", accessFSReadFile, 1},
+	{"read_dir", accessFSReadDir, 1},
+	{"remove_dir", accessFSRemoveDir, 1},
+	{"remove_file", accessFSRemoveFile, 1},
+	{"make_char", accessFSMakeChar, 1},
+	{"make_dir", accessFSMakeDir, 1},
+	{"make_reg", accessFSMakeReg, 1},
+	{"make_sock", accessFSMakeSock, 1},
+	{"make_fifo", accessFSMakeFifo, 1},
+	{"make_block", accessFSMakeBlock, 1},
+	{"make_sym", accessFSMakeSym, 1},
+	{"refer", accessFSRefer, 2},
+	{"truncate", accessFSTruncate, 3},
+	{"ioctl_dev", accessFSIoctlDev, 5},
+}
+
+var netRights = []right{
+	{"bind_tcp", accessNetBindTCP, 4},
+	{"connect_tcp", accessNetConnectTCP, 4},
+}
+
+func names(rights []right) []string {
+	ret := make([]string, len(rights))
+	for i, r := range rights {
+		ret[i] = r.name
+	}
+	return ret
+}
+
+// KnownAccessFS returns the list of known filesystem access rights.
+func KnownAccessFS() []string {
+	return names(fsRights)
+}
+
+// KnownAccessNetwork returns the list of known network access rights.
+func KnownAccessNetwork() []string {
+	return names(netRights)
+}
+
+// ErrNotSupported is returned when Landlock is not supported by the kernel,
+// or not enabled.
+var ErrNotSupported = errors.New("landlock is not supported")
+
+// parseAccess converts a list of access right names into a bitmask. Rights
+// not supported by the given ABI version are either dropped (if bestEffort
+// is set), or result in an error.
+func parseAccess(rights []right, list []string, abi int, bestEffort bool) (uint64, error) {
+	var mask uint64
+	for _, name := range list {
+		i := slices.IndexFunc(rights, func(r right) bool { return r.name == name })
+		if i == -1 {
+			return 0, fmt.Errorf("unknown landlock access right %q", name)
+		}
+		if rights[i].abi > abi {
+			if bestEffort {
+				continue
+			}
+			return 0, fmt.Errorf("landlock access right %q requires landlock ABI version %d (kernel supports %d)", name, rights[i].abi, abi)
+		}
+		mask |= rights[i].bit
+	}
+	return mask, nil
+}
+
+// ParseAccessFS converts a list of filesystem access right names into
+// a bitmask.
+func ParseAccessFS(list []string) (uint64, error) {
+	return parseAccess(fsRights, list, maxABI, false)
+}
+
+// ParseAccessNetwork converts a list of network access right names into
+// a bitmask.
+func ParseAccessNetwork(list []string) (uint64, error) {
+	return parseAccess(netRights, list, maxABI, false)
+}
+
+// maxABI is the highest Landlock ABI version known to us.
+const maxABI = 6
@@ -0,0 +1,189 @@
+package landlock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// landlockRuleNetPort is LANDLOCK_RULE_NET_PORT.
+const landlockRuleNetPort = 2
+
+// netPortAttr is struct landlock_net_port_attr.
+type netPortAttr struct {
+	allowedAccess uint64
+	port          uint64
+}
+
+// ABIVersion returns the Landlock ABI version supported by the kernel.
+// If Landlock is not supported or not enabled, [ErrNotSupported] is
+// returned.
+func ABIVersion() (int, error) {
+	v, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		if errno == unix.ENOSYS || errno == unix.EOPNOTSUPP {
+			return 0, ErrNotSupported
+		}
+		return 0, os.NewSyscallError("landlock_create_ruleset", errno)
+	}
+	return int(v), nil
+}
+
+// Ruleset is a Landlock ruleset ready to be enforced.
+type Ruleset struct {
+	fd        int
+	handledFS uint64
+}
+
+// NewRuleset creates a Landlock ruleset according to config. The paths are
+// resolved in the current mount namespace, so this should be called after
+// the container root filesystem is set up.
+//
+// A nil Ruleset is returned (which is fine to use) if there is nothing to
+// enforce, which includes the case of Landlock not being supported with
+// config.DisableBestEffort unset.
+func NewRuleset(config *configs.Landlock) (_ *Ruleset, retErr error) {
+	if config == nil {
+		return nil, nil
+	}
+	bestEffort := !config.DisableBestEffort
+	abi, err := ABIVersion()
+	if err != nil {
+		if bestEffort && errors.Is(err, ErrNotSupported) {
+			logrus.Warn("landlock is not supported by the kernel, ignoring landlock configuration")
+			return nil, nil
+		}
+		return nil, err
+	}
+	abi = min(abi, maxABI)
+
+	handledFS, err := parseAccess(fsRights, config.HandledAccessFS, abi, bestEffort)
+	if err != nil {
+		return nil, err
+	}
+	handledNet, err := parseAccess(netRights, config.HandledAccessNetwork, abi, bestEffort)
+	if err != nil {
+		return nil, err
+	}
+	if handledFS == 0 && handledNet == 0 {
+		return nil, nil
+	}
+	attr := unix.LandlockRulesetAttr{Access_fs: handledFS, Access_net: handledNet}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr), 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("landlock_create_ruleset", errno)
+	}
+	r := &Ruleset{fd: int(fd), handledFS: handledFS}
+	defer func() {
+		if retErr != nil {
+			r.Close()
+		}
+	}()
+
+	for _, rule := range config.PathBeneath {
+		access, err := parseAccess(fsRights, rule.AllowedAccess, abi, bestEffort)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range rule.Paths {
+			if err := r.addPath(path, access&handledFS); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, rule := range config.NetPort {
+		access, err := parseAccess(netRights, rule.AllowedAccess, abi, bestEffort)
+		if err != nil {
+			return nil, err
+		}
+		if access &= handledNet; access == 0 {
+			continue
+		}
+		for _, port := range rule.Ports {
+			attr := netPortAttr{allowedAccess: access, port: port}
+			if err := r.addRule(landlockRuleNetPort, unsafe.Pointer(&attr)); err != nil {
+				return nil, fmt.Errorf("unable to add rule for port %d: %w", port, err)
+			}
+		}
+	}
+	return r, nil
+}
+
+func (r *Ruleset) addPath(path string, access uint64) error {
+	fd, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	defer unix.Close(fd)
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "fstat", Path: path, Err: err}
+	}
+	// Directory-only access rights can't be used for other files.
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		access &= accessFSFile
+	}
+	if access == 0 {
+		return nil
+	}
+	attr := unix.LandlockPathBeneathAttr{Allowed_access: access, Parent_fd: int32(fd)}
+	if err := r.addRule(unix.LANDLOCK_RULE_PATH_BENEATH, unsafe.Pointer(&attr)); err != nil {
+		return fmt.Errorf("unable to add rule for %s: %w", path, err)
+	}
+	return nil
+}
+
+// AllowWrite allows the file f (which may be an O_PATH file) to be opened
+// for writing, if the ruleset handles this access right.
+func (r *Ruleset) AllowWrite(f *os.File) error {
+	if r == nil || r.handledFS&accessFSWriteFile == 0 {
+		return nil
+	}
+	attr := unix.LandlockPathBeneathAttr{Allowed_access: accessFSWriteFile, Parent_fd: int32(f.Fd())}
+	if err := r.addRule(unix.LANDLOCK_RULE_PATH_BENEATH, unsafe.Pointer(&attr)); err != nil {
+		return fmt.Errorf("unable to add rule for %s: %w", f.Name(), err)
+	}
+	return nil
+}
+
+func (r *Ruleset) addRule(ruleType int, attr unsafe.Pointer) error {
+	_, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(r.fd), uintptr(ruleType), uintptr(attr), 0, 0, 0)
+	if errno != 0 {
+		return os.NewSyscallError("landlock_add_rule", errno)
+	}
+	return nil
+}
+
+// RestrictSelf enforces the ruleset on the calling thread (and its future
+// children), and closes the ruleset. This requires either no_new_privs to be
+// set, or CAP_SYS_ADMIN.
+func (r *Ruleset) RestrictSelf() error {
+	if r == nil {
+		return nil
+	}
+	defer r.Close()
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(r.fd), 0, 0); errno != 0 {
+		err := os.NewSyscallError("landlock_restrict_self", errno)
+		if errno == unix.EPERM {
+			return fmt.Errorf("%w (landlock requires noNewPrivileges to be set)", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close releases the ruleset.
+func (r *Ruleset) Close() error {
+	if r == nil || r.fd == -1 {
+		return nil
+	}
+	err := unix.Close(r.fd)
+	r.fd = -1
+	return err
+}
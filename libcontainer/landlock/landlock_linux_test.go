@@ -0,0 +1,72 @@
+package landlock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestRestrictSelf(t *testing.T) {
+	if _, err := ABIVersion(); err != nil {
+		t.Skipf("landlock not available: %v", err)
+	}
+	dir := t.TempDir()
+	allowed := filepath.Join(dir, "allowed")
+	denied := filepath.Join(dir, "denied")
+	for _, d := range []string{allowed, denied} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(d, "file"), []byte("data"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := &configs.Landlock{
+		HandledAccessFS: []string{"read_file", "write_file", "ioctl_dev"},
+		PathBeneath: []configs.LandlockPathBeneath{
+			// ioctl_dev is ignored if not supported, and read_dir is
+			// dropped for a regular file.
+			{AllowedAccess: []string{"read_file", "ioctl_dev"}, Paths: []string{allowed}},
+			{AllowedAccess: []string{"read_file", "read_dir"}, Paths: []string{filepath.Join(denied, "file")}},
+		},
+	}
+
+	// Landlock restricts the calling thread, so do it in a locked thread
+	// which is never unlocked (and is thus terminated with the goroutine).
+	errCh := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		errCh <- func() error {
+			if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+				return err
+			}
+			r, err := NewRuleset(config)
+			if err != nil {
+				return err
+			}
+			if err := r.RestrictSelf(); err != nil {
+				return err
+			}
+			if _, err := os.ReadFile(filepath.Join(allowed, "file")); err != nil {
+				return err
+			}
+			if _, err := os.ReadFile(filepath.Join(denied, "file")); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(allowed, "file"), nil, 0o644); !errors.Is(err, unix.EACCES) {
+				return fmt.Errorf("expected EACCES writing to allowed/file, got %v", err)
+			}
+			return nil
+		}()
+	}()
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
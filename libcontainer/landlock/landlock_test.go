@@ -0,0 +1,38 @@
+package landlock
+
+import "testing"
+
+func TestParseAccess(t *testing.T) {
+	testCases := []struct {
+		list       []string
+		abi        int
+		bestEffort bool
+		mask       uint64
+		isErr      bool
+	}{
+		{list: nil, abi: 1, mask: 0},
+		{list: []string{"execute", "read_file"}, abi: 1, mask: accessFSExecute | accessFSReadFile},
+		{list: []string{"read_dir", "refer"}, abi: 2, mask: accessFSReadDir | accessFSRefer},
+		{list: []string{"read_dir", "refer"}, abi: 1, isErr: true},
+		{list: []string{"read_dir", "refer"}, abi: 1, bestEffort: true, mask: accessFSReadDir},
+		{list: []string{"ioctl_dev"}, abi: 4, bestEffort: true, mask: 0},
+		{list: []string{"bind_tcp"}, abi: 6, bestEffort: true, isErr: true},
+		{list: []string{"READ_FILE"}, abi: 6, isErr: true},
+	}
+	for _, tc := range testCases {
+		mask, err := parseAccess(fsRights, tc.list, tc.abi, tc.bestEffort)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%v (abi %d): expected error, got nil", tc.list, tc.abi)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v (abi %d): unexpected error: %v", tc.list, tc.abi, err)
+			continue
+		}
+		if mask != tc.mask {
+			t.Errorf("%v (abi %d): expected %#x, got %#x", tc.list, tc.abi, tc.mask, mask)
+		}
+	}
+}
@@ -51,6 +51,41 @@ func processEntry(text []byte, logger *logrus.Logger) {
 		logrus.Errorf("failed to decode %q to json: %v", text, err)
 		return
 	}
+	// Keep the other fields of the entry (such as the correlation ID),
+	// except for its time, which is set anew.
+	var fields logrus.Fields
+	if err := json.Unmarshal(text, &fields); err != nil {
+		logrus.Errorf("failed to decode %q to json: %v", text, err)
+		return
+	}
+	delete(fields, logrus.FieldKeyLevel)
+	delete(fields, logrus.FieldKeyMsg)
+	delete(fields, logrus.FieldKeyTime)
+
+	logger.WithFields(fields).Log(jl.Level, jl.Msg)
+}
+
+// CorrelationIDKey is the field of the log entries which holds the ID
+// correlating them with the operation which triggered them, such as the
+// API call of a container engine.
+const CorrelationIDKey = "correlation_id"
 
-	logger.Log(jl.Level, jl.Msg)
+// FieldsHook is a logrus hook which adds Fields to all the log entries,
+// unless they are set already (such as in the entries forwarded from
+// runc init by ForwardLogs).
+type FieldsHook struct {
+	Fields logrus.Fields
+}
+
+func (h *FieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *FieldsHook) Fire(entry *logrus.Entry) error {
+	for k, v := range h.Fields {
+		if _, ok := entry.Data[k]; !ok {
+			entry.Data[k] = v
+		}
+	}
+	return nil
 }
@@ -67,6 +67,16 @@ func TestLogForwardingStopsAfterClosingTheWriter(t *testing.T) {
 	check(t, l, msg, msgErr)
 }
 
+func TestLogForwardingKeepsFields(t *testing.T) {
+	l := runLogForwarding(t)
+	logrus.AddHook(&FieldsHook{Fields: logrus.Fields{"container": "ct", CorrelationIDKey: "parent"}})
+	t.Cleanup(func() { logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks)) })
+
+	logToLogWriter(t, l, `"correlation_id":"child","level":"info","msg":"kitten","stage":"init","time":"now"`)
+	finish(t, l)
+	check(t, l, `{"container":"ct","correlation_id":"child","level":"info","msg":"kitten","stage":"init","time":`, `"time":"now"`)
+}
+
 func logToLogWriter(t *testing.T, l *log, message string) {
 	t.Helper()
 	_, err := l.w.Write([]byte("{" + message + "}\n"))
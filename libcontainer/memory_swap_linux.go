@@ -0,0 +1,93 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fscommon"
+)
+
+// SwapStats are the cgroup v2 swap and zswap stats of the container which
+// the cgroup stats lack. A limit of math.MaxUint64 means no limit.
+type SwapStats struct {
+	// High is the swap usage throttle limit (memory.swap.high).
+	High uint64
+	// HighEvents, MaxEvents and FailEvents are the counters of
+	// memory.swap.events: the times the swap usage went over the high
+	// limit, or was about to go over the max limit, and the swap
+	// allocation failures.
+	HighEvents uint64
+	MaxEvents  uint64
+	FailEvents uint64
+	// ZswapUsage is the memory used by the zswap compressed pool
+	// (memory.zswap.current), and ZswapLimit its limit (memory.zswap.max).
+	ZswapUsage uint64
+	ZswapLimit uint64
+	// ZswapWriteback is whether the zswap pool pages are written back to
+	// swap (memory.zswap.writeback).
+	ZswapWriteback bool
+}
+
+// swapStats returns the swap and zswap stats of the container, or nil if
+// they are unavailable, such as on cgroup v1. The files missing because of
+// an older kernel are skipped.
+func (c *Container) swapStats() (*SwapStats, error) {
+	if !cgroups.IsCgroup2UnifiedMode() || !c.cgroupManager.Exists() {
+		return nil, nil
+	}
+	return readSwapStats(c.cgroupManager.Path(""))
+}
+
+// readSwapStats reads the swap and zswap stats of the cgroup v2 directory
+// dir.
+func readSwapStats(dir string) (*SwapStats, error) {
+	_, err := os.Stat(filepath.Join(dir, "memory.swap.current"))
+	if errors.Is(err, os.ErrNotExist) {
+		// No memory controller, or no swap accounting.
+		return nil, nil
+	}
+	s := &SwapStats{ZswapWriteback: true}
+	for _, f := range []struct {
+		file string
+		dest *uint64
+	}{
+		{"memory.swap.high", &s.High},
+		{"memory.zswap.current", &s.ZswapUsage},
+		{"memory.zswap.max", &s.ZswapLimit},
+	} {
+		if *f.dest, err = fscommon.GetCgroupParamUint(dir, f.file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	wb, err := fscommon.GetCgroupParamUint(dir, "memory.zswap.writeback")
+	if err == nil {
+		s.ZswapWriteback = wb != 0
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	data, err := cgroups.ReadFile(dir, "memory.swap.events")
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return s, nil
+		}
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		key, val, err := fscommon.ParseKeyValue(line)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "high":
+			s.HighEvents = val
+		case "max":
+			s.MaxEvents = val
+		case "fail":
+			s.FailEvents = val
+		}
+	}
+	return s, nil
+}
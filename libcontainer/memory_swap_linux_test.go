@@ -0,0 +1,53 @@
+package libcontainer
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+)
+
+func TestReadSwapStats(t *testing.T) {
+	cgroups.TestMode = true
+	defer func() { cgroups.TestMode = false }()
+	dir := t.TempDir()
+	write := func(file, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No swap accounting.
+	if s, err := readSwapStats(dir); err != nil || s != nil {
+		t.Fatalf("expected no stats, got %+v, %v", s, err)
+	}
+
+	// A kernel with no zswap files.
+	write("memory.swap.current", "0\n")
+	write("memory.swap.high", "max\n")
+	write("memory.swap.events", "high 0\nmax 2\nfail 1\n")
+	s, err := readSwapStats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := SwapStats{High: math.MaxUint64, MaxEvents: 2, FailEvents: 1, ZswapWriteback: true}
+	if *s != expected {
+		t.Errorf("expected %+v, got %+v", expected, *s)
+	}
+
+	write("memory.swap.high", "1048576\n")
+	write("memory.zswap.current", "4096\n")
+	write("memory.zswap.max", "65536\n")
+	write("memory.zswap.writeback", "0\n")
+	s, err = readSwapStats(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = SwapStats{High: 1048576, MaxEvents: 2, FailEvents: 1, ZswapUsage: 4096, ZswapLimit: 65536}
+	if *s != expected {
+		t.Errorf("expected %+v, got %+v", expected, *s)
+	}
+}
@@ -0,0 +1,237 @@
+package libcontainer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
+)
+
+// migrateMems moves the memory of the container off the NUMA nodes which
+// config removes from the cpuset mems of old, before those are set, as per
+// config.MemsMigration. Cutting the mems abruptly leaves the kernel to move
+// the memory while the container keeps allocating, which it may fail to.
+func (c *Container) migrateMems(old, config *configs.Config) error {
+	policy := config.MemsMigration
+	if policy == "" || policy == configs.MemsMigrationNone {
+		return nil
+	}
+	from, to, err := removedMems(resourcesOf(old).CpusetMems, resourcesOf(config).CpusetMems)
+	if err != nil || len(from) == 0 {
+		return err
+	}
+
+	dir := c.cgroupManager.Path("memory")
+	usage, err := nodeUsage(dir, from)
+	if err != nil {
+		return err
+	}
+	free, err := nodesFree(to)
+	if err != nil {
+		return err
+	}
+	if usage > free && policy == configs.MemsMigrationReclaim {
+		// memory.reclaim can't be told which nodes to reclaim from, so
+		// that the usage on the removed nodes is read again.
+		err := cgroups.WriteFile(dir, "memory.reclaim", strconv.FormatUint(usage-free, 10))
+		// EAGAIN is returned if less than requested has been reclaimed.
+		if err != nil && !errors.Is(err, unix.EAGAIN) {
+			return fmt.Errorf("unable to reclaim memory: %w", err)
+		}
+		if usage, err = nodeUsage(dir, from); err != nil {
+			return err
+		}
+	}
+	if usage > free {
+		return fmt.Errorf("unable to migrate %d bytes of memory off NUMA nodes %s: only %d bytes are free on nodes %s",
+			usage, cpuset.Format(from), free, cpuset.Format(to))
+	}
+
+	pids, err := c.cgroupManager.GetAllPids()
+	if err != nil {
+		return err
+	}
+	oldMask, newMask := nodeMask(from), nodeMask(to)
+	for _, pid := range pids {
+		left, err := migratePages(pid, oldMask, newMask)
+		if err != nil {
+			// The process may have exited meanwhile.
+			if errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			return fmt.Errorf("unable to migrate memory of pid %d: %w", pid, err)
+		}
+		if left > 0 {
+			logrus.Warnf("%d pages of pid %d could not be migrated off NUMA nodes %s", left, pid, cpuset.Format(from))
+		}
+	}
+	return nil
+}
+
+// removedMems returns the NUMA nodes of the cpuset mems old which are not in
+// the cpuset mems new, and the nodes of new. An empty list is all the NUMA
+// nodes with memory.
+func removedMems(old, new string) (from, to []int, _ error) {
+	if old == new || new == "" {
+		return nil, nil, nil
+	}
+	to, err := cpuset.Parse(strings.TrimSpace(new))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid cpuset mems %q: %w", new, err)
+	}
+	var oldNodes []int
+	if old == "" {
+		topo, err := cpuset.HostTopology()
+		if err != nil {
+			return nil, nil, err
+		}
+		oldNodes = topo.MemoryNodes
+	} else if oldNodes, err = cpuset.Parse(strings.TrimSpace(old)); err != nil {
+		return nil, nil, fmt.Errorf("invalid cpuset mems %q: %w", old, err)
+	}
+	for _, node := range oldNodes {
+		if !slices.Contains(to, node) {
+			from = append(from, node)
+		}
+	}
+	return from, to, nil
+}
+
+// nodeUsage returns how much memory (in bytes) of the cgroup dir is on the
+// NUMA nodes, according to its memory.numa_stat.
+func nodeUsage(dir string, nodes []int) (uint64, error) {
+	if dir == "" {
+		return 0, errors.New("unable to get memory usage per NUMA node: no memory cgroup")
+	}
+	data, err := cgroups.ReadFile(dir, "memory.numa_stat")
+	if err != nil {
+		return 0, err
+	}
+	usage := parseNumaStat(data, cgroups.IsCgroup2UnifiedMode(), uint64(os.Getpagesize()))
+	var sum uint64
+	for _, node := range nodes {
+		sum += usage[node]
+	}
+	return sum, nil
+}
+
+// parseNumaStat parses a memory.numa_stat file, returning the memory usage
+// (in bytes) per NUMA node. On cgroup v2, it is the sum of the anonymous
+// and the file memory, in bytes; on cgroup v1, it is the hierarchical total,
+// in pages.
+func parseNumaStat(data string, v2 bool, pageSize uint64) map[int]uint64 {
+	usage := make(map[int]uint64)
+	s := bufio.NewScanner(strings.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		scale := uint64(1)
+		if v2 {
+			if fields[0] != "anon" && fields[0] != "file" {
+				continue
+			}
+		} else {
+			if !strings.HasPrefix(fields[0], "hierarchical_total=") {
+				continue
+			}
+			scale = pageSize
+		}
+		for _, f := range fields[1:] {
+			k, v, ok := strings.Cut(f, "=")
+			if !ok || !strings.HasPrefix(k, "N") {
+				continue
+			}
+			node, err := strconv.Atoi(k[1:])
+			if err != nil {
+				continue
+			}
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			usage[node] += n * scale
+		}
+	}
+	return usage
+}
+
+// nodesFree returns the free memory (in bytes) of the NUMA nodes.
+func nodesFree(nodes []int) (uint64, error) {
+	var sum uint64
+	for _, node := range nodes {
+		path := filepath.Join("/sys/devices/system/node", "node"+strconv.Itoa(node), "meminfo")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		free, err := parseNodeMemFree(string(data))
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", path, err)
+		}
+		sum += free
+	}
+	return sum, nil
+}
+
+// parseNodeMemFree returns the free memory (in bytes) from the meminfo file
+// of a NUMA node, which lines are like "Node 0 MemFree: 1024 kB".
+func parseNodeMemFree(data string) (uint64, error) {
+	s := bufio.NewScanner(strings.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 || fields[2] != "MemFree:" {
+			continue
+		}
+		free, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if len(fields) > 4 && fields[4] == "kB" {
+			free *= 1024
+		}
+		return free, nil
+	}
+	return 0, errors.New("no MemFree")
+}
+
+// nodeMask returns the nodes as a bitmask, as migrate_pages(2) takes it.
+func nodeMask(nodes []int) []uint64 {
+	// The kernel ignores the last bit of maxnode, so that one more is
+	// needed than the highest node.
+	mask := make([]uint64, (slices.Max(nodes)+1)/64+1)
+	for _, node := range nodes {
+		mask[node/64] |= 1 << (node % 64)
+	}
+	return mask
+}
+
+// migratePages moves the pages of the process pid on the nodes of the mask
+// from to the nodes of the mask to, returning the number of the pages which
+// could not be moved.
+func migratePages(pid int, from, to []uint64) (int, error) {
+	if len(from) < len(to) {
+		from = append(from, make([]uint64, len(to)-len(from))...)
+	} else if len(to) < len(from) {
+		to = append(to, make([]uint64, len(from)-len(to))...)
+	}
+	left, _, errno := unix.Syscall6(unix.SYS_MIGRATE_PAGES, uintptr(pid), uintptr(len(from)*64),
+		uintptr(unsafe.Pointer(&from[0])), uintptr(unsafe.Pointer(&to[0])), 0, 0)
+	if errno != 0 {
+		return 0, os.NewSyscallError("migrate_pages", errno)
+	}
+	return int(left), nil
+}
@@ -0,0 +1,94 @@
+package libcontainer
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseNumaStat(t *testing.T) {
+	v2 := `anon N0=4096 N1=8192
+file N0=100 N1=0
+kernel_stack N0=16384 N1=0
+shmem N0=0 N1=0
+`
+	v1 := `total=10 N0=7 N1=3
+file=4 N0=4 N1=0
+hierarchical_total=12 N0=8 N1=4
+hierarchical_file=4 N0=4 N1=0
+`
+	for _, tc := range []struct {
+		name     string
+		data     string
+		v2       bool
+		expected map[int]uint64
+	}{
+		{name: "v2", data: v2, v2: true, expected: map[int]uint64{0: 4196, 1: 8192}},
+		{name: "v1", data: v1, expected: map[int]uint64{0: 8 * 4096, 1: 4 * 4096}},
+		{name: "empty", data: "", expected: map[int]uint64{}},
+	} {
+		usage := parseNumaStat(tc.data, tc.v2, 4096)
+		if !maps.Equal(usage, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, usage)
+		}
+	}
+}
+
+func TestParseNodeMemFree(t *testing.T) {
+	data := `Node 1 MemTotal:       16384 kB
+Node 1 MemFree:         2048 kB
+Node 1 MemUsed:        14336 kB
+`
+	free, err := parseNodeMemFree(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if free != 2048*1024 {
+		t.Errorf("expected %d, got %d", 2048*1024, free)
+	}
+	if _, err := parseNodeMemFree("Node 1 MemTotal: 16384 kB\n"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestNodeMask(t *testing.T) {
+	for _, tc := range []struct {
+		nodes    []int
+		expected []uint64
+	}{
+		{nodes: []int{0}, expected: []uint64{1}},
+		{nodes: []int{1, 3}, expected: []uint64{0b1010}},
+		// Bit 63 of the first word can't be used.
+		{nodes: []int{63}, expected: []uint64{1 << 63, 0}},
+		{nodes: []int{0, 64}, expected: []uint64{1, 1}},
+	} {
+		if mask := nodeMask(tc.nodes); !slices.Equal(mask, tc.expected) {
+			t.Errorf("%v: expected %b, got %b", tc.nodes, tc.expected, mask)
+		}
+	}
+}
+
+func TestRemovedMems(t *testing.T) {
+	for _, tc := range []struct {
+		old, new string
+		from, to []int
+	}{
+		{old: "0-1", new: "0-1"},
+		{old: "0-1", new: ""},
+		{old: "0", new: "0-1"},
+		{old: "0-3", new: "1,3", from: []int{0, 2}, to: []int{1, 3}},
+		{old: "0,1", new: "1", from: []int{0}, to: []int{1}},
+	} {
+		from, to, err := removedMems(tc.old, tc.new)
+		if err != nil {
+			t.Errorf("%q -> %q: unexpected error: %v", tc.old, tc.new, err)
+			continue
+		}
+		if !slices.Equal(from, tc.from) || (len(from) > 0 && !slices.Equal(to, tc.to)) {
+			t.Errorf("%q -> %q: expected %v %v, got %v %v", tc.old, tc.new, tc.from, tc.to, from, to)
+		}
+	}
+	if _, _, err := removedMems("0-1", "x"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -11,17 +11,18 @@ import (
 // list of known message types we want to send to bootstrap program
 // The number is randomly chosen to not conflict with known netlink types
 const (
-	InitMsg          uint16 = 62000
-	CloneFlagsAttr   uint16 = 27281
-	NsPathsAttr      uint16 = 27282
-	UidmapAttr       uint16 = 27283
-	GidmapAttr       uint16 = 27284
-	SetgroupAttr     uint16 = 27285
-	OomScoreAdjAttr  uint16 = 27286
-	RootlessEUIDAttr uint16 = 27287
-	UidmapPathAttr   uint16 = 27288
-	GidmapPathAttr   uint16 = 27289
-	TimeOffsetsAttr  uint16 = 27290
+	InitMsg           uint16 = 62000
+	CloneFlagsAttr    uint16 = 27281
+	NsPathsAttr       uint16 = 27282
+	UidmapAttr        uint16 = 27283
+	GidmapAttr        uint16 = 27284
+	SetgroupAttr      uint16 = 27285
+	OomScoreAdjAttr   uint16 = 27286
+	RootlessEUIDAttr  uint16 = 27287
+	UidmapPathAttr    uint16 = 27288
+	GidmapPathAttr    uint16 = 27289
+	TimeOffsetsAttr   uint16 = 27290
+	IDMapDelegateAttr uint16 = 27291
 )
 
 type Int32msg struct {
@@ -0,0 +1,56 @@
+package libcontainer
+
+import (
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// applyMountPolicy returns m with the mount flags enforced by policy added,
+// or m itself if the policy does not apply to it. The policy only applies to
+// bind mounts which destinations are not in the policy allow list. For
+// recursive bind mounts, the flags are also enforced on the submounts, which
+// requires mount_setattr(2) (Linux 5.12+).
+func applyMountPolicy(policy *configs.MountPolicy, m *configs.Mount) *configs.Mount {
+	if policy == nil || !m.IsBind() {
+		return m
+	}
+	dst := filepath.Clean(m.Destination)
+	if slices.ContainsFunc(policy.Allow, func(a string) bool {
+		return filepath.Clean(a) == dst
+	}) {
+		return m
+	}
+	var flags int
+	var attrs uint64
+	if policy.NoSuid {
+		flags |= unix.MS_NOSUID
+		attrs |= unix.MOUNT_ATTR_NOSUID
+	}
+	if policy.NoDev {
+		flags |= unix.MS_NODEV
+		attrs |= unix.MOUNT_ATTR_NODEV
+	}
+	if policy.NoExec {
+		flags |= unix.MS_NOEXEC
+		attrs |= unix.MOUNT_ATTR_NOEXEC
+	}
+
+	// Do not modify the configuration.
+	mm := *m
+	mm.Flags |= flags
+	mm.ClearedFlags &^= flags
+	if mm.Flags&unix.MS_REC != 0 {
+		var attr unix.MountAttr
+		if m.RecAttr != nil {
+			attr = *m.RecAttr
+		}
+		attr.Attr_set |= attrs
+		attr.Attr_clr &^= attrs
+		mm.RecAttr = &attr
+	}
+	return &mm
+}
@@ -0,0 +1,87 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestApplyMountPolicy(t *testing.T) {
+	policy := &configs.MountPolicy{NoSuid: true, NoDev: true, Allow: []string{"/allowed"}}
+	testCases := []struct {
+		name    string
+		policy  *configs.MountPolicy
+		mount   configs.Mount
+		flags   int
+		cleared int
+		recAttr *unix.MountAttr
+	}{
+		{
+			name:   "no policy",
+			mount:  configs.Mount{Device: "bind", Destination: "/dst", Flags: unix.MS_BIND},
+			flags:  unix.MS_BIND,
+			policy: nil,
+		},
+		{
+			name:   "bind",
+			policy: policy,
+			mount:  configs.Mount{Device: "bind", Destination: "/dst", Flags: unix.MS_BIND | unix.MS_RDONLY},
+			flags:  unix.MS_BIND | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV,
+		},
+		{
+			name:    "cleared flags",
+			policy:  policy,
+			mount:   configs.Mount{Device: "bind", Destination: "/dst", Flags: unix.MS_BIND, ClearedFlags: unix.MS_NOSUID | unix.MS_NOEXEC},
+			flags:   unix.MS_BIND | unix.MS_NOSUID | unix.MS_NODEV,
+			cleared: unix.MS_NOEXEC,
+		},
+		{
+			name:    "rbind",
+			policy:  policy,
+			mount:   configs.Mount{Device: "bind", Destination: "/dst", Flags: unix.MS_BIND | unix.MS_REC},
+			flags:   unix.MS_BIND | unix.MS_REC | unix.MS_NOSUID | unix.MS_NODEV,
+			recAttr: &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_NOSUID | unix.MOUNT_ATTR_NODEV},
+		},
+		{
+			name:   "rbind with rro",
+			policy: policy,
+			mount: configs.Mount{
+				Device: "bind", Destination: "/dst", Flags: unix.MS_BIND | unix.MS_REC,
+				RecAttr: &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY, Attr_clr: unix.MOUNT_ATTR_NODEV},
+			},
+			flags:   unix.MS_BIND | unix.MS_REC | unix.MS_NOSUID | unix.MS_NODEV,
+			recAttr: &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY | unix.MOUNT_ATTR_NOSUID | unix.MOUNT_ATTR_NODEV},
+		},
+		{
+			name:   "allowed",
+			policy: policy,
+			mount:  configs.Mount{Device: "bind", Destination: "/allowed/", Flags: unix.MS_BIND},
+			flags:  unix.MS_BIND,
+		},
+		{
+			name:   "not a bind mount",
+			policy: policy,
+			mount:  configs.Mount{Device: "tmpfs", Destination: "/dst"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orig := tc.mount
+			m := applyMountPolicy(tc.policy, &tc.mount)
+			if m.Flags != tc.flags {
+				t.Errorf("expected flags %#x, got %#x", tc.flags, m.Flags)
+			}
+			if m.ClearedFlags != tc.cleared {
+				t.Errorf("expected cleared flags %#x, got %#x", tc.cleared, m.ClearedFlags)
+			}
+			if tc.recAttr != nil && (m.RecAttr == nil || *m.RecAttr != *tc.recAttr) {
+				t.Errorf("expected rec attr %+v, got %+v", tc.recAttr, m.RecAttr)
+			}
+			if tc.mount.Flags != orig.Flags || tc.mount.ClearedFlags != orig.ClearedFlags || tc.mount.RecAttr != orig.RecAttr {
+				t.Error("the original mount was modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package mountdriver lets programs using libcontainer handle custom mount
+// types (such as "fuse.foo", "nfs", or image mounts) themselves.
+//
+// A [Driver] registered for a mount type is called by the container init
+// process to do the mounts of that type, in the container mount namespace
+// (and user namespace, if any), at the time the other mounts of the
+// container are done, rather than the mount being done on the host and then
+// bind mounted into the container.
+//
+// As the container init process is a new instance of the current binary
+// (see the "Container init" section of the libcontainer README), the
+// drivers must be registered by both the process creating the container and
+// the init process, which is best done from an init function.
+package mountdriver
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// Mount is a mount to be done by a [Driver].
+type Mount struct {
+	*configs.Mount
+	// Target is the path to mount on. It is a /proc/self/fd/N path to the
+	// mountpoint, which has been created in the container root filesystem,
+	// and must be used rather than Destination, which is relative to the
+	// container root filesystem and could be subject to symlink attacks.
+	// It is only valid until Mount returns.
+	Target string
+	// Rootfs is the path of the container root filesystem.
+	Rootfs string
+	// Label is the SELinux mount label of the container, or "".
+	Label string
+}
+
+// Driver mounts the filesystems of a mount type.
+type Driver interface {
+	// Mount mounts m on m.Target. The mount propagation flags and the
+	// recursive mount attributes of m are applied by libcontainer once
+	// Mount returns successfully.
+	Mount(m *Mount) error
+}
+
+// DriverFunc is a [Driver] implemented by a function.
+type DriverFunc func(m *Mount) error
+
+// Mount calls f(m).
+func (f DriverFunc) Mount(m *Mount) error {
+	return f(m)
+}
+
+// builtinTypes are the mount types handled by libcontainer itself, which
+// can't be overridden by a driver.
+var builtinTypes = []string{"bind", "cgroup", "mqueue", "proc", "sysfs", "tmpfs"}
+
+var (
+	mu      sync.RWMutex
+	drivers = map[string]Driver{}
+)
+
+// Register registers d as the driver for the mounts of type typ (the
+// configs.Mount Device field, or the runtime-spec mount "type"). It is meant
+// to be called from an init function.
+//
+// Register panics if typ is empty, is one of the types handled by
+// libcontainer itself ("bind", "cgroup", "mqueue", "proc", "sysfs", and
+// "tmpfs"), or is already registered.
+func Register(typ string, d Driver) {
+	if typ == "" || slices.Contains(builtinTypes, typ) {
+		panic(fmt.Sprintf("mountdriver: can't register a driver for mount type %q", typ))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := drivers[typ]; ok {
+		panic(fmt.Sprintf("mountdriver: mount type %q registered twice", typ))
+	}
+	drivers[typ] = d
+}
+
+// Get returns the driver registered for the mounts of type typ, or nil.
+func Get(typ string) Driver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return drivers[typ]
+}
+
+// Types returns the sorted list of mount types with a registered driver.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(drivers))
+	for typ := range drivers {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
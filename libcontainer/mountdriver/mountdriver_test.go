@@ -0,0 +1,44 @@
+package mountdriver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	var called bool
+	Register("fuse.test", DriverFunc(func(m *Mount) error {
+		called = true
+		return nil
+	}))
+	defer func() {
+		mu.Lock()
+		delete(drivers, "fuse.test")
+		mu.Unlock()
+	}()
+
+	d := Get("fuse.test")
+	if d == nil {
+		t.Fatal("expected a driver for fuse.test")
+	}
+	if err := d.Mount(&Mount{}); err != nil || !called {
+		t.Errorf("expected the driver function to be called, got %v", err)
+	}
+	if d := Get("nfs"); d != nil {
+		t.Errorf("expected no driver for nfs, got %v", d)
+	}
+	if types := Types(); !slices.Equal(types, []string{"fuse.test"}) {
+		t.Errorf("unexpected types %v", types)
+	}
+
+	for _, typ := range []string{"", "bind", "proc", "tmpfs", "fuse.test"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q: expected Register to panic", typ)
+				}
+			}()
+			Register(typ, DriverFunc(func(*Mount) error { return nil }))
+		}()
+	}
+}
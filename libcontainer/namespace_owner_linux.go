@@ -0,0 +1,136 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// openOwnedNamespaces opens the existing namespaces to join which have an
+// expected owner (see [configs.Namespace.Owner]), and checks their owner
+// according to config.NamespaceOwnerCheck. The paths of these namespaces in
+// nsMaps are replaced by the /proc paths of the returned files, so that runc
+// init joins the namespaces which were checked, even if the configured paths
+// are replaced meanwhile. The files must be kept open until runc init has
+// joined the namespaces.
+func (c *Container) openOwnedNamespaces(nsMaps map[configs.NamespaceType]string) (_ []*os.File, retErr error) {
+	var files []*os.File
+	defer func() {
+		if retErr != nil {
+			closeFiles(files)
+		}
+	}()
+	for _, ns := range c.config.Namespaces {
+		if ns.Path == "" || ns.Owner == nil {
+			continue
+		}
+		f, err := os.Open(ns.Path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s namespace: %w", configs.NsName(ns.Type), err)
+		}
+		files = append(files, f)
+		if err := checkNamespaceOwner(f, ns); err != nil {
+			if c.config.NamespaceOwnerCheck == configs.NamespaceOwnerCheckWarn {
+				logrus.Warn(err)
+			} else {
+				return nil, err
+			}
+		}
+		// runc init opens the namespace paths before joining any of them,
+		// so it can still access the files of this process.
+		nsMaps[ns.Type] = "/proc/" + strconv.Itoa(os.Getpid()) + "/fd/" + strconv.Itoa(int(f.Fd()))
+	}
+	return files, nil
+}
+
+// checkNamespaceOwner checks that the namespace file f is of the type of ns,
+// and owned as expected by ns.Owner.
+func checkNamespaceOwner(f *os.File, ns configs.Namespace) error {
+	name := configs.NsName(ns.Type)
+	fd := int(f.Fd())
+	nstype, err := unix.IoctlRetInt(fd, unix.NS_GET_NSTYPE)
+	if err != nil {
+		return fmt.Errorf("%s namespace %s: unable to get the namespace type: %w", name, ns.Path, err)
+	}
+	// The type of the namespaces unknown to runc can't be checked.
+	if t := ns.Syscall(); t != 0 && nstype != t {
+		return fmt.Errorf("%s namespace %s: not a %s namespace", name, ns.Path, name)
+	}
+	owner := ns.Owner
+	if owner.Inode != 0 {
+		ino, err := fileInode(f)
+		if err != nil {
+			return fmt.Errorf("%s namespace %s: %w", name, ns.Path, err)
+		}
+		if ino != owner.Inode {
+			return fmt.Errorf("%s namespace %s: inode is %d, expected %d", name, ns.Path, ino, owner.Inode)
+		}
+	}
+	if owner.UsernsInode == 0 && owner.UID == nil {
+		return nil
+	}
+
+	// The owning user namespace of a user namespace is its parent.
+	req := uint(unix.NS_GET_USERNS)
+	if ns.Type == configs.NEWUSER {
+		req = unix.NS_GET_PARENT
+	}
+	if owner.UsernsInode != 0 {
+		usernsFd, err := unix.IoctlRetInt(fd, req)
+		if err != nil {
+			return fmt.Errorf("%s namespace %s: unable to get the owning user namespace: %w", name, ns.Path, err)
+		}
+		userns := os.NewFile(uintptr(usernsFd), "userns")
+		ino, err := fileInode(userns)
+		userns.Close()
+		if err != nil {
+			return fmt.Errorf("%s namespace %s: owning user namespace: %w", name, ns.Path, err)
+		}
+		if ino != owner.UsernsInode {
+			return fmt.Errorf("%s namespace %s: owning user namespace inode is %d, expected %d", name, ns.Path, ino, owner.UsernsInode)
+		}
+	}
+	if owner.UID != nil {
+		uid, err := namespaceOwnerUID(fd, ns.Type)
+		if err != nil {
+			return fmt.Errorf("%s namespace %s: unable to get the owner UID: %w", name, ns.Path, err)
+		}
+		if uid != *owner.UID {
+			return fmt.Errorf("%s namespace %s: owner UID is %d, expected %d", name, ns.Path, uid, *owner.UID)
+		}
+	}
+	return nil
+}
+
+// namespaceOwnerUID returns the owner UID of the user namespace owning the
+// namespace fd of type t, or of the user namespace fd itself.
+func namespaceOwnerUID(fd int, t configs.NamespaceType) (uint32, error) {
+	if t != configs.NEWUSER {
+		usernsFd, err := unix.IoctlRetInt(fd, unix.NS_GET_USERNS)
+		if err != nil {
+			return 0, err
+		}
+		defer unix.Close(usernsFd)
+		fd = usernsFd
+	}
+	return unix.IoctlGetUint32(fd, unix.NS_GET_OWNER_UID)
+}
+
+func fileInode(f *os.File) (uint64, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		return 0, &os.PathError{Op: "fstat", Path: f.Name(), Err: err}
+	}
+	return st.Ino, nil
+}
+
+func closeFiles(files []*os.File) {
+	for _, f := range files {
+		_ = f.Close()
+	}
+}
@@ -0,0 +1,68 @@
+package libcontainer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestCheckNamespaceOwner(t *testing.T) {
+	inode := func(path string) uint64 {
+		t.Helper()
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		ino, err := fileInode(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ino
+	}
+	netns := inode("/proc/self/ns/net")
+	userns := inode("/proc/self/ns/user")
+
+	// The network namespace is owned by the user namespace of this process,
+	// whose owner UID is that of the user namespace itself.
+	f, err := os.Open("/proc/self/ns/user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	uid, err := namespaceOwnerUID(int(f.Fd()), configs.NEWUSER)
+	if err != nil {
+		t.Skipf("unable to get the user namespace owner: %v", err)
+	}
+	otherUID := uid + 1
+
+	for _, tc := range []struct {
+		name  string
+		isErr bool
+		typ   configs.NamespaceType
+		owner configs.NamespaceOwner
+	}{
+		{name: "inode", typ: configs.NEWNET, owner: configs.NamespaceOwner{Inode: netns}},
+		{name: "owner", typ: configs.NEWNET, owner: configs.NamespaceOwner{UID: &uid, UsernsInode: userns}},
+		{name: "wrong type", isErr: true, typ: configs.NEWIPC, owner: configs.NamespaceOwner{Inode: netns}},
+		{name: "wrong inode", isErr: true, typ: configs.NEWNET, owner: configs.NamespaceOwner{Inode: netns + 1}},
+		{name: "wrong userns", isErr: true, typ: configs.NEWNET, owner: configs.NamespaceOwner{UsernsInode: netns}},
+		{name: "wrong uid", isErr: true, typ: configs.NEWNET, owner: configs.NamespaceOwner{UID: &otherUID}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := os.Open("/proc/self/ns/net")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			err = checkNamespaceOwner(f, configs.Namespace{Type: tc.typ, Path: f.Name(), Owner: &tc.owner})
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/types"
+)
+
+// NetDeviceState is the state of a network device moved into the container
+// network namespace (see [configs.Config.NetDevices]).
+type NetDeviceState struct {
+	// HostName is the name of the device in the runtime network namespace.
+	HostName string `json:"host_name"`
+	// Name is the name of the device in the container network namespace.
+	Name string `json:"name"`
+	// HardwareAddress is the MAC address of the device in the container
+	// network namespace.
+	HardwareAddress string `json:"hardware_address,omitempty"`
+	// Physical is whether the device is a physical one, which the kernel
+	// moves back to the initial network namespace when the container
+	// network namespace is gone, rather than deleting it.
+	Physical bool `json:"physical,omitempty"`
+
+	// The attributes of the device in the runtime network namespace,
+	// restored when the device is moved back.
+	HostMTU             int    `json:"host_mtu,omitempty"`
+	HostHardwareAddress string `json:"host_hardware_address,omitempty"`
+	HostTxQueueLen      int    `json:"host_txqueuelen,omitempty"`
+	HostUp              bool   `json:"host_up,omitempty"`
+}
+
+// netDeviceReturnTimeout is how long a physical network device is waited
+// for to be moved back by the kernel, as the network namespaces are
+// destroyed asynchronously.
+const netDeviceReturnTimeout = time.Second
+
+// configureNetDevice sets the attributes of device to link, in the network
+// namespace ns handled by nh.
+func configureNetDevice(nh *netlink.Handle, ns netns.NsHandle, link netlink.Link, device configs.LinuxNetDevice) error {
+	if device.MTU > 0 {
+		if err := nh.LinkSetMTU(link, device.MTU); err != nil {
+			return fmt.Errorf("unable to set mtu: %w", err)
+		}
+	}
+	if device.HardwareAddress != "" {
+		mac, err := net.ParseMAC(device.HardwareAddress)
+		if err != nil {
+			return err
+		}
+		if err := nh.LinkSetHardwareAddr(link, mac); err != nil {
+			return fmt.Errorf("unable to set hardware address: %w", err)
+		}
+	}
+	if device.TxQueueLen > 0 {
+		if err := nh.LinkSetTxQLen(link, device.TxQueueLen); err != nil {
+			return fmt.Errorf("unable to set txqueuelen: %w", err)
+		}
+	}
+	if device.Queues > 0 {
+		sock, err := socketAt(ns)
+		if err != nil {
+			return err
+		}
+		defer unix.Close(sock)
+		if err := setCombinedQueues(sock, link.Attrs().Name, device.Queues); err != nil {
+			return fmt.Errorf("unable to set queues: %w", err)
+		}
+	}
+	return nil
+}
+
+// ethtoolChannels is struct ethtool_channels.
+type ethtoolChannels struct {
+	cmd           uint32
+	maxRx         uint32
+	maxTx         uint32
+	maxOther      uint32
+	maxCombined   uint32
+	rxCount       uint32
+	txCount       uint32
+	otherCount    uint32
+	combinedCount uint32
+}
+
+// ifreqData is struct ifreq, with its ifr_data member.
+type ifreqData struct {
+	name [unix.IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ethtoolChannelsIoctl does the ETHTOOL_GCHANNELS or ETHTOOL_SCHANNELS
+// (as set in ch) ioctl on the device name.
+func ethtoolChannelsIoctl(sock int, name string, ch *ethtoolChannels) error {
+	ifr := ifreqData{data: unsafe.Pointer(ch)}
+	copy(ifr.name[:unix.IFNAMSIZ-1], name)
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(sock), unix.SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		return os.NewSyscallError("ioctl SIOCETHTOOL", errno)
+	}
+	return nil
+}
+
+// setCombinedQueues sets the number of combined queues of the device name,
+// using the socket sock in its network namespace.
+func setCombinedQueues(sock int, name string, n uint32) error {
+	ch := ethtoolChannels{cmd: unix.ETHTOOL_GCHANNELS}
+	if err := ethtoolChannelsIoctl(sock, name, &ch); err != nil {
+		return err
+	}
+	if n > ch.maxCombined {
+		return fmt.Errorf("%d queues requested, the maximum is %d", n, ch.maxCombined)
+	}
+	ch.cmd = unix.ETHTOOL_SCHANNELS
+	ch.combinedCount = n
+	return ethtoolChannelsIoctl(sock, name, &ch)
+}
+
+// netDeviceStats returns the statistics of the network devices, in the
+// network namespace of the process pid.
+func netDeviceStats(pid int, devices []NetDeviceState) ([]*types.NetworkInterface, error) {
+	ns, err := netns.GetFromPid(pid)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+	nh, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, err
+	}
+	defer nh.Close()
+
+	var ifaces []*types.NetworkInterface
+	for _, dev := range devices {
+		link, err := nh.LinkByName(dev.Name)
+		if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
+			return nil, fmt.Errorf("network device %s: %w", dev.Name, err)
+		}
+		s := link.Attrs().Statistics
+		if s == nil {
+			continue
+		}
+		ifaces = append(ifaces, &types.NetworkInterface{
+			Name:      dev.Name,
+			RxBytes:   s.RxBytes,
+			RxPackets: s.RxPackets,
+			RxErrors:  s.RxErrors,
+			RxDropped: s.RxDropped,
+			TxBytes:   s.TxBytes,
+			TxPackets: s.TxPackets,
+			TxErrors:  s.TxErrors,
+			TxDropped: s.TxDropped,
+		})
+	}
+	return ifaces, nil
+}
+
+// restoreNetDevices restores the name and the attributes of the physical
+// network devices which the kernel has moved back from the container
+// network namespace, once it is gone (such as after the container init is
+// killed). The devices moved into a network namespace runc did not create,
+// or keeps (see [configs.Config.KeepNetns]), are left in it.
+func (c *Container) restoreNetDevices() {
+	if c.config.Namespaces.PathOf(configs.NEWNET) != "" || c.config.KeepNetns {
+		c.netDevices = nil
+		return
+	}
+	for _, dev := range c.netDevices {
+		if !dev.Physical {
+			// Deleted with the network namespace.
+			continue
+		}
+		if err := restoreNetDevice(dev); err != nil {
+			logrus.WithError(err).Warnf("unable to restore network device %s", dev.HostName)
+		}
+	}
+	c.netDevices = nil
+}
+
+// restoreNetDevice finds the device dev in the runtime network namespace,
+// by its hardware address, and restores its name and attributes.
+func restoreNetDevice(dev NetDeviceState) error {
+	if _, err := netlink.LinkByName(dev.HostName); err == nil {
+		// Already restored.
+		return nil
+	}
+	if dev.HardwareAddress == "" {
+		return errors.New("no hardware address to find the device")
+	}
+	var link netlink.Link
+	deadline := time.Now().Add(netDeviceReturnTimeout)
+	for link == nil {
+		links, err := netlink.LinkList()
+		if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
+			return err
+		}
+		for _, l := range links {
+			if l.Type() == "device" && l.Attrs().HardwareAddr.String() == dev.HardwareAddress {
+				link = l
+				break
+			}
+		}
+		if link == nil {
+			if time.Now().After(deadline) {
+				return errors.New("device not found (the container network namespace may still be in use)")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	logrus.Debugf("restoring network device %s (now %s)", dev.HostName, link.Attrs().Name)
+	if err := netlink.LinkSetDown(link); err != nil {
+		return err
+	}
+	if err := netlink.LinkSetName(link, dev.HostName); err != nil {
+		return err
+	}
+	attrs := link.Attrs()
+	if dev.HostMTU > 0 && attrs.MTU != dev.HostMTU {
+		if err := netlink.LinkSetMTU(link, dev.HostMTU); err != nil {
+			return err
+		}
+	}
+	if dev.HostHardwareAddress != "" && dev.HostHardwareAddress != dev.HardwareAddress {
+		mac, err := net.ParseMAC(dev.HostHardwareAddress)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetHardwareAddr(link, mac); err != nil {
+			return err
+		}
+	}
+	if attrs.TxQLen != dev.HostTxQueueLen {
+		if err := netlink.LinkSetTxQLen(link, dev.HostTxQueueLen); err != nil {
+			return err
+		}
+	}
+	if dev.HostUp {
+		return netlink.LinkSetUp(link)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netns"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// netnsDir is the directory in root where the kept network namespaces of
+// the containers are bind-mounted (see [configs.Config.KeepNetns]). Like
+// groupsDir, it never clashes with a container state directory.
+const netnsDir = "@netns"
+
+// keptNetnsPath returns the path the network namespace of the container id
+// is kept at.
+func keptNetnsPath(root, id string) string {
+	return filepath.Join(root, netnsDir, id)
+}
+
+// isNetnsMount returns whether path is a mounted namespace.
+func isNetnsMount(path string) bool {
+	var st unix.Statfs_t
+	return unix.Statfs(path, &st) == nil && st.Type == unix.NSFS_MAGIC
+}
+
+// resolveKeptNetns makes config join the network namespace kept by the
+// previous container with the ID id, if any. The kept network namespace is
+// removed instead if config does not keep it.
+func resolveKeptNetns(root, id string, config *configs.Config) error {
+	path := keptNetnsPath(root, id)
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+	if !isNetnsMount(path) || !config.KeepNetns || config.Namespaces.PathOf(configs.NEWNET) != "" {
+		return RemoveKeptNetns(root, id)
+	}
+	logrus.Debugf("reusing the network namespace kept at %s", path)
+	config.Namespaces = slices.Clone(config.Namespaces)
+	for i := range config.Namespaces {
+		if config.Namespaces[i].Type == configs.NEWNET {
+			config.Namespaces[i].Path = path
+		}
+	}
+	return nil
+}
+
+// keepNetns bind-mounts the network namespace of the container init pid
+// under the root directory, unless it is the kept one already.
+func (c *Container) keepNetns(pid int) error {
+	root := filepath.Dir(c.stateDir)
+	path := keptNetnsPath(root, c.id)
+	if c.config.Namespaces.PathOf(configs.NEWNET) == path {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	src := "/proc/" + strconv.Itoa(pid) + "/ns/net"
+	if err := unix.Mount(src, path, "", unix.MS_BIND, ""); err != nil {
+		_ = os.Remove(path)
+		return &os.PathError{Op: "mount", Path: path, Err: err}
+	}
+	return nil
+}
+
+// RemoveKeptNetns removes the network namespace kept by the container id
+// in the root directory, if any (see [configs.Config.KeepNetns]). The
+// network namespace is gone once no process uses it.
+func RemoveKeptNetns(root, id string) error {
+	if err := validateID(id); err != nil {
+		return err
+	}
+	path := keptNetnsPath(root, id)
+	if err := unix.Unmount(path, unix.MNT_DETACH); err != nil && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.ENOENT) {
+		return &os.PathError{Op: "unmount", Path: path, Err: err}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// keptNetDevice returns the state of the network device name of the
+// container, if it is in the kept network namespace at nsPath already,
+// rather than in the runtime network namespace.
+func keptNetDevice(name, nsPath string, device configs.LinuxNetDevice) (*NetDeviceState, error) {
+	if _, err := netlink.LinkByName(name); err == nil {
+		return nil, nil
+	}
+	ctrName := name
+	if device.Name != "" {
+		ctrName = device.Name
+	}
+	ns, err := netns.GetFromPath(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+	nh, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return nil, err
+	}
+	defer nh.Close()
+	link, err := nh.LinkByName(ctrName)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("link %s on namespace %s: %w", ctrName, nsPath, err)
+	}
+	logrus.Debugf("network device %s is in the kept network namespace already, as %s", name, ctrName)
+	return &NetDeviceState{
+		HostName:        name,
+		Name:            ctrName,
+		HardwareAddress: link.Attrs().HardwareAddr.String(),
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -109,13 +110,24 @@ func (l *loopback) detach(n *configs.Network) (err error) {
 // and optionally change the device name.
 // The device name will be kept the same if device.Name is the zero value.
 // This function ensures that the move and rename operations occur atomically.
-// It preserves existing interface attributes, including global IP addresses.
-func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDevice) error {
+// It preserves existing interface attributes, including global IP addresses,
+// unless overridden by device (see configureNetDevice). It returns the state
+// of the device needed to restore it (see restoreNetDevices).
+func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDevice) (*NetDeviceState, error) {
 	logrus.Debugf("attaching network device %s with attrs %+v to network namespace %s", name, device, nsPath)
 	link, err := netlink.LinkByName(name)
 	// recover same behavior on vishvananda/netlink@1.2.1 and do not fail when the kernel returns NLM_F_DUMP_INTR.
 	if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
-		return fmt.Errorf("link not found for interface %s on runtime namespace: %w", name, err)
+		return nil, fmt.Errorf("link not found for interface %s on runtime namespace: %w", name, err)
+	}
+	attrs := link.Attrs()
+	st := &NetDeviceState{
+		HostName:            name,
+		Physical:            link.Type() == "device",
+		HostMTU:             attrs.MTU,
+		HostHardwareAddress: attrs.HardwareAddr.String(),
+		HostTxQueueLen:      attrs.TxQLen,
+		HostUp:              attrs.Flags&net.FlagUp != 0,
 	}
 
 	// Set the interface link state to DOWN before modifying attributes like namespace or name.
@@ -123,14 +135,14 @@ func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDe
 	// particularly if other host components depend on this specific interface or its properties.
 	err = netlink.LinkSetDown(link)
 	if err != nil {
-		return fmt.Errorf("fail to set link down: %w", err)
+		return nil, fmt.Errorf("fail to set link down: %w", err)
 	}
 
 	// Get the existing IP addresses on the interface.
 	addresses, err := netlink.AddrList(link, netlink.FAMILY_ALL)
 	// recover same behavior on vishvananda/netlink@1.2.1 and do not fail when the kernel returns NLM_F_DUMP_INTR.
 	if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
-		return fmt.Errorf("fail to get ip addresses: %w", err)
+		return nil, fmt.Errorf("fail to get ip addresses: %w", err)
 	}
 
 	// Do interface rename and namespace change in the same operation to avoid
@@ -144,7 +156,7 @@ func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDe
 	// Get a netlink socket in current namespace
 	nlSock, err := nl.GetNetlinkSocketAt(netns.None(), netns.None(), unix.NETLINK_ROUTE)
 	if err != nil {
-		return fmt.Errorf("could not get network namespace handle: %w", err)
+		return nil, fmt.Errorf("could not get network namespace handle: %w", err)
 	}
 	defer nlSock.Close()
 
@@ -168,7 +180,7 @@ func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDe
 	// Get the new network namespace.
 	ns, err := netns.GetFromPath(nsPath)
 	if err != nil {
-		return fmt.Errorf("could not get network namespace from path %s for network device %s : %w", nsPath, name, err)
+		return nil, fmt.Errorf("could not get network namespace from path %s for network device %s : %w", nsPath, name, err)
 	}
 	defer ns.Close()
 
@@ -179,21 +191,29 @@ func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDe
 	_, err = req.Execute(unix.NETLINK_ROUTE, 0)
 	// recover same behavior on vishvananda/netlink@1.2.1 and do not fail when the kernel returns NLM_F_DUMP_INTR.
 	if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
-		return fmt.Errorf("fail to move network device %s to network namespace %s: %w", name, nsPath, err)
+		return nil, fmt.Errorf("fail to move network device %s to network namespace %s: %w", name, nsPath, err)
 	}
 
 	// To avoid us the husle with goroutines when joining a netns,
 	// we let the library create the socket in the namespace for us.
 	nhNs, err := netlink.NewHandleAt(ns)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer nhNs.Close()
 
 	nsLink, err := nhNs.LinkByName(newName)
 	// recover same behavior on vishvananda/netlink@1.2.1 and do not fail when the kernel returns NLM_F_DUMP_INTR.
 	if err != nil && !errors.Is(err, netlink.ErrDumpInterrupted) {
-		return fmt.Errorf("link not found for interface %s on namespace %s : %w", newName, nsPath, err)
+		return nil, fmt.Errorf("link not found for interface %s on namespace %s : %w", newName, nsPath, err)
+	}
+	if err := configureNetDevice(nhNs, ns, nsLink, device); err != nil {
+		return nil, fmt.Errorf("fail to configure interface %s on namespace %s: %w", newName, nsPath, err)
+	}
+	st.Name = newName
+	st.HardwareAddress = nsLink.Attrs().HardwareAddr.String()
+	if device.HardwareAddress != "" {
+		st.HardwareAddress = device.HardwareAddress
 	}
 
 	// Re-add the original IP addresses to the interface in the new namespace.
@@ -219,14 +239,14 @@ func devChangeNetNamespace(name string, nsPath string, device configs.LinuxNetDe
 		// to avoid issues when the interface is renamed.
 		err = nhNs.AddrAdd(nsLink, &netlink.Addr{IPNet: address.IPNet})
 		if err != nil {
-			return fmt.Errorf("fail to set up address %s on namespace %s: %w", address.String(), nsPath, err)
+			return nil, fmt.Errorf("fail to set up address %s on namespace %s: %w", address.String(), nsPath, err)
 		}
 	}
 
 	err = nhNs.LinkSetUp(nsLink)
 	if err != nil {
-		return fmt.Errorf("fail to set up interface %s on namespace %s: %w", nsLink.Attrs().Name, nsPath, err)
+		return nil, fmt.Errorf("fail to set up interface %s on namespace %s: %w", nsLink.Attrs().Name, nsPath, err)
 	}
 
-	return nil
+	return st, nil
 }
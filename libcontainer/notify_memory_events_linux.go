@@ -0,0 +1,175 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fscommon"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// MemoryEvents is the counters of the cgroup v2 memory.events (or
+// memory.events.local) file. See the "memory.events" section of the kernel
+// cgroup v2 documentation.
+type MemoryEvents struct {
+	Low          uint64
+	High         uint64
+	Max          uint64
+	OOM          uint64
+	OOMKill      uint64
+	OOMGroupKill uint64
+}
+
+// sub returns the counters of e minus the ones of prev.
+func (e MemoryEvents) sub(prev MemoryEvents) MemoryEvents {
+	return MemoryEvents{
+		Low:          e.Low - prev.Low,
+		High:         e.High - prev.High,
+		Max:          e.Max - prev.Max,
+		OOM:          e.OOM - prev.OOM,
+		OOMKill:      e.OOMKill - prev.OOMKill,
+		OOMGroupKill: e.OOMGroupKill - prev.OOMGroupKill,
+	}
+}
+
+// MemoryEventsNotification is sent on the channel returned by
+// [Container.NotifyMemoryEvents] when the memory events counters change.
+type MemoryEventsNotification struct {
+	// Counters is the current value of the counters.
+	Counters MemoryEvents
+	// Delta is how much the counters have increased since the previous
+	// notification (or, for the first one, since the channel was created).
+	Delta MemoryEvents
+}
+
+// NotifyMemoryEvents returns a read-only channel receiving the cgroup v2
+// memory events of the container, such as the memory.high throttling or the
+// OOM kills. If local is true, the events of the container cgroup only are
+// reported (using memory.events.local), rather than the ones of its whole
+// subtree (using memory.events). The channel is closed when the container
+// processes have all exited. This requires cgroup v2.
+func (c *Container) NotifyMemoryEvents(local bool) (<-chan MemoryEventsNotification, error) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return nil, errors.New("memory events notifications require cgroup v2")
+	}
+	file := "memory.events"
+	if local {
+		file = "memory.events.local"
+	}
+	return notifyOnMemoryEvents(c.cgroupManager.Path(""), file)
+}
+
+// parseMemoryEvents parses the content of a memory.events file.
+func parseMemoryEvents(data string) (MemoryEvents, error) {
+	var e MemoryEvents
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		if line == "" {
+			continue
+		}
+		key, val, err := fscommon.ParseKeyValue(line)
+		if err != nil {
+			return e, err
+		}
+		switch key {
+		case "low":
+			e.Low = val
+		case "high":
+			e.High = val
+		case "max":
+			e.Max = val
+		case "oom":
+			e.OOM = val
+		case "oom_kill":
+			e.OOMKill = val
+		case "oom_group_kill":
+			e.OOMGroupKill = val
+		}
+	}
+	return e, nil
+}
+
+func readMemoryEvents(cgDir, file string) (MemoryEvents, error) {
+	data, err := cgroups.ReadFile(cgDir, file)
+	if err != nil {
+		return MemoryEvents{}, err
+	}
+	return parseMemoryEvents(data)
+}
+
+func notifyOnMemoryEvents(cgDir, file string) (<-chan MemoryEventsNotification, error) {
+	prev, err := readMemoryEvents(cgDir, file)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init inotify: %w", err)
+	}
+	evFd, err := unix.InotifyAddWatch(fd, filepath.Join(cgDir, file), unix.IN_MODIFY)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("unable to add inotify watch: %w", err)
+	}
+	// As in registerMemoryEventV2, cgroup.events is watched to know when
+	// the processes have all exited.
+	cgFd, err := unix.InotifyAddWatch(fd, filepath.Join(cgDir, "cgroup.events"), unix.IN_MODIFY)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("unable to add inotify watch: %w", err)
+	}
+	ch := make(chan MemoryEventsNotification)
+	go func() {
+		var buffer [unix.SizeofInotifyEvent + unix.PathMax + 1]byte
+		defer func() {
+			unix.Close(fd)
+			close(ch)
+		}()
+
+		for {
+			n, err := unix.Read(fd, buffer[:])
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				logrus.Warnf("unable to read event data from inotify: %v", os.NewSyscallError("read", err))
+				return
+			}
+			if n < unix.SizeofInotifyEvent {
+				logrus.Warnf("we should read at least %d bytes from inotify, but got %d bytes.", unix.SizeofInotifyEvent, n)
+				return
+			}
+			for offset := uint32(0); offset <= uint32(n-unix.SizeofInotifyEvent); {
+				rawEvent := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+				offset += unix.SizeofInotifyEvent + rawEvent.Len
+				if rawEvent.Mask&unix.IN_MODIFY != unix.IN_MODIFY {
+					continue
+				}
+				switch int(rawEvent.Wd) {
+				case evFd:
+					cur, err := readMemoryEvents(cgDir, file)
+					if err != nil {
+						logrus.Warnf("unable to read %s: %v", file, err)
+						return
+					}
+					if cur == prev {
+						continue
+					}
+					ch <- MemoryEventsNotification{Counters: cur, Delta: cur.sub(prev)}
+					prev = cur
+				case cgFd:
+					pids, err := fscommon.GetValueByKey(cgDir, "cgroup.events", "populated")
+					if err != nil || pids == 0 {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,67 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/cgroups"
+)
+
+func TestParseMemoryEvents(t *testing.T) {
+	e, err := parseMemoryEvents("low 1\nhigh 2\nmax 3\noom 4\noom_kill 5\noom_group_kill 6\nsock_throttled 7\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := MemoryEvents{Low: 1, High: 2, Max: 3, OOM: 4, OOMKill: 5, OOMGroupKill: 6}
+	if e != expected {
+		t.Errorf("expected %+v, got %+v", expected, e)
+	}
+	if _, err := parseMemoryEvents("high\n"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestNotifyOnMemoryEvents(t *testing.T) {
+	cgroups.TestMode = true
+	defer func() { cgroups.TestMode = false }()
+	dir := t.TempDir()
+	write := func(file, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("memory.events", "high 1\nmax 0\noom 0\noom_kill 0\n")
+	write("cgroup.events", "populated 1\nfrozen 0\n")
+
+	ch, err := notifyOnMemoryEvents(dir, "memory.events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write("memory.events", "high 4\nmax 1\noom 1\noom_kill 1\n")
+	select {
+	case n := <-ch:
+		expected := MemoryEventsNotification{
+			Counters: MemoryEvents{High: 4, Max: 1, OOM: 1, OOMKill: 1},
+			Delta:    MemoryEvents{High: 3, Max: 1, OOM: 1, OOMKill: 1},
+		}
+		if n != expected {
+			t.Errorf("expected %+v, got %+v", expected, n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+
+	write("cgroup.events", "populated 0\nfrozen 0\n")
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Fatal("expected the channel to be closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the channel is not closed")
+	}
+}
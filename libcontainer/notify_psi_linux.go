@@ -0,0 +1,132 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// PSITrigger is a pressure stall information (PSI) threshold, which is
+// crossed when the tasks of the container are stalled on Resource for
+// more than Stall time within a Window. See the "Monitoring for pressure
+// thresholds" section of the kernel PSI documentation.
+type PSITrigger struct {
+	// Resource is one of "cpu", "memory", or "io".
+	Resource string
+	// Full selects the "full" stall type (all tasks are stalled)
+	// instead of "some" (at least one task is stalled).
+	Full bool
+	// Stall is the stall time threshold.
+	Stall time.Duration
+	// Window is the time window, from 500ms to 10s.
+	Window time.Duration
+}
+
+// ParsePSITrigger parses a trigger in the "resource:type:stall/window"
+// format, e.g. "memory:some:150ms/1s".
+func ParsePSITrigger(s string) (*PSITrigger, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid PSI trigger %q: expected resource:type:stall/window", s)
+	}
+	t := &PSITrigger{Resource: parts[0]}
+	switch parts[0] {
+	case "cpu", "memory", "io":
+	default:
+		return nil, fmt.Errorf("invalid PSI trigger %q: unknown resource %q", s, parts[0])
+	}
+	switch parts[1] {
+	case "some":
+	case "full":
+		t.Full = true
+	default:
+		return nil, fmt.Errorf("invalid PSI trigger %q: type must be some or full", s)
+	}
+	stall, window, ok := strings.Cut(parts[2], "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid PSI trigger %q: expected stall/window", s)
+	}
+	var err error
+	if t.Stall, err = time.ParseDuration(stall); err != nil {
+		return nil, fmt.Errorf("invalid PSI trigger %q: %w", s, err)
+	}
+	if t.Window, err = time.ParseDuration(window); err != nil {
+		return nil, fmt.Errorf("invalid PSI trigger %q: %w", s, err)
+	}
+	if t.Window < 500*time.Millisecond || t.Window > 10*time.Second {
+		return nil, fmt.Errorf("invalid PSI trigger %q: window must be from 500ms to 10s", s)
+	}
+	if t.Stall <= 0 || t.Stall > t.Window {
+		return nil, fmt.Errorf("invalid PSI trigger %q: stall must be positive and not exceed the window", s)
+	}
+	return t, nil
+}
+
+func (t *PSITrigger) String() string {
+	typ := "some"
+	if t.Full {
+		typ = "full"
+	}
+	return t.Resource + ":" + typ + ":" + t.Stall.String() + "/" + t.Window.String()
+}
+
+// NotifyPSI returns a read-only channel signaling when the container crosses
+// the given pressure stall information threshold. The channel is closed when
+// the container cgroup is removed. This requires cgroup v2.
+func (c *Container) NotifyPSI(trigger *PSITrigger) (<-chan struct{}, error) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return nil, errors.New("PSI notifications require cgroup v2")
+	}
+	return notifyOnPSI(c.cgroupManager.Path(""), trigger)
+}
+
+func notifyOnPSI(cgDir string, trigger *PSITrigger) (<-chan struct{}, error) {
+	path := filepath.Join(cgDir, trigger.Resource+".pressure")
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: path, Err: err}
+	}
+	typ := "some"
+	if trigger.Full {
+		typ = "full"
+	}
+	// The trigger is registered for as long as the fd is open.
+	data := fmt.Sprintf("%s %d %d\x00", typ, trigger.Stall.Microseconds(), trigger.Window.Microseconds())
+	if _, err := unix.Write(fd, []byte(data)); err != nil {
+		unix.Close(fd)
+		return nil, &os.PathError{Op: "write", Path: path, Err: err}
+	}
+	ch := make(chan struct{})
+	go func() {
+		defer func() {
+			unix.Close(fd)
+			close(ch)
+		}()
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLPRI}}
+		for {
+			_, err := unix.Poll(fds, -1)
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				logrus.Warnf("unable to poll %s: %v", path, os.NewSyscallError("poll", err))
+				return
+			}
+			// POLLERR is returned once the cgroup is removed.
+			if fds[0].Revents&unix.POLLERR != 0 {
+				return
+			}
+			if fds[0].Revents&unix.POLLPRI != 0 {
+				ch <- struct{}{}
+			}
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,43 @@
+package libcontainer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePSITrigger(t *testing.T) {
+	testCases := []struct {
+		in      string
+		trigger PSITrigger
+		isErr   bool
+	}{
+		{in: "memory:some:150ms/1s", trigger: PSITrigger{Resource: "memory", Stall: 150 * time.Millisecond, Window: time.Second}},
+		{in: "io:full:1s/10s", trigger: PSITrigger{Resource: "io", Full: true, Stall: time.Second, Window: 10 * time.Second}},
+		{in: "cpu:some:500ms/500ms", trigger: PSITrigger{Resource: "cpu", Stall: 500 * time.Millisecond, Window: 500 * time.Millisecond}},
+		{in: "irq:full:1s/2s", isErr: true},
+		{in: "memory:half:1s/2s", isErr: true},
+		{in: "memory:some:1s", isErr: true},
+		{in: "memory:some:2s/1s", isErr: true},
+		{in: "memory:some:100ms/100ms", isErr: true},
+		{in: "memory:some:1s/20s", isErr: true},
+		{in: "memory:some:0s/1s", isErr: true},
+		{in: "memory:some:x/1s", isErr: true},
+		{in: "memory", isErr: true},
+	}
+	for _, tc := range testCases {
+		trigger, err := ParsePSITrigger(tc.in)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if *trigger != tc.trigger {
+			t.Errorf("%q: expected %+v, got %+v", tc.in, tc.trigger, *trigger)
+		}
+	}
+}
@@ -12,10 +12,13 @@ static const char *level_str[] = { "panic", "fatal", "error", "warning", "info",
 
 int logfd = -1;
 static int loglevel = DEBUG;
+/* The "correlation_id" field of the log entries, if any. */
+static char *logfield = NULL;
 
 extern char *escape_json_string(char *str);
 void setup_logpipe(void)
 {
+	char *id;
 	int i;
 
 	i = getenv_int("_LIBCONTAINER_LOGPIPE");
@@ -25,6 +28,16 @@ void setup_logpipe(void)
 	}
 	logfd = i;
 
+	id = getenv("_LIBCONTAINER_CORRELATIONID");
+	if (id != NULL && *id != '\0') {
+		id = strdup(id);
+		if (id != NULL)
+			id = escape_json_string(id);
+		if (id != NULL && asprintf(&logfield, "\"correlation_id\":\"%s\", ", id) < 0)
+			logfield = NULL;
+		free(id);
+	}
+
 	i = getenv_int("_LIBCONTAINER_LOGLEVEL");
 	if (i < 0)
 		return;
@@ -69,8 +82,8 @@ void write_log(int level, const char *format, ...)
 			goto out;
 		}
 	}
-	ret = asprintf(&json, "{\"level\":\"%s\", \"msg\": \"%s[%d]: %s\"}\n",
-		       level_str[level], stage, getpid(), message);
+	ret = asprintf(&json, "{%s\"level\":\"%s\", \"msg\": \"%s[%d]: %s\"}\n",
+		       logfield ? logfield : "", level_str[level], stage, getpid(), message);
 	if (ret < 0) {
 		json = NULL;
 		goto out;
@@ -32,4 +32,10 @@
 #	define CLONE_NEWTIME 0x00000080	/* New time namespace */
 #endif
 
+/* Taken from include/uapi/linux/nsfs.h */
+#ifndef NS_GET_NSTYPE
+#	include <sys/ioctl.h>
+#	define NS_GET_NSTYPE _IO(0xb7, 0x3) /* Get the namespace type */
+#endif
+
 #endif /* NSENTER_NAMESPACE_H */
@@ -90,6 +90,9 @@ struct nlconfig_t {
 	char *gidmappath;
 	size_t gidmappath_len;
 
+	/* Whether the runc parent sets up the user namespace mappings. */
+	uint8_t is_idmap_delegated;
+
 	/* Time NS offsets. */
 	char *timensoffset;
 	size_t timensoffset_len;
@@ -110,6 +113,7 @@ struct nlconfig_t {
 #define UIDMAPPATH_ATTR		27288
 #define GIDMAPPATH_ATTR		27289
 #define TIMENSOFFSET_ATTR	27290
+#define IDMAPDELEGATE_ATTR	27291
 
 /*
  * Use the raw syscall for versions of glibc which don't include a function for
@@ -278,6 +282,23 @@ static void update_uidmap(const char *path, int pid, char *map, size_t map_len)
 	}
 }
 
+/*
+ * Request the runc parent (through the init pipe) to set up the user
+ * namespace mappings of @pid, and wait for it to be done.
+ */
+static void delegate_idmap(int pipenum, int pid)
+{
+	char ack;
+
+	write_log(DEBUG, "request the parent to map the user namespace of %d", pid);
+	if (dprintf(pipenum, "{\"usermap_pid\":%d}\n", pid) < 0)
+		bail("failed to request the user namespace mappings of %d", pid);
+	if (read(pipenum, &ack, sizeof(ack)) != sizeof(ack))
+		bail("failed to read the user namespace mappings result");
+	if (ack != SYNC_USERMAP_ACK)
+		bail("parent failed to set up the user namespace mappings of %d", pid);
+}
+
 static void update_gidmap(const char *path, int pid, char *map, size_t map_len)
 {
 	if (map == NULL || map_len == 0)
@@ -407,6 +428,9 @@ static void nl_parse(int fd, struct nlconfig_t *config)
 			config->timensoffset = current;
 			config->timensoffset_len = payload_len;
 			break;
+		case IDMAPDELEGATE_ATTR:
+			config->is_idmap_delegated = readint8(current);
+			break;
 		default:
 			bail("unknown netlink message type %d", nlattr->nla_type);
 		}
@@ -422,6 +446,7 @@ void nl_free(struct nlconfig_t *config)
 
 struct namespace_t {
 	int fd;
+	int flag;
 	char type[PATH_MAX];
 	char path[PATH_MAX];
 };
@@ -443,20 +468,28 @@ static struct nstype_t {
 	{ },			/* null terminator */
 };
 
-/* Returns the clone(2) flag for a namespace, given the name of a namespace. */
-static int nstype(char *name)
+/*
+ * Returns the clone(2) flag for a namespace, given the name of a namespace
+ * and a file descriptor of the namespace.
+ */
+static int nstype(char *name, int fd)
 {
+	int type;
+
 	for (struct nstype_t * ns = all_ns_types; ns->name != NULL; ns++)
 		if (!strcmp(name, ns->name))
 			return ns->type;
 	/*
-	 * setns(2) lets us join namespaces without knowing the type, but
-	 * namespaces usually require special handling of some kind (so joining
-	 * a namespace without knowing its type or joining a new namespace type
-	 * without corresponding handling could result in broken behaviour) and
-	 * the rest of runc doesn't allow unknown namespace types anyway.
+	 * Namespaces usually require special handling of some kind (so joining a
+	 * new namespace type without corresponding handling could result in
+	 * broken behaviour), so the rest of runc only allows unknown namespace
+	 * types if the container configuration explicitly allows them. In that
+	 * case, get the type from the kernel.
 	 */
-	bail("unknown namespace type %s", name);
+	type = ioctl(fd, NS_GET_NSTYPE);
+	if (type <= 0)
+		bail("unknown namespace type %s", name);
+	return type;
 }
 
 static nsset_t __open_namespaces(char *nsspec, struct namespace_t **ns_list, size_t *ns_len)
@@ -496,8 +529,9 @@ static nsset_t __open_namespaces(char *nsspec, struct namespace_t **ns_list, siz
 		strncpy(ns->type, namespace, PATH_MAX - 1);
 		strncpy(ns->path, path, PATH_MAX - 1);
 		ns->path[PATH_MAX - 1] = '\0';
+		ns->flag = nstype(ns->type, fd);
 
-		ns_to_join |= nstype(ns->type);
+		ns_to_join |= ns->flag;
 	} while ((namespace = strtok_r(NULL, ",", &saveptr)) != NULL);
 
 	*ns_list = namespaces;
@@ -516,7 +550,7 @@ static nsset_t __join_namespaces(nsset_t allow, struct namespace_t *ns_list, siz
 
 	for (size_t i = 0; i < ns_len; i++) {
 		struct namespace_t *ns = &ns_list[i];
-		int type = nstype(ns->type);
+		int type = ns->flag;
 		int err, saved_errno;
 
 		if (!(type & allow))
@@ -583,7 +617,7 @@ static void __close_namespaces(nsset_t to_join, nsset_t joined, struct namespace
 	/* Double-check that we used up (and thus joined) all of the nsfds. */
 	for (size_t i = 0; i < ns_len; i++) {
 		struct namespace_t *ns = &ns_list[i];
-		int type = nstype(ns->type);
+		int type = ns->flag;
 
 		if (ns->fd < 0)
 			continue;
@@ -875,8 +909,12 @@ void nsexec(void)
 						update_setgroups(stage1_pid, SETGROUPS_DENY);
 
 					/* Set up mappings. */
-					update_uidmap(config.uidmappath, stage1_pid, config.uidmap, config.uidmap_len);
-					update_gidmap(config.gidmappath, stage1_pid, config.gidmap, config.gidmap_len);
+					if (config.is_idmap_delegated) {
+						delegate_idmap(pipenum, stage1_pid);
+					} else {
+						update_uidmap(config.uidmappath, stage1_pid, config.uidmap, config.uidmap_len);
+						update_gidmap(config.gidmappath, stage1_pid, config.gidmap, config.gidmap_len);
+					}
 
 					s = SYNC_USERMAP_ACK;
 					if (write(syncfd, &s, sizeof(s)) != sizeof(s)) {
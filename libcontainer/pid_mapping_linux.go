@@ -0,0 +1,145 @@
+package libcontainer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// PidMapping is the PID of a container process in the PID namespace of
+// runc, and in the PID namespace of the container.
+type PidMapping struct {
+	Host int `json:"host"`
+	// Container is the PID in the container PID namespace, or -1 if the
+	// process is not in it (such as a helper process run by runc on behalf
+	// of the container, see [Container.AddHelper]).
+	Container int `json:"container"`
+}
+
+// PidMappings returns the PID mappings of the container processes (see
+// [Container.Processes]), sorted by host PID. The processes which exit
+// meanwhile are omitted. The PIDs are read from the NSpid field of
+// /proc/<pid>/status, which requires Linux 4.1.
+func (c *Container) PidMappings() ([]PidMapping, error) {
+	level, err := c.pidNamespaceLevel()
+	if err != nil {
+		return nil, err
+	}
+	pids, err := c.Processes()
+	if err != nil {
+		return nil, err
+	}
+	mappings := make([]PidMapping, 0, len(pids))
+	for _, pid := range pids {
+		nspid, err := readNSpid(pid)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			return nil, fmt.Errorf("process %d: %w", pid, err)
+		}
+		m := PidMapping{Host: pid, Container: -1}
+		if level < len(nspid) {
+			m.Container = nspid[level]
+		}
+		mappings = append(mappings, m)
+	}
+	slices.SortFunc(mappings, func(a, b PidMapping) int { return a.Host - b.Host })
+	return mappings, nil
+}
+
+// HostPid returns the PID in the PID namespace of runc of the container
+// process whose PID in the container PID namespace is pid.
+func (c *Container) HostPid(pid int) (int, error) {
+	mappings, err := c.PidMappings()
+	if err != nil {
+		return -1, err
+	}
+	for _, m := range mappings {
+		if m.Container == pid {
+			return m.Host, nil
+		}
+	}
+	return -1, fmt.Errorf("no process %d in the container: %w", pid, unix.ESRCH)
+}
+
+// ContainerPid returns the PID in the container PID namespace of the
+// container process whose PID in the PID namespace of runc is pid.
+func (c *Container) ContainerPid(pid int) (int, error) {
+	level, err := c.pidNamespaceLevel()
+	if err != nil {
+		return -1, err
+	}
+	pids, err := c.Processes()
+	if err != nil {
+		return -1, err
+	}
+	if !slices.Contains(pids, pid) {
+		return -1, fmt.Errorf("process %d is not in the container: %w", pid, unix.ESRCH)
+	}
+	nspid, err := readNSpid(pid)
+	if err != nil {
+		return -1, fmt.Errorf("process %d: %w", pid, err)
+	}
+	if level >= len(nspid) {
+		return -1, fmt.Errorf("process %d is not in the container PID namespace", pid)
+	}
+	return nspid[level], nil
+}
+
+// pidNamespaceLevel returns the index of the container PID namespace in the
+// NSpid lists of the container processes.
+func (c *Container) pidNamespaceLevel() (int, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !c.hasInit() {
+		return -1, ErrNotRunning
+	}
+	nspid, err := readNSpid(c.initProcess.pid())
+	if err != nil {
+		return -1, fmt.Errorf("container init: %w", err)
+	}
+	return len(nspid) - 1, nil
+}
+
+// readNSpid returns the PIDs of the process pid in each of its PID
+// namespaces, from the one of runc to the innermost one, as listed by the
+// NSpid field of /proc/<pid>/status.
+func readNSpid(pid int) ([]int, error) {
+	path := "/proc/" + strconv.Itoa(pid) + "/status"
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		val, ok := strings.CutPrefix(s.Text(), "NSpid:")
+		if !ok {
+			continue
+		}
+		var nspid []int
+		for _, field := range strings.Fields(val) {
+			p, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid NSpid in %s: %w", path, err)
+			}
+			nspid = append(nspid, p)
+		}
+		if len(nspid) == 0 {
+			return nil, fmt.Errorf("invalid NSpid in %s", path)
+		}
+		return nspid, nil
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no NSpid in %s (requires Linux 4.1)", path)
+}
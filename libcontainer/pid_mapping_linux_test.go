@@ -0,0 +1,19 @@
+package libcontainer
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReadNSpid(t *testing.T) {
+	nspid, err := readNSpid(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nspid) == 0 || nspid[0] != os.Getpid() {
+		t.Errorf("expected NSpid to start with %d, got %v", os.Getpid(), nspid)
+	}
+	if _, err := readNSpid(-1); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
+)
+
+// The values the utilization clamps are set back to when unset.
+const (
+	uclampMinDefault = "0"
+	uclampMaxDefault = "max"
+)
+
+// EPPState is the energy performance preference of a CPU before it was set
+// for the container, which is restored once the CPU is no longer in the
+// container cpuset, or once the container is destroyed.
+type EPPState struct {
+	CPU int    `json:"cpu"`
+	EPP string `json:"epp"`
+}
+
+// applyPowerHint applies the power hint of config (see
+// [configs.Config.PowerHint]), the one of old (which is nil on create)
+// being the current one. The utilization clamps which are no longer set are
+// set back to their defaults, and the energy performance preference of the
+// CPUs which are no longer in the container cpuset to its previous value.
+func (c *Container) applyPowerHint(old, config *configs.Config) error {
+	var oldHint, hint configs.PowerHint
+	if old != nil && old.PowerHint != nil {
+		oldHint = *old.PowerHint
+	}
+	if config.PowerHint != nil {
+		hint = *config.PowerHint
+	}
+	for _, u := range []struct {
+		file, old, new, def string
+	}{
+		{configs.UclampMinFile, oldHint.UclampMin, hint.UclampMin, uclampMinDefault},
+		{configs.UclampMaxFile, oldHint.UclampMax, hint.UclampMax, uclampMaxDefault},
+	} {
+		if u.new == "" {
+			if u.old == "" {
+				continue
+			}
+			u.new = u.def
+		}
+		dir := c.cgroupManager.Path("cpu")
+		if dir == "" {
+			return fmt.Errorf("unable to set %s: no cpu cgroup", u.file)
+		}
+		if err := cgroups.WriteFile(dir, u.file, u.new); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%s is not supported by the kernel (CONFIG_UCLAMP_TASK_GROUP)", u.file)
+			}
+			return fmt.Errorf("unable to set %s: %w", u.file, err)
+		}
+	}
+
+	var oldCPUs, cpus []int
+	if oldHint.EPP != "" {
+		oldCPUs = powerHintCPUs(old)
+	}
+	if hint.EPP != "" {
+		cpus = powerHintCPUs(config)
+	}
+	for _, cpu := range oldCPUs {
+		if !slices.Contains(cpus, cpu) {
+			c.restoreEPP(cpu)
+		}
+	}
+	for _, cpu := range cpus {
+		if err := c.saveEPP(cpu); err != nil {
+			return err
+		}
+		if err := os.WriteFile(configs.EPPPath(strconv.Itoa(cpu)), []byte(hint.EPP), 0); err != nil {
+			return fmt.Errorf("unable to set the energy performance preference of CPU %d: %w", cpu, err)
+		}
+	}
+	return nil
+}
+
+// restorePowerHint restores the energy performance preference of the
+// container CPUs, once the container is gone.
+func (c *Container) restorePowerHint() {
+	for _, s := range c.eppSaved {
+		writeEPP(s)
+	}
+	c.eppSaved = nil
+}
+
+// powerHintCPUs returns the CPUs of the container cpuset.
+func powerHintCPUs(config *configs.Config) []int {
+	if config.Cgroups == nil || config.Cgroups.Resources == nil {
+		return nil
+	}
+	cpus, err := cpuset.Parse(config.Cgroups.Resources.CpusetCpus)
+	if err != nil {
+		// Validated already.
+		return nil
+	}
+	return cpus
+}
+
+// saveEPP saves the energy performance preference of the CPU cpu, unless
+// it was already saved.
+func (c *Container) saveEPP(cpu int) error {
+	for _, s := range c.eppSaved {
+		if s.CPU == cpu {
+			return nil
+		}
+	}
+	data, err := os.ReadFile(configs.EPPPath(strconv.Itoa(cpu)))
+	if err != nil {
+		return fmt.Errorf("unable to get the energy performance preference of CPU %d: %w", cpu, err)
+	}
+	c.eppSaved = append(c.eppSaved, EPPState{CPU: cpu, EPP: strings.TrimSpace(string(data))})
+	return nil
+}
+
+// restoreEPP restores the saved energy performance preference of the CPU
+// cpu.
+func (c *Container) restoreEPP(cpu int) {
+	c.eppSaved = slices.DeleteFunc(c.eppSaved, func(s EPPState) bool {
+		if s.CPU != cpu {
+			return false
+		}
+		writeEPP(s)
+		return true
+	})
+}
+
+// writeEPP sets the energy performance preference of a CPU back to s.EPP.
+func writeEPP(s EPPState) {
+	if err := os.WriteFile(configs.EPPPath(strconv.Itoa(s.CPU)), []byte(s.EPP), 0); err != nil {
+		logrus.WithError(err).Warnf("unable to restore the energy performance preference of CPU %d", s.CPU)
+	}
+}
@@ -0,0 +1,283 @@
+package libcontainer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// probesFilename is the name of the file in the container state directory
+// which keeps the state of the container probes, see [Container.Probes].
+const probesFilename = "probes.json"
+
+// probeOutputMax is the maximum size of the probe command output kept in
+// the probe state.
+const probeOutputMax = 1024
+
+// ProbeStatus is the status of a container probe.
+type ProbeStatus string
+
+const (
+	// ProbeUnknown is the status of a probe which has neither passed nor
+	// reached its failure threshold yet.
+	ProbeUnknown ProbeStatus = "unknown"
+	// ProbePassing is the status of a probe whose last check passed.
+	ProbePassing ProbeStatus = "passing"
+	// ProbeFailing is the status of a probe whose last checks failed, at
+	// least as many times in a row as its failure threshold.
+	ProbeFailing ProbeStatus = "failing"
+)
+
+// ProbeState is the state of a container probe.
+type ProbeState struct {
+	Type   configs.ProbeType `json:"type"`
+	Args   []string          `json:"args"`
+	Status ProbeStatus       `json:"status"`
+	// Failures is the number of consecutive failed checks.
+	Failures int `json:"failures"`
+	// LastRun is when the probe was last run.
+	LastRun *time.Time `json:"last_run,omitempty"`
+	// ExitCode is the probe command exit code, for the last check.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Error is why the last check could not be done, or timed out.
+	Error string `json:"error,omitempty"`
+	// Output is the beginning of the probe command output, for the last
+	// check.
+	Output string `json:"output,omitempty"`
+}
+
+// Probes returns the state of the container probes, as recorded by
+// [Container.RunProbes], or nil if they are not run.
+func (c *Container) Probes() ([]ProbeState, error) {
+	data, err := os.ReadFile(filepath.Join(c.stateDir, probesFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var states []ProbeState
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("invalid probes state: %w", err)
+	}
+	return states, nil
+}
+
+// RunProbes runs the container probes (see [configs.Config.Probes]) until
+// ctx is done or the container init exits. Each check is an exec process,
+// created by newProcess from the probe command line, and started with
+// [Container.Run]. The probe states are recorded (see [Container.Probes]),
+// and an EventProbe event is sent whenever a probe status changes.
+func (c *Container) RunProbes(ctx context.Context, newProcess func(args []string) (*Process, error)) error {
+	c.m.Lock()
+	probes := c.config.Probes
+	running := c.hasInit()
+	c.m.Unlock()
+	if len(probes) == 0 {
+		return errors.New("container has no probes")
+	}
+	if !running {
+		return ErrNotRunning
+	}
+
+	r := &probeRunner{c: c, newProcess: newProcess, states: make([]ProbeState, len(probes))}
+	for i, p := range probes {
+		r.states[i] = ProbeState{Type: p.Type, Args: p.Args, Status: ProbeUnknown}
+	}
+	if err := r.save(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.run(ctx, i, p)
+		}()
+	}
+	// Stop once the container init is gone.
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for running {
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-ticker.C:
+			c.m.Lock()
+			running = c.hasInit()
+			c.m.Unlock()
+		}
+	}
+	cancel()
+	wg.Wait()
+	return nil
+}
+
+// probeRunner runs the probes of a container, and keeps their state.
+type probeRunner struct {
+	c          *Container
+	newProcess func(args []string) (*Process, error)
+
+	mu     sync.Mutex
+	states []ProbeState
+}
+
+// run runs the probe i every interval, until ctx is done.
+func (r *probeRunner) run(ctx context.Context, i int, p *configs.Probe) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		exitCode, output, err := r.check(ctx, p)
+		if ctx.Err() != nil {
+			return
+		}
+		r.update(i, p, exitCode, output, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check runs the probe command once, and returns its exit code and output,
+// or an error if it could not be run, or timed out.
+func (r *probeRunner) check(ctx context.Context, p *configs.Probe) (*int, string, error) {
+	process, err := r.newProcess(p.Args)
+	if err != nil {
+		return nil, "", err
+	}
+	out := &probeOutput{}
+	process.Stdin = nil
+	process.Stdout = out
+	process.Stderr = out
+	if err := r.c.Run(process); err != nil {
+		return nil, "", err
+	}
+
+	type result struct {
+		state *os.ProcessState
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		state, err := process.Wait()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			state, err = exitErr.ProcessState, nil
+		}
+		done <- result{state, err}
+	}()
+	timer := time.NewTimer(p.Timeout)
+	defer timer.Stop()
+	var res result
+	select {
+	case res = <-done:
+	case <-timer.C:
+		_ = process.Signal(unix.SIGKILL)
+		res = <-done
+		res.err = fmt.Errorf("timed out after %s", p.Timeout)
+	case <-ctx.Done():
+		_ = process.Signal(unix.SIGKILL)
+		res = <-done
+	}
+	if res.state == nil {
+		return nil, out.String(), res.err
+	}
+	exitCode := utils.ExitStatus(unix.WaitStatus(res.state.Sys().(syscall.WaitStatus)))
+	r.c.ExecExited(process, exitCode)
+	return &exitCode, out.String(), res.err
+}
+
+// update records the result of a check of the probe i.
+func (r *probeRunner) update(i int, p *configs.Probe, exitCode *int, output string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	st := &r.states[i]
+	st.LastRun = &now
+	st.ExitCode = exitCode
+	st.Output = output
+	st.Error = ""
+	if err != nil {
+		st.Error = err.Error()
+	}
+	prev := st.Status
+	if err == nil && exitCode != nil && *exitCode == 0 {
+		st.Failures = 0
+		st.Status = ProbePassing
+	} else {
+		st.Failures++
+		if st.Failures >= p.FailureThreshold {
+			st.Status = ProbeFailing
+		}
+	}
+	if err := r.save(); err != nil {
+		logrus.Warnf("unable to record probe state: %v", err)
+	}
+	if st.Status != prev {
+		logrus.Debugf("%s probe %v is %s", st.Type, st.Args, st.Status)
+		state := *st
+		r.c.publish(Event{Type: EventProbe, Time: now, Probe: &state})
+	}
+}
+
+// save writes the probe states to the container state directory. It must
+// be called with r.mu held, or before the probes are run.
+func (r *probeRunner) save() (retErr error) {
+	tmpFile, err := os.CreateTemp(r.c.stateDir, "probes-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if retErr != nil {
+			tmpFile.Close()
+			os.Remove(tmpFile.Name())
+		}
+	}()
+	if err := utils.WriteJSON(tmpFile, r.states); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), filepath.Join(r.c.stateDir, probesFilename))
+}
+
+// probeOutput keeps the beginning of the probe command output.
+type probeOutput struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (o *probeOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if n := probeOutputMax - o.buf.Len(); n > 0 {
+		o.buf.Write(p[:min(n, len(p))])
+	}
+	return len(p), nil
+}
+
+func (o *probeOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
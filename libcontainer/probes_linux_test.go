@@ -0,0 +1,70 @@
+package libcontainer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestProbeUpdate(t *testing.T) {
+	c := &Container{config: &configs.Config{}, stateDir: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := c.Subscribe(ctx)
+
+	probe := &configs.Probe{Type: configs.ProbeLiveness, Args: []string{"/check"}, FailureThreshold: 2}
+	r := &probeRunner{c: c, states: []ProbeState{{Type: probe.Type, Args: probe.Args, Status: ProbeUnknown}}}
+	ok, failed := 0, 1
+	for _, tc := range []struct {
+		exitCode *int
+		err      error
+		status   ProbeStatus
+		failures int
+		event    bool
+	}{
+		{exitCode: &failed, status: ProbeUnknown, failures: 1},
+		{exitCode: &ok, status: ProbePassing, event: true},
+		{exitCode: &failed, status: ProbePassing, failures: 1},
+		{err: errors.New("timed out"), status: ProbeFailing, failures: 2, event: true},
+		{exitCode: &failed, status: ProbeFailing, failures: 3},
+		{exitCode: &ok, status: ProbePassing, event: true},
+	} {
+		r.update(0, probe, tc.exitCode, "output", tc.err)
+
+		states, err := c.Probes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(states) != 1 || states[0].Status != tc.status || states[0].Failures != tc.failures {
+			t.Fatalf("expected status %s with %d failures, got %+v", tc.status, tc.failures, states)
+		}
+		if tc.err != nil && states[0].Error != tc.err.Error() {
+			t.Errorf("expected error %q, got %q", tc.err, states[0].Error)
+		}
+		if tc.event {
+			if ev := recvEvent(t, ch); ev.Type != EventProbe || ev.Probe.Status != tc.status {
+				t.Fatalf("expected %s probe event, got %+v", tc.status, ev)
+			}
+		}
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+}
+
+func TestProbeOutput(t *testing.T) {
+	var o probeOutput
+	for range 3 {
+		if n, err := o.Write([]byte(strings.Repeat("x", probeOutputMax/2+1))); err != nil || n != probeOutputMax/2+1 {
+			t.Fatalf("unexpected write result: %d, %v", n, err)
+		}
+	}
+	if n := len(o.String()); n != probeOutputMax {
+		t.Errorf("expected %d bytes of output, got %d", probeOutputMax, n)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"io"
 	"math"
 	"os"
+	"time"
+
+	"github.com/opencontainers/cgroups"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
 )
@@ -71,6 +74,11 @@ type Process struct {
 	// If not empty, takes precedence over container's [configs.Config.AppArmorProfile].
 	AppArmorProfile string
 
+	// Seccomp specifies the seccomp filter to apply to the process.
+	//
+	// If not nil, takes precedence over container's [configs.Config.Seccomp].
+	Seccomp *configs.Seccomp
+
 	// Label specifies the label to apply to the process. It is commonly used by selinux.
 	//
 	// If not empty, takes precedence over container's [configs.Config.ProcessLabel].
@@ -96,22 +104,50 @@ type Process struct {
 	// Init specifies whether the process is the first process in the container.
 	Init bool
 
+	// IDMapper, if set, is used to set up the user namespace mappings of
+	// the container, instead of writing them directly or running the
+	// newuidmap and newgidmap tools. It is only used for the init process.
+	IDMapper IDMapper
+
 	ops processOperations
 
+	// started is when the process was started, for the exec processes.
+	started time.Time
+
 	// LogLevel is a string containing a numeric representation of the current
 	// log level (i.e. "4", but never "info"). It is passed on to runc init as
 	// _LIBCONTAINER_LOGLEVEL environment variable.
 	LogLevel string
 
+	// CorrelationID, if set, is passed on to runc init as the
+	// _LIBCONTAINER_CORRELATIONID environment variable, for it to tag its
+	// log entries with it.
+	CorrelationID string
+
 	// SubCgroupPaths specifies sub-cgroups to run the process in.
 	// Map keys are controller names, map values are paths (relative to
-	// container's top-level cgroup).
+	// container's top-level cgroup, which is the root of the container
+	// cgroup namespace, so those can be absolute and can't go past it).
 	//
 	// If empty, the default top-level container's cgroup is used.
 	//
 	// For cgroup v2, the only key allowed is "".
 	SubCgroupPaths map[string]string
 
+	// CreateSubCgroups, if set, creates the SubCgroupPaths which do not
+	// exist. The new sub-cgroups inherit the controllers enabled for the
+	// children of their parent.
+	CreateSubCgroups bool
+
+	// SubCgroupResources, if set, runs the process in a transient sub-cgroup
+	// of the container cgroup, created with these resources (limiting the
+	// process on top of the container limits), and removed once the process
+	// has been waited for. It can't be used together with SubCgroupPaths.
+	//
+	// On cgroup v2, the container cgroup can't have processes of its own, as
+	// its controllers are then enabled for its sub-cgroups.
+	SubCgroupResources *cgroups.Resources
+
 	// Scheduler represents the scheduling attributes for a process.
 	//
 	// If not empty, takes precedence over container's [configs.Config.Scheduler].
@@ -122,7 +158,27 @@ type Process struct {
 	// If not empty, takes precedence over container's [configs.Config.IOPriority].
 	IOPriority *configs.IOPriority
 
+	// CPUAffinity is a non-init process CPU affinity.
+	//
+	// If not empty, takes precedence over container's [configs.Config.ExecCPUAffinity].
+	// The init process affinity is [configs.Config.InitCPUAffinity].
 	CPUAffinity *configs.CPUAffinity
+
+	// EnvDefaults is the policy of the default environment variables of the
+	// process.
+	//
+	// If not empty, takes precedence over container's [configs.Config.EnvDefaults].
+	EnvDefaults configs.EnvDefaults
+
+	// Readiness, if set, is a probe of the readiness of a non-init process,
+	// which [Container.Run] waits for before returning. If the process is
+	// not ready in time, it is killed, and Run returns an error.
+	Readiness *Readiness
+
+	// Progress, if set, is called with the progress of the long phases of
+	// the container creation, periodically and once each is complete. It
+	// is only used for the init process.
+	Progress func(Progress)
 }
 
 // Wait waits for the process to exit.
@@ -0,0 +1,186 @@
+package libcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/internal/userns"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+// processInfoNamespaces are the namespaces listed in [ProcessInfo].
+var processInfoNamespaces = []string{"cgroup", "ipc", "mnt", "net", "pid", "time", "user", "uts"}
+
+// ProcessInfo is the information about a container process, read from
+// /proc.
+type ProcessInfo struct {
+	Pid  int `json:"pid"`
+	PPid int `json:"ppid"`
+	// Cgroup is the path of the process cgroup in the cgroup v2 unified
+	// hierarchy, if any.
+	Cgroup string `json:"cgroup,omitempty"`
+	// CgroupsV1 are the paths of the process cgroups in the cgroup v1
+	// hierarchies, by controller.
+	CgroupsV1 map[string]string `json:"cgroups_v1,omitempty"`
+	// StartTime is the process start time, in clock ticks after the system
+	// boot.
+	StartTime uint64   `json:"starttime"`
+	Cmdline   []string `json:"cmdline"`
+	// UID and GID are the process effective user and group IDs.
+	UID ProcessID `json:"uid"`
+	GID ProcessID `json:"gid"`
+	// Namespaces are the inode numbers of the process namespaces, by type
+	// (as in /proc/<pid>/ns).
+	Namespaces map[string]uint64 `json:"namespaces"`
+}
+
+// ProcessID is a user or group ID of a process.
+type ProcessID struct {
+	// Host is the ID in the user namespace of runc.
+	Host int64 `json:"host"`
+	// Container is the ID in the user namespace of the process, or -1 if
+	// the host ID is not mapped in it.
+	Container int64 `json:"container"`
+}
+
+// ProcessesInfo returns the information about the container processes (see
+// [Container.Processes]). The processes which exit meanwhile are omitted.
+func (c *Container) ProcessesInfo() ([]ProcessInfo, error) {
+	pids, err := c.Processes()
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]ProcessInfo, 0, len(pids))
+	for _, pid := range pids {
+		info, err := readProcessInfo(pid)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) || errors.Is(err, unix.ESRCH) {
+				continue
+			}
+			return nil, fmt.Errorf("process %d: %w", pid, err)
+		}
+		infos = append(infos, *info)
+	}
+	return infos, nil
+}
+
+// readProcessInfo reads the information about the process pid from /proc.
+func readProcessInfo(pid int) (*ProcessInfo, error) {
+	dir := "/proc/" + strconv.Itoa(pid)
+	info := &ProcessInfo{Pid: pid, Namespaces: map[string]uint64{}}
+
+	stat, err := system.Stat(pid)
+	if err != nil {
+		return nil, err
+	}
+	info.StartTime = stat.StartTime
+
+	cmdline, err := os.ReadFile(dir + "/cmdline")
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range bytes.Split(bytes.TrimSuffix(cmdline, []byte{0}), []byte{0}) {
+		info.Cmdline = append(info.Cmdline, string(arg))
+	}
+
+	cgroupPaths, err := cgroups.ParseCgroupFile(dir + "/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	for ctrl, path := range cgroupPaths {
+		if ctrl == "" {
+			info.Cgroup = path
+			continue
+		}
+		if info.CgroupsV1 == nil {
+			info.CgroupsV1 = map[string]string{}
+		}
+		info.CgroupsV1[ctrl] = path
+	}
+
+	var uid, gid int64
+	if err := parseProcessStatus(dir+"/status", &info.PPid, &uid, &gid); err != nil {
+		return nil, err
+	}
+	uidMap, gidMap, err := userns.GetUserNamespaceMappings(dir + "/ns/user")
+	if err != nil {
+		return nil, err
+	}
+	info.UID = ProcessID{Host: uid, Container: containerID(uid, uidMap)}
+	info.GID = ProcessID{Host: gid, Container: containerID(gid, gidMap)}
+
+	for _, ns := range processInfoNamespaces {
+		var st unix.Stat_t
+		if err := unix.Stat(dir+"/ns/"+ns, &st); err != nil {
+			if errors.Is(err, unix.ENOENT) && ns == "time" {
+				// Not supported by the kernel.
+				continue
+			}
+			return nil, &os.PathError{Op: "stat", Path: dir + "/ns/" + ns, Err: err}
+		}
+		info.Namespaces[ns] = st.Ino
+	}
+	return info, nil
+}
+
+// parseProcessStatus gets the parent pid, and the effective user and group
+// IDs of a process from its /proc/<pid>/status file.
+func parseProcessStatus(path string, ppid *int, uid, gid *int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var found int
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, val, ok := strings.Cut(s.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(val)
+		switch {
+		case key == "PPid" && len(fields) == 1:
+			*ppid, err = strconv.Atoi(fields[0])
+		case key == "Uid" && len(fields) > 1:
+			*uid, err = strconv.ParseInt(fields[1], 10, 64)
+		case key == "Gid" && len(fields) > 1:
+			*gid, err = strconv.ParseInt(fields[1], 10, 64)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s in %s: %w", key, path, err)
+		}
+		found++
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	if found != 3 {
+		return fmt.Errorf("unable to parse %s", path)
+	}
+	return nil
+}
+
+// containerID returns the ID in a user namespace with the given mappings
+// (as read from /proc/<pid>/uid_map or gid_map) of the host ID id, or -1
+// if it is not mapped.
+func containerID(id int64, mappings []configs.IDMap) int64 {
+	for _, m := range mappings {
+		if id >= m.HostID && id < m.HostID+m.Size {
+			return m.ContainerID + id - m.HostID
+		}
+	}
+	return -1
+}
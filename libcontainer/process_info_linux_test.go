@@ -0,0 +1,43 @@
+package libcontainer
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestReadProcessInfo(t *testing.T) {
+	info, err := readProcessInfo(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.PPid != os.Getppid() {
+		t.Errorf("expected ppid %d, got %d", os.Getppid(), info.PPid)
+	}
+	if !slices.Equal(info.Cmdline, os.Args) {
+		t.Errorf("expected cmdline %q, got %q", os.Args, info.Cmdline)
+	}
+	if info.UID.Host != int64(os.Geteuid()) || info.GID.Host != int64(os.Getegid()) {
+		t.Errorf("expected uid %d and gid %d, got %+v and %+v", os.Geteuid(), os.Getegid(), info.UID, info.GID)
+	}
+	if info.StartTime == 0 || info.Namespaces["mnt"] == 0 || info.Namespaces["user"] == 0 {
+		t.Errorf("unexpected process info %+v", info)
+	}
+}
+
+func TestContainerID(t *testing.T) {
+	mappings := []configs.IDMap{{ContainerID: 0, HostID: 100000, Size: 1000}, {ContainerID: 1000, HostID: 1000, Size: 1}}
+	for _, tc := range []struct{ host, container int64 }{
+		{100000, 0},
+		{100999, 999},
+		{101000, -1},
+		{1000, 1000},
+		{0, -1},
+	} {
+		if id := containerID(tc.host, mappings); id != tc.container {
+			t.Errorf("host ID %d: expected %d, got %d", tc.host, tc.container, id)
+		}
+	}
+}
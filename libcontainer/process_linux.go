@@ -21,7 +21,9 @@ import (
 
 	"github.com/opencontainers/cgroups"
 	"github.com/opencontainers/cgroups/fs2"
+	"github.com/opencontainers/runc/internal/trace"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/faultinject"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
 	"github.com/opencontainers/runc/libcontainer/internal/userns"
 	"github.com/opencontainers/runc/libcontainer/logs"
@@ -157,10 +159,16 @@ type setnsProcess struct {
 	rootlessCgroups bool
 	intelRdtPath    string
 	initProcessPid  int
+	// coreSchedPid is the pid of the container init, whose core scheduling
+	// cookie the process is given (initProcessPid may be unset).
+	coreSchedPid int
+	// transient, if set, is the transient sub-cgroup the process is run in
+	// (see [Process.SubCgroupResources]), at cgroupPaths.
+	transient *transientCgroup
 }
 
-// Starts setns process with specified initial CPU affinity.
-func (p *setnsProcess) startWithCPUAffinity() error {
+// Starts the process with the specified initial CPU affinity.
+func (p *containerProcess) startWithCPUAffinity() error {
 	aff := p.config.CPUAffinity
 	if aff == nil || aff.Initial == nil {
 		return p.cmd.Start()
@@ -187,7 +195,31 @@ func (p *setnsProcess) startWithCPUAffinity() error {
 	return <-errCh
 }
 
-func (p *setnsProcess) setFinalCPUAffinity() error {
+// setupCoreSched gives the process the core scheduling cookie of the
+// container (see [configs.Config.SchedCore]): a new one if initPid is 0, or
+// else the one of the container init initPid. It is a no-op if SMT is not
+// present on the host.
+func (p *containerProcess) setupCoreSched(initPid int) error {
+	if !p.config.Config.SchedCore {
+		return nil
+	}
+	var err error
+	if initPid == 0 {
+		err = system.CreateCoreSchedCookie(p.pid())
+	} else {
+		err = system.ShareCoreSchedCookie(initPid, p.pid())
+	}
+	if errors.Is(err, unix.ENODEV) {
+		logrus.Debug("core scheduling: SMT is not present, skipping")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to set up core scheduling: %w", err)
+	}
+	return nil
+}
+
+func (p *containerProcess) setFinalCPUAffinity() error {
 	aff := p.config.CPUAffinity
 	if aff == nil || aff.Final == nil {
 		return nil
@@ -201,8 +233,22 @@ func (p *setnsProcess) setFinalCPUAffinity() error {
 func (p *setnsProcess) start() (retErr error) {
 	defer p.comm.closeParent()
 
+	if p.transient != nil {
+		if err := p.transient.create(p.cgroupPaths); err != nil {
+			return err
+		}
+		defer func() {
+			if retErr != nil {
+				p.transient.remove()
+			}
+		}()
+	}
+
 	// Get the "before" value of oom kill count.
 	oom, _ := p.manager.OOMKillCount()
+	// The nsexec handshake, up to the process joining the namespaces.
+	span := trace.Start("exec.bootstrap")
+	defer func() { span.End(retErr) }()
 	err := p.startWithCPUAffinity()
 	// Close the child-side of the pipes (controlled by child).
 	p.comm.closeChild()
@@ -231,6 +277,8 @@ func (p *setnsProcess) start() (retErr error) {
 	if err := p.execSetns(); err != nil {
 		return fmt.Errorf("error executing setns process: %w", err)
 	}
+	span.End(nil)
+	span = trace.Start("cgroup.join")
 	for _, path := range p.cgroupPaths {
 		if err := cgroups.WriteCgroupProc(path, p.pid()); err != nil && !p.rootlessCgroups {
 			// On cgroup v2 + nesting + domain controllers, WriteCgroupProc may fail with EBUSY.
@@ -265,9 +313,22 @@ func (p *setnsProcess) start() (retErr error) {
 			if err := intelrdt.WriteIntelRdtTasks(p.intelRdtPath, p.pid()); err != nil {
 				return fmt.Errorf("error adding pid %d to Intel RDT: %w", p.pid(), err)
 			}
+			if rdt := p.config.Config.IntelRdt; rdt != nil && rdt.Monitoring() {
+				monPath := intelrdt.MonGroupPath(p.intelRdtPath, p.container.ID())
+				if err := intelrdt.WriteIntelRdtTasks(monPath, p.pid()); err != nil {
+					return fmt.Errorf("error adding pid %d to Intel RDT monitoring group: %w", p.pid(), err)
+				}
+			}
 		}
 	}
+	if err := p.setupCoreSched(p.coreSchedPid); err != nil {
+		return err
+	}
 
+	span.End(nil)
+
+	// The process setup by runc init, up to the process being run.
+	span = trace.Start("exec.setup")
 	if err := utils.WriteJSON(p.comm.initSockParent, p.config); err != nil {
 		return fmt.Errorf("error writing config to pipe: %w", err)
 	}
@@ -317,7 +378,6 @@ func (p *setnsProcess) start() (retErr error) {
 				return err
 			}
 
-			bundle, annotations := utils.Annotations(p.config.Config.Labels)
 			containerProcessState := &specs.ContainerProcessState{
 				Version:  specs.Version,
 				Fds:      []string{specs.SeccompFdName},
@@ -328,8 +388,8 @@ func (p *setnsProcess) start() (retErr error) {
 					ID:          p.config.ContainerID,
 					Status:      specs.StateRunning,
 					Pid:         p.initProcessPid,
-					Bundle:      bundle,
-					Annotations: annotations,
+					Bundle:      p.config.Config.Bundle(),
+					Annotations: p.config.Config.Annotations,
 				},
 			}
 			if err := sendContainerProcessState(p.config.Config.Seccomp.ListenerPath,
@@ -395,14 +455,27 @@ func (p *setnsProcess) execSetns() error {
 type initProcess struct {
 	containerProcess
 	intelRdtManager *intelrdt.Manager
+	// nsFiles are the checked namespaces to join, kept open until runc
+	// init has joined them.
+	nsFiles []*os.File
 }
 
 // getChildPid receives the final child's pid over the provided pipe.
 func (p *initProcess) getChildPid() (int, error) {
 	var pid pid
-	if err := json.NewDecoder(p.comm.initSockParent).Decode(&pid); err != nil {
-		_ = p.cmd.Wait()
-		return -1, err
+	dec := json.NewDecoder(p.comm.initSockParent)
+	for {
+		if err := dec.Decode(&pid); err != nil {
+			_ = p.cmd.Wait()
+			return -1, err
+		}
+		if pid.UsermapPid == 0 {
+			break
+		}
+		if err := p.mapIDs(pid.UsermapPid); err != nil {
+			return -1, err
+		}
+		pid.UsermapPid = 0
 	}
 
 	// Clean up the zombie parent process
@@ -415,6 +488,21 @@ func (p *initProcess) getChildPid() (int, error) {
 	return pid.Pid, nil
 }
 
+// mapIDs sets up the user namespace mappings of the process pid using
+// the process IDMapper, and reports the result to runc init.
+func (p *initProcess) mapIDs(pid int) error {
+	ack := []byte{syncUsermapAck}
+	mapErr := p.process.IDMapper.MapIDs(pid, p.container.config.UIDMappings, p.container.config.GIDMappings)
+	if mapErr != nil {
+		ack[0] = 0
+		mapErr = fmt.Errorf("unable to set up user namespace mappings: %w", mapErr)
+	}
+	if _, err := p.comm.initSockParent.Write(ack); err != nil && mapErr == nil {
+		return fmt.Errorf("unable to send user namespace mappings result: %w", err)
+	}
+	return mapErr
+}
+
 func (p *initProcess) waitForChildExit(childPid int) error {
 	status, err := p.cmd.Process.Wait()
 	if err != nil {
@@ -541,7 +629,12 @@ func (p *initProcess) goCreateMountSources(ctx context.Context) (mountSourceRequ
 
 func (p *initProcess) start() (retErr error) {
 	defer p.comm.closeParent()
-	err := p.cmd.Start()
+	defer closeFiles(p.nsFiles)
+	// The nsexec handshake, up to the container init being in its
+	// namespaces.
+	span := trace.Start("init.bootstrap")
+	defer func() { span.End(retErr) }()
+	err := p.startWithCPUAffinity()
 	p.process.ops = p
 	// close the child-side of the pipes (controlled by child)
 	p.comm.closeChild()
@@ -591,17 +684,37 @@ func (p *initProcess) start() (retErr error) {
 				logrus.WithError(err).Warn("unable to terminate initProcess")
 			}
 
-			_ = p.manager.Destroy()
+			err = p.manager.Destroy()
 			if p.intelRdtManager != nil {
-				_ = p.intelRdtManager.Destroy()
+				if rdtErr := p.intelRdtManager.Destroy(); err == nil {
+					err = rdtErr
+				}
 			}
+			// Only release the host resources once the container
+			// processes are gone, see destroy.
+			if err == nil {
+				p.container.teardownSwap()
+				p.container.teardownRootfsQuota()
+				p.container.restorePowerHint()
+				p.container.restoreIOCost()
+				p.container.restoreIRQAffinity()
+			} else {
+				logrus.WithError(err).Warn("unable to remove the container cgroup, keeping its host resources")
+			}
+			p.container.killAsyncHooks()
 		}
 	}()
 
 	// Do this before syncing with child so that no children can escape the
 	// cgroup. We don't need to worry about not doing this and not being root
 	// because we'd be using the rootless cgroup manager in that case.
-	if err := p.manager.Apply(p.pid()); err != nil {
+	cgroupSpan := trace.Start("cgroup.apply")
+	err = faultinject.Check(faultinject.CgroupApply)
+	if err == nil {
+		err = p.manager.Apply(p.pid())
+	}
+	cgroupSpan.End(err)
+	if err != nil {
 		if errors.Is(err, cgroups.ErrRootless) {
 			// ErrRootless is to be ignored except when
 			// the container doesn't have private pidns.
@@ -620,6 +733,12 @@ func (p *initProcess) start() (retErr error) {
 			return fmt.Errorf("unable to apply Intel RDT configuration: %w", err)
 		}
 	}
+	if err := p.container.setupSwap(); err != nil {
+		return fmt.Errorf("unable to set up swap: %w", err)
+	}
+	if err := p.container.setupRootfsQuota(p.process.Progress); err != nil {
+		return fmt.Errorf("unable to set up root filesystem quota: %w", err)
+	}
 	if _, err := io.Copy(p.comm.initSockParent, p.bootstrapData); err != nil {
 		return fmt.Errorf("can't copy bootstrap data to pipe: %w", err)
 	}
@@ -642,6 +761,9 @@ func (p *initProcess) start() (retErr error) {
 	if err := p.waitForChildExit(childPid); err != nil {
 		return fmt.Errorf("error waiting for our first child to exit: %w", err)
 	}
+	span.End(nil)
+	// The container setup by runc init, up to the container being created.
+	span = trace.Start("init.setup")
 
 	// Spin up a goroutine to handle remapping mount requests by runc init.
 	// There is no point doing this for rootless containers because they cannot
@@ -663,6 +785,14 @@ func (p *initProcess) start() (retErr error) {
 		return fmt.Errorf("error creating network interfaces: %w", err)
 	}
 
+	if p.config.Config.KeepNetns {
+		if err := p.container.keepNetns(p.pid()); err != nil {
+			return fmt.Errorf("unable to keep network namespace: %w", err)
+		}
+	}
+	if err := p.setupCoreSched(0); err != nil {
+		return err
+	}
 	if err := p.setupNetworkDevices(); err != nil {
 		return fmt.Errorf("error creating network interfaces: %w", err)
 	}
@@ -679,6 +809,9 @@ func (p *initProcess) start() (retErr error) {
 	if err := utils.WriteJSON(p.comm.initSockParent, p.config); err != nil {
 		return fmt.Errorf("error sending config to init process: %w", err)
 	}
+	// runc init sets up the rootfs, and then asks for the hooks to be run.
+	rootfsSpan := trace.Start("rootfs.setup")
+	defer func() { rootfsSpan.End(retErr) }()
 
 	var seenProcReady bool
 	ierr := parseSync(p.comm.syncSockParent, func(sync *syncT) error {
@@ -763,6 +896,28 @@ func (p *initProcess) start() (retErr error) {
 				return fmt.Errorf("error setting rlimits for ready process: %w", err)
 			}
 
+			// The cgroup configuration is applied by now, see procHooks.
+			p.container.skippedResources = skippedCgroupResources(p.config.Config, p.manager)
+			if len(p.container.skippedResources) > 0 {
+				logrus.WithField("resources", p.container.skippedResources).
+					Warn("rootless cgroups: some cgroup resources are not applied due to a lack of permissions")
+			}
+			if err := checkStrictSpec(p.config.Config, p.manager, p.container.skippedResources); err != nil {
+				return err
+			}
+			// Set final CPU affinity after the cgroup configuration, as
+			// setting the cpuset resets the affinity of its processes.
+			if err := p.setFinalCPUAffinity(); err != nil {
+				return err
+			}
+			if p.config.Config.IRQAffinity {
+				saved, err := steerIRQs(p.pid(), p.config.Config.Cgroups.Resources.CpusetCpus)
+				if err != nil {
+					return fmt.Errorf("error steering network device interrupts: %w", err)
+				}
+				p.container.irqAffinity = saved
+			}
+
 			// generate a timestamp indicating when the container was started
 			p.container.created = time.Now().UTC()
 			p.container.state = &createdState{
@@ -789,14 +944,13 @@ func (p *initProcess) start() (retErr error) {
 				return err
 			}
 		case procHooks:
+			rootfsSpan.End(nil)
 			// Setup cgroup before prestart hook, so that the prestart hook could apply cgroup permissions.
-			if err := p.manager.Set(p.config.Config.Cgroups.Resources); err != nil {
-				return fmt.Errorf("error setting cgroup config for procHooks process: %w", err)
-			}
-			if p.intelRdtManager != nil {
-				if err := p.intelRdtManager.Set(p.config.Config); err != nil {
-					return fmt.Errorf("error setting Intel RDT config for procHooks process: %w", err)
-				}
+			cgroupSpan := trace.Start("cgroup.set")
+			err := p.container.applyResources(nil, p.config.Config)
+			cgroupSpan.End(err)
+			if err != nil {
+				return fmt.Errorf("error setting resources for procHooks process: %w", err)
 			}
 			if p.config.Config.HasHook(configs.Prestart, configs.CreateRuntime) {
 				s, err := p.container.currentOCIState()
@@ -806,12 +960,13 @@ func (p *initProcess) start() (retErr error) {
 				// initProcessStartTime hasn't been set yet.
 				s.Pid = p.cmd.Process.Pid
 				s.Status = specs.StateCreating
-				hooks := p.config.Config.Hooks
-
-				if err := hooks.Run(configs.Prestart, s); err != nil {
-					return err
+				progress := trackProgress(p.process.Progress, "hooks", nil, nil)
+				err = p.container.runHooks(configs.Prestart, s)
+				if err == nil {
+					err = p.container.runHooks(configs.CreateRuntime, s)
 				}
-				if err := hooks.Run(configs.CreateRuntime, s); err != nil {
+				progress.end(err)
+				if err != nil {
 					return err
 				}
 			}
@@ -834,7 +989,15 @@ func (p *initProcess) start() (retErr error) {
 	if ierr != nil {
 		return fmt.Errorf("error during container init: %w", ierr)
 	}
-	return nil
+	// The container is only created once the asynchronous prestart and
+	// createRuntime hooks are ready.
+	if len(p.container.asyncHooks) == 0 {
+		return nil
+	}
+	progress := trackProgress(p.process.Progress, "async-hooks", nil, nil)
+	err = p.container.waitAsyncHooks()
+	progress.end(err)
+	return err
 }
 
 func (p *initProcess) createNetworkInterfaces() error {
@@ -869,10 +1032,21 @@ func (p *initProcess) setupNetworkDevices() error {
 	// that were successfully moved before the failure occurred.
 	// See: https://github.com/opencontainers/runtime-spec/blob/27cb0027fd92ef81eda1ea3a8153b8337f56d94a/config-linux.md#namespace-lifecycle-and-container-termination
 	for name, netDevice := range p.config.Config.NetDevices {
-		err := devChangeNetNamespace(name, nsPath, *netDevice)
+		if p.config.Config.KeepNetns {
+			st, err := keptNetDevice(name, nsPath, *netDevice)
+			if err != nil {
+				return err
+			}
+			if st != nil {
+				p.container.netDevices = append(p.container.netDevices, *st)
+				continue
+			}
+		}
+		st, err := devChangeNetNamespace(name, nsPath, *netDevice)
 		if err != nil {
 			return fmt.Errorf("move netDevice %s to namespace %s: %w", name, nsPath, err)
 		}
+		p.container.netDevices = append(p.container.netDevices, *st)
 	}
 
 	return nil
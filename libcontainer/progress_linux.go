@@ -0,0 +1,175 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress is a progress record of a long operation (checkpoint, restore,
+// or container creation), reported periodically to the Progress callback
+// of [CriuOpts] or [Process].
+type Progress struct {
+	// Phase is the phase of the operation, such as "dump", "restore", or
+	// "rootfs-quota".
+	Phase string
+	// Bytes is the number of bytes processed so far in the phase, if known.
+	Bytes uint64
+	// TotalBytes is the estimated number of bytes to process in the phase,
+	// or 0 if unknown.
+	TotalBytes uint64
+	// Done tells that the phase is complete.
+	Done bool
+}
+
+// Percent returns the estimated completion percentage of the phase, or -1
+// if unknown. It is only 100 once the phase is done, as the total is an
+// estimate.
+func (p Progress) Percent() int {
+	switch {
+	case p.Done:
+		return 100
+	case p.TotalBytes == 0:
+		return -1
+	case p.Bytes >= p.TotalBytes:
+		return 99
+	}
+	return min(int(p.Bytes*100/p.TotalBytes), 99)
+}
+
+// progressInterval is the interval of the periodic progress records.
+var progressInterval = time.Second
+
+// progressTracker reports the progress of a phase to a Progress callback,
+// periodically until it is stopped.
+type progressTracker struct {
+	report func(Progress)
+	phase  string
+	total  func() uint64
+	bytes  func() uint64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// trackProgress reports the progress of phase to report right away, then
+// periodically until [progressTracker.end] is called. The total and bytes
+// functions, either of which may be nil, return the estimated total and
+// the processed number of bytes. It is a no-op if report is nil.
+func trackProgress(report func(Progress), phase string, total, bytes func() uint64) *progressTracker {
+	t := &progressTracker{
+		report: report,
+		phase:  phase,
+		total:  total,
+		bytes:  bytes,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if report == nil {
+		close(t.done)
+		return t
+	}
+	t.send(false)
+	go func() {
+		defer close(t.done)
+		tick := time.NewTicker(progressInterval)
+		defer tick.Stop()
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-tick.C:
+				t.send(false)
+			}
+		}
+	}()
+	return t
+}
+
+func (t *progressTracker) send(done bool) {
+	p := Progress{Phase: t.phase, Done: done}
+	if t.total != nil {
+		p.TotalBytes = t.total()
+	}
+	if t.bytes != nil {
+		p.Bytes = t.bytes()
+	}
+	t.report(p)
+}
+
+// end stops the periodic progress records, and reports the phase as done
+// if err is nil.
+func (t *progressTracker) end(err error) {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+		<-t.done
+		if t.report != nil && err == nil {
+			t.send(true)
+		}
+	})
+}
+
+// dirSize returns the total size of the regular files of dir (but not of
+// its subdirectories).
+func dirSize(dir string) uint64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	var size uint64
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if fi, err := e.Info(); err == nil {
+			size += uint64(fi.Size())
+		}
+	}
+	return size
+}
+
+// criuPagesSize returns the total size of the memory pages of the CRIU
+// images of dir, and of its parent images (for an incremental dump).
+func criuPagesSize(dir string) uint64 {
+	var size uint64
+	// Bound the chain of the parent images, in case of a loop.
+	for range 100 {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			break
+		}
+		for _, e := range entries {
+			if !e.Type().IsRegular() || !strings.HasPrefix(e.Name(), "pages-") || !strings.HasSuffix(e.Name(), ".img") {
+				continue
+			}
+			if fi, err := e.Info(); err == nil {
+				size += uint64(fi.Size())
+			}
+		}
+		parent, err := os.Readlink(filepath.Join(dir, "parent"))
+		if err != nil {
+			break
+		}
+		if !filepath.IsAbs(parent) {
+			parent = filepath.Join(dir, parent)
+		}
+		dir = parent
+	}
+	return size
+}
+
+// memoryUsage returns the memory usage of the container cgroup, or 0 if
+// unknown.
+func (c *Container) memoryUsage() uint64 {
+	if c.cgroupManager == nil {
+		return 0
+	}
+	stats, err := c.cgroupManager.GetStats()
+	if err != nil {
+		return 0
+	}
+	return stats.MemoryStats.Usage.Usage
+}
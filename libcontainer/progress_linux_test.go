@@ -0,0 +1,116 @@
+package libcontainer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgressPercent(t *testing.T) {
+	for _, tc := range []struct {
+		p   Progress
+		pct int
+	}{
+		{Progress{}, -1},
+		{Progress{Bytes: 10}, -1},
+		{Progress{Bytes: 10, TotalBytes: 40}, 25},
+		{Progress{Bytes: 40, TotalBytes: 40}, 99},
+		{Progress{Bytes: 50, TotalBytes: 40}, 99},
+		{Progress{Bytes: 10, TotalBytes: 40, Done: true}, 100},
+		{Progress{Done: true}, 100},
+	} {
+		if pct := tc.p.Percent(); pct != tc.pct {
+			t.Errorf("%+v: expected %d%%, got %d%%", tc.p, tc.pct, pct)
+		}
+	}
+}
+
+func TestTrackProgress(t *testing.T) {
+	defer func(i time.Duration) { progressInterval = i }(progressInterval)
+	progressInterval = 10 * time.Millisecond
+
+	var (
+		mu      sync.Mutex
+		records []Progress
+	)
+	report := func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		records = append(records, p)
+	}
+	var n uint64
+	tr := trackProgress(report, "test", func() uint64 { return 100 }, func() uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		n += 10
+		return n
+	})
+	time.Sleep(5 * progressInterval)
+	tr.end(nil)
+	// A second end is a no-op.
+	tr.end(nil)
+
+	if len(records) < 3 {
+		t.Fatalf("expected at least 3 records, got %+v", records)
+	}
+	for i, p := range records {
+		if p.Phase != "test" || p.TotalBytes != 100 {
+			t.Errorf("unexpected record %+v", p)
+		}
+		if last := i == len(records)-1; p.Done != last {
+			t.Errorf("record %d: expected done to be %v, got %+v", i, last, p)
+		}
+		if i > 0 && p.Bytes <= records[i-1].Bytes {
+			t.Errorf("record %d: expected the bytes to increase, got %+v", i, records)
+		}
+	}
+
+	// A failed phase is not reported as done.
+	records = nil
+	trackProgress(report, "test", nil, nil).end(errors.New("failed"))
+	if len(records) != 1 || records[0].Done {
+		t.Errorf("expected a single record not done, got %+v", records)
+	}
+
+	// Nothing is reported without a callback.
+	trackProgress(nil, "test", nil, nil).end(nil)
+}
+
+func TestCriuPagesSize(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "pre-dump-1"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	write("pages-1.img", 100)
+	write("pages-2.img", 20)
+	write("pagemap-1.img", 1000)
+	write("pre-dump-1/pages-1.img", 3)
+	// The parent image path is relative to the images directory.
+	if err := os.Symlink("pre-dump-1", filepath.Join(dir, "parent")); err != nil {
+		t.Fatal(err)
+	}
+	// A loop of parent images.
+	if err := os.Symlink("..", filepath.Join(dir, "pre-dump-1", "parent")); err != nil {
+		t.Fatal(err)
+	}
+	// The chain is bounded to 100 images.
+	if size := criuPagesSize(filepath.Join(dir, "pre-dump-1")); size != 50*(3+120) {
+		t.Errorf("expected the size of 100 images, got %d", size)
+	}
+	os.Remove(filepath.Join(dir, "pre-dump-1", "parent"))
+	if size := criuPagesSize(dir); size != 120+3 {
+		t.Errorf("expected 123, got %d", size)
+	}
+	if size := dirSize(dir); size != 1120 {
+		t.Errorf("expected the directory size to be 1120, got %d", size)
+	}
+}
@@ -0,0 +1,254 @@
+package libcontainer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// PropagationState is the mount propagation state of a shared bind mount of
+// the container, see [Container.WatchPropagation].
+type PropagationState struct {
+	// Destination is the mount point in the container, and Source is the
+	// host path bind-mounted on it.
+	Destination string
+	Source      string
+	// Broken is whether the mount events of the source no longer propagate
+	// to the container mount, and Reason is why.
+	Broken bool
+	Reason string
+	// Remediated is whether the propagation has been re-established, by
+	// bind-mounting the source over the destination again. Err is why it
+	// could not be.
+	Remediated bool
+	Err        error
+
+	// sourceShared is whether the source mount is shared, which is
+	// required to remediate.
+	sourceShared bool
+}
+
+// WatchPropagation checks, every interval until ctx is done or the container
+// init exits, that the mount events of the sources of the container bind
+// mounts marked shared (such as with the rshared option) propagate to the
+// container mounts, that is, that the container mount is either a peer or a
+// slave of the source mount. This is no longer the case once the propagation
+// of either mount is changed (e.g. when the source is remounted private on
+// the host).
+//
+// An EventPropagation event is sent whenever a mount propagation breaks, or
+// is working again. If remediate is set, a broken propagation is
+// re-established (with an EventPropagation event sent each time) by
+// bind-mounting the source over the destination again, in the container
+// mount namespace, which only works if the source mount is still shared.
+//
+// It returns right away if the container has no shared bind mounts.
+func (c *Container) WatchPropagation(ctx context.Context, interval time.Duration, remediate bool) error {
+	c.m.Lock()
+	mounts := sharedBindMounts(c.config)
+	running := c.hasInit()
+	var pid int
+	if running {
+		pid = c.initProcess.pid()
+	}
+	c.m.Unlock()
+	if len(mounts) == 0 {
+		return nil
+	}
+	if !running {
+		return ErrNotRunning
+	}
+
+	// The last outcome reported for each mount, "" being a working (or
+	// remediated) propagation.
+	reported := make(map[string]string)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		states, err := checkPropagation(pid, mounts)
+		if err != nil {
+			return err
+		}
+		for i, st := range states {
+			if st.Broken && remediate {
+				if st.sourceShared {
+					st.Err = c.remediatePropagation(pid, mounts[i])
+				} else {
+					st.Err = errors.New("the source mount must be shared")
+				}
+				st.Remediated = st.Err == nil
+			}
+			outcome := st.Reason
+			if st.Err != nil {
+				outcome += ": " + st.Err.Error()
+			}
+			if st.Remediated {
+				outcome = ""
+			} else if outcome == reported[st.Destination] {
+				continue
+			}
+			reported[st.Destination] = outcome
+			c.publish(Event{Type: EventPropagation, Pid: pid, Propagation: &st})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		c.m.Lock()
+		running = c.hasInit()
+		c.m.Unlock()
+		if !running {
+			return nil
+		}
+	}
+}
+
+// sharedBindMounts returns the bind mounts of config which are marked
+// shared.
+func sharedBindMounts(config *configs.Config) []*configs.Mount {
+	var mounts []*configs.Mount
+	for _, m := range config.Mounts {
+		if m.IsBind() && slices.ContainsFunc(m.PropagationFlags, func(f int) bool { return f&unix.MS_SHARED != 0 }) {
+			mounts = append(mounts, m)
+		}
+	}
+	return mounts
+}
+
+// checkPropagation returns the propagation states of mounts, in the mount
+// namespace of the process pid.
+func checkPropagation(pid int, mounts []*configs.Mount) ([]PropagationState, error) {
+	host, err := mountinfo.GetMounts(nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read mount table: %w", err)
+	}
+	// The mount points are relative to the root of the process.
+	ctr, err := mountinfo.PidMountInfo(pid)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read container mount table: %w", err)
+	}
+	states := make([]PropagationState, 0, len(mounts))
+	for _, m := range mounts {
+		st := PropagationState{Destination: m.Destination, Source: m.Source}
+		st.Reason, st.sourceShared = propagationBroken(host, ctr, m)
+		st.Broken = st.Reason != ""
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+// propagationBroken returns why the mount events of the source of m do not
+// propagate to m, according to the host and the container mount tables, or
+// "" if they do, and whether the source mount is shared.
+func propagationBroken(host, ctr []*mountinfo.Info, m *configs.Mount) (string, bool) {
+	source := m.Source
+	if real, err := filepath.EvalSymlinks(source); err == nil {
+		source = real
+	}
+	src := containingMount(host, source)
+	if src == nil {
+		return "source mount not found", false
+	}
+	group := peerGroup(src, "shared")
+	if group == "" {
+		return "source mount " + src.Mountpoint + " is not shared", false
+	}
+	dst := topMount(ctr, m.Destination)
+	if dst == nil {
+		return "not mounted", true
+	}
+	if peerGroup(dst, "shared") != group && peerGroup(dst, "master") != group {
+		return "neither a peer nor a slave of source mount " + src.Mountpoint + " (peer group " + group + ")", true
+	}
+	return "", true
+}
+
+// containingMount returns the topmost mount of mounts which path is on.
+func containingMount(mounts []*mountinfo.Info, path string) *mountinfo.Info {
+	var top *mountinfo.Info
+	for _, m := range mounts {
+		if m.Mountpoint != "/" && m.Mountpoint != path && !strings.HasPrefix(path, m.Mountpoint+"/") {
+			continue
+		}
+		if top == nil || len(m.Mountpoint) >= len(top.Mountpoint) {
+			top = m
+		}
+	}
+	return top
+}
+
+// peerGroup returns the ID of the peer group of m (for the "shared" tag),
+// or of its master (for the "master" tag), or "" if it has none.
+func peerGroup(m *mountinfo.Info, tag string) string {
+	for _, opt := range strings.Fields(m.Optional) {
+		if id, ok := strings.CutPrefix(opt, tag+":"); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// remediatePropagation bind-mounts the source of m (which is cloned in the
+// runtime mount namespace, so that the clone is a peer of the source mount)
+// over its destination, in the mount namespace of the process pid. As when
+// the container is created, the new mount is then made a slave of the source
+// mount (unless the rootfs propagation is shared), before the propagation
+// flags of m are applied.
+func (c *Container) remediatePropagation(pid int, m *configs.Mount) error {
+	if c.config.Namespaces.Contains(configs.NEWUSER) {
+		return errors.New("not supported with user namespaces")
+	}
+	fd, err := unix.OpenTree(unix.AT_FDCWD, m.Source, unix.OPEN_TREE_CLONE|unix.OPEN_TREE_CLOEXEC|unix.AT_RECURSIVE)
+	if err != nil {
+		return &os.PathError{Op: "open_tree", Path: m.Source, Err: err}
+	}
+	flags := []int{unix.MS_SLAVE | unix.MS_REC}
+	if c.config.RootPropagation != 0 {
+		flags[0] = c.config.RootPropagation
+	}
+	flags = append(flags, m.PropagationFlags...)
+	defer unix.Close(fd)
+
+	errCh := make(chan error, 1)
+	go func() {
+		// There is no UnlockOSThread, so that the thread (which joins the
+		// container mount namespace) is terminated once done.
+		runtime.LockOSThread()
+		if err := unix.Unshare(unix.CLONE_FS); err != nil {
+			errCh <- os.NewSyscallError("unshare(CLONE_FS)", err)
+			return
+		}
+		if err := setns(pid, "mnt", unix.CLONE_NEWNS); err != nil {
+			errCh <- err
+			return
+		}
+		// Joining the mount namespace sets the root to the container one,
+		// so that the destination is resolved in the container.
+		dest := m.Destination
+		if err := unix.MoveMount(fd, "", unix.AT_FDCWD, dest, unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+			errCh <- &os.PathError{Op: "move_mount", Path: dest, Err: err}
+			return
+		}
+		for _, flag := range flags {
+			if err := mount("", dest, "", uintptr(flag), ""); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- nil
+	}()
+	return <-errCh
+}
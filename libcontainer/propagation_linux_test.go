@@ -0,0 +1,42 @@
+package libcontainer
+
+import (
+	"testing"
+
+	"github.com/moby/sys/mountinfo"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestPropagationBroken(t *testing.T) {
+	host := []*mountinfo.Info{
+		{Mountpoint: "/", Optional: "shared:1"},
+		{Mountpoint: "/srv", Optional: "shared:7"},
+		{Mountpoint: "/srv/private"},
+	}
+	for _, tc := range []struct {
+		name, source, optional string
+		broken, shared         bool
+	}{
+		{name: "peer", source: "/srv/vol", optional: "shared:7", shared: true},
+		{name: "slave", source: "/srv/vol", optional: "shared:9 master:7", shared: true},
+		{name: "other peer group", source: "/srv/vol", optional: "shared:9 master:1", broken: true, shared: true},
+		{name: "private", source: "/srv/vol", broken: true, shared: true},
+		{name: "private source", source: "/srv/private/vol", optional: "master:7", broken: true},
+		{name: "not mounted", source: "/srv/vol", broken: true, shared: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctr := []*mountinfo.Info{{Mountpoint: "/", Optional: "master:1"}}
+			if tc.name != "not mounted" {
+				ctr = append(ctr, &mountinfo.Info{Mountpoint: "/data", Optional: tc.optional})
+			}
+			reason, shared := propagationBroken(host, ctr, &configs.Mount{Source: tc.source, Destination: "/data"})
+			if (reason != "") != tc.broken {
+				t.Errorf("expected broken: %v, got reason %q", tc.broken, reason)
+			}
+			if shared != tc.shared {
+				t.Errorf("expected source shared: %v, got %v", tc.shared, shared)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package libcontainer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+const (
+	// DefaultReadinessTimeout is the default [Readiness.Timeout].
+	DefaultReadinessTimeout = 30 * time.Second
+	// DefaultReadinessInterval is the default [Readiness.Interval].
+	DefaultReadinessInterval = 100 * time.Millisecond
+)
+
+// Readiness is a probe of the readiness of an exec process, see
+// [Process.Readiness]. The process is ready once all the checks which are
+// set pass.
+type Readiness struct {
+	// File is the path, in the container, of a file which exists once the
+	// process is ready.
+	File string
+
+	// Port is a TCP port which is listened on, in the container network
+	// namespace, once the process is ready.
+	Port int
+
+	// Args is a command line, run in the container as the process user and
+	// with its environment, which exits with status 0 once the process is
+	// ready.
+	Args []string
+
+	// Timeout is how long to wait for the process to be ready. If zero,
+	// DefaultReadinessTimeout is used.
+	Timeout time.Duration
+
+	// Interval is the time between two checks. If zero,
+	// DefaultReadinessInterval is used.
+	Interval time.Duration
+}
+
+// Validate checks that r has at least one check, and valid values.
+func (r *Readiness) Validate() error {
+	if r.File == "" && r.Port == 0 && len(r.Args) == 0 {
+		return errors.New("readiness probe has no check")
+	}
+	if r.Port < 0 || r.Port > 65535 {
+		return fmt.Errorf("invalid readiness port %d", r.Port)
+	}
+	if r.Timeout < 0 || r.Interval < 0 {
+		return errors.New("readiness timeout and interval must not be negative")
+	}
+	return nil
+}
+
+// waitReady waits for the started exec process p to be ready, as probed by
+// p.Readiness. If the process exits before, or is not ready in time, it is
+// killed, and an error is returned.
+func (c *Container) waitReady(p *Process) error {
+	r := p.Readiness
+	timeout, interval := r.Timeout, r.Interval
+	if timeout == 0 {
+		timeout = DefaultReadinessTimeout
+	}
+	if interval == 0 {
+		interval = DefaultReadinessInterval
+	}
+	pid, err := p.Pid()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		ready, err := c.checkReady(p, pid, deadline)
+		if err != nil {
+			return c.notReady(p, err)
+		}
+		if ready {
+			return nil
+		}
+		if stat, err := system.Stat(pid); err != nil || stat.State == system.Zombie || stat.State == system.Dead {
+			return c.notReady(p, errors.New("process exited before it was ready"))
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return c.notReady(p, fmt.Errorf("process not ready after %s", timeout))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// notReady kills the exec process p which is not ready, and returns err.
+func (c *Container) notReady(p *Process, err error) error {
+	_ = p.Signal(unix.SIGKILL)
+	if _, werr := p.Wait(); werr != nil {
+		logrus.Debugf("exec process not ready: %v", werr)
+	}
+	return err
+}
+
+// checkReady runs the readiness checks of the exec process p once, and
+// returns whether they all pass.
+func (c *Container) checkReady(p *Process, pid int, deadline time.Time) (bool, error) {
+	r := p.Readiness
+	if r.File != "" {
+		path, err := securejoin.SecureJoin("/proc/"+strconv.Itoa(pid)+"/root", r.File)
+		if err != nil {
+			return false, err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return false, nil
+		}
+	}
+	if r.Port != 0 {
+		ok, err := isListening(pid, r.Port)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if len(r.Args) != 0 {
+		return c.runReadinessCommand(p, time.Until(deadline))
+	}
+	return true, nil
+}
+
+// runReadinessCommand runs the readiness command line of the exec process
+// p, and returns whether it exits with status 0 within timeout.
+func (c *Container) runReadinessCommand(p *Process, timeout time.Duration) (bool, error) {
+	process := &Process{
+		Args:             p.Readiness.Args,
+		Env:              p.Env,
+		UID:              p.UID,
+		GID:              p.GID,
+		AdditionalGroups: p.AdditionalGroups,
+		Cwd:              p.Cwd,
+		Capabilities:     p.Capabilities,
+		AppArmorProfile:  p.AppArmorProfile,
+		Label:            p.Label,
+		NoNewPrivileges:  p.NoNewPrivileges,
+		EnvDefaults:      p.EnvDefaults,
+		LogLevel:         p.LogLevel,
+		CorrelationID:    p.CorrelationID,
+	}
+	out := &probeOutput{}
+	process.Stdout = out
+	process.Stderr = out
+	if err := c.Run(process); err != nil {
+		return false, fmt.Errorf("unable to run readiness command: %w", err)
+	}
+	done := make(chan *os.ProcessState, 1)
+	go func() {
+		state, err := process.Wait()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			state = exitErr.ProcessState
+		}
+		done <- state
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var state *os.ProcessState
+	select {
+	case state = <-done:
+	case <-timer.C:
+		_ = process.Signal(unix.SIGKILL)
+		<-done
+		return false, nil
+	}
+	if state == nil {
+		return false, nil
+	}
+	exitCode := utils.ExitStatus(unix.WaitStatus(state.Sys().(syscall.WaitStatus)))
+	c.ExecExited(process, exitCode)
+	if exitCode != 0 {
+		logrus.Debugf("readiness command %v exited with status %d: %s", process.Args, exitCode, out.String())
+	}
+	return exitCode == 0, nil
+}
+
+// isListening returns whether a TCP socket listens on port in the network
+// namespace of pid.
+func isListening(pid, port int) (bool, error) {
+	for _, file := range []string{"tcp", "tcp6"} {
+		ok, err := readListening("/proc/"+strconv.Itoa(pid)+"/net/"+file, port)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tcpListen is the state of a listening socket in /proc/net/tcp.
+const tcpListen = "0A"
+
+// readListening returns whether a socket of the /proc/net/tcp format file
+// path is listening on port.
+func readListening(path string, port int) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	// Skip the header.
+	s.Scan()
+	for s.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 || fields[3] != tcpListen {
+			continue
+		}
+		_, hexPort, ok := strings.Cut(fields[1], ":")
+		if !ok {
+			continue
+		}
+		if p, err := strconv.ParseUint(hexPort, 16, 16); err == nil && int(p) == port {
+			return true, nil
+		}
+	}
+	return false, s.Err()
+}
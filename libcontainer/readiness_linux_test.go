@@ -0,0 +1,60 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadListening(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tcp")
+	data := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0050 0100007F:A1B2 01 00000000:00000000 00:00000000 00000000     0        0 2 1 0000000000000000 20 4 30 10 -1
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		port int
+		ok   bool
+	}{
+		{port: 8080, ok: true},
+		// Established, not listening.
+		{port: 80},
+		{port: 443},
+	} {
+		ok, err := readListening(path, tc.port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tc.ok {
+			t.Errorf("port %d: expected %v, got %v", tc.port, tc.ok, ok)
+		}
+	}
+}
+
+func TestReadinessValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		isErr bool
+		r     Readiness
+	}{
+		{name: "file", r: Readiness{File: "/ready"}},
+		{name: "all", r: Readiness{File: "/ready", Port: 80, Args: []string{"true"}, Timeout: time.Second}},
+		{name: "none", isErr: true, r: Readiness{Timeout: time.Second}},
+		{name: "port", isErr: true, r: Readiness{Port: 65536}},
+		{name: "timeout", isErr: true, r: Readiness{File: "/ready", Timeout: -time.Second}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.r.Validate()
+			if tc.isErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tc.isErr && err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/moby/sys/mountinfo"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// checkReadonly verifies that all the mounts requested to be read-only
+// actually are, according to config.ReadonlyCheck. It must be called from
+// the container mount namespace and root, after the rootfs is finalized and
+// ReadonlyPaths are applied.
+func checkReadonly(config *configs.Config) error {
+	if config.ReadonlyCheck == "" {
+		return nil
+	}
+	mounts, err := mountinfo.GetMounts(nil)
+	if err != nil {
+		return fmt.Errorf("unable to read mount table: %w", err)
+	}
+	bad := readonlyViolations(config, mounts, os.Stat)
+	if len(bad) == 0 {
+		return nil
+	}
+	if config.ReadonlyCheck == configs.ReadonlyCheckFail {
+		return fmt.Errorf("mounts requested to be read-only are writable: %s", strings.Join(bad, ", "))
+	}
+	for _, path := range bad {
+		logrus.Warnf("mount %s requested to be read-only is writable", path)
+	}
+	return nil
+}
+
+// readonlyViolations returns the list of mount points which are requested to
+// be read-only by config, but are not according to mounts. The stat function
+// is used to tell whether a path exists.
+func readonlyViolations(config *configs.Config, mounts []*mountinfo.Info, stat func(string) (os.FileInfo, error)) []string {
+	var bad []string
+	check := func(path string, recursive, mustExist bool) {
+		path = utils.CleanPath(path)
+		if slices.Contains(bad, path) {
+			return
+		}
+		top := topMount(mounts, path)
+		if top == nil {
+			if real, err := filepath.EvalSymlinks(path); err == nil && real != path {
+				top = topMount(mounts, real)
+			}
+		}
+		if top == nil {
+			// ReadonlyPaths which do not exist are skipped.
+			if _, err := stat(path); !mustExist && errors.Is(err, os.ErrNotExist) {
+				return
+			}
+			bad = append(bad, path)
+			return
+		}
+		if !isReadonly(top) {
+			bad = append(bad, path)
+			return
+		}
+		if !recursive {
+			return
+		}
+		for _, m := range mounts {
+			if strings.HasPrefix(m.Mountpoint, top.Mountpoint+"/") && m == topMount(mounts, m.Mountpoint) && !isReadonly(m) {
+				bad = append(bad, m.Mountpoint)
+			}
+		}
+	}
+
+	if config.Readonlyfs {
+		check("/", false, true)
+	}
+	for _, m := range config.Mounts {
+		recursive := m.RecAttr != nil && m.RecAttr.Attr_set&unix.MOUNT_ATTR_RDONLY != 0
+		if m.Flags&unix.MS_RDONLY != 0 || recursive {
+			check(m.Destination, recursive, true)
+		}
+	}
+	for _, path := range config.ReadonlyPaths {
+		check(path, false, false)
+	}
+	return bad
+}
+
+// topMount returns the last (i.e. topmost) mount at the given mount point.
+func topMount(mounts []*mountinfo.Info, path string) *mountinfo.Info {
+	var top *mountinfo.Info
+	for _, m := range mounts {
+		if m.Mountpoint == path {
+			top = m
+		}
+	}
+	return top
+}
+
+// isReadonly tells whether either the mount or the filesystem is read-only.
+func isReadonly(m *mountinfo.Info) bool {
+	return slices.Contains(strings.Split(m.Options, ","), "ro") ||
+		slices.Contains(strings.Split(m.VFSOptions, ","), "ro")
+}
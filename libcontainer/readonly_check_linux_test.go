@@ -0,0 +1,45 @@
+package libcontainer
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/moby/sys/mountinfo"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestReadonlyViolations(t *testing.T) {
+	mounts := []*mountinfo.Info{
+		{Mountpoint: "/", Options: "ro,relatime"},
+		{Mountpoint: "/proc", Options: "rw,nosuid"},
+		{Mountpoint: "/proc/sys", Options: "ro,nosuid"},
+		{Mountpoint: "/proc/bus", Options: "rw,nosuid"},
+		// Read-only superblock.
+		{Mountpoint: "/etc/hosts", Options: "rw", VFSOptions: "ro"},
+		{Mountpoint: "/data", Options: "ro"},
+		{Mountpoint: "/data", Options: "rw"},
+		{Mountpoint: "/tree", Options: "ro"},
+		{Mountpoint: "/tree/sub", Options: "rw"},
+		{Mountpoint: "/tree/ro", Options: "ro"},
+	}
+	config := &configs.Config{
+		Readonlyfs: true,
+		Mounts: []*configs.Mount{
+			{Destination: "/proc"},
+			{Destination: "/etc/hosts", Flags: unix.MS_RDONLY},
+			{Destination: "/data/", Flags: unix.MS_RDONLY},
+			{Destination: "/tree", RecAttr: &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}},
+			{Destination: "/missing", Flags: unix.MS_RDONLY},
+		},
+		ReadonlyPaths: []string{"/proc/sys", "/proc/bus", "/proc/nonexistent"},
+	}
+	stat := func(string) (os.FileInfo, error) { return nil, os.ErrNotExist }
+	bad := readonlyViolations(config, mounts, stat)
+	expected := []string{"/data", "/tree/sub", "/missing", "/proc/bus"}
+	if !slices.Equal(bad, expected) {
+		t.Errorf("expected %v, got %v", expected, bad)
+	}
+}
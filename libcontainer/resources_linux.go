@@ -0,0 +1,159 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/sirupsen/logrus"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
+)
+
+// ResourceDelta is which of the container resources differ between two
+// configurations, see [DiffResources].
+type ResourceDelta struct {
+	// Cgroup is whether the cgroup resources, other than the device
+	// rules, differ.
+	Cgroup bool
+	// Devices is whether the device rules differ. Those are not compared
+	// (and are left as they are by [Container.Set]) if the new
+	// configuration has Cgroups.SkipDevices set.
+	Devices   bool
+	IntelRdt  bool
+	IOCost    bool
+	PowerHint bool
+	Shm       bool
+}
+
+// Empty returns whether no resources differ.
+func (d ResourceDelta) Empty() bool {
+	return d == ResourceDelta{}
+}
+
+// DiffResources returns which of the resources applied by [Container.Set]
+// differ between the configurations old and new.
+func DiffResources(old, new *configs.Config) ResourceDelta {
+	var d ResourceDelta
+	oldRes, newRes := resourcesOf(old), resourcesOf(new)
+	oldDevs, newDevs := oldRes.Devices, newRes.Devices
+	oldRes.Devices, newRes.Devices = nil, nil
+	d.Cgroup = !reflect.DeepEqual(oldRes, newRes)
+	if new.Cgroups == nil || !new.Cgroups.SkipDevices {
+		d.Devices = !reflect.DeepEqual(oldDevs, newDevs)
+	}
+	d.IntelRdt = !reflect.DeepEqual(old.IntelRdt, new.IntelRdt)
+	d.IOCost = !reflect.DeepEqual(old.IOCost, new.IOCost)
+	d.PowerHint = !reflect.DeepEqual(old.PowerHint, new.PowerHint)
+	d.Shm = shmSize(old) != shmSize(new)
+	return d
+}
+
+// resourcesOf returns a copy of the cgroup resources of config.
+func resourcesOf(config *configs.Config) cgroups.Resources {
+	if config.Cgroups == nil || config.Cgroups.Resources == nil {
+		return cgroups.Resources{}
+	}
+	return *config.Cgroups.Resources
+}
+
+func shmSize(config *configs.Config) int64 {
+	if config.Shm == nil {
+		return 0
+	}
+	return config.Shm.Size
+}
+
+// applyResources applies the resources of config to the container. It is
+// used both when the container is created (with old set to nil), and by
+// Set (with old being the current configuration), so that the resources
+// are applied in the same order, with the same errors:
+//
+//  1. on update, the resources are validated (as those of a new container
+//     are by [validate.Validate]), the cgroup controllers they need are
+//     checked (the time namespace offsets, which can't be changed once
+//     the container init has entered its time namespace, must be
+//     unchanged), and the /dev/shm size is set (on create, the container
+//     init sets it);
+//  2. the blk-iocost parameters are set in the root cgroup;
+//  3. on update, the memory on the NUMA nodes removed from the cpuset mems
+//     is moved off those, as per the mems migration policy (so that the
+//     cgroup cpuset mems are only set once it is done);
+//  4. the cgroup resources are set;
+//  5. the Intel RDT schemas are set;
+//  6. the power hint is applied (again if the cgroup resources, which
+//     include the cpuset it applies to, differ).
+//
+// On update, if setting the cgroup resources or the Intel RDT schemas fails,
+// the old ones are set back, and so is the /dev/shm size if any step fails.
+func (c *Container) applyResources(old, config *configs.Config) (retErr error) {
+	var delta ResourceDelta
+	if old != nil {
+		delta = DiffResources(old, config)
+		if err := validate.ValidateResources(config); err != nil {
+			return err
+		}
+		if err := c.checkControllers(config.Cgroups.Resources); err != nil {
+			return err
+		}
+		if !maps.Equal(old.TimeOffsets, config.TimeOffsets) {
+			return errors.New("the time namespace offsets can't be changed once the container is created")
+		}
+		prevShm, err := c.setShm(config)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if retErr != nil {
+				c.restoreShm(prevShm)
+			}
+		}()
+	}
+	if old == nil || delta.IOCost {
+		if err := c.applyIOCost(config.IOCost); err != nil {
+			return err
+		}
+	}
+	if old != nil && delta.Cgroup {
+		if err := c.migrateMems(old, config); err != nil {
+			return err
+		}
+	}
+	if err := c.cgroupManager.Set(config.Cgroups.Resources); err != nil {
+		if old != nil {
+			c.rollbackResources(old, false)
+		}
+		return fmt.Errorf("unable to set cgroup config: %w", err)
+	}
+	if c.intelRdtManager != nil {
+		if err := c.intelRdtManager.Set(config); err != nil {
+			if old != nil {
+				c.rollbackResources(old, true)
+			}
+			return fmt.Errorf("unable to set Intel RDT config: %w", err)
+		}
+	}
+	if old == nil || delta.PowerHint || delta.Cgroup {
+		if err := c.applyPowerHint(old, config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollbackResources sets back the cgroup resources (and the Intel RDT
+// schemas, if intelRdt is set) of the configuration old, after a failed
+// update.
+func (c *Container) rollbackResources(old *configs.Config, intelRdt bool) {
+	if err := c.cgroupManager.Set(old.Cgroups.Resources); err != nil {
+		logrus.Warnf("Setting back cgroup configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err)
+	}
+	if intelRdt {
+		if err := c.intelRdtManager.Set(old); err != nil {
+			logrus.Warnf("Setting back intelrdt configs failed due to error: %v, your state.json and actual configs might be inconsistent.", err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package libcontainer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+	devices "github.com/opencontainers/cgroups/devices/config"
+
+	"github.com/opencontainers/runc/libcontainer/cgroupstest"
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+func TestDiffResources(t *testing.T) {
+	old := &configs.Config{
+		Cgroups: &cgroups.Cgroup{Resources: &cgroups.Resources{
+			Memory:  1024,
+			Devices: []*devices.Rule{{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true}},
+		}},
+		Shm: &configs.Shm{Size: 1 << 20},
+	}
+	if d := DiffResources(old, old); !d.Empty() {
+		t.Errorf("expected no differences, got %+v", d)
+	}
+
+	res := *old.Cgroups.Resources
+	res.Memory = 2048
+	res.Devices = nil
+	new := &configs.Config{
+		Cgroups:   &cgroups.Cgroup{Resources: &res},
+		Shm:       &configs.Shm{Size: 1 << 20, Policy: configs.ShmPolicySkip},
+		PowerHint: &configs.PowerHint{UclampMin: "50"},
+	}
+	expected := ResourceDelta{Cgroup: true, Devices: true, PowerHint: true}
+	if d := DiffResources(old, new); d != expected {
+		t.Errorf("expected %+v, got %+v", expected, d)
+	}
+
+	// The device rules are not updated with SkipDevices.
+	new.Cgroups.SkipDevices = true
+	expected.Devices = false
+	if d := DiffResources(old, new); d != expected {
+		t.Errorf("SkipDevices: expected %+v, got %+v", expected, d)
+	}
+}
+
+func TestSetInvalidResources(t *testing.T) {
+	pid := os.Getpid()
+	stat, err := system.Stat(pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &configs.Config{
+		Namespaces: configs.Namespaces{{Type: configs.NEWNS}},
+		Cgroups:    &cgroups.Cgroup{Resources: &cgroups.Resources{Memory: 1024}},
+	}
+	m := cgroupstest.NewManager(config.Cgroups)
+	container := &Container{
+		stateDir:             t.TempDir(),
+		id:                   "myid",
+		config:               config,
+		initProcess:          &mockProcess{_pid: pid, started: stat.StartTime},
+		initProcessStartTime: stat.StartTime,
+		cgroupManager:        m,
+	}
+	container.state = &runningState{c: container}
+
+	// A negative /dev/shm size is rejected on update, as on create.
+	newConfig := container.Config()
+	newConfig.Cgroups = &cgroups.Cgroup{Resources: &cgroups.Resources{Memory: 2048}}
+	newConfig.Shm = &configs.Shm{Size: -1}
+	if err := container.Set(newConfig); err == nil {
+		t.Fatal("expected an error")
+	}
+	if m.Resources().Memory != 1024 {
+		t.Errorf("expected the resources not to be set, got memory %d", m.Resources().Memory)
+	}
+
+	newConfig.Shm = nil
+	if err := container.Set(newConfig); err != nil {
+		t.Fatal(err)
+	}
+	if m.Resources().Memory != 2048 {
+		t.Errorf("expected memory 2048, got %d", m.Resources().Memory)
+	}
+}
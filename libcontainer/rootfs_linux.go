@@ -26,6 +26,8 @@ import (
 	"github.com/opencontainers/cgroups/fs2"
 	"github.com/opencontainers/runc/internal/linux"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/faultinject"
+	"github.com/opencontainers/runc/libcontainer/mountdriver"
 	"github.com/opencontainers/runc/libcontainer/utils"
 )
 
@@ -110,58 +112,24 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig) (err error) {
 		rootlessCgroups: config.RootlessCgroups,
 		cgroupns:        config.Namespaces.Contains(configs.NEWCGROUP),
 	}
-	for _, m := range config.Mounts {
-		entry := mountEntry{Mount: m}
-		// Figure out whether we need to request runc to give us an
-		// open_tree(2)-style mountfd. For idmapped mounts, this is always
-		// necessary. For bind-mounts, this is only necessary if we cannot
-		// resolve the parent mount (this is only hit if you are running in a
-		// userns -- but for rootless the host-side thread can't help).
-		wantSourceFile := m.IsIDMapped()
-		if m.IsBind() && !config.RootlessEUID {
-			if _, err := os.Stat(m.Source); err != nil {
-				wantSourceFile = true
-			}
-		}
-		if wantSourceFile {
-			// Request a source file from the host.
-			if err := writeSyncArg(pipe, procMountPlease, m); err != nil {
-				return fmt.Errorf("failed to request mountfd for %q: %w", m.Source, err)
-			}
-			sync, err := readSyncFull(pipe, procMountFd)
-			if err != nil {
-				return fmt.Errorf("mountfd request for %q failed: %w", m.Source, err)
-			}
-			if sync.File == nil {
-				return fmt.Errorf("mountfd request for %q: response missing attached fd", m.Source)
-			}
-			defer sync.File.Close()
-			// Sanity-check to make sure we didn't get the wrong fd back. Note
-			// that while m.Source might contain symlinks, the (*os.File).Name
-			// is based on the path provided to os.OpenFile, not what it
-			// resolves to. So this should never happen.
-			if sync.File.Name() != m.Source {
-				return fmt.Errorf("returned mountfd for %q doesn't match requested mount configuration: mountfd path is %q", m.Source, sync.File.Name())
-			}
-			// Unmarshal the procMountFd argument (the file is sync.File).
-			var src *mountSource
-			if sync.Arg == nil {
-				return fmt.Errorf("sync %q is missing an argument", sync.Type)
-			}
-			if err := json.Unmarshal(*sync.Arg, &src); err != nil {
-				return fmt.Errorf("invalid mount fd response argument %q: %w", string(*sync.Arg), err)
-			}
-			if src == nil {
-				return fmt.Errorf("mountfd request for %q: no mount source info received", m.Source)
-			}
-			src.file = sync.File
-			entry.srcFile = src
+	for i, m := range config.Mounts {
+		err := faultinject.Check(faultinject.Mount(i))
+		if err == nil {
+			err = setupMount(pipe, mountConfig, config, m)
 		}
-		if err := mountToRootfs(mountConfig, entry); err != nil {
+		if err != nil {
 			return fmt.Errorf("error mounting %q to rootfs at %q: %w", m.Source, m.Destination, err)
 		}
 	}
 
+	if err := setupSysfsWritable(config); err != nil {
+		return fmt.Errorf("error setting up writable /sys paths: %w", err)
+	}
+
+	if err := setupShm(config); err != nil {
+		return fmt.Errorf("error setting up /dev/shm: %w", err)
+	}
+
 	setupDev := needsSetupDev(config)
 	if setupDev {
 		if err := createDevices(config); err != nil {
@@ -175,6 +143,16 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig) (err error) {
 		}
 	}
 
+	if err := setupTmpfiles(config); err != nil {
+		return fmt.Errorf("error provisioning tmpfiles: %w", err)
+	}
+
+	for _, m := range identityMounts(iConfig) {
+		if err := setupMount(pipe, mountConfig, config, m); err != nil {
+			return fmt.Errorf("error mounting %q to rootfs at %q: %w", m.Source, m.Destination, err)
+		}
+	}
+
 	// Signal the parent to run the pre-start hooks.
 	// The hooks are run after the mounts are setup, but before we switch to the new
 	// root, so that the old root is still available in the hooks for any mount
@@ -199,7 +177,7 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig) (err error) {
 	if s := iConfig.SpecState; s != nil {
 		s.Pid = unix.Getpid()
 		s.Status = specs.StateCreating
-		if err := iConfig.Config.Hooks.Run(configs.CreateContainer, s); err != nil {
+		if _, err := iConfig.Config.Hooks.Run(configs.CreateContainer, s); err != nil {
 			return err
 		}
 	}
@@ -244,16 +222,75 @@ func prepareRootfs(pipe *syncSocket, iConfig *initConfig) (err error) {
 	return nil
 }
 
+// setupMount mounts m in the container root filesystem, with the mount
+// policy applied, requesting its source from the host if needed.
+func setupMount(pipe *syncSocket, mountConfig *mountConfig, config *configs.Config, m *configs.Mount) error {
+	entry := mountEntry{Mount: applyMountPolicy(config.MountPolicy, m)}
+	if isSysfsReadonlyLater(config, entry.Mount) {
+		// Do not modify the configuration.
+		mnt := *entry.Mount
+		mnt.Flags &^= unix.MS_RDONLY
+		entry.Mount = &mnt
+	}
+	// Figure out whether we need to request runc to give us an
+	// open_tree(2)-style mountfd. For idmapped mounts, this is always
+	// necessary. For bind-mounts, this is only necessary if we cannot
+	// resolve the parent mount (this is only hit if you are running in a
+	// userns -- but for rootless the host-side thread can't help).
+	wantSourceFile := m.IsIDMapped()
+	if m.IsBind() && !config.RootlessEUID {
+		if _, err := os.Stat(m.Source); err != nil {
+			wantSourceFile = true
+		}
+	}
+	if wantSourceFile {
+		// Request a source file from the host.
+		if err := writeSyncArg(pipe, procMountPlease, m); err != nil {
+			return fmt.Errorf("failed to request mountfd for %q: %w", m.Source, err)
+		}
+		sync, err := readSyncFull(pipe, procMountFd)
+		if err != nil {
+			return fmt.Errorf("mountfd request for %q failed: %w", m.Source, err)
+		}
+		if sync.File == nil {
+			return fmt.Errorf("mountfd request for %q: response missing attached fd", m.Source)
+		}
+		defer sync.File.Close()
+		// Sanity-check to make sure we didn't get the wrong fd back. Note
+		// that while m.Source might contain symlinks, the (*os.File).Name
+		// is based on the path provided to os.OpenFile, not what it
+		// resolves to. So this should never happen.
+		if sync.File.Name() != m.Source {
+			return fmt.Errorf("returned mountfd for %q doesn't match requested mount configuration: mountfd path is %q", m.Source, sync.File.Name())
+		}
+		// Unmarshal the procMountFd argument (the file is sync.File).
+		var src *mountSource
+		if sync.Arg == nil {
+			return fmt.Errorf("sync %q is missing an argument", sync.Type)
+		}
+		if err := json.Unmarshal(*sync.Arg, &src); err != nil {
+			return fmt.Errorf("invalid mount fd response argument %q: %w", string(*sync.Arg), err)
+		}
+		if src == nil {
+			return fmt.Errorf("mountfd request for %q: no mount source info received", m.Source)
+		}
+		src.file = sync.File
+		entry.srcFile = src
+	}
+	return mountToRootfs(mountConfig, entry)
+}
+
 // finalizeRootfs sets anything to ro if necessary. You must call
 // prepareRootfs first.
 func finalizeRootfs(config *configs.Config) (err error) {
 	// All tmpfs mounts and /dev were previously mounted as rw
-	// by mountPropagate. Remount them read-only as requested.
+	// by mountPropagate, and so was /sys if it has writable paths.
+	// Remount them read-only as requested.
 	for _, m := range config.Mounts {
 		if m.Flags&unix.MS_RDONLY != unix.MS_RDONLY {
 			continue
 		}
-		if m.Device == "tmpfs" || utils.CleanPath(m.Destination) == "/dev" {
+		if m.Device == "tmpfs" || utils.CleanPath(m.Destination) == "/dev" || isSysfsReadonlyLater(config, m) {
 			if err := remountReadonly(m); err != nil {
 				return err
 			}
@@ -267,6 +304,12 @@ func finalizeRootfs(config *configs.Config) (err error) {
 		}
 	}
 
+	// This is done once the mounts are read-only as requested, so that the
+	// bind mounts of the paths which are not mount points are as well.
+	if err := setupPropagationPaths(config); err != nil {
+		return err
+	}
+
 	if config.Umask != nil {
 		unix.Umask(int(*config.Umask))
 	} else {
@@ -603,6 +646,10 @@ func mountToRootfs(c *mountConfig, m mountEntry) error {
 	}
 	mountLabel := c.label
 
+	if d := mountdriver.Get(m.Device); d != nil {
+		return mountWithDriver(d, m, rootfs, mountLabel)
+	}
+
 	switch m.Device {
 	case "mqueue":
 		if err := mountPropagate(m, rootfs, ""); err != nil {
@@ -842,6 +889,7 @@ func checkProcMount(rootfs, dest string, m mountEntry) error {
 		"/proc/slabinfo",
 		"/proc/sys/kernel/ns_last_pid",
 		"/proc/sys/crypto/fips_enabled",
+		"/proc/sys/kernel/random/boot_id",
 	}
 	for _, valid := range validProcMounts {
 		path, err := filepath.Rel(filepath.Join(rootfs, valid), dest)
@@ -1059,6 +1107,27 @@ func setReadonly() error {
 	return mount("", "/", "", flags, "")
 }
 
+// setupPropagationPaths applies the propagation flags of the paths of
+// config.PropagationPaths. A path which is not a mount point is bind
+// mounted onto itself first (the bind mount inheriting the flags, such as
+// read-only, of the mount it is on).
+func setupPropagationPaths(config *configs.Config) error {
+	for _, p := range config.PropagationPaths {
+		err := mount("", p.Path, "", uintptr(p.Flags), "")
+		// EINVAL means the path is not a mount point.
+		if errors.Is(err, unix.EINVAL) {
+			err = mount(p.Path, p.Path, "", unix.MS_BIND|unix.MS_REC, "")
+			if err == nil {
+				err = mount("", p.Path, "", uintptr(p.Flags), "")
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("unable to set the mount propagation of %s: %w", p.Path, err)
+		}
+	}
+	return nil
+}
+
 func setupPtmx(config *configs.Config) error {
 	ptmx := filepath.Join(config.Rootfs, "dev/ptmx")
 	if err := os.Remove(ptmx); err != nil && !os.IsNotExist(err) {
@@ -1296,8 +1365,27 @@ func mountPropagate(m mountEntry, rootfs string, mountLabel string) error {
 	}); err != nil {
 		return err
 	}
+	return setPropagation(m, rootfs)
+}
+
+// mountWithDriver mounts m using the mount driver d, then applies the mount
+// propagation flags and the recursive mount attributes of m.
+func mountWithDriver(d mountdriver.Driver, m mountEntry, rootfs, mountLabel string) error {
+	if err := utils.WithProcfd(rootfs, m.Destination, func(dstFd string) error {
+		return d.Mount(&mountdriver.Mount{Mount: m.Mount, Target: dstFd, Rootfs: rootfs, Label: mountLabel})
+	}); err != nil {
+		return fmt.Errorf("%s mount driver: %w", m.Device, err)
+	}
+	if err := setPropagation(m, rootfs); err != nil {
+		return err
+	}
+	return setRecAttr(m.Mount, rootfs)
+}
+
+// setPropagation applies the mount propagation flags of m.
+func setPropagation(m mountEntry, rootfs string) error {
 	// We have to apply mount propagation flags in a separate WithProcfd() call
-	// because the previous call invalidates the passed procfd -- the mount
+	// because the mount invalidates the procfd used for it -- the mount
 	// target needs to be re-opened.
 	if err := utils.WithProcfd(rootfs, m.Destination, func(dstFd string) error {
 		for _, pflag := range m.PropagationFlags {
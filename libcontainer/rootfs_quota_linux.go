@@ -0,0 +1,257 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// See include/uapi/linux/fs.h.
+	fsIocFsGetXattr    = 0x801c581f
+	fsIocFsSetXattr    = 0x401c5820
+	fsXflagProjInherit = 0x200
+
+	// See include/uapi/linux/quota.h.
+	qGetQuota  = 0x800007
+	qSetQuota  = 0x800008
+	prjQuota   = 2
+	qifBLimits = 1
+	qifILimits = 4
+	// quotaBlockSize is the unit of the block limits of ifDqblk.
+	quotaBlockSize = 1024
+
+	// projectIDBase is the lowest project ID picked for a container,
+	// leaving the lower ones for the administrator.
+	projectIDBase = 1 << 24
+)
+
+// fsxattr is struct fsxattr, see include/uapi/linux/fs.h.
+type fsxattr struct {
+	Xflags     uint32
+	Extsize    uint32
+	Nextents   uint32
+	Projid     uint32
+	Cowextsize uint32
+	Pad        [8]byte
+}
+
+// ifDqblk is struct if_dqblk, see include/uapi/linux/quota.h.
+type ifDqblk struct {
+	BHardLimit uint64
+	BSoftLimit uint64
+	CurSpace   uint64
+	IHardLimit uint64
+	ISoftLimit uint64
+	CurInodes  uint64
+	BTime      uint64
+	ITime      uint64
+	Valid      uint32
+	_          uint32
+}
+
+// unused returns whether the quota entry d has neither usage nor limits.
+func (d *ifDqblk) unused() bool {
+	return d.BHardLimit == 0 && d.BSoftLimit == 0 && d.CurSpace == 0 &&
+		d.IHardLimit == 0 && d.ISoftLimit == 0 && d.CurInodes == 0
+}
+
+// setupRootfsQuota assigns the root filesystem files to the project of the
+// root filesystem quota, if any, and sets the project limits. The project
+// ID is recorded in the container. The progress of the assignment, which
+// walks the whole root filesystem, is reported to report, if set.
+func (c *Container) setupRootfsQuota(report func(Progress)) error {
+	q := c.config.RootfsQuota
+	if q == nil {
+		return nil
+	}
+	root, err := os.OpenFile(c.config.Rootfs, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	id := q.ProjectID
+	if id == 0 {
+		attr, err := getFsxattr(root)
+		if err != nil {
+			return err
+		}
+		id = attr.Projid
+	}
+	if id == 0 {
+		if id, err = unusedProject(root, c.id); err != nil {
+			return err
+		}
+	}
+	var assigned atomic.Uint64
+	progress := trackProgress(report, "rootfs-quota", nil, assigned.Load)
+	err = setProject(c.config.Rootfs, id, &assigned)
+	progress.end(err)
+	if err != nil {
+		if errors.Is(err, unix.EOPNOTSUPP) {
+			err = fmt.Errorf("%w (the filesystem must support project IDs, such as ext4 with the project feature)", err)
+		}
+		return fmt.Errorf("unable to assign the root filesystem to project %d: %w", id, err)
+	}
+	limits := ifDqblk{
+		BHardLimit: (q.Size + quotaBlockSize - 1) / quotaBlockSize,
+		IHardLimit: q.Inodes,
+		Valid:      qifBLimits | qifILimits,
+	}
+	if err := quotactl(root, qSetQuota, id, &limits); err != nil {
+		return fmt.Errorf("unable to set project %d quota: %w", id, err)
+	}
+	c.rootfsProject = id
+	return nil
+}
+
+// teardownRootfsQuota removes the limits of the root filesystem project, if
+// any, so that the project ID can be used again.
+func (c *Container) teardownRootfsQuota() {
+	if c.rootfsProject == 0 {
+		return
+	}
+	root, err := os.OpenFile(c.config.Rootfs, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err == nil {
+		err = quotactl(root, qSetQuota, c.rootfsProject, &ifDqblk{Valid: qifBLimits | qifILimits})
+		root.Close()
+	}
+	if err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("unable to remove project %d quota: %v", c.rootfsProject, err)
+	}
+	c.rootfsProject = 0
+}
+
+// rootfsQuotaStats returns the disk usage and the limits of the root
+// filesystem project, or nil if there is none.
+func (c *Container) rootfsQuotaStats() (*RootfsQuotaStats, error) {
+	if c.rootfsProject == 0 {
+		return nil, nil
+	}
+	root, err := os.OpenFile(c.config.Rootfs, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	var d ifDqblk
+	if err := quotactl(root, qGetQuota, c.rootfsProject, &d); err != nil {
+		return nil, err
+	}
+	return &RootfsQuotaStats{
+		ProjectID:   c.rootfsProject,
+		Limit:       d.BHardLimit * quotaBlockSize,
+		Usage:       d.CurSpace,
+		InodesLimit: d.IHardLimit,
+		InodesUsage: d.CurInodes,
+	}, nil
+}
+
+// unusedProject returns a project ID, derived from the container ID, which
+// has neither usage nor limits on the filesystem of root.
+func unusedProject(root *os.File, containerID string) (uint32, error) {
+	h := fnv.New32a()
+	h.Write([]byte(containerID))
+	id := projectIDBase + h.Sum32()%(1<<30)
+	for range 1000 {
+		var d ifDqblk
+		err := quotactl(root, qGetQuota, id, &d)
+		// ESRCH means the project has no quota entry.
+		if errors.Is(err, unix.ESRCH) || (err == nil && d.unused()) {
+			return id, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to get project %d quota: %w", id, err)
+		}
+		id++
+	}
+	return 0, errors.New("no unused project ID found")
+}
+
+// setProject assigns the files and the directories under root (but not on
+// the other filesystems mounted under it) to the project id, the
+// directories being marked for their new files to inherit it. The other
+// files (such as symlinks and device nodes) are skipped. The size of the
+// regular files assigned is added to assigned.
+func setProject(root string, id uint32, assigned *atomic.Uint64) error {
+	var st unix.Stat_t
+	if err := unix.Stat(root, &st); err != nil {
+		return &os.PathError{Op: "stat", Path: root, Err: err}
+	}
+	dev := st.Dev
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		f, err := os.OpenFile(path, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if d.IsDir() {
+			if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+				return &os.PathError{Op: "fstat", Path: path, Err: err}
+			}
+			if st.Dev != dev {
+				return fs.SkipDir
+			}
+		}
+		attr, err := getFsxattr(f)
+		if err != nil {
+			return err
+		}
+		orig := *attr
+		attr.Projid = id
+		if d.IsDir() {
+			attr.Xflags |= fsXflagProjInherit
+		}
+		if err := setFsxattr(f, attr); err != nil {
+			// Some filesystems (such as ext4) set the flags before failing
+			// to set the project ID.
+			_ = setFsxattr(f, &orig)
+			return err
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				assigned.Add(uint64(fi.Size()))
+			}
+		}
+		return nil
+	})
+}
+
+func getFsxattr(f *os.File) (*fsxattr, error) {
+	var attr fsxattr
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIocFsGetXattr, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		return nil, &os.PathError{Op: "ioctl FS_IOC_FSGETXATTR", Path: f.Name(), Err: errno}
+	}
+	return &attr, nil
+}
+
+func setFsxattr(f *os.File, attr *fsxattr) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), fsIocFsSetXattr, uintptr(unsafe.Pointer(attr))); errno != 0 {
+		return &os.PathError{Op: "ioctl FS_IOC_FSSETXATTR", Path: f.Name(), Err: errno}
+	}
+	return nil
+}
+
+// quotactl runs the project quota command cmd for the project id, on the
+// filesystem of f.
+func quotactl(f *os.File, cmd int, id uint32, d *ifDqblk) error {
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL_FD, f.Fd(), uintptr(cmd<<8|prjQuota), uintptr(id), uintptr(unsafe.Pointer(d)), 0, 0)
+	if errno != 0 {
+		return os.NewSyscallError("quotactl_fd", errno)
+	}
+	return nil
+}
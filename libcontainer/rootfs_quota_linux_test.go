@@ -0,0 +1,28 @@
+package libcontainer
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestQuotaStructSizes(t *testing.T) {
+	// The sizes are encoded in the ioctl numbers, and expected by the
+	// quotactl_fd syscall.
+	if size := unsafe.Sizeof(fsxattr{}); size != 28 {
+		t.Errorf("expected struct fsxattr size 28, got %d", size)
+	}
+	if size := unsafe.Sizeof(ifDqblk{}); size != 72 {
+		t.Errorf("expected struct if_dqblk size 72, got %d", size)
+	}
+}
+
+func TestDqblkUnused(t *testing.T) {
+	if d := (&ifDqblk{Valid: 0xff, BTime: 1}); !d.unused() {
+		t.Error("expected an entry with no usage and no limits to be unused")
+	}
+	for _, d := range []*ifDqblk{{CurSpace: 1}, {CurInodes: 1}, {BHardLimit: 1}, {ISoftLimit: 1}} {
+		if d.unused() {
+			t.Errorf("expected %+v to be used", d)
+		}
+	}
+}
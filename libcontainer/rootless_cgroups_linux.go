@@ -0,0 +1,49 @@
+package libcontainer
+
+import (
+	"slices"
+
+	"github.com/moby/sys/userns"
+	"github.com/opencontainers/cgroups"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// skippedCgroupResources returns the sorted list of the cgroup resources
+// which are not in force for a container in the rootless cgroups mode,
+// because of a lack of permissions. It must be called once the cgroup
+// configuration is applied by m.
+//
+// The list may contain:
+//   - "cgroup", if (on cgroup v2) the container cgroup could not be created
+//     at all, meaning that the container processes can not be accounted,
+//     frozen, or reliably killed;
+//   - the names of the cgroup v1 controllers which could not be joined;
+//   - "devices", if the device rules could not be applied.
+func skippedCgroupResources(config *configs.Config, m cgroups.Manager) []string {
+	if !config.RootlessCgroups {
+		return nil
+	}
+	var skipped []string
+	if cgroups.IsCgroup2UnifiedMode() {
+		if m.Path("") == "" || !m.Exists() {
+			skipped = append(skipped, "cgroup")
+		}
+	} else {
+		paths := m.GetPaths()
+		all, _ := cgroups.GetAllSubsystems()
+		for _, ctrl := range all {
+			if paths[ctrl] == "" {
+				skipped = append(skipped, ctrl)
+			}
+		}
+	}
+	// The device rules can not be applied by an unprivileged user, and
+	// the errors setting those are ignored by the cgroup managers.
+	if r := config.Cgroups.Resources; r != nil && len(r.Devices) > 0 && !r.SkipDevices &&
+		(config.RootlessEUID || userns.RunningInUserNS()) && !slices.Contains(skipped, "devices") {
+		skipped = append(skipped, "devices")
+	}
+	slices.Sort(skipped)
+	return skipped
+}
@@ -0,0 +1,46 @@
+package libcontainer
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+	devices "github.com/opencontainers/cgroups/devices/config"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestSkippedCgroupResourcesNotRootless(t *testing.T) {
+	config := &configs.Config{
+		Cgroups: &cgroups.Cgroup{Resources: &cgroups.Resources{
+			Devices: []*devices.Rule{{Type: devices.WildcardDevice, Allow: false}},
+		}},
+	}
+	if skipped := skippedCgroupResources(config, &mockCgroupManager{}); skipped != nil {
+		t.Fatalf("expected nothing skipped, got %v", skipped)
+	}
+}
+
+func TestSkippedCgroupResourcesDevices(t *testing.T) {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("requires cgroup v2")
+	}
+	config := &configs.Config{
+		RootlessEUID:    true,
+		RootlessCgroups: true,
+		Cgroups: &cgroups.Cgroup{Resources: &cgroups.Resources{
+			Devices: []*devices.Rule{{Type: devices.WildcardDevice, Allow: false}},
+		}},
+	}
+	// No cgroup path means the cgroup was not created.
+	skipped := skippedCgroupResources(config, &mockCgroupManager{})
+	if !slices.Equal(skipped, []string{"cgroup", "devices"}) {
+		t.Fatalf("expected [cgroup devices], got %v", skipped)
+	}
+
+	config.Cgroups.Resources.SkipDevices = true
+	skipped = skippedCgroupResources(config, &mockCgroupManager{})
+	if !slices.Equal(skipped, []string{"cgroup"}) {
+		t.Fatalf("expected [cgroup], got %v", skipped)
+	}
+}
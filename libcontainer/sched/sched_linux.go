@@ -0,0 +1,185 @@
+// Package sched converts between the runtime-spec process scheduler
+// configuration and the Linux sched_setattr(2) attributes, and validates
+// the scheduler configuration.
+package sched
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+var policies = []struct {
+	name  specs.LinuxSchedulerPolicy
+	value uint32
+}{
+	{specs.SchedOther, 0},
+	{specs.SchedFIFO, 1},
+	{specs.SchedRR, 2},
+	{specs.SchedBatch, 3},
+	{specs.SchedISO, 4},
+	{specs.SchedIdle, 5},
+	{specs.SchedDeadline, 6},
+}
+
+var flags = []struct {
+	name  specs.LinuxSchedulerFlag
+	value uint64
+}{
+	{specs.SchedFlagResetOnFork, 0x01},
+	{specs.SchedFlagReclaim, 0x02},
+	{specs.SchedFlagDLOverrun, 0x04},
+	{specs.SchedFlagKeepPolicy, 0x08},
+	{specs.SchedFlagKeepParams, 0x10},
+	{specs.SchedFlagUtilClampMin, 0x20},
+	{specs.SchedFlagUtilClampMax, 0x40},
+}
+
+// minDeadlineRuntime is the minimal SCHED_DEADLINE runtime, in nanoseconds,
+// accepted by the kernel (see __checkparam_dl in kernel/sched/deadline.c).
+const minDeadlineRuntime = 1 << 10
+
+func policyValue(policy specs.LinuxSchedulerPolicy) (uint32, error) {
+	for _, p := range policies {
+		if p.name == policy {
+			return p.value, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid scheduler policy: %s", policy)
+}
+
+// ToSchedAttr converts the scheduler configuration to the attributes of
+// sched_setattr(2).
+func ToSchedAttr(scheduler *specs.Scheduler) (*unix.SchedAttr, error) {
+	policy, err := policyValue(scheduler.Policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrFlags uint64
+next:
+	for _, flag := range scheduler.Flags {
+		for _, f := range flags {
+			if f.name == flag {
+				attrFlags |= f.value
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("invalid scheduler flag: %s", flag)
+	}
+
+	return &unix.SchedAttr{
+		Size:     unix.SizeofSchedAttr,
+		Policy:   policy,
+		Flags:    attrFlags,
+		Nice:     scheduler.Nice,
+		Priority: uint32(scheduler.Priority),
+		Runtime:  scheduler.Runtime,
+		Deadline: scheduler.Deadline,
+		Period:   scheduler.Period,
+	}, nil
+}
+
+// FromSchedAttr converts the attributes of sched_setattr(2) to the
+// scheduler configuration. The utilization clamping values have no
+// equivalent in the configuration, and are ignored, as are the runtime,
+// deadline and period for the policies other than SCHED_DEADLINE (newer
+// kernels report the time slice of the task as its runtime).
+func FromSchedAttr(attr *unix.SchedAttr) (*specs.Scheduler, error) {
+	s := &specs.Scheduler{
+		Nice:     attr.Nice,
+		Priority: int32(attr.Priority),
+	}
+	for _, p := range policies {
+		if p.value == attr.Policy {
+			s.Policy = p.name
+			break
+		}
+	}
+	if s.Policy == "" {
+		return nil, fmt.Errorf("unknown scheduler policy: %d", attr.Policy)
+	}
+	if s.Policy == specs.SchedDeadline {
+		s.Runtime, s.Deadline, s.Period = attr.Runtime, attr.Deadline, attr.Period
+	}
+	unknown := attr.Flags
+	for _, f := range flags {
+		if attr.Flags&f.value != 0 {
+			s.Flags = append(s.Flags, f.name)
+			unknown &^= f.value
+		}
+	}
+	if unknown != 0 {
+		return nil, fmt.Errorf("unknown scheduler flags: %#x", unknown)
+	}
+	return s, nil
+}
+
+// Validate checks that the scheduler configuration is consistent: the
+// policy is set, the nice value is only set within its range, the
+// priority and the deadline parameters are only set for the policies using
+// them, and the deadline parameters are accepted by the kernel
+// (runtime <= deadline <= period, where period defaults to deadline).
+func Validate(s *specs.Scheduler) error {
+	if s.Policy == "" {
+		return errors.New("scheduler policy is required")
+	}
+	if _, err := policyValue(s.Policy); err != nil {
+		return err
+	}
+	if s.Policy == specs.SchedOther || s.Policy == specs.SchedBatch {
+		if s.Nice < -20 || s.Nice > 19 {
+			return fmt.Errorf("invalid scheduler.nice: %d when scheduler.policy is %s", s.Nice, string(s.Policy))
+		}
+	}
+	if s.Priority != 0 && (s.Policy != specs.SchedFIFO && s.Policy != specs.SchedRR) {
+		return errors.New("scheduler.priority can only be specified for SchedFIFO or SchedRR policy")
+	}
+	if s.Policy != specs.SchedDeadline {
+		if s.Runtime != 0 || s.Deadline != 0 || s.Period != 0 {
+			return errors.New("scheduler runtime/deadline/period can only be specified for SchedDeadline policy")
+		}
+		return nil
+	}
+	// The kernel uses the signed 64-bit nanoseconds.
+	if s.Deadline == 0 || s.Deadline > math.MaxInt64 || s.Period > math.MaxInt64 {
+		return fmt.Errorf("invalid scheduler.deadline: %d", s.Deadline)
+	}
+	if s.Runtime < minDeadlineRuntime || s.Runtime > s.Deadline {
+		return fmt.Errorf("invalid scheduler.runtime: %d, must be at least %d and at most the deadline", s.Runtime, minDeadlineRuntime)
+	}
+	if s.Period != 0 && s.Period < s.Deadline {
+		return fmt.Errorf("invalid scheduler.period: %d, must be at least the deadline", s.Period)
+	}
+	return nil
+}
+
+// CheckSupported checks that the scheduler policy is supported by the
+// running kernel.
+func CheckSupported(s *specs.Scheduler) error {
+	policy, err := policyValue(s.Policy)
+	if err != nil {
+		return err
+	}
+	// sched_get_priority_max(2) fails with EINVAL for an unknown policy.
+	if _, _, errno := unix.Syscall(unix.SYS_SCHED_GET_PRIORITY_MAX, uintptr(policy), 0, 0); errno != 0 {
+		if errno == unix.EINVAL {
+			return fmt.Errorf("scheduler policy %s is not supported by the kernel", s.Policy)
+		}
+		return &os.SyscallError{Syscall: "sched_get_priority_max", Err: errno}
+	}
+	return nil
+}
+
+// Get returns the current scheduler configuration of the process pid.
+func Get(pid int) (*specs.Scheduler, error) {
+	attr, err := unix.SchedGetAttr(pid, 0)
+	if err != nil {
+		return nil, &os.SyscallError{Syscall: "sched_getattr", Err: err}
+	}
+	return FromSchedAttr(attr)
+}
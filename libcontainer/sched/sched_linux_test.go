@@ -0,0 +1,86 @@
+package sched
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestSchedAttrRoundTrip(t *testing.T) {
+	for _, s := range []*specs.Scheduler{
+		{Policy: specs.SchedOther, Nice: -5},
+		{Policy: specs.SchedFIFO, Priority: 50, Flags: []specs.LinuxSchedulerFlag{specs.SchedFlagResetOnFork}},
+		{Policy: specs.SchedDeadline, Runtime: 10000, Deadline: 20000, Period: 30000, Flags: []specs.LinuxSchedulerFlag{specs.SchedFlagReclaim, specs.SchedFlagDLOverrun}},
+	} {
+		attr, err := ToSchedAttr(s)
+		if err != nil {
+			t.Fatalf("%+v: %v", s, err)
+		}
+		got, err := FromSchedAttr(attr)
+		if err != nil {
+			t.Fatalf("%+v: %v", s, err)
+		}
+		if !reflect.DeepEqual(got, s) {
+			t.Errorf("expected %+v, got %+v", s, got)
+		}
+	}
+}
+
+func TestToSchedAttrInvalid(t *testing.T) {
+	if _, err := ToSchedAttr(&specs.Scheduler{Policy: "SCHED_FOO"}); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+	if _, err := ToSchedAttr(&specs.Scheduler{Policy: specs.SchedOther, Flags: []specs.LinuxSchedulerFlag{"SCHED_FLAG_FOO"}}); err == nil {
+		t.Error("expected an error for an invalid flag")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		s     specs.Scheduler
+		isErr bool
+	}{
+		{s: specs.Scheduler{}, isErr: true},
+		{s: specs.Scheduler{Policy: "SCHED_FOO"}, isErr: true},
+		{s: specs.Scheduler{Policy: specs.SchedBatch, Nice: 19}},
+		{s: specs.Scheduler{Policy: specs.SchedBatch, Nice: 20}, isErr: true},
+		{s: specs.Scheduler{Policy: specs.SchedRR, Priority: 10}},
+		{s: specs.Scheduler{Policy: specs.SchedIdle, Priority: 10}, isErr: true},
+		{s: specs.Scheduler{Policy: specs.SchedDeadline, Runtime: 1024, Deadline: 1024}},
+		{s: specs.Scheduler{Policy: specs.SchedDeadline, Runtime: 1023, Deadline: 1024}, isErr: true},
+		{s: specs.Scheduler{Policy: specs.SchedDeadline, Runtime: 2048, Deadline: 1024}, isErr: true},
+		{s: specs.Scheduler{Policy: specs.SchedDeadline, Runtime: 1024, Deadline: 2048, Period: 1024}, isErr: true},
+		{s: specs.Scheduler{Policy: specs.SchedDeadline, Runtime: 1024, Deadline: 1 << 63}, isErr: true},
+	}
+	for _, tc := range testCases {
+		err := Validate(&tc.s)
+		if tc.isErr && err == nil {
+			t.Errorf("%+v: expected error, got nil", tc.s)
+		}
+		if !tc.isErr && err != nil {
+			t.Errorf("%+v: expected nil, got error %v", tc.s, err)
+		}
+	}
+}
+
+func TestCheckSupported(t *testing.T) {
+	if err := CheckSupported(&specs.Scheduler{Policy: specs.SchedOther}); err != nil {
+		t.Errorf("SCHED_OTHER: %v", err)
+	}
+	// SCHED_ISO is reserved, but not implemented by Linux.
+	if err := CheckSupported(&specs.Scheduler{Policy: specs.SchedISO}); err == nil {
+		t.Error("SCHED_ISO: expected error, got nil")
+	}
+}
+
+func TestGet(t *testing.T) {
+	s, err := Get(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(s); err != nil {
+		t.Errorf("current scheduler %+v: %v", s, err)
+	}
+}
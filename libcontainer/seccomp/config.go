@@ -134,3 +134,22 @@ func SupportedFlags() []string {
 
 	return res
 }
+
+// SupportedActions returns the list of the supported actions.
+// This list may be a subset of one returned by KnownActions due to
+// some actions not supported by the current kernel and/or libseccomp.
+// Used by `runc features`.
+func SupportedActions() []string {
+	if !Enabled {
+		return nil
+	}
+
+	var res []string
+	for _, action := range KnownActions() {
+		if ActionSupported(action) == nil {
+			res = append(res, action)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Handler handles a system call notification from the container c, and
+// returns the response.
+type Handler func(c *Container, req *Request) Response
+
+// Agent is a seccomp agent, which dispatches the system call notifications
+// of the containers to the handlers registered for each system call. Each
+// notification is handled in its own goroutine, so a slow handler does not
+// hold up the other system calls.
+//
+// An Agent is safe for concurrent use, and may serve multiple containers.
+type Agent struct {
+	mu       sync.RWMutex
+	handlers map[int32]Handler
+	// Default handles the system calls with no registered handler. If it
+	// is nil, those fail with ENOSYS.
+	Default Handler
+}
+
+// Handle registers h as the handler of the system call number nr. As the
+// number depends on the architecture, h should check req.Arch if the
+// seccomp profile allows several architectures.
+func (a *Agent) Handle(nr int, h Handler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.handlers == nil {
+		a.handlers = make(map[int32]Handler)
+	}
+	a.handlers[int32(nr)] = h
+}
+
+func (a *Agent) handler(nr int32) Handler {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if h, ok := a.handlers[nr]; ok {
+		return h
+	}
+	if a.Default != nil {
+		return a.Default
+	}
+	return func(*Container, *Request) Response {
+		return Errno(unix.ENOSYS)
+	}
+}
+
+// Serve handles the system call notifications of the container c, until
+// all the processes using its seccomp filter have exited, and then closes
+// c.Fd.
+func (a *Agent) Serve(c *Container) error {
+	defer c.Fd.Close()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		fds := []unix.PollFd{{Fd: int32(c.Fd.Fd()), Events: unix.POLLIN}}
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			return os.NewSyscallError("poll", err)
+		}
+		if fds[0].Revents&unix.POLLIN == 0 {
+			// POLLHUP: the filter is no longer used.
+			return nil
+		}
+		req, err := Receive(c.Fd)
+		if err != nil {
+			if errors.Is(err, unix.ENOENT) {
+				// The process was interrupted in between.
+				continue
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := a.handler(req.Nr)(c, req)
+			if resp.Responded {
+				return
+			}
+			if err := req.Respond(resp); err != nil && !errors.Is(err, unix.ENOENT) {
+				logrus.Warnf("seccomp agent: %v", err)
+			}
+		}()
+	}
+}
+
+// ListenAndServe accepts the containers sent to the listener l, and serves
+// each of them in its own goroutine (see [Agent.Serve]), until l is closed.
+func (a *Agent) ListenAndServe(l *Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			logrus.Warnf("seccomp agent: unable to receive container: %v", err)
+			continue
+		}
+		go func() {
+			if err := a.Serve(c); err != nil {
+				logrus.Warnf("seccomp agent: container %s: %v", c.State.State.ID, err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// Listener is the socket of a seccomp agent, receiving the seccomp listener
+// file descriptors of the containers.
+type Listener struct {
+	l *net.UnixListener
+}
+
+// Container is a container seccomp listener received by a [Listener].
+type Container struct {
+	// State is the state of the container process sent along the listener
+	// file descriptor, including the seccomp listenerMetadata.
+	State *specs.ContainerProcessState
+	// Fd is the seccomp listener file descriptor, which is to be closed by
+	// the caller.
+	Fd *os.File
+}
+
+// Listen creates the seccomp agent socket at path, which is the seccomp
+// listenerPath of the container configuration. A stale socket at path is
+// removed.
+func Listen(path string) (*Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{l: l}, nil
+}
+
+// Accept waits for a container seccomp listener to be sent to the socket.
+func (l *Listener) Accept() (*Container, error) {
+	conn, err := l.l.AcceptUnix()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	socket, err := conn.File()
+	if err != nil {
+		return nil, err
+	}
+	defer socket.Close()
+	// The file name is the message sent along the file descriptor, which
+	// is the container process state.
+	fd, err := utils.RecvFile(socket)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	var state specs.ContainerProcessState
+	if err := json.Unmarshal([]byte(fd.Name()), &state); err != nil {
+		return nil, fmt.Errorf("invalid container process state: %w", err)
+	}
+	if len(state.Fds) != 1 || state.Fds[0] != specs.SeccompFdName {
+		return nil, fmt.Errorf("unexpected file descriptors %q, expected [%q]", state.Fds, specs.SeccompFdName)
+	}
+	// Get a file with a proper name, for the error messages.
+	newFd, err := unix.FcntlInt(fd.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("fcntl(F_DUPFD_CLOEXEC)", err)
+	}
+	return &Container{
+		State: &state,
+		Fd:    os.NewFile(uintptr(newFd), specs.SeccompFdName),
+	}, nil
+}
+
+// Close closes the socket, and removes it.
+func (l *Listener) Close() error {
+	return l.l.Close()
+}
+
+// Send sends the container seccomp listener c to the seccomp agent
+// listening at path, in the same way as runc does. It allows passing the
+// same listener to multiple agents, such as for monitoring and handling
+// the system calls. Note that each notification is only received by one
+// of the agents reading from the listener.
+func Send(path string, c *Container) error {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	socket, err := conn.(*net.UnixConn).File()
+	if err != nil {
+		return err
+	}
+	defer socket.Close()
+	state, err := json.Marshal(c.State)
+	if err != nil {
+		return err
+	}
+	err = utils.SendRawFd(socket, string(state), c.Fd.Fd())
+	runtime.KeepAlive(c.Fd)
+	return err
+}
@@ -0,0 +1,228 @@
+// Package notify implements the seccomp user notification protocol, for
+// writing seccomp agents: the programs handling the system calls of the
+// container processes for which the seccomp profile action is
+// SCMP_ACT_NOTIFY.
+//
+// runc sends the seccomp listener file descriptor of a container to the
+// agent listening on the seccomp listenerPath of the container configuration
+// (see [Listen]). The agent then receives the system call notifications from
+// the listener, and responds to them (see [Agent]).
+package notify
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// seccompNotif is struct seccomp_notif.
+type seccompNotif struct {
+	id    uint64
+	pid   uint32
+	flags uint32
+	data  seccompData
+}
+
+// seccompData is struct seccomp_data.
+type seccompData struct {
+	nr                 int32
+	arch               uint32
+	instructionPointer uint64
+	args               [6]uint64
+}
+
+// seccompNotifResp is struct seccomp_notif_resp.
+type seccompNotifResp struct {
+	id    uint64
+	val   int64
+	error int32
+	flags uint32
+}
+
+// seccompNotifAddFD is struct seccomp_notif_addfd.
+type seccompNotifAddFD struct {
+	id         uint64
+	flags      uint32
+	srcfd      uint32
+	newfd      uint32
+	newfdFlags uint32
+}
+
+// Request is a system call notification.
+type Request struct {
+	// ID is the notification ID, which is unique for the listener.
+	ID uint64
+	// Pid is the PID (in the agent PID namespace) of the process which made
+	// the system call.
+	Pid uint32
+	// Nr is the system call number, for the Arch architecture.
+	Nr int32
+	// Arch is the architecture of the system call, as an AUDIT_ARCH_*
+	// value.
+	Arch uint32
+	// InstructionPointer is the address of the system call instruction.
+	InstructionPointer uint64
+	// Args are the system call arguments.
+	Args [6]uint64
+
+	fd *os.File
+}
+
+// Response is the response to a system call notification.
+type Response struct {
+	// Val is the system call return value, when Error is 0.
+	Val int64
+	// Error is the system call error.
+	Error unix.Errno
+	// Continue makes the kernel execute the system call, as if it was
+	// not intercepted. Val and Error must be 0. Note that this is not
+	// safe to use for security decisions, as the process memory may be
+	// changed between the notification and the system call execution.
+	Continue bool
+	// Responded means that the response was already sent by the handler
+	// (see [Request.AddFD]), so no response is to be sent.
+	Responded bool
+}
+
+// Continue is the response executing the system call.
+func Continue() Response {
+	return Response{Continue: true}
+}
+
+// Errno is the response failing the system call with err.
+func Errno(err unix.Errno) Response {
+	return Response{Error: err}
+}
+
+// Return is the response making the system call return val.
+func Return(val int64) Response {
+	return Response{Val: val}
+}
+
+// Responded is the response of a handler which has already responded.
+func Responded() Response {
+	return Response{Responded: true}
+}
+
+// ioctl does an ioctl on the listener fd, and returns its result.
+func ioctl(fd *os.File, req uint, arg unsafe.Pointer) (uintptr, error) {
+	conn, err := fd.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		ret   uintptr
+		errno unix.Errno
+	)
+	if err := conn.Control(func(fd uintptr) {
+		for {
+			ret, _, errno = unix.Syscall(unix.SYS_IOCTL, fd, uintptr(req), uintptr(arg))
+			if errno != unix.EINTR {
+				return
+			}
+		}
+	}); err != nil {
+		return 0, err
+	}
+	if errno != 0 {
+		return 0, errno
+	}
+	return ret, nil
+}
+
+// Receive waits for a system call notification from the listener fd.
+// It fails with ENOENT if the process which made the system call was
+// interrupted (e.g. by a signal) in between, in which case the caller
+// should simply retry.
+func Receive(fd *os.File) (*Request, error) {
+	// The kernel requires the structure to be zeroed.
+	var n seccompNotif
+	if _, err := ioctl(fd, unix.SECCOMP_IOCTL_NOTIF_RECV, unsafe.Pointer(&n)); err != nil {
+		return nil, &os.PathError{Op: "seccomp notify receive", Path: fd.Name(), Err: err}
+	}
+	return &Request{
+		ID:                 n.id,
+		Pid:                n.pid,
+		Nr:                 n.data.nr,
+		Arch:               n.data.arch,
+		InstructionPointer: n.data.instructionPointer,
+		Args:               n.data.args,
+		fd:                 fd,
+	}, nil
+}
+
+// Respond sends the response r to the notification req. It fails with
+// ENOENT if the process which made the system call is no longer waiting for
+// the response (e.g. it was killed).
+func (req *Request) Respond(r Response) error {
+	resp := seccompNotifResp{id: req.ID, val: r.Val, error: -int32(r.Error)}
+	if r.Continue {
+		if r.Val != 0 || r.Error != 0 {
+			return errors.New("seccomp notify: the continue response must have no value and error")
+		}
+		resp.flags = unix.SECCOMP_USER_NOTIF_FLAG_CONTINUE
+	}
+	if _, err := ioctl(req.fd, unix.SECCOMP_IOCTL_NOTIF_SEND, unsafe.Pointer(&resp)); err != nil {
+		return &os.PathError{Op: "seccomp notify send", Path: req.fd.Name(), Err: err}
+	}
+	return nil
+}
+
+// Valid checks that the process which made the system call is still waiting
+// for the response. It must be called after reading the process memory (such
+// as with [Request.ReadString]), and before acting on it, as the PID may have
+// been reused by another process in between.
+func (req *Request) Valid() bool {
+	id := req.ID
+	_, err := ioctl(req.fd, unix.SECCOMP_IOCTL_NOTIF_ID_VALID, unsafe.Pointer(&id))
+	return err == nil
+}
+
+// AddFD installs a copy of the file descriptor of f in the process which
+// made the system call, and returns its number there. If respond is true,
+// the system call returns the new file descriptor number (as for open),
+// and the response must not be sent with [Request.Respond]. The new file
+// descriptor has the O_CLOEXEC flag set if cloexec is true.
+//
+// This requires Linux 5.9 (or 5.14 for respond).
+func (req *Request) AddFD(f *os.File, cloexec, respond bool) (int, error) {
+	addfd := seccompNotifAddFD{id: req.ID, srcfd: uint32(f.Fd())}
+	if cloexec {
+		addfd.newfdFlags = unix.O_CLOEXEC
+	}
+	if respond {
+		addfd.flags = unix.SECCOMP_ADDFD_FLAG_SEND
+	}
+	newfd, err := ioctl(req.fd, unix.SECCOMP_IOCTL_NOTIF_ADDFD, unsafe.Pointer(&addfd))
+	if err != nil {
+		return -1, &os.PathError{Op: "seccomp notify addfd", Path: req.fd.Name(), Err: err}
+	}
+	return int(newfd), nil
+}
+
+// ReadString reads a NUL-terminated string, such as a path, of at most
+// unix.PathMax bytes, at the address addr of the process which made the
+// system call. The result must not be used before checking that the request
+// is still valid (see [Request.Valid]).
+func (req *Request) ReadString(addr uint64) (string, error) {
+	mem, err := os.Open("/proc/" + strconv.FormatUint(uint64(req.Pid), 10) + "/mem")
+	if err != nil {
+		return "", err
+	}
+	defer mem.Close()
+	buf := make([]byte, unix.PathMax)
+	n, err := mem.ReadAt(buf, int64(addr))
+	if n == 0 && err != nil {
+		return "", err
+	}
+	buf = buf[:n]
+	for i, c := range buf {
+		if c == 0 {
+			return string(buf[:i]), nil
+		}
+	}
+	return "", unix.ENAMETOOLONG
+}
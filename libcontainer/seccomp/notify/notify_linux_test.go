@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	childEnv = "_SECCOMP_NOTIFY_TEST_CHILD"
+	// childSkip is the exit code of the child if seccomp notify is not
+	// supported.
+	childSkip = 42
+)
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(childEnv); path != "" {
+		if err := child(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// child installs a seccomp filter notifying about mkdirat, sends the
+// listener to the agent at path, and checks the results of mkdirat calls.
+func child(path string) error {
+	// The seccomp filter is only installed for this thread.
+	runtime.LockOSThread()
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+	filter := []unix.SockFilter{
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: 0}, // seccomp_data.nr
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 1, K: unix.SYS_MKDIRAT},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_USER_NOTIF},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ALLOW},
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	fd, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER,
+		unix.SECCOMP_FILTER_FLAG_NEW_LISTENER, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		os.Exit(childSkip)
+	}
+	listener := os.NewFile(fd, "seccomp")
+	err := Send(path, &Container{
+		State: &specs.ContainerProcessState{
+			Fds:      []string{specs.SeccompFdName},
+			Metadata: "test",
+		},
+		Fd: listener,
+	})
+	if err != nil {
+		return err
+	}
+	listener.Close()
+
+	dir := filepath.Dir(path)
+	if err := unix.Mkdirat(unix.AT_FDCWD, filepath.Join(dir, "deny"), 0o755); !errors.Is(err, unix.ENOMEDIUM) {
+		return fmt.Errorf("deny: expected ENOMEDIUM, got %v", err)
+	}
+	if err := unix.Mkdirat(unix.AT_FDCWD, filepath.Join(dir, "continue"), 0o755); err != nil {
+		return fmt.Errorf("continue: %w", err)
+	}
+	// The mkdirat return value is the new file descriptor number.
+	name, err := unix.BytePtrFromString("addfd")
+	if err != nil {
+		return err
+	}
+	fdcwd := unix.AT_FDCWD
+	newfd, _, errno := unix.Syscall(unix.SYS_MKDIRAT, uintptr(fdcwd), uintptr(unsafe.Pointer(name)), 0o755)
+	switch errno {
+	case 0:
+		var st unix.Stat_t
+		if err := unix.Fstat(int(newfd), &st); err != nil {
+			return fmt.Errorf("addfd: %w", err)
+		}
+		if st.Rdev != unix.Mkdev(1, 3) {
+			return fmt.Errorf("addfd: expected /dev/null, got rdev %d", st.Rdev)
+		}
+	case unix.EOPNOTSUPP:
+		// Not supported by the kernel.
+	default:
+		return fmt.Errorf("addfd: %w", errno)
+	}
+	return nil
+}
+
+func TestAgent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "agent.sock")
+	l, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	devNull, err := os.Open("/dev/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+
+	a := &Agent{}
+	a.Handle(unix.SYS_MKDIRAT, func(c *Container, req *Request) Response {
+		if c.State.Metadata != "test" {
+			return Errno(unix.EINVAL)
+		}
+		name, err := req.ReadString(req.Args[1])
+		if err != nil || !req.Valid() {
+			return Errno(unix.EFAULT)
+		}
+		switch filepath.Base(name) {
+		case "deny":
+			return Errno(unix.ENOMEDIUM)
+		case "continue":
+			return Continue()
+		case "addfd":
+			if _, err := req.AddFD(devNull, true, true); err != nil {
+				return Errno(unix.EOPNOTSUPP)
+			}
+			return Responded()
+		}
+		return Errno(unix.EINVAL)
+	})
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), childEnv+"="+path)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := l.Accept()
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- a.Serve(c)
+	}()
+
+	err = cmd.Wait()
+	if cmd.ProcessState.ExitCode() == childSkip {
+		t.Skip("seccomp user notification is not supported")
+	}
+	if err != nil {
+		t.Fatalf("child: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "continue")); err != nil {
+		t.Fatalf("continue: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package profiles
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// rule is a rule of a profile for a single system call.
+type rule struct {
+	action   specs.LinuxSeccompAction
+	errnoRet *uint
+	args     []specs.LinuxSeccompArg
+}
+
+// key returns the string identifying the action and conditions of r, used
+// to merge the rules with the same ones.
+func (r rule) key() string {
+	var b strings.Builder
+	b.WriteString(string(r.action))
+	if r.errnoRet != nil {
+		fmt.Fprintf(&b, "/%d", *r.errnoRet)
+	}
+	for _, a := range r.args {
+		fmt.Fprintf(&b, " %d:%s:%d:%d", a.Index, a.Op, a.Value, a.ValueTwo)
+	}
+	return b.String()
+}
+
+// ruleSet is the rules of a profile, by system call name.
+type ruleSet struct {
+	// allow are the rules allowing the system calls. A rule without
+	// arguments is unconditional, and is then the only one.
+	allow map[string][]rule
+	// other are the other rules (such as the ones returning a specific
+	// errno), for the system calls which are not allowed.
+	other map[string][]rule
+}
+
+func newRuleSet(p *specs.LinuxSeccomp) (*ruleSet, error) {
+	if isAllowAction(p.DefaultAction) {
+		return nil, fmt.Errorf("only the profiles denying the system calls by default can be composed (default action %s)", p.DefaultAction)
+	}
+	rs := &ruleSet{allow: make(map[string][]rule), other: make(map[string][]rule)}
+	for _, call := range p.Syscalls {
+		r := rule{action: call.Action, errnoRet: call.ErrnoRet, args: call.Args}
+		for _, name := range call.Names {
+			if isAllowAction(call.Action) {
+				rs.addAllow(name, r)
+			} else {
+				rs.other[name] = append(rs.other[name], r)
+			}
+		}
+	}
+	return rs, nil
+}
+
+// isAllowAction returns whether a makes the system calls succeed.
+func isAllowAction(a specs.LinuxSeccompAction) bool {
+	return a == specs.ActAllow || a == specs.ActLog
+}
+
+// addAllow adds the allow rule r of the system call name.
+func (rs *ruleSet) addAllow(name string, r rule) {
+	rules := rs.allow[name]
+	if len(rules) == 1 && len(rules[0].args) == 0 {
+		// Already unconditionally allowed.
+		return
+	}
+	if len(r.args) == 0 {
+		rs.allow[name] = []rule{r}
+		return
+	}
+	if !slices.ContainsFunc(rules, func(o rule) bool { return o.key() == r.key() }) {
+		rs.allow[name] = append(rules, r)
+	}
+}
+
+// profile returns the profile with the rules of rs, and the other settings
+// of base.
+func (rs *ruleSet) profile(base *specs.LinuxSeccomp) *specs.LinuxSeccomp {
+	p := *base
+	p.Syscalls = nil
+	// Group the system calls with the same rules, in a stable order.
+	byKey := make(map[string]int)
+	add := func(rules map[string][]rule) {
+		for _, name := range slices.Sorted(maps.Keys(rules)) {
+			for _, r := range rules[name] {
+				k := r.key()
+				if i, ok := byKey[k]; ok {
+					p.Syscalls[i].Names = append(p.Syscalls[i].Names, name)
+					continue
+				}
+				byKey[k] = len(p.Syscalls)
+				p.Syscalls = append(p.Syscalls, specs.LinuxSyscall{
+					Names:    []string{name},
+					Action:   r.action,
+					ErrnoRet: r.errnoRet,
+					Args:     r.args,
+				})
+			}
+		}
+	}
+	add(rs.allow)
+	add(rs.other)
+	return &p
+}
+
+// Union returns the profile allowing the system calls which are allowed by
+// any of profiles. The profiles must deny the system calls by default. The
+// default action, flags, and listener of the result are those of the first
+// profile.
+func Union(profiles ...*specs.LinuxSeccomp) (*specs.LinuxSeccomp, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no profile to compose")
+	}
+	res := &ruleSet{allow: make(map[string][]rule), other: make(map[string][]rule)}
+	var others []map[string][]rule
+	for _, p := range profiles {
+		rs, err := newRuleSet(p)
+		if err != nil {
+			return nil, err
+		}
+		for name, rules := range rs.allow {
+			for _, r := range rules {
+				res.addAllow(name, r)
+			}
+		}
+		others = append(others, rs.other)
+	}
+	// The other rules only apply to the system calls no profile allows,
+	// the first profile having some taking precedence.
+	for _, other := range others {
+		for name, rules := range other {
+			if _, ok := res.allow[name]; ok {
+				continue
+			}
+			if _, ok := res.other[name]; !ok {
+				res.other[name] = rules
+			}
+		}
+	}
+
+	p := res.profile(profiles[0])
+	p.Architectures = nil
+	for _, prof := range profiles {
+		if len(prof.Architectures) == 0 {
+			continue
+		}
+		for _, arch := range prof.Architectures {
+			if !slices.Contains(p.Architectures, arch) {
+				p.Architectures = append(p.Architectures, arch)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Intersection returns the profile allowing the system calls which are
+// allowed by all of profiles. The profiles must deny the system calls by
+// default. The default action, flags, and listener of the result are those
+// of the first profile.
+//
+// The conditions of the rules allowing a system call are combined, unless
+// those compare the same argument differently, in which case the system
+// call is only allowed by the other combinations (if any).
+func Intersection(profiles ...*specs.LinuxSeccomp) (*specs.LinuxSeccomp, error) {
+	if len(profiles) == 0 {
+		return nil, errors.New("no profile to compose")
+	}
+	sets := make([]*ruleSet, len(profiles))
+	for i, p := range profiles {
+		rs, err := newRuleSet(p)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = rs
+	}
+
+	res := &ruleSet{allow: make(map[string][]rule), other: make(map[string][]rule)}
+	for name, rules := range sets[0].allow {
+		for _, rs := range sets[1:] {
+			rules = intersectRules(rules, rs.allow[name])
+		}
+		for _, r := range rules {
+			res.addAllow(name, r)
+		}
+	}
+	for _, rs := range sets {
+		for name, rules := range rs.other {
+			if _, ok := res.allow[name]; ok {
+				continue
+			}
+			if _, ok := res.other[name]; !ok {
+				res.other[name] = rules
+			}
+		}
+	}
+
+	p := res.profile(profiles[0])
+	for _, prof := range profiles[1:] {
+		if len(prof.Architectures) == 0 || len(p.Architectures) == 0 {
+			// Only the native architecture.
+			p.Architectures = nil
+			break
+		}
+		p.Architectures = slices.DeleteFunc(slices.Clone(p.Architectures), func(arch specs.Arch) bool {
+			return !slices.Contains(prof.Architectures, arch)
+		})
+		if len(p.Architectures) == 0 {
+			return nil, errors.New("the profiles have no architecture in common")
+		}
+	}
+	return p, nil
+}
+
+// intersectRules returns the rules allowing a system call when both a and b
+// do.
+func intersectRules(a, b []rule) []rule {
+	var res []rule
+	for _, ra := range a {
+		for _, rb := range b {
+			if args, ok := combineArgs(ra.args, rb.args); ok {
+				res = append(res, rule{action: ra.action, errnoRet: ra.errnoRet, args: args})
+			}
+		}
+	}
+	return res
+}
+
+// combineArgs returns the conditions of both a and b, or false if those
+// compare the same argument differently.
+func combineArgs(a, b []specs.LinuxSeccompArg) ([]specs.LinuxSeccompArg, bool) {
+	args := slices.Clone(a)
+	for _, arg := range b {
+		i := slices.IndexFunc(args, func(o specs.LinuxSeccompArg) bool { return o.Index == arg.Index })
+		if i == -1 {
+			args = append(args, arg)
+			continue
+		}
+		if args[i] != arg {
+			return nil, false
+		}
+	}
+	slices.SortStableFunc(args, func(x, y specs.LinuxSeccompArg) int { return int(x.Index) - int(y.Index) })
+	return args, true
+}
+
+// removeSyscalls returns syscalls without the rules of names.
+func removeSyscalls(syscalls []specs.LinuxSyscall, names ...string) []specs.LinuxSyscall {
+	var res []specs.LinuxSyscall
+	for _, call := range syscalls {
+		call.Names = slices.DeleteFunc(slices.Clone(call.Names), func(name string) bool {
+			return slices.Contains(names, name)
+		})
+		if len(call.Names) > 0 {
+			res = append(res, call)
+		}
+	}
+	return res
+}
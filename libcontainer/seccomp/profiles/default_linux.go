@@ -0,0 +1,514 @@
+package profiles
+
+import (
+	"runtime"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+	"golang.org/x/sys/unix"
+)
+
+// defaultSyscalls are the system calls unconditionally allowed by the
+// default profile. Those are the ones allowed by the Docker and containerd
+// default profiles without any capability, on a kernel >= 4.8. The names of
+// the system calls which do not exist on an architecture are ignored.
+var defaultSyscalls = []string{
+	"accept",
+	"accept4",
+	"access",
+	"adjtimex",
+	"alarm",
+	"bind",
+	"brk",
+	"cachestat",
+	"capget",
+	"capset",
+	"chdir",
+	"chmod",
+	"chown",
+	"chown32",
+	"clock_adjtime",
+	"clock_adjtime64",
+	"clock_getres",
+	"clock_getres_time64",
+	"clock_gettime",
+	"clock_gettime64",
+	"clock_nanosleep",
+	"clock_nanosleep_time64",
+	"close",
+	"close_range",
+	"connect",
+	"copy_file_range",
+	"creat",
+	"dup",
+	"dup2",
+	"dup3",
+	"epoll_create",
+	"epoll_create1",
+	"epoll_ctl",
+	"epoll_ctl_old",
+	"epoll_pwait",
+	"epoll_pwait2",
+	"epoll_wait",
+	"epoll_wait_old",
+	"eventfd",
+	"eventfd2",
+	"execve",
+	"execveat",
+	"exit",
+	"exit_group",
+	"faccessat",
+	"faccessat2",
+	"fadvise64",
+	"fadvise64_64",
+	"fallocate",
+	"fanotify_mark",
+	"fchdir",
+	"fchmod",
+	"fchmodat",
+	"fchmodat2",
+	"fchown",
+	"fchown32",
+	"fchownat",
+	"fcntl",
+	"fcntl64",
+	"fdatasync",
+	"fgetxattr",
+	"flistxattr",
+	"flock",
+	"fork",
+	"fremovexattr",
+	"fsetxattr",
+	"fstat",
+	"fstat64",
+	"fstatat64",
+	"fstatfs",
+	"fstatfs64",
+	"fsync",
+	"ftruncate",
+	"ftruncate64",
+	"futex",
+	"futex_requeue",
+	"futex_time64",
+	"futex_wait",
+	"futex_waitv",
+	"futex_wake",
+	"futimesat",
+	"get_robust_list",
+	"get_thread_area",
+	"getcpu",
+	"getcwd",
+	"getdents",
+	"getdents64",
+	"getegid",
+	"getegid32",
+	"geteuid",
+	"geteuid32",
+	"getgid",
+	"getgid32",
+	"getgroups",
+	"getgroups32",
+	"getitimer",
+	"getpeername",
+	"getpgid",
+	"getpgrp",
+	"getpid",
+	"getppid",
+	"getpriority",
+	"getrandom",
+	"getresgid",
+	"getresgid32",
+	"getresuid",
+	"getresuid32",
+	"getrlimit",
+	"getrusage",
+	"getsid",
+	"getsockname",
+	"getsockopt",
+	"gettid",
+	"gettimeofday",
+	"getuid",
+	"getuid32",
+	"getxattr",
+	"inotify_add_watch",
+	"inotify_init",
+	"inotify_init1",
+	"inotify_rm_watch",
+	"io_cancel",
+	"io_destroy",
+	"io_getevents",
+	"io_pgetevents",
+	"io_pgetevents_time64",
+	"io_setup",
+	"io_submit",
+	"ioctl",
+	"ioprio_get",
+	"ioprio_set",
+	"ipc",
+	"kill",
+	"landlock_add_rule",
+	"landlock_create_ruleset",
+	"landlock_restrict_self",
+	"lchown",
+	"lchown32",
+	"lgetxattr",
+	"link",
+	"linkat",
+	"listen",
+	"listxattr",
+	"llistxattr",
+	"_llseek",
+	"lremovexattr",
+	"lseek",
+	"lsetxattr",
+	"lstat",
+	"lstat64",
+	"madvise",
+	"map_shadow_stack",
+	"membarrier",
+	"memfd_create",
+	"memfd_secret",
+	"mincore",
+	"mkdir",
+	"mkdirat",
+	"mknod",
+	"mknodat",
+	"mlock",
+	"mlock2",
+	"mlockall",
+	"mmap",
+	"mmap2",
+	"mprotect",
+	"mq_getsetattr",
+	"mq_notify",
+	"mq_open",
+	"mq_timedreceive",
+	"mq_timedreceive_time64",
+	"mq_timedsend",
+	"mq_timedsend_time64",
+	"mq_unlink",
+	"mremap",
+	"msgctl",
+	"msgget",
+	"msgrcv",
+	"msgsnd",
+	"msync",
+	"munlock",
+	"munlockall",
+	"munmap",
+	"name_to_handle_at",
+	"nanosleep",
+	"newfstatat",
+	"_newselect",
+	"open",
+	"openat",
+	"openat2",
+	"pause",
+	"pidfd_open",
+	"pidfd_send_signal",
+	"pipe",
+	"pipe2",
+	"pkey_alloc",
+	"pkey_free",
+	"pkey_mprotect",
+	"poll",
+	"ppoll",
+	"ppoll_time64",
+	"prctl",
+	"pread64",
+	"preadv",
+	"preadv2",
+	"prlimit64",
+	"process_mrelease",
+	"pselect6",
+	"pselect6_time64",
+	"ptrace",
+	"pwrite64",
+	"pwritev",
+	"pwritev2",
+	"read",
+	"readahead",
+	"readlink",
+	"readlinkat",
+	"readv",
+	"recv",
+	"recvfrom",
+	"recvmmsg",
+	"recvmmsg_time64",
+	"recvmsg",
+	"remap_file_pages",
+	"removexattr",
+	"rename",
+	"renameat",
+	"renameat2",
+	"restart_syscall",
+	"rmdir",
+	"rseq",
+	"rt_sigaction",
+	"rt_sigpending",
+	"rt_sigprocmask",
+	"rt_sigqueueinfo",
+	"rt_sigreturn",
+	"rt_sigsuspend",
+	"rt_sigtimedwait",
+	"rt_sigtimedwait_time64",
+	"rt_tgsigqueueinfo",
+	"sched_get_priority_max",
+	"sched_get_priority_min",
+	"sched_getaffinity",
+	"sched_getattr",
+	"sched_getparam",
+	"sched_getscheduler",
+	"sched_rr_get_interval",
+	"sched_rr_get_interval_time64",
+	"sched_setaffinity",
+	"sched_setattr",
+	"sched_setparam",
+	"sched_setscheduler",
+	"sched_yield",
+	"seccomp",
+	"select",
+	"semctl",
+	"semget",
+	"semop",
+	"semtimedop",
+	"semtimedop_time64",
+	"send",
+	"sendfile",
+	"sendfile64",
+	"sendmmsg",
+	"sendmsg",
+	"sendto",
+	"set_robust_list",
+	"set_thread_area",
+	"set_tid_address",
+	"setfsgid",
+	"setfsgid32",
+	"setfsuid",
+	"setfsuid32",
+	"setgid",
+	"setgid32",
+	"setgroups",
+	"setgroups32",
+	"setitimer",
+	"setpgid",
+	"setpriority",
+	"setregid",
+	"setregid32",
+	"setresgid",
+	"setresgid32",
+	"setresuid",
+	"setresuid32",
+	"setreuid",
+	"setreuid32",
+	"setrlimit",
+	"setsid",
+	"setsockopt",
+	"setuid",
+	"setuid32",
+	"setxattr",
+	"shmat",
+	"shmctl",
+	"shmdt",
+	"shmget",
+	"shutdown",
+	"sigaltstack",
+	"signalfd",
+	"signalfd4",
+	"sigprocmask",
+	"sigreturn",
+	"socket",
+	"socketcall",
+	"socketpair",
+	"splice",
+	"stat",
+	"stat64",
+	"statfs",
+	"statfs64",
+	"statx",
+	"symlink",
+	"symlinkat",
+	"sync",
+	"sync_file_range",
+	"syncfs",
+	"sysinfo",
+	"tee",
+	"tgkill",
+	"time",
+	"timer_create",
+	"timer_delete",
+	"timer_getoverrun",
+	"timer_gettime",
+	"timer_gettime64",
+	"timer_settime",
+	"timer_settime64",
+	"timerfd_create",
+	"timerfd_gettime",
+	"timerfd_gettime64",
+	"timerfd_settime",
+	"timerfd_settime64",
+	"times",
+	"tkill",
+	"truncate",
+	"truncate64",
+	"ugetrlimit",
+	"umask",
+	"uname",
+	"unlink",
+	"unlinkat",
+	"utime",
+	"utimensat",
+	"utimensat_time64",
+	"utimes",
+	"vfork",
+	"vmsplice",
+	"wait4",
+	"waitid",
+	"waitpid",
+	"write",
+	"writev",
+}
+
+// archSyscalls are the architecture specific system calls allowed by the
+// default profile, by GOARCH.
+var archSyscalls = map[string][]string{
+	"386":     {"modify_ldt"},
+	"amd64":   {"arch_prctl", "modify_ldt"},
+	"arm":     {"arm_fadvise64_64", "arm_sync_file_range", "breakpoint", "cacheflush", "set_tls", "sync_file_range2"},
+	"arm64":   {"arm_fadvise64_64", "arm_sync_file_range", "breakpoint", "cacheflush", "set_tls", "sync_file_range2"},
+	"ppc64le": {"sync_file_range2", "swapcontext"},
+	"riscv64": {"riscv_flush_icache", "riscv_hwprobe"},
+	"s390x":   {"s390_pci_mmio_read", "s390_pci_mmio_write", "s390_runtime_instr"},
+}
+
+// nativeArchs are the seccomp architectures of the default profile, by
+// GOARCH: the native one, along with those it can run the binaries of.
+var nativeArchs = map[string][]specs.Arch{
+	"386":      {specs.ArchX86},
+	"amd64":    {specs.ArchX86_64, specs.ArchX86, specs.ArchX32},
+	"arm":      {specs.ArchARM},
+	"arm64":    {specs.ArchAARCH64, specs.ArchARM},
+	"mips64":   {specs.ArchMIPS64, specs.ArchMIPS64N32, specs.ArchMIPS},
+	"mips64le": {specs.ArchMIPSEL64, specs.ArchMIPSEL64N32, specs.ArchMIPSEL},
+	"ppc64":    {specs.ArchPPC64, specs.ArchPPC},
+	"ppc64le":  {specs.ArchPPC64LE},
+	"riscv64":  {specs.ArchRISCV64},
+	"s390x":    {specs.ArchS390X, specs.ArchS390},
+}
+
+// The personality(2) values allowed by the default profile, other than
+// PER_LINUX (0) and the query (0xffffffff).
+const (
+	perLinux32 = 0x8
+	uname26    = 0x20000
+)
+
+// cloneNamespaceFlags are the clone flags creating new namespaces, which
+// are not allowed by the default profile.
+const cloneNamespaceFlags = unix.CLONE_NEWNS | unix.CLONE_NEWUTS | unix.CLONE_NEWIPC |
+	unix.CLONE_NEWUSER | unix.CLONE_NEWPID | unix.CLONE_NEWNET | unix.CLONE_NEWCGROUP
+
+// defaultProfile returns the default profile: an allowlist of the system
+// calls a regular container workload needs, which denies the others with
+// EPERM. The system calls requiring privileges (such as mount, or bpf), or
+// able to create namespaces, are not allowed.
+func defaultProfile() *specs.LinuxSeccomp {
+	names := append([]string{}, defaultSyscalls...)
+	names = append(names, archSyscalls[runtime.GOARCH]...)
+
+	cloneArg := uint(0)
+	if runtime.GOARCH == "s390x" {
+		// The clone flags are the second argument on s390.
+		cloneArg = 1
+	}
+	return &specs.LinuxSeccomp{
+		DefaultAction:   specs.ActErrno,
+		DefaultErrnoRet: errnoRet(unix.EPERM),
+		Architectures:   nativeArchs[runtime.GOARCH],
+		Syscalls: []specs.LinuxSyscall{
+			{
+				Names:  names,
+				Action: specs.ActAllow,
+			},
+			{
+				Names:  []string{"personality"},
+				Action: specs.ActAllow,
+				Args:   []specs.LinuxSeccompArg{{Index: 0, Value: 0x0, Op: specs.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: specs.ActAllow,
+				Args:   []specs.LinuxSeccompArg{{Index: 0, Value: perLinux32, Op: specs.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: specs.ActAllow,
+				Args:   []specs.LinuxSeccompArg{{Index: 0, Value: uname26, Op: specs.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: specs.ActAllow,
+				Args:   []specs.LinuxSeccompArg{{Index: 0, Value: uname26 | perLinux32, Op: specs.OpEqualTo}},
+			},
+			{
+				Names:  []string{"personality"},
+				Action: specs.ActAllow,
+				Args:   []specs.LinuxSeccompArg{{Index: 0, Value: 0xffffffff, Op: specs.OpEqualTo}},
+			},
+			{
+				Names:  []string{"clone"},
+				Action: specs.ActAllow,
+				Args:   []specs.LinuxSeccompArg{{Index: cloneArg, Value: cloneNamespaceFlags, ValueTwo: 0, Op: specs.OpMaskedEqual}},
+			},
+			{
+				// The clone3 flags can't be checked, so make the
+				// C libraries fall back to clone.
+				Names:    []string{"clone3"},
+				Action:   specs.ActErrno,
+				ErrnoRet: errnoRet(unix.ENOSYS),
+			},
+		},
+	}
+}
+
+// strictProfile returns the strict profile, which is the default profile
+// restricted to the native architecture, without the system calls rarely
+// needed by containers, which are used to debug or inspect other processes,
+// or were involved in kernel vulnerabilities.
+func strictProfile() *specs.LinuxSeccomp {
+	p := defaultProfile()
+	if len(p.Architectures) > 0 {
+		p.Architectures = p.Architectures[:1]
+	}
+	p.Syscalls = removeSyscalls(p.Syscalls,
+		"fanotify_mark",
+		"memfd_secret",
+		"modify_ldt",
+		"name_to_handle_at",
+		"personality",
+		"process_mrelease",
+		"ptrace",
+		"remap_file_pages",
+		"vmsplice",
+	)
+	return p
+}
+
+// noNetworkProfile returns the no-network profile, which is the default
+// profile only allowing the creation of Unix domain sockets.
+func noNetworkProfile() *specs.LinuxSeccomp {
+	p := defaultProfile()
+	// socketcall multiplexes the socket system calls on some
+	// architectures, and its arguments can't be checked.
+	p.Syscalls = removeSyscalls(p.Syscalls, "socket", "socketcall")
+	p.Syscalls = append(p.Syscalls, specs.LinuxSyscall{
+		Names:  []string{"socket"},
+		Action: specs.ActAllow,
+		Args:   []specs.LinuxSeccompArg{{Index: 0, Value: unix.AF_UNIX, Op: specs.OpEqualTo}},
+	})
+	return p
+}
+
+func errnoRet(errno unix.Errno) *uint {
+	ret := uint(errno)
+	return &ret
+}
@@ -0,0 +1,84 @@
+// Package profiles provides built-in seccomp profiles, in the runtime-spec
+// format, and their composition.
+package profiles
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// The names of the built-in profiles.
+const (
+	// Default is an allowlist of the system calls a regular container
+	// workload needs, similar to the Docker and containerd default profiles
+	// for a container without capabilities.
+	Default = "default"
+	// Strict is the default profile restricted to the native architecture,
+	// without the system calls rarely needed by containers, such as ptrace.
+	Strict = "strict"
+	// NoNetwork is the default profile only allowing the creation of Unix
+	// domain sockets.
+	NoNetwork = "no-network"
+)
+
+var builtin = map[string]func() *specs.LinuxSeccomp{
+	Default:   defaultProfile,
+	Strict:    strictProfile,
+	NoNetwork: noNetworkProfile,
+}
+
+// Names returns the names of the built-in profiles.
+func Names() []string {
+	return slices.Sorted(maps.Keys(builtin))
+}
+
+// Get returns the built-in profile name.
+func Get(name string) (*specs.LinuxSeccomp, error) {
+	p, ok := builtin[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown seccomp profile %q (known profiles: %s)", name, strings.Join(Names(), ", "))
+	}
+	return p(), nil
+}
+
+// Compose returns the profile described by expr: either a single profile
+// name, or a union ("a|b") or an intersection ("a&b") of profiles (see
+// [Union] and [Intersection]). The union and intersection operators can't be
+// mixed. The profiles are looked up by lookup, or [Get] if it is nil.
+func Compose(expr string, lookup func(name string) (*specs.LinuxSeccomp, error)) (*specs.LinuxSeccomp, error) {
+	if lookup == nil {
+		lookup = Get
+	}
+	union, inter := strings.Contains(expr, "|"), strings.Contains(expr, "&")
+	if union && inter {
+		return nil, fmt.Errorf("invalid seccomp profile %q: can't mix unions and intersections", expr)
+	}
+	sep := "|"
+	if inter {
+		sep = "&"
+	}
+	var profiles []*specs.LinuxSeccomp
+	for _, name := range strings.Split(expr, sep) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("invalid seccomp profile %q: empty profile name", expr)
+		}
+		p, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, p)
+	}
+	switch {
+	case len(profiles) == 1:
+		return profiles[0], nil
+	case inter:
+		return Intersection(profiles...)
+	default:
+		return Union(profiles...)
+	}
+}
@@ -0,0 +1,149 @@
+package profiles
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// allowed returns the rules of p allowing name, as their arguments, or nil
+// if it is not allowed. An unconditional rule is an empty (non-nil) slice.
+func allowed(p *specs.LinuxSeccomp, name string) [][]specs.LinuxSeccompArg {
+	var res [][]specs.LinuxSeccompArg
+	for _, call := range p.Syscalls {
+		if isAllowAction(call.Action) && slices.Contains(call.Names, name) {
+			res = append(res, append([]specs.LinuxSeccompArg{}, call.Args...))
+		}
+	}
+	return res
+}
+
+func TestBuiltinProfiles(t *testing.T) {
+	for _, name := range Names() {
+		p, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.DefaultAction != specs.ActErrno {
+			t.Errorf("%s: default action is %s", name, p.DefaultAction)
+		}
+		if allowed(p, "read") == nil {
+			t.Errorf("%s: read is not allowed", name)
+		}
+		for _, denied := range []string{"mount", "bpf", "unshare", "setns", "kexec_load", "io_uring_setup"} {
+			if allowed(p, denied) != nil {
+				t.Errorf("%s: %s is allowed", name, denied)
+			}
+		}
+	}
+	if _, err := Get("nonexistent"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+
+	strict, _ := Get(Strict)
+	if allowed(strict, "ptrace") != nil {
+		t.Error("strict: ptrace is allowed")
+	}
+	noNet, _ := Get(NoNetwork)
+	if rules := allowed(noNet, "socket"); len(rules) != 1 || len(rules[0]) != 1 || rules[0][0].Value != 1 {
+		t.Errorf("no-network: socket rules are %+v, expected AF_UNIX only", rules)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	a := &specs.LinuxSeccomp{
+		DefaultAction: specs.ActErrno,
+		Architectures: []specs.Arch{specs.ArchX86_64, specs.ArchX86},
+		Syscalls: []specs.LinuxSyscall{
+			{Names: []string{"read", "write", "open"}, Action: specs.ActAllow},
+			{Names: []string{"socket"}, Action: specs.ActAllow, Args: []specs.LinuxSeccompArg{{Index: 0, Value: 1, Op: specs.OpEqualTo}}},
+			{Names: []string{"clone3"}, Action: specs.ActErrno, ErrnoRet: errnoRet(38)},
+		},
+	}
+	b := &specs.LinuxSeccomp{
+		DefaultAction: specs.ActKill,
+		Architectures: []specs.Arch{specs.ArchX86_64},
+		Syscalls: []specs.LinuxSyscall{
+			{Names: []string{"read", "write", "socket", "clone3"}, Action: specs.ActAllow},
+			{Names: []string{"open"}, Action: specs.ActAllow, Args: []specs.LinuxSeccompArg{{Index: 1, Value: 0, Op: specs.OpEqualTo}}},
+		},
+	}
+	lookup := func(name string) (*specs.LinuxSeccomp, error) {
+		return map[string]*specs.LinuxSeccomp{"a": a, "b": b}[name], nil
+	}
+
+	union, err := Compose("a|b", lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if union.DefaultAction != specs.ActErrno {
+		t.Errorf("union: default action is %s", union.DefaultAction)
+	}
+	if !slices.Equal(union.Architectures, []specs.Arch{specs.ArchX86_64, specs.ArchX86}) {
+		t.Errorf("union: architectures are %v", union.Architectures)
+	}
+	for _, name := range []string{"read", "write", "open", "socket", "clone3"} {
+		if rules := allowed(union, name); len(rules) != 1 || len(rules[0]) != 0 {
+			t.Errorf("union: %s rules are %+v, expected an unconditional one", name, rules)
+		}
+	}
+
+	inter, err := Compose("a & b", lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(inter.Architectures, []specs.Arch{specs.ArchX86_64}) {
+		t.Errorf("intersection: architectures are %v", inter.Architectures)
+	}
+	if rules := allowed(inter, "read"); len(rules) != 1 || len(rules[0]) != 0 {
+		t.Errorf("intersection: read rules are %+v", rules)
+	}
+	if rules := allowed(inter, "socket"); len(rules) != 1 || len(rules[0]) != 1 || rules[0][0].Index != 0 {
+		t.Errorf("intersection: socket rules are %+v", rules)
+	}
+	if rules := allowed(inter, "open"); len(rules) != 1 || len(rules[0]) != 1 || rules[0][0].Index != 1 {
+		t.Errorf("intersection: open rules are %+v", rules)
+	}
+	if rules := allowed(inter, "clone3"); rules != nil {
+		t.Errorf("intersection: clone3 is allowed: %+v", rules)
+	}
+	for _, call := range inter.Syscalls {
+		if slices.Contains(call.Names, "clone3") && (call.ErrnoRet == nil || *call.ErrnoRet != 38) {
+			t.Errorf("intersection: clone3 rule is %+v", call)
+		}
+	}
+
+	for _, expr := range []string{"a|b&a", "a|", "a|nonexistent"} {
+		if _, err := Compose(expr, nil); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+	allowAll := &specs.LinuxSeccomp{DefaultAction: specs.ActAllow}
+	if _, err := Union(a, allowAll); err == nil {
+		t.Error("expected an error composing a profile allowing all the system calls")
+	}
+}
+
+func TestIntersectionConflictingArgs(t *testing.T) {
+	a := &specs.LinuxSeccomp{
+		DefaultAction: specs.ActErrno,
+		Syscalls: []specs.LinuxSyscall{
+			{Names: []string{"personality"}, Action: specs.ActAllow, Args: []specs.LinuxSeccompArg{{Index: 0, Value: 0, Op: specs.OpEqualTo}}},
+			{Names: []string{"personality"}, Action: specs.ActAllow, Args: []specs.LinuxSeccompArg{{Index: 0, Value: 8, Op: specs.OpEqualTo}}},
+		},
+	}
+	b := &specs.LinuxSeccomp{
+		DefaultAction: specs.ActErrno,
+		Syscalls: []specs.LinuxSyscall{
+			{Names: []string{"personality"}, Action: specs.ActAllow, Args: []specs.LinuxSeccompArg{{Index: 0, Value: 8, Op: specs.OpEqualTo}}},
+		},
+	}
+	p, err := Intersection(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules := allowed(p, "personality"); len(rules) != 1 || rules[0][0].Value != 8 {
+		t.Errorf("personality rules are %+v, expected the value 8 only", rules)
+	}
+}
@@ -5,12 +5,15 @@ package seccomp
 import (
 	"errors"
 	"fmt"
+	"os"
+	"unsafe"
 
 	libseccomp "github.com/seccomp/libseccomp-golang"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/faultinject"
 	"github.com/opencontainers/runc/libcontainer/seccomp/patchbpf"
 	"github.com/opencontainers/runtime-spec/specs-go"
 )
@@ -33,6 +36,9 @@ func InitSeccomp(config *configs.Seccomp) (int, error) {
 	if config == nil {
 		return -1, errors.New("cannot initialize Seccomp - nil config passed")
 	}
+	if err := faultinject.Check(faultinject.SeccompLoad); err != nil {
+		return -1, err
+	}
 
 	defaultAction, err := getAction(config.DefaultAction, config.DefaultErrnoRet)
 	if err != nil {
@@ -192,6 +198,51 @@ func FlagSupported(flag specs.LinuxSeccompFlag) error {
 	return nil
 }
 
+// kernelActions are the kernel return values of the actions, for
+// SECCOMP_GET_ACTION_AVAIL.
+var kernelActions = map[configs.Action]uint32{
+	configs.Kill:        unix.SECCOMP_RET_KILL_THREAD,
+	configs.KillThread:  unix.SECCOMP_RET_KILL_THREAD,
+	configs.KillProcess: unix.SECCOMP_RET_KILL_PROCESS,
+	configs.Errno:       unix.SECCOMP_RET_ERRNO,
+	configs.Trap:        unix.SECCOMP_RET_TRAP,
+	configs.Allow:       unix.SECCOMP_RET_ALLOW,
+	configs.Trace:       unix.SECCOMP_RET_TRACE,
+	configs.Log:         unix.SECCOMP_RET_LOG,
+	configs.Notify:      unix.SECCOMP_RET_USER_NOTIF,
+}
+
+// ActionSupported checks if the action is known to runc and supported by
+// the kernel, and by the currently used libseccomp for SCMP_ACT_NOTIFY.
+func ActionSupported(action string) error {
+	act, err := ConvertStringToAction(action)
+	if err != nil {
+		return err
+	}
+	if act == configs.Notify {
+		if major, minor, _ := Version(); major < 2 || (major == 2 && minor < 5) {
+			return fmt.Errorf("%s requires libseccomp >= 2.5.0", action)
+		}
+	}
+	ret := kernelActions[act]
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_GET_ACTION_AVAIL, 0, uintptr(unsafe.Pointer(&ret)))
+	switch errno {
+	case 0:
+		return nil
+	case unix.EOPNOTSUPP:
+		return fmt.Errorf("%s is not supported by the kernel", action)
+	case unix.EINVAL:
+		// SECCOMP_GET_ACTION_AVAIL (Linux 4.14) is not supported, but
+		// the actions older than it are.
+		switch act {
+		case configs.Kill, configs.KillThread, configs.Errno, configs.Trap, configs.Allow, configs.Trace:
+			return nil
+		}
+		return fmt.Errorf("%s is not supported by the kernel", action)
+	}
+	return os.NewSyscallError("seccomp", errno)
+}
+
 // Convert Libcontainer Action to Libseccomp ScmpAction
 func getAction(act configs.Action, errnoRet *uint) (libseccomp.ScmpAction, error) {
 	switch act {
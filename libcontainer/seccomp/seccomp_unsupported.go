@@ -24,6 +24,11 @@ func FlagSupported(_ specs.LinuxSeccompFlag) error {
 	return ErrSeccompNotEnabled
 }
 
+// ActionSupported tells if a provided seccomp action is supported.
+func ActionSupported(_ string) error {
+	return ErrSeccompNotEnabled
+}
+
 // Version returns major, minor, and micro.
 func Version() (uint, uint, uint) {
 	return 0, 0, 0
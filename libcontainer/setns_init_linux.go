@@ -12,7 +12,9 @@ import (
 
 	"github.com/opencontainers/runc/internal/linux"
 	"github.com/opencontainers/runc/libcontainer/apparmor"
+	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/keys"
+	"github.com/opencontainers/runc/libcontainer/landlock"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -29,7 +31,7 @@ type linuxSetnsInit struct {
 }
 
 func (l *linuxSetnsInit) getSessionRingName() string {
-	return "_ses." + l.config.ContainerID
+	return l.config.Config.Keyring.SessionName(l.config.ContainerID)
 }
 
 func (l *linuxSetnsInit) Init() error {
@@ -64,6 +66,11 @@ func (l *linuxSetnsInit) Init() error {
 			return fmt.Errorf("failed to setup pidfd: %w", err)
 		}
 	}
+	landlockRuleset, err := landlock.NewRuleset(l.config.Config.Landlock)
+	if err != nil {
+		return fmt.Errorf("unable to set up landlock: %w", err)
+	}
+	defer landlockRuleset.Close()
 	if l.config.NoNewPrivileges {
 		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
 			return err
@@ -80,6 +87,10 @@ func (l *linuxSetnsInit) Init() error {
 	if err := setupIOPriority(l.config); err != nil {
 		return err
 	}
+
+	if err := setupMemoryPolicy(l.config.Config); err != nil {
+		return err
+	}
 	// Tell our parent that we're ready to exec. This must be done before the
 	// Seccomp rules have been applied, because we need to be able to read and
 	// write to a socket.
@@ -95,12 +106,12 @@ func (l *linuxSetnsInit) Init() error {
 	// Without NoNewPrivileges seccomp is a privileged operation, so we need to
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
-	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
-		if err != nil {
-			return err
+	if !l.config.NoNewPrivileges {
+		// Landlock is enforced before seccomp, which may deny its syscalls.
+		if err := landlockRuleset.RestrictSelf(); err != nil {
+			return fmt.Errorf("unable to apply landlock ruleset: %w", err)
 		}
-		if err := syncParentSeccomp(l.pipe, seccompFd); err != nil {
+		if err := initExecSeccomp(l.pipe, l.config.Config); err != nil {
 			return err
 		}
 	}
@@ -120,17 +131,18 @@ func (l *linuxSetnsInit) Init() error {
 	if err != nil {
 		return err
 	}
+	if l.config.NoNewPrivileges {
+		if err := landlockRuleset.RestrictSelf(); err != nil {
+			return fmt.Errorf("unable to apply landlock ruleset: %w", err)
+		}
+	}
 	// Set seccomp as close to execve as possible, so as few syscalls take
 	// place afterward (reducing the amount of syscalls that users need to
 	// enable in their seccomp profiles).
-	if l.config.Config.Seccomp != nil && l.config.NoNewPrivileges {
-		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
-		if err != nil {
+	if l.config.NoNewPrivileges {
+		if err := initExecSeccomp(l.pipe, l.config.Config); err != nil {
 			return fmt.Errorf("unable to init seccomp: %w", err)
 		}
-		if err := syncParentSeccomp(l.pipe, seccompFd); err != nil {
-			return err
-		}
 	}
 
 	// Close the pipe to signal that we have completed our init.
@@ -159,3 +171,22 @@ func (l *linuxSetnsInit) Init() error {
 	}
 	return linux.Exec(name, l.config.Args, l.config.Env)
 }
+
+// initExecSeccomp installs the seccomp filter of config, then the filters
+// stacked onto it (see [configs.Config.ExecSeccomp]), sending the seccomp
+// notify listener (if any) to the parent.
+func initExecSeccomp(pipe *syncSocket, config *configs.Config) error {
+	for _, s := range append([]*configs.Seccomp{config.Seccomp}, config.ExecSeccomp...) {
+		if s == nil {
+			continue
+		}
+		seccompFd, err := seccomp.InitSeccomp(s)
+		if err != nil {
+			return err
+		}
+		if err := syncParentSeccomp(pipe, seccompFd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/moby/sys/mountinfo"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// shmResizeAllowed tells whether /dev/shm of a container can be resized in
+// place. This is always the case if the container has its own /dev/shm
+// tmpfs; otherwise it depends on the configured policy.
+func shmResizeAllowed(config *configs.Config) bool {
+	if !config.IsShmShared() {
+		return true
+	}
+	return config.Shm != nil && config.Shm.Policy == configs.ShmPolicyRemount
+}
+
+// setupShm applies the configured /dev/shm size to a shared /dev/shm. A
+// private /dev/shm tmpfs already has the size set in its mount options.
+func setupShm(config *configs.Config) error {
+	if config.Shm == nil || config.Shm.Size <= 0 || !config.IsShmShared() {
+		return nil
+	}
+	if !shmResizeAllowed(config) {
+		logrus.Debugf("/dev/shm is shared, not resizing it (policy %q)", config.Shm.Policy)
+		return nil
+	}
+	_, err := resizeShm(config.Rootfs, config.Shm.Size, config.Rootfs)
+	return err
+}
+
+// resizeShm changes the size of the tmpfs mounted on /dev/shm inside root,
+// keeping all the other mount and superblock options intact. It returns
+// the previous size.
+//
+// If rootfs is set (for a shared /dev/shm), the tmpfs must not be mounted
+// outside of it in the current mount namespace: the tmpfs instances runc
+// creates for the containers are only mounted in their mount namespaces,
+// unlike the ones of the host (such as its /dev/shm).
+func resizeShm(root string, size int64, rootfs string) (int64, error) {
+	rootDir, err := os.OpenFile(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer rootDir.Close()
+
+	fd, err := unix.Openat2(int(rootDir.Fd()), "dev/shm", &unix.OpenHow{
+		Flags:   unix.O_PATH | unix.O_DIRECTORY | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_IN_ROOT | unix.RESOLVE_NO_MAGICLINKS,
+	})
+	if err != nil {
+		return 0, &os.PathError{Op: "openat2", Path: root + "/dev/shm", Err: err}
+	}
+	shm := os.NewFile(uintptr(fd), root+"/dev/shm")
+	defer shm.Close()
+
+	var st unix.Statfs_t
+	if err := unix.Fstatfs(int(shm.Fd()), &st); err != nil {
+		return 0, os.NewSyscallError("fstatfs", err)
+	}
+	if st.Type != unix.TMPFS_MAGIC {
+		return 0, errors.New("/dev/shm is not a tmpfs")
+	}
+	if rootfs != "" {
+		if err := checkShmNotMounted(shm, rootfs); err != nil {
+			return 0, err
+		}
+	}
+
+	// Unlike mount(MS_REMOUNT), reconfiguring a superblock using fspick(2)
+	// only changes the options explicitly set, and it also works for
+	// a mount which is not in our mount namespace.
+	fsfd, err := unix.Fspick(int(shm.Fd()), "", unix.FSPICK_EMPTY_PATH|unix.FSPICK_CLOEXEC)
+	runtime.KeepAlive(shm)
+	if err != nil {
+		return 0, os.NewSyscallError("fspick /dev/shm", err)
+	}
+	defer unix.Close(fsfd)
+	if err := unix.FsconfigSetString(fsfd, "size", strconv.FormatInt(size, 10)); err != nil {
+		return 0, fmt.Errorf("unable to set /dev/shm size to %d: %w", size, err)
+	}
+	if err := unix.FsconfigReconfigure(fsfd); err != nil {
+		return 0, fmt.Errorf("unable to resize /dev/shm: %w", err)
+	}
+	return int64(st.Blocks) * st.Bsize, nil
+}
+
+// checkShmNotMounted returns an error if the filesystem of shm is mounted
+// outside of rootfs in the current mount namespace.
+func checkShmNotMounted(shm *os.File, rootfs string) error {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(shm.Fd()), &st); err != nil {
+		return os.NewSyscallError("fstat", err)
+	}
+	major, minor := int(unix.Major(st.Dev)), int(unix.Minor(st.Dev))
+	mounts, err := mountinfo.GetMounts(func(m *mountinfo.Info) (bool, bool) {
+		return m.Major != major || m.Minor != minor, false
+	})
+	if err != nil {
+		return err
+	}
+	rootfs = filepath.Clean(rootfs)
+	for _, m := range mounts {
+		if m.Mountpoint != rootfs && !strings.HasPrefix(m.Mountpoint, rootfs+"/") {
+			return fmt.Errorf("/dev/shm is not a tmpfs created for a container (it is mounted at %s), not resizing it", m.Mountpoint)
+		}
+	}
+	return nil
+}
+
+// setShm resizes /dev/shm of a running container if the size in config
+// differs from the current one, and returns the previous size, or 0 if it
+// is not resized. If /dev/shm is shared and the policy does not allow to
+// resize it, the size change is dropped from config.
+func (c *Container) setShm(config *configs.Config) (int64, error) {
+	if config.Shm == nil || config.Shm.Size <= 0 {
+		return 0, nil
+	}
+	if c.config.Shm != nil && c.config.Shm.Size == config.Shm.Size {
+		return 0, nil
+	}
+	if !shmResizeAllowed(config) {
+		logrus.Warnf("/dev/shm is shared, not resizing it (policy %q)", config.Shm.Policy)
+		config.Shm = c.config.Shm
+		return 0, nil
+	}
+	var rootfs string
+	if config.IsShmShared() {
+		rootfs = c.config.Rootfs
+	}
+	return resizeShm(c.shmRoot(), config.Shm.Size, rootfs)
+}
+
+// restoreShm sets back the /dev/shm size returned by setShm, after a failed
+// update.
+func (c *Container) restoreShm(size int64) {
+	if size <= 0 {
+		return
+	}
+	if _, err := resizeShm(c.shmRoot(), size, ""); err != nil {
+		logrus.Warnf("Setting back /dev/shm size failed due to error: %v, your state.json and actual configs might be inconsistent.", err)
+	}
+}
+
+func (c *Container) shmRoot() string {
+	return "/proc/" + strconv.Itoa(c.initProcess.pid()) + "/root"
+}
@@ -0,0 +1,77 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestResizeShm(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	root := t.TempDir()
+	shm := filepath.Join(root, "dev/shm")
+	if err := os.MkdirAll(shm, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Mount("shm", shm, "tmpfs", 0, "size=1m"); err != nil {
+		t.Skip(err)
+	}
+	defer unix.Unmount(shm, unix.MNT_DETACH) //nolint:errcheck
+
+	prev, err := resizeShm(root, 2<<20, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev != 1<<20 {
+		t.Errorf("expected a previous size of 1m, got %d", prev)
+	}
+	// Setting back the previous size, as after a failed update.
+	prev, err = resizeShm(root, prev, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev != 2<<20 {
+		t.Errorf("expected a previous size of 2m, got %d", prev)
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(shm, &st); err != nil {
+		t.Fatal(err)
+	}
+	if size := int64(st.Blocks) * st.Bsize; size != 1<<20 {
+		t.Errorf("expected a size of 1m, got %d", size)
+	}
+}
+
+func TestResizeShmMountedElsewhere(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	root := t.TempDir()
+	shm := filepath.Join(root, "dev/shm")
+	if err := os.MkdirAll(shm, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Mount("shm", shm, "tmpfs", 0, "size=1m"); err != nil {
+		t.Skip(err)
+	}
+	defer unix.Unmount(shm, unix.MNT_DETACH) //nolint:errcheck
+	// The same tmpfs, mounted outside of the container rootfs, as the host
+	// /dev/shm would be.
+	other := t.TempDir()
+	if err := unix.Mount(shm, other, "", unix.MS_BIND, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer unix.Unmount(other, unix.MNT_DETACH) //nolint:errcheck
+
+	if _, err := resizeShm(root, 2<<20, root); err == nil {
+		t.Fatal("expected an error for a tmpfs mounted outside of the rootfs")
+	}
+	// A private /dev/shm is not checked.
+	if _, err := resizeShm(root, 2<<20, ""); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+package specconv
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/capabilities"
+)
+
+// IgnoredFields returns the fields of spec which runc understands, but
+// ignores (with a warning) when creating a container on this host, each as
+// "<path>: <reason>". In the strict mode (see [CreateOpts.StrictSpec]), those
+// are an error instead.
+//
+// The cgroup resources which can not be applied (such as those of a cgroup
+// v1 controller which is not mounted, or in the rootless cgroups mode) are
+// only known once the container cgroup is created, so they are not a part of
+// the list, but checked by libcontainer (see [configs.Config.StrictSpec]).
+func IgnoredFields(spec *specs.Spec) ([]string, error) {
+	var ignored []string
+	add := func(path, reason string) {
+		ignored = append(ignored, path+": "+reason)
+	}
+
+	if p := spec.Process; p != nil && p.Capabilities != nil {
+		for _, set := range []struct {
+			name string
+			caps []string
+		}{
+			{"bounding", p.Capabilities.Bounding},
+			{"effective", p.Capabilities.Effective},
+			{"inheritable", p.Capabilities.Inheritable},
+			{"permitted", p.Capabilities.Permitted},
+			{"ambient", p.Capabilities.Ambient},
+		} {
+			unsupported, err := capabilities.Unsupported(set.caps)
+			if err != nil {
+				return nil, err
+			}
+			if len(unsupported) > 0 {
+				add("process.capabilities."+set.name, "unknown or unavailable capabilities "+strings.Join(unsupported, ", "))
+			}
+		}
+	}
+
+	if spec.Linux == nil {
+		return ignored, nil
+	}
+	if p := spec.Linux.Personality; p != nil && len(p.Flags) > 0 {
+		add("linux.personality.flags", "personality flags are not supported")
+	}
+	if r := spec.Linux.Resources; r != nil && r.Memory != nil {
+		if r.Memory.Kernel != nil { //nolint:staticcheck // Ignore SA1019. Need to keep deprecated package for compatibility.
+			add("linux.resources.memory.kernel", "kernel memory settings are not supported")
+		}
+		if r.Memory.KernelTCP != nil { //nolint:staticcheck // Ignore SA1019. Need to keep deprecated package for compatibility.
+			add("linux.resources.memory.kernelTCP", "kernel memory settings are not supported")
+		}
+	}
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type != specs.UserNamespace || ns.Path == "" {
+			continue
+		}
+		// The mappings of the user namespace to join are read from it.
+		if len(spec.Linux.UIDMappings) > 0 {
+			add("linux.uidMappings", "the user namespace path "+ns.Path+" is used instead")
+		}
+		if len(spec.Linux.GIDMappings) > 0 {
+			add("linux.gidMappings", "the user namespace path "+ns.Path+" is used instead")
+		}
+	}
+	return ignored, nil
+}
+
+// checkIgnoredFields returns an error enumerating the fields of spec which
+// would be ignored, if any.
+func checkIgnoredFields(spec *specs.Spec) error {
+	ignored, err := IgnoredFields(spec)
+	if err != nil {
+		return err
+	}
+	if len(ignored) > 0 {
+		return fmt.Errorf("strict spec: %d field(s) can not be honored: %s", len(ignored), strings.Join(ignored, "; "))
+	}
+	return nil
+}
@@ -3,24 +3,33 @@
 package specconv
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"maps"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	systemdDbus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/docker/go-units"
 	dbus "github.com/godbus/dbus/v5"
 	"github.com/opencontainers/cgroups"
 	devices "github.com/opencontainers/cgroups/devices/config"
 	"github.com/opencontainers/runc/internal/linux"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
 	"github.com/opencontainers/runc/libcontainer/internal/userns"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
+	"github.com/opencontainers/runc/libcontainer/seccomp/profiles"
 	libcontainerUtils "github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
@@ -163,6 +172,21 @@ func KnownNamespaces() []string {
 	return res
 }
 
+// SupportedNamespaces returns the list of the known namespaces which the
+// kernel supports.
+// Used by `runc features`.
+func SupportedNamespaces() []string {
+	initMaps()
+	var res []string
+	for k, t := range namespaceMapping {
+		if configs.IsNamespaceSupported(t) {
+			res = append(res, string(k))
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
 // KnownMountOptions returns the list of the known mount options.
 // Used by `runc features`.
 func KnownMountOptions() []string {
@@ -184,6 +208,19 @@ func KnownMountOptions() []string {
 	return res
 }
 
+// KnownRecursiveMountOptions returns the list of the known recursive mount
+// options (such as "rro"), which require mount_setattr(2).
+// Used by `runc features`.
+func KnownRecursiveMountOptions() []string {
+	initMaps()
+	var res []string
+	for k := range recAttrFlags {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // AllowedDevices is the set of devices which are automatically included for
 // all containers.
 //
@@ -336,6 +373,330 @@ var AllowedDevices = []*devices.Device{
 	},
 }
 
+const (
+	// AnnotationShmSize sets the size of the container's /dev/shm. The value
+	// is in bytes, with an optional unit suffix (e.g. "64m").
+	AnnotationShmSize = "org.opencontainers.runc.shm.size"
+
+	// AnnotationShmPolicy is either "skip" (the default) or "remount", and
+	// determines whether the size from [AnnotationShmSize] is applied when
+	// /dev/shm is shared with the host or another container. As "remount"
+	// changes a tmpfs of another container, it is only allowed with
+	// [CreateOpts.AllowShmRemount].
+	AnnotationShmPolicy = "org.opencontainers.runc.shm.policy"
+
+	// AnnotationSwapType enables a swap area managed by runc for the
+	// container. It can be either "file" or "zram".
+	AnnotationSwapType = "org.opencontainers.runc.swap.type"
+
+	// AnnotationSwapSize is the size of the managed swap area, in bytes,
+	// with an optional unit suffix (e.g. "1g").
+	AnnotationSwapSize = "org.opencontainers.runc.swap.size"
+
+	// AnnotationSwapPath is the swap file path for the "file" swap type.
+	AnnotationSwapPath = "org.opencontainers.runc.swap.path"
+
+	// AnnotationPtyDelegate, if set to true, makes runc allocate the
+	// console and exec PTYs from the container's own devpts instance, with
+	// the PTY owned by the container process user.
+	AnnotationPtyDelegate = "org.opencontainers.runc.pty.delegate"
+
+	// AnnotationLandlock is a JSON Landlock configuration, in the format of
+	// the runtime-spec Landlock proposal (the "landlock" process property).
+	AnnotationLandlock = "org.opencontainers.runc.landlock"
+
+	// AnnotationReadonlyCheck enables the verification that the mounts
+	// requested to be read-only actually are. It is either "warn" or "fail".
+	AnnotationReadonlyCheck = "org.opencontainers.runc.readonly.check"
+
+	// AnnotationAllowUnknownNamespaces, if set to true, allows the namespace
+	// types unknown to runc, so that new kernel namespaces can be used
+	// before runc supports them. The type of such a namespace is the name of
+	// its /proc/[pid]/ns file.
+	AnnotationAllowUnknownNamespaces = "org.opencontainers.runc.namespaces.allow-unknown"
+
+	// AnnotationTmpfiles is a list of entries to provision in the container
+	// rootfs, one per line, in the tmpfiles.d(5) format. Only the d, f, L,
+	// c, b and z types are supported, with numeric user and group IDs, and
+	// no age.
+	AnnotationTmpfiles = "org.opencontainers.runc.tmpfiles"
+
+	// AnnotationSysfsWritable is a comma-separated list of paths under /sys
+	// (e.g. "/sys/fs/cgroup") which are writable, while the rest of the /sys
+	// mount is made read-only.
+	AnnotationSysfsWritable = "org.opencontainers.runc.sysfs.writable"
+
+	// AnnotationExecLimits limits the processes executed in the container
+	// (e.g. by "runc exec"). It is a comma-separated list of the limits,
+	// among "concurrent=N", "rate=N/DURATION" (such as "rate=10/1m"), and
+	// "total=N".
+	AnnotationExecLimits = "org.opencontainers.runc.exec.limits"
+
+	// AnnotationProbes is a JSON list of the container probes, run by "runc
+	// probe", such as [{"type": "readiness", "args": ["/bin/check"],
+	// "interval": "10s", "timeout": "1s", "failureThreshold": 3}]. The type
+	// is either "readiness" or "liveness", and the interval, timeout and
+	// failure threshold default to 10s, 1s and 3.
+	AnnotationProbes = "org.opencontainers.runc.probes"
+
+	// AnnotationNetSysctl is a comma-separated list of the network namespace
+	// sysctls set as soon as the container network namespace is created,
+	// before the network interfaces are configured and the hooks are run.
+	// Each entry is either a "key=value" sysctl (such as
+	// "net.core.somaxconn=4096"), or the name of a preset among
+	// "no-ipv6-ra", "no-ipv6", "ip-forward", "unprivileged-ports", and
+	// "unprivileged-ping". The later entries take precedence.
+	AnnotationNetSysctl = "org.opencontainers.runc.net.sysctl"
+
+	// AnnotationIOCostQoS and AnnotationIOCostModel are the cgroup v2
+	// blk-iocost QoS and cost model parameters of block devices, one device
+	// per line, in the io.cost.qos and io.cost.model format (such as
+	// "8:0 enable=1 ctrl=user rpct=95 rlat=5000"). They are set in the root
+	// cgroup, so they apply to all the cgroups, until the container is
+	// deleted and the previous parameters are restored.
+	AnnotationIOCostQoS   = "org.opencontainers.runc.io.cost.qos"
+	AnnotationIOCostModel = "org.opencontainers.runc.io.cost.model"
+
+	// AnnotationPowerHint is the power management hint of the container, as
+	// comma-separated key=value pairs: epp, the energy_performance_preference
+	// of the container CPUs (which requires linux.resources.cpu.cpus), and
+	// uclamp.min and uclamp.max, the utilization clamps of the container
+	// cgroup (such as "epp=performance,uclamp.min=50").
+	AnnotationPowerHint = "org.opencontainers.runc.power.hint"
+
+	// AnnotationMemsMigration is how the memory of the container on the
+	// NUMA nodes removed from its cpuset mems by "runc update" is handled:
+	// "none" (the default), "migrate" (moved off the removed nodes first),
+	// or "reclaim" (as "migrate", reclaiming the memory not fitting on the
+	// remaining nodes first; cgroup v2 only).
+	AnnotationMemsMigration = "org.opencontainers.runc.cpuset.mems-migration"
+
+	// AnnotationRootfsQuota limits the disk usage of the root filesystem,
+	// with a project quota, as a comma-separated list among "size=SIZE"
+	// (in bytes, with an optional unit suffix, such as "size=10g"),
+	// "inodes=N", and "project=ID" (the project ID to use, which by default
+	// is the one of the root filesystem directory, or an unused one).
+	AnnotationRootfsQuota = "org.opencontainers.runc.rootfs.quota"
+
+	// AnnotationMachineID is the machine ID of the container (32
+	// hexadecimal characters), or "auto" to generate a random one, which
+	// is bind mounted read-only over /etc/machine-id in the container.
+	AnnotationMachineID = "org.opencontainers.runc.machine-id"
+
+	// AnnotationVirtualBootID, if set to true, makes the container see a
+	// random boot ID of its own, generated each time it is started, in
+	// /proc/sys/kernel/random/boot_id. See [configs.Config.VirtualBootID].
+	AnnotationVirtualBootID = "org.opencontainers.runc.boot-id.virtual"
+
+	// AnnotationSignalsForward maps the signals runc receives to the ones
+	// it forwards to the container process run in the foreground, as
+	// comma-separated FROM=TO pairs of signal names or numbers (such as
+	// "SIGTERM=SIGINT"), and AnnotationSignalsNoForward lists the signals
+	// which are not forwarded (such as "SIGHUP"). Those can be extended by
+	// the run and exec --forward-signal and --no-forward-signals options.
+	// See [configs.SignalForwarding].
+	AnnotationSignalsForward   = "org.opencontainers.runc.signals.forward"
+	AnnotationSignalsNoForward = "org.opencontainers.runc.signals.no-forward"
+
+	// AnnotationUsernsAuto makes the user namespace of the container (which
+	// must have neither mappings, nor a path) map an ID range allocated
+	// automatically, so that it is unused by the other containers of the
+	// host, as a comma-separated list among "size=N" (the number of IDs,
+	// [DefaultUsernsAutoSize] by default) and "pool=USER" (the user whose
+	// subordinate ID ranges, in both /etc/subuid and /etc/subgid, make up
+	// the pool, [DefaultUsernsAutoPool] by default). An empty value uses the
+	// defaults. See [CreateOpts.AllocateUserns].
+	AnnotationUsernsAuto = "org.opencontainers.runc.userns.auto"
+
+	// AnnotationIPCLimits are the IPC limits of the container IPC namespace,
+	// as a comma-separated list of KEY=VALUE limits, among the System V IPC
+	// "msgmax", "msgmnb", "msgmni", "shmmax", "shmall", "shmmni",
+	// "shm_rmid_forced" (true or false), "semmsl", "semmns", "semopm", and
+	// "semmni", and the POSIX message queue "mqueue.msg_max",
+	// "mqueue.msgsize_max", "mqueue.queues_max", "mqueue.msg_default", and
+	// "mqueue.msgsize_default" (such as "msgmax=64k,semmni=256"). The sizes
+	// in bytes can have a unit suffix. See [configs.IPCLimits].
+	AnnotationIPCLimits = "org.opencontainers.runc.ipc.limits"
+
+	// AnnotationHooks is a JSON object of the hook execution options, by
+	// hook type, in the order of the hooks of that type, such as
+	// {"createRuntime": [{"parallel": true}, {"parallel": true, "retries": 2}],
+	// "poststop": [{"continueOnError": true}]}. The adjacent hooks with
+	// "parallel" set run concurrently, a failed hook is run again up to
+	// "retries" times, and the failure of a hook with "continueOnError" set
+	// is only logged. A prestart or createRuntime hook with "async" set is
+	// started without being waited for, and the container is only created
+	// once it is ready, which it tells on the socket it gets as fd 3 (see
+	// [configs.AsyncHook]).
+	AnnotationHooks = "org.opencontainers.runc.hooks"
+
+	// AnnotationDependsOn is a comma-separated list of the containers which
+	// "runc daemon" waits for before starting the container, each as
+	// "<id>" or "<id>:<condition>". The condition is either "started" (the
+	// container is running), or "ready" (the default: the container is
+	// running, and its readiness probes, if any, are passing).
+	AnnotationDependsOn = "org.opencontainers.runc.depends-on"
+
+	// AnnotationDependsOnTimeout is how long "runc daemon" waits for the
+	// dependencies of [AnnotationDependsOn], such as "30s". The default is
+	// five minutes.
+	AnnotationDependsOnTimeout = "org.opencontainers.runc.depends-on.timeout"
+
+	// AnnotationStopSignal is the signal "runc daemon" sends to the container
+	// when the host shuts down (such as "SIGINT"), overriding its
+	// --stop-signal option.
+	AnnotationStopSignal = "org.opencontainers.runc.stop.signal"
+
+	// AnnotationStopGracePeriod is how long "runc daemon" waits for the
+	// container to stop on host shutdown before killing it (such as "30s"),
+	// overriding its --stop-grace-period option.
+	AnnotationStopGracePeriod = "org.opencontainers.runc.stop.grace-period"
+
+	// AnnotationHelperCgroup is the name of the sub-cgroup of the container
+	// cgroup which the helper processes runc runs on behalf of the container
+	// (such as the criu lazy-pages daemon, or the probe runner of "runc
+	// run") are moved into, so that those are accounted to the container.
+	// It defaults to [DefaultHelperCgroup]. If empty, the helpers are not
+	// moved.
+	AnnotationHelperCgroup = "org.opencontainers.runc.helpers.cgroup"
+
+	// DefaultHelperCgroup is the default [AnnotationHelperCgroup] value.
+	DefaultHelperCgroup = "runc-helpers"
+
+	// AnnotationSeccompProfile is the seccomp profile of the container,
+	// made of the built-in profiles (see [profiles.Names]): either a single
+	// profile name, or their union ("a|b") or intersection ("a&b"). The
+	// linux.seccomp profile of the spec, if any, must be a part of it, as
+	// [SeccompProfileSpec] (such as "spec&no-network").
+	AnnotationSeccompProfile = "org.opencontainers.runc.seccomp.profile"
+
+	// SeccompProfileSpec is the name of the linux.seccomp profile of the
+	// spec in [AnnotationSeccompProfile].
+	SeccompProfileSpec = "spec"
+
+	// AnnotationInitCPUAffinity is the CPU affinity of the container init
+	// process, as a JSON object in the format of process.execCPUAffinity
+	// (such as {"initial": "0", "final": "2-3"}).
+	AnnotationInitCPUAffinity = "org.opencontainers.runc.init.cpu-affinity"
+
+	// AnnotationInitSignalsBlocked and AnnotationInitSignalsIgnored are the
+	// signals the container init process starts with blocked, and ignored,
+	// as comma-separated signal names or numbers (such as "SIGPIPE,USR1").
+	// If AnnotationInitSignalsInherit is set to true, the signal mask and
+	// the ignored signals runc inherits are kept, rather than being reset.
+	// See [configs.InitSignals].
+	AnnotationInitSignalsBlocked = "org.opencontainers.runc.init.signals.blocked"
+	AnnotationInitSignalsIgnored = "org.opencontainers.runc.init.signals.ignored"
+	AnnotationInitSignalsInherit = "org.opencontainers.runc.init.signals.inherit"
+
+	// AnnotationNetDevices is a JSON object of the settings of the network
+	// devices of linux.netDevices, by device name, with the optional
+	// "mtu", "hardwareAddress", "txQueueLen", and "queues" (the number of
+	// combined queues) fields, such as {"eth1": {"mtu": 9000, "queues": 4}}.
+	// Those are applied in the container network namespace.
+	AnnotationNetDevices = "org.opencontainers.runc.netdevices"
+
+	// AnnotationKeepNetns, if set to true, makes runc keep the network
+	// namespace it creates for the container once the container is deleted,
+	// and reuse it (along with its network devices) for the next container
+	// with the same ID. See [configs.Config.KeepNetns].
+	AnnotationKeepNetns = "org.opencontainers.runc.netns.keep"
+
+	// AnnotationIRQAffinity, if set to true, makes runc set the affinity of
+	// the interrupts of the PCI network devices in the container network
+	// namespace to the container cpuset (linux.resources.cpu.cpus), which
+	// must both be set. The previous affinities are restored once the
+	// container is deleted.
+	AnnotationIRQAffinity = "org.opencontainers.runc.irq.affinity"
+
+	// AnnotationMemoryPolicy is the NUMA memory policy of the container
+	// processes, as a JSON object in the format of the linux.memoryPolicy
+	// proposal of the OCI runtime spec, with the "mode" (such as
+	// "MPOL_BIND"), the "nodes" list (such as "0-1"), and the "flags"
+	// (such as ["MPOL_F_STATIC_NODES"]). See [configs.MemoryPolicy].
+	AnnotationMemoryPolicy = "org.opencontainers.runc.memory.policy"
+
+	// AnnotationPropagationPaths is a JSON object of the mount propagation
+	// (such as "rshared", "slave", or "private") of paths in the container,
+	// applied after linux.rootfsPropagation, such as
+	// {"/var/lib/kubelet": "rshared"}. A path which is not a mount point is
+	// bind mounted onto itself first.
+	AnnotationPropagationPaths = "org.opencontainers.runc.propagation.paths"
+
+	// AnnotationNamespacesOwner is a JSON object of the expected owner of
+	// the existing namespaces to join (those with a path), by namespace
+	// type, such as {"network": {"uid": 1000, "userns_inode": 4026532385}}.
+	// The fields are "uid" (the owner UID of the owning user namespace),
+	// "userns_inode" (the inode number of the owning user namespace), and
+	// "inode" (the inode number of the namespace). See
+	// [configs.NamespaceOwner].
+	AnnotationNamespacesOwner = "org.opencontainers.runc.namespaces.owner"
+
+	// AnnotationNamespacesOwnerCheck is the policy of the verification of
+	// [AnnotationNamespacesOwner], either "fail" (the default) or "warn".
+	AnnotationNamespacesOwnerCheck = "org.opencontainers.runc.namespaces.owner.check"
+
+	// AnnotationIntelRdtShared, if set to true, makes the linux.intelRdt
+	// closID group shared by the containers which use it: runc creates it
+	// for the first one, and removes it along with the last one. See
+	// [configs.IntelRdt.Shared].
+	AnnotationIntelRdtShared = "org.opencontainers.runc.intelrdt.shared"
+
+	// AnnotationEnvDefaults is the policy of the default environment
+	// variables (PATH, HOME, and TERM with a terminal) of the container
+	// processes, both the init and the exec ones: "always", "if-missing",
+	// or "never". If unset, only HOME is added if missing. See
+	// [configs.EnvDefaults].
+	AnnotationEnvDefaults = "org.opencontainers.runc.env.defaults"
+
+	// AnnotationKeyring is a JSON object of the configuration of the
+	// session keyring of the container: its "name" (after the "_ses."
+	// prefix, the default being the container ID), the host keys to link
+	// into it ("links", a list of objects with the key "type" and
+	// "description"), and the minimum host per-user key "quota" (an object
+	// with "maxkeys" and "maxbytes"), such as {"name": "krb5", "links":
+	// [{"type": "user", "description": "krb5cc"}]}. See [configs.Keyring].
+	// As it gives the container access to host keys, it is only allowed
+	// with [CreateOpts.AllowKeyring].
+	AnnotationKeyring = "org.opencontainers.runc.keyring"
+)
+
+const (
+	// DefaultUsernsAutoSize is the default number of IDs allocated for
+	// [AnnotationUsernsAuto].
+	DefaultUsernsAutoSize = 65536
+	// DefaultUsernsAutoPool is the default user whose subordinate ID ranges
+	// make up the pool of [AnnotationUsernsAuto].
+	DefaultUsernsAutoPool = "runc"
+)
+
+// netSysctlPresets are the presets usable in [AnnotationNetSysctl].
+var netSysctlPresets = map[string]map[string]string{
+	// Do not autoconfigure IPv6 addresses from router advertisements.
+	"no-ipv6-ra": {
+		"net.ipv6.conf.all.accept_ra":     "0",
+		"net.ipv6.conf.default.accept_ra": "0",
+	},
+	// Disable IPv6 on all the interfaces.
+	"no-ipv6": {
+		"net.ipv6.conf.all.disable_ipv6":     "1",
+		"net.ipv6.conf.default.disable_ipv6": "1",
+	},
+	// Forward the IPv4 and IPv6 packets between the interfaces.
+	"ip-forward": {
+		"net.ipv4.ip_forward":          "1",
+		"net.ipv6.conf.all.forwarding": "1",
+	},
+	// Let unprivileged processes bind to any port.
+	"unprivileged-ports": {
+		"net.ipv4.ip_unprivileged_port_start": "0",
+	},
+	// Let all the users create ICMP echo sockets.
+	"unprivileged-ping": {
+		"net.ipv4.ping_group_range": "0 2147483647",
+	},
+}
+
 type CreateOpts struct {
 	CgroupName       string
 	UseSystemdCgroup bool
@@ -344,22 +705,58 @@ type CreateOpts struct {
 	Spec             *specs.Spec
 	RootlessEUID     bool
 	RootlessCgroups  bool
+	// MountPolicy is the mount flags policy to enforce on the bind mounts.
+	MountPolicy *configs.MountPolicy
+	// Bundle is the absolute path of the bundle directory, which the
+	// relative paths of the spec are relative to. If empty, the current
+	// directory is used.
+	Bundle string
+	// SpecDigest is the digest of the spec file, recorded in the container
+	// configuration (see [configs.Config.SpecDigest]).
+	SpecDigest string
+	// StrictSpec makes the spec fields which runc understands, but can not
+	// honor on this host (see [IgnoredFields]), an error rather than being
+	// ignored with a warning.
+	StrictSpec bool
+	// SchedCore makes the container processes share a core scheduling
+	// cookie (see [configs.Config.SchedCore]).
+	SchedCore bool
+	// AllocateUserns, if set, allocates a range of size IDs from the
+	// subordinate ID ranges of poolUser, unused by the other containers,
+	// and returns its first host ID, for [AnnotationUsernsAuto].
+	AllocateUserns func(poolUser string, size int64) (int64, error)
+	// StateBlobs stores the large fields of the container state in shared
+	// blobs (see [configs.Config.StateBlobs]).
+	StateBlobs bool
+	// AllowKeyring allows [AnnotationKeyring].
+	AllowKeyring bool
+	// AllowShmRemount allows the "remount" [AnnotationShmPolicy].
+	AllowShmRemount bool
 }
 
 // CreateLibcontainerConfig creates a new libcontainer configuration from a
 // given specification and a cgroup name
 func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
-	// Runc's cwd will always be the bundle path.
+	// Unless set, runc's cwd will always be the bundle path.
 	// Use the value from the kernel, which guarantees the returned value
 	// to be absolute and clean.
-	cwd, err := linux.Getwd()
-	if err != nil {
-		return nil, err
+	cwd := opts.Bundle
+	if cwd == "" {
+		var err error
+		cwd, err = linux.Getwd()
+		if err != nil {
+			return nil, err
+		}
 	}
 	spec := opts.Spec
 	if spec.Root == nil {
 		return nil, errors.New("root must be specified")
 	}
+	if opts.StrictSpec {
+		if err := checkIgnoredFields(spec); err != nil {
+			return nil, err
+		}
+	}
 	rootfsPath := spec.Root.Path
 	if !filepath.IsAbs(rootfsPath) {
 		rootfsPath = filepath.Join(cwd, rootfsPath)
@@ -375,9 +772,15 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		Hostname:        spec.Hostname,
 		Domainname:      spec.Domainname,
 		Labels:          append(labels, "bundle="+cwd),
+		Annotations:     maps.Clone(spec.Annotations),
+		SpecDigest:      opts.SpecDigest,
+		StrictSpec:      opts.StrictSpec,
 		NoNewKeyring:    opts.NoNewKeyring,
 		RootlessEUID:    opts.RootlessEUID,
 		RootlessCgroups: opts.RootlessCgroups,
+		MountPolicy:     opts.MountPolicy,
+		SchedCore:       opts.SchedCore,
+		StateBlobs:      opts.StateBlobs,
 	}
 
 	for _, m := range spec.Mounts {
@@ -413,10 +816,24 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 			}
 		}
 
+		if v, ok := spec.Annotations[AnnotationAllowUnknownNamespaces]; ok {
+			allow, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationAllowUnknownNamespaces, v, err)
+			}
+			config.AllowUnknownNamespaces = allow
+		}
 		for _, ns := range spec.Linux.Namespaces {
 			t, exists := namespaceMapping[ns.Type]
 			if !exists {
-				return nil, fmt.Errorf("namespace %q does not exist", ns)
+				if !config.AllowUnknownNamespaces {
+					return nil, fmt.Errorf("namespace %q does not exist", ns)
+				}
+				t = configs.NamespaceType(ns.Type)
+				if configs.IsKnownNamespace(t) {
+					return nil, fmt.Errorf("namespace %q does not exist", ns)
+				}
+				// The rest is validated by libcontainer.
 			}
 			if config.Namespaces.Contains(t) {
 				return nil, fmt.Errorf("malformed spec file: duplicated ns %q", ns)
@@ -431,7 +848,7 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 			}
 		}
 		if config.Namespaces.Contains(configs.NEWUSER) {
-			if err := setupUserNamespace(spec, config); err != nil {
+			if err := setupUserNamespace(opts, config); err != nil {
 				return nil, err
 			}
 			// For idmap and ridmap mounts without explicit mappings, use the
@@ -453,8 +870,12 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		config.MountLabel = spec.Linux.MountLabel
 		config.Sysctl = spec.Linux.Sysctl
 		config.TimeOffsets = spec.Linux.TimeOffsets
-		if spec.Linux.Seccomp != nil {
-			seccomp, err := SetupSeccomp(spec.Linux.Seccomp)
+		profile, err := seccompProfile(spec)
+		if err != nil {
+			return nil, err
+		}
+		if profile != nil {
+			seccomp, err := SetupSeccomp(profile)
 			if err != nil {
 				return nil, err
 			}
@@ -465,6 +886,8 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 				ClosID:        spec.Linux.IntelRdt.ClosID,
 				L3CacheSchema: spec.Linux.IntelRdt.L3CacheSchema,
 				MemBwSchema:   spec.Linux.IntelRdt.MemBwSchema,
+				EnableCMT:     spec.Linux.IntelRdt.EnableCMT,
+				EnableMBM:     spec.Linux.IntelRdt.EnableMBM,
 			}
 		}
 		if spec.Linux.Personality != nil {
@@ -561,11 +984,870 @@ func CreateLibcontainerConfig(opts *CreateOpts) (*configs.Config, error) {
 		}
 
 	}
+	if err := setupShm(spec, config, opts.AllowShmRemount); err != nil {
+		return nil, err
+	}
+	if err := setupSwap(spec, config); err != nil {
+		return nil, err
+	}
+	// Symbolic cpuset requests are resolved by libcontainer.
+	if r := config.Cgroups.Resources; cpuset.IsRequest(r.CpusetCpus) {
+		config.CpusetRequest = r.CpusetCpus
+		r.CpusetCpus = ""
+	}
+	config.MemsMigration = configs.MemsMigration(spec.Annotations[AnnotationMemsMigration])
+	if v, ok := spec.Annotations[AnnotationPtyDelegate]; ok {
+		delegate, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationPtyDelegate, v, err)
+		}
+		config.DelegatePty = delegate
+	}
+	if err := setupLandlock(spec, config); err != nil {
+		return nil, err
+	}
+	config.ReadonlyCheck = configs.ReadonlyCheck(spec.Annotations[AnnotationReadonlyCheck])
+	if v, ok := spec.Annotations[AnnotationNamespacesOwner]; ok {
+		if err := setupNamespacesOwner(v, config); err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationNamespacesOwner, v, err)
+		}
+	}
+	config.NamespaceOwnerCheck = configs.NamespaceOwnerCheck(spec.Annotations[AnnotationNamespacesOwnerCheck])
+	if v, ok := spec.Annotations[AnnotationTmpfiles]; ok {
+		config.Tmpfiles, err = parseTmpfiles(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationTmpfiles, v, err)
+		}
+	}
+	setupSysfsWritable(spec, config)
+	if v, ok := spec.Annotations[AnnotationPropagationPaths]; ok {
+		config.PropagationPaths, err = parsePropagationPaths(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationPropagationPaths, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationExecLimits]; ok {
+		config.ExecLimits, err = parseExecLimits(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationExecLimits, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationProbes]; ok {
+		config.Probes, err = parseProbes(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationProbes, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationNetSysctl]; ok {
+		config.NetSysctl, err = parseNetSysctl(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationNetSysctl, v, err)
+		}
+	}
+	var ioCost configs.IOCost
+	for _, a := range []struct {
+		name    string
+		devices *[]*configs.IOCostDevice
+	}{
+		{AnnotationIOCostQoS, &ioCost.QoS},
+		{AnnotationIOCostModel, &ioCost.Model},
+	} {
+		v, ok := spec.Annotations[a.name]
+		if !ok {
+			continue
+		}
+		*a.devices, err = parseIOCost(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", a.name, v, err)
+		}
+		config.IOCost = &ioCost
+	}
+	if v, ok := spec.Annotations[AnnotationPowerHint]; ok {
+		config.PowerHint, err = configs.ParsePowerHint(v, nil)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationPowerHint, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationRootfsQuota]; ok {
+		config.RootfsQuota, err = parseRootfsQuota(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationRootfsQuota, v, err)
+		}
+	}
+	if _, ok := spec.Annotations[AnnotationUsernsAuto]; ok && !config.UsernsAuto {
+		return nil, fmt.Errorf("annotation %s requires a user namespace", AnnotationUsernsAuto)
+	}
+	if v, ok := spec.Annotations[AnnotationIPCLimits]; ok {
+		config.IPCLimits, err = parseIPCLimits(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationIPCLimits, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationMachineID]; ok {
+		config.MachineID, err = parseMachineID(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationMachineID, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationVirtualBootID]; ok {
+		config.VirtualBootID, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationVirtualBootID, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationNetDevices]; ok {
+		if err := setupNetDevices(v, config.NetDevices); err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationNetDevices, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationKeepNetns]; ok {
+		config.KeepNetns, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationKeepNetns, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationIntelRdtShared]; ok {
+		shared, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationIntelRdtShared, v, err)
+		}
+		if shared && (config.IntelRdt == nil || config.IntelRdt.ClosID == "") {
+			return nil, fmt.Errorf("annotation %s requires linux.intelRdt.closID", AnnotationIntelRdtShared)
+		}
+		if config.IntelRdt != nil {
+			config.IntelRdt.Shared = shared
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationEnvDefaults]; ok {
+		config.EnvDefaults = configs.EnvDefaults(v)
+		if v == "" || !config.EnvDefaults.IsValid() {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: unknown policy", AnnotationEnvDefaults, v)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationKeyring]; ok {
+		if !opts.AllowKeyring {
+			return nil, fmt.Errorf("annotation %s is not allowed (see runc create --allow-keyring)", AnnotationKeyring)
+		}
+		config.Keyring, err = parseKeyring(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationKeyring, v, err)
+		}
+	}
+	config.InitSignals, err = initSignalsFromAnnotations(spec.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	config.SignalForwarding, err = signalForwardingFromAnnotations(spec.Annotations)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := spec.Annotations[AnnotationInitCPUAffinity]; ok {
+		var aff specs.CPUAffinity
+		err := json.Unmarshal([]byte(v), &aff)
+		if err == nil {
+			config.InitCPUAffinity, err = configs.ConvertCPUAffinity(&aff)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationInitCPUAffinity, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationMemoryPolicy]; ok {
+		config.MemoryPolicy, err = parseMemoryPolicy(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationMemoryPolicy, v, err)
+		}
+	}
+	if v, ok := spec.Annotations[AnnotationIRQAffinity]; ok {
+		config.IRQAffinity, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationIRQAffinity, v, err)
+		}
+		if config.IRQAffinity && !config.Namespaces.IsPrivate(configs.NEWNET) {
+			return nil, fmt.Errorf("annotation %s requires a private network namespace", AnnotationIRQAffinity)
+		}
+		if config.IRQAffinity && config.Cgroups.Resources.CpusetCpus == "" {
+			return nil, fmt.Errorf("annotation %s requires linux.resources.cpu.cpus", AnnotationIRQAffinity)
+		}
+	}
+	config.HelperCgroup = DefaultHelperCgroup
+	if v, ok := spec.Annotations[AnnotationHelperCgroup]; ok {
+		config.HelperCgroup = v
+	}
 	createHooks(spec, config)
+	if v, ok := spec.Annotations[AnnotationHooks]; ok {
+		if err := setupHookOptions(v, config.Hooks); err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationHooks, v, err)
+		}
+	}
 	config.Version = specs.Version
 	return config, nil
 }
 
+// setupNetDevices sets the network device settings of the
+// [AnnotationNetDevices] value v to devices.
+func setupNetDevices(v string, devices map[string]*configs.LinuxNetDevice) error {
+	var settings map[string]struct {
+		MTU             int    `json:"mtu"`
+		HardwareAddress string `json:"hardwareAddress"`
+		TxQueueLen      int    `json:"txQueueLen"`
+		Queues          uint32 `json:"queues"`
+	}
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&settings); err != nil {
+		return err
+	}
+	for name, s := range settings {
+		dev, ok := devices[name]
+		if !ok {
+			return fmt.Errorf("network device %s is not in linux.netDevices", name)
+		}
+		dev.MTU = s.MTU
+		dev.HardwareAddress = s.HardwareAddress
+		dev.TxQueueLen = s.TxQueueLen
+		dev.Queues = s.Queues
+	}
+	return nil
+}
+
+// setupShm parses the /dev/shm related annotations. If the size is set and
+// the container gets its own /dev/shm tmpfs, the size is applied to its mount
+// options (adding a /dev/shm mount if there is none and the IPC namespace is
+// private). Otherwise, the size is applied by libcontainer according to the
+// configured policy.
+func setupShm(spec *specs.Spec, config *configs.Config, allowRemount bool) error {
+	sizeStr, hasSize := spec.Annotations[AnnotationShmSize]
+	policy, hasPolicy := spec.Annotations[AnnotationShmPolicy]
+	if !hasSize && !hasPolicy {
+		return nil
+	}
+	shm := &configs.Shm{Policy: configs.ShmPolicy(policy)}
+	if shm.Policy == configs.ShmPolicyRemount && !allowRemount {
+		return fmt.Errorf("annotation %s=%s is not allowed (see runc create --allow-shm-remount)", AnnotationShmPolicy, policy)
+	}
+	if hasSize {
+		size, err := units.RAMInBytes(sizeStr)
+		if err != nil {
+			return fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationShmSize, sizeStr, err)
+		}
+		shm.Size = size
+	}
+	config.Shm = shm
+	if shm.Size <= 0 {
+		return nil
+	}
+
+	for _, m := range config.Mounts {
+		if libcontainerUtils.CleanPath(m.Destination) != "/dev/shm" {
+			continue
+		}
+		if m.Device == "tmpfs" {
+			m.Data = setTmpfsSize(m.Data, shm.Size)
+		}
+		return nil
+	}
+	// There is no /dev/shm mount. If the IPC namespace is shared, /dev/shm
+	// should be shared as well, so only add one for a private IPC namespace.
+	if config.Namespaces.IsPrivate(configs.NEWIPC) {
+		config.Mounts = append(config.Mounts, &configs.Mount{
+			Source:      "shm",
+			Destination: "/dev/shm",
+			Device:      "tmpfs",
+			Flags:       unix.MS_NOSUID | unix.MS_NOEXEC | unix.MS_NODEV,
+			Data:        setTmpfsSize("mode=1777", shm.Size),
+		})
+	}
+	return nil
+}
+
+// setupSwap parses the managed swap annotations, and sets the container's
+// swap limit to the swap area size, unless it is already set.
+func setupSwap(spec *specs.Spec, config *configs.Config) error {
+	swapType, ok := spec.Annotations[AnnotationSwapType]
+	if !ok {
+		return nil
+	}
+	swap := &configs.Swap{
+		Type: configs.SwapType(swapType),
+		Path: spec.Annotations[AnnotationSwapPath],
+	}
+	if sizeStr := spec.Annotations[AnnotationSwapSize]; sizeStr != "" {
+		size, err := units.RAMInBytes(sizeStr)
+		if err != nil {
+			return fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationSwapSize, sizeStr, err)
+		}
+		swap.Size = size
+	}
+	config.Swap = swap
+
+	r := config.Cgroups.Resources
+	if r.MemorySwap != 0 {
+		return nil
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		if _, ok := r.Unified["memory.swap.max"]; !ok {
+			if r.Unified == nil {
+				r.Unified = make(map[string]string)
+			}
+			r.Unified["memory.swap.max"] = strconv.FormatInt(swap.Size, 10)
+		}
+	} else if r.Memory > 0 {
+		// On cgroup v1, the limit is for memory+swap.
+		r.MemorySwap = r.Memory + swap.Size
+	}
+	return nil
+}
+
+// parseExecLimits parses the [AnnotationExecLimits] value.
+func parseExecLimits(v string) (*configs.ExecLimits, error) {
+	limits := &configs.ExecLimits{}
+	for _, limit := range strings.Split(v, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(limit), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		var err error
+		switch key {
+		case "concurrent":
+			limits.MaxConcurrent, err = strconv.Atoi(val)
+		case "total":
+			limits.MaxTotal, err = strconv.Atoi(val)
+		case "rate":
+			n, window, ok := strings.Cut(val, "/")
+			if !ok {
+				return nil, fmt.Errorf("invalid rate %q, expected N/DURATION", val)
+			}
+			if limits.MaxPerWindow, err = strconv.Atoi(n); err != nil {
+				break
+			}
+			limits.Window, err = time.ParseDuration(window)
+		default:
+			return nil, fmt.Errorf("unknown limit %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s limit: %w", key, err)
+		}
+	}
+	return limits, nil
+}
+
+// parseMachineID parses the [AnnotationMachineID] value, generating a
+// random machine ID for "auto" (formatted as systemd does, as a version 4
+// UUID without the dashes).
+func parseMachineID(v string) (string, error) {
+	if v != "auto" {
+		return strings.ToLower(v), nil
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return hex.EncodeToString(b[:]), nil
+}
+
+// parseRootfsQuota parses the [AnnotationRootfsQuota] value.
+func parseRootfsQuota(v string) (*configs.RootfsQuota, error) {
+	quota := &configs.RootfsQuota{}
+	for _, limit := range strings.Split(v, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(limit), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		var err error
+		switch key {
+		case "size":
+			var size int64
+			if size, err = units.RAMInBytes(val); err == nil && size < 0 {
+				err = errors.New("negative size")
+			}
+			quota.Size = uint64(size)
+		case "inodes":
+			quota.Inodes, err = strconv.ParseUint(val, 10, 64)
+		case "project":
+			var id uint64
+			id, err = strconv.ParseUint(val, 10, 32)
+			quota.ProjectID = uint32(id)
+		default:
+			return nil, fmt.Errorf("unknown limit %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return quota, nil
+}
+
+// parseKeyring parses the [AnnotationKeyring] value.
+func parseKeyring(v string) (*configs.Keyring, error) {
+	dec := json.NewDecoder(strings.NewReader(v))
+	dec.DisallowUnknownFields()
+	k := &configs.Keyring{}
+	if err := dec.Decode(k); err != nil {
+		return nil, err
+	}
+	if k.Quota != nil && k.Quota.MaxKeys == 0 && k.Quota.MaxBytes == 0 {
+		return nil, errors.New("empty quota")
+	}
+	return k, nil
+}
+
+// parseMemoryPolicy parses the [AnnotationMemoryPolicy] value.
+func parseMemoryPolicy(v string) (*configs.MemoryPolicy, error) {
+	var mp struct {
+		Mode  string   `json:"mode"`
+		Nodes string   `json:"nodes"`
+		Flags []string `json:"flags"`
+	}
+	if err := json.Unmarshal([]byte(v), &mp); err != nil {
+		return nil, err
+	}
+	mode, err := configs.MemoryPolicyMode(mp.Mode)
+	if err != nil {
+		return nil, err
+	}
+	p := &configs.MemoryPolicy{Mode: mode}
+	if mp.Nodes != "" {
+		if p.Nodes, err = cpuset.Parse(mp.Nodes); err != nil {
+			return nil, fmt.Errorf("invalid nodes: %w", err)
+		}
+	}
+	for _, f := range mp.Flags {
+		flag, err := configs.MemoryPolicyFlag(f)
+		if err != nil {
+			return nil, err
+		}
+		p.Flags |= flag
+	}
+	return p, nil
+}
+
+// parseIPCLimits parses the [AnnotationIPCLimits] value.
+func parseIPCLimits(v string) (*configs.IPCLimits, error) {
+	l := &configs.IPCLimits{}
+	sizes := map[string]*uint64{
+		"msgmax":                 &l.MsgMax,
+		"msgmnb":                 &l.MsgMnb,
+		"shmmax":                 &l.ShmMax,
+		"mqueue.msgsize_max":     &l.MqueueMsgSizeMax,
+		"mqueue.msgsize_default": &l.MqueueMsgSizeDefault,
+	}
+	counts := map[string]*uint64{
+		"msgmni":             &l.MsgMni,
+		"shmall":             &l.ShmAll,
+		"shmmni":             &l.ShmMni,
+		"semmsl":             &l.SemMsl,
+		"semmns":             &l.SemMns,
+		"semopm":             &l.SemOpm,
+		"semmni":             &l.SemMni,
+		"mqueue.msg_max":     &l.MqueueMsgMax,
+		"mqueue.queues_max":  &l.MqueueQueuesMax,
+		"mqueue.msg_default": &l.MqueueMsgDefault,
+	}
+	for _, limit := range strings.Split(v, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(limit), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit %q", limit)
+		}
+		var err error
+		if p, ok := sizes[key]; ok {
+			var size int64
+			if size, err = units.RAMInBytes(val); err == nil && size <= 0 {
+				err = errors.New("must be positive")
+			}
+			*p = uint64(size)
+		} else if p, ok := counts[key]; ok {
+			if *p, err = strconv.ParseUint(val, 10, 64); err == nil && *p == 0 {
+				err = errors.New("must be positive")
+			}
+		} else if key == "shm_rmid_forced" {
+			l.ShmRmidForced, err = strconv.ParseBool(val)
+		} else {
+			return nil, fmt.Errorf("unknown limit %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+	return l, nil
+}
+
+// initSignalsFromAnnotations returns the signal configuration of the
+// container init from the [AnnotationInitSignalsBlocked],
+// [AnnotationInitSignalsIgnored], and [AnnotationInitSignalsInherit]
+// annotations, or nil if none is set.
+func initSignalsFromAnnotations(annotations map[string]string) (*configs.InitSignals, error) {
+	var (
+		s   configs.InitSignals
+		set bool
+		err error
+	)
+	for _, a := range []struct {
+		name    string
+		signals *[]unix.Signal
+	}{
+		{AnnotationInitSignalsBlocked, &s.Blocked},
+		{AnnotationInitSignalsIgnored, &s.Ignored},
+	} {
+		v, ok := annotations[a.name]
+		if !ok {
+			continue
+		}
+		*a.signals, err = parseSignals(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", a.name, v, err)
+		}
+		set = true
+	}
+	if v, ok := annotations[AnnotationInitSignalsInherit]; ok {
+		s.Inherit, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationInitSignalsInherit, v, err)
+		}
+		set = true
+	}
+	if !set {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func signalForwardingFromAnnotations(annotations map[string]string) (*configs.SignalForwarding, error) {
+	var (
+		f   configs.SignalForwarding
+		set bool
+		err error
+	)
+	if v, ok := annotations[AnnotationSignalsForward]; ok {
+		f.Map, err = parseSignalMap(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationSignalsForward, v, err)
+		}
+		set = true
+	}
+	if v, ok := annotations[AnnotationSignalsNoForward]; ok {
+		f.Ignored, err = parseSignals(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationSignalsNoForward, v, err)
+		}
+		set = true
+	}
+	if !set {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// parseSignalMap parses a comma-separated list of FROM=TO pairs of signal
+// names or numbers.
+func parseSignalMap(v string) (map[unix.Signal]unix.Signal, error) {
+	m := make(map[unix.Signal]unix.Signal)
+	for _, pair := range strings.Split(v, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid signal mapping %q (must be FROM=TO)", pair)
+		}
+		signals, err := parseSignals(from + "," + to)
+		if err != nil {
+			return nil, err
+		}
+		m[signals[0]] = signals[1]
+	}
+	return m, nil
+}
+
+// parseSignals parses a comma-separated list of signal names (with or
+// without the SIG prefix) or numbers.
+func parseSignals(v string) ([]unix.Signal, error) {
+	var signals []unix.Signal
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if n, err := strconv.Atoi(name); err == nil {
+			signals = append(signals, unix.Signal(n))
+			continue
+		}
+		name = strings.ToUpper(name)
+		if !strings.HasPrefix(name, "SIG") {
+			name = "SIG" + name
+		}
+		sig := unix.SignalNum(name)
+		if sig == 0 {
+			return nil, fmt.Errorf("unknown signal %q", name)
+		}
+		signals = append(signals, sig)
+	}
+	return signals, nil
+}
+
+// parseIOCost parses the [AnnotationIOCostQoS] or [AnnotationIOCostModel]
+// value.
+func parseIOCost(v string) ([]*configs.IOCostDevice, error) {
+	var devices []*configs.IOCostDevice
+	for _, line := range strings.Split(v, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		d, err := configs.ParseIOCostDevice(line)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	if len(devices) == 0 {
+		return nil, errors.New("no devices")
+	}
+	return devices, nil
+}
+
+// parseNetSysctl parses the [AnnotationNetSysctl] value.
+func parseNetSysctl(v string) (map[string]string, error) {
+	sysctl := map[string]string{}
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if key, val, ok := strings.Cut(entry, "="); ok {
+			if key == "" {
+				return nil, fmt.Errorf("invalid sysctl %q", entry)
+			}
+			sysctl[key] = val
+			continue
+		}
+		preset, ok := netSysctlPresets[entry]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q", entry)
+		}
+		maps.Copy(sysctl, preset)
+	}
+	return sysctl, nil
+}
+
+// parseProbes parses the [AnnotationProbes] value.
+func parseProbes(v string) ([]*configs.Probe, error) {
+	var specs []struct {
+		Type             configs.ProbeType `json:"type"`
+		Args             []string          `json:"args"`
+		Interval         string            `json:"interval"`
+		Timeout          string            `json:"timeout"`
+		FailureThreshold int               `json:"failureThreshold"`
+	}
+	if err := json.Unmarshal([]byte(v), &specs); err != nil {
+		return nil, err
+	}
+	probes := make([]*configs.Probe, 0, len(specs))
+	for _, ps := range specs {
+		p := &configs.Probe{
+			Type:             ps.Type,
+			Args:             ps.Args,
+			Interval:         10 * time.Second,
+			Timeout:          time.Second,
+			FailureThreshold: 3,
+		}
+		var err error
+		if ps.Interval != "" {
+			if p.Interval, err = time.ParseDuration(ps.Interval); err != nil {
+				return nil, fmt.Errorf("invalid %s probe interval: %w", ps.Type, err)
+			}
+		}
+		if ps.Timeout != "" {
+			if p.Timeout, err = time.ParseDuration(ps.Timeout); err != nil {
+				return nil, fmt.Errorf("invalid %s probe timeout: %w", ps.Type, err)
+			}
+		}
+		if ps.FailureThreshold != 0 {
+			p.FailureThreshold = ps.FailureThreshold
+		}
+		probes = append(probes, p)
+	}
+	return probes, nil
+}
+
+// landlockSpec is the Landlock configuration format of the runtime-spec
+// Landlock proposal.
+type landlockSpec struct {
+	Ruleset struct {
+		HandledAccessFS      []string `json:"handledAccessFS"`
+		HandledAccessNetwork []string `json:"handledAccessNetwork"`
+	} `json:"ruleset"`
+	Rules struct {
+		PathBeneath []struct {
+			AllowedAccess []string `json:"allowedAccess"`
+			Paths         []string `json:"paths"`
+		} `json:"pathBeneath"`
+		NetPort []struct {
+			AllowedAccess []string `json:"allowedAccess"`
+			Ports         []uint64 `json:"ports"`
+		} `json:"netPort"`
+	} `json:"rules"`
+	DisableBestEffort bool `json:"disableBestEffort"`
+}
+
+// setupLandlock parses the Landlock annotation.
+func setupLandlock(spec *specs.Spec, config *configs.Config) error {
+	v, ok := spec.Annotations[AnnotationLandlock]
+	if !ok {
+		return nil
+	}
+	var ls landlockSpec
+	if err := json.Unmarshal([]byte(v), &ls); err != nil {
+		return fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationLandlock, v, err)
+	}
+	ll := &configs.Landlock{
+		HandledAccessFS:      ls.Ruleset.HandledAccessFS,
+		HandledAccessNetwork: ls.Ruleset.HandledAccessNetwork,
+		DisableBestEffort:    ls.DisableBestEffort,
+	}
+	for _, r := range ls.Rules.PathBeneath {
+		ll.PathBeneath = append(ll.PathBeneath, configs.LandlockPathBeneath{
+			AllowedAccess: r.AllowedAccess,
+			Paths:         r.Paths,
+		})
+	}
+	for _, r := range ls.Rules.NetPort {
+		ll.NetPort = append(ll.NetPort, configs.LandlockNetPort{
+			AllowedAccess: r.AllowedAccess,
+			Ports:         r.Ports,
+		})
+	}
+	config.Landlock = ll
+	return nil
+}
+
+// setupSysfsWritable parses the writable /sys paths annotation, and makes
+// the /sys mount read-only.
+func setupSysfsWritable(spec *specs.Spec, config *configs.Config) {
+	v := spec.Annotations[AnnotationSysfsWritable]
+	if v == "" {
+		return
+	}
+	for _, path := range strings.Split(v, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			config.SysfsWritable = append(config.SysfsWritable, path)
+		}
+	}
+	for _, m := range config.Mounts {
+		if libcontainerUtils.CleanPath(m.Destination) == "/sys" {
+			m.Flags |= unix.MS_RDONLY
+		}
+	}
+}
+
+// parsePropagationPaths parses the [AnnotationPropagationPaths] value. The
+// paths are sorted, so that the propagation of a path is applied before
+// that of the paths under it.
+func parsePropagationPaths(v string) ([]configs.PropagationPath, error) {
+	var paths map[string]string
+	if err := json.Unmarshal([]byte(v), &paths); err != nil {
+		return nil, err
+	}
+	initMaps()
+	var res []configs.PropagationPath
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		flags, ok := mountPropagationMapping[paths[path]]
+		if !ok {
+			return nil, fmt.Errorf("invalid propagation %q of %s", paths[path], path)
+		}
+		res = append(res, configs.PropagationPath{Path: path, Flags: flags})
+	}
+	return res, nil
+}
+
+// setupNamespacesOwner sets the expected owner of the config namespaces from
+// the [AnnotationNamespacesOwner] value.
+func setupNamespacesOwner(v string, config *configs.Config) error {
+	var owners map[specs.LinuxNamespaceType]*configs.NamespaceOwner
+	if err := json.Unmarshal([]byte(v), &owners); err != nil {
+		return err
+	}
+	initMaps()
+	for typ, owner := range owners {
+		t, ok := namespaceMapping[typ]
+		if !ok {
+			t = configs.NamespaceType(typ)
+		}
+		i := slices.IndexFunc(config.Namespaces, func(ns configs.Namespace) bool { return ns.Type == t })
+		if i == -1 {
+			return fmt.Errorf("no %s namespace", typ)
+		}
+		config.Namespaces[i].Owner = owner
+	}
+	return nil
+}
+
+// parseTmpfiles parses the tmpfiles.d(5) lines of the tmpfiles annotation.
+// The fields are "Type Path Mode User Group Age Argument", where "-" means
+// the default value, and the trailing ones may be omitted.
+func parseTmpfiles(v string) ([]*configs.Tmpfile, error) {
+	var list []*configs.Tmpfile
+	for i, line := range strings.Split(v, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		t, err := parseTmpfile(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		list = append(list, t)
+	}
+	return list, nil
+}
+
+func parseTmpfile(fields []string) (*configs.Tmpfile, error) {
+	if len(fields) < 2 {
+		return nil, errors.New("no path")
+	}
+	// Missing trailing fields are defaults.
+	for len(fields) < 6 {
+		fields = append(fields, "-")
+	}
+	t := &configs.Tmpfile{
+		Type: configs.TmpfileType(fields[0]),
+		Path: fields[1],
+	}
+	if fields[2] != "-" {
+		mode, err := strconv.ParseUint(fields[2], 8, 32)
+		if err != nil || mode > 0o7777 {
+			return nil, fmt.Errorf("invalid mode %q", fields[2])
+		}
+		m := uint32(mode)
+		t.Mode = &m
+	}
+	for _, id := range []struct {
+		value string
+		dest  **uint32
+	}{{fields[3], &t.UID}, {fields[4], &t.GID}} {
+		if id.value == "-" {
+			continue
+		}
+		n, err := strconv.ParseUint(id.value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user or group %q: only numeric IDs are supported", id.value)
+		}
+		u := uint32(n)
+		*id.dest = &u
+	}
+	if fields[5] != "-" {
+		return nil, fmt.Errorf("unsupported age %q", fields[5])
+	}
+	if len(fields) > 6 {
+		t.Argument = strings.Join(fields[6:], " ")
+	}
+	return t, nil
+}
+
+// setTmpfsSize replaces (or adds) the size option in tmpfs mount data.
+func setTmpfsSize(data string, size int64) string {
+	opts := []string{}
+	for _, o := range strings.Split(data, ",") {
+		if o == "" || strings.HasPrefix(o, "size=") {
+			continue
+		}
+		opts = append(opts, o)
+	}
+	opts = append(opts, "size="+strconv.FormatInt(size, 10))
+	return strings.Join(opts, ",")
+}
+
 func toConfigIDMap(specMaps []specs.LinuxIDMapping) []configs.IDMap {
 	if specMaps == nil {
 		return nil
@@ -777,36 +2059,11 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*cgrou
 	if spec.Linux != nil {
 		r := spec.Linux.Resources
 		if r != nil {
-			for i, d := range r.Devices {
-				var (
-					t     = "a"
-					major = int64(-1)
-					minor = int64(-1)
-				)
-				if d.Type != "" {
-					t = d.Type
-				}
-				if d.Major != nil {
-					major = *d.Major
-				}
-				if d.Minor != nil {
-					minor = *d.Minor
-				}
-				if d.Access == "" {
-					return nil, fmt.Errorf("device access at %d field cannot be empty", i)
-				}
-				dt, err := stringToCgroupDeviceRune(t)
-				if err != nil {
-					return nil, err
-				}
-				c.Resources.Devices = append(c.Resources.Devices, &devices.Rule{
-					Type:        dt,
-					Major:       major,
-					Minor:       minor,
-					Permissions: devices.Permissions(d.Access),
-					Allow:       d.Allow,
-				})
+			rules, err := CreateDeviceRules(r.Devices)
+			if err != nil {
+				return nil, err
 			}
+			c.Resources.Devices = append(c.Resources.Devices, rules...)
 			if r.Memory != nil {
 				if r.Memory.Limit != nil {
 					c.Resources.Memory = *r.Memory.Limit
@@ -937,6 +2194,43 @@ func CreateCgroupConfig(opts *CreateOpts, defaultDevs []*devices.Device) (*cgrou
 	return c, nil
 }
 
+// CreateDeviceRules converts the runtime-spec device cgroup rules to the
+// libcontainer ones.
+func CreateDeviceRules(rs []specs.LinuxDeviceCgroup) ([]*devices.Rule, error) {
+	rules := make([]*devices.Rule, 0, len(rs))
+	for i, d := range rs {
+		var (
+			t     = "a"
+			major = int64(-1)
+			minor = int64(-1)
+		)
+		if d.Type != "" {
+			t = d.Type
+		}
+		if d.Major != nil {
+			major = *d.Major
+		}
+		if d.Minor != nil {
+			minor = *d.Minor
+		}
+		if d.Access == "" {
+			return nil, fmt.Errorf("device access at %d field cannot be empty", i)
+		}
+		dt, err := stringToCgroupDeviceRune(t)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &devices.Rule{
+			Type:        dt,
+			Major:       major,
+			Minor:       minor,
+			Permissions: devices.Permissions(d.Access),
+			Allow:       d.Allow,
+		})
+	}
+	return rules, nil
+}
+
 func stringToCgroupDeviceRune(s string) (devices.Type, error) {
 	switch s {
 	case "a":
@@ -1020,11 +2314,17 @@ next:
 	return dedupedAllowDevs, nil
 }
 
-func setupUserNamespace(spec *specs.Spec, config *configs.Config) error {
+func setupUserNamespace(opts *CreateOpts, config *configs.Config) error {
+	spec := opts.Spec
 	if spec.Linux != nil {
 		config.UIDMappings = toConfigIDMap(spec.Linux.UIDMappings)
 		config.GIDMappings = toConfigIDMap(spec.Linux.GIDMappings)
 	}
+	if v, ok := spec.Annotations[AnnotationUsernsAuto]; ok {
+		if err := setupUsernsAuto(opts, v, config); err != nil {
+			return fmt.Errorf("annotation %s=%s: %w", AnnotationUsernsAuto, v, err)
+		}
+	}
 	if path := config.Namespaces.PathOf(configs.NEWUSER); path != "" {
 		// Cache the current userns mappings in our configuration, so that we
 		// can calculate uid and gid mappings within runc. These mappings are
@@ -1074,6 +2374,50 @@ func setupUserNamespace(spec *specs.Spec, config *configs.Config) error {
 	return nil
 }
 
+// setupUsernsAuto sets up the mappings of the user namespace to an ID
+// range allocated by opts.AllocateUserns, per the [AnnotationUsernsAuto]
+// value.
+func setupUsernsAuto(opts *CreateOpts, v string, config *configs.Config) error {
+	size, pool := int64(DefaultUsernsAutoSize), DefaultUsernsAutoPool
+	if v != "" {
+		for _, opt := range strings.Split(v, ",") {
+			key, val, ok := strings.Cut(strings.TrimSpace(opt), "=")
+			if !ok {
+				return fmt.Errorf("invalid option %q", opt)
+			}
+			switch key {
+			case "size":
+				n, err := strconv.ParseInt(val, 10, 64)
+				if err != nil || n <= 0 || n > math.MaxUint32 {
+					return fmt.Errorf("invalid size %q", val)
+				}
+				size = n
+			case "pool":
+				if val == "" {
+					return errors.New("empty pool user")
+				}
+				pool = val
+			default:
+				return fmt.Errorf("unknown option %q", key)
+			}
+		}
+	}
+	if config.Namespaces.PathOf(configs.NEWUSER) != "" || config.UIDMappings != nil || config.GIDMappings != nil {
+		return errors.New("the user namespace must have neither a path, nor mappings")
+	}
+	if opts.AllocateUserns == nil {
+		return errors.New("automatic user namespace allocation is not supported")
+	}
+	hostID, err := opts.AllocateUserns(pool, size)
+	if err != nil {
+		return err
+	}
+	config.UIDMappings = []configs.IDMap{{ContainerID: 0, HostID: hostID, Size: size}}
+	config.GIDMappings = []configs.IDMap{{ContainerID: 0, HostID: hostID, Size: size}}
+	config.UsernsAuto = true
+	return nil
+}
+
 // parseMountOptions parses options and returns a configs.Mount
 // structure with fields that depends on options set accordingly.
 func parseMountOptions(options []string) *configs.Mount {
@@ -1135,6 +2479,33 @@ func parseMountOptions(options []string) *configs.Mount {
 	return &m
 }
 
+// seccompProfile returns the seccomp profile of spec, which is either set by
+// [AnnotationSeccompProfile], or linux.seccomp.
+func seccompProfile(spec *specs.Spec) (*specs.LinuxSeccomp, error) {
+	v, ok := spec.Annotations[AnnotationSeccompProfile]
+	if !ok {
+		return spec.Linux.Seccomp, nil
+	}
+	usesSpec := false
+	profile, err := profiles.Compose(v, func(name string) (*specs.LinuxSeccomp, error) {
+		if name != SeccompProfileSpec {
+			return profiles.Get(name)
+		}
+		if spec.Linux.Seccomp == nil {
+			return nil, errors.New("no linux.seccomp profile")
+		}
+		usesSpec = true
+		return spec.Linux.Seccomp, nil
+	})
+	if err == nil && spec.Linux.Seccomp != nil && !usesSpec {
+		err = fmt.Errorf("the linux.seccomp profile is not used (use the %q profile name)", SeccompProfileSpec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("annotation %s=%s value parse error: %w", AnnotationSeccompProfile, v, err)
+	}
+	return profile, nil
+}
+
 func SetupSeccomp(config *specs.LinuxSeccomp) (*configs.Seccomp, error) {
 	if config == nil {
 		return nil, nil
@@ -1256,6 +2627,42 @@ func createHooks(rspec *specs.Spec, config *configs.Config) {
 	}
 }
 
+// setupHookOptions parses the [AnnotationHooks] value, and sets the options
+// of the command hooks accordingly.
+func setupHookOptions(v string, hooks configs.Hooks) error {
+	var options map[configs.HookName][]*struct {
+		Parallel        bool `json:"parallel"`
+		Retries         int  `json:"retries"`
+		ContinueOnError bool `json:"continueOnError"`
+		Async           bool `json:"async"`
+	}
+	if err := json.Unmarshal([]byte(v), &options); err != nil {
+		return err
+	}
+	for name, opts := range options {
+		if !slices.Contains(configs.KnownHookNames(), string(name)) {
+			return fmt.Errorf("unknown hook type %q", name)
+		}
+		for i, o := range opts {
+			if o == nil {
+				continue
+			}
+			if i >= len(hooks[name]) {
+				return fmt.Errorf("no %s hook #%d", name, i)
+			}
+			if o.Retries < 0 {
+				return fmt.Errorf("%s hook #%d: negative retries", name, i)
+			}
+			h, ok := hooks[name][i].(configs.CommandHook)
+			if !ok {
+				return fmt.Errorf("%s hook #%d is not a command", name, i)
+			}
+			h.Parallel, h.Retries, h.ContinueOnError, h.Async = o.Parallel, o.Retries, o.ContinueOnError, o.Async
+		}
+	}
+	return nil
+}
+
 func createCommandHook(h specs.Hook) *configs.Command {
 	cmd := &configs.Command{
 		Path: h.Path,
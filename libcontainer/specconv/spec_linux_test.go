@@ -2,8 +2,12 @@ package specconv
 
 import (
 	"os"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	dbus "github.com/godbus/dbus/v5"
 	devices "github.com/opencontainers/cgroups/devices/config"
@@ -608,6 +612,39 @@ func TestDupNamespaces(t *testing.T) {
 	}
 }
 
+func TestUnknownNamespaces(t *testing.T) {
+	spec := &specs.Spec{
+		Root: &specs.Root{
+			Path: "rootfs",
+		},
+		Linux: &specs.Linux{
+			Namespaces: []specs.LinuxNamespace{
+				{Type: "foo"},
+			},
+		},
+	}
+
+	_, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected unknown namespace error, got %v", err)
+	}
+
+	spec.Annotations = map[string]string{AnnotationAllowUnknownNamespaces: "true"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.AllowUnknownNamespaces || !config.Namespaces.Contains("foo") {
+		t.Errorf("expected unknown namespace foo to be allowed, got %+v", config.Namespaces)
+	}
+
+	// A known namespace type can not be used as an unknown one.
+	spec.Linux.Namespaces = []specs.LinuxNamespace{{Type: specs.LinuxNamespaceType(configs.NEWNET)}}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected an error for a known namespace type")
+	}
+}
+
 func TestUserNamespaceMappingAndPath(t *testing.T) {
 	if _, err := os.Stat("/proc/self/ns/user"); os.IsNotExist(err) {
 		t.Skip("Test requires userns.")
@@ -1017,3 +1054,966 @@ func TestCreateNetDevices(t *testing.T) {
 		})
 	}
 }
+
+func TestNetDevicesAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Linux.NetDevices = map[string]specs.LinuxNetDevice{"eth1": {Name: "ctr1"}}
+	spec.Annotations = map[string]string{
+		AnnotationNetDevices: `{"eth1": {"mtu": 9000, "hardwareAddress": "02:11:22:33:44:55", "txQueueLen": 100, "queues": 4}}`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := configs.LinuxNetDevice{Name: "ctr1", MTU: 9000, HardwareAddress: "02:11:22:33:44:55", TxQueueLen: 100, Queues: 4}
+	if dev := config.NetDevices["eth1"]; dev == nil || *dev != expected {
+		t.Errorf("expected %+v, got %+v", expected, dev)
+	}
+
+	for _, v := range []string{
+		`{"eth2": {"mtu": 9000}}`,
+		`{"eth1": {"speed": 1000}}`,
+		`{"eth1": {"mtu": "big"}}`,
+	} {
+		spec.Annotations[AnnotationNetDevices] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%s: expected error, got nil", v)
+		}
+	}
+}
+
+func TestSetupShm(t *testing.T) {
+	findShm := func(config *configs.Config) *configs.Mount {
+		for _, m := range config.Mounts {
+			if m.Destination == "/dev/shm" {
+				return m
+			}
+		}
+		return nil
+	}
+	testCases := []struct {
+		name      string
+		noShm     bool
+		sharedIPC bool
+		expData   string // empty if no /dev/shm mount is expected
+	}{
+		{name: "replace size", expData: "mode=1777,size=67108864"},
+		{name: "add mount", noShm: true, expData: "mode=1777,size=67108864"},
+		{name: "shared ipc", noShm: true, sharedIPC: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := Example()
+			spec.Root.Path = "/"
+			spec.Annotations = map[string]string{AnnotationShmSize: "64m"}
+			if tc.noShm {
+				spec.Mounts = slices.DeleteFunc(spec.Mounts, func(m specs.Mount) bool {
+					return m.Destination == "/dev/shm"
+				})
+			}
+			if tc.sharedIPC {
+				spec.Linux.Namespaces = slices.DeleteFunc(spec.Linux.Namespaces, func(ns specs.LinuxNamespace) bool {
+					return ns.Type == specs.IPCNamespace
+				})
+			}
+
+			config, err := CreateLibcontainerConfig(&CreateOpts{
+				CgroupName: "ContainerID",
+				Spec:       spec,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Shm == nil || config.Shm.Size != 64<<20 {
+				t.Fatalf("expected shm size %d, got %+v", 64<<20, config.Shm)
+			}
+			m := findShm(config)
+			if tc.expData == "" {
+				if m != nil {
+					t.Fatalf("expected no /dev/shm mount, got %+v", m)
+				}
+				return
+			}
+			if m == nil {
+				t.Fatal("expected /dev/shm mount, got none")
+			}
+			if m.Data != tc.expData {
+				t.Fatalf("expected /dev/shm mount data %q, got %q", tc.expData, m.Data)
+			}
+		})
+	}
+}
+
+func TestSetupShmBadSize(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationShmSize: "lots"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSetupShmRemountPolicy(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationShmSize: "64m", AnnotationShmPolicy: "remount"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Fatal("expected error without AllowShmRemount, got nil")
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec, AllowShmRemount: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Shm.Policy != configs.ShmPolicyRemount {
+		t.Fatalf("expected the remount policy, got %q", config.Shm.Policy)
+	}
+}
+
+func TestCpusetRequest(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Linux.Resources.CPU = &specs.LinuxCPU{Cpus: "cores:2-exclusive"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.CpusetRequest != "cores:2-exclusive" {
+		t.Errorf("expected cpuset request to be set, got %q", config.CpusetRequest)
+	}
+	if cpus := config.Cgroups.Resources.CpusetCpus; cpus != "" {
+		t.Errorf("expected cpuset cpus to be empty, got %q", cpus)
+	}
+}
+
+func TestPtyDelegateAnnotation(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		delegate bool
+		isErr    bool
+	}{
+		{value: "true", delegate: true},
+		{value: "0"},
+		{value: "sure", isErr: true},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Annotations = map[string]string{AnnotationPtyDelegate: tc.value}
+		config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.value, err)
+			continue
+		}
+		if config.DelegatePty != tc.delegate {
+			t.Errorf("%q: expected DelegatePty %v, got %v", tc.value, tc.delegate, config.DelegatePty)
+		}
+	}
+}
+
+func TestLandlockAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationLandlock: `{
+		"ruleset": {
+			"handledAccessFS": ["execute", "read_file", "write_file"],
+			"handledAccessNetwork": ["connect_tcp"]
+		},
+		"rules": {
+			"pathBeneath": [{"allowedAccess": ["execute", "read_file"], "paths": ["/usr", "/bin"]}],
+			"netPort": [{"allowedAccess": ["connect_tcp"], "ports": [443]}]
+		},
+		"disableBestEffort": true
+	}`}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.Landlock{
+		HandledAccessFS:      []string{"execute", "read_file", "write_file"},
+		HandledAccessNetwork: []string{"connect_tcp"},
+		PathBeneath: []configs.LandlockPathBeneath{
+			{AllowedAccess: []string{"execute", "read_file"}, Paths: []string{"/usr", "/bin"}},
+		},
+		NetPort: []configs.LandlockNetPort{
+			{AllowedAccess: []string{"connect_tcp"}, Ports: []uint64{443}},
+		},
+		DisableBestEffort: true,
+	}
+	if !reflect.DeepEqual(config.Landlock, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.Landlock)
+	}
+
+	spec.Annotations[AnnotationLandlock] = `{"ruleset": []}`
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestTmpfilesAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationTmpfiles: `
+# Comments and empty lines are ignored.
+d /run/app 0750 1000 1000 -
+L /etc/mtab - - - - ../proc/self/mounts
+f /etc/motd - - - - hello world
+z /var/lib/app - 1000
+`}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode, id := uint32(0o750), uint32(1000)
+	expected := []*configs.Tmpfile{
+		{Type: configs.TmpfileDir, Path: "/run/app", Mode: &mode, UID: &id, GID: &id},
+		{Type: configs.TmpfileSymlink, Path: "/etc/mtab", Argument: "../proc/self/mounts"},
+		{Type: configs.TmpfileFile, Path: "/etc/motd", Argument: "hello world"},
+		{Type: configs.TmpfileAdjust, Path: "/var/lib/app", UID: &id},
+	}
+	if !reflect.DeepEqual(config.Tmpfiles, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.Tmpfiles)
+	}
+
+	for _, v := range []string{
+		"d",
+		"d /run/app 0999",
+		"d /run/app 010000",
+		"d /run/app - root",
+		"d /run/app - - - 10d",
+	} {
+		spec.Annotations[AnnotationTmpfiles] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestSysfsWritableAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	for i := range spec.Mounts {
+		if spec.Mounts[i].Destination == "/sys" {
+			spec.Mounts[i].Options = slices.DeleteFunc(spec.Mounts[i].Options, func(o string) bool { return o == "ro" })
+		}
+	}
+	spec.Annotations = map[string]string{AnnotationSysfsWritable: "/sys/fs/cgroup, /sys/kernel/mm,"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"/sys/fs/cgroup", "/sys/kernel/mm"}
+	if !reflect.DeepEqual(config.SysfsWritable, expected) {
+		t.Errorf("expected %v, got %v", expected, config.SysfsWritable)
+	}
+	for _, m := range config.Mounts {
+		if m.Destination == "/sys" && m.Flags&unix.MS_RDONLY == 0 {
+			t.Error("expected /sys to be read-only")
+		}
+	}
+}
+
+func TestExecLimitsAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationExecLimits: "concurrent=2, rate=10/1m, total=100"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.ExecLimits{MaxConcurrent: 2, MaxPerWindow: 10, Window: time.Minute, MaxTotal: 100}
+	if !reflect.DeepEqual(config.ExecLimits, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.ExecLimits)
+	}
+
+	for _, v := range []string{
+		"",
+		"concurrent",
+		"concurrent=two",
+		"rate=10",
+		"rate=10/forever",
+		"processes=10",
+	} {
+		spec.Annotations[AnnotationExecLimits] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestRootfsQuotaAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationRootfsQuota: "size=10m, inodes=1000, project=42"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.RootfsQuota{Size: 10 << 20, Inodes: 1000, ProjectID: 42}
+	if !reflect.DeepEqual(config.RootfsQuota, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.RootfsQuota)
+	}
+
+	for _, v := range []string{
+		"",
+		"size",
+		"size=big",
+		"size=-1",
+		"inodes=-1",
+		"project=4294967296",
+		"blocks=10",
+	} {
+		spec.Annotations[AnnotationRootfsQuota] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestMachineIDAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationMachineID:     "0123456789ABCDEF0123456789abcdef",
+		AnnotationVirtualBootID: "true",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.MachineID != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("expected a lowercase machine ID, got %q", config.MachineID)
+	}
+	if !config.VirtualBootID {
+		t.Error("expected a virtual boot ID")
+	}
+
+	spec.Annotations[AnnotationMachineID] = "auto"
+	auto, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^[0-9a-f]{12}4[0-9a-f]{3}[89ab][0-9a-f]{15}$`).MatchString(auto.MachineID) {
+		t.Errorf("expected a generated machine ID, got %q", auto.MachineID)
+	}
+
+	spec.Annotations[AnnotationVirtualBootID] = "yes"
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestSignalForwardingAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationSignalsForward:   "SIGTERM=SIGINT, usr1=10",
+		AnnotationSignalsNoForward: "HUP",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.SignalForwarding{
+		Map:     map[unix.Signal]unix.Signal{unix.SIGTERM: unix.SIGINT, unix.SIGUSR1: unix.SIGUSR1},
+		Ignored: []unix.Signal{unix.SIGHUP},
+	}
+	if !reflect.DeepEqual(config.SignalForwarding, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.SignalForwarding)
+	}
+
+	for _, v := range []string{"SIGTERM", "SIGTERM=", "SIGFOO=SIGINT"} {
+		spec.Annotations[AnnotationSignalsForward] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestUsernsAutoAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Linux.Namespaces = append(spec.Linux.Namespaces, specs.LinuxNamespace{Type: specs.UserNamespace})
+	spec.Annotations = map[string]string{AnnotationUsernsAuto: "size=1000, pool=containers"}
+
+	// Without an allocator.
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	var pool string
+	opts := &CreateOpts{
+		Spec: spec,
+		AllocateUserns: func(poolUser string, size int64) (int64, error) {
+			pool = poolUser
+			return 100000, nil
+		},
+	}
+	config, err := CreateLibcontainerConfig(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []configs.IDMap{{ContainerID: 0, HostID: 100000, Size: 1000}}
+	if !config.UsernsAuto || pool != "containers" ||
+		!reflect.DeepEqual(config.UIDMappings, expected) || !reflect.DeepEqual(config.GIDMappings, expected) {
+		t.Errorf("expected %+v allocated from containers, got %+v and %+v from %s", expected, config.UIDMappings, config.GIDMappings, pool)
+	}
+	if uid, _ := config.HostRootUID(); uid != 100000 {
+		t.Errorf("expected host root uid 100000, got %d", uid)
+	}
+
+	for _, v := range []string{"size=0", "size=big", "pool=", "count=10"} {
+		spec.Annotations[AnnotationUsernsAuto] = v
+		if _, err := CreateLibcontainerConfig(opts); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+
+	// With mappings.
+	spec.Annotations[AnnotationUsernsAuto] = ""
+	spec.Linux.UIDMappings = []specs.LinuxIDMapping{{ContainerID: 0, HostID: 1000, Size: 1}}
+	if _, err := CreateLibcontainerConfig(opts); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	// Without a user namespace.
+	spec.Linux.UIDMappings = nil
+	spec.Linux.Namespaces = spec.Linux.Namespaces[:len(spec.Linux.Namespaces)-1]
+	if _, err := CreateLibcontainerConfig(opts); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestMemoryPolicyAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationMemoryPolicy: `{"mode": "MPOL_INTERLEAVE", "nodes": "0-1,3", "flags": ["MPOL_F_STATIC_NODES"]}`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.MemoryPolicy{
+		Mode:  configs.MPOL_INTERLEAVE,
+		Nodes: []int{0, 1, 3},
+		Flags: configs.MPOL_F_STATIC_NODES,
+	}
+	if !reflect.DeepEqual(config.MemoryPolicy, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.MemoryPolicy)
+	}
+
+	for _, v := range []string{"", "{}", `{"mode": "bind"}`, `{"mode": "MPOL_BIND", "nodes": "1-0"}`, `{"mode": "MPOL_BIND", "flags": ["MPOL_F_NONE"]}`} {
+		spec.Annotations[AnnotationMemoryPolicy] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestIPCLimitsAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationIPCLimits: "msgmax=64k, msgmni=1024, shmmax=1g, shm_rmid_forced=true, semmni=256, mqueue.msg_max=100, mqueue.msgsize_default=4096",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.IPCLimits{
+		MsgMax:               64 << 10,
+		MsgMni:               1024,
+		ShmMax:               1 << 30,
+		ShmRmidForced:        true,
+		SemMni:               256,
+		MqueueMsgMax:         100,
+		MqueueMsgSizeDefault: 4096,
+	}
+	if !reflect.DeepEqual(config.IPCLimits, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.IPCLimits)
+	}
+
+	for _, v := range []string{"", "msgmax", "msgmax=-1", "msgmni=0", "semmsl=many", "shm_rmid_forced=2", "mqueue.msgmax=10"} {
+		spec.Annotations[AnnotationIPCLimits] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestProbesAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationProbes: `[
+		{"type": "readiness", "args": ["/ready"], "interval": "1s", "failureThreshold": 1},
+		{"type": "liveness", "args": ["/alive", "-q"], "timeout": "5s"}
+	]`}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*configs.Probe{
+		{Type: configs.ProbeReadiness, Args: []string{"/ready"}, Interval: time.Second, Timeout: time.Second, FailureThreshold: 1},
+		{Type: configs.ProbeLiveness, Args: []string{"/alive", "-q"}, Interval: 10 * time.Second, Timeout: 5 * time.Second, FailureThreshold: 3},
+	}
+	if !reflect.DeepEqual(config.Probes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.Probes)
+	}
+
+	for _, v := range []string{
+		"",
+		`{"type": "readiness"}`,
+		`[{"type": "readiness", "args": ["/ready"], "interval": "often"}]`,
+	} {
+		spec.Annotations[AnnotationProbes] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestSpecDigest(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	const digest = "sha256:0123456789abcdef"
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec, SpecDigest: digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.SpecDigest != digest {
+		t.Errorf("expected spec digest %q, got %q", digest, config.SpecDigest)
+	}
+}
+
+func TestNetSysctlAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationNetSysctl: "no-ipv6-ra, net.core.somaxconn=4096,net.ipv6.conf.default.accept_ra=2"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"net.ipv6.conf.all.accept_ra":     "0",
+		"net.ipv6.conf.default.accept_ra": "2",
+		"net.core.somaxconn":              "4096",
+	}
+	if !reflect.DeepEqual(config.NetSysctl, expected) {
+		t.Errorf("expected %v, got %v", expected, config.NetSysctl)
+	}
+
+	for _, v := range []string{"", "no-such-preset", "=1"} {
+		spec.Annotations[AnnotationNetSysctl] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestIOCostAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationIOCostQoS:   "8:0 enable=1 rlat=5000\n\n259:0 enable=1 ctrl=auto\n",
+		AnnotationIOCostModel: "8:0 ctrl=user model=linear rbps=1000000",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var qos, model []string
+	for _, d := range config.IOCost.QoS {
+		qos = append(qos, d.String())
+	}
+	for _, d := range config.IOCost.Model {
+		model = append(model, d.String())
+	}
+	if !reflect.DeepEqual(qos, []string{"8:0 enable=1 rlat=5000", "259:0 ctrl=auto enable=1"}) {
+		t.Errorf("unexpected qos %q", qos)
+	}
+	if !reflect.DeepEqual(model, []string{"8:0 ctrl=user model=linear rbps=1000000"}) {
+		t.Errorf("unexpected model %q", model)
+	}
+
+	for _, v := range []string{"", "8:0", "8:0 enable=1\nsda enable=1"} {
+		spec.Annotations[AnnotationIOCostQoS] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestPowerHintAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationPowerHint: "epp=performance,uclamp.min=50"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := configs.PowerHint{EPP: "performance", UclampMin: "50"}
+	if config.PowerHint == nil || *config.PowerHint != expected {
+		t.Errorf("expected %+v, got %+v", expected, config.PowerHint)
+	}
+
+	spec.Annotations[AnnotationPowerHint] = "uclamp.min=200"
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestInitSignalsAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationInitSignalsBlocked: "USR1, 12",
+		AnnotationInitSignalsIgnored: "SIGPIPE",
+		AnnotationInitSignalsInherit: "true",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := config.InitSignals
+	if s == nil || !slices.Equal(s.Blocked, []unix.Signal{unix.SIGUSR1, unix.SIGUSR2}) ||
+		!slices.Equal(s.Ignored, []unix.Signal{unix.SIGPIPE}) || !s.Inherit {
+		t.Errorf("unexpected init signals: %+v", s)
+	}
+
+	for k, v := range map[string]string{
+		AnnotationInitSignalsBlocked: "SIGFOO",
+		AnnotationInitSignalsInherit: "maybe",
+	} {
+		spec.Annotations = map[string]string{k: v}
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%s=%s: expected error, got nil", k, v)
+		}
+	}
+}
+
+func TestHooksAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Hooks = &specs.Hooks{
+		CreateRuntime: []specs.Hook{{Path: "/a"}, {Path: "/b"}, {Path: "/c"}},
+		Poststop:      []specs.Hook{{Path: "/d"}},
+	}
+	spec.Annotations = map[string]string{AnnotationHooks: `{
+		"createRuntime": [{"parallel": true}, {"parallel": true, "retries": 2}, {"async": true}],
+		"poststop": [{"continueOnError": true}]
+	}`}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name     configs.HookName
+		index    int
+		expected configs.Command
+	}{
+		{configs.CreateRuntime, 0, configs.Command{Path: "/a", Parallel: true}},
+		{configs.CreateRuntime, 1, configs.Command{Path: "/b", Parallel: true, Retries: 2}},
+		{configs.CreateRuntime, 2, configs.Command{Path: "/c", Async: true}},
+		{configs.Poststop, 0, configs.Command{Path: "/d", ContinueOnError: true}},
+	} {
+		h := config.Hooks[tc.name][tc.index].(configs.CommandHook)
+		if !reflect.DeepEqual(*h.Command, tc.expected) {
+			t.Errorf("%s hook #%d: expected %+v, got %+v", tc.name, tc.index, tc.expected, *h.Command)
+		}
+	}
+
+	for _, v := range []string{
+		"",
+		`{"foo": [{"parallel": true}]}`,
+		`{"poststop": [{}, {"parallel": true}]}`,
+		`{"poststop": [{"retries": -1}]}`,
+	} {
+		spec.Annotations[AnnotationHooks] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestStrictSpec(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	opts := &CreateOpts{
+		CgroupName: "ContainerID",
+		Spec:       spec,
+		StrictSpec: true,
+	}
+	config, err := CreateLibcontainerConfig(opts)
+	if err != nil {
+		t.Fatalf("the example spec must be honored in the strict mode: %v", err)
+	}
+	if !config.StrictSpec {
+		t.Error("expected the strict spec mode to be in the configuration")
+	}
+
+	kmem := int64(1 << 20)
+	spec.Process.Capabilities.Bounding = append(spec.Process.Capabilities.Bounding, "CAP_UNKNOWN")
+	spec.Linux.Personality = &specs.LinuxPersonality{
+		Domain: specs.PerLinux,
+		Flags:  []specs.LinuxPersonalityFlag{"FOO"},
+	}
+	spec.Linux.Resources.Memory = &specs.LinuxMemory{Kernel: &kmem} //nolint:staticcheck // Ignore SA1019. Need to keep deprecated package for compatibility.
+	ignored, err := IgnoredFields(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"process.capabilities.bounding: unknown or unavailable capabilities CAP_UNKNOWN",
+		"linux.personality.flags: personality flags are not supported",
+		"linux.resources.memory.kernel: kernel memory settings are not supported",
+	}
+	if !slices.Equal(ignored, want) {
+		t.Fatalf("expected %q, got %q", want, ignored)
+	}
+
+	if _, err := CreateLibcontainerConfig(opts); err == nil {
+		t.Fatal("expected an error in the strict mode")
+	}
+	opts.StrictSpec = false
+	if _, err := CreateLibcontainerConfig(opts); err != nil {
+		t.Fatalf("the ignored fields must not be an error unless in the strict mode: %v", err)
+	}
+}
+
+func TestSeccompProfileAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationSeccompProfile: "strict&no-network"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Seccomp == nil || config.Seccomp.DefaultAction != configs.Errno {
+		t.Fatalf("expected the strict&no-network profile, got %+v", config.Seccomp)
+	}
+	for _, call := range config.Seccomp.Syscalls {
+		switch call.Name {
+		case "ptrace":
+			t.Error("ptrace is allowed")
+		case "socket":
+			if len(call.Args) != 1 || call.Args[0].Value != unix.AF_UNIX {
+				t.Errorf("socket is allowed with %+v", call.Args)
+			}
+		}
+	}
+
+	// The spec profile must be a part of the annotation.
+	spec.Linux.Seccomp = &specs.LinuxSeccomp{
+		DefaultAction: specs.ActErrno,
+		Syscalls:      []specs.LinuxSyscall{{Names: []string{"read", "ptrace"}, Action: specs.ActAllow}},
+	}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected an error for an unused linux.seccomp profile")
+	}
+	spec.Annotations[AnnotationSeccompProfile] = "spec&strict"
+	config, err = CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var allowed []string
+	for _, call := range config.Seccomp.Syscalls {
+		if call.Action == configs.Allow {
+			allowed = append(allowed, call.Name)
+		}
+	}
+	if !reflect.DeepEqual(allowed, []string{"read"}) {
+		t.Errorf("expected only read to be allowed, got %v", allowed)
+	}
+
+	for _, v := range []string{"", "nonexistent", "default|strict&no-network"} {
+		spec.Annotations[AnnotationSeccompProfile] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestCPUAffinityAnnotations(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Linux.Resources.CPU = &specs.LinuxCPU{Cpus: "2-3"}
+	spec.Annotations = map[string]string{
+		AnnotationInitCPUAffinity: `{"initial": "0", "final": "2-3"}`,
+		AnnotationIRQAffinity:     "true",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aff := config.InitCPUAffinity
+	if aff == nil || aff.Initial.Count() != 1 || !aff.Initial.IsSet(0) || aff.Final.Count() != 2 || !aff.Final.IsSet(3) {
+		t.Errorf("unexpected init CPU affinity %+v", aff)
+	}
+	if !config.IRQAffinity {
+		t.Error("expected IRQ affinity to be set")
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*specs.Spec)
+	}{
+		{"bad init affinity", func(s *specs.Spec) { s.Annotations[AnnotationInitCPUAffinity] = `{"initial": "x"}` }},
+		{"bad irq affinity", func(s *specs.Spec) { s.Annotations[AnnotationIRQAffinity] = "yes please" }},
+		{"no cpuset", func(s *specs.Spec) { s.Linux.Resources.CPU = nil }},
+		{"no netns", func(s *specs.Spec) {
+			s.Linux.Namespaces = slices.DeleteFunc(s.Linux.Namespaces, func(ns specs.LinuxNamespace) bool {
+				return ns.Type == specs.NetworkNamespace
+			})
+		}},
+	} {
+		spec := Example()
+		spec.Root.Path = "/"
+		spec.Linux.Resources.CPU = &specs.LinuxCPU{Cpus: "2-3"}
+		spec.Annotations = map[string]string{AnnotationIRQAffinity: "true"}
+		tc.modify(spec)
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
+	}
+}
+
+func TestPropagationPathsAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationPropagationPaths: `{"/var/lib/kubelet/pods": "slave", "/var/lib/kubelet": "rshared"}`,
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parent paths come first.
+	expected := []configs.PropagationPath{
+		{Path: "/var/lib/kubelet", Flags: unix.MS_SHARED | unix.MS_REC},
+		{Path: "/var/lib/kubelet/pods", Flags: unix.MS_SLAVE},
+	}
+	if !slices.Equal(config.PropagationPaths, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.PropagationPaths)
+	}
+
+	for _, v := range []string{`{"/mnt": "ro"}`, `["/mnt"]`} {
+		spec.Annotations[AnnotationPropagationPaths] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%s: expected error, got nil", v)
+		}
+	}
+}
+
+func TestNamespacesOwnerAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	for i, ns := range spec.Linux.Namespaces {
+		if ns.Type == specs.NetworkNamespace {
+			spec.Linux.Namespaces[i].Path = "/run/netns/a"
+		}
+	}
+	spec.Annotations = map[string]string{
+		AnnotationNamespacesOwner:      `{"network": {"uid": 1000, "userns_inode": 4026531837}}`,
+		AnnotationNamespacesOwnerCheck: "warn",
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.NamespaceOwnerCheck != configs.NamespaceOwnerCheckWarn {
+		t.Errorf("expected the warn policy, got %q", config.NamespaceOwnerCheck)
+	}
+	for _, ns := range config.Namespaces {
+		if ns.Type != configs.NEWNET {
+			if ns.Owner != nil {
+				t.Errorf("%s namespace: unexpected owner %+v", ns.Type, ns.Owner)
+			}
+			continue
+		}
+		if ns.Owner == nil || ns.Owner.UID == nil || *ns.Owner.UID != 1000 || ns.Owner.UsernsInode != 4026531837 || ns.Owner.Inode != 0 {
+			t.Errorf("unexpected network namespace owner %+v", ns.Owner)
+		}
+	}
+
+	// The example spec has no user namespace.
+	for _, v := range []string{`{"user": {"uid": 0}}`, `{"network": 1000}`} {
+		spec.Annotations[AnnotationNamespacesOwner] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%s: expected error, got nil", v)
+		}
+	}
+}
+
+func TestIntelRdtSharedAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationIntelRdtShared: "true"}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Error("expected error with no closID, got nil")
+	}
+
+	spec.Linux.IntelRdt = &specs.LinuxIntelRdt{ClosID: "shared", L3CacheSchema: "L3:0=f"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !config.IntelRdt.Shared || config.IntelRdt.ClosID != "shared" {
+		t.Errorf("expected a shared clos group, got %+v", config.IntelRdt)
+	}
+}
+
+func TestEnvDefaultsAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{AnnotationEnvDefaults: "if-missing"}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.EnvDefaults != configs.EnvDefaultsIfMissing {
+		t.Errorf("expected the if-missing policy, got %q", config.EnvDefaults)
+	}
+
+	for _, v := range []string{"", "sometimes"} {
+		spec.Annotations[AnnotationEnvDefaults] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
+
+func TestKeyringAnnotation(t *testing.T) {
+	spec := Example()
+	spec.Root.Path = "/"
+	spec.Annotations = map[string]string{
+		AnnotationKeyring: `{"name": "krb5", "links": [{"type": "user", "description": "krb5cc"}], "quota": {"maxkeys": 1000}}`,
+	}
+	if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec}); err == nil {
+		t.Fatal("expected the annotation not to be allowed by default")
+	}
+	config, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec, AllowKeyring: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &configs.Keyring{
+		Name:  "krb5",
+		Links: []configs.KeyLink{{Type: "user", Description: "krb5cc"}},
+		Quota: &configs.KeyringQuota{MaxKeys: 1000},
+	}
+	if !reflect.DeepEqual(config.Keyring, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config.Keyring)
+	}
+
+	for _, v := range []string{"", "krb5", `{"nmae": "krb5"}`, `{"quota": {}}`} {
+		spec.Annotations[AnnotationKeyring] = v
+		if _, err := CreateLibcontainerConfig(&CreateOpts{Spec: spec, AllowKeyring: true}); err == nil {
+			t.Errorf("%q: expected error, got nil", v)
+		}
+	}
+}
@@ -3,8 +3,10 @@ package libcontainer
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"os/exec"
+	"slices"
 
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/opencontainers/selinux/go-selinux"
@@ -15,6 +17,7 @@ import (
 	"github.com/opencontainers/runc/libcontainer/apparmor"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/keys"
+	"github.com/opencontainers/runc/libcontainer/landlock"
 	"github.com/opencontainers/runc/libcontainer/seccomp"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
@@ -43,7 +46,29 @@ func (l *linuxStandardInit) getSessionRingParams() (string, uint32, uint32) {
 
 	// Create a unique per session container name that we can join in setns;
 	// However, other containers can also join it.
-	return "_ses." + l.config.ContainerID, 0xffffffff, newperms
+	return l.config.Config.Keyring.SessionName(l.config.ContainerID), 0xffffffff, newperms
+}
+
+// holdKeyLinks finds the host keys to link into the session keyring (see
+// [configs.Keyring.Links]), and links them into the thread keyring, for
+// them to stay possessed once the session keyring of the caller is left.
+// The thread keyring is discarded at the exec of the container process.
+func (l *linuxStandardInit) holdKeyLinks() ([]keys.KeySerial, error) {
+	if l.config.Config.Keyring == nil {
+		return nil, nil
+	}
+	var ids []keys.KeySerial
+	for _, k := range l.config.Config.Keyring.Links {
+		id, err := keys.Search(unix.KEY_SPEC_SESSION_KEYRING, k.Type, k.Description, unix.KEY_SPEC_THREAD_KEYRING)
+		if errors.Is(err, unix.ENOKEY) {
+			id, err = keys.Search(unix.KEY_SPEC_USER_KEYRING, k.Type, k.Description, unix.KEY_SPEC_THREAD_KEYRING)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to find host key %s:%s: %w", k.Type, k.Description, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
 }
 
 func (l *linuxStandardInit) Init() error {
@@ -55,6 +80,10 @@ func (l *linuxStandardInit) Init() error {
 			defer selinux.SetKeyLabel("") //nolint: errcheck
 		}
 		ringname, keepperms, newperms := l.getSessionRingParams()
+		keyLinks, err := l.holdKeyLinks()
+		if err != nil {
+			return err
+		}
 
 		// Do not inherit the parent's session keyring.
 		if sessKeyId, err := keys.JoinSessionKeyring(ringname); err != nil {
@@ -68,6 +97,14 @@ func (l *linuxStandardInit) Init() error {
 				return fmt.Errorf("unable to join session keyring: %w", err)
 			}
 		} else {
+			for _, id := range keyLinks {
+				if err := keys.Link(id, sessKeyId); err != nil {
+					return fmt.Errorf("unable to link host key %d into session keyring: %w", id, err)
+				}
+			}
+			if len(keyLinks) > 0 {
+				_ = keys.Clear(unix.KEY_SPEC_THREAD_KEYRING)
+			}
 			// Make session keyring searchable. If we've gotten this far we
 			// bail on any error -- we don't want to have a keyring with bad
 			// permissions.
@@ -77,6 +114,15 @@ func (l *linuxStandardInit) Init() error {
 		}
 	}
 
+	// The net sysctls are set before the network is configured, and the
+	// hooks (which may configure it too) are run. The sysctls are set in
+	// the order of their names, so that the container creation is
+	// reproducible.
+	for _, key := range slices.Sorted(maps.Keys(l.config.Config.NetSysctl)) {
+		if err := writeSystemProperty(key, l.config.Config.NetSysctl[key]); err != nil {
+			return err
+		}
+	}
 	if err := setupNetwork(l.config); err != nil {
 		return err
 	}
@@ -131,8 +177,11 @@ func (l *linuxStandardInit) Init() error {
 		return fmt.Errorf("unable to apply apparmor profile: %w", err)
 	}
 
-	for key, value := range l.config.Config.Sysctl {
-		if err := writeSystemProperty(key, value); err != nil {
+	if err := setupIPCLimits(l.config.Config.IPCLimits); err != nil {
+		return fmt.Errorf("unable to set the IPC limits: %w", err)
+	}
+	for _, key := range slices.Sorted(maps.Keys(l.config.Config.Sysctl)) {
+		if err := writeSystemProperty(key, l.config.Config.Sysctl[key]); err != nil {
 			return err
 		}
 	}
@@ -146,6 +195,18 @@ func (l *linuxStandardInit) Init() error {
 			return fmt.Errorf("can't mask path %s: %w", path, err)
 		}
 	}
+	if l.config.Config.Namespaces.Contains(configs.NEWNS) {
+		if err := checkReadonly(l.config.Config); err != nil {
+			return err
+		}
+	}
+	// The Landlock ruleset paths are resolved in the container root, but
+	// the ruleset is only enforced right before seccomp, see below.
+	landlockRuleset, err := landlock.NewRuleset(l.config.Config.Landlock)
+	if err != nil {
+		return fmt.Errorf("unable to set up landlock: %w", err)
+	}
+	defer landlockRuleset.Close()
 	pdeath, err := system.GetParentDeathSignal()
 	if err != nil {
 		return fmt.Errorf("can't get pdeath signal: %w", err)
@@ -164,6 +225,10 @@ func (l *linuxStandardInit) Init() error {
 		return err
 	}
 
+	if err := setupMemoryPolicy(l.config.Config); err != nil {
+		return err
+	}
+
 	// Tell our parent that we're ready to exec. This must be done before the
 	// Seccomp rules have been applied, because we need to be able to read and
 	// write to a socket.
@@ -176,9 +241,21 @@ func (l *linuxStandardInit) Init() error {
 		}
 		defer selinux.SetExecLabel("") //nolint: errcheck
 	}
+	// Set up the signals before seccomp, as the profile may not allow the
+	// rt_sigprocmask and rt_sigaction syscalls this needs. The StartContainer
+	// hooks inherit them.
+	if err := setupInitSignals(l.config.Config.InitSignals); err != nil {
+		return fmt.Errorf("unable to set up signals: %w", err)
+	}
 	// Without NoNewPrivileges seccomp is a privileged operation, so we need to
 	// do this before dropping capabilities; otherwise do it as late as possible
 	// just before execve so as few syscalls take place after it as possible.
+	// Landlock is enforced right before it, either way.
+	if !l.config.NoNewPrivileges {
+		if err := l.restrictLandlock(landlockRuleset); err != nil {
+			return err
+		}
+	}
 	if l.config.Config.Seccomp != nil && !l.config.NoNewPrivileges {
 		seccompFd, err := seccomp.InitSeccomp(l.config.Config.Seccomp)
 		if err != nil {
@@ -222,6 +299,12 @@ func (l *linuxStandardInit) Init() error {
 		return err
 	}
 
+	if l.config.NoNewPrivileges {
+		if err := l.restrictLandlock(landlockRuleset); err != nil {
+			return err
+		}
+	}
+
 	// Set seccomp as close to execve as possible, so as few syscalls take
 	// place afterward (reducing the amount of syscalls that users need to
 	// enable in their seccomp profiles). However, this needs to be done
@@ -281,7 +364,7 @@ func (l *linuxStandardInit) Init() error {
 	if s := l.config.SpecState; s != nil {
 		s.Pid = unix.Getpid()
 		s.Status = specs.StateCreated
-		if err := l.config.Config.Hooks.Run(configs.StartContainer, s); err != nil {
+		if _, err := l.config.Config.Hooks.Run(configs.StartContainer, s); err != nil {
 			return err
 		}
 	}
@@ -301,3 +384,18 @@ func (l *linuxStandardInit) Init() error {
 	}
 	return linux.Exec(name, l.config.Args, l.config.Env)
 }
+
+// restrictLandlock enforces the Landlock ruleset. This is done before the
+// seccomp filter is installed, as it may deny the Landlock syscalls. The
+// ruleset also applies to the StartContainer hooks, which are run in the
+// container; the exec fifo is allowed to be opened for writing, to start
+// the container.
+func (l *linuxStandardInit) restrictLandlock(ruleset *landlock.Ruleset) error {
+	if err := ruleset.AllowWrite(l.fifoFile); err != nil {
+		return fmt.Errorf("unable to set up landlock: %w", err)
+	}
+	if err := ruleset.RestrictSelf(); err != nil {
+		return fmt.Errorf("unable to apply landlock ruleset: %w", err)
+	}
+	return nil
+}
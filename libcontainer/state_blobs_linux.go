@@ -0,0 +1,236 @@
+package libcontainer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// blobsDir is the directory in root where the large fields of the
+	// container states are stored, content-addressed, so that they are
+	// shared by the containers with identical configurations. Like
+	// groupsDir, it never clashes with a container state directory.
+	blobsDir = "@blobs"
+	// blobMinSize is the minimum JSON size of a state field stored in a
+	// blob, rather than in state.json.
+	blobMinSize = 1024
+	// blobRefPrefix is the prefix of the value of a state field stored in a
+	// blob, followed by the hex SHA-256 digest of the field JSON. As it is
+	// a string rather than the field object, an older runc version fails to
+	// decode the state, rather than ignoring the field.
+	blobRefPrefix = "sha256:"
+	// stateVersionBlobs is the version of the states with blobs, see
+	// [State.StateVersion]. A state of a later version is rejected.
+	stateVersionBlobs = 1
+)
+
+// blobFields are the JSON paths of the state fields which may be stored in
+// blobs, those which may be large and are usually identical across
+// containers.
+var blobFields = [][]string{
+	{"config", "seccomp"},
+	{"config", "exec_seccomp"},
+	{"config", "devices"},
+	{"config", "cgroups", "devices"},
+}
+
+// blobName returns the name of the file of the blob of digest in a container
+// state directory.
+func blobName(digest string) string {
+	return "blob-" + digest + ".json"
+}
+
+// encodeState returns the state.json content of s and, if the container
+// opted in (see [configs.Config.StateBlobs]), with its large fields stored in
+// blobs in the container state directory (see blobFields), along with the
+// digests of those blobs. Otherwise, the state is self-contained.
+func (c *Container) encodeState(s *State) ([]byte, []string, error) {
+	s.StateVersion = 0
+	if s.Config.StateBlobs {
+		s.StateVersion = stateVersionBlobs
+	}
+	data, err := json.Marshal(s)
+	if err != nil || !s.Config.StateBlobs {
+		return data, nil, err
+	}
+	var digests []string
+	for _, path := range blobFields {
+		data, err = replaceField(data, path, func(v json.RawMessage) (json.RawMessage, error) {
+			if len(v) < blobMinSize {
+				return nil, nil
+			}
+			digest, err := c.writeBlob(v)
+			if err != nil {
+				return nil, err
+			}
+			digests = append(digests, digest)
+			return json.Marshal(blobRefPrefix + digest)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, digests, nil
+}
+
+// decodeState decodes the state.json content data of the container state
+// directory dir, reading the fields stored in blobs, if any.
+func decodeState(dir string, data []byte) (*State, error) {
+	if bytes.Contains(data, []byte(`"state_version"`)) {
+		var v struct {
+			StateVersion int `json:"state_version"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		if v.StateVersion > stateVersionBlobs {
+			return nil, fmt.Errorf("unsupported state version %d", v.StateVersion)
+		}
+		var err error
+		for _, path := range blobFields {
+			data, err = replaceField(data, path, func(v json.RawMessage) (json.RawMessage, error) {
+				var ref string
+				if json.Unmarshal(v, &ref) != nil || !strings.HasPrefix(ref, blobRefPrefix) {
+					return nil, nil
+				}
+				return readBlob(dir, strings.TrimPrefix(ref, blobRefPrefix))
+			})
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", strings.Join(path, "."), err)
+			}
+		}
+	}
+	var state *State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// replaceField replaces the value of the field at path in the JSON object
+// data with the value returned by fn, unless it is nil. A missing or null
+// field is left as is.
+func replaceField(data []byte, path []string, fn func(json.RawMessage) (json.RawMessage, error)) ([]byte, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	v, ok := obj[path[0]]
+	if !ok || string(v) == "null" {
+		return data, nil
+	}
+	var (
+		nv  json.RawMessage
+		err error
+	)
+	if len(path) > 1 {
+		nv, err = replaceField(v, path[1:], fn)
+		if err == nil && bytes.Equal(nv, v) {
+			nv = nil
+		}
+	} else {
+		nv, err = fn(v)
+	}
+	if err != nil || nv == nil {
+		return data, err
+	}
+	obj[path[0]] = nv
+	return json.Marshal(obj)
+}
+
+// writeBlob stores data in the blob file of the container state directory,
+// linked to the shared one of the same content (if any), and returns its
+// digest.
+func (c *Container) writeBlob(data []byte) (string, error) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	path := filepath.Join(c.stateDir, blobName(digest))
+	if _, err := os.Lstat(path); err == nil {
+		return digest, nil
+	}
+	shared := filepath.Join(filepath.Dir(c.stateDir), blobsDir, digest+".json")
+	if err := os.Link(shared, path); err == nil {
+		return digest, nil
+	}
+	tmpFile, err := os.CreateTemp(c.stateDir, "blob-")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmpFile.Write(data)
+	if err1 := tmpFile.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmpFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmpFile.Name())
+		return "", err
+	}
+	// Share the blob with the next containers, as far as possible.
+	if err := os.Mkdir(filepath.Dir(shared), 0o700); err == nil || errors.Is(err, os.ErrExist) {
+		_ = os.Link(path, shared)
+	}
+	return digest, nil
+}
+
+// readBlob reads the blob of digest in the container state directory dir,
+// verifying its content.
+func readBlob(dir, digest string) ([]byte, error) {
+	if _, err := hex.DecodeString(digest); err != nil || len(digest) != sha256.Size*2 {
+		return nil, fmt.Errorf("invalid blob digest %q", digest)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, blobName(digest)))
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != digest {
+		return nil, fmt.Errorf("blob %s: digest mismatch", digest)
+	}
+	return data, nil
+}
+
+// removeUnusedBlobs removes the blob files of the container state directory
+// which are not among digests, then the shared blobs which are no longer
+// linked to any container state directory.
+func (c *Container) removeUnusedBlobs(digests []string) {
+	names, _ := filepath.Glob(filepath.Join(c.stateDir, blobName("*")))
+	removed := false
+	for _, name := range names {
+		digest := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(name), "blob-"), ".json")
+		if !slices.Contains(digests, digest) {
+			_ = os.Remove(name)
+			removed = true
+		}
+	}
+	if removed {
+		removeSharedBlobs(filepath.Dir(c.stateDir))
+	}
+}
+
+// removeSharedBlobs removes the shared blobs of root which are no longer
+// linked to any container state directory. A blob removed while a container
+// links it is only no longer shared with the next containers.
+func removeSharedBlobs(root string) {
+	entries, err := os.ReadDir(filepath.Join(root, blobsDir))
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(root, blobsDir, e.Name())
+		var st unix.Stat_t
+		if err := unix.Lstat(path, &st); err == nil && st.Nlink <= 1 {
+			_ = os.Remove(path)
+		}
+	}
+}
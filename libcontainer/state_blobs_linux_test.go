@@ -0,0 +1,168 @@
+package libcontainer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func testStateContainer(t *testing.T, root, id string) *Container {
+	t.Helper()
+	dir := filepath.Join(root, id)
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	return &Container{id: id, stateDir: dir}
+}
+
+func testStateSeccomp() *configs.Seccomp {
+	s := &configs.Seccomp{DefaultAction: configs.Errno}
+	for i := range 100 {
+		s.Syscalls = append(s.Syscalls, &configs.Syscall{Name: "syscall" + strconv.Itoa(i), Action: configs.Allow})
+	}
+	return s
+}
+
+func stat(t *testing.T, path string) *unix.Stat_t {
+	t.Helper()
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		t.Fatal(err)
+	}
+	return &st
+}
+
+func TestStateBlobs(t *testing.T) {
+	root := t.TempDir()
+	c1 := testStateContainer(t, root, "c1")
+	c2 := testStateContainer(t, root, "c2")
+	state := &State{BaseState: BaseState{ID: "c1", Config: configs.Config{
+		Seccomp:    testStateSeccomp(),
+		Hostname:   "small",
+		Cgroups:    &cgroups.Cgroup{},
+		StateBlobs: true,
+	}}}
+
+	if err := c1.saveState(state); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(c1.stateDir, stateFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"seccomp":"sha256:`)) || bytes.Contains(data, []byte("syscall99")) {
+		t.Fatalf("expected the seccomp config in a blob, got %s", data)
+	}
+	loaded, err := loadState(c1.stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Config.Seccomp, state.Config.Seccomp) || loaded.Config.Hostname != "small" {
+		t.Errorf("expected %+v, got %+v", state.Config, loaded.Config)
+	}
+
+	// The blob is shared with a container of the same config.
+	state.ID = "c2"
+	if err := c2.saveState(state); err != nil {
+		t.Fatal(err)
+	}
+	blobs, _ := filepath.Glob(filepath.Join(root, blobsDir, "*"))
+	if len(blobs) != 1 {
+		t.Fatalf("expected a single shared blob, got %v", blobs)
+	}
+	if n := stat(t, blobs[0]).Nlink; n != 3 {
+		t.Errorf("expected the blob to have 3 links, got %d", n)
+	}
+
+	// An unchanged state is not written again.
+	statePath := filepath.Join(c2.stateDir, stateFilename)
+	before := stat(t, statePath).Ino
+	if err := c2.saveState(state); err != nil {
+		t.Fatal(err)
+	}
+	if after := stat(t, statePath).Ino; after != before {
+		t.Error("expected the unchanged state not to be written again")
+	}
+
+	// A blob no longer used is removed, along with the shared one once no
+	// container uses it.
+	state.Config.Seccomp = nil
+	if err := c2.saveState(state); err != nil {
+		t.Fatal(err)
+	}
+	if n := stat(t, blobs[0]).Nlink; n != 2 {
+		t.Errorf("expected the blob to have 2 links, got %d", n)
+	}
+	if err := os.RemoveAll(c1.stateDir); err != nil {
+		t.Fatal(err)
+	}
+	removeSharedBlobs(root)
+	if _, err := os.Stat(blobs[0]); !os.IsNotExist(err) {
+		t.Errorf("expected the unused shared blob to be removed, got %v", err)
+	}
+}
+
+func TestStateBlobCorrupted(t *testing.T) {
+	root := t.TempDir()
+	c := testStateContainer(t, root, "c")
+	state := &State{BaseState: BaseState{ID: "c", Config: configs.Config{Seccomp: testStateSeccomp(), Cgroups: &cgroups.Cgroup{}, StateBlobs: true}}}
+	if err := c.saveState(state); err != nil {
+		t.Fatal(err)
+	}
+	blobs, _ := filepath.Glob(filepath.Join(c.stateDir, blobName("*")))
+	if len(blobs) != 1 {
+		t.Fatalf("expected a single blob, got %v", blobs)
+	}
+	// Break the link with the shared blob, then alter the content.
+	data, err := os.ReadFile(blobs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(blobs[0])
+	if err := os.WriteFile(blobs[0], bytes.Replace(data, []byte("syscall1"), []byte("syscallX"), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(c.stateDir); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestStateSelfContained(t *testing.T) {
+	root := t.TempDir()
+	c := testStateContainer(t, root, "c")
+	state := &State{BaseState: BaseState{ID: "c", Config: configs.Config{Seccomp: testStateSeccomp(), Cgroups: &cgroups.Cgroup{}}}}
+	if err := c.saveState(state); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(c.stateDir, stateFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("syscall99")) || bytes.Contains(data, []byte("state_version")) {
+		t.Fatalf("expected a self-contained state, got %s", data)
+	}
+	if blobs, _ := filepath.Glob(filepath.Join(c.stateDir, blobName("*"))); len(blobs) != 0 {
+		t.Errorf("expected no blobs, got %v", blobs)
+	}
+	if _, err := os.Stat(filepath.Join(root, blobsDir)); !os.IsNotExist(err) {
+		t.Errorf("expected no shared blobs, got %v", err)
+	}
+}
+
+func TestStateVersionUnsupported(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, stateFilename), []byte(`{"id":"c","state_version":2}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadState(dir); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -1,16 +1,26 @@
 package libcontainer
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 
+	"github.com/moby/sys/mountinfo"
 	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/runc/internal/trace"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 )
 
+// unmountWorkers is the maximum number of leftover mounts of a container
+// that are unmounted at once on destroy.
+const unmountWorkers = 8
+
 func newStateTransitionError(from, to containerState) error {
 	return &stateTransitionError{
 		From: from.status().String(),
@@ -35,7 +45,10 @@ type containerState interface {
 	status() Status
 }
 
-func destroy(c *Container) error {
+func destroy(c *Container) (retErr error) {
+	span := trace.Start("container.destroy")
+	defer func() { span.End(retErr) }()
+
 	// Usually, when a container init is gone, all other processes in its
 	// cgroup are killed by the kernel. This is not the case for a shared
 	// PID namespace container, which may have some processes left after
@@ -48,26 +61,147 @@ func destroy(c *Container) error {
 		// Likely to fail when c.config.RootlessCgroups is true
 		_ = signalAllProcesses(c.cgroupManager, unix.SIGKILL)
 	}
-	if err := c.cgroupManager.Destroy(); err != nil {
-		return fmt.Errorf("unable to remove container's cgroup: %w", err)
-	}
+	// The cgroup, the Intel RDT group, and the leftover mounts are
+	// independent, and each of them may take a while to remove, so they are
+	// removed at once. The host
+	// resources of the container (such as its user namespace ID range) are
+	// only released, and the state directory removed, once they are both
+	// gone, as the container processes may still be alive otherwise, so that
+	// a failed destroy can be retried.
+	var (
+		wg                sync.WaitGroup
+		cgroupErr, rdtErr error
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cgroupErr = destroyCgroup(c)
+	}()
 	if c.intelRdtManager != nil {
-		if err := c.intelRdtManager.Destroy(); err != nil {
-			return fmt.Errorf("unable to remove container's IntelRDT group: %w", err)
-		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rdtErr = c.intelRdtManager.Destroy()
+		}()
+	}
+	if c.config.RootPropagation&unix.MS_SHARED != 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := unmountLeftovers(c.config.Rootfs); err != nil {
+				logrus.Warnf("unable to remove the leftover mounts of the container: %v", err)
+			}
+		}()
+	}
+	c.closeAsyncHooks()
+	wg.Wait()
+	if cgroupErr != nil {
+		return fmt.Errorf("unable to remove container's cgroup: %w", cgroupErr)
+	}
+	if rdtErr != nil {
+		return fmt.Errorf("unable to remove container's IntelRDT group: %w", rdtErr)
 	}
+	c.teardownSwap()
+	c.teardownRootfsQuota()
+	c.releaseUserns()
+	c.restorePowerHint()
+	c.restoreNetDevices()
+	c.restoreIOCost()
+	c.restoreIRQAffinity()
 	if err := os.RemoveAll(c.stateDir); err != nil {
 		return fmt.Errorf("unable to remove container state dir: %w", err)
 	}
+	if c.config.StateBlobs {
+		removeSharedBlobs(filepath.Dir(c.stateDir))
+	}
 	c.initProcess = nil
 	err := runPoststopHooks(c)
 	c.state = &stoppedState{c: c}
 	return err
 }
 
+// destroyCgroup removes the container cgroup. With cgroup v1 (and no
+// systemd), the cgroups of the hierarchies are first removed concurrently,
+// as removing each of them may involve retries while its last processes are
+// going away.
+func destroyCgroup(c *Container) error {
+	if paths := c.cgroupManager.GetPaths(); len(paths) > 1 && !c.config.Cgroups.Systemd {
+		var wg sync.WaitGroup
+		for _, path := range paths {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Any error is reported by Destroy, which tries again.
+				_ = cgroups.RemovePath(path)
+			}()
+		}
+		wg.Wait()
+	}
+	return c.cgroupManager.Destroy()
+}
+
+// unmountLeftovers unmounts the mounts below rootfs in the current mount
+// namespace. With a shared rootfs propagation, these are the mounts of the
+// container propagated to the host, which are left behind once the
+// container mount namespace is gone. There may be hundreds of them (such as
+// with many device bind mounts), so they are unmounted concurrently.
+func unmountLeftovers(rootfs string) error {
+	rootfs = filepath.Clean(rootfs)
+	mounts, err := mountinfo.GetMounts(mountinfo.PrefixFilter(rootfs))
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(mounts))
+	for _, m := range mounts {
+		if strings.HasPrefix(m.Mountpoint, rootfs+"/") {
+			paths = append(paths, m.Mountpoint)
+		}
+	}
+	return unmountAll(paths, func(path string) error {
+		err := unix.Unmount(path, unix.MNT_DETACH)
+		// A mount below another one is gone once the latter is unmounted.
+		if errors.Is(err, unix.EINVAL) || errors.Is(err, unix.ENOENT) {
+			return nil
+		}
+		if err != nil {
+			return &os.PathError{Op: "unmount", Path: path, Err: err}
+		}
+		return nil
+	})
+}
+
+// unmountAll calls unmount for each of paths, using up to unmountWorkers
+// goroutines, and returns all the errors.
+func unmountAll(paths []string, unmount func(string) error) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	ch := make(chan string)
+	for range min(unmountWorkers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range ch {
+				if err := unmount(path); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, path := range paths {
+		ch <- path
+	}
+	close(ch)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
 func runPoststopHooks(c *Container) error {
-	hooks := c.config.Hooks
-	if hooks == nil {
+	if c.config.Hooks == nil {
 		return nil
 	}
 
@@ -77,7 +211,7 @@ func runPoststopHooks(c *Container) error {
 	}
 	s.Status = specs.StateStopped
 
-	return hooks.Run(configs.Poststop, s)
+	return c.runHooks(configs.Poststop, s)
 }
 
 // stoppedState represents a container is a stopped/destroyed state.
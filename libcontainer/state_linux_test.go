@@ -1,9 +1,23 @@
 package libcontainer
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/moby/sys/mountinfo"
+	"github.com/opencontainers/cgroups"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
 )
 
 var states = map[containerState]Status{
@@ -109,3 +123,159 @@ func TestCreatedStateTransition(t *testing.T) {
 		},
 	)
 }
+
+func TestDestroy(t *testing.T) {
+	dir := t.TempDir()
+	paths := map[string]string{}
+	for _, ctrl := range []string{"cpu", "memory", "pids"} {
+		paths[ctrl] = filepath.Join(dir, ctrl, "ctr")
+		// A sub-cgroup, which is removed first.
+		if err := os.MkdirAll(filepath.Join(paths[ctrl], "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stateDir := filepath.Join(dir, "state")
+	if err := os.Mkdir(stateDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	old := usernsAutoFile
+	usernsAutoFile = filepath.Join(dir, "userns-auto.json")
+	t.Cleanup(func() { usernsAutoFile = old })
+	allocs, err := json.Marshal([]usernsAllocation{{StateDir: stateDir, HostID: 100000, Size: 65536, Pid: -1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(usernsAutoFile, allocs, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	usernsAllocated := func() bool {
+		data, err := os.ReadFile(usernsAutoFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.Contains(data, []byte(stateDir))
+	}
+
+	cm := &mockCgroupManager{paths: paths, destroyErr: errors.New("busy")}
+	c := &Container{
+		config: &configs.Config{
+			Namespaces: configs.Namespaces{{Type: configs.NEWPID}},
+			Cgroups:    &cgroups.Cgroup{},
+			UsernsAuto: true,
+		},
+		cgroupManager: cm,
+		stateDir:      stateDir,
+	}
+
+	// The state directory is kept if the cgroup can't be removed.
+	if err := destroy(c); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(stateDir); err != nil {
+		t.Fatalf("expected the state directory to be kept: %v", err)
+	}
+	// So is the user namespace ID range, which the container processes may
+	// still use.
+	if !usernsAllocated() {
+		t.Error("expected the user namespace ID range to be kept")
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", path, err)
+		}
+	}
+
+	cm.destroyErr = nil
+	if err := destroy(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(stateDir); !os.IsNotExist(err) {
+		t.Errorf("expected the state directory to be removed, got %v", err)
+	}
+	if usernsAllocated() {
+		t.Error("expected the user namespace ID range to be released")
+	}
+}
+
+func TestUnmountAllConcurrent(t *testing.T) {
+	paths := make([]string, 100)
+	for i := range paths {
+		paths[i] = "/rootfs/dev/" + strconv.Itoa(i)
+	}
+	var (
+		inFlight, maxInFlight atomic.Int32
+		mu                    sync.Mutex
+		done                  []string
+	)
+	// Each unmount waits for the others, until either unmountWorkers of them
+	// are running at once, or a timeout if the teardown is sequential.
+	full := make(chan struct{})
+	var fullOnce sync.Once
+	err := unmountAll(paths, func(path string) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if n == unmountWorkers {
+			fullOnce.Do(func() { close(full) })
+		}
+		select {
+		case <-full:
+		case <-time.After(5 * time.Second):
+			fullOnce.Do(func() { close(full) })
+		}
+		mu.Lock()
+		done = append(done, path)
+		mu.Unlock()
+		if path == paths[42] {
+			return errors.New("busy")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Error("expected the unmount error to be returned")
+	}
+	if len(done) != len(paths) {
+		t.Errorf("expected %d unmounts, got %d", len(paths), len(done))
+	}
+	if n := maxInFlight.Load(); n != unmountWorkers {
+		t.Errorf("expected %d concurrent unmounts, got %d", unmountWorkers, n)
+	}
+}
+
+func TestUnmountLeftovers(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	rootfs := t.TempDir()
+	var paths []string
+	for _, dir := range []string{"dev", "dev/pts", "proc", "sys"} {
+		path := filepath.Join(rootfs, dir)
+		if err := os.MkdirAll(path, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := unix.Mount("tmpfs", path, "tmpfs", 0, ""); err != nil {
+			t.Skip(err)
+		}
+		paths = append(paths, path)
+	}
+	defer func() {
+		for _, path := range paths {
+			_ = unix.Unmount(path, unix.MNT_DETACH)
+		}
+	}()
+
+	if err := unmountLeftovers(rootfs); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		// dev/pts is gone with the dev tmpfs.
+		if mounted, err := mountinfo.Mounted(path); (err != nil && !os.IsNotExist(err)) || mounted {
+			t.Errorf("expected %s to be unmounted, got %v, %v", path, mounted, err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/opencontainers/cgroups"
+
+	"github.com/opencontainers/runc/libcontainer/bpfstats"
+)
+
+// ErrNoBPFStats is returned by BPFStats if the usage does not include the
+// container cgroup.
+var ErrNoBPFStats = errors.New("no BPF stats for the container cgroup")
+
+// CgroupPath returns the container cgroup v2 path, as collected by
+// [bpfstats.Collector.Collect], or an empty string on a cgroup v1 host.
+func (c *Container) CgroupPath() string {
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return ""
+	}
+	return c.cgroupManager.Path("")
+}
+
+// BPFStats returns the container stats from the cgroup usage collected by
+// [bpfstats.Collector.Collect], which only has the CPU, memory, and pids
+// usage (no limits, and none of the other stats of Stats). The usage is
+// to include the container cgroup (see CgroupPath).
+func (c *Container) BPFStats(usage map[uint64]*bpfstats.Usage) (*Stats, error) {
+	path := c.CgroupPath()
+	if path == "" {
+		return nil, errors.New("BPF stats require cgroup v2")
+	}
+	id, err := bpfstats.CgroupID(path)
+	if err != nil {
+		return nil, err
+	}
+	u, ok := usage[id]
+	if !ok {
+		return nil, fmt.Errorf("%w (%s)", ErrNoBPFStats, path)
+	}
+	s := cgroups.NewStats()
+	s.CpuStats.CpuUsage.TotalUsage = u.CPUTotal
+	s.CpuStats.CpuUsage.UsageInUsermode = u.CPUUser
+	s.CpuStats.CpuUsage.UsageInKernelmode = u.CPUSystem
+	if u.HasMemory {
+		s.MemoryStats.Usage.Usage = u.Memory
+	}
+	if u.HasPids {
+		s.PidsStats.Current = u.Pids
+	}
+	return &Stats{CgroupStats: s}, nil
+}
@@ -10,4 +10,24 @@ type Stats struct {
 	Interfaces    []*types.NetworkInterface
 	CgroupStats   *cgroups.Stats
 	IntelRdtStats *intelrdt.Stats
+	// HelperCgroupStats are the stats of the helper sub-cgroup of the
+	// container (see [configs.Config.HelperCgroup]), which are also a
+	// part of CgroupStats.
+	HelperCgroupStats *cgroups.Stats
+	// RootfsQuotaStats is the disk usage of the root filesystem, if it has
+	// a quota (see [configs.Config.RootfsQuota]).
+	RootfsQuotaStats *RootfsQuotaStats
+	// SwapStats are the cgroup v2 swap and zswap stats, if available.
+	SwapStats *SwapStats
+}
+
+// RootfsQuotaStats is the disk usage of the root filesystem project, and
+// its limits (zero meaning no limit).
+type RootfsQuotaStats struct {
+	ProjectID uint32
+	// Limit and Usage are in bytes.
+	Limit       uint64
+	Usage       uint64
+	InodesLimit uint64
+	InodesUsage uint64
 }
@@ -0,0 +1,97 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+// requestedControllers returns the names of the cgroup v1 controllers which
+// are needed to apply r, along with the runtime spec fields requiring those.
+func requestedControllers(r *cgroups.Resources) map[string]string {
+	ctrls := make(map[string]string)
+	if r == nil {
+		return ctrls
+	}
+	if r.Memory != 0 || r.MemoryReservation != 0 || r.MemorySwap != 0 ||
+		r.MemorySwappiness != nil || r.OomKillDisable {
+		ctrls["memory"] = "linux.resources.memory"
+	}
+	if r.CpuShares != 0 || r.CpuQuota != 0 || r.CpuPeriod != 0 || r.CpuBurst != nil ||
+		r.CpuRtRuntime != 0 || r.CpuRtPeriod != 0 || r.CpuWeight != 0 || r.CPUIdle != nil {
+		ctrls["cpu"] = "linux.resources.cpu"
+	}
+	if r.CpusetCpus != "" || r.CpusetMems != "" {
+		ctrls["cpuset"] = "linux.resources.cpu.cpus"
+	}
+	if r.PidsLimit != 0 {
+		ctrls["pids"] = "linux.resources.pids"
+	}
+	if r.BlkioWeight != 0 || r.BlkioLeafWeight != 0 || len(r.BlkioWeightDevice) > 0 ||
+		len(r.BlkioThrottleReadBpsDevice) > 0 || len(r.BlkioThrottleWriteBpsDevice) > 0 ||
+		len(r.BlkioThrottleReadIOPSDevice) > 0 || len(r.BlkioThrottleWriteIOPSDevice) > 0 {
+		ctrls["blkio"] = "linux.resources.blockIO"
+	}
+	if len(r.HugetlbLimit) > 0 {
+		ctrls["hugetlb"] = "linux.resources.hugepageLimits"
+	}
+	if r.NetClsClassid != 0 {
+		ctrls["net_cls"] = "linux.resources.network.classID"
+	}
+	if len(r.NetPrioIfpriomap) > 0 {
+		ctrls["net_prio"] = "linux.resources.network.priorities"
+	}
+	if len(r.Rdma) > 0 {
+		ctrls["rdma"] = "linux.resources.rdma"
+	}
+	return ctrls
+}
+
+// checkStrictSpec returns an error enumerating the settings of config which
+// are not in force for the container, if config.StrictSpec is set. skipped
+// is the list returned by [skippedCgroupResources]. It must be called once
+// the cgroup configuration is applied by m.
+func checkStrictSpec(config *configs.Config, m cgroups.Manager, skipped []string) error {
+	if !config.StrictSpec {
+		return nil
+	}
+	var ignored []string
+	var requested map[string]string
+	if config.Cgroups != nil {
+		requested = requestedControllers(config.Cgroups.Resources)
+	}
+	if cgroups.IsCgroup2UnifiedMode() {
+		if slices.Contains(skipped, "cgroup") {
+			for _, path := range requested {
+				ignored = append(ignored, path+": the container cgroup could not be created")
+			}
+		}
+	} else {
+		paths := m.GetPaths()
+		for ctrl, path := range requested {
+			if paths[ctrl] == "" {
+				ignored = append(ignored, path+": the "+ctrl+" cgroup controller is not available")
+			}
+		}
+	}
+	if slices.Contains(skipped, "devices") {
+		ignored = append(ignored, "linux.resources.devices: the device rules can not be applied without privileges")
+	}
+	if c := config.IOCost; c != nil && len(c.QoS)+len(c.Model) > 0 {
+		if _, err := os.Stat(filepath.Join(ioCostRoot, configs.IOCostQoSFile)); errors.Is(err, os.ErrNotExist) {
+			ignored = append(ignored, "annotations: io.cost is not supported by the kernel")
+		}
+	}
+	if len(ignored) == 0 {
+		return nil
+	}
+	slices.Sort(ignored)
+	return fmt.Errorf("strict spec: %d field(s) can not be honored: %s", len(ignored), strings.Join(ignored, "; "))
+}
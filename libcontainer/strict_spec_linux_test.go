@@ -0,0 +1,43 @@
+package libcontainer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestCheckStrictSpec(t *testing.T) {
+	config := &configs.Config{
+		Cgroups: &cgroups.Cgroup{Resources: &cgroups.Resources{
+			Memory:    1 << 30,
+			PidsLimit: 100,
+		}},
+	}
+	m := &mockCgroupManager{paths: map[string]string{"memory": "/sys/fs/cgroup/memory/test"}}
+	var skipped []string
+	if cgroups.IsCgroup2UnifiedMode() {
+		skipped = []string{"cgroup"}
+	}
+	if err := checkStrictSpec(config, m, skipped); err != nil {
+		t.Fatalf("expected no error unless in the strict mode, got %v", err)
+	}
+
+	config.StrictSpec = true
+	err := checkStrictSpec(config, m, skipped)
+	if err == nil {
+		t.Fatal("expected an error in the strict mode")
+	}
+	if !strings.Contains(err.Error(), "linux.resources.pids") {
+		t.Errorf("expected the error to mention linux.resources.pids, got %v", err)
+	}
+
+	if !cgroups.IsCgroup2UnifiedMode() {
+		m.paths["pids"] = "/sys/fs/cgroup/pids/test"
+		if err := checkStrictSpec(config, m, nil); err != nil {
+			t.Fatalf("expected no error with all the controllers available, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package libcontainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/fs"
+)
+
+// subCgroupPath returns the path of the sub-cgroup sub of the container
+// cgroup base. As in the container cgroup namespace, whose root is the
+// container cgroup, sub is relative to base whether it is absolute or not
+// (so a path read from /proc/self/cgroup in the container can be used as
+// is), and ".." can not go past base.
+func subCgroupPath(base, sub string) string {
+	return filepath.Join(base, filepath.Clean("/"+sub))
+}
+
+// createSubCgroup creates the sub-cgroup dir of the container cgroup base
+// of the cgroup v1 controller ctrl (or of the unified hierarchy), along with
+// its missing parents. Those inherit the cpuset settings (on cgroup v1) or
+// the controllers (on cgroup v2) of their parent, so the process to run in
+// dir can be subject to its own limits.
+func createSubCgroup(base, dir, ctrl string) error {
+	if dir == base {
+		return nil
+	}
+	if ctrl == "cpuset" {
+		return (&fs.CpusetGroup{}).ApplyDir(dir, &cgroups.Resources{}, -1)
+	}
+	if !cgroups.IsCgroup2UnifiedMode() {
+		return os.MkdirAll(dir, 0o755)
+	}
+	rel, err := filepath.Rel(base, dir)
+	if err != nil {
+		return err
+	}
+	elems := strings.Split(rel, "/")
+	cur := base
+	for i, elem := range elems {
+		cur = filepath.Join(cur, elem)
+		if err := os.Mkdir(cur, 0o755); err != nil {
+			if os.IsExist(err) {
+				continue
+			}
+			return err
+		}
+		// Enable all the available controllers for the children of the
+		// new intermediate cgroups, but not of dir itself, as a cgroup
+		// with controllers enabled for its children can't have processes.
+		if i == len(elems)-1 {
+			break
+		}
+		available, err := cgroups.ReadFile(cur, "cgroup.controllers")
+		if err != nil {
+			return err
+		}
+		var enable []string
+		for _, c := range strings.Fields(available) {
+			enable = append(enable, "+"+c)
+		}
+		if len(enable) == 0 {
+			continue
+		}
+		if err := cgroups.WriteFile(cur, "cgroup.subtree_control", strings.Join(enable, " ")); err != nil {
+			return fmt.Errorf("unable to enable controllers of %s: %w", cur, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/cgroups"
+)
+
+func TestSubCgroupPath(t *testing.T) {
+	const base = "/sys/fs/cgroup/memory/ct1"
+	for _, tc := range []struct {
+		sub, want string
+	}{
+		{sub: "", want: base},
+		{sub: "/", want: base},
+		{sub: "workers", want: base + "/workers"},
+		{sub: "/workers/a", want: base + "/workers/a"},
+		{sub: "workers/../b", want: base + "/b"},
+		{sub: "..", want: base},
+		{sub: "../ct2", want: base + "/ct2"},
+		{sub: "/../../ct1x", want: base + "/ct1x"},
+	} {
+		if got := subCgroupPath(base, tc.sub); got != tc.want {
+			t.Errorf("subCgroupPath(%q): got %q, want %q", tc.sub, got, tc.want)
+		}
+	}
+}
+
+func TestCreateSubCgroup(t *testing.T) {
+	if cgroups.IsCgroup2UnifiedMode() {
+		t.Skip("cgroup v1 test")
+	}
+	base := t.TempDir()
+	dir := subCgroupPath(base, "/a/b")
+	if err := createSubCgroup(base, dir, "memory"); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Fatalf("%s is not created: %v", dir, err)
+	}
+	// An existing sub-cgroup is fine.
+	if err := createSubCgroup(base, filepath.Dir(dir), "memory"); err != nil {
+		t.Fatal(err)
+	}
+	if err := createSubCgroup(base, base, "memory"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+const (
+	zramControl = "/sys/class/zram-control"
+
+	// Offsets in the swap header, see union swap_header in
+	// include/linux/swap.h.
+	swapHeaderVersionOff  = 1024
+	swapHeaderLastPageOff = 1028
+	swapHeaderMagic       = "SWAPSPACE2"
+)
+
+// setupSwap provisions and enables the swap area configured for the
+// container, and records the swap device in the container.
+func (c *Container) setupSwap() (retErr error) {
+	swap := c.config.Swap
+	if swap == nil {
+		return nil
+	}
+	var dev string
+	switch swap.Type {
+	case configs.SwapFile:
+		if err := createSwapFile(swap.Path, swap.Size); err != nil {
+			return err
+		}
+		dev = swap.Path
+	case configs.SwapZram:
+		var err error
+		if dev, err = createZram(swap.Size); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown swap type %q", swap.Type)
+	}
+	c.swapDevice = dev
+	defer func() {
+		if retErr != nil {
+			c.teardownSwap()
+		}
+	}()
+
+	if err := mkswap(dev, swap.Size); err != nil {
+		return err
+	}
+	return swapon(dev)
+}
+
+// teardownSwap disables and removes the container's swap area, if any.
+func (c *Container) teardownSwap() {
+	dev := c.swapDevice
+	if dev == "" || c.config.Swap == nil {
+		return
+	}
+	if err := swapoff(dev); err != nil && !errors.Is(err, unix.EINVAL) {
+		// EINVAL means the swap area is not active.
+		logrus.Warnf("unable to disable swap on %s: %v", dev, err)
+	}
+	switch c.config.Swap.Type {
+	case configs.SwapFile:
+		if err := os.Remove(dev); err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("unable to remove swap file: %v", err)
+		}
+	case configs.SwapZram:
+		if err := removeZram(dev); err != nil {
+			logrus.Warnf("unable to remove zram device %s: %v", dev, err)
+		}
+	}
+	c.swapDevice = ""
+}
+
+func createSwapFile(path string, size int64) (retErr error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		if retErr != nil {
+			os.Remove(path)
+		}
+	}()
+	// A swap file must not have holes, so allocate all the blocks.
+	if err := unix.Fallocate(int(f.Fd()), 0, 0, size); err != nil {
+		return &os.PathError{Op: "fallocate", Path: path, Err: err}
+	}
+	return nil
+}
+
+// createZram allocates a new zram device of the given size, and returns its
+// device node path.
+func createZram(size int64) (_ string, retErr error) {
+	id, err := os.ReadFile(zramControl + "/hot_add")
+	if err != nil {
+		return "", fmt.Errorf("unable to allocate zram device: %w", err)
+	}
+	dev := "/dev/zram" + strings.TrimSpace(string(id))
+	defer func() {
+		if retErr != nil {
+			_ = removeZram(dev)
+		}
+	}()
+	sysfs := "/sys/block/" + strings.TrimPrefix(dev, "/dev/")
+	if err := os.WriteFile(sysfs+"/disksize", []byte(strconv.FormatInt(size, 10)), 0); err != nil {
+		return "", fmt.Errorf("unable to set zram device size: %w", err)
+	}
+	return dev, nil
+}
+
+func removeZram(dev string) error {
+	name := strings.TrimPrefix(dev, "/dev/")
+	id, ok := strings.CutPrefix(name, "zram")
+	if !ok {
+		return fmt.Errorf("%s is not a zram device", dev)
+	}
+	if err := os.WriteFile("/sys/block/"+name+"/reset", []byte("1"), 0); err != nil {
+		return err
+	}
+	return os.WriteFile(zramControl+"/hot_remove", []byte(id), 0)
+}
+
+// mkswap writes a swap area header to dev, same as mkswap(8) does.
+func mkswap(dev string, size int64) error {
+	pageSize := int64(os.Getpagesize())
+	pages := size / pageSize
+	if pages < 10 {
+		return fmt.Errorf("swap area of %d bytes is too small", size)
+	}
+	header := make([]byte, pageSize)
+	binary.NativeEndian.PutUint32(header[swapHeaderVersionOff:], 1)
+	binary.NativeEndian.PutUint32(header[swapHeaderLastPageOff:], uint32(pages-1))
+	copy(header[pageSize-int64(len(swapHeaderMagic)):], swapHeaderMagic)
+
+	f, err := os.OpenFile(dev, os.O_WRONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteAt(header, 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func swapon(dev string) error {
+	p, err := unix.BytePtrFromString(dev)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_SWAPON, uintptr(unsafe.Pointer(p)), 0, 0); errno != 0 {
+		return &os.PathError{Op: "swapon", Path: dev, Err: errno}
+	}
+	return nil
+}
+
+func swapoff(dev string) error {
+	p, err := unix.BytePtrFromString(dev)
+	if err != nil {
+		return err
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_SWAPOFF, uintptr(unsafe.Pointer(p)), 0, 0); errno != 0 {
+		return &os.PathError{Op: "swapoff", Path: dev, Err: errno}
+	}
+	return nil
+}
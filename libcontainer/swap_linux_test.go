@@ -0,0 +1,52 @@
+package libcontainer
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMkswap(t *testing.T) {
+	pageSize := os.Getpagesize()
+	size := int64(pageSize * 16)
+	path := filepath.Join(t.TempDir(), "swapfile")
+	if err := createSwapFile(path, size); err != nil {
+		t.Fatal(err)
+	}
+	if err := mkswap(path, size); err != nil {
+		t.Fatal(err)
+	}
+
+	header, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(header)) != size {
+		t.Fatalf("expected swap file size %d, got %d", size, len(header))
+	}
+	if magic := string(header[pageSize-len(swapHeaderMagic) : pageSize]); magic != swapHeaderMagic {
+		t.Errorf("expected magic %q, got %q", swapHeaderMagic, magic)
+	}
+	if v := binary.NativeEndian.Uint32(header[swapHeaderVersionOff:]); v != 1 {
+		t.Errorf("expected version 1, got %d", v)
+	}
+	if last := binary.NativeEndian.Uint32(header[swapHeaderLastPageOff:]); last != 15 {
+		t.Errorf("expected last page 15, got %d", last)
+	}
+
+	// The file must not be overwritten.
+	if err := createSwapFile(path, size); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestMkswapTooSmall(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swapfile")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mkswap(path, int64(os.Getpagesize())); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,45 @@
+package libcontainer
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// isSysfsReadonlyLater tells whether m is a read-only /sys mount with
+// writable paths. Such a mount is mounted read-write, so that the writable
+// paths can be bind mounted read-write, and is only remounted read-only by
+// finalizeRootfs.
+func isSysfsReadonlyLater(config *configs.Config, m *configs.Mount) bool {
+	return len(config.SysfsWritable) > 0 && m.Flags&unix.MS_RDONLY != 0 &&
+		utils.CleanPath(m.Destination) == "/sys"
+}
+
+// setupSysfsWritable bind mounts each writable /sys path onto itself,
+// together with the mounts under it (such as the cgroup v1 hierarchies under
+// /sys/fs/cgroup), and makes them all read-write.
+func setupSysfsWritable(config *configs.Config) error {
+	for _, path := range config.SysfsWritable {
+		if err := utils.WithProcfd(config.Rootfs, path, func(procfd string) error {
+			return mountViaFds(procfd, nil, path, procfd, "", unix.MS_BIND|unix.MS_REC, "")
+		}); err != nil {
+			return err
+		}
+		// The previous mount invalidates the procfd, which must be re-opened.
+		if err := utils.WithProcfd(config.Rootfs, path, func(procfd string) error {
+			err := unix.MountSetattr(-1, procfd, unix.AT_RECURSIVE, &unix.MountAttr{
+				Attr_clr: unix.MOUNT_ATTR_RDONLY,
+			})
+			if err != nil {
+				return &os.PathError{Op: "mount_setattr", Path: path, Err: err}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
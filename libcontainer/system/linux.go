@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
 	"unsafe"
 
 	"github.com/sirupsen/logrus"
@@ -160,3 +161,55 @@ func SetLinuxPersonality(personality int) error {
 	}
 	return nil
 }
+
+// CoreSchedCookie returns the core scheduling cookie of the thread tid (or
+// the calling thread, if 0), which is 0 if it has none. The error is EINVAL
+// if the kernel does not support core scheduling, and ENODEV if SMT is not
+// present on the host.
+func CoreSchedCookie(tid int) (uint64, error) {
+	var cookie uint64
+	err := unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_GET, uintptr(tid),
+		unix.PR_SCHED_CORE_SCOPE_THREAD, uintptr(unsafe.Pointer(&cookie)))
+	if err != nil {
+		return 0, os.NewSyscallError("prctl PR_SCHED_CORE_GET", err)
+	}
+	return cookie, nil
+}
+
+// CreateCoreSchedCookie gives all the threads of the process pid a new core
+// scheduling cookie, which its children inherit. The tasks with different
+// cookies never run on the SMT siblings of the same core at the same time.
+func CreateCoreSchedCookie(pid int) error {
+	err := unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_CREATE, uintptr(pid),
+		unix.PR_SCHED_CORE_SCOPE_THREAD_GROUP, 0)
+	if err != nil {
+		return os.NewSyscallError("prctl PR_SCHED_CORE_CREATE", err)
+	}
+	return nil
+}
+
+// ShareCoreSchedCookie gives all the threads of the process pid the core
+// scheduling cookie of the thread from.
+func ShareCoreSchedCookie(from, pid int) error {
+	errCh := make(chan error)
+	// Use a goroutine to dedicate an OS thread, as the cookie is passed
+	// through the calling thread.
+	go func() {
+		runtime.LockOSThread()
+		err := unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_SHARE_FROM, uintptr(from),
+			unix.PR_SCHED_CORE_SCOPE_THREAD, 0)
+		if err != nil {
+			errCh <- os.NewSyscallError("prctl PR_SCHED_CORE_SHARE_FROM", err)
+			return
+		}
+		err = unix.Prctl(unix.PR_SCHED_CORE, unix.PR_SCHED_CORE_SHARE_TO, uintptr(pid),
+			unix.PR_SCHED_CORE_SCOPE_THREAD_GROUP, 0)
+		if err != nil {
+			err = os.NewSyscallError("prctl PR_SCHED_CORE_SHARE_TO", err)
+		}
+		errCh <- err
+		// Deliberately omit runtime.UnlockOSThread here, so that the
+		// thread, which has the cookie, is terminated.
+	}()
+	return <-errCh
+}
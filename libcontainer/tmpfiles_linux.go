@@ -0,0 +1,168 @@
+package libcontainer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+// setupTmpfiles provisions the tmpfiles entries of the container in its
+// rootfs, in order.
+func setupTmpfiles(config *configs.Config) error {
+	for _, t := range config.Tmpfiles {
+		if err := setupTmpfile(config.Rootfs, t); err != nil {
+			return fmt.Errorf("tmpfiles entry %s %s: %w", t.Type, t.Path, err)
+		}
+	}
+	return nil
+}
+
+func setupTmpfile(rootfs string, t *configs.Tmpfile) error {
+	// The entry itself is not resolved, as it may be a symlink, so all
+	// the operations are done relative to its parent directory.
+	var (
+		dir *os.File
+		err error
+	)
+	if t.Type == configs.TmpfileAdjust {
+		dir, err = securejoin.OpenInRoot(rootfs, filepath.Dir(t.Path))
+	} else {
+		dir, err = utils.MkdirAllInRootOpen(rootfs, filepath.Dir(t.Path), 0o755)
+	}
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	dirFd := int(dir.Fd())
+	name := filepath.Base(t.Path)
+
+	mode := uint32(0o644)
+	if t.Type == configs.TmpfileDir {
+		mode = 0o755
+	}
+	if t.Mode != nil {
+		mode = *t.Mode
+	}
+	switch t.Type {
+	case configs.TmpfileDir:
+		err = unix.Mkdirat(dirFd, name, 0o700)
+	case configs.TmpfileFile:
+		err = createTmpfile(dirFd, name, t.Argument)
+	case configs.TmpfileSymlink:
+		err = unix.Symlinkat(t.Argument, dirFd, name)
+	case configs.TmpfileCharDevice, configs.TmpfileBlockDevice:
+		var major, minor uint32
+		major, minor, err = t.DeviceNumber()
+		if err != nil {
+			return err
+		}
+		typ := uint32(unix.S_IFCHR)
+		if t.Type == configs.TmpfileBlockDevice {
+			typ = unix.S_IFBLK
+		}
+		err = unix.Mknodat(dirFd, name, typ|0o600, int(unix.Mkdev(major, minor)))
+	case configs.TmpfileAdjust:
+		err = unix.EEXIST
+	default:
+		return fmt.Errorf("unknown type %q", t.Type)
+	}
+	created := err == nil
+	if err != nil && !errors.Is(err, unix.EEXIST) {
+		return &os.PathError{Op: "create", Path: t.Path, Err: err}
+	}
+	if !created {
+		switch t.Type {
+		case configs.TmpfileSymlink:
+			// An existing symlink is left as is.
+			return nil
+		case configs.TmpfileAdjust:
+		default:
+			if err := checkTmpfileType(dirFd, name, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	if created || t.UID != nil || t.GID != nil {
+		uid, gid := -1, -1
+		if t.UID != nil {
+			uid = int(*t.UID)
+		}
+		if t.GID != nil {
+			gid = int(*t.GID)
+		}
+		if err := unix.Fchownat(dirFd, name, uid, gid, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			return &os.PathError{Op: "chown", Path: t.Path, Err: err}
+		}
+	}
+	if t.Type == configs.TmpfileSymlink || (!created && t.Mode == nil) {
+		return nil
+	}
+	return chmodTmpfile(dirFd, name, t, mode)
+}
+
+// tmpfileTypes are the file types of the tmpfiles entry types.
+var tmpfileTypes = map[configs.TmpfileType]uint32{
+	configs.TmpfileDir:         unix.S_IFDIR,
+	configs.TmpfileFile:        unix.S_IFREG,
+	configs.TmpfileCharDevice:  unix.S_IFCHR,
+	configs.TmpfileBlockDevice: unix.S_IFBLK,
+}
+
+// checkTmpfileType checks that the existing entry name in the directory
+// dirFd has the file type of the entry t.
+func checkTmpfileType(dirFd int, name string, t *configs.Tmpfile) error {
+	var st unix.Stat_t
+	if err := unix.Fstatat(dirFd, name, &st, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+		return &os.PathError{Op: "stat", Path: t.Path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT != tmpfileTypes[t.Type] {
+		return fmt.Errorf("%s exists, with a different file type (mode %#o)", t.Path, st.Mode)
+	}
+	return nil
+}
+
+// createTmpfile creates the file name in the directory dirFd, with contents.
+// It fails with EEXIST if the file exists.
+func createTmpfile(dirFd int, name, contents string) error {
+	fd, err := unix.Openat(dirFd, name, unix.O_WRONLY|unix.O_CREAT|unix.O_EXCL|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	f := os.NewFile(uintptr(fd), name)
+	defer f.Close()
+	_, err = f.WriteString(contents)
+	return err
+}
+
+// chmodTmpfile sets the mode of the entry name in the directory dirFd,
+// which must not be a symlink (as the mode of a symlink can not be set).
+func chmodTmpfile(dirFd int, name string, t *configs.Tmpfile, mode uint32) error {
+	fd, err := unix.Openat(dirFd, name, unix.O_PATH|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: t.Path, Err: err}
+	}
+	defer unix.Close(fd)
+	var st unix.Stat_t
+	if err := unix.Fstat(fd, &st); err != nil {
+		return &os.PathError{Op: "fstat", Path: t.Path, Err: err}
+	}
+	if st.Mode&unix.S_IFMT == unix.S_IFLNK {
+		return fmt.Errorf("unable to set the mode of %s: it is a symlink", t.Path)
+	}
+	// fchmod(2) does not work on an O_PATH file descriptor.
+	procFd, closer := utils.ProcThreadSelf("fd/" + strconv.Itoa(fd))
+	defer closer()
+	if err := unix.Chmod(procFd, mode); err != nil {
+		return &os.PathError{Op: "chmod", Path: t.Path, Err: err}
+	}
+	return nil
+}
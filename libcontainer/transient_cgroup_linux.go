@@ -0,0 +1,121 @@
+package libcontainer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/opencontainers/cgroups"
+	"github.com/opencontainers/cgroups/manager"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// transientCgroup is a sub-cgroup of the container cgroup which a process
+// is run in, with its own resources, for as long as the process runs. See
+// [Process.SubCgroupResources].
+type transientCgroup struct {
+	// name is the sub-cgroup path, relative to the container cgroups.
+	name      string
+	base      map[string]string
+	resources *cgroups.Resources
+	manager   cgroups.Manager
+}
+
+// newTransientCgroup returns a transient sub-cgroup, with a unique name, of
+// the container cgroups base, to be created with resources.
+func newTransientCgroup(base map[string]string, resources *cgroups.Resources) (*transientCgroup, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+	r := *resources
+	// The device rules are those of the container.
+	r.SkipDevices = true
+	return &transientCgroup{
+		name:      "runc-exec-" + hex.EncodeToString(b[:]),
+		base:      base,
+		resources: &r,
+	}, nil
+}
+
+// create creates the sub-cgroup, at paths, and sets its resources.
+func (t *transientCgroup) create(paths map[string]string) error {
+	if cgroups.IsCgroup2UnifiedMode() {
+		if err := enableSubtreeControllers(t.base[""], t.resources); err != nil {
+			return err
+		}
+	}
+	for ctrl, path := range paths {
+		if err := createSubCgroup(t.base[ctrl], path, ctrl); err != nil {
+			cgroups.RemovePaths(paths) //nolint:errcheck // Best effort.
+			return fmt.Errorf("unable to create transient sub-cgroup %s: %w", path, err)
+		}
+	}
+	m, err := manager.NewWithPaths(&cgroups.Cgroup{Resources: t.resources}, paths)
+	if err == nil {
+		t.manager = m
+		err = m.Set(t.resources)
+	}
+	if err != nil {
+		cgroups.RemovePaths(paths) //nolint:errcheck // Best effort.
+		t.manager = nil
+		return fmt.Errorf("unable to set transient sub-cgroup resources: %w", err)
+	}
+	return nil
+}
+
+// remove removes the sub-cgroup, once its process is gone. It is a no-op if
+// it has been removed already.
+func (t *transientCgroup) remove() {
+	if t.manager == nil {
+		return
+	}
+	if err := t.manager.Destroy(); err != nil {
+		logrus.Warnf("unable to remove transient sub-cgroup %s: %v", t.name, err)
+	}
+	t.manager = nil
+}
+
+// enableSubtreeControllers enables the cgroup v2 controllers needed for
+// resources in the cgroup.subtree_control of the cgroup dir, so that its
+// sub-cgroups can have those set.
+func enableSubtreeControllers(dir string, resources *cgroups.Resources) error {
+	var enable []string
+	if resources.CpuQuota != 0 || resources.CpuPeriod != 0 {
+		enable = append(enable, "+cpu")
+	}
+	if resources.Memory != 0 {
+		enable = append(enable, "+memory")
+	}
+	if len(enable) == 0 {
+		return nil
+	}
+	err := cgroups.WriteFile(dir, "cgroup.subtree_control", strings.Join(enable, " "))
+	if errors.Is(err, unix.EBUSY) {
+		return errors.New("the container cgroup has processes, so its controllers can't be enabled for a transient sub-cgroup (on cgroup v2, the container processes must be in sub-cgroups)")
+	}
+	if err != nil {
+		return fmt.Errorf("unable to enable controllers of %s: %w", dir, err)
+	}
+	return nil
+}
+
+func (p *setnsProcess) wait() (*os.ProcessState, error) {
+	state, err := p.containerProcess.wait()
+	if p.transient != nil {
+		p.transient.remove()
+	}
+	return state, err
+}
+
+func (p *setnsProcess) terminate() error {
+	err := p.containerProcess.terminate()
+	if p.transient != nil {
+		p.transient.remove()
+	}
+	return err
+}
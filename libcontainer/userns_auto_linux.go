@@ -0,0 +1,257 @@
+package libcontainer
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/moby/sys/user"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/system"
+)
+
+var (
+	// usernsAutoFile is the host-wide registry of the ID ranges allocated
+	// by AllocateUsernsRange, which is also locked during the allocations.
+	usernsAutoFile = "/run/runc/userns-auto.json"
+
+	// subUIDFile and subGIDFile are the subordinate ID files which define
+	// the pool of the ID ranges allocated by AllocateUsernsRange.
+	subUIDFile = "/etc/subuid"
+	subGIDFile = "/etc/subgid"
+)
+
+// usernsAllocation is an ID range allocated to a container, in the
+// usernsAutoFile registry.
+type usernsAllocation struct {
+	// StateDir is the state directory of the container.
+	StateDir string `json:"state_dir"`
+	HostID   int64  `json:"host_id"`
+	Size     int64  `json:"size"`
+	// Pid and StartTime identify the process which allocated the range,
+	// so that the range is not released while the container is being
+	// created (before its state directory exists).
+	Pid       int    `json:"pid"`
+	StartTime uint64 `json:"start_time"`
+}
+
+// stale returns whether the range can be released, as both its container
+// and the process which allocated it are gone.
+func (a *usernsAllocation) stale() bool {
+	if _, err := os.Stat(a.StateDir); !errors.Is(err, os.ErrNotExist) {
+		return false
+	}
+	stat, err := system.Stat(a.Pid)
+	return err != nil || stat.StartTime != a.StartTime || stat.State == system.Zombie || stat.State == system.Dead
+}
+
+// idRange is a range of size IDs, starting at start.
+type idRange struct {
+	start, size int64
+}
+
+// AllocateUsernsRange allocates size IDs to the container with the given
+// root and id, from the subordinate ID ranges of the pool user which are
+// in both /etc/subuid and /etc/subgid, so that they do not overlap with the
+// ones allocated to the other containers on the host. It returns the first
+// host ID of the range, the same for the uids and gids.
+//
+// The allocations are recorded in a host-wide registry under /run/runc. The
+// range is released when the container is destroyed (as long as it has
+// [configs.Config.UsernsAuto] set), or by [ReleaseUsernsRange].
+func AllocateUsernsRange(root, id, poolUser string, size int64) (int64, error) {
+	if size <= 0 {
+		return -1, fmt.Errorf("invalid user namespace size %d", size)
+	}
+	stateDir, err := usernsStateDir(root, id)
+	if err != nil {
+		return -1, err
+	}
+	pool, err := usernsPool(poolUser)
+	if err != nil {
+		return -1, err
+	}
+	self, err := system.Stat(os.Getpid())
+	if err != nil {
+		return -1, err
+	}
+	var hostID int64
+	err = withUsernsRegistry(func(allocs []usernsAllocation) ([]usernsAllocation, error) {
+		var used []idRange
+		for _, a := range allocs {
+			if a.StateDir == stateDir {
+				return nil, fmt.Errorf("container %s already has an allocated user namespace range: %w", id, ErrExist)
+			}
+			used = append(used, idRange{a.HostID, a.Size})
+		}
+		start, ok := findFreeIDRange(pool, used, size)
+		if !ok {
+			return nil, fmt.Errorf("no free range of %d IDs left in the subordinate ID ranges of user %s", size, poolUser)
+		}
+		hostID = start
+		return append(allocs, usernsAllocation{
+			StateDir:  stateDir,
+			HostID:    start,
+			Size:      size,
+			Pid:       os.Getpid(),
+			StartTime: self.StartTime,
+		}), nil
+	})
+	if err != nil {
+		return -1, fmt.Errorf("unable to allocate a user namespace range: %w", err)
+	}
+	return hostID, nil
+}
+
+// ReleaseUsernsRange releases the ID range allocated to the container with
+// the given root and id by [AllocateUsernsRange], if any.
+func ReleaseUsernsRange(root, id string) error {
+	stateDir, err := usernsStateDir(root, id)
+	if err != nil {
+		return err
+	}
+	return releaseUsernsRange(stateDir)
+}
+
+// releaseUserns releases the automatically allocated ID range of the
+// container, if any.
+func (c *Container) releaseUserns() {
+	if !c.config.UsernsAuto {
+		return
+	}
+	stateDir, err := filepath.Abs(c.stateDir)
+	if err == nil {
+		err = releaseUsernsRange(stateDir)
+	}
+	if err != nil {
+		logrus.Warn(err)
+	}
+}
+
+func releaseUsernsRange(stateDir string) error {
+	err := withUsernsRegistry(func(allocs []usernsAllocation) ([]usernsAllocation, error) {
+		return slices.DeleteFunc(allocs, func(a usernsAllocation) bool {
+			return a.StateDir == stateDir
+		}), nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to release the user namespace range: %w", err)
+	}
+	return nil
+}
+
+// usernsStateDir returns the absolute state directory of the container, by
+// which its allocation is recorded, as the root may be relative.
+func usernsStateDir(root, id string) (string, error) {
+	if err := validateID(id); err != nil {
+		return "", err
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	return securejoin.SecureJoin(root, id)
+}
+
+// withUsernsRegistry runs fn with the allocations of the registry, which
+// is locked meanwhile, and writes the allocations fn returns, without the
+// stale ones.
+func withUsernsRegistry(fn func([]usernsAllocation) ([]usernsAllocation, error)) error {
+	if err := os.MkdirAll(filepath.Dir(usernsAutoFile), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(usernsAutoFile, os.O_RDWR|os.O_CREATE|unix.O_CLOEXEC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX); err != nil {
+		return &os.PathError{Op: "flock", Path: usernsAutoFile, Err: err}
+	}
+	var allocs []usernsAllocation
+	if err := json.NewDecoder(f).Decode(&allocs); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("unable to read %s: %w", usernsAutoFile, err)
+	}
+	allocs = slices.DeleteFunc(allocs, func(a usernsAllocation) bool { return a.stale() })
+	allocs, err = fn(allocs)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(allocs)
+	if err != nil {
+		return err
+	}
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err = f.WriteAt(data, 0)
+	return err
+}
+
+// usernsPool returns the subordinate ID ranges of the pool user which are
+// in both the subordinate uid and gid files.
+func usernsPool(poolUser string) ([]idRange, error) {
+	var ranges [2][]idRange
+	for i, file := range []string{subUIDFile, subGIDFile} {
+		ids, err := user.ParseSubIDFileFilter(file, func(e user.SubID) bool {
+			return e.Name == poolUser
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			ranges[i] = append(ranges[i], idRange{id.SubID, id.Count})
+		}
+	}
+	pool := intersectIDRanges(ranges[0], ranges[1])
+	if len(pool) == 0 {
+		return nil, fmt.Errorf("user %s has no subordinate ID ranges in both %s and %s", poolUser, subUIDFile, subGIDFile)
+	}
+	return pool, nil
+}
+
+// intersectIDRanges returns the ranges of the IDs which are in both a and b.
+func intersectIDRanges(a, b []idRange) []idRange {
+	var ret []idRange
+	for _, x := range a {
+		for _, y := range b {
+			start, end := max(x.start, y.start), min(x.start+x.size, y.start+y.size)
+			if start < end {
+				ret = append(ret, idRange{start, end - start})
+			}
+		}
+	}
+	slices.SortFunc(ret, func(x, y idRange) int { return cmp.Compare(x.start, y.start) })
+	return ret
+}
+
+// findFreeIDRange returns the start of the first range of size IDs in the
+// pool which does not overlap with the used ones.
+func findFreeIDRange(pool, used []idRange, size int64) (int64, bool) {
+	used = slices.Clone(used)
+	slices.SortFunc(used, func(x, y idRange) int { return cmp.Compare(x.start, y.start) })
+	for _, p := range pool {
+		start := p.start
+		for _, u := range used {
+			if u.start+u.size <= start {
+				continue
+			}
+			if u.start >= start+size {
+				break
+			}
+			start = u.start + u.size
+		}
+		if start+size <= p.start+p.size {
+			return start, true
+		}
+	}
+	return 0, false
+}
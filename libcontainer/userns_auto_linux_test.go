@@ -0,0 +1,89 @@
+package libcontainer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindFreeIDRange(t *testing.T) {
+	pool := []idRange{{100000, 30000}, {200000, 65536}}
+	testCases := []struct {
+		used     []idRange
+		size     int64
+		expected int64
+		ok       bool
+	}{
+		{size: 10000, expected: 100000, ok: true},
+		{used: []idRange{{100000, 10000}}, size: 10000, expected: 110000, ok: true},
+		{used: []idRange{{110000, 10000}, {100000, 5000}}, size: 10000, expected: 120000, ok: true},
+		{used: []idRange{{100000, 10000}}, size: 25000, expected: 200000, ok: true},
+		{size: 65536, expected: 200000, ok: true},
+		{used: []idRange{{200000, 1}}, size: 65536},
+	}
+	for _, tc := range testCases {
+		start, ok := findFreeIDRange(pool, tc.used, tc.size)
+		if start != tc.expected || ok != tc.ok {
+			t.Errorf("%+v, size %d: expected %d, %v, got %d, %v", tc.used, tc.size, tc.expected, tc.ok, start, ok)
+		}
+	}
+}
+
+func TestIntersectIDRanges(t *testing.T) {
+	a := []idRange{{200000, 65536}, {100000, 65536}}
+	b := []idRange{{100000, 1000}, {150000, 100000}}
+	expected := []idRange{{100000, 1000}, {150000, 15536}, {200000, 50000}}
+	if r := intersectIDRanges(a, b); !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %+v, got %+v", expected, r)
+	}
+}
+
+func TestAllocateUsernsRange(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []struct {
+		file *string
+		name string
+	}{
+		{&usernsAutoFile, "run/userns-auto.json"},
+		{&subUIDFile, "subuid"},
+		{&subGIDFile, "subgid"},
+	} {
+		old := *v.file
+		*v.file = filepath.Join(dir, v.name)
+		t.Cleanup(func() { *v.file = old })
+	}
+	for _, f := range []string{subUIDFile, subGIDFile} {
+		if err := os.WriteFile(f, []byte("runc:100000:20000\nuser:300000:65536\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.Join(dir, "root")
+
+	first, err := AllocateUsernsRange(root, "a", "runc", 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := AllocateUsernsRange(root, "b", "runc", 10000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 100000 || second != 110000 {
+		t.Fatalf("expected the ranges at 100000 and 110000, got %d and %d", first, second)
+	}
+	if _, err := AllocateUsernsRange(root, "a", "runc", 10000); err == nil {
+		t.Fatal("expected an error for a container which has a range already")
+	}
+	if _, err := AllocateUsernsRange(root, "c", "runc", 10000); err == nil {
+		t.Fatal("expected an error once the pool is exhausted")
+	}
+	if err := ReleaseUsernsRange(root, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := AllocateUsernsRange(root, "c", "runc", 10000); err != nil || id != first {
+		t.Fatalf("expected the released range at %d, got %d (%v)", first, id, err)
+	}
+	if _, err := AllocateUsernsRange(root, "d", "nobody", 1); err == nil {
+		t.Fatal("expected an error for a user without subordinate IDs")
+	}
+}
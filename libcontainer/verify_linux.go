@@ -0,0 +1,398 @@
+package libcontainer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/moby/sys/mountinfo"
+	"github.com/opencontainers/cgroups"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer/capabilities"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
+)
+
+// Drift is a difference between the container configuration and the actual
+// state of the container.
+type Drift struct {
+	// Kind is the kind of state: "mount", "cgroup", "sysctl", "seccomp", or
+	// "capabilities".
+	Kind string `json:"kind"`
+	// Name identifies the state of its kind: the mount destination, the
+	// cgroup file, the sysctl, or the capability set.
+	Name     string `json:"name"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Verify compares the container configuration against the actual state of
+// the running container init process, and returns the differences. It
+// checks that:
+//   - the mounts are mounted, and read-only if configured so;
+//   - the cgroup memory, pids, cpu, and cpuset limits are set;
+//   - the sysctls have their configured values;
+//   - a seccomp filter is installed, if configured;
+//   - the capability bounding set is the configured one, and the other sets
+//     have no capabilities beyond the configured ones (as they are changed
+//     by execve(2), and can be reduced by the process itself).
+func (c *Container) Verify() ([]Drift, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	status, err := c.currentStatus()
+	if err != nil {
+		return nil, err
+	}
+	if status != Running && status != Paused {
+		return nil, ErrNotRunning
+	}
+	pid := c.initProcess.pid()
+
+	var drifts []Drift
+	for _, verify := range []func(int) ([]Drift, error){
+		c.verifyMounts,
+		c.verifyCgroup,
+		c.verifySysctls,
+		c.verifySeccomp,
+		c.verifyCapabilities,
+	} {
+		d, err := verify(pid)
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, d...)
+	}
+	return drifts, nil
+}
+
+func (c *Container) verifyMounts(pid int) ([]Drift, error) {
+	// The mount points are relative to the root of the process.
+	infos, err := mountinfo.PidMountInfo(pid)
+	if err != nil {
+		return nil, err
+	}
+	// The last mount on a mount point is the visible one.
+	mounts := make(map[string]*mountinfo.Info, len(infos))
+	for _, info := range infos {
+		mounts[info.Mountpoint] = info
+	}
+
+	var drifts []Drift
+	root := "/proc/" + strconv.Itoa(pid) + "/root"
+	check := func(dest string, readonly bool) {
+		info, ok := mounts[dest]
+		if !ok {
+			// The destination may contain symlinks, which runc has
+			// resolved in the container root filesystem.
+			if resolved, err := securejoin.SecureJoin(root, dest); err == nil {
+				info, ok = mounts[path.Join("/", strings.TrimPrefix(resolved, root))]
+			}
+		}
+		if !ok {
+			drifts = append(drifts, Drift{Kind: "mount", Name: dest, Expected: "mounted", Actual: "not mounted"})
+			return
+		}
+		if readonly && !slices.Contains(strings.Split(info.Options, ","), "ro") {
+			drifts = append(drifts, Drift{Kind: "mount", Name: dest, Expected: "ro", Actual: info.Options})
+		}
+	}
+	check("/", c.config.Readonlyfs)
+	for _, m := range c.config.Mounts {
+		readonly := m.Flags&unix.MS_RDONLY != 0
+		if m.Device == "cgroup" && !cgroups.IsCgroup2UnifiedMode() {
+			// The cgroup v1 controllers are mounted read-only on a tmpfs
+			// which is not.
+			readonly = false
+		}
+		check(path.Join("/", m.Destination), readonly)
+	}
+	return drifts, nil
+}
+
+// cgroupCheck is a cgroup file to verify.
+type cgroupCheck struct {
+	controller, file string
+	expected         string
+	// equal compares the expected and actual values. If nil, they are
+	// compared as strings.
+	equal func(expected, actual string) bool
+}
+
+func (c *Container) verifyCgroup(_ int) ([]Drift, error) {
+	if c.config.Cgroups == nil || c.config.Cgroups.Resources == nil {
+		return nil, nil
+	}
+	r := c.config.Cgroups.Resources
+	var checks []cgroupCheck
+	add := func(controller, file, expected string, equal func(string, string) bool) {
+		checks = append(checks, cgroupCheck{controller: controller, file: file, expected: expected, equal: equal})
+	}
+	v2 := cgroups.IsCgroup2UnifiedMode()
+	if r.Memory > 0 {
+		// The kernel rounds the limit down to the page size.
+		limit := strconv.FormatInt(r.Memory/int64(os.Getpagesize())*int64(os.Getpagesize()), 10)
+		if v2 {
+			add("", "memory.max", limit, nil)
+		} else {
+			add("memory", "memory.limit_in_bytes", limit, nil)
+		}
+	}
+	if r.PidsLimit > 0 {
+		add("pids", "pids.max", strconv.FormatInt(r.PidsLimit, 10), nil)
+	}
+	if v2 {
+		if r.CpuQuota != 0 || r.CpuPeriod != 0 {
+			quota := "max"
+			if r.CpuQuota > 0 {
+				quota = strconv.FormatInt(r.CpuQuota, 10)
+			}
+			// An unset quota or period is left as is.
+			add("", "cpu.max", quota+" "+strconv.FormatUint(r.CpuPeriod, 10), func(expected, actual string) bool {
+				e, a := strings.Fields(expected), strings.Fields(actual)
+				return len(a) == 2 && (r.CpuQuota == 0 || e[0] == a[0]) && (r.CpuPeriod == 0 || e[1] == a[1])
+			})
+		}
+		weight := r.CpuWeight
+		if weight == 0 {
+			weight = cgroups.ConvertCPUSharesToCgroupV2Value(r.CpuShares)
+		}
+		if weight != 0 {
+			add("", "cpu.weight", strconv.FormatUint(weight, 10), nil)
+		}
+	} else {
+		if r.CpuQuota != 0 {
+			add("cpu", "cpu.cfs_quota_us", strconv.FormatInt(r.CpuQuota, 10), nil)
+		}
+		if r.CpuPeriod != 0 {
+			add("cpu", "cpu.cfs_period_us", strconv.FormatUint(r.CpuPeriod, 10), nil)
+		}
+		if r.CpuShares != 0 {
+			add("cpu", "cpu.shares", strconv.FormatUint(r.CpuShares, 10), nil)
+		}
+	}
+	if r.CpusetCpus != "" {
+		add("cpuset", "cpuset.cpus", r.CpusetCpus, cpusetEqual)
+	}
+	if r.CpusetMems != "" {
+		add("cpuset", "cpuset.mems", r.CpusetMems, cpusetEqual)
+	}
+
+	var drifts []Drift
+	for _, check := range checks {
+		if v2 {
+			check.controller = ""
+		}
+		dir := c.cgroupManager.Path(check.controller)
+		if dir == "" {
+			continue
+		}
+		actual, err := cgroups.ReadFile(dir, check.file)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// The controller is not available.
+				continue
+			}
+			return nil, err
+		}
+		actual = strings.TrimSpace(actual)
+		equal := check.expected == actual
+		if check.equal != nil {
+			equal = check.equal(check.expected, actual)
+		}
+		if !equal {
+			drifts = append(drifts, Drift{Kind: "cgroup", Name: check.file, Expected: check.expected, Actual: actual})
+		}
+	}
+	return drifts, nil
+}
+
+func cpusetEqual(expected, actual string) bool {
+	e, err1 := cpuset.Parse(expected)
+	a, err2 := cpuset.Parse(actual)
+	return err1 == nil && err2 == nil && slices.Equal(e, a)
+}
+
+func (c *Container) verifySysctls(pid int) ([]Drift, error) {
+	sysctls := make(map[string]string, len(c.config.Sysctl)+len(c.config.NetSysctl))
+	for k, v := range c.config.NetSysctl {
+		sysctls[k] = v
+	}
+	for k, v := range c.config.Sysctl {
+		sysctls[k] = v
+	}
+	if len(sysctls) == 0 {
+		return nil, nil
+	}
+
+	type result struct {
+		drifts []Drift
+		err    error
+	}
+	resultCh := make(chan result)
+	go func() {
+		// The namespaced sysctls are read in the namespaces of the reading
+		// thread, so this one joins the container namespaces. It is not
+		// unlocked, so that the Go runtime terminates it once this
+		// goroutine returns.
+		runtime.LockOSThread()
+		var r result
+		for _, ns := range []struct {
+			name string
+			flag int
+		}{{"net", unix.CLONE_NEWNET}, {"ipc", unix.CLONE_NEWIPC}, {"uts", unix.CLONE_NEWUTS}} {
+			if r.err = setns(pid, ns.name, ns.flag); r.err != nil {
+				resultCh <- r
+				return
+			}
+		}
+		keys := make([]string, 0, len(sysctls))
+		for k := range sysctls {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			data, err := os.ReadFile(path.Join("/proc/sys", strings.ReplaceAll(k, ".", "/")))
+			if err != nil {
+				r.err = err
+				break
+			}
+			// Multiple values are separated by tabs, or by spaces.
+			expected := strings.Join(strings.Fields(sysctls[k]), " ")
+			if actual := strings.Join(strings.Fields(string(data)), " "); actual != expected {
+				r.drifts = append(r.drifts, Drift{Kind: "sysctl", Name: k, Expected: expected, Actual: actual})
+			}
+		}
+		resultCh <- r
+	}()
+	r := <-resultCh
+	if errors.Is(r.err, unix.EPERM) {
+		// As when runc is rootless, and can't join the namespaces.
+		logrus.Warnf("unable to verify the sysctls: %v", r.err)
+		return nil, nil
+	}
+	return r.drifts, r.err
+}
+
+// setns makes the current thread join the namespace of type name of the
+// process pid.
+func setns(pid int, name string, flag int) error {
+	fd, err := unix.Open("/proc/"+strconv.Itoa(pid)+"/ns/"+name, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return &os.PathError{Op: "open", Path: "/proc/" + strconv.Itoa(pid) + "/ns/" + name, Err: err}
+	}
+	defer unix.Close(fd)
+	if err := unix.Setns(fd, flag); err != nil {
+		return fmt.Errorf("join %s namespace: %w", name, os.NewSyscallError("setns", err))
+	}
+	return nil
+}
+
+func (c *Container) verifySeccomp(pid int) ([]Drift, error) {
+	if c.config.Seccomp == nil {
+		return nil, nil
+	}
+	status, err := readProcStatus(pid, "Seccomp")
+	if err != nil {
+		return nil, err
+	}
+	// 2 is SECCOMP_MODE_FILTER.
+	if status["Seccomp"] != "2" {
+		return []Drift{{Kind: "seccomp", Name: "mode", Expected: "filter", Actual: "mode " + status["Seccomp"]}}, nil
+	}
+	return nil, nil
+}
+
+func (c *Container) verifyCapabilities(pid int) ([]Drift, error) {
+	caps := c.config.Capabilities
+	if caps == nil {
+		return nil, nil
+	}
+	status, err := readProcStatus(pid, "CapBnd", "CapEff", "CapPrm", "CapInh", "CapAmb")
+	if err != nil {
+		return nil, err
+	}
+	actual := make(map[string]uint64, len(status))
+	for k, v := range status {
+		if actual[k], err = strconv.ParseUint(v, 16, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s in /proc/%d/status: %w", k, pid, err)
+		}
+	}
+	var allowed uint64
+	for _, set := range [][]string{caps.Bounding, caps.Effective, caps.Permitted, caps.Inheritable, caps.Ambient} {
+		mask, err := capabilities.Mask(set)
+		if err != nil {
+			return nil, err
+		}
+		allowed |= mask
+	}
+	inheritable, err := capabilities.Mask(caps.Inheritable)
+	if err != nil {
+		return nil, err
+	}
+	ambient, err := capabilities.Mask(caps.Ambient)
+	if err != nil {
+		return nil, err
+	}
+	bounding, err := capabilities.Mask(caps.Bounding)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []Drift
+	names := func(mask uint64) string {
+		return strings.Join(capabilities.Names(mask), ",")
+	}
+	if actual["CapBnd"] != bounding {
+		drifts = append(drifts, Drift{Kind: "capabilities", Name: "bounding", Expected: names(bounding), Actual: names(actual["CapBnd"])})
+	}
+	for _, set := range []struct {
+		name, field string
+		allowed     uint64
+	}{
+		{"effective", "CapEff", allowed},
+		{"permitted", "CapPrm", allowed},
+		{"inheritable", "CapInh", inheritable},
+		{"ambient", "CapAmb", ambient},
+	} {
+		if extra := actual[set.field] &^ set.allowed; extra != 0 {
+			drifts = append(drifts, Drift{Kind: "capabilities", Name: set.name, Expected: "no " + names(extra), Actual: names(actual[set.field])})
+		}
+	}
+	return drifts, nil
+}
+
+// readProcStatus returns the values of the fields of /proc/<pid>/status.
+func readProcStatus(pid int, fields ...string) (map[string]string, error) {
+	file := "/proc/" + strconv.Itoa(pid) + "/status"
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string, len(fields))
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		key, val, ok := strings.Cut(s.Text(), ":")
+		if ok && slices.Contains(fields, key) {
+			values[key] = strings.TrimSpace(val)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		if _, ok := values[field]; !ok {
+			return nil, fmt.Errorf("no %s in %s", field, file)
+		}
+	}
+	return values, nil
+}
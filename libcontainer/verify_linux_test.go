@@ -0,0 +1,40 @@
+package libcontainer
+
+import (
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestReadProcStatus(t *testing.T) {
+	status, err := readProcStatus(os.Getpid(), "Pid", "CapBnd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status["Pid"] != strconv.Itoa(os.Getpid()) {
+		t.Errorf("expected Pid %d, got %q", os.Getpid(), status["Pid"])
+	}
+	if _, err := strconv.ParseUint(status["CapBnd"], 16, 64); err != nil {
+		t.Errorf("invalid CapBnd %q: %v", status["CapBnd"], err)
+	}
+	if _, err := readProcStatus(os.Getpid(), "NoSuchField"); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+}
+
+func TestCpusetEqual(t *testing.T) {
+	for _, tc := range []struct {
+		expected, actual string
+		equal            bool
+	}{
+		{"0-3", "0-3", true},
+		{"0,1,2,3", "0-3", true},
+		{"3,1-2", "1-3", true},
+		{"0-3", "0-2", false},
+		{"0-3", "", false},
+	} {
+		if equal := cpusetEqual(tc.expected, tc.actual); equal != tc.equal {
+			t.Errorf("cpusetEqual(%q, %q): expected %v, got %v", tc.expected, tc.actual, tc.equal, equal)
+		}
+	}
+}
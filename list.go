@@ -6,17 +6,19 @@ import (
 	"fmt"
 	"os"
 	"os/user"
+	"slices"
 	"strconv"
+	"strings"
 	"syscall"
 	"text/tabwriter"
+	"text/template"
 	"time"
 
 	"github.com/opencontainers/runc/libcontainer"
-	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/urfave/cli"
 )
 
-const formatOptions = `table or json`
+const formatOptions = `table, json, or a Go template`
 
 // containerState represents the platform agnostic pieces relating to a
 // running container's status and state
@@ -39,6 +41,23 @@ type containerState struct {
 	Annotations map[string]string `json:"annotations,omitempty"`
 	// The owner of the state directory (the owner of the container).
 	Owner string `json:"owner"`
+	// SkippedCgroupResources is the list of cgroup resources not applied
+	// because of a lack of permissions in the rootless cgroups mode.
+	SkippedCgroupResources []string `json:"skippedCgroupResources,omitempty"`
+	// ConfigDigest is the digest of the bundle config.json the container
+	// was created from, in the "sha256:<hex>" format.
+	ConfigDigest string `json:"configDigest,omitempty"`
+	// Probes is the state of the container probes, if they are run.
+	Probes []libcontainer.ProbeState `json:"probes,omitempty"`
+	// ExecHistory is the last exec sessions of the container, for
+	// "runc state --exec-history".
+	ExecHistory []libcontainer.ExecSession `json:"execHistory,omitempty"`
+	// Cgroup is the live state of the container cgroup and processes, for
+	// "runc state".
+	Cgroup *libcontainer.CgroupState `json:"cgroup,omitempty"`
+	// Labels are the container config labels, as a key to value map, for
+	// the label filters and the templates.
+	Labels map[string]string `json:"-"`
 }
 
 var listCommand = cli.Command{
@@ -55,7 +74,13 @@ To list containers created via the default "--root":
 
 EXAMPLE 2:
 To list containers created using a non-default value for "--root":
-       # runc --root value list`,
+       # runc --root value list
+
+EXAMPLE 3:
+To list the IDs and PIDs of the running containers with the label
+"app=web", by creation time:
+       # runc list --filter status=running --filter label=app=web \
+                   --sort created --format '{{.ID}} {{.InitProcessPid}}'`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
 			Name:  "format, f",
@@ -66,15 +91,46 @@ To list containers created using a non-default value for "--root":
 			Name:  "quiet, q",
 			Usage: "display only container IDs",
 		},
+		cli.StringSliceFlag{
+			Name:  "filter",
+			Usage: "only list the containers matching a filter, among status=STATUS, label=KEY, and label=KEY=VALUE (can be specified multiple times)",
+		},
+		cli.StringFlag{
+			Name:  "sort",
+			Value: "id",
+			Usage: "sort the containers by id or created",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
+		filter, err := parseListFilters(context.StringSlice("filter"))
+		if err != nil {
+			return err
+		}
+		sortBy := context.String("sort")
+		if sortBy != "id" && sortBy != "created" {
+			return fmt.Errorf("invalid sort option %q", sortBy)
+		}
+		format := context.String("format")
+		var tmpl *template.Template
+		if format != "table" && format != "json" {
+			if !strings.Contains(format, "{{") {
+				return errors.New("invalid format option")
+			}
+			if tmpl, err = template.New("list").Parse(format); err != nil {
+				return fmt.Errorf("invalid format template: %w", err)
+			}
+		}
 		s, err := getContainers(context)
 		if err != nil {
 			return err
 		}
+		s = slices.DeleteFunc(s, func(c containerState) bool { return !filter.match(&c) })
+		if sortBy == "created" {
+			slices.SortStableFunc(s, func(a, b containerState) int { return a.Created.Compare(b.Created) })
+		}
 
 		if context.Bool("quiet") {
 			for _, item := range s {
@@ -83,7 +139,7 @@ To list containers created using a non-default value for "--root":
 			return nil
 		}
 
-		switch context.String("format") {
+		switch format {
 		case "table":
 			w := tabwriter.NewWriter(os.Stdout, 12, 1, 3, ' ', 0)
 			fmt.Fprint(w, "ID\tPID\tSTATUS\tBUNDLE\tCREATED\tOWNER\n")
@@ -104,12 +160,74 @@ To list containers created using a non-default value for "--root":
 				return err
 			}
 		default:
-			return errors.New("invalid format option")
+			for _, item := range s {
+				if err := tmpl.Execute(os.Stdout, item); err != nil {
+					return err
+				}
+				fmt.Println()
+			}
 		}
 		return nil
 	},
 }
 
+// listFilter is the set of the "runc list" filters. A container matches
+// if it has one of the statuses (if any), and all the labels.
+type listFilter struct {
+	statuses []string
+	// labels are the labels to match, with a nil value for any value.
+	labels map[string]*string
+}
+
+// parseListFilters parses the "runc list --filter" values.
+func parseListFilters(filters []string) (*listFilter, error) {
+	f := &listFilter{labels: make(map[string]*string)}
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid filter %q: must be KEY=VALUE", filter)
+		}
+		switch key {
+		case "status":
+			if !slices.Contains([]string{"created", "running", "paused", "stopped"}, value) {
+				return nil, fmt.Errorf("invalid filter %q: unknown status", filter)
+			}
+			f.statuses = append(f.statuses, value)
+		case "label":
+			if k, v, ok := strings.Cut(value, "="); ok {
+				f.labels[k] = &v
+			} else {
+				f.labels[value] = nil
+			}
+		default:
+			return nil, fmt.Errorf("invalid filter %q: unknown filter", filter)
+		}
+	}
+	return f, nil
+}
+
+func (f *listFilter) match(c *containerState) bool {
+	if len(f.statuses) > 0 && !slices.Contains(f.statuses, c.Status) {
+		return false
+	}
+	for k, v := range f.labels {
+		if l, ok := c.Labels[k]; !ok || (v != nil && l != *v) {
+			return false
+		}
+	}
+	return true
+}
+
+// labelsMap returns the "key=value" labels as a key to value map.
+func labelsMap(labels []string) map[string]string {
+	m := make(map[string]string, len(labels))
+	for _, l := range labels {
+		k, v, _ := strings.Cut(l, "=")
+		m[k] = v
+	}
+	return m
+}
+
 func getContainers(context *cli.Context) ([]containerState, error) {
 	root := context.GlobalString("root")
 	list, err := os.ReadDir(root)
@@ -145,6 +263,10 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 
 		container, err := libcontainer.Load(root, item.Name())
 		if err != nil {
+			if errors.Is(err, libcontainer.ErrInvalidID) {
+				// Not a container (e.g. the resource groups directory).
+				continue
+			}
 			fmt.Fprintf(os.Stderr, "load container %s: %v\n", item.Name(), err)
 			continue
 		}
@@ -162,17 +284,18 @@ func getContainers(context *cli.Context) ([]containerState, error) {
 		if containerStatus == libcontainer.Stopped {
 			pid = 0
 		}
-		bundle, annotations := utils.Annotations(state.Config.Labels)
 		s = append(s, containerState{
 			Version:        state.BaseState.Config.Version,
 			ID:             state.BaseState.ID,
 			InitProcessPid: pid,
 			Status:         containerStatus.String(),
-			Bundle:         bundle,
+			Bundle:         state.Config.Bundle(),
 			Rootfs:         state.BaseState.Config.Rootfs,
 			Created:        state.BaseState.Created,
-			Annotations:    annotations,
+			Annotations:    state.Config.Annotations,
+			ConfigDigest:   state.Config.SpecDigest,
 			Owner:          owner,
+			Labels:         labelsMap(state.Config.Labels),
 		})
 	}
 	return s, nil
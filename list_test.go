@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestListFilter(t *testing.T) {
+	web := containerState{Status: "running", Labels: map[string]string{"app": "web", "bundle": "/b"}}
+	db := containerState{Status: "paused", Labels: map[string]string{"app": "db", "bundle": "/b"}}
+	for _, tc := range []struct {
+		filters []string
+		web, db bool
+	}{
+		{web: true, db: true},
+		{filters: []string{"status=running"}, web: true},
+		{filters: []string{"status=running", "status=paused"}, web: true, db: true},
+		{filters: []string{"label=app"}, web: true, db: true},
+		{filters: []string{"label=app=db"}, db: true},
+		{filters: []string{"label=app="}},
+		{filters: []string{"label=app=web", "status=paused"}},
+		{filters: []string{"label=bundle=/b", "label=tier"}},
+	} {
+		f, err := parseListFilters(tc.filters)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.filters, err)
+			continue
+		}
+		if got := f.match(&web); got != tc.web {
+			t.Errorf("%q: expected web match %v, got %v", tc.filters, tc.web, got)
+		}
+		if got := f.match(&db); got != tc.db {
+			t.Errorf("%q: expected db match %v, got %v", tc.filters, tc.db, got)
+		}
+	}
+
+	for _, filter := range []string{"status", "status=", "status=up", "name=web", "label="} {
+		if _, err := parseListFilters([]string{filter}); err == nil {
+			t.Errorf("%q: expected error, got nil", filter)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer/logs"
+)
+
+// logFields are the fields every log entry is tagged with, if the log format
+// is json: the correlation ID, and the operation and the container ID of
+// the command being run.
+var logFields logrus.Fields
+
+// logCorrelationID is the correlation ID of the log entries, which is passed
+// on to runc init (see [libcontainer.Process.CorrelationID]), if the log
+// format is json.
+var logCorrelationID string
+
+// configLogFields makes the log entries tagged with logFields, with the
+// correlation ID set by the --correlation-id option, or a random one.
+func configLogFields(context *cli.Context) error {
+	if context.GlobalString("log-format") != "json" {
+		return nil
+	}
+	id := context.GlobalString("correlation-id")
+	if id == "" {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return err
+		}
+		id = hex.EncodeToString(b[:])
+	}
+	logCorrelationID = id
+	logFields = logrus.Fields{logs.CorrelationIDKey: id}
+	logrus.AddHook(&logs.FieldsHook{Fields: logFields})
+	return nil
+}
+
+// logCommands makes the log entries of the commands tagged with the
+// operation, and the container ID (for the commands taking one).
+func logCommands(commands []cli.Command) {
+	for i, cmd := range commands {
+		withID := strings.HasPrefix(cmd.ArgsUsage, "<container-id>")
+		action, ok := cmd.Action.(func(*cli.Context) error)
+		if !ok {
+			// A command with subcommands.
+			continue
+		}
+		commands[i].Action = func(context *cli.Context) error {
+			if logFields != nil {
+				logFields["op"] = context.Command.Name
+				if id := context.Args().First(); id != "" && withID {
+					logFields["container"] = id
+				}
+			}
+			return action(context)
+		}
+	}
+}
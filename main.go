@@ -108,6 +108,10 @@ func main() {
 			Value: "text",
 			Usage: "set the log format ('text' (default), or 'json')",
 		},
+		cli.StringFlag{
+			Name:  "correlation-id",
+			Usage: "tag the json log entries, including the ones of runc init, with the specified ID (default is a random one)",
+		},
 		cli.StringFlag{
 			Name:  "root",
 			Value: root,
@@ -122,16 +126,44 @@ func main() {
 			Value: "auto",
 			Usage: "ignore cgroup permission errors ('true', 'false', or 'auto')",
 		},
+		cli.StringFlag{
+			Name:  "mount-policy",
+			Usage: "enforce mount flags on all bind mounts (a comma-separated list of 'nosuid', 'nodev', and 'noexec')",
+		},
+		cli.StringSliceFlag{
+			Name:  "mount-policy-allow",
+			Usage: "exempt the bind mount at the specified container path from the mount policy (can be specified multiple times)",
+		},
+		cli.StringFlag{
+			Name:  "audit-log",
+			Usage: "append a record of each create, start, run, exec, kill, update, and delete operation to the specified file (as JSON lines)",
+		},
+		cli.BoolFlag{
+			Name:  "audit-daemon",
+			Usage: "send a record of each audited operation (see --audit-log) to the system audit daemon",
+		},
+		cli.BoolFlag{
+			Name:  "state-blobs",
+			Usage: "store the large configuration fields of the states of the containers created in blobs, shared by the containers with identical configurations (the states can not be read by runc versions without blob support)",
+		},
+		cli.StringFlag{
+			Name:  "otel-endpoint",
+			Usage: "export OpenTelemetry traces of the container lifecycle to the specified OTLP/HTTP endpoint",
+		},
 	}
 	app.Commands = []cli.Command{
 		checkpointCommand,
+		consoleLogCommand,
 		createCommand,
+		daemonCommand,
 		deleteCommand,
 		eventsCommand,
 		execCommand,
+		gcCommand,
 		killCommand,
 		listCommand,
 		pauseCommand,
+		probeCommand,
 		psCommand,
 		restoreCommand,
 		resumeCommand,
@@ -140,8 +172,13 @@ func main() {
 		startCommand,
 		stateCommand,
 		updateCommand,
+		verifyCommand,
 		featuresCommand,
+		groupCommand,
 	}
+	auditCommands(app.Commands)
+	traceCommands(app.Commands)
+	logCommands(app.Commands)
 	app.Before = func(context *cli.Context) error {
 		if !context.IsSet("root") && xdgDirUsed {
 			// According to the XDG specification, we need to set anything in
@@ -159,8 +196,17 @@ func main() {
 		if err := reviseRootDir(context); err != nil {
 			return err
 		}
+		if err := configLogrus(context); err != nil {
+			return err
+		}
+		if err := configLogFields(context); err != nil {
+			return err
+		}
 
-		return configLogrus(context)
+		if err := configAudit(context); err != nil {
+			return err
+		}
+		return configTracing(context)
 	}
 
 	// If the command returns an error, cli takes upon itself to print
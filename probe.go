@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/specconv"
+	"github.com/opencontainers/runc/libcontainer/utils"
+)
+
+var probeCommand = cli.Command{
+	Name:  "probe",
+	Usage: "run the readiness and liveness probes of a container",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The probe command runs the probes of a running container (see the
+"` + specconv.AnnotationProbes + `" annotation) in the foreground, until
+the container exits. Each check is a process executed in the container, with
+the user, environment, and working directory of the container process.
+
+The probe states are shown by "runc state", and the probe status changes are
+reported as "probe" events by "runc events". The probes are run automatically
+for a container run in the foreground by "runc run".`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		return runProbesInterruptible(container)
+	},
+}
+
+// runProbesInterruptible runs the probes of the container until it exits, or
+// runc is interrupted.
+func runProbesInterruptible(container *libcontainer.Container) error {
+	ctx, stop := signal.NotifyContext(context.Background(), unix.SIGINT, unix.SIGTERM)
+	defer stop()
+	return runProbes(ctx, container)
+}
+
+// runProbes runs the probes of the container until it exits, or ctx is done.
+func runProbes(ctx context.Context, container *libcontainer.Container) error {
+	bundle, ok := utils.SearchLabels(container.Config().Labels, "bundle")
+	if !ok {
+		return errors.New("bundle not found in labels")
+	}
+	spec, err := loadSpec(filepath.Join(bundle, specConfig))
+	if err != nil {
+		return err
+	}
+	return container.RunProbes(ctx, func(args []string) (*libcontainer.Process, error) {
+		p := *spec.Process
+		p.Args = args
+		p.Terminal = false
+		process, err := newProcess(&p)
+		if err != nil {
+			return nil, err
+		}
+		process.LogLevel = strconv.Itoa(int(logrus.GetLevel()))
+		process.CorrelationID = logCorrelationID
+		return process, nil
+	})
+}
+
+// probeArgs returns the "runc probe" command line for the container, with
+// the global options of the current runc invocation.
+func probeArgs(context *cli.Context, id string) []string {
+	return append(globalArgs(context), "probe", id)
+}
+
+// globalArgs returns the global options of the current runc invocation
+// which are passed to the runc commands it runs.
+func globalArgs(context *cli.Context) []string {
+	args := []string{"--root", context.GlobalString("root")}
+	if context.GlobalBool("debug") {
+		args = append(args, "--debug")
+	}
+	if log := context.GlobalString("log"); log != "" {
+		args = append(args, "--log", log, "--log-format", context.GlobalString("log-format"))
+	}
+	return args
+}
+
+// startProbes starts "runc probe" with args (see probeArgs). A separate
+// process is used as "runc run" reaps all its children while the container
+// runs, including the processes it would execute for the checks.
+func startProbes(args []string) (*exec.Cmd, error) {
+	cmd := exec.Command("/proc/self/exe", args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
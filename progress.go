@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/sys/unix"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// progressRecord is a progress record written by --progress json.
+type progressRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	ID        string    `json:"id"`
+	Phase     string    `json:"phase"`
+	// Percent is omitted if unknown.
+	Percent    *int   `json:"percent,omitempty"`
+	Bytes      uint64 `json:"bytes"`
+	TotalBytes uint64 `json:"total_bytes,omitempty"`
+	Done       bool   `json:"done"`
+}
+
+// newProgressReporter returns the progress callback of the --progress and
+// --progress-fd options for the container id, or nil if --progress is not
+// set.
+func newProgressReporter(context *cli.Context, id string) (func(libcontainer.Progress), error) {
+	switch format := context.String("progress"); format {
+	case "":
+		if context.IsSet("progress-fd") {
+			return nil, errors.New("--progress-fd requires --progress")
+		}
+		return nil, nil
+	case "json":
+	default:
+		return nil, fmt.Errorf("invalid --progress format %q", format)
+	}
+	fd := context.Int("progress-fd")
+	if fd < 0 {
+		return nil, fmt.Errorf("invalid --progress-fd %d", fd)
+	}
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_GETFD, 0); err != nil {
+		return nil, fmt.Errorf("invalid --progress-fd %d: %w", fd, err)
+	}
+	return progressReporter(os.NewFile(uintptr(fd), "progress"), context.Command.Name, id), nil
+}
+
+// progressReporter returns a progress callback writing the records of the
+// operation on the container id to w, one JSON object per line.
+func progressReporter(w io.Writer, operation, id string) func(libcontainer.Progress) {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return func(p libcontainer.Progress) {
+		r := progressRecord{
+			Time:       time.Now().UTC(),
+			Operation:  operation,
+			ID:         id,
+			Phase:      p.Phase,
+			Bytes:      p.Bytes,
+			TotalBytes: p.TotalBytes,
+			Done:       p.Done,
+		}
+		if pct := p.Percent(); pct >= 0 {
+			r.Percent = &pct
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		// The progress records are best effort.
+		_ = enc.Encode(r)
+	}
+}
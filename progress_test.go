@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+func TestProgressReporter(t *testing.T) {
+	var buf bytes.Buffer
+	report := progressReporter(&buf, "checkpoint", "ctr")
+	report(libcontainer.Progress{Phase: "dump", Bytes: 10})
+	report(libcontainer.Progress{Phase: "dump", Bytes: 10, TotalBytes: 40})
+	report(libcontainer.Progress{Phase: "dump", Bytes: 40, TotalBytes: 40, Done: true})
+
+	var records []map[string]any
+	s := bufio.NewScanner(&buf)
+	for s.Scan() {
+		var r map[string]any
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			t.Fatalf("invalid record %q: %v", s.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	for _, r := range records {
+		if r["operation"] != "checkpoint" || r["id"] != "ctr" || r["phase"] != "dump" || r["time"] == nil {
+			t.Errorf("unexpected record %v", r)
+		}
+	}
+	// The percentage and the total are omitted if unknown.
+	if _, ok := records[0]["percent"]; ok {
+		t.Errorf("expected no percent, got %v", records[0])
+	}
+	if _, ok := records[0]["total_bytes"]; ok {
+		t.Errorf("expected no total_bytes, got %v", records[0])
+	}
+	if r := records[1]; r["percent"] != 25.0 || r["total_bytes"] != 40.0 || r["done"] != false {
+		t.Errorf("unexpected record %v", r)
+	}
+	if r := records[2]; r["percent"] != 100.0 || r["done"] != true {
+		t.Errorf("unexpected record %v", r)
+	}
+}
@@ -11,6 +11,8 @@ import (
 	"strings"
 
 	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
 )
 
 var psCommand = cli.Command{
@@ -23,6 +25,14 @@ var psCommand = cli.Command{
 			Value: "table",
 			Usage: `select one of: ` + formatOptions,
 		},
+		cli.BoolFlag{
+			Name:  "details",
+			Usage: "show the details of each process (with --format json)",
+		},
+		cli.BoolFlag{
+			Name:  "translate",
+			Usage: "show the PID of each process in the container PID namespace",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, minArgs); err != nil {
@@ -34,7 +44,33 @@ var psCommand = cli.Command{
 			return err
 		}
 
-		pids, err := container.Processes()
+		if context.Bool("details") {
+			if context.String("format") != "json" {
+				return errors.New("--details requires --format json")
+			}
+			if context.Bool("translate") {
+				return errors.New("--details can't be used with --translate")
+			}
+			infos, err := container.ProcessesInfo()
+			if err != nil {
+				maybeLogCgroupWarning("ps", err)
+				return err
+			}
+			return json.NewEncoder(os.Stdout).Encode(infos)
+		}
+
+		var (
+			pids     []int
+			mappings []libcontainer.PidMapping
+		)
+		if context.Bool("translate") {
+			mappings, err = container.PidMappings()
+			for _, m := range mappings {
+				pids = append(pids, m.Host)
+			}
+		} else {
+			pids, err = container.Processes()
+		}
 		if err != nil {
 			maybeLogCgroupWarning("ps", err)
 			return err
@@ -43,6 +79,9 @@ var psCommand = cli.Command{
 		switch context.String("format") {
 		case "table":
 		case "json":
+			if mappings != nil {
+				return json.NewEncoder(os.Stdout).Encode(mappings)
+			}
 			return json.NewEncoder(os.Stdout).Encode(pids)
 		default:
 			return errors.New("invalid format option")
@@ -69,7 +108,14 @@ var psCommand = cli.Command{
 			return err
 		}
 
-		fmt.Println(lines[0])
+		// With --translate, the container PID is shown in a first
+		// CPID column.
+		prefix := func(string) string { return "" }
+		if mappings != nil {
+			prefix = func(cpid string) string { return fmt.Sprintf("%7s ", cpid) }
+		}
+
+		fmt.Println(prefix("CPID") + lines[0])
 		for _, line := range lines[1:] {
 			if len(line) == 0 {
 				continue
@@ -80,9 +126,15 @@ var psCommand = cli.Command{
 				return fmt.Errorf("unable to parse pid: %w", err)
 			}
 
-			if slices.Contains(pids, p) {
-				fmt.Println(line)
+			i := slices.Index(pids, p)
+			if i == -1 {
+				continue
+			}
+			cpid := "-"
+			if mappings != nil && mappings[i].Container != -1 {
+				cpid = strconv.Itoa(mappings[i].Container)
 			}
+			fmt.Println(prefix(cpid) + line)
 		}
 		return nil
 	},
@@ -21,7 +21,7 @@ using the runc checkpoint command.`,
 		cli.StringFlag{
 			Name:  "console-socket",
 			Value: "",
-			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
+			Usage: "path to an AF_UNIX socket (or fd://N for an inherited one) which will receive a file descriptor referencing the master end of the console's pseudoterminal",
 		},
 		cli.StringFlag{
 			Name:  "image-path",
@@ -88,6 +88,11 @@ using the runc checkpoint command.`,
 			Name:  "lazy-pages",
 			Usage: "use userfaultfd to lazily restore memory pages",
 		},
+		cli.StringFlag{
+			Name:  "lazy-pages-server",
+			Value: "",
+			Usage: "lazily restore memory pages from the page server on ADDRESS:PORT (implies --lazy-pages)",
+		},
 		cli.StringFlag{
 			Name:  "lsm-profile",
 			Value: "",
@@ -98,6 +103,27 @@ using the runc checkpoint command.`,
 			Value: "",
 			Usage: "Specify an LSM mount context to be used during restore.",
 		},
+		cli.StringSliceFlag{
+			Name:  "external-mount",
+			Usage: "restore the mount at DEST in the container, which was not dumped, from the host path SOURCE (DEST by default), in the form DEST[:SOURCE] (can be specified multiple times)",
+		},
+		cli.StringFlag{
+			Name:  "external-netns",
+			Usage: "restore the container into the network namespace bind-mounted at PATH, if it was not dumped (by default, the configured network namespace path is used)",
+		},
+		cli.StringSliceFlag{
+			Name:  "inherit-fd",
+			Usage: "pass the file descriptor FD to criu for the external resource KEY, such as socket:[INODE], in the form FD:KEY (can be specified multiple times)",
+		},
+		cli.StringFlag{
+			Name:  "progress",
+			Usage: "report the progress of the long phases as JSON lines (the only supported format is json)",
+		},
+		cli.IntFlag{
+			Name:  "progress-fd",
+			Value: 2,
+			Usage: "write the progress records of --progress to this file descriptor",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -33,12 +33,30 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 		cli.StringFlag{
 			Name:  "console-socket",
 			Value: "",
-			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the master end of the console's pseudoterminal",
+			Usage: "path to an AF_UNIX socket (or fd://N for an inherited one) which will receive a file descriptor referencing the master end of the console's pseudoterminal",
+		},
+		cli.StringFlag{
+			Name:  "console-log",
+			Usage: "path to a file to write the console output to, when runc detaches with a terminal (instead of using a console socket)",
+		},
+		cli.StringFlag{
+			Name:  "console-log-max-size",
+			Value: "10M",
+			Usage: "the size the console log file is rotated at (0 for no limit)",
+		},
+		cli.IntFlag{
+			Name:  "console-log-max-files",
+			Value: 5,
+			Usage: "the number of rotated console log files kept (0 to truncate the console log file instead)",
 		},
 		cli.StringFlag{
 			Name:  "pidfd-socket",
 			Usage: "path to an AF_UNIX socket which will receive a file descriptor referencing the init process",
 		},
+		cli.StringFlag{
+			Name:  "idmap-helper",
+			Usage: "how to set up the user namespace mappings: direct, newuidmap, subid, or unix://<path> for a privileged helper socket",
+		},
 		cli.BoolFlag{
 			Name:  "detach, d",
 			Usage: "detach from the container's process",
@@ -52,6 +70,14 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Value: "",
 			Usage: "specify the file to write the process id to",
 		},
+		cli.StringSliceFlag{
+			Name:  "forward-signal",
+			Usage: "forward a signal runc receives as another one, as FROM=TO (such as SIGTERM=SIGINT), unless detached (can be repeated)",
+		},
+		cli.StringFlag{
+			Name:  "no-forward-signals",
+			Usage: "comma-separated list of the signals runc receives which are not forwarded, unless detached",
+		},
 		cli.BoolFlag{
 			Name:  "no-subreaper",
 			Usage: "disable the use of the subreaper used to reap reparented processes",
@@ -64,10 +90,39 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 			Name:  "no-new-keyring",
 			Usage: "do not create a new session keyring for the container.  This will cause the container to inherit the calling processes session key",
 		},
+		cli.BoolFlag{
+			Name:  "strict-spec",
+			Usage: "fail if any spec field would be ignored, as it can not be honored on this host (or in the rootless mode)",
+		},
+		cli.BoolFlag{
+			Name:  "sched-core",
+			Usage: "make the container processes share a core scheduling cookie, so that no other task runs on the SMT siblings of their cores",
+		},
+		cli.BoolFlag{
+			Name:  "allow-keyring",
+			Usage: "allow the org.opencontainers.runc.keyring annotation, which links host keys into the container session keyring, and may raise the host key quotas",
+		},
+		cli.BoolFlag{
+			Name:  "allow-shm-remount",
+			Usage: "allow the org.opencontainers.runc.shm.policy=remount annotation, which resizes a /dev/shm shared with another container",
+		},
 		cli.IntFlag{
 			Name:  "preserve-fds",
 			Usage: "Pass N additional file descriptors to the container (stdio + $LISTEN_FDS + N in total)",
 		},
+		cli.StringFlag{
+			Name:  "group",
+			Usage: "create the container as a member of the specified resource group (see runc group)",
+		},
+		cli.StringFlag{
+			Name:  "progress",
+			Usage: "report the progress of the long phases as JSON lines (the only supported format is json)",
+		},
+		cli.IntFlag{
+			Name:  "progress-fd",
+			Value: 2,
+			Usage: "write the progress records of --progress to this file descriptor",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -75,6 +130,8 @@ command(s) that get executed on start, edit the args parameter of the spec. See
 		}
 		status, err := startContainer(context, CT_ACT_RUN, nil)
 		if err == nil {
+			auditEndStatus(context, status)
+			traceEnd(nil)
 			// exit with the container's exit status so any external supervisor is
 			// notified of the exit with the correct exit status.
 			os.Exit(status)
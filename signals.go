@@ -1,14 +1,18 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/system"
 	"github.com/opencontainers/runc/libcontainer/utils"
 
 	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
 	"golang.org/x/sys/unix"
 )
 
@@ -54,6 +58,7 @@ type exit struct {
 type signalHandler struct {
 	signals      chan os.Signal
 	notifySocket *notifySocket
+	forwarding   *configs.SignalForwarding
 }
 
 // forward handles the main signal event loop forwarding, resizing, or reaping depending
@@ -113,8 +118,13 @@ func (h *signalHandler) forward(process *libcontainer.Process, tty *tty, detach
 			// Do nothing.
 		default:
 			us := s.(unix.Signal)
-			logrus.Debugf("forwarding signal %d (%s) to %d", int(us), unix.SignalName(us), pid1)
-			if err := process.Signal(s); err != nil {
+			fs := h.forwarding.Forwarded(us)
+			if fs == 0 {
+				logrus.Debugf("not forwarding signal %d (%s)", int(us), unix.SignalName(us))
+				continue
+			}
+			logrus.Debugf("forwarding signal %d (%s) as %d (%s) to %d", int(us), unix.SignalName(us), int(fs), unix.SignalName(fs), pid1)
+			if err := process.Signal(fs); err != nil {
 				logrus.Error(err)
 			}
 		}
@@ -122,6 +132,45 @@ func (h *signalHandler) forward(process *libcontainer.Process, tty *tty, detach
 	return -1, nil
 }
 
+// getSignalForwarding returns the signal forwarding policy set by the
+// --forward-signal and --no-forward-signals options, which extends the one
+// of the container, or nil if neither is set.
+func getSignalForwarding(context *cli.Context) (*configs.SignalForwarding, error) {
+	pairs, ignored := context.StringSlice("forward-signal"), context.String("no-forward-signals")
+	if len(pairs) == 0 && ignored == "" {
+		return nil, nil
+	}
+	f := &configs.SignalForwarding{Map: make(map[unix.Signal]unix.Signal)}
+	for _, pair := range pairs {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --forward-signal value %q (must be FROM=TO)", pair)
+		}
+		fromSig, err := parseSignal(from)
+		if err != nil {
+			return nil, err
+		}
+		toSig, err := parseSignal(to)
+		if err != nil {
+			return nil, err
+		}
+		f.Map[fromSig] = toSig
+	}
+	if ignored != "" {
+		for _, name := range strings.Split(ignored, ",") {
+			sig, err := parseSignal(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := f.Map[sig]; ok {
+				return nil, fmt.Errorf("signal %s can't be both forwarded as another one and not forwarded", unix.SignalName(sig))
+			}
+			f.Ignored = append(f.Ignored, sig)
+		}
+	}
+	return f, nil
+}
+
 // reap runs wait4 in a loop until we have finished processing any existing exits
 // then returns all exits to the main event loop for further processing.
 func (h *signalHandler) reap() (exits []exit, err error) {
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 
+	"github.com/opencontainers/runc/internal/specjson"
+	"github.com/opencontainers/runc/libcontainer/cgtranslate"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/configs/validate"
 	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -63,6 +70,16 @@ generate a proper rootless spec file.
 
 Note that --rootless is not needed when you execute runc as the root in a user namespace
 created by an unprivileged user.
+
+With --validate, the existing specification file is validated instead, and all
+the problems found are printed as a JSON array of findings, each having the
+path of the spec field, the severity ("error" or "warning"), a message, and a
+suggested fix. The command fails if any error is found.
+
+With --cgroupv2, the cgroup v1 resources of the existing specification file
+are translated into their closest cgroup v2 equivalents instead, and the
+translated spec is printed. The settings which can't be translated exactly
+are reported as warnings.
 `,
 	Flags: []cli.Flag{
 		cli.StringFlag{
@@ -74,11 +91,33 @@ created by an unprivileged user.
 			Name:  "rootless",
 			Usage: "generate a configuration for a rootless container",
 		},
+		cli.BoolFlag{
+			Name:  "validate",
+			Usage: "validate the existing specification file, and print the problems found as JSON",
+		},
+		cli.BoolFlag{
+			Name:  "cgroupv2",
+			Usage: "translate the cgroup v1 resources of the existing specification file to cgroup v2, and print the translated spec",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 0, exactArgs); err != nil {
 			return err
 		}
+		if context.Bool("validate") || context.Bool("cgroupv2") {
+			if context.Bool("validate") && context.Bool("cgroupv2") {
+				return errors.New("--validate and --cgroupv2 can't be used together")
+			}
+			if bundle := context.String("bundle"); bundle != "" {
+				if err := os.Chdir(bundle); err != nil {
+					return err
+				}
+			}
+			if context.Bool("cgroupv2") {
+				return translateSpec()
+			}
+			return validateSpec(context)
+		}
 		spec := specconv.Example()
 
 		rootless := context.Bool("rootless")
@@ -113,24 +152,145 @@ created by an unprivileged user.
 	},
 }
 
-// loadSpec loads the specification from the provided path.
-func loadSpec(cPath string) (spec *specs.Spec, err error) {
-	cf, err := os.Open(cPath)
+// validateSpec validates the specification file in the current directory,
+// and prints all the problems found as a JSON array of findings.
+func validateSpec(context *cli.Context) error {
+	findings, err := specFindings(context)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("JSON specification file %s not found", cPath)
+		return err
+	}
+	if findings == nil {
+		findings = []validate.Finding{}
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(findings); err != nil {
+		return err
+	}
+	errs := 0
+	for _, f := range findings {
+		if f.Severity == validate.SeverityError {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("%s: %d error(s) found", specConfig, errs)
+	}
+	return nil
+}
+
+// translateSpec prints the specification file in the current directory,
+// with its cgroup v1 resources translated to cgroup v2.
+func translateSpec() error {
+	f, err := os.Open(specConfig)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	spec, err := specjson.DecodeSpec(f)
+	if err != nil {
+		return err
+	}
+	if spec == nil {
+		return errors.New("config cannot be null")
+	}
+	if spec.Linux != nil {
+		var warnings []cgtranslate.Warning
+		spec.Linux.Resources, warnings = cgtranslate.Resources(spec.Linux.Resources)
+		for _, w := range warnings {
+			logrus.Warn(w)
 		}
-		return nil, err
 	}
-	defer cf.Close()
+	data, err := json.MarshalIndent(spec, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Printf("%s\n", data)
+	return err
+}
 
-	if err = json.NewDecoder(cf).Decode(&spec); err != nil {
+// specFindings returns the problems of the specification file in the
+// current directory, when used with the runc global options.
+func specFindings(context *cli.Context) ([]validate.Finding, error) {
+	rootlessCg, err := shouldUseRootlessCgroupManager(context)
+	if err != nil {
+		return nil, err
+	}
+	mountPolicy, err := parseMountPolicy(context)
+	if err != nil {
 		return nil, err
 	}
+	f, err := os.Open(specConfig)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	spec, err := specjson.DecodeSpec(f)
+	if err == nil && spec == nil {
+		err = errors.New("config cannot be null")
+	}
+	if err != nil {
+		return []validate.Finding{{
+			Severity: validate.SeverityError,
+			Message:  err.Error(),
+			Fix:      "use a valid JSON runtime spec",
+		}}, nil
+	}
+	var findings []validate.Finding
+	if err := validateProcessSpec(spec.Process); err != nil {
+		findings = append(findings, validate.Finding{
+			Path:     "process",
+			Severity: validate.SeverityError,
+			Message:  err.Error(),
+		})
+	}
+	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
+		CgroupName:       "runc-spec-validate",
+		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
+		Spec:             spec,
+		RootlessEUID:     os.Geteuid() != 0,
+		RootlessCgroups:  rootlessCg,
+		MountPolicy:      mountPolicy,
+		AllowKeyring:     true,
+		AllowShmRemount:  true,
+	})
+	if err != nil {
+		// The other problems can not be found without a valid
+		// configuration.
+		return append(findings, validate.Finding{
+			Severity: validate.SeverityError,
+			Message:  err.Error(),
+		}), nil
+	}
+	return append(findings, validate.ValidateAll(config)...), nil
+}
+
+// loadSpec loads the specification from the provided path.
+func loadSpec(cPath string) (*specs.Spec, error) {
+	spec, _, err := loadSpecDigest(cPath)
+	return spec, err
+}
+
+// loadSpecDigest loads the specification from the provided path, and also
+// returns the digest of the file, in the "sha256:<hex>" format.
+func loadSpecDigest(cPath string) (*specs.Spec, string, error) {
+	data, err := os.ReadFile(cPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("JSON specification file %s not found", cPath)
+		}
+		return nil, "", err
+	}
+	spec, err := specjson.DecodeSpec(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
 	if spec == nil {
-		return nil, errors.New("config cannot be null")
+		return nil, "", errors.New("config cannot be null")
 	}
-	return spec, validateProcessSpec(spec.Process)
+	digest := sha256.Sum256(data)
+	return spec, "sha256:" + hex.EncodeToString(digest[:]), validateProcessSpec(spec.Process)
 }
 
 func createLibContainerRlimit(rlimit specs.POSIXRlimit) (configs.Rlimit, error) {
@@ -5,7 +5,6 @@ import (
 	"os"
 
 	"github.com/opencontainers/runc/libcontainer"
-	"github.com/opencontainers/runc/libcontainer/utils"
 	"github.com/urfave/cli"
 )
 
@@ -17,6 +16,12 @@ var stateCommand = cli.Command{
 Where "<container-id>" is your name for the instance of the container.`,
 	Description: `The state command outputs current state information for the
 instance of a container.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "exec-history",
+			Usage: "include the history of the processes executed in the container",
+		},
+	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
 			return err
@@ -33,20 +38,38 @@ instance of a container.`,
 		if err != nil {
 			return err
 		}
+		probes, err := container.Probes()
+		if err != nil {
+			return err
+		}
 		pid := state.BaseState.InitProcessPid
 		if containerStatus == libcontainer.Stopped {
 			pid = 0
 		}
-		bundle, annotations := utils.Annotations(state.Config.Labels)
 		cs := containerState{
-			Version:        state.BaseState.Config.Version,
-			ID:             state.BaseState.ID,
-			InitProcessPid: pid,
-			Status:         containerStatus.String(),
-			Bundle:         bundle,
-			Rootfs:         state.BaseState.Config.Rootfs,
-			Created:        state.BaseState.Created,
-			Annotations:    annotations,
+			Version:                state.BaseState.Config.Version,
+			ID:                     state.BaseState.ID,
+			InitProcessPid:         pid,
+			Status:                 containerStatus.String(),
+			Bundle:                 state.Config.Bundle(),
+			Rootfs:                 state.BaseState.Config.Rootfs,
+			Created:                state.BaseState.Created,
+			Annotations:            state.Config.Annotations,
+			SkippedCgroupResources: state.SkippedCgroupResources,
+			ConfigDigest:           state.Config.SpecDigest,
+			Probes:                 probes,
+		}
+		if containerStatus != libcontainer.Stopped {
+			cs.Cgroup, err = container.CgroupState()
+			if err != nil {
+				return err
+			}
+		}
+		if context.Bool("exec-history") {
+			cs.ExecHistory, err = container.ExecHistory()
+			if err != nil {
+				return err
+			}
 		}
 		data, err := json.MarshalIndent(cs, "", "  ")
 		if err != nil {
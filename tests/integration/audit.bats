@@ -0,0 +1,67 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+	AUDIT_LOG="$BATS_RUN_TMPDIR/audit.log"
+	rm -f "$AUDIT_LOG"
+}
+
+function teardown() {
+	teardown_bundle
+	rm -f "$AUDIT_LOG"
+}
+
+@test "runc --audit-log" {
+	runc --audit-log "$AUDIT_LOG" run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc --audit-log "$AUDIT_LOG" exec test_busybox sh -c 'exit 3'
+	[ "$status" -eq 3 ]
+
+	# Not audited.
+	runc --audit-log "$AUDIT_LOG" state test_busybox
+	[ "$status" -eq 0 ]
+
+	runc --audit-log "$AUDIT_LOG" delete test_busybox
+	[ "$status" -ne 0 ]
+
+	runc --audit-log "$AUDIT_LOG" delete -f test_busybox
+	[ "$status" -eq 0 ]
+
+	run -0 jq -c '[.command, .id, .result, .exit_code, .uid == '"$EUID"']' "$AUDIT_LOG"
+	[ "${lines[0]}" = '["run","test_busybox","attempt",null,true]' ]
+	[ "${lines[1]}" = '["run","test_busybox","success",null,true]' ]
+	[ "${lines[2]}" = '["exec","test_busybox","attempt",null,true]' ]
+	[ "${lines[3]}" = '["exec","test_busybox","success",3,true]' ]
+	[ "${lines[4]}" = '["delete","test_busybox","attempt",null,true]' ]
+	[ "${lines[5]}" = '["delete","test_busybox","failure",null,true]' ]
+	[ "${lines[6]}" = '["delete","test_busybox","attempt",null,true]' ]
+	[ "${lines[7]}" = '["delete","test_busybox","success",null,true]' ]
+	[ "${#lines[@]}" -eq 8 ]
+}
+
+@test "runc --audit-log [killed]" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# A runc killed during the operation leaves the attempt record.
+	__runc --audit-log "$AUDIT_LOG" exec test_busybox sleep 100 &
+	retry 10 0.5 grep -q '"result":"attempt"' "$AUDIT_LOG"
+	kill -9 $!
+	wait $! || true
+
+	run -0 jq -c '[.command, .id, .result]' "$AUDIT_LOG"
+	[ "${lines[0]}" = '["exec","test_busybox","attempt"]' ]
+	[ "${#lines[@]}" -eq 1 ]
+}
+
+@test "runc --audit-log [unwritable]" {
+	runc --audit-log /nonexistent/audit.log run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"unable to open audit log"* ]]
+
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+}
@@ -192,6 +192,23 @@ function simple_cr_with_netdevice() {
 	simple_cr
 }
 
+@test "checkpoint and restore --progress json" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc checkpoint --work-path ./work-dir --progress json --progress-fd 5 test_busybox 5>checkpoint-progress.json
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox checkpointed
+	[ "$(jq -s -c 'last | [.operation, .phase, .done, .percent]' <checkpoint-progress.json)" = '["checkpoint","dump",true,100]' ]
+	[ "$(jq -s 'last | .bytes' <checkpoint-progress.json)" -gt 0 ]
+
+	runc restore -d --work-path ./work-dir --console-socket "$CONSOLE_SOCKET" --progress json --progress-fd 5 test_busybox 5>restore-progress.json
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+	[ "$(jq -s -c 'last | [.operation, .phase, .done, .percent]' <restore-progress.json)" = '["restore","restore",true,100]' ]
+	[ "$(jq -s 'last | .total_bytes' <restore-progress.json)" -gt 0 ]
+}
+
 @test "checkpoint and restore (bind mount, destination is symlink)" {
 	mkdir -p rootfs/real/conf
 	ln -s /real/conf rootfs/conf
@@ -246,6 +263,61 @@ function simple_cr_with_netdevice() {
 	simple_cr_with_netdevice
 }
 
+@test "checkpoint and restore (--external-netns)" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+
+	# Keep the network namespace of the container, like a pod one.
+	pid=$(__runc state test_busybox | jq '.pid')
+	tmp=$(mktemp -u)
+	ns_name=$(basename "$tmp")
+	ip netns attach "$ns_name" "$pid"
+	ns_path="/run/netns/$ns_name"
+	ns_inode=$(stat -L -c %i "$ns_path")
+
+	runc checkpoint --external-netns "$ns_path" --work-path ./work-dir test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox checkpointed
+
+	runc restore -d --external-netns "$ns_path" --work-path ./work-dir --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+	pid=$(__runc state test_busybox | jq '.pid')
+	[ "$(stat -L -c %i "/proc/$pid/ns/net")" = "$ns_inode" ]
+
+	# It is now the configured network namespace path.
+	runc checkpoint --work-path ./work-dir test_busybox
+	[ "$status" -eq 0 ]
+}
+
+@test "checkpoint --manifest" {
+	update_config '	  .mounts += [{
+					source: ".",
+					destination: "/conf",
+					options: ["bind"]
+				}]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+
+	runc checkpoint --manifest --work-path ./work-dir --image-path ./image-dir test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox checkpointed
+
+	manifest=./image-dir/checkpoint-manifest.json
+	[ "$(jq -r .id "$manifest")" = "test_busybox" ]
+	[ "$(jq -r '.mounts[] | select(.destination == "/conf") | .source' "$manifest")" = "$(pwd)" ]
+
+	runc checkpoint --manifest --pre-dump --image-path ./parent-dir test_busybox
+	[ "$status" -ne 0 ]
+}
+
 @test "checkpoint --pre-dump (bad --parent-path)" {
 	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
 	[ "$status" -eq 0 ]
@@ -295,6 +367,31 @@ function simple_cr_with_netdevice() {
 	check_pipes
 }
 
+@test "checkpoint --pre-dump-count and restore" {
+	# Requires kernel dirty memory tracking (missing on ARM, see
+	# https://github.com/checkpoint-restore/criu/issues/1729).
+	requires criu_feature_mem_dirty_track
+
+	setup_pipes
+	runc_run_with_pipes test_busybox
+
+	mkdir image-dir
+	mkdir work-dir
+	runc checkpoint --pre-dump-count 2 --pre-dump-interval 100ms --work-path ./work-dir --image-path ./image-dir test_busybox
+	[ "$status" -eq 0 ]
+
+	# check the pre-dumps are chained
+	[ -e ./image-dir/pre-dump-1 ]
+	[ -e ./image-dir/pre-dump-2/parent ]
+	[ "$(readlink ./image-dir/parent)" = "pre-dump-2" ]
+
+	# after checkpoint busybox is no longer running
+	testcontainer test_busybox checkpointed
+
+	runc_restore_with_pipes ./work-dir test_busybox
+	check_pipes
+}
+
 @test "checkpoint --lazy-pages and restore" {
 	# Requires lazy-pages support.
 	requires criu_feature_uffd-noncoop
@@ -363,6 +460,51 @@ function simple_cr_with_netdevice() {
 	check_pipes
 }
 
+@test "checkpoint --lazy-pages-server and restore --lazy-pages-server" {
+	# Requires lazy-pages support.
+	requires criu_feature_uffd-noncoop
+
+	setup_pipes
+	runc_run_with_pipes test_busybox
+
+	mkdir image-dir
+	mkdir work-dir
+
+	exec {pipe}<> <(:)
+	# shellcheck disable=SC2094
+	exec {lazy_r}</proc/self/fd/$pipe {lazy_w}>/proc/self/fd/$pipe
+	exec {pipe}>&-
+
+	port=27278
+
+	__runc checkpoint \
+		--lazy-pages-server 0.0.0.0:${port} \
+		--status-fd ${lazy_w} \
+		--manage-cgroups-mode=ignore \
+		--work-path ./work-dir \
+		--image-path ./image-dir \
+		test_busybox &
+	cpt_pid=$!
+
+	# wait for lazy page server to be ready
+	out=$(timeout 2 dd if=/proc/self/fd/${lazy_r} bs=1 count=1 2>/dev/null | od)
+	exec {lazy_r}>&-
+	exec {lazy_w}>&-
+	# shellcheck disable=SC2116,SC2086
+	out=$(echo $out) # rm newlines
+	[ "$out" = "0000000 000000 0000001" ]
+
+	# No external criu lazy-pages daemon is needed, runc starts it.
+	runc_restore_with_pipes ./image-dir test_busybox_restore \
+		--lazy-pages-server 127.0.0.1:${port} \
+		--manage-cgroups-mode=ignore
+
+	# The checkpoint completes once all the pages are transferred.
+	wait $cpt_pid
+
+	check_pipes
+}
+
 @test "checkpoint and restore in external network namespace" {
 	# Requires external network namespaces (criu >= 3.10).
 	requires criu_feature_external_net_ns
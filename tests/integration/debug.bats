@@ -71,3 +71,14 @@ function check_debug() {
 	[[ "${output}" == *'"level":"debug"'* ]]
 	check_debug "$output"
 }
+
+@test "global --log-format 'json' --correlation-id" {
+	runc --log log.out --log-format "json" --correlation-id "req-42" --debug run test_hello
+	[ "$status" -eq 0 ]
+
+	cat log.out >&2
+	# All the entries, including the ones of runc init, are tagged.
+	[ "$(grep -cv '"correlation_id":"req-42"' log.out)" -eq 0 ]
+	grep -q '"container":"test_hello",.*"msg":"nsexec' log.out
+	grep -q '"msg":"child process in init()","op":"run"' log.out
+}
@@ -135,6 +135,27 @@ function test_runc_delete_host_pidns() {
 }
 
 # Issue 4047, case "runc delete".
+@test "runc delete [created container]" {
+	[ $EUID -ne 0 ] && requires systemd
+	set_cgroups_path
+
+	runc create --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox created
+	init_pid=$(__runc state test_busybox | jq .pid)
+	cgpath=$(get_cgroup_path "pids")
+
+	runc delete test_busybox
+	[ "$status" -eq 0 ]
+
+	# The container cgroup must be gone once delete returns, and its init
+	# can only be a zombie waiting to be reaped.
+	[ ! -d "$cgpath" ]
+	wait_pids_gone 10 0.2 "$init_pid"
+	runc state test_busybox
+	[ "$status" -ne 0 ]
+}
+
 @test "runc delete [host pidns + init gone]" {
 	test_runc_delete_host_pidns
 }
@@ -95,3 +95,37 @@ function teardown() {
 	#
 	[ "$(wc -l <<<"$output")" -eq 4 ]
 }
+
+@test "env defaults if-missing [run and exec]" {
+	update_config ' .annotations += {"org.opencontainers.runc.env.defaults": "if-missing"}
+		| .process.env = ["FOO=bar"]
+		| .process.args = ["/bin/env"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"* ]]
+	[[ "$output" == *"HOME=/root"* ]]
+	[[ "$output" != *"TERM="* ]]
+
+	update_config ' .process.args = ["sleep", "1h"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_env
+	[ "$status" -eq 0 ]
+
+	# The exec processes get the same defaults, unless overridden.
+	runc exec --env HOME=/home test_env /bin/env
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"PATH=/usr/local/sbin"* ]]
+	[[ "$output" == *"HOME=/home"* ]]
+
+	runc exec --env-defaults always --env HOME=/home test_env /bin/env
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"HOME=/root"* ]]
+
+	runc exec --env-defaults never test_env /bin/env
+	[ "$status" -eq 0 ]
+	[[ "$output" != *"HOME="* ]]
+
+	runc exec --env-defaults sometimes test_env /bin/env
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid value for env-defaults"* ]]
+}
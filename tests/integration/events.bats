@@ -58,6 +58,24 @@ function test_events() {
 	[[ "${lines[0]}" == *"data"* ]]
 }
 
+@test "events --stats --annotations" {
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+	init_cgroup_paths
+
+	update_config '.annotations += {"com.example.team": "infra", "a=b": "c"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc events --stats --annotations test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(jq -c '.annotations | {"com.example.team", "a=b"}' <<<"${lines[0]}")" = '{"com.example.team":"infra","a=b":"c"}' ]
+
+	# Without --annotations, the events are not tagged.
+	runc events --stats test_busybox
+	[ "$status" -eq 0 ]
+	[[ "${lines[0]}" != *'"annotations"'* ]]
+}
+
 @test "events --stats with psi data" {
 	# XXX: CPU PSI avg data only available to root.
 	requires root cgroups_v2 psi
@@ -127,3 +145,25 @@ function test_events() {
 
 	grep -q '{"type":"oom","id":"test_busybox"}' events.log
 }
+
+@test "events exec" {
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	(__runc events test_busybox >events.log) &
+	(
+		retry 10 1 grep -q test_busybox events.log
+		__runc exec test_busybox sh -c 'exit 3'
+		retry 10 1 grep -q exec-exited events.log
+		__runc delete -f test_busybox
+	) &
+	wait # for both subshells to finish
+
+	run -0 jq -c 'select(.type == "exec-started") | .data | [.args, .user]' events.log
+	[ "$output" = '[["sh","-c","exit 3"],"0:0"]' ]
+	run -0 jq -c 'select(.type == "exec-exited") | .data | [.args, .exit_code, .duration > 0]' events.log
+	[ "$output" = '[["sh","-c","exit 3"],3,true]' ]
+}
@@ -265,6 +265,35 @@ function check_exec_debug() {
 	[[ "$output" == *":cpu"*":$REL_CGROUPS_PATH/subcpu"* ]]
 }
 
+@test "runc exec --cpus --memory [v1]" {
+	requires root cgroups_v1
+
+	set_cgroups_path
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	testcontainer test_busybox running
+
+	# The process is in a transient sub-cgroup, with its own limits.
+	runc exec --cpus 0.5 --memory 64m test_busybox cat /proc/self/cgroup
+	[ "$status" -eq 0 ]
+	[[ "$output" == *":memory:$REL_CGROUPS_PATH/runc-exec-"* ]]
+	[[ "$output" == *":cpu"*":$REL_CGROUPS_PATH/runc-exec-"* ]]
+
+	# It is removed once the process exits.
+	run ! compgen -G "$(get_cgroup_path memory)/runc-exec-*"
+
+	runc exec -d --memory 64m test_busybox sleep 1h
+	[ "$status" -eq 0 ]
+	run cat "$(get_cgroup_path memory)"/runc-exec-*/memory.limit_in_bytes
+	[ "$status" -eq 0 ]
+	[ "$output" = "67108864" ]
+
+	runc exec --memory 64m --cgroup / test_busybox true
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"can't be used together with --cgroup"* ]]
+}
+
 @test "runc exec --cgroup subcgroup [v2]" {
 	requires root cgroups_v2
 
@@ -366,3 +395,54 @@ EOF
 	[ "$status" -eq 0 ]
 	[ "${lines[0]}" = "/home/tempuser" ]
 }
+
+@test "runc state --exec-history" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox sh -c 'exit 3'
+	[ "$status" -eq 3 ]
+	runc exec -d test_busybox sleep 1d
+	[ "$status" -eq 0 ]
+
+	runc state --exec-history test_busybox
+	[ "$status" -eq 0 ]
+	run -0 jq -c '.execHistory[] | [.args, .user, .exit_code, .exited != null]' <<<"$output"
+	[ "${lines[0]}" = '[["sh","-c","exit 3"],"0:0",3,true]' ]
+	[ "${lines[1]}" = '[["sleep","1d"],"0:0",null,false]' ]
+
+	# The history is only shown with the option.
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	run -0 jq 'has("execHistory")' <<<"$output"
+	[ "$output" = "false" ]
+}
+
+@test "runc exec --wait-for-*" {
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec -d --wait-for-file /tmp/ready test_busybox sh -c 'sleep 1; touch /tmp/ready; sleep 1d'
+	[ "$status" -eq 0 ]
+	runc exec test_busybox test -e /tmp/ready
+	[ "$status" -eq 0 ]
+
+	runc exec -d --wait-for-exit-code "test -e /tmp/done" test_busybox sh -c 'sleep 1; touch /tmp/done; sleep 1d'
+	[ "$status" -eq 0 ]
+
+	# The process is killed if it is not ready in time.
+	runc exec -d --wait-for-file /tmp/never --wait-timeout 1s test_busybox sleep 2d
+	[ "$status" -eq 255 ]
+	[[ "$output" == *"not ready after 1s"* ]]
+	runc exec test_busybox sh -c 'ps | grep "[s]leep 2d"'
+	[ "$status" -eq 1 ]
+
+	# Or if it exits before.
+	runc exec -d --wait-for-file /tmp/never test_busybox true
+	[ "$status" -eq 255 ]
+	[[ "$output" == *"exited before it was ready"* ]]
+
+	runc exec --wait-for-file /tmp/ready test_busybox true
+	[ "$status" -eq 255 ]
+	[[ "$output" == *"require --detach"* ]]
+}
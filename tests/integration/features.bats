@@ -0,0 +1,37 @@
+#!/usr/bin/env bats
+
+load helpers
+
+@test "runc features" {
+	runc features
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .schemaVersion <<<"$output")" = "1" ]
+	# The host is only probed with --probe.
+	[ "$(jq 'has("host")' <<<"$output")" = "false" ]
+}
+
+@test "runc features --probe" {
+	runc features --probe
+	[ "$status" -eq 0 ]
+	[ "$(jq -r .host.kernel <<<"$output")" = "$(uname -r)" ]
+	[ "$(jq '.host.namespaces | index("mount") != null' <<<"$output")" = "true" ]
+	# The usable mount options are among the known ones.
+	[ "$(jq '.host.mountOptions - .mountOptions | length' <<<"$output")" -eq 0 ]
+
+	init_cgroup_paths
+	if [ -v CGROUP_V2 ]; then
+		[ "$(jq .host.cgroup.v2 <<<"$output")" = "true" ]
+	else
+		[ "$(jq .host.cgroup.v2 <<<"$output")" = "false" ]
+	fi
+
+	if command -v criu &>/dev/null; then
+		[ "$(jq .host.checkpoint.enabled <<<"$output")" = "true" ]
+	else
+		[ "$(jq .host.checkpoint.enabled <<<"$output")" = "false" ]
+	fi
+
+	if [ "$(jq '.linux.seccomp.enabled' <<<"$output")" = "true" ]; then
+		[ "$(jq '.host.seccomp.actions | index("SCMP_ACT_ALLOW") != null' <<<"$output")" = "true" ]
+	fi
+}
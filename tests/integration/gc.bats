@@ -0,0 +1,47 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	setup_busybox
+}
+
+function teardown() {
+	teardown_bundle
+}
+
+@test "runc gc" {
+	update_config '.process.args = ["/bin/true"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_stopped
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_stopped stopped
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_old
+	[ "$status" -eq 0 ]
+	wait_for_container 10 1 test_old stopped
+	# Pretend test_old was created before the host booted.
+	jq '.created = "2000-01-01T00:00:00Z"' "$ROOT/state/test_old/state.json" >"$ROOT/state.json"
+	mv "$ROOT/state.json" "$ROOT/state/test_old/state.json"
+	# A state directory without a state, and a stale kept network namespace.
+	mkdir "$ROOT/state/test_aborted"
+	mkdir -p "$ROOT/state/@netns"
+	touch "$ROOT/state/@netns/test_gone"
+
+	runc gc --dry-run --min-age 0 --format json
+	[ "$status" -eq 0 ]
+	[ "$(jq -r '[.[] | .kind + ":" + .id] | sort | join(" ")' <<<"$output")" = "container:test_aborted container:test_old netns:test_gone" ]
+	testcontainer test_old stopped
+
+	runc gc --min-age 0
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"test_old"*"created before the host booted"* ]]
+	runc state test_old
+	[ "$status" -ne 0 ]
+	[ ! -e "$ROOT/state/test_aborted" ]
+	[ ! -e "$ROOT/state/@netns/test_gone" ]
+	testcontainer test_stopped stopped
+
+	runc gc --stopped
+	[ "$status" -eq 0 ]
+	runc state test_stopped
+	[ "$status" -ne 0 ]
+}
@@ -0,0 +1,62 @@
+#!/usr/bin/env bats
+
+load helpers
+
+function setup() {
+	requires root cgroups_v2 cgroups_pids
+	setup_busybox
+	echo '{"pids": {"limit": 42}}' >"$BATS_RUN_TMPDIR"/group.json
+}
+
+function teardown() {
+	teardown_bundle
+	runc group delete test_group
+	rm -f "$BATS_RUN_TMPDIR"/group.json
+}
+
+@test "runc group create/update/stats/delete" {
+	runc group create -r "$BATS_RUN_TMPDIR"/group.json test_group
+	[ "$status" -eq 0 ]
+
+	runc group list
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"test_group"* ]]
+
+	# The group name must be unique.
+	runc group create test_group
+	[ "$status" -ne 0 ]
+
+	runc run -d --group test_group --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The container cgroup is a child of the group cgroup.
+	runc exec test_busybox cat /proc/self/cgroup
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"test_group/"* || "$output" == *"runc_group_test_group.slice/"* ]]
+
+	runc group stats test_group
+	[ "$status" -eq 0 ]
+	[ "$(jq '.pids.current' <<<"$output")" -gt 0 ]
+	[ "$(jq '.pids.limit' <<<"$output")" -eq 42 ]
+
+	echo '{"pids": {"limit": 43}}' >"$BATS_RUN_TMPDIR"/group.json
+	runc group update -r "$BATS_RUN_TMPDIR"/group.json test_group
+	[ "$status" -eq 0 ]
+	runc group stats test_group
+	[ "$status" -eq 0 ]
+	[ "$(jq '.pids.limit' <<<"$output")" -eq 43 ]
+
+	# A group with running members can not be deleted.
+	runc group delete test_group
+	[ "$status" -ne 0 ]
+
+	runc delete --force test_busybox
+	[ "$status" -eq 0 ]
+
+	runc group delete test_group
+	[ "$status" -eq 0 ]
+
+	runc group list
+	[ "$status" -eq 0 ]
+	[[ "$output" != *"test_group"* ]]
+}
@@ -591,6 +591,11 @@ function requires() {
 				skip_me=1
 			fi
 			;;
+		numa)
+			if [ ! -e "/proc/self/numa_maps" ] || [ ! -d "/sys/devices/system/node/node0" ]; then
+				skip_me=1
+			fi
+			;;
 		cgroups_v1)
 			init_cgroup_paths
 			if [ ! -v CGROUP_V1 ]; then
@@ -41,6 +41,52 @@ function teardown() {
 	done
 }
 
+@test "runc create [async createRuntime hook]" {
+	bundle=$(pwd)
+	# Gets the state, and tells it is ready, after a createRuntime hook
+	# which only succeeds if it is run in the meantime.
+	cat >"$bundle/async-hook.sh" <<-EOF
+		#!/bin/sh -e
+		printf state >&3
+		dd bs=4096 count=1 <&3 >"$bundle/async-state" 2>/dev/null
+		while [ ! -e "$bundle/sync-done" ]; do sleep 0.1; done
+		printf ready >&3
+	EOF
+	chmod +x "$bundle/async-hook.sh"
+	update_config --arg bundle "$bundle" '
+		.hooks |= {"createRuntime": [
+			{"path": ($bundle + "/async-hook.sh"), "timeout": 10},
+			{"path": "/bin/touch", "args": ["touch", ($bundle + "/sync-done")]}
+		]}
+		| .annotations += {"org.opencontainers.runc.hooks": "{\"createRuntime\": [{\"async\": true}]}"}'
+
+	runc create --console-socket "$CONSOLE_SOCKET" test_hooks
+	[ "$status" -eq 0 ]
+	testcontainer test_hooks created
+	[ "$(jq -r .status <"$bundle/async-state")" = "creating" ]
+
+	# The container is not created if the hook fails before it is ready.
+	runc delete --force test_hooks
+	update_config '.hooks.createRuntime[0] |= {"path": "/bin/false"}'
+	runc create --console-socket "$CONSOLE_SOCKET" test_hooks
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"error running createRuntime hook #0: hook exited before it was ready"* ]]
+}
+
+@test "runc create --progress json [hooks]" {
+	update_config '.hooks |= {"createRuntime": [{"path": "/bin/sleep", "args": ["sleep", "1.5"]}]}'
+
+	runc create --console-socket "$CONSOLE_SOCKET" --progress json --progress-fd 5 test_hooks 5>progress.json
+	[ "$status" -eq 0 ]
+	# A record when the hooks begin, then periodically, and a final one.
+	[ "$(jq -s 'map(select(.operation == "create" and .id == "test_hooks" and .phase == "hooks")) | length' <progress.json)" -ge 2 ]
+	[ "$(jq -s -c 'last | [.done, .percent]' <progress.json)" = "[true,100]" ]
+
+	runc create --console-socket "$CONSOLE_SOCKET" --progress yaml test_hooks2
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid --progress format"* ]]
+}
+
 # While runtime-spec does not say what environment variables hooks should have,
 # if not explicitly specified, historically the StartContainer hook inherited
 # the process environment specified for init.
@@ -92,7 +92,7 @@ test_host_pidns_kill() {
 	[ "$status" -ne 0 ]
 	[[ "$output" == *"container not running"* ]]
 
-	# Check that -a (now obsoleted) makes kill return no error for a stopped container.
+	# Check that -a makes kill return no error for a stopped container.
 	runc kill -a test_busybox 0
 	[ "$status" -eq 0 ]
 
@@ -100,6 +100,40 @@ test_host_pidns_kill() {
 	[ "$status" -eq 0 ]
 }
 
+@test "kill --all [host pidns]" {
+	requires cgroups_freezer root
+
+	update_config '	  .linux.namespaces -= [{"type": "pid"}]'
+	set_cgroups_path
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	for _ in 1 2 3; do
+		__runc exec -d test_busybox sleep 1h
+	done
+	cgpath=$(get_cgroup_path "pids")
+	mapfile -t pids < <(cat "$cgpath"/cgroup.procs)
+	[ ${#pids[@]} -eq 4 ]
+
+	runc kill --all test_busybox TERM
+	[ "$status" -eq 0 ]
+	wait_pids_gone 10 0.2 "${pids[@]}"
+	testcontainer test_busybox stopped
+}
+
+@test "kill --timeout" {
+	# A process which ignores SIGTERM.
+	update_config '.process.args = ["sh", "-c", "trap \"\" TERM; while :; do sleep 1; done"]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox running
+
+	runc kill --all --timeout 1s test_busybox TERM
+	[ "$status" -eq 0 ]
+	testcontainer test_busybox stopped
+}
+
 # This is roughly the same as TestPIDHostInitProcessWait in libcontainer/integration.
 # The differences are:
 #
@@ -52,3 +52,33 @@ function teardown() {
 	[[ "${lines[0]}" == *[,][\{]"\"ociVersion\""[:]"\""*[0-9][\.]*[0-9][\.]*[0-9]*"\""[,]"\"id\""[:]"\"test_box2\""[,]"\"pid\""[:]*[0-9][,]"\"status\""[:]*"\"running\""[,]"\"bundle\""[:]*$bundle*[,]"\"rootfs\""[:]"\""*"\""[,]"\"created\""[:]*[0-9]*[\}]* ]]
 	[[ "${lines[0]}" == *[,][\{]"\"ociVersion\""[:]"\""*[0-9][\.]*[0-9][\.]*[0-9]*"\""[,]"\"id\""[:]"\"test_box3\""[,]"\"pid\""[:]*[0-9][,]"\"status\""[:]*"\"running\""[,]"\"bundle\""[:]*$bundle*[,]"\"rootfs\""[:]"\""*"\""[,]"\"created\""[:]*[0-9]*[\}][\]] ]]
 }
+
+@test "list --filter --sort --format" {
+	update_config '.annotations += {"app": "web"}'
+	ROOT=$ALT_ROOT runc run -d --console-socket "$CONSOLE_SOCKET" test_box2
+	[ "$status" -eq 0 ]
+
+	update_config '.annotations.app = "db"'
+	ROOT=$ALT_ROOT runc create --console-socket "$CONSOLE_SOCKET" test_box1
+	[ "$status" -eq 0 ]
+
+	ROOT=$ALT_ROOT runc list -q --sort created
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "test_box2" ]
+	[ "${lines[1]}" = "test_box1" ]
+
+	ROOT=$ALT_ROOT runc list -q --filter status=running
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_box2" ]
+
+	ROOT=$ALT_ROOT runc list --filter label=app=db --format '{{.ID}} {{.Status}} {{.Labels.app}}'
+	[ "$status" -eq 0 ]
+	[ "$output" = "test_box1 created db" ]
+
+	ROOT=$ALT_ROOT runc list -q --filter label=app --filter status=paused
+	[ "$status" -eq 0 ]
+	[ "$output" = "" ]
+
+	ROOT=$ALT_ROOT runc list --filter name=test_box1
+	[ "$status" -ne 0 ]
+}
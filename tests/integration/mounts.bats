@@ -247,6 +247,32 @@ function test_mount_order() {
 	test_ro_cgroup_mount
 }
 
+@test "runc run [ro /sys + writable paths]" {
+	# Make /sys rw in the config, to check it is made read-only.
+	update_config '   .mounts |= map((select(.destination == "/sys") | .options -= ["ro"]) // .)
+			| .annotations += {"org.opencontainers.runc.sysfs.writable": "/sys/kernel/mm,/sys/fs/cgroup"}
+			| .process.args |= ["sh", "-euc", "for f in /sys /sys/kernel/mm /sys/fs/cgroup; do grep \" $f \" /proc/mounts | tail -n1; done"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "${#lines[@]}" -eq 3 ]
+	[[ "${lines[0]}" == *' ro,'* ]]
+	[[ "${lines[1]}" == *' rw,'* ]]
+	[[ "${lines[2]}" == *' rw,'* ]]
+}
+
+@test "runc run [machine-id + virtual boot_id]" {
+	update_config '   .annotations += {"org.opencontainers.runc.machine-id": "0123456789abcdef0123456789abcdef", "org.opencontainers.runc.boot-id.virtual": "true"}
+			| .process.args |= ["sh", "-euc", "cat /etc/machine-id /proc/sys/kernel/random/boot_id; echo x > /etc/machine-id || echo ro"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "0123456789abcdef0123456789abcdef" ]
+	[[ "${lines[1]}" =~ ^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$ ]]
+	[ "${lines[1]}" != "$(cat /proc/sys/kernel/random/boot_id)" ]
+	[ "${lines[-1]}" = "ro" ]
+}
+
 @test "runc run [mount order, container bind-mount source]" {
 	test_mount_order
 }
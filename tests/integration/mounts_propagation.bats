@@ -20,3 +20,17 @@ function teardown() {
 	[ "$status" -eq 0 ]
 	[ "$output" = "shared" ]
 }
+
+@test "runc run [propagation paths]" {
+	mkdir -p rootfs/mnt/sub
+	update_config '.annotations += {"org.opencontainers.runc.propagation.paths": "{\"/mnt\": \"rshared\", \"/mnt/sub\": \"slave\"}"}
+		| .root.readonly = true
+		| .process.args = ["findmnt", "--noheadings", "-o", "TARGET,PROPAGATION,OPTIONS", "-R", "/mnt"]'
+
+	runc run test_propagation_paths
+	[ "$status" -eq 0 ]
+	# The paths, which are not mount points, are bind mounted onto
+	# themselves, read-only as the rootfs is.
+	[[ "${lines[0]}" == "/mnt "*" shared "*"ro,"* ]]
+	[[ "${lines[1]}" == *"/mnt/sub "*" private,slave "*"ro,"* ]]
+}
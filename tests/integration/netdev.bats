@@ -214,3 +214,52 @@ function teardown() {
 	[[ "$output" == *"ether $mac_address "* ]]
 	[[ "$output" == *"mtu $mtu_value "* ]]
 }
+
+@test "move network device to container network namespace and configure it with the netdevices annotation" {
+	update_config ' .linux.netDevices |= { "dummy0": { "name" : "ctr_dummy0" } }
+		| .annotations["org.opencontainers.runc.netdevices"] |= "{\"dummy0\": {\"mtu\": 1789, \"hardwareAddress\": \"00:11:22:33:44:55\", \"txQueueLen\": 77}}"
+		| .process.args |= ["ip", "link", "show", "dev", "ctr_dummy0"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"mtu 1789 "* ]]
+	[[ "$output" == *"qlen 77"* ]]
+	[[ "$output" == *"ether 00:11:22:33:44:55 "* ]]
+}
+
+@test "network device stats" {
+	update_config ' .linux.netDevices |= { "dummy0": { "name" : "ctr_dummy0" } }'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc events --stats test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$(jq -r '.data.network_interfaces[0].Name' <<<"$output")" == "ctr_dummy0" ]]
+}
+
+@test "keep the network namespace and its network devices across container recreate" {
+	update_config ' .linux.netDevices |= { "dummy0": { "name" : "ctr_dummy0" } }
+		| .annotations["org.opencontainers.runc.netns.keep"] |= "true"'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	netns=$(readlink "/proc/$(__runc state test_busybox | jq .pid)/ns/net")
+
+	runc delete --force test_busybox
+	[ "$status" -eq 0 ]
+
+	# The device is not in the host network namespace.
+	run ! ip link show dev dummy0
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	[ "$(readlink "/proc/$(__runc state test_busybox | jq .pid)/ns/net")" = "$netns" ]
+
+	runc exec test_busybox ip link show dev ctr_dummy0
+	[ "$status" -eq 0 ]
+
+	runc delete --force --remove-netns test_busybox
+	[ "$status" -eq 0 ]
+	[ ! -e "$ROOT/state/@netns/test_busybox" ]
+}
@@ -235,3 +235,43 @@ EOF
 	[ "$status" -eq 0 ]
 	[ "${lines[0]}" = "/home/tempuser" ]
 }
+
+@test "runc run --forward-signal --no-forward-signals" {
+	# shellcheck disable=SC2016
+	update_config '   .annotations += {"org.opencontainers.runc.signals.no-forward": "SIGUSR1"}
+			| .process.args |= ["sh", "-c", "trap \"echo HUP\" HUP; trap \"echo USR1\" USR1; trap \"echo INT; exit 0\" INT; echo ready; while :; do sleep 0.1; done"]'
+
+	__runc run --forward-signal SIGTERM=SIGINT --no-forward-signals HUP test_busybox >run.log 2>&1 &
+	runc_pid=$!
+	retry 10 1 grep -q ready run.log
+	kill -HUP "$runc_pid"
+	kill -USR1 "$runc_pid"
+	kill -TERM "$runc_pid"
+	wait "$runc_pid"
+
+	run -0 cat run.log
+	[ "${lines[-1]}" = "INT" ]
+	! grep -q -e HUP -e USR1 run.log
+}
+
+@test "runc run [ipc limits]" {
+	update_config '   .annotations += {"org.opencontainers.runc.ipc.limits": "msgmax=64k,semmni=256,mqueue.msg_max=100,mqueue.msg_default=50"}
+			| .process.args |= ["sh", "-c", "cat /proc/sys/kernel/msgmax /proc/sys/kernel/sem /proc/sys/fs/mqueue/msg_max /proc/sys/fs/mqueue/msg_default"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[ "${lines[0]}" = "65536" ]
+	[[ "${lines[1]}" =~ [[:space:]]256$ ]]
+	[ "${lines[2]}" = "100" ]
+	[ "${lines[3]}" = "50" ]
+}
+
+@test "runc run [memory policy]" {
+	requires numa
+	update_config '   .annotations += {"org.opencontainers.runc.memory.policy": "{\"mode\": \"MPOL_BIND\", \"nodes\": \"0\", \"flags\": [\"MPOL_F_STATIC_NODES\"]}"}
+			| .process.args |= ["sh", "-c", "head -n 1 /proc/self/numa_maps"]'
+
+	runc run test_busybox
+	[ "$status" -eq 0 ]
+	[[ "$output" == *" bind=static:0 "* ]]
+}
@@ -66,6 +66,77 @@ function teardown() {
 	[[ "$output" == *"Network is down"* ]]
 }
 
+@test "runc exec --seccomp-profile" {
+	update_config '   .process.args = ["/bin/sleep", "1d"]
+			| .process.noNewPrivileges = false
+			| .linux.seccomp = {
+				"defaultAction":"SCMP_ACT_ALLOW",
+				"architectures":["SCMP_ARCH_X86","SCMP_ARCH_X32","SCMP_ARCH_X86_64","SCMP_ARCH_AARCH64","SCMP_ARCH_ARM"],
+				"syscalls":[{"names":["mkdir","mkdirat"], "action":"SCMP_ACT_ERRNO"}]
+			}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox mkdir /dev/shm/foo
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Operation not permitted"* ]]
+
+	# Replace the container profile with a looser one.
+	echo '{"defaultAction":"SCMP_ACT_ALLOW"}' >"$BATS_RUN_TMPDIR/seccomp.json"
+	runc exec --seccomp-profile "$BATS_RUN_TMPDIR/seccomp.json" test_busybox mkdir /dev/shm/foo
+	[ "$status" -eq 0 ]
+
+	# Or with a tighter one.
+	echo '{"defaultAction":"SCMP_ACT_ALLOW", "syscalls":[{"names":["rmdir","unlinkat"], "action":"SCMP_ACT_ERRNO", "errnoRet": 100}]}' >"$BATS_RUN_TMPDIR/seccomp.json"
+	runc exec --seccomp-profile "$BATS_RUN_TMPDIR/seccomp.json" test_busybox rmdir /dev/shm/foo
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Network is down"* ]]
+}
+
+@test "runc update --exec-seccomp-add" {
+	update_config '   .process.args = ["/bin/sleep", "1d"]
+			| .process.noNewPrivileges = false
+			| .linux.seccomp = {
+				"defaultAction":"SCMP_ACT_ALLOW",
+				"architectures":["SCMP_ARCH_X86","SCMP_ARCH_X32","SCMP_ARCH_X86_64","SCMP_ARCH_AARCH64","SCMP_ARCH_ARM"],
+				"syscalls":[{"names":["mkdir","mkdirat"], "action":"SCMP_ACT_ERRNO"}]
+			}'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+
+	runc exec test_busybox touch /dev/shm/foo
+	[ "$status" -eq 0 ]
+
+	echo '{"defaultAction":"SCMP_ACT_ALLOW", "syscalls":[{"names":["unlink","unlinkat"], "action":"SCMP_ACT_ERRNO", "errnoRet": 100}]}' >"$BATS_RUN_TMPDIR/seccomp.json"
+	runc update --exec-seccomp-add "$BATS_RUN_TMPDIR/seccomp.json" test_busybox
+	[ "$status" -eq 0 ]
+
+	# The stacked profile is recorded in the state.
+	[ "$(jq '.config.exec_seccomp | length' "$ROOT/state/test_busybox/state.json")" -eq 1 ]
+
+	# Both profiles apply to the new processes.
+	runc exec test_busybox rm /dev/shm/foo
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Network is down"* ]]
+	runc exec test_busybox mkdir /dev/shm/bar
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Operation not permitted"* ]]
+
+	# Even with a process profile replacing the container one.
+	echo '{"defaultAction":"SCMP_ACT_ALLOW"}' >"$BATS_RUN_TMPDIR/seccomp-allow.json"
+	runc exec --seccomp-profile "$BATS_RUN_TMPDIR/seccomp-allow.json" test_busybox rm /dev/shm/foo
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"Network is down"* ]]
+
+	# A stacked profile can't use the notify action.
+	echo '{"defaultAction":"SCMP_ACT_ALLOW", "listenerPath": "/run/agent.sock", "syscalls":[{"names":["mkdir"], "action":"SCMP_ACT_NOTIFY"}]}' >"$BATS_RUN_TMPDIR/seccomp.json"
+	runc update --exec-seccomp-add "$BATS_RUN_TMPDIR/seccomp.json" test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"notify action"* ]]
+}
+
 # Prints the numeric value of provided seccomp flags combination.
 # The parameter is flags string, as supplied in OCI spec, for example
 # '"SECCOMP_FILTER_FLAG_TSYNC","SECCOMP_FILTER_FLAG_LOG"'.
@@ -39,3 +39,18 @@ function teardown() {
 
 	./validate config-schema.json ../../config.json
 }
+
+@test "spec --validate" {
+	runc spec --validate
+	[ "$status" -eq 0 ]
+	[ "$(jq length <<<"$output")" -eq 0 ]
+
+	# Several problems are reported at once.
+	update_config '	  .linux.namespaces -= [{"type": "uts"}, {"type": "network"}]
+			| .linux.sysctl = {"net.ipv4.ip_forward": "1"}'
+	runc spec --validate
+	[ "$status" -ne 0 ]
+	[[ "$output" == *'"path": "linux.namespaces"'* ]]
+	[[ "$output" == *'"path": "linux.sysctl"'* ]]
+	[[ "$output" == *"2 error(s) found"* ]]
+}
@@ -61,3 +61,36 @@ function teardown() {
 	# test state of busybox is back to running
 	testcontainer test_busybox running
 }
+
+@test "state cgroup (pause + kill)" {
+	# XXX: pause and resume require cgroups.
+	requires root
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -eq 0 ]
+	runc exec -d test_busybox sleep 1d
+	[ "$status" -eq 0 ]
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	run -0 jq -c '.cgroup' <<<"$output"
+	[ "$output" = '{"freezer":"thawed","processes":2}' ]
+
+	runc pause test_busybox
+	[ "$status" -eq 0 ]
+	# Kill the exec process directly, so that it stays frozen.
+	runc ps -f json test_busybox
+	[ "$status" -eq 0 ]
+	pid=$(jq '.[1]' <<<"$output")
+	kill -KILL "$pid"
+
+	runc state test_busybox
+	[ "$status" -eq 0 ]
+	run -0 jq -c '.cgroup' <<<"$output"
+	[ "$output" = '{"freezer":"frozen","processes":2,"pendingFatalSignals":[{"pid":'"$pid"',"signals":["SIGKILL"]}]}' ]
+
+	runc resume test_busybox
+	[ "$status" -eq 0 ]
+	# The killed process exits once thawed.
+	retry 10 0.1 eval '[ "$(__runc state test_busybox | jq .cgroup.processes)" = "1" ]'
+}
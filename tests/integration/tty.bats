@@ -250,3 +250,20 @@ function teardown() {
 	runc kill test_busybox KILL
 	[ "$status" -eq 0 ]
 }
+
+@test "runc run [--console-log]" {
+	update_config '.process.args = ["sh", "-c", "echo hello from the console; sleep 1"]'
+
+	runc run -d --console-log "$ROOT/console.log" test_busybox
+	[ "$status" -eq 0 ]
+
+	# Wait for the container to exit, and the console logger to write the log.
+	wait_for_container 10 1 test_busybox stopped
+	retry 10 1 grep -q "hello from the console" "$ROOT/console.log"
+
+	# The console log can't be used along with a console socket.
+	runc delete test_busybox
+	runc run -d --console-log "$ROOT/console.log" --console-socket "$CONSOLE_SOCKET" test_busybox
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"cannot use both console socket and console log"* ]]
+}
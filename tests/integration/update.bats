@@ -809,12 +809,15 @@ EOF
 	cat "$CONTAINER_OUTPUT"
 	[ "$status" -eq 0 ]
 
-	# Trigger an update. This update doesn't actually change the device rules,
-	# but it will trigger the devices cgroup code to reapply the current rules.
+	# Trigger an update. These updates change an unrelated device rule,
+	# which makes the devices cgroup code reapply the current rules.
 	# We trigger the update a few times to make sure we hit the race.
-	for _ in {1..30}; do
-		# TODO: Update "runc update" so we can change the device rules.
-		runc update --pids-limit 30 test_update
+	for i in {1..30}; do
+		if ((i % 2)); then
+			runc update --device-add 'b 7:0 rw' test_update
+		else
+			runc update --device-remove 'b 7:0 rw' test_update
+		fi
 		[ "$status" -eq 0 ]
 	done
 
@@ -826,6 +829,33 @@ EOF
 	[ -z "$(<"$CONTAINER_OUTPUT")" ]
 }
 
+@test "update devices via --device-add and --device-remove" {
+	requires root
+
+	update_config '.linux.devices = [{"path": "/dev/kmsg", "type": "c", "major": 1, "minor": 11}]'
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+
+	runc exec test_update sh -c 'echo "runc: device update test" > /dev/kmsg'
+	[ "$status" -ne 0 ]
+
+	runc update --device-add 'c 1:11 w' test_update
+	[ "$status" -eq 0 ]
+	runc exec test_update sh -c 'echo "runc: device update test" > /dev/kmsg'
+	[ "$status" -eq 0 ]
+
+	runc update --device-remove 'c 1:11 w' test_update
+	[ "$status" -eq 0 ]
+	runc exec test_update sh -c 'echo "runc: device update test" > /dev/kmsg'
+	[ "$status" -ne 0 ]
+
+	# The rule is no longer there.
+	runc update --device-remove 'c 1:11 w' test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"not found"* ]]
+}
+
 @test "update paused container" {
 	requires cgroups_freezer
 	[ $EUID -ne 0 ] && requires rootless_cgroup
@@ -954,3 +984,54 @@ EOF
 	[ "$status" -eq 0 ]
 	check_cgroup_dev_iops "$dev" 10485760 9437184 1000 900
 }
+
+@test "update cgroup v2 cpuset partition" {
+	requires cgroups_v2 cgroups_cpuset
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "cpuset.cpus.partition" "member"
+
+	runc update --cpuset-partition foo test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid value for cpuset-partition"* ]]
+
+	runc update --cpuset-partition member test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "cpuset.cpus.partition" "member"
+}
+
+@test "update cgroup v2 swap high and zswap" {
+	requires cgroups_v2 cgroups_swap
+	[ $EUID -ne 0 ] && requires rootless_cgroup
+
+	update_config '.linux.resources.unified |= {"memory.swap.high": "10485760"}'
+	runc run -d --console-socket "$CONSOLE_SOCKET" test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "memory.swap.high" 10485760
+
+	runc update --memory-swap-high 20M test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "memory.swap.high" 20971520
+
+	runc update --memory-swap-high -1 test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "memory.swap.high" max
+
+	runc update --zswap-writeback maybe test_update
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"invalid value for zswap-writeback"* ]]
+
+	if [ ! -e "$(get_cgroup_path memory)/memory.zswap.max" ]; then
+		return
+	fi
+	runc update --zswap-max 1M --zswap-writeback false test_update
+	[ "$status" -eq 0 ]
+	check_cgroup_value "memory.zswap.max" 1048576
+	check_cgroup_value "memory.zswap.writeback" 0
+
+	runc events --stats test_update
+	[ "$status" -eq 0 ]
+	[ "$(jq .data.memory.zswap.writeback <<<"$output")" = "false" ]
+}
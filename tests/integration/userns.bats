@@ -247,6 +247,45 @@ function teardown() {
 	[[ "$output" == "$netns_id" ]]
 }
 
+@test "userns join other container userns [namespace owner]" {
+	requires root
+
+	update_config '.process.args = ["sleep", "infinity"]'
+	runc run -d --console-socket "$CONSOLE_SOCKET" target_userns
+	[ "$status" -eq 0 ]
+
+	target_pid="$(__runc state target_userns | jq .pid)"
+	userns_path=$(mktemp "$BATS_RUN_TMPDIR/userns.XXXXXX")
+	mount --bind "/proc/$target_pid/ns/user" "$userns_path"
+	echo "$userns_path" >>"$to_umount_list"
+	userns_inode="$(stat -c "%i" "$userns_path")"
+	# The owning user namespace of a user namespace is its parent.
+	parent_inode="$(stat -L -c "%i" /proc/self/ns/user)"
+
+	update_config '.linux.namespaces |= map(if .type == "user" then (.path = "'"$userns_path"'") else . end)
+		| del(.linux.uidMappings)
+		| del(.linux.gidMappings)
+		| .annotations["org.opencontainers.runc.namespaces.owner"] = "{\"user\": {\"uid\": 0, \"userns_inode\": '"$parent_inode"', \"inode\": '"$userns_inode"'}}"'
+	runc run -d --console-socket "$CONSOLE_SOCKET" in_userns
+	[ "$status" -eq 0 ]
+
+	runc exec in_userns readlink /proc/self/ns/user
+	[ "$status" -eq 0 ]
+	[[ "$output" == "user:[$userns_inode]" ]]
+
+	# A namespace with an unexpected owner is not joined.
+	update_config '.annotations["org.opencontainers.runc.namespaces.owner"] = "{\"user\": {\"uid\": 1234}}"'
+	runc run -d --console-socket "$CONSOLE_SOCKET" wrong_owner
+	[ "$status" -ne 0 ]
+	[[ "$output" == *"owner UID is 0, expected 1234"* ]]
+
+	# Unless the check only warns.
+	update_config '.annotations["org.opencontainers.runc.namespaces.owner.check"] = "warn"'
+	runc run -d --console-socket "$CONSOLE_SOCKET" wrong_owner
+	[ "$status" -eq 0 ]
+	[[ "$output" == *"owner UID is 0, expected 1234"* ]]
+}
+
 @test "userns with network interface" {
 	requires root
 
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/internal/trace"
+)
+
+// tracedCommands are the commands which are traced, if the --otel-endpoint
+// option is set.
+var tracedCommands = []string{"create", "delete", "exec", "run", "start"}
+
+// traceSpan is the span of the command being run.
+var traceSpan *trace.Span
+
+// configTracing enables the tracer according to the global options.
+func configTracing(context *cli.Context) error {
+	if endpoint := context.GlobalString("otel-endpoint"); endpoint != "" {
+		return trace.Enable(endpoint, context.App.Version)
+	}
+	return nil
+}
+
+// traceCommands makes the commands listed in tracedCommands trace their
+// execution.
+func traceCommands(commands []cli.Command) {
+	for i, cmd := range commands {
+		for _, name := range tracedCommands {
+			if cmd.Name != name {
+				continue
+			}
+			action := cmd.Action.(func(*cli.Context) error)
+			commands[i].Action = func(context *cli.Context) error {
+				traceSpan = trace.Start("runc " + context.Command.Name)
+				traceSpan.SetAttribute("container.id", context.Args().First())
+				err := action(context)
+				traceEnd(err)
+				return err
+			}
+		}
+	}
+}
+
+// traceEnd ends the span of the command being run, if any, with the command
+// result (err), and exports the spans. Like auditEnd, it is called once the
+// command completes, either by returning, or by exiting.
+func traceEnd(err error) {
+	if traceSpan == nil {
+		return
+	}
+	traceSpan.End(err)
+	traceSpan = nil
+	if err := trace.Flush(); err != nil {
+		logrus.Warnf("unable to export traces: %v", err)
+	}
+}
@@ -0,0 +1,6 @@
+// Package daemon is the gRPC API served by "runc daemon", generated from
+// runtime.proto (which requires protoc, protoc-gen-go, and
+// protoc-gen-go-grpc).
+package daemon
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative runtime.proto
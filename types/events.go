@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/opencontainers/cgroups"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
 )
@@ -10,6 +12,74 @@ type Event struct {
 	Type string `json:"type"`
 	ID   string `json:"id"`
 	Data any    `json:"data,omitempty"`
+	// Annotations are the OCI spec annotations of the container, with
+	// "runc events --annotations".
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Drift is the data of a "drift" event, sent when the list of cgroup
+// controllers used by the container that are no longer available changes.
+type Drift struct {
+	Controllers []string `json:"controllers"`
+}
+
+// PSITrigger is the data of a "psi" event, sent when a pressure stall
+// information threshold is crossed.
+type PSITrigger struct {
+	// Trigger is the threshold crossed, in the same format as the
+	// "runc events --psi-trigger" option (e.g. "memory:some:150ms/1s").
+	Trigger string `json:"trigger"`
+}
+
+// MemoryEvents is the data of a "memory-events" event, sent when the cgroup
+// v2 memory events counters of the container change.
+type MemoryEvents struct {
+	// Local is whether the counters are of the container cgroup only
+	// (memory.events.local), rather than of its subtree (memory.events).
+	Local    bool                 `json:"local,omitempty"`
+	Counters MemoryEventsCounters `json:"counters"`
+	// Delta is how much the counters have increased since the previous
+	// "memory-events" event.
+	Delta MemoryEventsCounters `json:"delta"`
+}
+
+// MemoryEventsCounters is the counters of a memory.events file.
+type MemoryEventsCounters struct {
+	Low          uint64 `json:"low"`
+	High         uint64 `json:"high"`
+	Max          uint64 `json:"max"`
+	OOM          uint64 `json:"oom"`
+	OOMKill      uint64 `json:"oom_kill"`
+	OOMGroupKill uint64 `json:"oom_group_kill"`
+}
+
+// Exec is the data of an "exec-started" or "exec-exited" event, sent when a
+// process is executed in the container, or such a process exits.
+type Exec struct {
+	Time time.Time `json:"time"`
+	Pid  int       `json:"pid"`
+	Args []string  `json:"args,omitempty"`
+	// User is the user the process is run as, in the "uid:gid" format.
+	User string `json:"user"`
+	// ExitCode is the process exit code, for "exec-exited".
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Duration is how long the process has run (in nanoseconds), for
+	// "exec-exited".
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// Probe is the data of a "probe" event, sent when the status of a container
+// probe changes (see "runc probe").
+type Probe struct {
+	Type   string   `json:"type"`
+	Args   []string `json:"args"`
+	Status string   `json:"status"`
+	// Failures is the number of consecutive failed checks.
+	Failures int `json:"failures"`
+	// ExitCode is the probe command exit code, for the last check.
+	ExitCode *int `json:"exit_code,omitempty"`
+	// Error is why the last check could not be done, or timed out.
+	Error string `json:"error,omitempty"`
 }
 
 // Stats is the runc specific stats structure for stability when encoding and decoding stats.
@@ -22,6 +92,22 @@ type Stats struct {
 	Hugetlb           map[string]Hugetlb  `json:"hugetlb"`
 	IntelRdt          IntelRdt            `json:"intel_rdt"`
 	NetworkInterfaces []*NetworkInterface `json:"network_interfaces"`
+	// Helpers are the stats of the helper processes runc runs on behalf
+	// of the container, which are also a part of the container stats.
+	Helpers *Stats `json:"helpers,omitempty"`
+	// Rootfs is the disk usage of the root filesystem, if it has a quota.
+	Rootfs *RootfsQuota `json:"rootfs,omitempty"`
+}
+
+// RootfsQuota is the disk usage of the container root filesystem project,
+// and its limits (zero meaning no limit).
+type RootfsQuota struct {
+	ProjectID uint32 `json:"project_id"`
+	// Limit and Usage are in bytes.
+	Limit       uint64 `json:"limit,omitempty"`
+	Usage       uint64 `json:"usage"`
+	InodesLimit uint64 `json:"inodes_limit,omitempty"`
+	InodesUsage uint64 `json:"inodes_usage"`
 }
 
 type PSIData = cgroups.PSIData
@@ -109,6 +195,25 @@ type Memory struct {
 	KernelTCP MemoryEntry       `json:"kernelTCP,omitempty"`
 	Raw       map[string]uint64 `json:"raw,omitempty"`
 	PSI       *PSIStats         `json:"psi,omitempty"`
+	// SwapHigh is the swap usage throttle limit (cgroup v2 only).
+	SwapHigh   uint64      `json:"swapHigh,omitempty"`
+	SwapEvents *SwapEvents `json:"swapEvents,omitempty"`
+	Zswap      *Zswap      `json:"zswap,omitempty"`
+}
+
+// SwapEvents are the counters of the cgroup v2 swap events.
+type SwapEvents struct {
+	High uint64 `json:"high"`
+	Max  uint64 `json:"max"`
+	Fail uint64 `json:"fail"`
+}
+
+// Zswap is the usage of the zswap compressed pool, and its settings
+// (cgroup v2 only).
+type Zswap struct {
+	Usage     uint64 `json:"usage"`
+	Limit     uint64 `json:"limit,omitempty"`
+	Writeback bool   `json:"writeback"`
 }
 
 type L3CacheInfo struct {
@@ -163,3 +268,18 @@ type NetworkInterface struct {
 	TxErrors  uint64
 	TxDropped uint64
 }
+
+// Propagation is the data of a "propagation" event, sent when the mount
+// propagation of a shared bind mount of the container breaks, is working
+// again, or is re-established.
+type Propagation struct {
+	Destination string `json:"destination"`
+	Source      string `json:"source"`
+	Broken      bool   `json:"broken"`
+	// Reason is why the propagation is broken.
+	Reason string `json:"reason,omitempty"`
+	// Remediated is whether the broken propagation has been re-established,
+	// and Error is why it could not be.
+	Remediated bool   `json:"remediated,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
@@ -1,6 +1,126 @@
-// Package features provides the annotations for [github.com/opencontainers/runtime-spec/specs-go/features].
+// Package features provides the annotations for [github.com/opencontainers/runtime-spec/specs-go/features],
+// and the [Features] structure printed by "runc features".
 package features
 
+import (
+	"encoding/json"
+
+	"github.com/opencontainers/runtime-spec/specs-go/features"
+)
+
+// SchemaVersion is the version of the runc specific part of [Features].
+// It is incremented whenever a change is made to it that is not backward
+// compatible; new fields may be added without changing it.
+const SchemaVersion = "1"
+
+// Features is the output of "runc features". It is a superset of the
+// runtime-spec features structure, and can be parsed as such.
+type Features struct {
+	features.Features
+
+	// SchemaVersion is the version of the runc specific fields, which
+	// is [SchemaVersion] for this version of the structure.
+	SchemaVersion string `json:"schemaVersion"`
+
+	// Extensions is a map of capabilities advertised by downstream builds
+	// of runc (e.g. for vendored patches). The keys are in the reverse
+	// domain notation (e.g. "com.example.feature"), and the values are
+	// arbitrary JSON defined by the extension owner.
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+
+	// EnvDefaults is the support of the policies of the default
+	// environment variables of the container processes.
+	EnvDefaults *EnvDefaults `json:"envDefaults,omitempty"`
+
+	// Host is the subset of the features which is actually usable on the
+	// host, as probed at runtime ("runc features --probe"), or nil if it
+	// was not probed. The other fields only tell the features runc knows
+	// of, whether or not the host supports them.
+	Host *Host `json:"host,omitempty"`
+}
+
+// EnvDefaults is the support of the policies of the default environment
+// variables of the container processes, set by the
+// "org.opencontainers.runc.env.defaults" annotation for all the processes,
+// or "runc exec --env-defaults" for one. With no policy set, runc only adds
+// HOME if it is missing or empty.
+type EnvDefaults struct {
+	// Policies are the supported policies, e.g. "if-missing".
+	Policies []string `json:"policies"`
+	// Variables are the default variables with a fixed value, e.g.
+	// "PATH". HOME is the home directory of the process user in the
+	// container /etc/passwd (or "/"), and TERM is only added for a process
+	// with a terminal.
+	Variables map[string]string `json:"variables"`
+}
+
+// Host is the subset of the features usable on the host.
+type Host struct {
+	// Kernel is the release of the host kernel, e.g. "6.1.0".
+	Kernel string `json:"kernel"`
+
+	// Namespaces are the namespaces, in the runtime-spec names, which the
+	// kernel supports.
+	Namespaces []string `json:"namespaces"`
+
+	// MountOptions are the mount options which the kernel supports. The
+	// options which depend on the filesystem may still fail.
+	MountOptions []string `json:"mountOptions"`
+
+	// IDMap tells whether the kernel supports the idmapped mounts. Those
+	// also depend on the filesystem.
+	IDMap bool `json:"idmap"`
+
+	// Cgroup is the cgroup setup of the host.
+	Cgroup *HostCgroup `json:"cgroup,omitempty"`
+
+	// Seccomp is the seccomp support of the kernel and libseccomp, or nil
+	// if runc is built without seccomp.
+	Seccomp *HostSeccomp `json:"seccomp,omitempty"`
+
+	// Apparmor tells whether AppArmor is enabled.
+	Apparmor bool `json:"apparmor"`
+
+	// Selinux tells whether SELinux is enabled.
+	Selinux bool `json:"selinux"`
+
+	// IntelRdt tells whether Intel RDT (CAT or MBA) is enabled.
+	IntelRdt bool `json:"intelRdt"`
+
+	// Checkpoint is the checkpoint/restore support.
+	Checkpoint *HostCheckpoint `json:"checkpoint"`
+}
+
+// HostCgroup is the cgroup setup of the host.
+type HostCgroup struct {
+	// V2 tells whether the host is in the cgroup v2 unified mode, rather
+	// than cgroup v1 (or hybrid).
+	V2 bool `json:"v2"`
+	// Systemd tells whether the host runs systemd, for the systemd cgroup
+	// driver.
+	Systemd bool `json:"systemd"`
+	// Controllers are the enabled cgroup controllers.
+	Controllers []string `json:"controllers"`
+}
+
+// HostSeccomp is the seccomp support of the host.
+type HostSeccomp struct {
+	// Actions are the supported seccomp actions, e.g. "SCMP_ACT_NOTIFY".
+	Actions []string `json:"actions"`
+	// Flags are the supported seccomp filter flags.
+	Flags []string `json:"flags"`
+}
+
+// HostCheckpoint is the checkpoint/restore support of the host.
+type HostCheckpoint struct {
+	// Enabled tells whether a supported CRIU version is installed.
+	Enabled bool `json:"enabled"`
+	// CriuVersion is the CRIU version, e.g. "3.19.0", if known.
+	CriuVersion string `json:"criuVersion,omitempty"`
+	// Error is why checkpoint/restore is not enabled.
+	Error string `json:"error,omitempty"`
+}
+
 const (
 	// AnnotationRuncVersion represents the version of runc, e.g., "1.2.3", "1.2.3+dev", "1.2.3-rc.4.", "1.2.3-rc.4+dev".
 	// Third party implementations such as crun and runsc MAY use this annotation to report the most compatible runc version,
@@ -4,16 +4,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/opencontainers/cgroups"
+	devices "github.com/opencontainers/cgroups/devices/config"
 	"github.com/sirupsen/logrus"
 
 	"github.com/docker/go-units"
 	"github.com/opencontainers/runc/libcontainer/configs"
+	"github.com/opencontainers/runc/libcontainer/cpuset"
 	"github.com/opencontainers/runc/libcontainer/intelrdt"
+	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/urfave/cli"
 )
@@ -55,9 +60,19 @@ The accepted format is as follow (unchanged values can be omitted):
   },
   "blockIO": {
     "weight": 0
-  }
+  },
+  "devices": [
+    {
+      "allow": true,
+      "type": "c",
+      "major": 195,
+      "access": "rwm"
+    }
+  ]
 }
 
+The device rules are appended to the current ones.
+
 Note: if data is to be read from a file or the standard input, all
 other options are ignored.
 `,
@@ -109,6 +124,14 @@ other options are ignored.
 			Usage:  "(obsoleted; do not use)",
 			Hidden: true,
 		},
+		cli.StringFlag{
+			Name:  "mems-migration",
+			Usage: "How the memory on the NUMA nodes removed from the cpuset mems is handled (none, migrate, or reclaim); reclaim is cgroup v2 only",
+		},
+		cli.StringFlag{
+			Name:  "cpuset-partition",
+			Usage: "cpuset partition type (member, root, or isolated); cgroup v2 only",
+		},
 		cli.StringFlag{
 			Name:  "memory",
 			Usage: "Memory limit (in bytes)",
@@ -125,6 +148,18 @@ other options are ignored.
 			Name:  "memory-swap",
 			Usage: "Total memory usage (memory + swap); set '-1' to enable unlimited swap",
 		},
+		cli.StringFlag{
+			Name:  "memory-swap-high",
+			Usage: "Swap usage throttle limit (in bytes); set '-1' for no limit; cgroup v2 only",
+		},
+		cli.StringFlag{
+			Name:  "zswap-max",
+			Usage: "zswap compressed pool usage limit (in bytes); set '-1' for no limit; cgroup v2 only",
+		},
+		cli.StringFlag{
+			Name:  "zswap-writeback",
+			Usage: "Whether the zswap pool pages are written back to swap (true or false); cgroup v2 only",
+		},
 		cli.IntFlag{
 			Name:  "pids-limit",
 			Usage: "Maximum number of pids allowed in the container",
@@ -137,6 +172,38 @@ other options are ignored.
 			Name:  "mem-bw-schema",
 			Usage: "The string of Intel RDT/MBA memory bandwidth schema",
 		},
+		cli.StringSliceFlag{
+			Name:  "device-add",
+			Usage: "Allow access to device(s), specified as 'type major:minor access' (e.g. 'c 195:* rwm'); can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "device-remove",
+			Usage: "Remove a device access rule previously allowed, specified as for --device-add; can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name:  "shm-size",
+			Usage: "Size of /dev/shm (in bytes)",
+		},
+		cli.StringSliceFlag{
+			Name:  "io-cost-qos",
+			Usage: "Set the blk-iocost QoS parameters of a block device, specified as 'major:minor key=value ...' (e.g. '8:0 enable=1 rlat=5000'); cgroup v2 only; can be specified multiple times",
+		},
+		cli.StringSliceFlag{
+			Name:  "io-cost-model",
+			Usage: "Set the blk-iocost cost model parameters of a block device, specified as for --io-cost-qos; cgroup v2 only; can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name:  "power-hint",
+			Usage: "Set the power hint, specified as 'key=value,...', with the epp (energy performance preference of the cpuset CPUs), uclamp.min, and uclamp.max keys (e.g. 'epp=performance,uclamp.min=50'); an empty value unsets a key",
+		},
+		cli.StringSliceFlag{
+			Name:  "exec-seccomp-add",
+			Usage: "Stack the seccomp profile of a file (in the format of the linux.seccomp spec property) onto the profile of the processes executed in the container afterwards (runc exec), not the running ones; can be specified multiple times",
+		},
+		cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print the cgroup file writes to be done (as JSON), without applying them",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		if err := checkArgs(context, 1, exactArgs); err != nil {
@@ -158,6 +225,13 @@ other options are ignored.
 		}
 
 		config := container.Config()
+		var removeDevices []specs.LinuxDeviceCgroup
+		setUnified := func(file, val string) {
+			if r.Unified == nil {
+				r.Unified = make(map[string]string)
+			}
+			r.Unified[file] = val
+		}
 
 		if in := context.String("resources"); in != "" {
 			var (
@@ -195,6 +269,52 @@ other options are ignored.
 				}
 				r.CPU.Idle = i64Ptr(idle)
 			}
+			if val := context.String("cpuset-partition"); val != "" {
+				if !configs.CpusetPartition(val).IsValid() {
+					return fmt.Errorf("invalid value for cpuset-partition: %q (must be member, root, or isolated)", val)
+				}
+				if !cgroups.IsCgroup2UnifiedMode() {
+					return errors.New("cpuset-partition requires cgroup v2")
+				}
+				setUnified(configs.CpusetPartitionFile, val)
+			}
+			for _, pair := range []struct {
+				opt  string
+				file string
+			}{
+				{"memory-swap-high", configs.MemorySwapHighFile},
+				{"zswap-max", configs.MemoryZswapMaxFile},
+			} {
+				if val := context.String(pair.opt); val != "" {
+					if !cgroups.IsCgroup2UnifiedMode() {
+						return fmt.Errorf("%s requires cgroup v2", pair.opt)
+					}
+					if val == "-1" {
+						val = "max"
+					} else {
+						v, err := units.RAMInBytes(val)
+						if err != nil || v < 0 {
+							return fmt.Errorf("invalid value for %s: %q", pair.opt, val)
+						}
+						val = strconv.FormatInt(v, 10)
+					}
+					setUnified(pair.file, val)
+				}
+			}
+			if val := context.String("zswap-writeback"); val != "" {
+				if !cgroups.IsCgroup2UnifiedMode() {
+					return errors.New("zswap-writeback requires cgroup v2")
+				}
+				enabled, err := strconv.ParseBool(val)
+				if err != nil {
+					return fmt.Errorf("invalid value for zswap-writeback: %w", err)
+				}
+				wb := "0"
+				if enabled {
+					wb = "1"
+				}
+				setUnified(configs.MemoryZswapWritebackFile, wb)
+			}
 
 			for _, pair := range []struct {
 				opt  string
@@ -254,6 +374,21 @@ other options are ignored.
 			}
 
 			r.Pids.Limit = int64(context.Int("pids-limit"))
+
+			for _, val := range context.StringSlice("device-add") {
+				d, err := parseDeviceRule(val)
+				if err != nil {
+					return err
+				}
+				r.Devices = append(r.Devices, d)
+			}
+			for _, val := range context.StringSlice("device-remove") {
+				d, err := parseDeviceRule(val)
+				if err != nil {
+					return err
+				}
+				removeDevices = append(removeDevices, d)
+			}
 		}
 
 		// Fix up values
@@ -342,6 +477,9 @@ other options are ignored.
 		if r.CPU.RealtimeRuntime != nil {
 			config.Cgroups.Resources.CpuRtRuntime = *r.CPU.RealtimeRuntime
 		}
+		if cpuset.IsRequest(r.CPU.Cpus) {
+			return fmt.Errorf("cpuset request %q can only be used when creating a container", r.CPU.Cpus)
+		}
 		config.Cgroups.Resources.CpusetCpus = r.CPU.Cpus
 		config.Cgroups.Resources.CpusetMems = r.CPU.Mems
 		if r.Memory.Limit != nil {
@@ -391,17 +529,138 @@ other options are ignored.
 			config.IntelRdt.MemBwSchema = memBwSchema
 		}
 
-		// XXX(kolyshkin@): currently "runc update" is unable to change
-		// device configuration, so add this to skip device update.
-		// This helps in case an extra plugin (nvidia GPU) applies some
+		// Update /dev/shm size.
+		if val := context.String("shm-size"); val != "" {
+			size, err := units.RAMInBytes(val)
+			if err != nil {
+				return fmt.Errorf("invalid value for shm-size: %w", err)
+			}
+			// Do not modify the original config.Shm, as it is shared
+			// with the container.
+			shm := configs.Shm{}
+			if config.Shm != nil {
+				shm = *config.Shm
+			}
+			shm.Size = size
+			config.Shm = &shm
+		}
+
+		// Update the blk-iocost parameters.
+		qos, model := context.StringSlice("io-cost-qos"), context.StringSlice("io-cost-model")
+		if len(qos) > 0 || len(model) > 0 {
+			if !cgroups.IsCgroup2UnifiedMode() {
+				return errors.New("io-cost-qos and io-cost-model require cgroup v2")
+			}
+			// Do not modify the original config.IOCost, as it is shared
+			// with the container.
+			ioCost := configs.IOCost{}
+			if config.IOCost != nil {
+				ioCost = *config.IOCost
+			}
+			for _, v := range qos {
+				d, err := configs.ParseIOCostDevice(v)
+				if err != nil {
+					return err
+				}
+				ioCost.QoS = upsertIOCostDevice(ioCost.QoS, d)
+			}
+			for _, v := range model {
+				d, err := configs.ParseIOCostDevice(v)
+				if err != nil {
+					return err
+				}
+				ioCost.Model = upsertIOCostDevice(ioCost.Model, d)
+			}
+			config.IOCost = &ioCost
+		}
+
+		// Update the power hint.
+		if v := context.String("power-hint"); v != "" {
+			// ParsePowerHint does not modify the original config.PowerHint,
+			// which is shared with the container.
+			config.PowerHint, err = configs.ParsePowerHint(v, config.PowerHint)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Update the mems migration policy, which applies to this update
+		// already.
+		if v := context.String("mems-migration"); v != "" {
+			config.MemsMigration = configs.MemsMigration(v)
+			if !config.MemsMigration.IsValid() {
+				return fmt.Errorf("invalid value for mems-migration: %q (must be none, migrate, or reclaim)", v)
+			}
+		}
+
+		// Update the device rules. Unless those are changed, skip the device
+		// update. This helps in case an extra plugin (nvidia GPU) applies some
 		// configuration on top of what runc does.
 		// Note this field is not saved into container's state.json.
-		config.Cgroups.SkipDevices = true
+		if len(r.Devices) > 0 || len(removeDevices) > 0 {
+			devs, err := updateDeviceRules(config.Cgroups.Resources.Devices, r.Devices, removeDevices)
+			if err != nil {
+				return err
+			}
+			config.Cgroups.Resources.Devices = devs
+		} else {
+			config.Cgroups.SkipDevices = true
+		}
+
+		// Load the seccomp profiles to stack before any update, so that an
+		// invalid profile changes nothing.
+		var stack []*configs.Seccomp
+		for _, path := range context.StringSlice("exec-seccomp-add") {
+			s, err := loadSeccompProfile(path)
+			if err != nil {
+				return err
+			}
+			stack = append(stack, s)
+		}
+
+		if context.Bool("dry-run") {
+			if len(stack) > 0 {
+				return errors.New("--dry-run can not be used with --exec-seccomp-add")
+			}
+			writes, err := container.DryRunSet(config)
+			if err != nil {
+				return err
+			}
+			enc := json.NewEncoder(context.App.Writer)
+			enc.SetIndent("", "  ")
+			return enc.Encode(writes)
+		}
 
-		return container.Set(config)
+		traceCgroupWrites(container)
+		if err := container.Set(config); err != nil {
+			return err
+		}
+		for _, s := range stack {
+			if err := container.AddExecSeccomp(s); err != nil {
+				return err
+			}
+		}
+		return nil
 	},
 }
 
+// upsertIOCostDevice returns devices with the parameters of d added to the
+// ones of the same device, or d appended if there are none.
+func upsertIOCostDevice(devices []*configs.IOCostDevice, d *configs.IOCostDevice) []*configs.IOCostDevice {
+	// Do not modify the original slice and devices, as those are shared
+	// with the container config.
+	devices = slices.Clone(devices)
+	for i, dev := range devices {
+		if dev.Major == d.Major && dev.Minor == d.Minor {
+			params := maps.Clone(dev.Params)
+			maps.Copy(params, d.Params)
+			devices[i] = &configs.IOCostDevice{Major: d.Major, Minor: d.Minor, Params: params}
+			return devices
+		}
+	}
+	return append(devices, d)
+}
+
 func upsertWeightDevice(devices []*cgroups.WeightDevice, wd specs.LinuxWeightDevice) []*cgroups.WeightDevice {
 	// Iterate backwards because in case of a duplicate
 	// the last one will be used.
@@ -443,3 +702,72 @@ func upsertThrottleDevice(devices []*cgroups.ThrottleDevice, td specs.LinuxThrot
 
 	return append(devices, cgroups.NewThrottleDevice(td.Major, td.Minor, td.Rate))
 }
+
+// parseDeviceRule parses an allow device rule in the "type major:minor access"
+// format (the same as for the cgroup v1 devices.allow file), where major and
+// minor can be "*" (any), such as "c 195:* rwm".
+func parseDeviceRule(rule string) (specs.LinuxDeviceCgroup, error) {
+	d := specs.LinuxDeviceCgroup{Allow: true}
+	fields := strings.Fields(rule)
+	if len(fields) != 3 {
+		return d, fmt.Errorf("invalid device rule %q: must be 'type major:minor access'", rule)
+	}
+	d.Type = fields[0]
+	switch d.Type {
+	case "a", "b", "c":
+	default:
+		return d, fmt.Errorf("invalid device rule %q: bad type %q", rule, d.Type)
+	}
+	major, minor, ok := strings.Cut(fields[1], ":")
+	if !ok {
+		return d, fmt.Errorf("invalid device rule %q: must be 'type major:minor access'", rule)
+	}
+	for _, pair := range []struct {
+		val  string
+		dest **int64
+	}{
+		{major, &d.Major},
+		{minor, &d.Minor},
+	} {
+		if pair.val == "*" {
+			continue
+		}
+		v, err := strconv.ParseInt(pair.val, 10, 64)
+		if err != nil || v < 0 {
+			return d, fmt.Errorf("invalid device rule %q: bad device number %q", rule, pair.val)
+		}
+		*pair.dest = &v
+	}
+	d.Access = fields[2]
+	if !devices.Permissions(d.Access).IsValid() {
+		return d, fmt.Errorf("invalid device rule %q: bad access %q", rule, d.Access)
+	}
+	return d, nil
+}
+
+// updateDeviceRules returns the device rules cur, with the rules in remove
+// removed, and then the rules in add appended. Removing a rule which is not
+// in cur is an error.
+func updateDeviceRules(cur []*devices.Rule, add, remove []specs.LinuxDeviceCgroup) ([]*devices.Rule, error) {
+	// Do not modify the original slice, as it is shared with the container
+	// config.
+	devs := slices.Clone(cur)
+	rm, err := specconv.CreateDeviceRules(remove)
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rm {
+		n := len(devs)
+		devs = slices.DeleteFunc(devs, func(d *devices.Rule) bool {
+			return *d == *rule
+		})
+		if len(devs) == n {
+			return nil, fmt.Errorf("device rule %q not found", rule.CgroupString())
+		}
+	}
+	rules, err := specconv.CreateDeviceRules(add)
+	if err != nil {
+		return nil, err
+	}
+	return append(devs, rules...), nil
+}
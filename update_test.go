@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	devices "github.com/opencontainers/cgroups/devices/config"
+	"github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/opencontainers/runc/libcontainer/configs"
+)
+
+func TestParseDeviceRule(t *testing.T) {
+	for _, tc := range []struct {
+		rule  string
+		isErr bool
+		want  string
+	}{
+		{rule: "c 195:* rwm", want: "c 195:* rwm"},
+		{rule: "b 8:16 rw", want: "b 8:16 rw"},
+		{rule: "c *:* m", want: "c *:* m"},
+		{rule: "c 195 rwm", isErr: true},
+		{rule: "c 195:* rwx", isErr: true},
+		{rule: "c x:1 r", isErr: true},
+		{rule: "c -1:1 r", isErr: true},
+		{rule: "x 1:1 r", isErr: true},
+		{rule: "c 1:1", isErr: true},
+	} {
+		d, err := parseDeviceRule(tc.rule)
+		if tc.isErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", tc.rule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.rule, err)
+			continue
+		}
+		rules, err := updateDeviceRules(nil, []specs.LinuxDeviceCgroup{d}, nil)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.rule, err)
+			continue
+		}
+		if got := rules[0].CgroupString(); got != tc.want || !rules[0].Allow {
+			t.Errorf("%q: expected allow %q, got %+v", tc.rule, tc.want, rules[0])
+		}
+	}
+}
+
+func TestUpdateDeviceRules(t *testing.T) {
+	null := &devices.Rule{Type: devices.CharDevice, Major: 1, Minor: 3, Permissions: "rwm", Allow: true}
+	cur := []*devices.Rule{
+		{Type: devices.WildcardDevice, Major: devices.Wildcard, Minor: devices.Wildcard, Permissions: "rwm"},
+		null,
+	}
+	gpu, _ := parseDeviceRule("c 195:* rwm")
+
+	rules, err := updateDeviceRules(cur, []specs.LinuxDeviceCgroup{gpu}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 3 || rules[2].CgroupString() != "c 195:* rwm" {
+		t.Fatalf("unexpected rules after add: %v", rules)
+	}
+	if len(cur) != 2 {
+		t.Fatal("the current rules were modified")
+	}
+
+	rules, err = updateDeviceRules(rules, nil, []specs.LinuxDeviceCgroup{gpu})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[1] != null {
+		t.Fatalf("unexpected rules after remove: %v", rules)
+	}
+
+	if _, err := updateDeviceRules(rules, nil, []specs.LinuxDeviceCgroup{gpu}); err == nil {
+		t.Fatal("expected error removing a nonexistent rule, got nil")
+	}
+}
+
+func TestUpsertIOCostDevice(t *testing.T) {
+	cur := []*configs.IOCostDevice{
+		{Major: 8, Minor: 0, Params: map[string]string{"enable": "1", "rlat": "5000"}},
+	}
+	devs := upsertIOCostDevice(cur, &configs.IOCostDevice{Major: 8, Minor: 0, Params: map[string]string{"rlat": "2000", "wlat": "8000"}})
+	devs = upsertIOCostDevice(devs, &configs.IOCostDevice{Major: 259, Minor: 0, Params: map[string]string{"enable": "1"}})
+	if len(devs) != 2 || devs[0].String() != "8:0 enable=1 rlat=2000 wlat=8000" || devs[1].String() != "259:0 enable=1" {
+		t.Errorf("unexpected devices %v", devs)
+	}
+	// The current devices must be left as they are.
+	if len(cur) != 1 || cur[0].String() != "8:0 enable=1 rlat=5000" {
+		t.Errorf("current devices modified: %v", cur)
+	}
+}
@@ -58,6 +58,8 @@ func fatal(err error) {
 }
 
 func fatalWithCode(err error, ret int) {
+	auditEnd(err, nil)
+	traceEnd(err)
 	// Make sure the error is written to the logger.
 	logrus.Error(err)
 	if !logrusToStderr() {
@@ -67,19 +69,16 @@ func fatalWithCode(err error, ret int) {
 	os.Exit(ret)
 }
 
-// setupSpec performs initial setup based on the cli.Context for the container
-func setupSpec(context *cli.Context) (*specs.Spec, error) {
+// setupSpec performs initial setup based on the cli.Context for the container,
+// and returns the spec along with the digest of its file (see loadSpecDigest).
+func setupSpec(context *cli.Context) (*specs.Spec, string, error) {
 	bundle := context.String("bundle")
 	if bundle != "" {
 		if err := os.Chdir(bundle); err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	}
-	spec, err := loadSpec(specConfig)
-	if err != nil {
-		return nil, err
-	}
-	return spec, nil
+	return loadSpecDigest(specConfig)
 }
 
 func revisePidFile(context *cli.Context) error {
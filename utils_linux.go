@@ -8,8 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/coreos/go-systemd/v22/activation"
+	"github.com/opencontainers/cgroups"
 	"github.com/opencontainers/runtime-spec/specs-go"
 	selinux "github.com/opencontainers/selinux/go-selinux"
 	"github.com/sirupsen/logrus"
@@ -17,6 +19,7 @@ import (
 	"golang.org/x/sys/unix"
 
 	"github.com/opencontainers/runc/libcontainer"
+	"github.com/opencontainers/runc/libcontainer/cgtrace"
 	"github.com/opencontainers/runc/libcontainer/configs"
 	"github.com/opencontainers/runc/libcontainer/specconv"
 	"github.com/opencontainers/runc/libcontainer/system/kernelversion"
@@ -95,7 +98,7 @@ func newProcess(p *specs.Process) (*libcontainer.Process, error) {
 }
 
 // setupIO modifies the given process config according to the options.
-func setupIO(process *libcontainer.Process, container *libcontainer.Container, createTTY, detach bool, sockpath string) (*tty, error) {
+func setupIO(process *libcontainer.Process, container *libcontainer.Container, createTTY, detach bool, sockpath string, consoleLog []string) (*tty, error) {
 	if createTTY {
 		process.Stdin = nil
 		process.Stdout = nil
@@ -115,23 +118,18 @@ func setupIO(process *libcontainer.Process, container *libcontainer.Container, c
 			go func() {
 				t.consoleC <- t.recvtty(parent)
 			}()
-		} else {
-			// the caller of runc will handle receiving the console master
-			conn, err := net.Dial("unix", sockpath)
-			if err != nil {
+		} else if consoleLog != nil {
+			// runc console-log will write the console output to a file
+			if err := setupConsoleLog(t, process, consoleLog); err != nil {
+				t.Close()
 				return nil, err
 			}
-			uc, ok := conn.(*net.UnixConn)
-			if !ok {
-				return nil, errors.New("casting to UnixConn failed")
-			}
-			t.postStart = append(t.postStart, uc)
-			socket, err := uc.File()
-			if err != nil {
+		} else {
+			// the caller of runc will handle receiving the console master
+			if err := setupConsoleSocket(t, process, sockpath); err != nil {
+				t.Close()
 				return nil, err
 			}
-			t.postStart = append(t.postStart, socket)
-			process.ConsoleSocket = socket
 		}
 		return t, nil
 	}
@@ -178,11 +176,29 @@ func createPidFile(path string, process *libcontainer.Process) error {
 	return os.Rename(tmpName, path)
 }
 
-func createContainer(context *cli.Context, id string, spec *specs.Spec) (*libcontainer.Container, error) {
+// createContainer creates the container id from spec. The bundle is the
+// absolute path of the bundle directory, or empty if it is the current
+// directory.
+func createContainer(context *cli.Context, id, bundle string, spec *specs.Spec, specDigest string) (_ *libcontainer.Container, retErr error) {
 	rootlessCg, err := shouldUseRootlessCgroupManager(context)
 	if err != nil {
 		return nil, err
 	}
+	mountPolicy, err := parseMountPolicy(context)
+	if err != nil {
+		return nil, err
+	}
+	root := context.GlobalString("root")
+	// An automatically allocated user namespace range is released along
+	// with the container, or here if it can't be created.
+	usernsAllocated := false
+	defer func() {
+		if retErr != nil && usernsAllocated {
+			if err := libcontainer.ReleaseUsernsRange(root, id); err != nil {
+				logrus.Warn(err)
+			}
+		}
+	}()
 	config, err := specconv.CreateLibcontainerConfig(&specconv.CreateOpts{
 		CgroupName:       id,
 		UseSystemdCgroup: context.GlobalBool("systemd-cgroup"),
@@ -191,13 +207,54 @@ func createContainer(context *cli.Context, id string, spec *specs.Spec) (*libcon
 		Spec:             spec,
 		RootlessEUID:     os.Geteuid() != 0,
 		RootlessCgroups:  rootlessCg,
+		MountPolicy:      mountPolicy,
+		Bundle:           bundle,
+		SpecDigest:       specDigest,
+		StrictSpec:       context.Bool("strict-spec"),
+		SchedCore:        context.Bool("sched-core"),
+		StateBlobs:       context.GlobalBool("state-blobs"),
+		AllowKeyring:     context.Bool("allow-keyring"),
+		AllowShmRemount:  context.Bool("allow-shm-remount"),
+		AllocateUserns: func(poolUser string, size int64) (int64, error) {
+			hostID, err := libcontainer.AllocateUsernsRange(root, id, poolUser, size)
+			usernsAllocated = err == nil
+			return hostID, err
+		},
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	root := context.GlobalString("root")
-	return libcontainer.Create(root, id, config)
+	if name := context.String("group"); name != "" {
+		g, err := libcontainer.LoadGroup(root, name)
+		if err != nil {
+			return nil, fmt.Errorf("resource group %s: %w", name, err)
+		}
+		if err := g.Join(config.Cgroups); err != nil {
+			return nil, fmt.Errorf("resource group %s: %w", name, err)
+		}
+	}
+	container, err := libcontainer.Create(root, id, config)
+	if err != nil {
+		return nil, err
+	}
+	traceCgroupWrites(container)
+	return container, nil
+}
+
+// traceCgroupWrites logs all cgroup file writes done for the container,
+// if debug logging is enabled.
+func traceCgroupWrites(container *libcontainer.Container) {
+	if !logrus.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+	container.TraceCgroupWrites(func(w cgtrace.Write) {
+		logrus.WithFields(logrus.Fields{
+			"path":  w.Path,
+			"value": w.Value,
+			"error": w.Error,
+		}).Debug("cgroup write")
+	})
 }
 
 type runner struct {
@@ -209,12 +266,22 @@ type runner struct {
 	preserveFDs     int
 	pidFile         string
 	consoleSocket   string
+	consoleLog      []string
 	pidfdSocket     string
 	container       *libcontainer.Container
 	action          CtAct
 	notifySocket    *notifySocket
 	criuOpts        *libcontainer.CriuOpts
 	subCgroupPaths  map[string]string
+	createCgroups   bool
+	cgroupLimits    *cgroups.Resources
+	seccomp         *configs.Seccomp
+	envDefaults     configs.EnvDefaults
+	readiness       *libcontainer.Readiness
+	idMapper        libcontainer.IDMapper
+	probeArgs       []string
+	forwarding      *configs.SignalForwarding
+	progress        func(libcontainer.Progress)
 }
 
 func (r *runner) run(config *specs.Process) (int, error) {
@@ -232,9 +299,17 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		return -1, err
 	}
 	process.LogLevel = strconv.Itoa(int(logrus.GetLevel()))
+	process.CorrelationID = logCorrelationID
 	// Populate the fields that come from runner.
 	process.Init = r.init
 	process.SubCgroupPaths = r.subCgroupPaths
+	process.CreateSubCgroups = r.createCgroups
+	process.SubCgroupResources = r.cgroupLimits
+	process.Seccomp = r.seccomp
+	process.EnvDefaults = r.envDefaults
+	process.Readiness = r.readiness
+	process.IDMapper = r.idMapper
+	process.Progress = r.progress
 	if len(r.listenFDs) > 0 {
 		process.Env = append(process.Env, "LISTEN_FDS="+strconv.Itoa(len(r.listenFDs)), "LISTEN_PID=1")
 		process.ExtraFiles = append(process.ExtraFiles, r.listenFDs...)
@@ -254,7 +329,7 @@ func (r *runner) run(config *specs.Process) (int, error) {
 	// with detaching containers, and then we get a tty after the container has
 	// started.
 	handlerCh := newSignalHandler(r.enableSubreaper, r.notifySocket)
-	tty, err := setupIO(process, r.container, config.Terminal, detach, r.consoleSocket)
+	tty, err := setupIO(process, r.container, config.Terminal, detach, r.consoleSocket, r.consoleLog)
 	if err != nil {
 		return -1, err
 	}
@@ -292,8 +367,25 @@ func (r *runner) run(config *specs.Process) (int, error) {
 			return -1, err
 		}
 	}
+	stopProbes := func() {}
+	if r.probeArgs != nil {
+		probes, err := startProbes(r.probeArgs)
+		if err != nil {
+			logrus.Warnf("unable to run the container probes: %v", err)
+		} else {
+			if err := r.container.AddHelper(probes.Process.Pid); err != nil {
+				logrus.Warnf("unable to account the probe runner to the container: %v", err)
+			}
+			stopProbes = func() {
+				_ = probes.Process.Signal(unix.SIGTERM)
+				_ = probes.Wait()
+			}
+		}
+	}
 	handler := <-handlerCh
+	handler.forwarding = r.container.Config().SignalForwarding.Merge(r.forwarding)
 	status, err := handler.forward(process, tty, detach)
+	stopProbes()
 	if err != nil {
 		r.terminate(process)
 	}
@@ -301,6 +393,9 @@ func (r *runner) run(config *specs.Process) (int, error) {
 		return 0, nil
 	}
 	if err == nil {
+		if !r.init {
+			r.container.ExecExited(process, status)
+		}
 		r.destroy()
 	}
 	return status, err
@@ -322,12 +417,21 @@ func (r *runner) terminate(p *libcontainer.Process) {
 func (r *runner) checkTerminal(config *specs.Process) error {
 	detach := r.detach || (r.action == CT_ACT_CREATE)
 	// Check command-line for sanity.
-	if detach && config.Terminal && r.consoleSocket == "" {
-		return errors.New("cannot allocate tty if runc will detach without setting console socket")
+	if r.consoleSocket != "" && r.consoleLog != nil {
+		return errors.New("cannot use both console socket and console log")
+	}
+	if detach && config.Terminal && r.consoleSocket == "" && r.consoleLog == nil {
+		return errors.New("cannot allocate tty if runc will detach without setting console socket (or console log)")
 	}
 	if (!detach || !config.Terminal) && r.consoleSocket != "" {
 		return errors.New("cannot use console socket if runc will not detach or allocate tty")
 	}
+	if (!detach || !config.Terminal) && r.consoleLog != nil {
+		return errors.New("cannot use console log if runc will not detach or allocate tty")
+	}
+	if r.consoleSocket != "" {
+		return checkConsoleSocket(r.consoleSocket, false)
+	}
 	return nil
 }
 
@@ -362,7 +466,7 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 	if err := revisePidFile(context); err != nil {
 		return -1, err
 	}
-	spec, err := setupSpec(context)
+	spec, specDigest, err := setupSpec(context)
 	if err != nil {
 		return -1, err
 	}
@@ -371,13 +475,36 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 	if id == "" {
 		return -1, errEmptyID
 	}
+	consoleLog, err := consoleLogArgs(context)
+	if err != nil {
+		return -1, err
+	}
 
 	notifySocket := newNotifySocket(context, os.Getenv("NOTIFY_SOCKET"), id)
 	if notifySocket != nil {
 		notifySocket.setupSpec(spec)
 	}
 
-	container, err := createContainer(context, id, spec)
+	idMapper, err := parseIDMapper(context.String("idmap-helper"))
+	if err != nil {
+		return -1, err
+	}
+
+	forwarding, err := getSignalForwarding(context)
+	if err != nil {
+		return -1, err
+	}
+
+	// The progress of a restore is reported with criuOpts.
+	var progress func(libcontainer.Progress)
+	if criuOpts == nil {
+		progress, err = newProgressReporter(context, id)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	container, err := createContainer(context, id, "", spec, specDigest)
 	if err != nil {
 		return -1, err
 	}
@@ -406,6 +533,7 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 		listenFDs:       listenFDs,
 		notifySocket:    notifySocket,
 		consoleSocket:   context.String("console-socket"),
+		consoleLog:      consoleLog,
 		pidfdSocket:     context.String("pidfd-socket"),
 		detach:          context.Bool("detach"),
 		pidFile:         context.String("pid-file"),
@@ -413,6 +541,12 @@ func startContainer(context *cli.Context, action CtAct, criuOpts *libcontainer.C
 		action:          action,
 		criuOpts:        criuOpts,
 		init:            true,
+		idMapper:        idMapper,
+		forwarding:      forwarding,
+		progress:        progress,
+	}
+	if action == CT_ACT_RUN && !r.detach && len(container.Config().Probes) > 0 {
+		r.probeArgs = probeArgs(context, id)
 	}
 	return r.run(spec.Process)
 }
@@ -455,3 +589,50 @@ func maybeLogCgroupWarning(op string, err error) {
 		logrus.Warn("runc " + op + " failure might be caused by lack of full access to cgroups")
 	}
 }
+
+// parseMountPolicy parses the --mount-policy and --mount-policy-allow global
+// options.
+func parseMountPolicy(context *cli.Context) (*configs.MountPolicy, error) {
+	v := context.GlobalString("mount-policy")
+	if v == "" {
+		if len(context.GlobalStringSlice("mount-policy-allow")) > 0 {
+			return nil, errors.New("--mount-policy-allow requires --mount-policy")
+		}
+		return nil, nil
+	}
+	p := &configs.MountPolicy{Allow: context.GlobalStringSlice("mount-policy-allow")}
+	for _, flag := range strings.Split(v, ",") {
+		switch flag {
+		case "nosuid":
+			p.NoSuid = true
+		case "nodev":
+			p.NoDev = true
+		case "noexec":
+			p.NoExec = true
+		default:
+			return nil, fmt.Errorf("invalid --mount-policy flag: %q", flag)
+		}
+	}
+	return p, nil
+}
+
+// parseIDMapper parses the --idmap-helper option.
+func parseIDMapper(v string) (libcontainer.IDMapper, error) {
+	switch {
+	case v == "":
+		return nil, nil
+	case v == "direct":
+		return libcontainer.DirectIDMapper{}, nil
+	case v == "newuidmap":
+		return &libcontainer.ToolIDMapper{}, nil
+	case v == "subid":
+		return &libcontainer.SubIDMapper{}, nil
+	case strings.HasPrefix(v, "unix://"):
+		path := strings.TrimPrefix(v, "unix://")
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("invalid --idmap-helper socket path %q: must be absolute", path)
+		}
+		return &libcontainer.HelperIDMapper{Socket: path}, nil
+	}
+	return nil, fmt.Errorf("invalid --idmap-helper value %q", v)
+}
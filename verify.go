@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/urfave/cli"
+
+	"github.com/opencontainers/runc/libcontainer"
+)
+
+// verifyResult is the output of "runc verify".
+type verifyResult struct {
+	ID    string               `json:"id"`
+	Drift []libcontainer.Drift `json:"drift"`
+}
+
+var verifyCommand = cli.Command{
+	Name:  "verify",
+	Usage: "compare the configuration of a container against its actual state",
+	ArgsUsage: `<container-id>
+
+Where "<container-id>" is the name for the instance of the container.`,
+	Description: `The verify command compares the saved configuration of a running
+container against the actual state of its init process (the mounts, the cgroup
+limits, the sysctls, the seccomp filter, and the capabilities), and outputs
+the differences in JSON. The exit status is 1 if there are any.`,
+	Action: func(context *cli.Context) error {
+		if err := checkArgs(context, 1, exactArgs); err != nil {
+			return err
+		}
+		container, err := getContainer(context)
+		if err != nil {
+			return err
+		}
+		drift, err := container.Verify()
+		if err != nil {
+			return err
+		}
+		if drift == nil {
+			drift = []libcontainer.Drift{}
+		}
+		data, err := json.MarshalIndent(verifyResult{ID: container.ID(), Drift: drift}, "", "  ")
+		if err != nil {
+			return err
+		}
+		os.Stdout.Write(append(data, '\n'))
+		if len(drift) > 0 {
+			return errors.New("the container state differs from its configuration")
+		}
+		return nil
+	},
+}